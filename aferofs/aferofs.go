@@ -0,0 +1,334 @@
+// Package aferofs provides a read-only [afero.Fs] serving the files of a 7-zip
+// archive, so code written against afero can read archive contents directly.
+package aferofs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/javi11/sevenzip"
+	"github.com/spf13/afero"
+)
+
+var errNegativeOffset = errors.New("negative offset")
+
+// Fs is a read-only [afero.Fs] backed by a [sevenzip.Reader]. Names may be
+// absolute or relative and are resolved from the root of the archive. Any
+// method that would modify the filesystem fails with an error wrapping
+// [fs.ErrPermission].
+type Fs struct {
+	r *sevenzip.Reader
+}
+
+var _ afero.Fs = (*Fs)(nil)
+
+// New returns a new [*Fs] serving the files of r.
+func New(r *sevenzip.Reader) *Fs {
+	return &Fs{r: r}
+}
+
+// clean converts an afero name to a name accepted by [sevenzip.Reader.Open].
+func clean(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(name)), "/")
+	if name == "" {
+		return "."
+	}
+
+	return name
+}
+
+func denied(op, name string) error {
+	return &iofs.PathError{Op: op, Path: name, Err: iofs.ErrPermission}
+}
+
+// Name returns the name of the filesystem.
+func (fs *Fs) Name() string { return "sevenzip" }
+
+// Create always fails as the filesystem is read-only.
+func (fs *Fs) Create(name string) (afero.File, error) { return nil, denied("create", name) }
+
+// Mkdir always fails as the filesystem is read-only.
+func (fs *Fs) Mkdir(name string, _ os.FileMode) error { return denied("mkdir", name) }
+
+// MkdirAll always fails as the filesystem is read-only.
+func (fs *Fs) MkdirAll(name string, _ os.FileMode) error { return denied("mkdir", name) }
+
+// Remove always fails as the filesystem is read-only.
+func (fs *Fs) Remove(name string) error { return denied("remove", name) }
+
+// RemoveAll always fails as the filesystem is read-only.
+func (fs *Fs) RemoveAll(name string) error { return denied("remove", name) }
+
+// Rename always fails as the filesystem is read-only.
+func (fs *Fs) Rename(oldname, _ string) error { return denied("rename", oldname) }
+
+// Chmod always fails as the filesystem is read-only.
+func (fs *Fs) Chmod(name string, _ os.FileMode) error { return denied("chmod", name) }
+
+// Chown always fails as the filesystem is read-only.
+func (fs *Fs) Chown(name string, _, _ int) error { return denied("chown", name) }
+
+// Chtimes always fails as the filesystem is read-only.
+func (fs *Fs) Chtimes(name string, _, _ time.Time) error { return denied("chtimes", name) }
+
+// Stat returns the [fs.FileInfo] describing the named file.
+func (fs *Fs) Stat(name string) (os.FileInfo, error) {
+	info, err := iofs.Stat(fs.r, clean(name))
+	if err != nil {
+		return nil, fmt.Errorf("aferofs: %w", err)
+	}
+
+	return info, nil
+}
+
+// OpenFile opens the named file, which fails unless flag only requests
+// reading.
+func (fs *Fs) OpenFile(name string, flag int, _ os.FileMode) (afero.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0 {
+		return nil, denied("open", name)
+	}
+
+	return fs.Open(name)
+}
+
+// Open opens the named file for reading. Files can be read from any offset,
+// however seeking backwards or reading at an offset before the current
+// position decodes the file again from the start.
+func (fs *Fs) Open(name string) (afero.File, error) {
+	n := clean(name)
+
+	f, err := fs.r.Open(n)
+	if err != nil {
+		return nil, fmt.Errorf("aferofs: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("aferofs: %w", err), f.Close())
+	}
+
+	if info.IsDir() {
+		return &file{name: name, info: info, dir: f}, nil
+	}
+
+	// Only the reader from File.Open is needed
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("aferofs: %w", err)
+	}
+
+	zf, ok := fs.r.FileByName(n)
+	if !ok {
+		return nil, &iofs.PathError{Op: "open", Path: name, Err: iofs.ErrNotExist}
+	}
+
+	return &file{name: name, info: info, f: zf}, nil
+}
+
+// file is an [afero.File] for either a file or directory in the archive.
+type file struct {
+	name string
+	info iofs.FileInfo
+
+	// Set for directories
+	dir iofs.File
+
+	// Set for files, the reader is opened when needed and reopened when
+	// seeking backwards
+	f   *sevenzip.File
+	rc  io.ReadCloser
+	off int64
+	pos int64
+}
+
+var _ afero.File = (*file)(nil)
+
+func (f *file) Name() string { return f.name }
+
+func (f *file) Stat() (os.FileInfo, error) { return f.info, nil }
+
+func (f *file) Close() error {
+	var err error
+
+	if f.dir != nil {
+		err = f.dir.Close()
+	}
+
+	if f.rc != nil {
+		err = errors.Join(err, f.rc.Close())
+		f.rc = nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("aferofs: %w", err)
+	}
+
+	return nil
+}
+
+// reader returns the reader positioned at the current offset.
+func (f *file) reader() (io.Reader, error) {
+	if f.rc != nil && f.pos < f.off {
+		if err := f.rc.Close(); err != nil {
+			return nil, fmt.Errorf("aferofs: %w", err)
+		}
+
+		f.rc = nil
+	}
+
+	if f.rc == nil {
+		rc, err := f.f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("aferofs: %w", err)
+		}
+
+		f.rc, f.off = rc, 0
+	}
+
+	if f.pos > f.off {
+		n, err := io.CopyN(io.Discard, f.rc, f.pos-f.off)
+		f.off += n
+
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("aferofs: %w", err)
+		}
+	}
+
+	return f.rc, nil
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	if f.dir != nil {
+		return 0, &iofs.PathError{Op: "read", Path: f.name, Err: iofs.ErrInvalid}
+	}
+
+	r, err := f.reader()
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := r.Read(p)
+	f.off += int64(n)
+	f.pos = f.off
+
+	return n, err //nolint:wrapcheck
+}
+
+// ReadAt reads from the file at off without changing the offset used by
+// Read. It decodes the file from the start with a separate reader.
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	if f.dir != nil {
+		return 0, &iofs.PathError{Op: "readat", Path: f.name, Err: iofs.ErrInvalid}
+	}
+
+	if off < 0 {
+		return 0, &iofs.PathError{Op: "readat", Path: f.name, Err: errNegativeOffset}
+	}
+
+	rc, err := f.f.Open()
+	if err != nil {
+		return 0, fmt.Errorf("aferofs: %w", err)
+	}
+
+	defer rc.Close() //nolint:errcheck
+
+	if _, err := io.CopyN(io.Discard, rc, off); err != nil {
+		if errors.Is(err, io.EOF) {
+			return 0, io.EOF
+		}
+
+		return 0, fmt.Errorf("aferofs: %w", err)
+	}
+
+	n, err := io.ReadFull(rc, p)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+
+	return n, err //nolint:wrapcheck
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	if f.dir != nil {
+		return 0, &iofs.PathError{Op: "seek", Path: f.name, Err: iofs.ErrInvalid}
+	}
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.pos
+	case io.SeekEnd:
+		offset += f.info.Size()
+	default:
+		return 0, &iofs.PathError{Op: "seek", Path: f.name, Err: iofs.ErrInvalid}
+	}
+
+	if offset < 0 {
+		return 0, &iofs.PathError{Op: "seek", Path: f.name, Err: errNegativeOffset}
+	}
+
+	f.pos = offset
+
+	return offset, nil
+}
+
+func (f *file) Readdir(count int) ([]os.FileInfo, error) {
+	entries, err := f.readDir(count)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]os.FileInfo, len(entries))
+
+	for i, e := range entries {
+		if infos[i], err = e.Info(); err != nil {
+			return nil, fmt.Errorf("aferofs: %w", err)
+		}
+	}
+
+	return infos, nil
+}
+
+func (f *file) Readdirnames(count int) ([]string, error) {
+	entries, err := f.readDir(count)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.Name()
+	}
+
+	return names, nil
+}
+
+func (f *file) readDir(count int) ([]iofs.DirEntry, error) {
+	d, ok := f.dir.(iofs.ReadDirFile)
+	if !ok {
+		return nil, &iofs.PathError{Op: "readdir", Path: f.name, Err: iofs.ErrInvalid}
+	}
+
+	entries, err := d.ReadDir(count)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("aferofs: %w", err)
+	}
+
+	return entries, err //nolint:wrapcheck
+}
+
+func (f *file) Sync() error { return nil }
+
+func (f *file) Write([]byte) (int, error) { return 0, denied("write", f.name) }
+
+func (f *file) WriteAt([]byte, int64) (int, error) { return 0, denied("write", f.name) }
+
+func (f *file) WriteString(string) (int, error) { return 0, denied("write", f.name) }
+
+func (f *file) Truncate(int64) error { return denied("truncate", f.name) }
@@ -0,0 +1,160 @@
+package aferofs_test
+
+import (
+	"io"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/javi11/sevenzip/aferofs"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openFs(t *testing.T) (*sevenzip.ReadCloser, *aferofs.Fs) {
+	t.Helper()
+
+	r, err := sevenzip.OpenReader(filepath.Join("..", "testdata", "lzma1900.7z"))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, r.Close())
+	})
+
+	return r, aferofs.New(&r.Reader)
+}
+
+func readFile(t *testing.T, f *sevenzip.File) []byte {
+	t.Helper()
+
+	rc, err := f.Open()
+	require.NoError(t, err)
+
+	b, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+
+	return b
+}
+
+func TestWalk(t *testing.T) {
+	t.Parallel()
+
+	r, fs := openFs(t)
+
+	var files int
+
+	err := afero.Walk(fs, "/", func(name string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		files++
+
+		// Reading every file decodes the solid blocks repeatedly
+		if filepath.Dir(name) != filepath.FromSlash("/Asm/x86") {
+			return nil
+		}
+
+		f, ok := r.FileByName(filepath.ToSlash(name[1:]))
+		require.True(t, ok, name)
+
+		b, err := afero.ReadFile(fs, name)
+		require.NoError(t, err)
+		assert.Equal(t, readFile(t, f), b, name)
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	var want int
+
+	for _, f := range r.File {
+		if !f.FileInfo().IsDir() {
+			want++
+		}
+	}
+
+	assert.Equal(t, want, files)
+}
+
+func TestSeek(t *testing.T) {
+	t.Parallel()
+
+	r, fs := openFs(t)
+
+	zf, ok := r.FileByName("Asm/x86/7zCrcOpt.asm")
+	require.True(t, ok)
+
+	want := readFile(t, zf)
+
+	f, err := fs.Open("Asm/x86/7zCrcOpt.asm")
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, f.Close())
+	}()
+
+	b := make([]byte, 16)
+
+	for _, off := range []int64{100, 10, 200, 0} {
+		n, err := f.Seek(off, io.SeekStart)
+		require.NoError(t, err)
+		assert.Equal(t, off, n)
+
+		_, err = io.ReadFull(f, b)
+		require.NoError(t, err)
+		assert.Equal(t, want[off:off+16], b)
+	}
+
+	n, err := f.Seek(-16, io.SeekEnd)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(want)-16), n)
+
+	rest, err := io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, want[len(want)-16:], rest)
+
+	_, err = f.ReadAt(b, 50)
+	require.NoError(t, err)
+	assert.Equal(t, want[50:66], b)
+
+	_, err = f.ReadAt(b, int64(len(want)-8))
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestReadOnly(t *testing.T) {
+	t.Parallel()
+
+	_, fs := openFs(t)
+
+	_, err := fs.Create("new")
+	assert.ErrorIs(t, err, iofs.ErrPermission)
+
+	assert.ErrorIs(t, fs.Remove("Asm"), iofs.ErrPermission)
+	assert.ErrorIs(t, fs.Mkdir("new", 0o755), iofs.ErrPermission)
+
+	_, err = fs.OpenFile("Asm/x86/7zCrcOpt.asm", os.O_RDWR, 0)
+	assert.ErrorIs(t, err, iofs.ErrPermission)
+
+	f, err := fs.OpenFile("Asm/x86/7zCrcOpt.asm", os.O_RDONLY, 0)
+	require.NoError(t, err)
+
+	_, err = f.Write([]byte("x"))
+	assert.ErrorIs(t, err, iofs.ErrPermission)
+	require.NoError(t, f.Close())
+
+	_, err = fs.Stat("/missing")
+	assert.ErrorIs(t, err, iofs.ErrNotExist)
+
+	info, err := fs.Stat("/")
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+
+	names, err := afero.ReadDir(fs, "/Asm")
+	require.NoError(t, err)
+	assert.NotEmpty(t, names)
+}
@@ -0,0 +1,144 @@
+package sevenzip
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// An Appendable is an existing archive that can be both read and written,
+// such as an [*os.File] opened with [os.O_RDWR].
+type Appendable interface {
+	io.ReaderAt
+	io.WriteSeeker
+}
+
+// A WriteCloser is a [Writer] that must be closed when no longer needed.
+type WriteCloser struct {
+	c io.Closer
+	Writer
+}
+
+// OpenWriter opens the archive specified by name for appending new files, as
+// with [NewAppendWriter].
+func OpenWriter(name string, opts ...WriterOption) (*WriteCloser, error) {
+	f, err := os.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("sevenzip: error opening: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("sevenzip: error retrieving file info: %w", err), f.Close())
+	}
+
+	wc := &WriteCloser{c: f}
+
+	for _, opt := range opts {
+		opt(&wc.opts)
+	}
+
+	if err := wc.initAppend(f, info.Size()); err != nil {
+		return nil, errors.Join(err, f.Close())
+	}
+
+	return wc, nil
+}
+
+// Close finishes writing the archive and closes the files it was written
+// to.
+func (wc *WriteCloser) Close() error {
+	if err := errors.Join(wc.Writer.Close(), wc.c.Close()); err != nil {
+		return fmt.Errorf("sevenzip: error closing: %w", err)
+	}
+
+	return nil
+}
+
+// NewAppendWriter returns a new [*Writer] that adds files to the existing
+// archive a, which is size bytes long. The existing blocks are kept as they
+// are and each new file is written to a new block after them, overwriting
+// the old header, so only the header is rewritten when the Writer is closed.
+// Anything following the old header is lost and a is truncated to the new
+// end of the archive if it has a Truncate method, such as [*os.File].
+//
+// Archives whose header is encrypted can only be appended to if the password
+// is given with [WithWriterPassword], which is also used to encrypt the new
+// files, and the header stays encrypted. Archives split into volumes can't
+// be appended to. Nothing is written to a until files are added or the
+// Writer is closed, but if that fails a may be left corrupt.
+func NewAppendWriter(a Appendable, size int64, opts ...WriterOption) (*Writer, error) {
+	w := new(Writer)
+
+	for _, opt := range opts {
+		opt(&w.opts)
+	}
+
+	if err := w.initAppend(a, size); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// initAppend reads the header of the archive a and positions it after the
+// last packed stream, ready for new files to be appended.
+func (w *Writer) initAppend(a Appendable, size int64) (err error) {
+	z, err := NewReaderWithPassword(a, size, w.opts.password)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err = errors.Join(err, z.Close())
+	}()
+
+	offset := z.start
+	if si := z.si; si != nil && si.packInfo != nil {
+		offset += si.packedOffset(len(si.packInfo.size))
+	}
+
+	if w.opts.keepHeader {
+		// The new files are written over the old header and any it
+		// kept, so they're encoded again when the new header is
+		if w.kept, err = keptHeaders(z); err != nil {
+			return err
+		}
+	}
+
+	if _, err := a.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("sevenzip: error seeking: %w", err)
+	}
+
+	w.w, w.ws, w.data = a, a, a
+	w.start, w.started = z.base, true
+	w.base, w.truncate = z.h, true
+
+	// Keep the names of the existing files hidden
+	if z.encryptedHeader {
+		w.opts.encryptHeader = true
+	}
+
+	return nil
+}
+
+// keptHeaders returns the headers of the earlier versions of the archive z
+// followed by its own header, oldest first.
+func keptHeaders(z *Reader) (kept []*header, err error) {
+	gens, err := z.Generations()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, g := range gens {
+		kept = append(kept, g.h)
+		err = errors.Join(err, g.Close())
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append(kept, z.h), nil
+}
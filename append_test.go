@@ -0,0 +1,178 @@
+package sevenzip_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readAll returns the contents of every file in the archive, keyed by name.
+func readAll(t *testing.T, r *sevenzip.Reader) map[string][]byte {
+	t.Helper()
+
+	contents := make(map[string][]byte, len(r.File))
+
+	for _, f := range r.File {
+		rc, err := f.Open()
+		require.NoError(t, err, f.Name)
+
+		b, err := io.ReadAll(rc)
+		require.NoError(t, err, f.Name)
+		require.NoError(t, rc.Close(), f.Name)
+
+		contents[f.Name] = b
+	}
+
+	return contents
+}
+
+func TestOpenWriter(t *testing.T) {
+	t.Parallel()
+
+	tables := []string{
+		"copy.7z",
+		"lzma2.7z",
+		"bcj2.7z",
+		"empty.7z",
+		"empty2.7z",
+		"file_and_empty.7z",
+		"comment.7z",
+		"t1.7z",
+		"sfx.exe",
+	}
+
+	for _, table := range tables {
+		t.Run(table, func(t *testing.T) {
+			t.Parallel()
+
+			src, err := sevenzip.OpenReader(filepath.Join("testdata", table))
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				require.NoError(t, src.Close())
+			})
+
+			want := readAll(t, &src.Reader)
+
+			srcInfo, err := src.Info()
+			require.NoError(t, err)
+
+			b, err := os.ReadFile(filepath.Join("testdata", table))
+			require.NoError(t, err)
+
+			name := filepath.Join(t.TempDir(), table)
+			require.NoError(t, os.WriteFile(name, b, 0o600))
+
+			w, err := sevenzip.OpenWriter(name)
+			require.NoError(t, err)
+
+			fw, err := w.Create("appended/a.txt")
+			require.NoError(t, err)
+
+			_, err = io.WriteString(fw, "appended")
+			require.NoError(t, err)
+
+			_, err = w.Create("appended/empty.txt")
+			require.NoError(t, err)
+
+			require.NoError(t, w.Close())
+
+			want["appended/a.txt"] = []byte("appended")
+			want["appended/empty.txt"] = []byte{}
+
+			dst, err := sevenzip.OpenReader(name)
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				require.NoError(t, dst.Close())
+			})
+
+			assert.Len(t, dst.File, len(src.File)+2)
+
+			got := readAll(t, &dst.Reader)
+			assert.Equal(t, len(want), len(got))
+
+			for name, b := range want {
+				assert.True(t, bytes.Equal(b, got[name]), name)
+			}
+
+			for i, f := range src.File {
+				assert.Equal(t, f.FileHeader.Name, dst.File[i].Name)
+				assert.Equal(t, f.CRC32, dst.File[i].CRC32, f.Name)
+
+				// Files without attributes get the archive attribute, as
+				// the appended files have them
+				attributes := f.Attributes
+				if attributes == 0 {
+					attributes = 0x20
+				}
+
+				assert.Equal(t, attributes, dst.File[i].Attributes, f.Name)
+				assert.True(t, f.Modified.Equal(dst.File[i].Modified), f.Name)
+			}
+
+			info, err := dst.Info()
+			require.NoError(t, err)
+			assert.Equal(t, srcInfo.Properties, info.Properties)
+			assert.Equal(t, srcInfo.Comment, info.Comment)
+
+			// The old header and anything after it has gone
+			assert.Empty(t, info.Trailing)
+			assert.Equal(t, src.BaseOffset(), dst.BaseOffset())
+		})
+	}
+}
+
+func TestOpenWriterEncryptedHeader(t *testing.T) {
+	t.Parallel()
+
+	b, err := os.ReadFile(filepath.Join("testdata", "t2.7z"))
+	require.NoError(t, err)
+
+	name := filepath.Join(t.TempDir(), "t2.7z")
+	require.NoError(t, os.WriteFile(name, b, 0o600))
+
+	_, err = sevenzip.OpenWriter(name)
+	require.ErrorIs(t, err, sevenzip.ErrPasswordRequired)
+
+	// Nothing was written
+	a, err := os.ReadFile(name)
+	require.NoError(t, err)
+	assert.Equal(t, b, a)
+
+	w, err := sevenzip.OpenWriter(name, sevenzip.WithWriterPassword("password"))
+	require.NoError(t, err)
+
+	fw, err := w.Create("baz")
+	require.NoError(t, err)
+
+	_, err = io.WriteString(fw, "baz\n")
+	require.NoError(t, err)
+
+	require.NoError(t, w.Close())
+
+	// The header is still encrypted
+	_, err = sevenzip.OpenReader(name)
+	require.ErrorIs(t, err, sevenzip.ErrPasswordRequired)
+
+	r, err := sevenzip.OpenReaderWithPassword(name, "password")
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, r.Close())
+	})
+
+	got := readAll(t, &r.Reader)
+	assert.Equal(t, map[string][]byte{
+		"bar": []byte("bar\n"),
+		"foo": []byte("foo\n"),
+		"baz": []byte("baz\n"),
+	}, got)
+	assert.Equal(t, "7zAES", r.File[2].Coders()[0].Name())
+}
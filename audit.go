@@ -0,0 +1,131 @@
+package sevenzip
+
+import (
+	"cmp"
+	"io"
+	"slices"
+	"sync"
+)
+
+// A ReadRange is a range of bytes read from a volume of the archive.
+type ReadRange struct {
+	// Volume is the index of the volume, always zero unless the archive
+	// was opened from multiple volumes.
+	Volume int
+	// Offset is the offset of the range within the volume.
+	Offset int64
+	Size   int64
+}
+
+// audit records the ranges of the archive read on behalf of each file when
+// [WithAudit] is used.
+type audit struct {
+	mu     sync.Mutex
+	ranges map[*File][]ReadRange
+}
+
+// auditSink attributes reads by the decoders of a folder to the file most
+// recently opened with them, which changes as decoders are reused.
+type auditSink struct {
+	z  *Reader
+	mu sync.Mutex
+	f  *File
+}
+
+func (s *auditSink) setFile(f *File) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.f = f
+}
+
+func (s *auditSink) record(off, n int64) {
+	s.mu.Lock()
+	f := s.f
+	s.mu.Unlock()
+
+	// Reads before any file is opened, such as the header, aren't recorded
+	if f == nil || n <= 0 {
+		return
+	}
+
+	a := s.z.audit
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.ranges[f] = append(a.ranges[f], s.z.readRanges(off, n)...)
+}
+
+// auditReaderAt records the ranges read from the archive with a sink.
+type auditReaderAt struct {
+	r    io.ReaderAt
+	sink *auditSink
+}
+
+func (ra *auditReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := ra.r.ReadAt(p, off)
+	ra.sink.record(off, int64(n))
+
+	return n, err //nolint:wrapcheck
+}
+
+// readRanges splits the n bytes at offset off into a range for each volume
+// they span.
+func (z *Reader) readRanges(off, n int64) []ReadRange {
+	if len(z.volumes) == 0 {
+		return []ReadRange{{Offset: off, Size: n}}
+	}
+
+	var ranges []ReadRange
+
+	for i, size := range z.volumes {
+		if n > 0 && off < size {
+			m := min(n, size-off)
+			ranges = append(ranges, ReadRange{Volume: i, Offset: off, Size: m})
+			off, n = 0, n-m
+
+			continue
+		}
+
+		off -= size
+	}
+
+	return ranges
+}
+
+// ReadRanges returns the ranges of the archive read to satisfy every call to
+// [File.Open] for the file so far, sorted and with overlapping or adjacent
+// ranges merged, so that offsets recorded elsewhere can be checked against
+// what the decoders actually touched. It includes anything read to decode
+// the files before it in the same block and any read ahead, see
+// [WithReadAhead]. It returns nil unless the archive was opened with
+// [WithAudit].
+func (f *File) ReadRanges() []ReadRange {
+	a := f.zip.audit
+	if a == nil {
+		return nil
+	}
+
+	a.mu.Lock()
+	ranges := slices.Clone(a.ranges[f])
+	a.mu.Unlock()
+
+	slices.SortFunc(ranges, func(a, b ReadRange) int {
+		return cmp.Or(cmp.Compare(a.Volume, b.Volume), cmp.Compare(a.Offset, b.Offset))
+	})
+
+	merged := ranges[:0]
+
+	for _, r := range ranges {
+		if k := len(merged) - 1; k >= 0 && merged[k].Volume == r.Volume && r.Offset <= merged[k].Offset+merged[k].Size {
+			merged[k].Size = max(merged[k].Size, r.Offset+r.Size-merged[k].Offset)
+
+			continue
+		}
+
+		merged = append(merged, r)
+	}
+
+	return merged
+}
@@ -0,0 +1,143 @@
+package sevenzip_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// covered returns true if ranges include every byte from off to off+n, with
+// the offset of each range converted back to one from the start of the first
+// volume using the volume sizes.
+func covered(ranges []sevenzip.ReadRange, volumes []int64, off, n int64) bool {
+	for _, r := range ranges {
+		start := r.Offset
+		for _, size := range volumes[:r.Volume] {
+			start += size
+		}
+
+		if start <= off && start+r.Size > off {
+			m := min(n, start+r.Size-off)
+			off, n = off+m, n-m
+		}
+	}
+
+	return n == 0
+}
+
+func TestReadRanges(t *testing.T) {
+	t.Parallel()
+
+	archive, err := os.ReadFile(filepath.Join("testdata", "copy.7z"))
+	require.NoError(t, err)
+
+	const volumeSize = 4096
+
+	fs := afero.NewMemMapFs()
+
+	var volumes []int64
+
+	for i := 0; i*volumeSize < len(archive); i++ {
+		volume := archive[i*volumeSize : min((i+1)*volumeSize, len(archive))]
+		require.NoError(t, afero.WriteFile(fs, fmt.Sprintf("copy.7z.%03d", i+1), volume, 0o644))
+
+		volumes = append(volumes, int64(len(volume)))
+	}
+
+	tables := []struct {
+		name    string
+		file    string
+		volumes []int64
+	}{
+		{
+			name:    "single",
+			file:    filepath.Join("testdata", "copy.7z"),
+			volumes: []int64{int64(len(archive))},
+		},
+		{
+			name:    "volumes",
+			file:    "copy.7z.001",
+			volumes: volumes,
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			opts := []sevenzip.ReaderOption{sevenzip.WithAudit()}
+			if len(table.volumes) > 1 {
+				opts = append(opts, sevenzip.WithFs(fs))
+			}
+
+			r, err := sevenzip.OpenReaderWithOptions(table.file, opts...)
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				require.NoError(t, r.Close())
+			})
+
+			// In reverse so every file is decoded from the start of
+			// its block rather than following on from the last one
+			files := slices.Clone(r.File)
+			slices.Reverse(files)
+
+			var spanned bool
+
+			for _, f := range files {
+				if !f.HasStream() {
+					continue
+				}
+
+				rc, err := f.Open()
+				require.NoError(t, err)
+
+				_, err = io.Copy(io.Discard, rc)
+				require.NoError(t, err)
+				require.NoError(t, rc.Close())
+
+				offset, _, err := f.DataOffset()
+				require.NoError(t, err)
+
+				ranges := f.ReadRanges()
+				require.NotEmpty(t, ranges, f.Name)
+				assert.True(t, covered(ranges, table.volumes, offset, int64(f.UncompressedSize)), f.Name) //nolint:gosec
+
+				for _, rr := range ranges {
+					assert.LessOrEqual(t, rr.Offset+rr.Size, table.volumes[rr.Volume], f.Name)
+				}
+
+				spanned = spanned || ranges[0].Volume != ranges[len(ranges)-1].Volume
+			}
+
+			assert.Equal(t, len(table.volumes) > 1, spanned)
+		})
+	}
+}
+
+func TestReadRangesDisabled(t *testing.T) {
+	t.Parallel()
+
+	r, err := sevenzip.OpenReader(filepath.Join("testdata", "copy.7z"))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, r.Close())
+	})
+
+	for _, f := range r.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+
+		assert.Nil(t, f.ReadRanges())
+	}
+}
@@ -0,0 +1,147 @@
+// Package billyfs provides a read-only [billy.Filesystem] serving the files of
+// a 7-zip archive, so tools built on go-billy, such as go-git, can read
+// archive contents directly.
+package billyfs
+
+import (
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/helper/chroot"
+	"github.com/javi11/sevenzip"
+	"github.com/javi11/sevenzip/aferofs"
+	"github.com/spf13/afero"
+)
+
+// errReadOnly is returned by every method that would modify the filesystem.
+// It matches both [billy.ErrReadOnly] and [fs.ErrPermission].
+var errReadOnly = fmt.Errorf("%w: %w", billy.ErrReadOnly, iofs.ErrPermission)
+
+// Fs is a read-only [billy.Filesystem] backed by a [sevenzip.Reader]. Names
+// may be absolute or relative and are resolved from the root of the archive.
+// Any method that would modify the filesystem fails with an error wrapping
+// both [billy.ErrReadOnly] and [fs.ErrPermission]. Files are read in the same
+// way as with [aferofs.Fs].
+type Fs struct {
+	fs *aferofs.Fs
+}
+
+var (
+	_ billy.Filesystem = (*Fs)(nil)
+	_ billy.Capable    = (*Fs)(nil)
+)
+
+// New returns a new [*Fs] serving the files of r.
+func New(r *sevenzip.Reader) *Fs {
+	return &Fs{fs: aferofs.New(r)}
+}
+
+func denied(op, name string) error {
+	return &iofs.PathError{Op: op, Path: name, Err: errReadOnly}
+}
+
+// Capabilities reports that files can only be read and seeked.
+func (fs *Fs) Capabilities() billy.Capability {
+	return billy.ReadCapability | billy.SeekCapability
+}
+
+// Create always fails as the filesystem is read-only.
+func (fs *Fs) Create(filename string) (billy.File, error) {
+	return nil, denied("create", filename)
+}
+
+// Open opens the named file for reading. Seeking backwards or reading at an
+// offset before the current position decodes the file again from the start.
+func (fs *Fs) Open(filename string) (billy.File, error) {
+	f, err := fs.fs.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("billyfs: %w", err)
+	}
+
+	return &file{f}, nil
+}
+
+// OpenFile opens the named file, which fails unless flag only requests
+// reading.
+func (fs *Fs) OpenFile(filename string, flag int, _ os.FileMode) (billy.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0 {
+		return nil, denied("open", filename)
+	}
+
+	return fs.Open(filename)
+}
+
+// Stat returns the [fs.FileInfo] describing the named file.
+func (fs *Fs) Stat(filename string) (os.FileInfo, error) {
+	info, err := fs.fs.Stat(filename)
+	if err != nil {
+		return nil, fmt.Errorf("billyfs: %w", err)
+	}
+
+	return info, nil
+}
+
+// Rename always fails as the filesystem is read-only.
+func (fs *Fs) Rename(oldpath, _ string) error { return denied("rename", oldpath) }
+
+// Remove always fails as the filesystem is read-only.
+func (fs *Fs) Remove(filename string) error { return denied("remove", filename) }
+
+// Join joins any number of path elements into a single path.
+func (fs *Fs) Join(elem ...string) string { return filepath.Join(elem...) }
+
+// TempFile always fails as the filesystem is read-only.
+func (fs *Fs) TempFile(dir, _ string) (billy.File, error) { return nil, denied("tempfile", dir) }
+
+// ReadDir returns the entries of the named directory, sorted by name.
+func (fs *Fs) ReadDir(path string) ([]os.FileInfo, error) {
+	infos, err := afero.ReadDir(fs.fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("billyfs: %w", err)
+	}
+
+	return infos, nil
+}
+
+// MkdirAll always fails as the filesystem is read-only.
+func (fs *Fs) MkdirAll(filename string, _ os.FileMode) error { return denied("mkdir", filename) }
+
+// Lstat is the same as Stat as archives are read without any symbolic
+// links.
+func (fs *Fs) Lstat(filename string) (os.FileInfo, error) { return fs.Stat(filename) }
+
+// Symlink always fails as the filesystem is read-only.
+func (fs *Fs) Symlink(_, link string) error { return denied("symlink", link) }
+
+// Readlink always fails as no file is a symbolic link.
+func (fs *Fs) Readlink(link string) (string, error) {
+	if _, err := fs.Stat(link); err != nil {
+		return "", err
+	}
+
+	return "", &iofs.PathError{Op: "readlink", Path: link, Err: iofs.ErrInvalid}
+}
+
+// Chroot returns a [billy.Filesystem] serving the files under path.
+func (fs *Fs) Chroot(path string) (billy.Filesystem, error) {
+	return chroot.New(fs, fs.Join(fs.Root(), path)), nil
+}
+
+// Root returns the root of the filesystem.
+func (fs *Fs) Root() string { return string(filepath.Separator) }
+
+// file is a [billy.File] wrapping the read-only [afero.File] of the archive.
+type file struct {
+	afero.File
+}
+
+func (f *file) Lock() error { return nil }
+
+func (f *file) Unlock() error { return nil }
+
+func (f *file) Write([]byte) (int, error) { return 0, denied("write", f.Name()) }
+
+func (f *file) Truncate(int64) error { return denied("truncate", f.Name()) }
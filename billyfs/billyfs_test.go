@@ -0,0 +1,148 @@
+package billyfs_test
+
+import (
+	"io"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/javi11/sevenzip"
+	"github.com/javi11/sevenzip/billyfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openFs(t *testing.T) (*sevenzip.ReadCloser, *billyfs.Fs) {
+	t.Helper()
+
+	r, err := sevenzip.OpenReader(filepath.Join("..", "testdata", "lzma1900.7z"))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, r.Close())
+	})
+
+	return r, billyfs.New(&r.Reader)
+}
+
+func readFile(t *testing.T, f *sevenzip.File) []byte {
+	t.Helper()
+
+	rc, err := f.Open()
+	require.NoError(t, err)
+
+	b, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+
+	return b
+}
+
+func TestWalk(t *testing.T) {
+	t.Parallel()
+
+	r, fs := openFs(t)
+
+	var files int
+
+	err := util.Walk(fs, "/", func(name string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		files++
+
+		// Reading every file decodes the solid blocks repeatedly
+		if filepath.Dir(name) != filepath.FromSlash("/Asm/x86") {
+			return nil
+		}
+
+		f, ok := r.FileByName(filepath.ToSlash(name[1:]))
+		require.True(t, ok, name)
+
+		b, err := util.ReadFile(fs, name)
+		require.NoError(t, err)
+		assert.Equal(t, readFile(t, f), b, name)
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	var want int
+
+	for _, f := range r.File {
+		if !f.FileInfo().IsDir() {
+			want++
+		}
+	}
+
+	assert.Equal(t, want, files)
+}
+
+func TestChroot(t *testing.T) {
+	t.Parallel()
+
+	r, fs := openFs(t)
+
+	zf, ok := r.FileByName("Asm/x86/7zCrcOpt.asm")
+	require.True(t, ok)
+
+	sub, err := fs.Chroot("Asm")
+	require.NoError(t, err)
+
+	b, err := util.ReadFile(sub, "x86/7zCrcOpt.asm")
+	require.NoError(t, err)
+	assert.Equal(t, readFile(t, zf), b)
+
+	infos, err := sub.ReadDir("/")
+	require.NoError(t, err)
+	require.NotEmpty(t, infos)
+
+	for i := 1; i < len(infos); i++ {
+		assert.Less(t, infos[i-1].Name(), infos[i].Name())
+	}
+
+	_, err = sub.Open("../C")
+	assert.ErrorIs(t, err, billy.ErrCrossedBoundary)
+}
+
+func TestReadOnly(t *testing.T) {
+	t.Parallel()
+
+	_, fs := openFs(t)
+
+	assert.False(t, billy.CapabilityCheck(fs, billy.WriteCapability))
+	assert.True(t, billy.CapabilityCheck(fs, billy.ReadCapability|billy.SeekCapability))
+
+	_, err := fs.Create("new")
+	assert.ErrorIs(t, err, billy.ErrReadOnly)
+	assert.ErrorIs(t, err, iofs.ErrPermission)
+
+	assert.ErrorIs(t, fs.Remove("Asm"), billy.ErrReadOnly)
+	assert.ErrorIs(t, fs.MkdirAll("new", 0o755), billy.ErrReadOnly)
+	assert.ErrorIs(t, fs.Symlink("Asm", "link"), billy.ErrReadOnly)
+
+	_, err = fs.OpenFile("Asm/x86/7zCrcOpt.asm", os.O_RDWR, 0)
+	assert.ErrorIs(t, err, billy.ErrReadOnly)
+
+	f, err := fs.OpenFile("Asm/x86/7zCrcOpt.asm", os.O_RDONLY, 0)
+	require.NoError(t, err)
+
+	_, err = f.Write([]byte("x"))
+	assert.ErrorIs(t, err, billy.ErrReadOnly)
+	assert.ErrorIs(t, f.Truncate(0), billy.ErrReadOnly)
+	require.NoError(t, f.Close())
+
+	_, err = fs.Stat("/missing")
+	assert.ErrorIs(t, err, iofs.ErrNotExist)
+
+	_, err = fs.Readlink("Asm/x86/7zCrcOpt.asm")
+	assert.ErrorIs(t, err, iofs.ErrInvalid)
+
+	info, err := fs.Lstat("/")
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+}
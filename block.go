@@ -0,0 +1,299 @@
+package sevenzip
+
+import (
+	"encoding/hex"
+	"math/bits"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+//nolint:gochecknoglobals
+var methodNames = map[string]string{
+	"\x00":             "Copy",
+	"\x03":             "Delta",
+	"\x21":             "LZMA2",
+	"\x03\x01\x01":     "LZMA",
+	"\x03\x03\x01\x03": "BCJ",
+	"\x03\x03\x01\x1b": "BCJ2",
+	"\x03\x03\x02\x05": "PPC",
+	"\x03\x03\x04\x01": "IA64",
+	"\x03\x03\x05\x01": "ARM",
+	"\x03\x03\x07\x01": "ARMT",
+	"\x03\x03\x08\x05": "SPARC",
+	"\x0a":             "ARM64",
+	"\x0b":             "RISCV",
+	"\x03\x04\x01":     "PPMD",
+	"\x04\x01\x08":     "Deflate",
+	"\x04\x01\x09":     "Deflate64",
+	"\x04\x02\x02":     "BZip2",
+	"\x04\xf7\x11\x01": "ZSTD",
+	"\x04\xf7\x11\x02": "Brotli",
+	"\x04\xf7\x11\x04": "LZ4",
+	"\x04\xf7\x11\x05": "LZS",
+	"\x04\xf7\x11\x06": "Lizard",
+	"\x06\xf1\x07\x01": "7zAES",
+}
+
+// A Coder describes one of the methods used to decode a [Block].
+type Coder struct {
+	// ID is the method ID as registered with [RegisterDecompressor].
+	ID []byte
+	// Properties are the method specific properties, such as the
+	// dictionary size.
+	Properties []byte
+	// NumInStreams and NumOutStreams are the number of streams the method
+	// reads and writes, these are only greater than one for methods such
+	// as BCJ2.
+	NumInStreams  int
+	NumOutStreams int
+}
+
+// Name returns the name of the method as used by 7-zip, such as "LZMA2", or
+// the hex encoded method ID if it isn't known.
+func (c Coder) Name() string {
+	if name, ok := methodNames[string(c.ID)]; ok {
+		return name
+	}
+
+	return hex.EncodeToString(c.ID)
+}
+
+// sizeString formats a dictionary size the same as 7-zip, as a power of two
+// if possible, otherwise with a unit suffix.
+func sizeString(size uint64) string {
+	if size != 0 && size&(size-1) == 0 {
+		return strconv.Itoa(bits.TrailingZeros64(size))
+	}
+
+	suffix := "b"
+
+	for _, unit := range []string{"k", "m", "g"} {
+		if size == 0 || size&0x3ff != 0 {
+			break
+		}
+
+		size >>= 10
+		suffix = unit
+	}
+
+	return strconv.FormatUint(size, 10) + suffix
+}
+
+// String returns the name of the method followed by any notable parameters,
+// formatted the same as the Method column of `7z l -slt`, such as
+// "LZMA2:24" or "7zAES:19".
+//
+//nolint:cyclop,mnd
+func (c Coder) String() string {
+	name := c.Name()
+	p := c.Properties
+
+	switch name {
+	case "LZMA":
+		if len(p) < 5 {
+			break
+		}
+
+		dict := uint64(p[1]) | uint64(p[2])<<8 | uint64(p[3])<<16 | uint64(p[4])<<24
+		name += ":" + sizeString(dict)
+
+		lc, lp, pb := p[0]%9, p[0]/9%5, p[0]/45
+		if lc != 3 {
+			name += ":lc" + strconv.Itoa(int(lc))
+		}
+
+		if lp != 0 {
+			name += ":lp" + strconv.Itoa(int(lp))
+		}
+
+		if pb != 2 {
+			name += ":pb" + strconv.Itoa(int(pb))
+		}
+	case "LZMA2":
+		if len(p) < 1 {
+			break
+		}
+
+		if p[0] >= 40 {
+			name += ":4g"
+		} else {
+			name += ":" + sizeString(uint64(2|(p[0]&1))<<(p[0]/2+11))
+		}
+	case "PPMD":
+		if len(p) < 5 {
+			break
+		}
+
+		mem := uint64(p[1]) | uint64(p[2])<<8 | uint64(p[3])<<16 | uint64(p[4])<<24
+		name += ":o" + strconv.Itoa(int(p[0])) + ":mem" + sizeString(mem)
+	case "Delta":
+		if len(p) < 1 {
+			break
+		}
+
+		name += ":" + strconv.Itoa(int(p[0])+1)
+	case "7zAES":
+		if len(p) < 1 {
+			break
+		}
+
+		name += ":" + strconv.Itoa(int(p[0]&0x3f))
+	}
+
+	return name
+}
+
+// A Block is a solid block, or folder in 7-zip terminology, that is decoded
+// as a single stream containing one or more files.
+type Block struct {
+	// Coders are the methods used to decode the block, in the order they
+	// are listed in the header, which is normally the order they are
+	// applied to the packed streams.
+	Coders []Coder
+	// PackedSize is the total size of the packed streams in the archive.
+	PackedSize uint64
+	// UnpackedSize is the size of the decoded stream.
+	UnpackedSize uint64
+	// NumFiles is the number of files stored in the block.
+	NumFiles int
+	// BindPairs join the output streams of some coders to the input
+	// streams of others.
+	BindPairs []BindPair
+	// PackedStreams are the streams stored in the archive, each read by
+	// one of the input streams of the coders.
+	PackedStreams []PackedStream
+	// UnpackSizes are the sizes of every output stream of the coders.
+	UnpackSizes []uint64
+}
+
+// A BindPair joins an output stream of one coder in a [Block] to an input
+// stream of another. Streams are numbered across all of the coders in the
+// block in order, so a block of two coders with one input and output stream
+// each has input streams 0 and 1 and output streams 0 and 1.
+type BindPair struct {
+	InIndex  int
+	OutIndex int
+}
+
+// A PackedStream locates one of the streams of a [Block] in the archive.
+type PackedStream struct {
+	// InIndex is the input stream of the coders that reads the stream.
+	InIndex int
+	// Offset is the offset of the stream in the archive, including any
+	// base offset, so it can be read directly from the [io.ReaderAt]
+	// passed to [NewReader].
+	Offset int64
+	// Size is the size of the stream.
+	Size uint64
+}
+
+// Method returns the chain of methods used by the block formatted the same as
+// the Method column of `7z l -slt`, which lists the method producing the
+// final output first, such as "BCJ LZMA2:24" or "LZMA2:24 7zAES:19".
+func (b Block) Method() string {
+	methods := make([]string, 0, len(b.Coders))
+
+	for i := len(b.Coders) - 1; i >= 0; i-- {
+		methods = append(methods, b.Coders[i].String())
+	}
+
+	return strings.Join(methods, " ")
+}
+
+// Encrypted returns true if any of the methods used by the block require a
+// password.
+func (b Block) Encrypted() bool {
+	return slices.ContainsFunc(b.Coders, func(c Coder) bool {
+		return slices.Equal(c.ID, methodAES)
+	})
+}
+
+func (f *folder) coders() []Coder {
+	coders := make([]Coder, len(f.coder))
+
+	for i, c := range f.coder {
+		coders[i] = Coder{
+			ID:            slices.Clone(c.id),
+			Properties:    slices.Clone(c.properties),
+			NumInStreams:  int(c.in),  //nolint:gosec
+			NumOutStreams: int(c.out), //nolint:gosec
+		}
+	}
+
+	return coders
+}
+
+func (f *folder) bindPairs() []BindPair {
+	pairs := make([]BindPair, len(f.bindPair))
+
+	for i, bp := range f.bindPair {
+		pairs[i] = BindPair{
+			InIndex:  int(bp.in),  //nolint:gosec
+			OutIndex: int(bp.out), //nolint:gosec
+		}
+	}
+
+	return pairs
+}
+
+// Blocks returns the solid blocks in the archive. The index of each block
+// matches the Stream field of the files stored in it.
+func (z *Reader) Blocks() []Block {
+	return z.blocks(z.si)
+}
+
+// blocks returns the blocks described by si.
+func (z *Reader) blocks(si *streamsInfo) []Block {
+	blocks := make([]Block, si.Folders())
+	_, packedSizes := si.folderOffsets()
+
+	for i := range blocks {
+		f := si.unpackInfo.folder[i]
+
+		blocks[i] = Block{
+			Coders:       f.coders(),
+			UnpackedSize: f.unpackSize(),
+			NumFiles:     1,
+		}
+
+		if si.subStreamsInfo != nil {
+			blocks[i].NumFiles = int(si.subStreamsInfo.streams[i]) //nolint:gosec
+		}
+
+		blocks[i].PackedSize = packedSizes[i]
+		blocks[i].BindPairs, blocks[i].UnpackSizes = f.bindPairs(), slices.Clone(f.size)
+
+		if si.packInfo == nil {
+			continue
+		}
+
+		k := si.packedIndex(i)
+
+		for j, in := range f.packed {
+			if k+j >= len(si.packInfo.size) {
+				break
+			}
+
+			blocks[i].PackedStreams = append(blocks[i].PackedStreams, PackedStream{
+				InIndex: int(in), //nolint:gosec
+				Offset:  z.start + si.packedOffset(k+j),
+				Size:    si.packInfo.size[k+j],
+			})
+		}
+	}
+
+	return blocks
+}
+
+// Coders returns the methods used to decode the block containing the file,
+// in the same order as [Block.Coders]. It returns nil for
+// directories, empty files and files whose contents are missing as they
+// aren't stored in a block.
+func (f *File) Coders() []Coder {
+	if !f.HasStream() {
+		return nil
+	}
+
+	return f.zip.si.unpackInfo.folder[f.folder].coders()
+}
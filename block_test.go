@@ -0,0 +1,162 @@
+package sevenzip_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlocks(t *testing.T) {
+	t.Parallel()
+
+	tables := []struct {
+		name, file string
+		methods    []string
+		encrypted  bool
+	}{
+		{
+			name:    "bcj",
+			file:    "bcj.7z",
+			methods: []string{"BCJ LZMA2:12k"},
+		},
+		{
+			name:    "delta",
+			file:    "delta.7z",
+			methods: []string{"Delta:1"},
+		},
+		{
+			name:    "lzma1900",
+			file:    "lzma1900.7z",
+			methods: []string{"LZMA:22:pb0", "LZMA:96k:pb0", "BCJ2 LZMA:21 LZMA:20:lc0:lp2 LZMA:20:lc0:lp2"},
+		},
+		{
+			name:      "encrypted",
+			file:      "t4.7z",
+			methods:   []string{"LZMA2:12 7zAES:19"},
+			encrypted: true,
+		},
+		{
+			name: "empty",
+			file: "empty.7z",
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := sevenzip.OpenReaderWithPassword(filepath.Join("testdata", table.file), "password")
+			require.NoError(t, err)
+
+			defer func() {
+				require.NoError(t, r.Close())
+			}()
+
+			blocks := r.Blocks()
+
+			methods := make([]string, 0, len(blocks))
+			files := 0
+
+			for _, b := range blocks {
+				methods = append(methods, b.Method())
+				files += b.NumFiles
+
+				assert.Equal(t, table.encrypted, b.Encrypted())
+				assert.Positive(t, b.PackedSize)
+
+				var in, out int
+				for _, c := range b.Coders {
+					in += c.NumInStreams
+					out += c.NumOutStreams
+				}
+
+				// Every stream is either bound or packed, apart from
+				// the single final output stream
+				assert.Len(t, b.BindPairs, out-1)
+				assert.Len(t, b.PackedStreams, in-len(b.BindPairs))
+				assert.Len(t, b.UnpackSizes, out)
+
+				var size uint64
+				for _, ps := range b.PackedStreams {
+					size += ps.Size
+				}
+
+				assert.Equal(t, b.PackedSize, size)
+			}
+
+			assert.Equal(t, len(table.methods), len(methods))
+
+			if len(table.methods) > 0 {
+				assert.Equal(t, table.methods, methods)
+			}
+
+			stored := 0
+
+			for _, f := range r.File {
+				coders := f.Coders()
+				if coders == nil {
+					continue
+				}
+
+				stored++
+
+				assert.Equal(t, blocks[f.Stream].Coders, coders)
+			}
+
+			assert.Equal(t, stored, files)
+		})
+	}
+}
+
+func TestCoderName(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "ZSTD", sevenzip.Coder{ID: []byte{0x04, 0xf7, 0x11, 0x01}}.String())
+	assert.Equal(t, "LZMA2:4g", sevenzip.Coder{ID: []byte{0x21}, Properties: []byte{40}}.String())
+	assert.Equal(t, "LZMA2", sevenzip.Coder{ID: []byte{0x21}}.String())
+	assert.Equal(t, "ff00", sevenzip.Coder{ID: []byte{0xff, 0x00}}.String())
+}
+
+func TestBlockPackedStreams(t *testing.T) {
+	t.Parallel()
+
+	name := filepath.Join("testdata", "copy.7z")
+
+	b, err := os.ReadFile(name)
+	require.NoError(t, err)
+
+	r, err := sevenzip.OpenReader(name)
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, r.Close())
+	}()
+
+	blocks := r.Blocks()
+
+	for _, f := range r.File {
+		block := blocks[f.Stream]
+
+		require.Len(t, block.PackedStreams, 1)
+		assert.Empty(t, block.BindPairs)
+		assert.Equal(t, []uint64{f.UncompressedSize}, block.UnpackSizes)
+
+		ps := block.PackedStreams[0]
+		assert.Equal(t, 0, ps.InIndex)
+
+		rc, err := f.Open()
+		require.NoError(t, err)
+
+		contents, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+
+		// Copied streams are stored as they are
+		assert.Equal(t, contents, b[ps.Offset:ps.Offset+int64(ps.Size)], f.Name)
+	}
+}
@@ -0,0 +1,268 @@
+package sevenzip
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+type bundleOptions struct {
+	hashNames  bool
+	readerOpts []ReaderOption
+}
+
+// A BundleOption configures a call to [WriteDiagnosticBundle].
+type BundleOption func(*bundleOptions)
+
+// WithHashedNames makes [WriteDiagnosticBundle] replace every file name with
+// a keyed hash of it. Each part of a path is replaced with the same number of
+// characters, so the header keeps its layout, with any separators and
+// extension left as they are. The key is random and not kept, so the same
+// name is replaced the same way within a bundle but the names can't be
+// recovered from it.
+func WithHashedNames() BundleOption {
+	return func(o *bundleOptions) {
+		o.hashNames = true
+	}
+}
+
+// WithBundleReaderOptions sets the options used to read the archive, such as
+// [WithPassword] for an encrypted header or [WithBaseOffset].
+func WithBundleReaderOptions(opts ...ReaderOption) BundleOption {
+	return func(o *bundleOptions) {
+		o.readerOpts = append(o.readerOpts, opts...)
+	}
+}
+
+// WriteDiagnosticBundle writes a small archive to w holding just enough of
+// the archive in r to look into a problem with it, without any of the
+// contents of its files, for attaching to a bug report. It contains:
+//
+//   - report.txt, with the size and layout of the archive, the output of
+//     [DumpHeader], and the result of opening it along with any warnings
+//   - header.7z, the header on its own, after decoding any encoded headers,
+//     behind a new signature header. It can be examined with [DumpHeader]
+//     but not opened, as none of the packed streams are included
+//
+// With [WithHashedNames] every file name in both is hashed. If the header
+// couldn't be read in full then header.7z is left out, as some of the names
+// in it might not have been found.
+func WriteDiagnosticBundle(r io.ReaderAt, size int64, w io.Writer, opts ...BundleOption) error {
+	o := new(bundleOptions)
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	red := &redactor{names: make(map[string]string)}
+	if o.hashNames {
+		red.key = make([]byte, sha256.Size)
+		_, _ = rand.Read(red.key)
+	}
+
+	report := new(bytes.Buffer)
+	fmt.Fprintf(report, "Size: %d\n\n", size)
+
+	z := &Reader{opts: newReaderOptions(o.readerOpts), r: r, size: size, done: make(chan struct{})}
+	defer close(z.done)
+
+	d := &dumper{w: report, z: z, redact: red}
+
+	dumpErr := d.archive()
+	if dumpErr != nil {
+		d.printf("error: %v", dumpErr)
+	}
+
+	red.open(report, r, size, o.readerOpts)
+
+	zw := NewWriter(w, WithReproducible(time.Time{}))
+
+	if red.header != nil && (dumpErr == nil || !o.hashNames) {
+		if err := red.writeStub(zw, d.z.version); err != nil {
+			return fmt.Errorf("sevenzip: error writing bundle: %w", err)
+		}
+	}
+
+	f, err := zw.Create("report.txt")
+	if err != nil {
+		return fmt.Errorf("sevenzip: error writing bundle: %w", err)
+	}
+
+	if _, err := f.Write(report.Bytes()); err != nil {
+		return fmt.Errorf("sevenzip: error writing bundle: %w", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("sevenzip: error writing bundle: %w", err)
+	}
+
+	return nil
+}
+
+// redactor collects the header reached while dumping an archive for a
+// diagnostic bundle, and hashes the names in it if it has a key.
+type redactor struct {
+	key    []byte
+	header []byte
+	names  map[string]string
+}
+
+// name returns the replacement for the name u, or u itself if names aren't
+// being hashed.
+func (r *redactor) name(u []uint16) []uint16 {
+	if r.key == nil {
+		return u
+	}
+
+	h := make([]uint16, 0, len(u))
+	part := 0
+
+	for i := 0; i <= len(u); i++ {
+		if i < len(u) && u[i] != '/' && u[i] != '\\' {
+			continue
+		}
+
+		stem, ext := u[part:i], []uint16(nil)
+
+		// Keep the extension, unless it's all there is
+		for dot := len(stem) - 1; dot > 0; dot-- {
+			if stem[dot] == '.' {
+				stem, ext = stem[:dot], stem[dot:]
+
+				break
+			}
+		}
+
+		h = append(h, r.hash(stem)...)
+		h = append(h, ext...)
+
+		if i < len(u) {
+			h = append(h, u[i])
+		}
+
+		part = i + 1
+	}
+
+	r.names[string(utf16.Decode(u))] = string(utf16.Decode(h))
+
+	return h
+}
+
+// hash returns as many hex digits of the keyed hash of u as it's long.
+func (r *redactor) hash(u []uint16) []uint16 {
+	b := make([]byte, 2*len(u))
+	for i, c := range u {
+		binary.LittleEndian.PutUint16(b[2*i:], c)
+	}
+
+	var digits string
+
+	for block := byte(0); len(digits) < len(u); block++ {
+		mac := hmac.New(sha256.New, r.key)
+		_, _ = mac.Write([]byte{block})
+		_, _ = mac.Write(b)
+		digits += hex.EncodeToString(mac.Sum(nil))
+	}
+
+	return utf16.Encode([]rune(digits[:len(u)]))
+}
+
+// redact replaces any names in s with their hashes, longest first so a name
+// that's part of another isn't replaced by mistake.
+func (r *redactor) redact(s string) string {
+	if len(r.names) == 0 {
+		return s
+	}
+
+	names := make([]string, 0, len(r.names))
+	for name := range r.names {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+
+	slices.SortFunc(names, func(a, b string) int {
+		return cmp.Or(cmp.Compare(len(b), len(a)), strings.Compare(a, b))
+	})
+
+	pairs := make([]string, 0, 2*len(names))
+	for _, name := range names {
+		pairs = append(pairs, name, r.names[name])
+	}
+
+	return strings.NewReplacer(pairs...).Replace(s)
+}
+
+// open writes the result of opening the archive to report, along with any
+// warnings.
+func (r *redactor) open(report io.Writer, ra io.ReaderAt, size int64, opts []ReaderOption) {
+	fmt.Fprintln(report)
+
+	z, err := NewReaderWithOptions(ra, size, opts...)
+	if err != nil {
+		fmt.Fprintf(report, "Open: %s\n", r.redact(err.Error()))
+
+		return
+	}
+	defer func() { _ = z.Close() }()
+
+	fmt.Fprintf(report, "Open: ok, %d files\n", len(z.File))
+
+	if len(z.warnings) == 0 {
+		fmt.Fprintln(report, "Warnings: none")
+
+		return
+	}
+
+	fmt.Fprintln(report, "Warnings:")
+
+	for _, w := range z.warnings {
+		fmt.Fprintf(report, "  %s\n", r.redact(w))
+	}
+}
+
+// writeStub adds the header to w as header.7z, behind a signature header
+// with the given version that points straight at it.
+func (r *redactor) writeStub(w *Writer, version [2]byte) error {
+	start := startHeader{
+		Size: uint64(len(r.header)),
+		CRC:  crc32.ChecksumIEEE(r.header),
+	}
+
+	sb := new(bytes.Buffer)
+	_ = binary.Write(sb, binary.LittleEndian, start)
+
+	sh := signatureHeader{
+		Signature: [6]byte{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c},
+		Major:     version[0],
+		Minor:     version[1],
+		CRC:       crc32.ChecksumIEEE(sb.Bytes()),
+	}
+
+	f, err := w.Create("header.7z")
+	if err != nil {
+		return err
+	}
+
+	if err := binary.Write(f, binary.LittleEndian, sh); err != nil {
+		return err
+	}
+
+	if _, err := f.Write(sb.Bytes()); err != nil {
+		return err
+	}
+
+	_, err = f.Write(r.header)
+
+	return err
+}
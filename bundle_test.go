@@ -0,0 +1,193 @@
+package sevenzip_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readBundle(t *testing.T, b []byte) map[string][]byte {
+	t.Helper()
+
+	r, err := sevenzip.NewReader(bytes.NewReader(b), int64(len(b)))
+	require.NoError(t, err)
+
+	files := make(map[string][]byte)
+
+	for _, f := range r.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+
+		files[f.Name], err = io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+	}
+
+	return files
+}
+
+func utf16LE(s string) []byte {
+	var b []byte
+	for _, c := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(c), byte(c>>8))
+	}
+
+	return b
+}
+
+// secretArchive returns an archive with names long enough that none of them
+// could turn up by chance once hashed.
+func secretArchive(t *testing.T) []byte {
+	t.Helper()
+
+	b := new(bytes.Buffer)
+	w := sevenzip.NewWriter(b)
+
+	for _, name := range []string{"confidential/quarterly report.docx", "confidential/passwords.txt", "confidential/.hidden"} {
+		f, err := w.Create(name)
+		require.NoError(t, err)
+
+		_, err = f.Write([]byte(name))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, w.Close())
+
+	return b.Bytes()
+}
+
+//nolint:cyclop
+func TestWriteDiagnosticBundle(t *testing.T) {
+	t.Parallel()
+
+	tables := []struct {
+		name   string
+		file   string
+		opts   []sevenzip.BundleOption
+		hashed bool
+	}{
+		{
+			name: "plain header",
+			file: "sfx.exe",
+		},
+		{
+			name: "encoded header",
+			file: "copy.7z",
+		},
+		{
+			name:   "hashed names",
+			opts:   []sevenzip.BundleOption{sevenzip.WithHashedNames()},
+			hashed: true,
+		},
+		{
+			name: "encrypted header",
+			file: "t2.7z",
+			opts: []sevenzip.BundleOption{
+				sevenzip.WithHashedNames(),
+				sevenzip.WithBundleReaderOptions(sevenzip.WithPassword("password")),
+			},
+			hashed: true,
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			var b []byte
+			if table.file != "" {
+				var err error
+				b, err = os.ReadFile(filepath.Join("testdata", table.file))
+				require.NoError(t, err)
+			} else {
+				b = secretArchive(t)
+			}
+
+			out := new(bytes.Buffer)
+			require.NoError(t, sevenzip.WriteDiagnosticBundle(bytes.NewReader(b), int64(len(b)), out, table.opts...))
+
+			files := readBundle(t, out.Bytes())
+			require.Contains(t, files, "report.txt")
+			require.Contains(t, files, "header.7z")
+
+			report := string(files["report.txt"])
+			assert.Contains(t, report, "Size: "+strconv.Itoa(len(b)))
+			assert.Contains(t, report, "0x01 Header")
+			assert.Contains(t, report, "Open: ok")
+
+			header := files["header.7z"]
+
+			dump := new(bytes.Buffer)
+			require.NoError(t, sevenzip.DumpHeader(bytes.NewReader(header), int64(len(header)), dump))
+			assert.Contains(t, dump.String(), "0x01 Header")
+			assert.NotContains(t, dump.String(), "EncodedHeader")
+
+			var opts []sevenzip.ReaderOption
+			if table.file == "t2.7z" {
+				opts = append(opts, sevenzip.WithPassword("password"))
+			}
+
+			r, err := sevenzip.NewReaderWithOptions(bytes.NewReader(b), int64(len(b)), opts...)
+			require.NoError(t, err)
+
+			for _, f := range r.File {
+				if !table.hashed {
+					assert.Contains(t, report, strconv.Quote(f.Name))
+					assert.Contains(t, dump.String(), strconv.Quote(f.Name))
+
+					continue
+				}
+
+				assert.NotContains(t, report, strconv.Quote(f.Name))
+				assert.NotContains(t, dump.String(), strconv.Quote(f.Name))
+				assert.False(t, bytes.Contains(header, utf16LE(f.Name)), f.Name)
+
+				// Extensions are kept, but not a name that's all extension
+				if ext := path.Ext(f.Name); ext != "" && ext != path.Base(f.Name) {
+					assert.Contains(t, dump.String(), ext+`"`)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteDiagnosticBundleErrors(t *testing.T) {
+	t.Parallel()
+
+	b, err := os.ReadFile(filepath.Join("testdata", "t2.7z"))
+	require.NoError(t, err)
+
+	t.Run("no password", func(t *testing.T) {
+		t.Parallel()
+
+		out := new(bytes.Buffer)
+		require.NoError(t, sevenzip.WriteDiagnosticBundle(bytes.NewReader(b), int64(len(b)), out, sevenzip.WithHashedNames()))
+
+		files := readBundle(t, out.Bytes())
+		require.Contains(t, files, "report.txt")
+		assert.NotContains(t, files, "header.7z")
+		assert.Contains(t, string(files["report.txt"]), sevenzip.ErrPasswordRequired.Error())
+	})
+
+	t.Run("not an archive", func(t *testing.T) {
+		t.Parallel()
+
+		b := []byte("not an archive")
+
+		out := new(bytes.Buffer)
+		require.NoError(t, sevenzip.WriteDiagnosticBundle(bytes.NewReader(b), int64(len(b)), out))
+
+		files := readBundle(t, out.Bytes())
+		assert.NotContains(t, files, "header.7z")
+		assert.Contains(t, string(files["report.txt"]), "error: ")
+	})
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/javi11/sevenzip"
+	_ "modernc.org/sqlite"
+)
+
+type entry struct {
+	Name     string    `json:"name"`
+	Size     uint64    `json:"size"`
+	CRC32    uint32    `json:"crc32,omitempty"`
+	Modified time.Time `json:"modified"`
+	Block    *int      `json:"block,omitempty"`
+}
+
+// An archiveRecord is one line of the JSON catalog. Archives that couldn't
+// be opened are recorded with only the path, modification time and error.
+type archiveRecord struct {
+	Path         string    `json:"path"`
+	Modified     time.Time `json:"modified"`
+	Size         int64     `json:"size,omitempty"`
+	Volumes      int       `json:"volumes,omitempty"`
+	Files        int       `json:"files"`
+	Dirs         int       `json:"dirs"`
+	Blocks       int       `json:"blocks"`
+	UnpackedSize uint64    `json:"unpacked_size"`
+	PackedSize   uint64    `json:"packed_size"`
+	Encrypted    bool      `json:"encrypted"`
+	Methods      []string  `json:"methods,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	Entries      []entry   `json:"entries,omitempty"`
+}
+
+func newArchiveRecord(path string, modTime time.Time, rc *sevenzip.ReadCloser) *archiveRecord {
+	blocks := rc.Blocks()
+
+	rec := &archiveRecord{
+		Path:     path,
+		Modified: modTime,
+		Blocks:   len(blocks),
+		Entries:  make([]entry, 0, len(rc.File)),
+	}
+
+	for _, volume := range rc.Volumes() {
+		if info, err := os.Stat(volume); err == nil {
+			rec.Size += info.Size()
+			rec.Volumes++
+		}
+	}
+
+	for _, b := range blocks {
+		rec.PackedSize += b.PackedSize
+		rec.Encrypted = rec.Encrypted || b.Encrypted()
+
+		if method := b.Method(); !slices.Contains(rec.Methods, method) {
+			rec.Methods = append(rec.Methods, method)
+		}
+	}
+
+	for _, f := range rc.File {
+		crc, _ := f.Digest()
+
+		e := entry{
+			Name:     f.Name,
+			Size:     f.UncompressedSize,
+			CRC32:    crc,
+			Modified: f.Modified,
+		}
+
+		if f.FileInfo().IsDir() {
+			rec.Dirs++
+		} else {
+			rec.Files++
+			rec.UnpackedSize += f.UncompressedSize
+		}
+
+		if f.Coders() != nil {
+			e.Block = &f.Stream
+		}
+
+		rec.Entries = append(rec.Entries, e)
+	}
+
+	return rec
+}
+
+// A catalog records archives, reader is nil if the archive couldn't be
+// opened.
+type catalog interface {
+	indexed(path string, modTime time.Time) bool
+	write(rec *archiveRecord, reader *sevenzip.ReadCloser) error
+	Close() error
+}
+
+// jsonCatalog appends one JSON record per line. Archives recorded without
+// an error are remembered so they are skipped when indexing is resumed.
+type jsonCatalog struct {
+	f       *os.File
+	enc     *json.Encoder
+	entries bool
+	seen    map[string]time.Time
+}
+
+func openJSONCatalog(path string, entries bool) (*jsonCatalog, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644) //nolint:gosec,mnd
+	if err != nil {
+		return nil, err
+	}
+
+	c := &jsonCatalog{
+		f:       f,
+		enc:     json.NewEncoder(f),
+		entries: entries,
+		seen:    make(map[string]time.Time),
+	}
+
+	dec := json.NewDecoder(f)
+
+	var end int64
+
+	for {
+		var rec struct {
+			Path     string    `json:"path"`
+			Modified time.Time `json:"modified"`
+			Error    string    `json:"error"`
+		}
+
+		if err := dec.Decode(&rec); err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Printf("Discarding incomplete record at the end of %s", path)
+			}
+
+			break
+		}
+
+		end = dec.InputOffset()
+
+		if rec.Error == "" {
+			c.seen[rec.Path] = rec.Modified
+		}
+	}
+
+	// Drop anything after the last complete record, such as a line cut
+	// short by an interrupted run, and carry on from there
+	if err := f.Truncate(end); err != nil {
+		return nil, errors.Join(err, f.Close())
+	}
+
+	if _, err := f.Seek(end, io.SeekStart); err != nil {
+		return nil, errors.Join(err, f.Close())
+	}
+
+	if end > 0 {
+		if _, err := f.WriteString("\n"); err != nil {
+			return nil, errors.Join(err, f.Close())
+		}
+	}
+
+	return c, nil
+}
+
+func (c *jsonCatalog) indexed(path string, modTime time.Time) bool {
+	modified, ok := c.seen[path]
+
+	return ok && modified.Equal(modTime)
+}
+
+func (c *jsonCatalog) write(rec *archiveRecord, _ *sevenzip.ReadCloser) error {
+	if !c.entries {
+		r := *rec
+		r.Entries = nil
+		rec = &r
+	}
+
+	return c.enc.Encode(rec) //nolint:wrapcheck
+}
+
+func (c *jsonCatalog) Close() error {
+	return c.f.Close() //nolint:wrapcheck
+}
+
+// sqliteCatalog writes archives with [sevenzip.WriteIndex], archives that
+// couldn't be opened aren't recorded. The modification time of each archive
+// is recorded alongside so they are skipped when indexing is resumed.
+type sqliteCatalog struct {
+	db *sql.DB
+}
+
+const catalogSchema = `
+CREATE TABLE IF NOT EXISTS catalog (
+	path     TEXT PRIMARY KEY, -- path as passed to WriteIndex
+	modified TEXT NOT NULL     -- modification time of the archive when indexed
+);
+`
+
+func openSQLiteCatalog(path string) (*sqliteCatalog, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(sevenzip.IndexSchema + catalogSchema); err != nil {
+		return nil, errors.Join(fmt.Errorf("error creating schema: %w", err), db.Close())
+	}
+
+	return &sqliteCatalog{db: db}, nil
+}
+
+func (c *sqliteCatalog) indexed(path string, modTime time.Time) bool {
+	var modified string
+
+	// Only if the index of the archive written with it is still there
+	if err := c.db.QueryRow(
+		"SELECT catalog.modified FROM catalog JOIN archives USING (path) WHERE path = ?", path,
+	).Scan(&modified); err != nil {
+		return false
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, modified)
+
+	return err == nil && t.Equal(modTime)
+}
+
+func (c *sqliteCatalog) write(rec *archiveRecord, reader *sevenzip.ReadCloser) error {
+	if reader == nil {
+		return nil
+	}
+
+	if err := sevenzip.WriteIndex(context.Background(), c.db, rec.Path, &reader.Reader); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	_, err := c.db.Exec(
+		"INSERT OR REPLACE INTO catalog (path, modified) VALUES (?, ?)",
+		rec.Path, rec.Modified.UTC().Format(time.RFC3339Nano),
+	)
+
+	return err //nolint:wrapcheck
+}
+
+func (c *sqliteCatalog) Close() error {
+	return c.db.Close() //nolint:wrapcheck
+}
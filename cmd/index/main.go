@@ -0,0 +1,218 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	iofs "io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/javi11/sevenzip"
+	"golang.org/x/sync/errgroup"
+)
+
+func main() {
+	// Command line flags
+	var (
+		password = flag.String("p", "", "Password for encrypted archives")
+		jsonPath = flag.String("json", "", "Append a JSON Lines catalog to the file at `path`")
+		dbPath   = flag.String("sqlite", "", "Write the catalog to the SQLite database at `path`")
+		workers  = flag.Int("j", runtime.NumCPU(), "Number of archives to open in parallel")
+		entries  = flag.Bool("entries", true, "Include the files of each archive in the JSON catalog")
+		help     = flag.Bool("h", false, "Show help")
+	)
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] <directory>...\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Index every .7z and .7z.001 archive found under the directories into a catalog.\n")
+		fmt.Fprintf(os.Stderr, "Only the headers are read. Archives already in the catalog and unchanged since\n")
+		fmt.Fprintf(os.Stderr, "are skipped, so an interrupted run can be resumed by running it again.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExample:\n")
+		fmt.Fprintf(os.Stderr, "  %s -json catalog.jsonl /srv/archives\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -sqlite catalog.db -j 8 /srv/archives /mnt/backup\n", os.Args[0])
+	}
+
+	flag.Parse()
+
+	if *help || flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(0)
+	}
+
+	if *jsonPath == "" && *dbPath == "" {
+		log.Fatal("At least one of -json or -sqlite is required")
+	}
+
+	var catalogs []catalog
+
+	if *jsonPath != "" {
+		c, err := openJSONCatalog(*jsonPath, *entries)
+		if err != nil {
+			log.Fatalf("Failed to open JSON catalog: %v", err)
+		}
+
+		catalogs = append(catalogs, c)
+	}
+
+	if *dbPath != "" {
+		c, err := openSQLiteCatalog(*dbPath)
+		if err != nil {
+			log.Fatalf("Failed to open SQLite catalog: %v", err)
+		}
+
+		catalogs = append(catalogs, c)
+	}
+
+	s, err := index(flag.Args(), *password, max(*workers, 1), catalogs)
+
+	for _, c := range catalogs {
+		err = errors.Join(err, c.Close())
+	}
+
+	if err != nil {
+		log.Fatalf("Failed to index: %v", err)
+	}
+
+	fmt.Printf("Indexed %d archives (%d files), skipped %d unchanged, %d failed\n", s.indexed, s.files, s.skipped, s.failed)
+
+	if s.failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// isArchive reports whether the file is a single archive or the first volume
+// of a multi-volume archive.
+func isArchive(name string) bool {
+	name = strings.ToLower(name)
+
+	return strings.HasSuffix(name, ".7z") || strings.HasSuffix(name, ".7z.001")
+}
+
+func findArchives(roots []string) ([]string, error) {
+	var archives []string
+
+	for _, root := range roots {
+		if err := filepath.WalkDir(root, func(path string, d iofs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if d.Type().IsRegular() && isArchive(d.Name()) {
+				abs, err := filepath.Abs(path)
+				if err != nil {
+					return err
+				}
+
+				archives = append(archives, abs)
+			}
+
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("error walking %s: %w", root, err)
+		}
+	}
+
+	slices.Sort(archives)
+
+	return slices.Compact(archives), nil
+}
+
+type stats struct {
+	indexed, skipped, failed, files int
+}
+
+type result struct {
+	record *archiveRecord
+	reader *sevenzip.ReadCloser
+}
+
+func index(roots []string, password string, workers int, catalogs []catalog) (stats, error) {
+	var s stats
+
+	archives, err := findArchives(roots)
+	if err != nil {
+		return s, err
+	}
+
+	results := make(chan result, workers)
+
+	g := new(errgroup.Group)
+	g.SetLimit(workers)
+
+	go func() {
+		defer close(results)
+
+		for _, path := range archives {
+			info, err := os.Stat(path)
+			if err != nil {
+				results <- result{record: &archiveRecord{Path: path, Error: err.Error()}}
+
+				continue
+			}
+
+			if indexed(catalogs, path, info.ModTime()) {
+				s.skipped++
+
+				continue
+			}
+
+			g.Go(func() error {
+				rc, err := sevenzip.OpenReaderWithPassword(path, password)
+				if err != nil {
+					results <- result{record: &archiveRecord{Path: path, Modified: info.ModTime(), Error: err.Error()}}
+
+					return nil
+				}
+
+				results <- result{record: newArchiveRecord(path, info.ModTime(), rc), reader: rc}
+
+				return nil
+			})
+		}
+
+		_ = g.Wait()
+	}()
+
+	// Catalogs are only written from here, SQLite in particular doesn't
+	// cope well with concurrent writers
+	for r := range results {
+		if r.record.Error != "" {
+			log.Printf("%s: %s", r.record.Path, r.record.Error)
+
+			s.failed++
+		} else {
+			s.indexed++
+			s.files += r.record.Files
+		}
+
+		for _, c := range catalogs {
+			if cerr := c.write(r.record, r.reader); cerr != nil {
+				err = errors.Join(err, fmt.Errorf("%s: %w", r.record.Path, cerr))
+			}
+		}
+
+		if r.reader != nil {
+			err = errors.Join(err, r.reader.Close())
+		}
+	}
+
+	return s, err
+}
+
+func indexed(catalogs []catalog, path string, modTime time.Time) bool {
+	for _, c := range catalogs {
+		if !c.indexed(path, modTime) {
+			return false
+		}
+	}
+
+	return true
+}
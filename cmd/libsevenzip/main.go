@@ -0,0 +1,245 @@
+// Command libsevenzip is built as a C shared library so that applications
+// written in other languages, such as Python or C#, can list, extract and
+// verify 7-zip archives, including the offsets of stored files:
+//
+//	go build -buildmode=c-shared -o libsevenzip.so ./cmd/libsevenzip
+//
+// which also writes libsevenzip.h. Every function takes NUL-terminated UTF-8
+// strings and returns a NUL-terminated JSON document that must be released
+// with SevenZipFree. On failure the document is {"error": "..."} instead.
+// The ABI is versioned by SevenZipVersion; fields may be added to the JSON
+// documents without changing it, but never removed or renamed.
+//
+// From Python:
+//
+//	lib = ctypes.CDLL("./libsevenzip.so")
+//	lib.SevenZipList.restype = ctypes.c_void_p
+//	p = lib.SevenZipList(b"archive.7z", b"")
+//	files = json.loads(ctypes.string_at(p))
+//	lib.SevenZipFree(ctypes.c_void_p(p))
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+	"unsafe"
+
+	"github.com/javi11/sevenzip"
+)
+
+// abiVersion is incremented whenever an exported function changes in a way
+// that isn't backwards compatible.
+const abiVersion = 1
+
+type errorResult struct {
+	Error string `json:"error"`
+}
+
+type listEntry struct {
+	Name      string     `json:"name"`
+	Size      uint64     `json:"size"`
+	Modified  *time.Time `json:"modified,omitempty"`
+	CRC32     uint32     `json:"crc32"`
+	Dir       bool       `json:"dir"`
+	Block     int        `json:"block"`
+	Method    string     `json:"method,omitempty"`
+	Encrypted bool       `json:"encrypted"`
+	// Offset and Volume are only set for stored files whose contents
+	// can be read directly from the archive
+	Offset *int64 `json:"offset,omitempty"`
+	Volume *int   `json:"volume,omitempty"`
+}
+
+type listResult struct {
+	BaseOffset int64       `json:"base_offset"`
+	Files      []listEntry `json:"files"`
+}
+
+type extractResult struct {
+	Files int   `json:"files"`
+	Bytes int64 `json:"bytes"`
+}
+
+type verifyIssue struct {
+	Severity       string `json:"severity"`
+	Block          int    `json:"block"`
+	Problem        string `json:"problem"`
+	Recommendation string `json:"recommendation,omitempty"`
+}
+
+type verifyResult struct {
+	OK     bool          `json:"ok"`
+	Score  int           `json:"score"`
+	Issues []verifyIssue `json:"issues"`
+}
+
+// result encodes v, or err if it isn't nil, as a C string.
+func result(v any, err error) *C.char {
+	if err != nil {
+		v = errorResult{Error: err.Error()}
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(errorResult{Error: err.Error()})
+	}
+
+	return C.CString(string(b))
+}
+
+func open(path, password *C.char) (*sevenzip.ReadCloser, error) {
+	return sevenzip.OpenReaderWithPassword(C.GoString(path), C.GoString(password)) //nolint:wrapcheck
+}
+
+func list(r *sevenzip.Reader) listResult {
+	res := listResult{
+		BaseOffset: r.BaseOffset(),
+		Files:      make([]listEntry, 0, len(r.File)),
+	}
+
+	for _, f := range r.File {
+		e := listEntry{
+			Name:  f.Name,
+			Size:  f.UncompressedSize,
+			CRC32: f.CRC32,
+			Dir:   f.FileInfo().IsDir(),
+			Block: -1,
+		}
+
+		if !f.Modified.IsZero() {
+			modified := f.Modified.UTC()
+			e.Modified = &modified
+		}
+
+		if f.HasStream() {
+			e.Block = f.Stream
+		}
+
+		for i, c := range f.Coders() {
+			if i > 0 {
+				e.Method += " "
+			}
+
+			e.Method += c.String()
+			e.Encrypted = e.Encrypted || c.Name() == "7zAES"
+		}
+
+		if offset, volume, err := f.DataOffset(); err == nil {
+			e.Offset, e.Volume = &offset, &volume
+		}
+
+		res.Files = append(res.Files, e)
+	}
+
+	return res
+}
+
+// SevenZipVersion returns the version of the ABI implemented by the library.
+//
+//export SevenZipVersion
+func SevenZipVersion() C.int {
+	return abiVersion
+}
+
+// SevenZipList lists the files in the archive at path, which may be the
+// first volume of a multi-volume archive. The password may be empty.
+//
+//export SevenZipList
+func SevenZipList(path, password *C.char) *C.char {
+	r, err := open(path, password)
+	if err != nil {
+		return result(nil, err)
+	}
+
+	res := list(&r.Reader)
+
+	return result(res, r.Close())
+}
+
+// SevenZipExtract extracts every file in the archive at path to the
+// directory dir.
+//
+//export SevenZipExtract
+func SevenZipExtract(path, password, dir *C.char) *C.char {
+	r, err := open(path, password)
+	if err != nil {
+		return result(nil, err)
+	}
+
+	var res extractResult
+
+	// Files are reported concurrently
+	done := make(chan sevenzip.ExtractResult)
+	counted := make(chan struct{})
+
+	go func() {
+		defer close(counted)
+
+		for d := range done {
+			res.Files++
+			res.Bytes += d.Written
+		}
+	}()
+
+	err = r.ExtractAll(context.Background(), C.GoString(dir), sevenzip.WithFileDone(func(d sevenzip.ExtractResult) {
+		if d.Err == nil {
+			done <- d
+		}
+	}))
+
+	close(done)
+	<-counted
+
+	return result(res, errors.Join(err, r.Close()))
+}
+
+// SevenZipVerify decodes every file in the archive at path and checks it
+// against its CRC32, along with the other checks of [sevenzip.Reader.Health].
+// The archive is ok if no errors were found.
+//
+//export SevenZipVerify
+func SevenZipVerify(path, password *C.char) *C.char {
+	r, err := open(path, password)
+	if err != nil {
+		return result(nil, err)
+	}
+
+	report, err := r.Health(context.Background(), sevenzip.WithVerify())
+	if err != nil {
+		return result(nil, errors.Join(err, r.Close()))
+	}
+
+	res := verifyResult{
+		OK:     true,
+		Score:  report.Score,
+		Issues: make([]verifyIssue, 0, len(report.Issues)),
+	}
+
+	for _, issue := range report.Issues {
+		res.OK = res.OK && issue.Severity != sevenzip.HealthError
+		res.Issues = append(res.Issues, verifyIssue{
+			Severity:       issue.Severity.String(),
+			Block:          issue.Block,
+			Problem:        issue.Problem,
+			Recommendation: issue.Recommendation,
+		})
+	}
+
+	return result(res, r.Close())
+}
+
+// SevenZipFree releases a string returned by any of the other functions.
+//
+//export SevenZipFree
+func SevenZipFree(s *C.char) {
+	C.free(unsafe.Pointer(s))
+}
+
+func main() {}
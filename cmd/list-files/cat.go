@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/javi11/sevenzip"
+)
+
+// cat writes the contents of one or more members of an archive to stdout.
+func cat(args []string) {
+	fs := flag.NewFlagSet("cat", flag.ExitOnError)
+	password := fs.String("p", "", "Password for encrypted archives")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s cat [options] <archive> <member>...\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Write the contents of archive members to stdout.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+
+	_ = fs.Parse(args)
+
+	if fs.NArg() < 2 { //nolint:mnd
+		fs.Usage()
+		os.Exit(2) //nolint:mnd
+	}
+
+	r, err := sevenzip.OpenReaderWithPassword(fs.Arg(0), *password)
+	if err != nil {
+		log.Fatalf("Failed to open archive: %v", err)
+	}
+	defer r.Close()
+
+	for _, name := range fs.Args()[1:] {
+		if err := catFile(r, name); err != nil {
+			log.Fatalf("Failed to read %s: %v", name, err)
+		}
+	}
+}
+
+func catFile(r *sevenzip.ReadCloser, name string) error {
+	f, err := r.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(os.Stdout, f)
+
+	return err
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/javi11/sevenzip"
+)
+
+// The completion scripts call back into the binary with "completion members"
+// to complete the names of members once an archive has been given to cat.
+// They are formatted with the name of a shell function and the binary.
+const (
+	bashCompletion = `_%[1]s() {
+	local cur="${COMP_WORDS[COMP_CWORD]}"
+
+	if [ "$COMP_CWORD" -eq 1 ]; then
+		COMPREPLY=($(compgen -W "cat completion serve" -- "$cur") $(compgen -f -- "$cur"))
+		return
+	fi
+
+	case "${COMP_WORDS[1]}" in
+	cat)
+		local archive="" password="" i
+		for ((i = 2; i < COMP_CWORD; i++)); do
+			case "${COMP_WORDS[i]}" in
+			-p) i=$((i + 1)); password="${COMP_WORDS[i]}" ;;
+			-*) ;;
+			*) archive="${COMP_WORDS[i]}"; break ;;
+			esac
+		done
+
+		if [ -z "$archive" ]; then
+			COMPREPLY=($(compgen -f -- "$cur"))
+			return
+		fi
+
+		local IFS=$'\n'
+		COMPREPLY=($(%[2]s completion members -p "$password" "$archive" "$cur" 2>/dev/null))
+		;;
+	completion)
+		COMPREPLY=($(compgen -W "bash zsh fish members" -- "$cur"))
+		;;
+	*)
+		COMPREPLY=($(compgen -f -- "$cur"))
+		;;
+	esac
+}
+
+complete -o default -F _%[1]s %[2]s
+`
+
+	zshCompletion = `autoload -U +X bashcompinit && bashcompinit
+
+` + bashCompletion
+
+	fishCompletion = `function __%[1]s_members
+	set -l tokens (commandline -opc)
+	set -l archive
+	set -l password
+	set -l i 3
+
+	while test $i -le (count $tokens)
+		switch $tokens[$i]
+			case -p
+				set i (math $i + 1)
+				set password $tokens[$i]
+			case '-*'
+			case '*'
+				set archive $tokens[$i]
+				break
+		end
+		set i (math $i + 1)
+	end
+
+	if test -z "$archive"
+		__fish_complete_path (commandline -ct)
+		return
+	end
+
+	%[2]s completion members -p "$password" "$archive" (commandline -ct) 2>/dev/null
+end
+
+complete -c %[2]s -f -n __fish_use_subcommand -a 'cat completion serve'
+complete -c %[2]s -f -n '__fish_seen_subcommand_from completion' -a 'bash zsh fish members'
+complete -c %[2]s -f -n '__fish_seen_subcommand_from cat' -a '(__%[1]s_members)'
+`
+)
+
+// completion prints a completion script for a shell, or with "members" the
+// names of the files in an archive starting with a prefix, which only needs
+// the header to be read.
+func completion(args []string) {
+	name := filepath.Base(os.Args[0])
+
+	if len(args) < 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s completion bash|zsh|fish\n", name)
+		fmt.Fprintf(os.Stderr, "       %s completion members [-p password] <archive> [prefix]\n\n", name)
+		fmt.Fprintf(os.Stderr, "Print a shell completion script, for example:\n\n")
+		fmt.Fprintf(os.Stderr, "  source <(%s completion bash)\n", name)
+		os.Exit(2) //nolint:mnd
+	}
+
+	function := strings.Map(func(r rune) rune {
+		if r == '-' || r == '.' {
+			return '_'
+		}
+
+		return r
+	}, name)
+
+	switch args[0] {
+	case "bash":
+		fmt.Printf(bashCompletion, function, name)
+	case "zsh":
+		fmt.Printf(zshCompletion, function, name)
+	case "fish":
+		fmt.Printf(fishCompletion, function, name)
+	case "members":
+		members(args[1:])
+	default:
+		log.Fatalf("Unknown shell: %q", args[0])
+	}
+}
+
+func members(args []string) {
+	fs := flag.NewFlagSet("members", flag.ExitOnError)
+	password := fs.String("p", "", "Password for encrypted archives")
+
+	_ = fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		os.Exit(2) //nolint:mnd
+	}
+
+	r, err := sevenzip.OpenReaderWithPassword(fs.Arg(0), *password)
+	if err != nil {
+		// Nothing useful can be shown in the middle of completing
+		os.Exit(1)
+	}
+	defer r.Close()
+
+	prefix := fs.Arg(1)
+
+	for _, f := range r.File {
+		if !f.FileInfo().IsDir() && strings.HasPrefix(f.Name, prefix) {
+			fmt.Println(f.Name)
+		}
+	}
+}
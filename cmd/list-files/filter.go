@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/javi11/sevenzip"
+)
+
+// patterns is a flag.Value collecting every occurrence of a repeated flag.
+type patterns []string
+
+func (p *patterns) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *patterns) Set(value string) error {
+	if _, err := path.Match(value, ""); err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", value, err)
+	}
+
+	*p = append(*p, value)
+
+	return nil
+}
+
+// match reports whether name matches any of the patterns. Patterns without a
+// slash are matched against the base name so "*.txt" matches in any
+// directory, otherwise a pattern matching a directory also matches
+// everything within it.
+func (p patterns) match(name string) bool {
+	for _, pattern := range p {
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, path.Base(name)); ok {
+				return true
+			}
+
+			continue
+		}
+
+		for target := strings.TrimSuffix(name, "/"); target != "." && target != "/"; target = path.Dir(target) {
+			if ok, _ := path.Match(pattern, target); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func fileType(file sevenzip.FileInfo) string {
+	switch {
+	case file.Encrypted:
+		return "ENC"
+	case file.Compressed:
+		return "COMP"
+	default:
+		return "STORE"
+	}
+}
+
+type filter struct {
+	include, exclude patterns
+	minSize          uint64
+	only             string
+}
+
+func (f *filter) keep(file sevenzip.FileInfo) bool {
+	if len(f.include) > 0 && !f.include.match(file.Name) {
+		return false
+	}
+
+	if f.exclude.match(file.Name) {
+		return false
+	}
+
+	if file.Size < f.minSize {
+		return false
+	}
+
+	return f.only == "" || strings.EqualFold(f.only, fileType(file))
+}
+
+func (f *filter) apply(files []sevenzip.FileInfo) []sevenzip.FileInfo {
+	kept := files[:0]
+
+	for _, file := range files {
+		if f.keep(file) {
+			kept = append(kept, file)
+		}
+	}
+
+	return kept
+}
+
+func validOnly(only string) bool {
+	switch strings.ToLower(only) {
+	case "", "store", "comp", "enc":
+		return true
+	default:
+		return false
+	}
+}
+
+// sortFiles sorts files by the given key, keeping archive order for ties.
+func sortFiles(files []sevenzip.FileInfo, key string) error {
+	var less func(a, b sevenzip.FileInfo) bool
+
+	switch key {
+	case "":
+		return nil
+	case "name":
+		less = func(a, b sevenzip.FileInfo) bool { return a.Name < b.Name }
+	case "size":
+		less = func(a, b sevenzip.FileInfo) bool { return a.Size < b.Size }
+	case "offset":
+		less = func(a, b sevenzip.FileInfo) bool { return a.Offset < b.Offset }
+	default:
+		return fmt.Errorf("unknown sort key %q", key)
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return less(files[i], files[j])
+	})
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/javi11/sevenzip"
+	_ "modernc.org/sqlite"
+)
+
+// writeIndex writes the file table of the archive to a SQLite database, keyed
+// by its absolute path so the same archive isn't indexed twice.
+func writeIndex(dbPath, archivePath string, r *sevenzip.Reader) (err error) {
+	name, err := filepath.Abs(archivePath)
+	if err != nil {
+		return fmt.Errorf("error resolving path: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return fmt.Errorf("error opening database: %w", err)
+	}
+
+	defer func() {
+		err = errors.Join(err, db.Close())
+	}()
+
+	return sevenzip.WriteIndex(context.Background(), db, name, r)
+}
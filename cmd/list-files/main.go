@@ -12,15 +12,46 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "cat":
+			cat(os.Args[2:])
+
+			return
+		case "completion":
+			completion(os.Args[2:])
+
+			return
+		case "serve":
+			serve(os.Args[2:])
+
+			return
+		}
+	}
+
 	// Command line flags
 	var (
 		password = flag.String("p", "", "Password for encrypted archives")
 		verbose  = flag.Bool("v", false, "Verbose output")
 		help     = flag.Bool("h", false, "Show help")
+		probeArc = flag.Bool("probe", false, "Check the archive can be read and report the result as the exit code")
+		dumpHdr  = flag.Bool("dump-header", false, "Write an annotated dump of the archive headers and any warnings instead of listing it")
+		bundle   = flag.String("bundle", "", "Write a diagnostic bundle of the archive headers, with the file names hashed, to `path` instead of listing it")
+		dbPath   = flag.String("sqlite", "", "Write the file table to the SQLite database at `path` instead of listing it")
+		sortKey  = flag.String("sort", "", "Sort by `size|name|offset` instead of archive order")
+		f        filter
 	)
 
+	flag.Var(&f.include, "include", "Only list files matching the glob `pattern`, may be repeated")
+	flag.Var(&f.exclude, "exclude", "Don't list files matching the glob `pattern`, may be repeated")
+	flag.Uint64Var(&f.minSize, "min-size", 0, "Only list files of at least this many `bytes`")
+	flag.StringVar(&f.only, "only", "", "Only list files of one `type`: store, comp or enc")
+
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [options] <archive.7z or archive.7z.001>\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] <archive.7z or archive.7z.001>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s cat [options] <archive> <member>...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s completion bash|zsh|fish\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s serve [options]\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "List files in a 7zip archive with their offsets and compression status.\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
@@ -28,6 +59,11 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  %s archive.7z\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -p mypassword encrypted.7z\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s multipart.7z.001\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --include '*.go' --exclude 'vendor/*' --sort size archive.7z\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --probe -p mypassword encrypted.7z\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --sqlite catalog.db archive.7z\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --dump-header broken.7z\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\n%s", probeUsage)
 	}
 
 	flag.Parse()
@@ -37,8 +73,32 @@ func main() {
 		os.Exit(0)
 	}
 
+	if !validOnly(f.only) {
+		log.Fatalf("Invalid --only value: %q", f.only)
+	}
+
 	archivePath := flag.Arg(0)
 
+	if *probeArc {
+		os.Exit(probe(archivePath, *password))
+	}
+
+	if *dumpHdr {
+		if err := dumpHeader(archivePath, *password); err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	if *bundle != "" {
+		if err := writeBundle(archivePath, *password, *bundle); err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
 	// Open the archive
 	var reader *sevenzip.ReadCloser
 	var err error
@@ -54,6 +114,16 @@ func main() {
 	}
 	defer reader.Close()
 
+	if *dbPath != "" {
+		if err := writeIndex(*dbPath, archivePath, &reader.Reader); err != nil {
+			log.Fatalf("Failed to write index: %v", err)
+		}
+
+		fmt.Printf("Indexed %d files from %s into %s\n", len(reader.File), filepath.Base(archivePath), *dbPath)
+
+		return
+	}
+
 	// Show archive information
 	fmt.Printf("Archive: %s\n", filepath.Base(archivePath))
 
@@ -75,12 +145,30 @@ func main() {
 		log.Fatalf("Failed to list files: %v", err)
 	}
 
+	// Like `7z l -slt`, the packed size of a solid block is only shown
+	// against the first file in it, in archive order
+	firstInBlock := make(map[int]int64, len(files))
+	for _, file := range files {
+		if first, ok := firstInBlock[file.FolderIndex]; !ok || file.Offset < first {
+			firstInBlock[file.FolderIndex] = file.Offset
+		}
+	}
+
+	files = f.apply(files)
+
+	if err := sortFiles(files, *sortKey); err != nil {
+		log.Fatalf("Failed to sort files: %v", err)
+	}
+
+	// Solid blocks, used for the method chain and packed size
+	blocks := reader.Blocks()
+
 	// Create a tabwriter for better formatting
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 
 	// Print header
-	fmt.Fprintln(w, "Type\tOffset\tSize\tFolder\tName")
-	fmt.Fprintln(w, "----\t------\t----\t------\t----")
+	fmt.Fprintln(w, "Type\tOffset\tSize\tPacked\tBlock\tMethod\tName")
+	fmt.Fprintln(w, "----\t------\t----\t------\t-----\t------\t----")
 
 	// Statistics
 	var (
@@ -92,27 +180,36 @@ func main() {
 
 	// Print file information
 	for _, file := range files {
-		var fileType string
+		fileType := fileType(file)
 
-		if file.Encrypted {
-			fileType = "ENC"
+		switch fileType {
+		case "ENC":
 			encryptedCount++
-		} else if file.Compressed {
-			fileType = "COMP"
+		case "COMP":
 			compressedCount++
-		} else {
-			fileType = "STORE"
+		default:
 			uncompressedCount++
 		}
 
 		totalSize += file.Size
 
+		var packed, method string
+		if file.FolderIndex < len(blocks) {
+			method = blocks[file.FolderIndex].Method()
+
+			if firstInBlock[file.FolderIndex] == file.Offset {
+				packed = fmt.Sprint(blocks[file.FolderIndex].PackedSize)
+			}
+		}
+
 		// Format the output
-		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\n",
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%d\t%s\t%s\n",
 			fileType,
 			file.Offset,
 			file.Size,
+			packed,
 			file.FolderIndex,
+			method,
 			file.Name)
 	}
 
@@ -122,6 +219,7 @@ func main() {
 	// Print summary
 	fmt.Println("Summary:")
 	fmt.Printf("  Total files:     %d\n", len(files))
+	fmt.Printf("  Solid blocks:    %d\n", len(blocks))
 	fmt.Printf("  Total size:      %d bytes (%.2f GB)\n", totalSize, float64(totalSize)/(1024*1024*1024))
 	fmt.Printf("  Stored:          %d files (direct access possible)\n", uncompressedCount)
 	fmt.Printf("  Compressed:      %d files\n", compressedCount)
@@ -131,3 +229,81 @@ func main() {
 		fmt.Println("\nNote: Files marked as 'STORE' can be read directly at their offsets without decompression.")
 	}
 }
+
+// writeBundle writes a diagnostic bundle of the archive to out, with the
+// names of its files hashed.
+func writeBundle(name, password, out string) (err error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	w, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	return sevenzip.WriteDiagnosticBundle(f, fi.Size(), w, sevenzip.WithHashedNames(),
+		sevenzip.WithBundleReaderOptions(sevenzip.WithPassword(password)))
+}
+
+// dumpHeader writes an annotated dump of the headers of the archive to
+// standard output, followed by the result of opening it and any warnings,
+// which is everything needed to report a problem without sharing the
+// archive itself. Volumes of a multi-volume archive need joining first.
+func dumpHeader(name, password string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	opts := []sevenzip.ReaderOption{sevenzip.WithPassword(password)}
+	dumpErr := sevenzip.DumpHeader(f, fi.Size(), os.Stdout, opts...)
+
+	fmt.Println()
+
+	r, err := sevenzip.NewReaderWithOptions(f, fi.Size(), opts...)
+	if err != nil {
+		fmt.Printf("Open: %v\n", err)
+
+		return dumpErr
+	}
+	defer r.Close()
+
+	fmt.Printf("Open: ok, %d files\n", len(r.File))
+
+	info, err := r.Info()
+	if err != nil {
+		return err
+	}
+
+	if len(info.Warnings) == 0 {
+		fmt.Println("Warnings: none")
+	} else {
+		fmt.Println("Warnings:")
+
+		for _, w := range info.Warnings {
+			fmt.Printf("  %s\n", w)
+		}
+	}
+
+	return dumpErr
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/javi11/sevenzip"
+)
+
+// Exit codes used by --probe so scripts can branch on the state of an archive
+// without parsing any output.
+const (
+	exitOK = iota
+	exitError
+	exitNeedsPassword
+	exitWrongPassword
+	exitUnsupported
+	exitCorruptHeader
+	exitMissingVolumes
+	exitCorruptData
+)
+
+const probeUsage = `Exit codes with --probe:
+  0  archive is readable
+  1  other error, such as the archive not existing
+  2  archive is encrypted and no password was given
+  3  password is wrong
+  4  archive uses an unsupported method or format version
+  5  archive header is corrupt
+  6  volumes of a multi-volume archive are missing
+  7  file contents are corrupt
+`
+
+var errCRCMismatch = errors.New("CRC doesn't match")
+
+// probe opens the archive and decodes the first file of every solid block,
+// which is enough to check the password and methods without decoding
+// everything, returning one of the exit codes above.
+func probe(name, password string) int {
+	r, err := sevenzip.OpenReaderWithPassword(name, password)
+	if err != nil {
+		return report(probeOpenError(name, err), err)
+	}
+	defer r.Close()
+
+	blocks := r.Blocks()
+	seen := make(map[int]bool, len(blocks))
+
+	for _, f := range r.File {
+		if f.Coders() == nil || seen[f.Stream] {
+			continue
+		}
+
+		seen[f.Stream] = true
+
+		if err := probeFile(f); err != nil {
+			return report(probeReadError(blocks[f.Stream].Encrypted(), err), err)
+		}
+	}
+
+	return exitOK
+}
+
+func probeFile(f *sevenzip.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	h := crc32.NewIEEE()
+
+	if _, err := io.Copy(h, rc); err != nil {
+		return err
+	}
+
+	if crc, ok := f.Digest(); ok && h.Sum32() != crc {
+		return fmt.Errorf("%s: %w", f.Name, errCRCMismatch)
+	}
+
+	return nil
+}
+
+func probeOpenError(name string, err error) int {
+	var re *sevenzip.ReadError
+
+	switch {
+	case errors.Is(err, sevenzip.ErrPasswordRequired):
+		return exitNeedsPassword
+	case errors.Is(err, sevenzip.ErrUnsupportedMethod), errors.Is(err, sevenzip.ErrUnsupportedVersion):
+		return exitUnsupported
+	case errors.Is(err, sevenzip.ErrTruncated) && filepath.Ext(name) == ".001":
+		return exitMissingVolumes
+	case errors.As(err, &re) && re.Encrypted:
+		return exitWrongPassword
+	case errors.Is(err, iofs.ErrNotExist), errors.Is(err, iofs.ErrPermission):
+		return exitError
+	default:
+		return exitCorruptHeader
+	}
+}
+
+func probeReadError(encrypted bool, err error) int {
+	switch {
+	case errors.Is(err, sevenzip.ErrPasswordRequired):
+		return exitNeedsPassword
+	case errors.Is(err, sevenzip.ErrUnsupportedMethod):
+		return exitUnsupported
+	case encrypted:
+		// Decoding garbage from a wrong password fails in all sorts of
+		// ways, so any error from an encrypted block is blamed on it
+		return exitWrongPassword
+	default:
+		return exitCorruptData
+	}
+}
+
+func report(code int, err error) int {
+	fmt.Fprintf(os.Stderr, "%v\n", err)
+
+	return code
+}
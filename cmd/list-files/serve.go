@@ -0,0 +1,499 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/javi11/sevenzip"
+)
+
+const serveUsage = `Usage: %s serve [options]
+
+Keep archives open and answer list and extract requests over HTTP, either on
+a Unix socket or a TCP address, so the headers of large archives are only
+parsed once. Only archives under the directory given with -root are served.
+
+Endpoints:
+  GET /list?archive=PATH     List files as JSON, accepts the include,
+                             exclude, min-size, only and sort parameters
+  GET /extract?archive=PATH&file=NAME
+                             Return the contents of a file
+
+An archive PATH is relative to the root, or absolute if it's under it. Any
+symbolic links are followed before checking, so a link can't be used to reach
+outside of it.
+
+A password is passed with the X-Password request header. Extracted files are
+sent with ETag and Last-Modified headers and conditional requests are
+answered with 304 Not Modified.
+
+Options:
+`
+
+const passwordHeader = "X-Password"
+
+// An archive is an open archive shared between requests. It is closed once
+// it has been evicted from the cache and the last request using it finishes.
+type archive struct {
+	mu      sync.Mutex
+	rc      *sevenzip.ReadCloser
+	modTime time.Time
+	size    int64
+	refs    int
+	evicted bool
+}
+
+func (a *archive) acquire() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.refs++
+}
+
+func (a *archive) release() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.refs--
+	if a.refs == 0 && a.evicted {
+		a.rc.Close()
+	}
+}
+
+func (a *archive) evict() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.evicted = true
+	if a.refs == 0 {
+		a.rc.Close()
+	}
+}
+
+type archiveKey struct {
+	path, password string
+}
+
+// archiveCache keeps the most recently used archives open, reopening an
+// archive if it has changed on disk since it was opened.
+type archiveCache struct {
+	mu    sync.Mutex
+	cache *lru.Cache[archiveKey, *archive]
+}
+
+func newArchiveCache(size int) (*archiveCache, error) {
+	cache, err := lru.NewWithEvict(size, func(_ archiveKey, a *archive) {
+		a.evict()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating cache: %w", err)
+	}
+
+	return &archiveCache{cache: cache}, nil
+}
+
+// get returns the open archive, which must be released when finished with.
+func (c *archiveCache) get(path, password string) (*archive, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := archiveKey{path, password}
+
+	if a, ok := c.cache.Get(key); ok {
+		if a.modTime.Equal(info.ModTime()) && a.size == info.Size() {
+			a.acquire()
+
+			return a, nil
+		}
+
+		c.cache.Remove(key)
+	}
+
+	rc, err := sevenzip.OpenReaderWithPassword(path, password)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &archive{rc: rc, modTime: info.ModTime(), size: info.Size(), refs: 1}
+	c.cache.Add(key, a)
+
+	return a, nil
+}
+
+func (c *archiveCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cache.Purge()
+}
+
+type entry struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Offset int64  `json:"offset"`
+	Size   uint64 `json:"size"`
+	Block  int    `json:"block"`
+	Method string `json:"method,omitempty"`
+}
+
+type server struct {
+	cache *archiveCache
+	// root is the absolute path of the directory archives are served from,
+	// with any symbolic links resolved
+	root string
+}
+
+// errOutsideRoot is returned for archives that aren't under the root.
+var errOutsideRoot = errors.New("archive is outside of the root")
+
+// resolve returns the path of the archive once any symbolic links have been
+// followed, as long as it's under the root.
+func (s *server) resolve(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.root, path)
+	}
+
+	path, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err //nolint:wrapcheck
+	}
+
+	rel, err := filepath.Rel(s.root, path)
+	if err != nil || !filepath.IsLocal(rel) {
+		return "", errOutsideRoot
+	}
+
+	return path, nil
+}
+
+func (s *server) archive(w http.ResponseWriter, r *http.Request) *archive {
+	path := r.URL.Query().Get("archive")
+	if path == "" {
+		http.Error(w, "missing archive parameter", http.StatusBadRequest)
+
+		return nil
+	}
+
+	path, err := s.resolve(path)
+	if err != nil {
+		httpError(w, err)
+
+		return nil
+	}
+
+	a, err := s.cache.get(path, r.Header.Get(passwordHeader))
+	if err != nil {
+		httpError(w, err)
+
+		return nil
+	}
+
+	return a
+}
+
+func (s *server) list(w http.ResponseWriter, r *http.Request) {
+	a := s.archive(w, r)
+	if a == nil {
+		return
+	}
+	defer a.release()
+
+	query := r.URL.Query()
+
+	var f filter
+
+	for _, pattern := range query["include"] {
+		if err := f.include.Set(pattern); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	for _, pattern := range query["exclude"] {
+		if err := f.exclude.Set(pattern); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	if minSize := query.Get("min-size"); minSize != "" {
+		var err error
+		if f.minSize, err = strconv.ParseUint(minSize, 10, 64); err != nil {
+			http.Error(w, "invalid min-size: "+err.Error(), http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	if f.only = query.Get("only"); !validOnly(f.only) {
+		http.Error(w, fmt.Sprintf("invalid only value: %q", f.only), http.StatusBadRequest)
+
+		return
+	}
+
+	files, err := a.rc.ListFilesWithOffsets()
+	if err != nil {
+		httpError(w, err)
+
+		return
+	}
+
+	files = f.apply(files)
+
+	if err := sortFiles(files, query.Get("sort")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	blocks := a.rc.Blocks()
+	entries := make([]entry, 0, len(files))
+
+	for _, file := range files {
+		e := entry{
+			Name:   file.Name,
+			Type:   fileType(file),
+			Offset: file.Offset,
+			Size:   file.Size,
+			Block:  file.FolderIndex,
+		}
+
+		if file.FolderIndex < len(blocks) {
+			e.Method = blocks[file.FolderIndex].Method()
+		}
+
+		entries = append(entries, e)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		log.Printf("Failed to write list response: %v", err)
+	}
+}
+
+func (s *server) extract(w http.ResponseWriter, r *http.Request) {
+	a := s.archive(w, r)
+	if a == nil {
+		return
+	}
+	defer a.release()
+
+	name := r.URL.Query().Get("file")
+	if name == "" {
+		http.Error(w, "missing file parameter", http.StatusBadRequest)
+
+		return
+	}
+
+	// Answer conditional requests before the file is opened as that may
+	// mean decoding the start of a solid block
+	if file, ok := a.rc.FileByName(name); ok {
+		w.Header().Set("ETag", file.ETag())
+
+		if !file.Modified.IsZero() {
+			w.Header().Set("Last-Modified", file.Modified.UTC().Format(http.TimeFormat))
+		}
+
+		if notModified(r, file) {
+			w.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+	}
+
+	f, err := a.rc.Open(name)
+	if err != nil {
+		httpError(w, err)
+
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		httpError(w, err)
+
+		return
+	}
+
+	if info.IsDir() {
+		http.Error(w, name+" is a directory", http.StatusBadRequest)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+
+	// Once the body has started there's no way to report an error other
+	// than cutting the response short
+	if _, err := io.Copy(w, f); err != nil {
+		log.Printf("Failed to extract %s: %v", name, err)
+	}
+}
+
+// notModified reports whether the conditional headers of the request match
+// the file, If-None-Match taking precedence over If-Modified-Since.
+func notModified(r *http.Request, f *sevenzip.File) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		etag := strings.TrimPrefix(f.ETag(), "W/")
+
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == etag {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if f.Modified.IsZero() {
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+
+	return err == nil && !f.Modified.Truncate(time.Second).After(since)
+}
+
+func httpError(w http.ResponseWriter, err error) {
+	var (
+		re     *sevenzip.ReadError
+		status int
+	)
+
+	switch {
+	case errors.Is(err, iofs.ErrNotExist):
+		status = http.StatusNotFound
+	case errors.Is(err, errOutsideRoot):
+		status = http.StatusForbidden
+	case errors.Is(err, iofs.ErrInvalid):
+		status = http.StatusBadRequest
+	case errors.Is(err, sevenzip.ErrPasswordRequired):
+		status = http.StatusUnauthorized
+	case errors.As(err, &re) && re.Encrypted:
+		status = http.StatusForbidden
+	case errors.Is(err, sevenzip.ErrUnsupportedMethod):
+		status = http.StatusNotImplemented
+	default:
+		status = http.StatusInternalServerError
+	}
+
+	http.Error(w, err.Error(), status)
+}
+
+func listen(socket, addr string) (net.Listener, error) {
+	switch {
+	case socket != "" && addr != "":
+		return nil, errors.New("only one of -socket and -http can be used")
+	case socket != "":
+		return net.Listen("unix", socket) //nolint:wrapcheck
+	case addr != "":
+		return net.Listen("tcp", addr) //nolint:wrapcheck
+	default:
+		return nil, errors.New("one of -socket or -http is required")
+	}
+}
+
+func serve(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+
+	var (
+		socket    = fs.String("socket", "", "Listen on the Unix socket at `path`")
+		addr      = fs.String("http", "", "Listen on the TCP `address`, such as localhost:8080")
+		cacheSize = fs.Int("cache", 16, "Maximum `number` of archives to keep open")
+		root      = fs.String("root", "", "Only serve archives under the `directory`, required")
+	)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, serveUsage, os.Args[0])
+		fs.PrintDefaults()
+	}
+
+	_ = fs.Parse(args)
+
+	if *root == "" {
+		log.Fatal("-root is required")
+	}
+
+	rootPath, err := filepath.Abs(*root)
+	if err != nil {
+		log.Fatalf("Failed to resolve root: %v", err)
+	}
+
+	if rootPath, err = filepath.EvalSymlinks(rootPath); err != nil {
+		log.Fatalf("Failed to resolve root: %v", err)
+	}
+
+	cache, err := newArchiveCache(*cacheSize)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	l, err := listen(*socket, *addr)
+	if err != nil {
+		log.Fatalf("Failed to listen: %v", err)
+	}
+
+	s := &server{cache: cache, root: rootPath}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/list", s.list)
+	mux.HandleFunc("/extract", s.extract)
+
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdown := make(chan struct{})
+
+	go func() {
+		defer close(shutdown)
+
+		<-ctx.Done()
+
+		if err := srv.Shutdown(context.Background()); err != nil {
+			log.Printf("Failed to shut down: %v", err)
+		}
+	}()
+
+	log.Printf("Listening on %s", l.Addr())
+
+	if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("Failed to serve: %v", err)
+	}
+
+	<-shutdown
+
+	cache.purge()
+}
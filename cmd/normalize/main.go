@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/javi11/sevenzip"
+)
+
+func main() {
+	// Command line flags
+	var (
+		password = flag.String("p", "", "Password for encrypted archives")
+		mtime    = flag.String("mtime", "", "Set every modification time to this RFC 3339 timestamp instead of removing them")
+		help     = flag.Bool("h", false, "Show help")
+	)
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] <archive.7z or archive.7z.001> <output.7z>\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Rewrite a 7zip archive in a canonical form so that the same contents always produce a byte-identical archive.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExample:\n")
+		fmt.Fprintf(os.Stderr, "  %s archive.7z normalized.7z\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -mtime 2000-01-01T00:00:00Z archive.7z normalized.7z\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -p mypassword encrypted.7z normalized.7z\n", os.Args[0])
+	}
+
+	flag.Parse()
+
+	if *help || flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(0)
+	}
+
+	var opts []sevenzip.NormalizeOption
+
+	if *mtime != "" {
+		t, err := time.Parse(time.RFC3339, *mtime)
+		if err != nil {
+			log.Fatalf("Invalid modification time: %v", err)
+		}
+
+		opts = append(opts, sevenzip.WithModTime(t))
+	}
+
+	if err := normalize(flag.Arg(0), flag.Arg(1), *password, opts); err != nil {
+		log.Fatalf("Failed to normalize archive: %v", err)
+	}
+}
+
+func normalize(src, dst, password string, opts []sevenzip.NormalizeOption) (err error) {
+	reader, err := sevenzip.OpenReaderWithPassword(src, password)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err = errors.Join(err, reader.Close())
+	}()
+
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if err = sevenzip.Normalize(&reader.Reader, f, opts...); err != nil {
+		return errors.Join(err, f.Close(), os.Remove(dst))
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,118 @@
+package sevenzip
+
+import (
+	"io"
+
+	"github.com/bodgit/plumbing"
+	"github.com/javi11/sevenzip/internal/util"
+)
+
+// NewCoderReader returns an [io.ReadCloser] that decodes size bytes using the
+// registered [Decompressor] for method, configured with properties and
+// reading from inputs. Most methods take exactly one input; BCJ2 takes four.
+//
+// This is the same building block used internally to decode each folder, so
+// readers can be chained to construct custom pipelines, for example to only
+// decrypt a packed stream, or to resume decompression from a previously
+// cached intermediate stream. Any inputs that implement [io.Closer] are
+// closed when the returned reader is closed.
+//
+// If the method requires a password, such as AES, the returned reader also
+// implements [CryptoReadCloser] and Password must be called before the first
+// Read.
+func NewCoderReader(method, properties []byte, size uint64, inputs ...io.Reader) (io.ReadCloser, error) {
+	readers := make([]io.ReadCloser, len(inputs))
+
+	for i, r := range inputs {
+		if rc, ok := r.(io.ReadCloser); ok {
+			readers[i] = rc
+		} else {
+			readers[i] = io.NopCloser(r)
+		}
+	}
+
+	return newCoderReader(method, properties, size, readers, nil)
+}
+
+type cryptoLimitReadCloser struct {
+	io.ReadCloser
+	crc CryptoReadCloser
+}
+
+func (rc *cryptoLimitReadCloser) Password(password string) error {
+	return rc.crc.Password(password) //nolint:wrapcheck
+}
+
+func newCoderReader(method, properties []byte, size uint64, readers []io.ReadCloser, cfg *folderConfig) (io.ReadCloser, error) {
+	lookup := decompressor
+	if cfg != nil && cfg.lookup != nil {
+		lookup = cfg.lookup
+	}
+
+	dcomp := lookup(method)
+	if dcomp == nil {
+		return nil, unsupportedMethod(method)
+	}
+
+	cr, err := dcomp(properties, size, readers)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg != nil {
+		if c, ok := cr.(util.Canceller); ok {
+			c.SetCancel(cfg.done)
+		}
+
+		if c, ok := cr.(util.CBCDecrypterSetter); ok && cfg.cbc != nil {
+			c.SetCBCDecrypter(cfg.cbc)
+		}
+	}
+
+	lrc := plumbing.LimitReadCloser(cr, int64(size)) //nolint:gosec
+
+	if crc, ok := cr.(CryptoReadCloser); ok {
+		return &cryptoLimitReadCloser{lrc, crc}, nil
+	}
+
+	return lrc, nil
+}
+
+// newMultiCoderReader is the equivalent of newCoderReader for methods with
+// more than one output stream, returning a reader for each.
+func newMultiCoderReader(method, properties []byte, sizes []uint64, readers []io.ReadCloser, cfg *folderConfig) ([]io.ReadCloser, error) {
+	lookup := multiDecompressor
+	if cfg != nil && cfg.multiLookup != nil {
+		lookup = cfg.multiLookup
+	}
+
+	dcomp := lookup(method)
+	if dcomp == nil {
+		return nil, unsupportedMethod(method)
+	}
+
+	outs, err := dcomp(properties, sizes, readers)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(outs) != len(sizes) {
+		return nil, errOutputStreams
+	}
+
+	for i, rc := range outs {
+		if cfg != nil {
+			if c, ok := rc.(util.Canceller); ok {
+				c.SetCancel(cfg.done)
+			}
+
+			if c, ok := rc.(util.CBCDecrypterSetter); ok && cfg.cbc != nil {
+				c.SetCBCDecrypter(cfg.cbc)
+			}
+		}
+
+		outs[i] = plumbing.LimitReadCloser(rc, int64(sizes[i])) //nolint:gosec
+	}
+
+	return outs, nil
+}
@@ -0,0 +1,168 @@
+package sevenzip_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ulikunitz/xz/lzma"
+)
+
+var (
+	methodCopy  = []byte{0x00}
+	methodDelta = []byte{0x03}
+	methodLZMA  = []byte{0x03, 0x01, 0x01}
+	methodAES   = []byte{0x06, 0xf1, 0x07, 0x01}
+)
+
+// deltaEncode applies the inverse of the Delta filter with a distance of one.
+func deltaEncode(b []byte) []byte {
+	out := make([]byte, len(b))
+
+	var prev byte
+	for i, c := range b {
+		out[i] = c - prev
+		prev = c
+	}
+
+	return out
+}
+
+// lzmaEncode compresses b as a raw LZMA stream, terminated with an end marker
+// if eos is true, returning the coder properties and the stream.
+func lzmaEncode(tb testing.TB, b []byte, eos bool) ([]byte, []byte) {
+	tb.Helper()
+
+	size := int64(len(b))
+	if eos {
+		size = -1
+	}
+
+	var buf bytes.Buffer
+
+	w, err := lzma.WriterConfig{Size: size, EOSMarker: eos}.NewWriter(&buf)
+	require.NoError(tb, err)
+
+	_, err = w.Write(b)
+	require.NoError(tb, err)
+	require.NoError(tb, w.Close())
+
+	// Split the 13 byte header into the 5 bytes of properties, dropping
+	// the size which 7-zip stores separately
+	return buf.Bytes()[:5], buf.Bytes()[13:]
+}
+
+// aesEncrypt encrypts b the same way as 7-zip using a raw, unhashed key
+// derived from password with the given IV.
+func aesEncrypt(tb testing.TB, password string, iv, b []byte) []byte {
+	tb.Helper()
+
+	key := make([]byte, 32)
+
+	for i, r := range password {
+		key[i*2] = byte(r)
+	}
+
+	block, err := aes.NewCipher(key)
+	require.NoError(tb, err)
+
+	padded := make([]byte, (len(b)+aes.BlockSize-1)/aes.BlockSize*aes.BlockSize)
+	copy(padded, b)
+
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(padded, padded)
+
+	return padded
+}
+
+func TestNewCoderReader(t *testing.T) {
+	t.Parallel()
+
+	plaintext := []byte(strings.Repeat("The quick brown fox jumps over the lazy dog. ", 10))
+	iv := bytes.Repeat([]byte{0x42}, aes.BlockSize)
+
+	// No salt, 16 byte IV and the special cycles value for no hashing
+	props := append([]byte{0x7f, 0x0f}, iv...)
+	encrypted := aesEncrypt(t, "password", iv, deltaEncode(plaintext))
+
+	t.Run("pipeline", func(t *testing.T) {
+		t.Parallel()
+
+		copied, err := sevenzip.NewCoderReader(methodCopy, nil, uint64(len(encrypted)), bytes.NewReader(encrypted))
+		require.NoError(t, err)
+
+		decrypted, err := sevenzip.NewCoderReader(methodAES, props, uint64(len(plaintext)), copied)
+		require.NoError(t, err)
+
+		crc, ok := decrypted.(sevenzip.CryptoReadCloser)
+		require.True(t, ok)
+		require.NoError(t, crc.Password("password"))
+
+		rc, err := sevenzip.NewCoderReader(methodDelta, []byte{0x00}, uint64(len(plaintext)), decrypted)
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		assert.Equal(t, plaintext, b)
+		assert.NoError(t, rc.Close())
+	})
+
+	t.Run("decrypt only", func(t *testing.T) {
+		t.Parallel()
+
+		rc, err := sevenzip.NewCoderReader(methodAES, props, uint64(len(plaintext)), bytes.NewReader(encrypted))
+		require.NoError(t, err)
+
+		_, ok := rc.(sevenzip.CryptoReadCloser)
+		require.True(t, ok)
+
+		_, err = io.ReadAll(rc)
+		assert.Error(t, err)
+
+		require.NoError(t, rc.(sevenzip.CryptoReadCloser).Password("password")) //nolint:forcetypeassert
+
+		b, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		assert.Equal(t, deltaEncode(plaintext), b)
+	})
+
+	t.Run("lzma end marker", func(t *testing.T) {
+		t.Parallel()
+
+		for _, eos := range []bool{false, true} {
+			props, stream := lzmaEncode(t, plaintext, eos)
+
+			// Trailing padding after the stream shouldn't matter either
+			for _, padding := range []int{0, 16} {
+				in := append(bytes.Clone(stream), make([]byte, padding)...)
+
+				rc, err := sevenzip.NewCoderReader(methodLZMA, props, uint64(len(plaintext)), bytes.NewReader(in))
+				require.NoError(t, err)
+
+				b, err := io.ReadAll(rc)
+				require.NoError(t, err, "eos: %v, padding: %d", eos, padding)
+				assert.Equal(t, plaintext, b)
+				assert.NoError(t, rc.Close())
+			}
+		}
+	})
+
+	t.Run("unsupported method", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := sevenzip.NewCoderReader([]byte{0xff, 0xff}, nil, 0, bytes.NewReader(nil))
+		assert.ErrorIs(t, err, sevenzip.ErrUnsupportedMethod)
+		assert.ErrorContains(t, err, "ffff")
+
+		rc, err := sevenzip.NewCoderReader(methodCopy, nil, 0, bytes.NewReader(nil))
+		require.NoError(t, err)
+
+		_, ok := rc.(sevenzip.CryptoReadCloser)
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,222 @@
+package sevenzip_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// conformanceManifest lists the archives built by
+// testdata/conformance/generate.sh and what each should contain.
+type conformanceManifest struct {
+	Archives []conformanceArchive `json:"archives"`
+}
+
+type conformanceArchive struct {
+	Path     string            `json:"path"`
+	Producer string            `json:"producer"`
+	Codec    string            `json:"codec"`
+	Password string            `json:"password"`
+	Method   string            `json:"method"`
+	Files    []conformanceFile `json:"files"`
+}
+
+type conformanceFile struct {
+	Name   string `json:"name"`
+	Size   uint64 `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+func readManifest(t *testing.T, dir string) conformanceManifest {
+	t.Helper()
+
+	b, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		t.Skip("no fixtures, run testdata/conformance/generate.sh")
+	}
+
+	require.NoError(t, err)
+
+	var m conformanceManifest
+	require.NoError(t, json.Unmarshal(b, &m))
+
+	return m
+}
+
+// checkConformance opens archive from dir and checks the method of each file
+// matches what its producer used and that each decodes to the same bytes as
+// the file it was built from. Archives using a method missing from this build
+// are skipped so the gaps show up per producer and codec.
+func checkConformance(t *testing.T, dir string, archive conformanceArchive) {
+	t.Helper()
+
+	r, err := sevenzip.OpenReaderWithPassword(filepath.Join(dir, archive.Path), archive.Password)
+	if errors.Is(err, sevenzip.ErrUnsupportedMethod) {
+		t.Skip(err)
+	}
+
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, r.Close())
+	})
+
+	blocks := r.Blocks()
+	got := make(map[string]*sevenzip.File)
+
+	for _, f := range r.File {
+		if !f.FileInfo().IsDir() {
+			got[f.Name] = f
+		}
+	}
+
+	assert.Len(t, got, len(archive.Files))
+
+	for _, want := range archive.Files {
+		f, ok := got[want.Name]
+		if !assert.True(t, ok, want.Name) {
+			continue
+		}
+
+		assert.Equal(t, want.Size, f.UncompressedSize, want.Name)
+
+		if !f.HasStream() {
+			continue
+		}
+
+		require.Less(t, f.Stream, len(blocks), want.Name)
+		assert.Equal(t, archive.Method, blocks[f.Stream].Method(), want.Name)
+
+		rc, err := f.Open()
+		if errors.Is(err, sevenzip.ErrUnsupportedMethod) {
+			t.Skip(err)
+		}
+
+		require.NoError(t, err, want.Name)
+
+		h := sha256.New()
+		_, err = io.Copy(h, rc)
+		require.NoError(t, errors.Join(err, rc.Close()), want.Name)
+
+		assert.Equal(t, want.SHA256, hex.EncodeToString(h.Sum(nil)), want.Name)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join("testdata", "conformance")
+
+	for _, archive := range readManifest(t, dir).Archives {
+		t.Run(archive.Producer+"/"+archive.Codec, func(t *testing.T) {
+			t.Parallel()
+
+			checkConformance(t, dir, archive)
+		})
+	}
+}
+
+func TestConformanceHarness(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	contents := map[string][]byte{
+		"text.txt":       bytes.Repeat([]byte("The quick brown fox jumps over the lazy dog.\n"), 100),
+		"empty.txt":      {},
+		"dir/nested.txt": []byte("nested\n"),
+	}
+
+	var files []conformanceFile
+
+	for _, name := range []string{"dir/nested.txt", "empty.txt", "text.txt"} {
+		sum := sha256.Sum256(contents[name])
+		files = append(files, conformanceFile{
+			Name:   name,
+			Size:   uint64(len(contents[name])),
+			SHA256: hex.EncodeToString(sum[:]),
+		})
+	}
+
+	tables := []struct {
+		codec, password, method string
+		opts                    []sevenzip.WriterOption
+	}{
+		{
+			codec:  "copy",
+			method: "Copy",
+			opts: []sevenzip.WriterOption{
+				sevenzip.WithMethodRules(sevenzip.MethodForExtensions(sevenzip.MethodCopy, ".txt")),
+			},
+		},
+		{
+			codec:  "ppmd",
+			method: "PPMD:o6:mem24",
+			opts: []sevenzip.WriterOption{
+				sevenzip.WithMethodRules(sevenzip.MethodForExtensions(sevenzip.MethodPPMd, ".txt")),
+			},
+		},
+		{
+			codec:    "aes",
+			password: "conformance",
+			method:   "Copy 7zAES:19",
+			opts: []sevenzip.WriterOption{
+				sevenzip.WithWriterPassword("conformance"),
+				sevenzip.WithMethodRules(sevenzip.MethodForExtensions(sevenzip.MethodCopy, ".txt")),
+			},
+		},
+	}
+
+	var m conformanceManifest
+
+	for _, table := range tables {
+		path := table.codec + ".7z"
+
+		f, err := os.Create(filepath.Join(dir, path))
+		require.NoError(t, err)
+
+		w := sevenzip.NewWriter(f, table.opts...)
+
+		for _, file := range files {
+			fw, err := w.Create(file.Name)
+			require.NoError(t, err)
+
+			_, err = fw.Write(contents[file.Name])
+			require.NoError(t, err)
+		}
+
+		require.NoError(t, w.Close())
+		require.NoError(t, f.Close())
+
+		m.Archives = append(m.Archives, conformanceArchive{
+			Path:     path,
+			Producer: "self",
+			Codec:    table.codec,
+			Password: table.password,
+			Method:   table.method,
+			Files:    files,
+		})
+	}
+
+	b, err := json.Marshal(m)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "manifest.json"), b, 0o600))
+
+	for _, archive := range readManifest(t, dir).Archives {
+		t.Run(archive.Codec, func(t *testing.T) {
+			t.Parallel()
+
+			checkConformance(t, dir, archive)
+		})
+	}
+}
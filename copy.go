@@ -0,0 +1,139 @@
+package sevenzip
+
+import (
+	"fmt"
+)
+
+// CopyBlock adds block i of src, as listed by [Reader.Blocks], along with
+// every file stored in it, without decoding and compressing it again. The
+// packed streams are copied verbatim so the block keeps its methods,
+// including any encryption with the password of src, and the files keep
+// their names, times, attributes and CRCs. Any file being written is
+// finished first and the block it's in is ended, so later files go into a
+// new block.
+//
+// It fails if src was opened with a name filter.
+func (w *Writer) CopyBlock(src *Reader, i int) error {
+	if err := w.finish(); err != nil {
+		return err
+	}
+
+	if w.closed {
+		return errWriterClosed
+	}
+
+	if src.opts.nameFilter != nil {
+		return errFiltered
+	}
+
+	if src.si == nil || src.si.unpackInfo == nil || i < 0 || i >= len(src.si.unpackInfo.folder) {
+		return fmt.Errorf("sevenzip: block %d: %w", i, errNoBlock)
+	}
+
+	var files []FileHeader
+
+	for j, f := range src.File {
+		if f.isMissing {
+			return &ReadError{Err: errMissingUnpackInfo}
+		}
+
+		if f.HasStream() && f.folder == i {
+			files = append(files, src.h.filesInfo.file[j])
+		}
+	}
+
+	if err := w.endBlock(); err != nil {
+		return err
+	}
+
+	if err := w.begin(); err != nil {
+		return err
+	}
+
+	si := src.si
+	f := si.unpackInfo.folder[i]
+	k := si.packedIndex(i)
+
+	for j := range int(f.packedStreams) { //nolint:gosec
+		ps := src.copyStream(k + j)
+
+		if w.err = ps.write(w.data); w.err != nil {
+			return w.err
+		}
+
+		w.packed = append(w.packed, ps.size)
+		w.packedBytes += ps.size
+	}
+
+	var (
+		digest  uint32
+		defined bool
+	)
+
+	if i < len(si.unpackInfo.defined) {
+		digest, defined = si.unpackInfo.digest[i], si.unpackInfo.defined[i]
+	}
+
+	w.folders = append(w.folders, f)
+	w.blockDigests = append(w.blockDigests, digest)
+	w.blockDefined = append(w.blockDefined, defined)
+	w.streams = append(w.streams, uint64(len(files)))
+
+	for _, fh := range files {
+		w.opts.stamp(&fh)
+		w.files = append(w.files, fh)
+		w.sizes = append(w.sizes, fh.UncompressedSize)
+		w.digests = append(w.digests, fh.CRC32)
+		w.defined = append(w.defined, fh.crcDefined)
+		w.written += fh.UncompressedSize
+	}
+
+	return nil
+}
+
+// Copy adds every file in src, in the same order, by copying each of its
+// blocks with [Writer.CopyBlock] and adding the directories and empty files
+// as they are. This can be used to merge archives, or with [CreateVolumes]
+// to split an archive into volumes differently, without compressing
+// anything again.
+func (w *Writer) Copy(src *Reader) error {
+	if src.opts.nameFilter != nil {
+		return errFiltered
+	}
+
+	copied := make(map[int]bool)
+
+	for j, f := range src.File {
+		if f.isMissing {
+			return &ReadError{Err: errMissingUnpackInfo}
+		}
+
+		if f.HasStream() {
+			if copied[f.folder] {
+				continue
+			}
+
+			if err := w.CopyBlock(src, f.folder); err != nil {
+				return err
+			}
+
+			copied[f.folder] = true
+
+			continue
+		}
+
+		if err := w.finish(); err != nil {
+			return err
+		}
+
+		if w.closed {
+			return errWriterClosed
+		}
+
+		fh := src.h.filesInfo.file[j]
+		w.opts.stamp(&fh)
+		w.files = append(w.files, fh)
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package sevenzip_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriterCopy(t *testing.T) {
+	t.Parallel()
+
+	tables := []struct {
+		file     string
+		password string
+	}{
+		{file: "copy.7z"},
+		{file: "lzma2.7z"},
+		{file: "bcj2.7z"},
+		{file: "delta.7z"},
+		{file: "empty.7z"},
+		{file: "empty2.7z"},
+		{file: "file_and_empty.7z"},
+		{file: "t1.7z"},
+		{file: "sfx.exe"},
+		{file: "multi.7z.001"},
+		{file: "aes7z.7z", password: "password"},
+	}
+
+	for _, table := range tables {
+		t.Run(table.file, func(t *testing.T) {
+			t.Parallel()
+
+			src, err := sevenzip.OpenReaderWithPassword(filepath.Join("testdata", table.file), table.password)
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				require.NoError(t, src.Close())
+			})
+
+			buf := new(bytes.Buffer)
+			w := sevenzip.NewWriter(buf)
+
+			require.NoError(t, w.Copy(&src.Reader))
+			require.NoError(t, w.Close())
+
+			r, err := sevenzip.NewReaderWithPassword(bytes.NewReader(buf.Bytes()), int64(buf.Len()), table.password)
+			require.NoError(t, err)
+
+			require.Len(t, r.File, len(src.File))
+
+			for i, f := range r.File {
+				assert.Equal(t, src.File[i].Name, f.Name)
+				assert.Equal(t, src.File[i].CRC32, f.CRC32, f.Name)
+				assert.Equal(t, src.File[i].UncompressedSize, f.UncompressedSize, f.Name)
+				assert.Equal(t, src.File[i].Modified, f.Modified, f.Name)
+				assert.Equal(t, src.File[i].Attributes, f.Attributes, f.Name)
+			}
+
+			// Blocks without any files aren't copied
+			want := slices.DeleteFunc(src.Blocks(), func(b sevenzip.Block) bool { return b.NumFiles == 0 })
+			got := r.Blocks()
+			require.Len(t, got, len(want))
+
+			for i := range want {
+				assert.Equal(t, want[i].Coders, got[i].Coders)
+				assert.Equal(t, want[i].BindPairs, got[i].BindPairs)
+				assert.Equal(t, want[i].UnpackSizes, got[i].UnpackSizes)
+				assert.Equal(t, want[i].PackedSize, got[i].PackedSize)
+				assert.Equal(t, want[i].NumFiles, got[i].NumFiles)
+			}
+
+			assert.Equal(t, readAll(t, &src.Reader), readAll(t, r))
+		})
+	}
+}
+
+//nolint:funlen
+func TestWriterCopyBlock(t *testing.T) {
+	t.Parallel()
+
+	src, err := sevenzip.OpenReader(filepath.Join("testdata", "t1.7z"))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, src.Close())
+	})
+
+	blocks := src.Blocks()
+	require.NotEmpty(t, blocks)
+
+	want := make(map[string][]byte)
+
+	for name, b := range readAll(t, &src.Reader) {
+		if f, ok := src.FileByName(name); ok && f.HasStream() && f.Stream == len(blocks)-1 {
+			want[name] = b
+		}
+	}
+
+	name := filepath.Join(t.TempDir(), "merged.7z")
+
+	f, err := os.Create(name)
+	require.NoError(t, err)
+
+	w := sevenzip.NewWriter(f, sevenzip.WithSolidBlockFiles(10))
+
+	fw, err := w.Create("before.txt")
+	require.NoError(t, err)
+
+	_, err = io.WriteString(fw, "before")
+	require.NoError(t, err)
+
+	// The file being written is finished and its block ended
+	require.NoError(t, w.CopyBlock(&src.Reader, len(blocks)-1))
+
+	fw, err = w.Create("after.txt")
+	require.NoError(t, err)
+
+	_, err = io.WriteString(fw, "after")
+	require.NoError(t, err)
+
+	require.ErrorIs(t, w.CopyBlock(&src.Reader, len(blocks)), sevenzip.ErrNoBlock)
+	require.ErrorIs(t, w.CopyBlock(&src.Reader, -1), sevenzip.ErrNoBlock)
+
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+
+	require.ErrorIs(t, w.CopyBlock(&src.Reader, 0), sevenzip.ErrWriterClosed)
+
+	want["before.txt"] = []byte("before")
+	want["after.txt"] = []byte("after")
+
+	// Appending copies whole archives after the existing blocks
+	other, err := sevenzip.OpenReader(filepath.Join("testdata", "bcj2.7z"))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, other.Close())
+	})
+
+	for name, b := range readAll(t, &other.Reader) {
+		want[name] = b
+	}
+
+	wc, err := sevenzip.OpenWriter(name)
+	require.NoError(t, err)
+
+	require.NoError(t, wc.Copy(&other.Reader))
+	require.NoError(t, wc.Close())
+
+	r, err := sevenzip.OpenReader(name)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, r.Close())
+	})
+
+	assert.Equal(t, want, readAll(t, &r.Reader))
+	assert.Len(t, r.Blocks(), 3+len(other.Blocks()))
+	assert.Equal(t, blocks[len(blocks)-1].Coders, r.Blocks()[1].Coders)
+}
+
+func TestWriterCopyFiltered(t *testing.T) {
+	t.Parallel()
+
+	src, err := sevenzip.OpenReaderWithOptions(filepath.Join("testdata", "t1.7z"),
+		sevenzip.WithNameFilter(func(string) bool { return true }))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, src.Close())
+	})
+
+	w := sevenzip.NewWriter(io.Discard)
+
+	require.ErrorIs(t, w.CopyBlock(&src.Reader, 0), sevenzip.ErrFiltered)
+	require.ErrorIs(t, w.Copy(&src.Reader), sevenzip.ErrFiltered)
+}
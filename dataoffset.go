@@ -0,0 +1,60 @@
+package sevenzip
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNotStored is returned by [File.DataOffset] when the contents of the file
+// aren't stored as is in the archive, such as when they are compressed,
+// encrypted or filtered.
+var ErrNotStored = errors.New("sevenzip: file is not stored")
+
+var errNoStream = errors.New("sevenzip: file has no stream")
+
+// DataOffset returns the offset of the file's contents from the start of the
+// archive, including any [Reader.BaseOffset], along with the index of the
+// volume holding the first byte, similar to [zip.File.DataOffset]. For
+// archives split into volumes the offset is from the start of the first
+// volume, as if they were joined together. The contents are UncompressedSize
+// bytes long and may continue into the following volumes.
+//
+// It fails with an error wrapping [ErrNotStored] if the block holding the
+// file uses anything other than the Copy method, as the bytes can then only
+// be read with [File.Open]. Files in a solid block are decoded together so
+// they fail the same way unless the block is stored, in which case each
+// file's bytes are still contiguous. Directories, empty files and files whose
+// contents are missing have no offset and fail too.
+func (f *File) DataOffset() (offset int64, volume int, err error) {
+	if !f.HasStream() {
+		return 0, 0, fmt.Errorf("sevenzip: %s: %w", f.Name, errNoStream)
+	}
+
+	if coders := f.Coders(); len(coders) != 1 || coders[0].Name() != "Copy" {
+		names := make([]string, len(coders))
+		for i, c := range coders {
+			names[i] = c.Name()
+		}
+
+		return 0, 0, fmt.Errorf("%w: %s uses %s", ErrNotStored, f.Name, strings.Join(names, ", "))
+	}
+
+	si := f.zip.si
+	offset = f.zip.start + si.packedOffset(si.packedIndex(f.folder)) + f.offset
+
+	return offset, f.zip.volume(offset), nil
+}
+
+// volume returns the index of the volume holding offset.
+func (z *Reader) volume(offset int64) int {
+	for i, size := range z.volumes {
+		if offset < size {
+			return i
+		}
+
+		offset -= size
+	}
+
+	return 0
+}
@@ -0,0 +1,115 @@
+package sevenzip_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataOffset(t *testing.T) {
+	t.Parallel()
+
+	archive, err := os.ReadFile(filepath.Join("testdata", "copy.7z"))
+	require.NoError(t, err)
+
+	const volumeSize = 4096
+
+	// Split the archive into volumes
+	fs := afero.NewMemMapFs()
+
+	for i := 0; i*volumeSize < len(archive); i++ {
+		volume := archive[i*volumeSize : min((i+1)*volumeSize, len(archive))]
+		require.NoError(t, afero.WriteFile(fs, fmt.Sprintf("copy.7z.%03d", i+1), volume, 0o644))
+	}
+
+	tables := []struct {
+		name   string
+		open   func() (*sevenzip.ReadCloser, error)
+		volume func(int64) int
+	}{
+		{
+			name: "single",
+			open: func() (*sevenzip.ReadCloser, error) {
+				return sevenzip.OpenReader(filepath.Join("testdata", "copy.7z"))
+			},
+			volume: func(int64) int { return 0 },
+		},
+		{
+			name: "volumes",
+			open: func() (*sevenzip.ReadCloser, error) {
+				return sevenzip.OpenReader("copy.7z.001", fs)
+			},
+			volume: func(offset int64) int { return int(offset / volumeSize) },
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := table.open()
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				require.NoError(t, r.Close())
+			})
+
+			var stored int
+
+			for _, f := range r.File {
+				offset, volume, err := f.DataOffset()
+				if !f.HasStream() {
+					assert.Error(t, err, f.Name)
+
+					continue
+				}
+
+				require.NoError(t, err, f.Name)
+
+				stored++
+
+				assert.Equal(t, table.volume(offset), volume, f.Name)
+
+				rc, err := f.Open()
+				require.NoError(t, err)
+
+				b, err := io.ReadAll(rc)
+				require.NoError(t, err)
+				require.NoError(t, rc.Close())
+
+				assert.True(t, bytes.Equal(b, archive[offset:offset+int64(f.UncompressedSize)]), f.Name) //nolint:gosec
+			}
+
+			assert.NotZero(t, stored)
+		})
+	}
+}
+
+func TestDataOffsetNotStored(t *testing.T) {
+	t.Parallel()
+
+	r, err := sevenzip.OpenReader(filepath.Join("testdata", "lzma.7z"))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, r.Close())
+	})
+
+	for _, f := range r.File {
+		if !f.HasStream() {
+			continue
+		}
+
+		_, _, err := f.DataOffset()
+		assert.ErrorIs(t, err, sevenzip.ErrNotStored, f.Name)
+		assert.ErrorContains(t, err, "LZMA", f.Name)
+	}
+}
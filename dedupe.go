@@ -0,0 +1,205 @@
+package sevenzip
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+)
+
+type duplicateOptions struct {
+	newHash func() hash.Hash
+}
+
+// A DuplicateOption configures a call to [FindDuplicates].
+type DuplicateOption func(*duplicateOptions)
+
+// WithContentHash makes [FindDuplicates] confirm candidate duplicates by
+// hashing their contents with the hash returned by newHash, such as
+// [crypto/sha256.New], rather than trusting the CRC32 and size alone. Files
+// without a CRC32 are only considered when this is used, and then only
+// compared with others lacking one.
+func WithContentHash(newHash func() hash.Hash) DuplicateOption {
+	return func(o *duplicateOptions) {
+		o.newHash = newHash
+	}
+}
+
+// A FileRef identifies a file in one of the readers passed to
+// [FindDuplicates].
+type FileRef struct {
+	// Reader is the index of the reader containing the file.
+	Reader int
+	File   *File
+}
+
+// A DuplicateSet is a group of files with the same contents.
+type DuplicateSet struct {
+	Size uint64
+	// CRC32 is the CRC32 of the files, from [File.Digest], or 0 if they
+	// don't have one.
+	CRC32 uint32
+	// Hash is the content hash if [WithContentHash] was used.
+	Hash  []byte
+	Files []FileRef
+}
+
+// Savings returns the number of bytes that would be saved by only storing
+// one copy of the file.
+func (d DuplicateSet) Savings() uint64 {
+	return d.Size * uint64(len(d.Files)-1) //nolint:gosec
+}
+
+// A DuplicateReport is the result of [FindDuplicates].
+type DuplicateReport struct {
+	// Sets are ordered by the most savings first.
+	Sets []DuplicateSet
+	// Savings is the total of the savings of every set.
+	Savings uint64
+}
+
+type duplicateKey struct {
+	size uint64
+	crc  uint32
+	ok   bool
+	hash string
+}
+
+// FindDuplicates finds files with the same contents within and across
+// readers, for example to work out how much space consolidating a set of
+// backups would save. By default files are considered the same if they have
+// the same size and CRC32, which only needs the headers. The CRC32 is the one
+// returned by [File.Digest], so files in solid blocks that only have a CRC32
+// for the block are included once the block has been decoded. Directories
+// and empty files are ignored.
+func FindDuplicates(readers []*Reader, opts ...DuplicateOption) (*DuplicateReport, error) {
+	o := new(duplicateOptions)
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	candidates := make(map[duplicateKey][]FileRef)
+
+	for i, z := range readers {
+		for _, f := range z.File {
+			if f.UncompressedSize == 0 || f.FileInfo().IsDir() {
+				continue
+			}
+
+			// Without a CRC32 files of the same size would all match
+			crc, ok := f.Digest()
+			if !ok && o.newHash == nil {
+				continue
+			}
+
+			key := duplicateKey{size: f.UncompressedSize, crc: crc, ok: ok}
+			candidates[key] = append(candidates[key], FileRef{i, f})
+		}
+	}
+
+	if o.newHash != nil {
+		var err error
+		if candidates, err = hashCandidates(candidates, o.newHash); err != nil {
+			return nil, err
+		}
+	}
+
+	report := new(DuplicateReport)
+
+	for key, files := range candidates {
+		if len(files) < 2 { //nolint:mnd
+			continue
+		}
+
+		sort.Slice(files, func(i, j int) bool {
+			if files[i].Reader != files[j].Reader {
+				return files[i].Reader < files[j].Reader
+			}
+
+			return files[i].File.Name < files[j].File.Name
+		})
+
+		set := DuplicateSet{
+			Size:  key.size,
+			CRC32: key.crc,
+			Files: files,
+		}
+
+		if key.hash != "" {
+			set.Hash = []byte(key.hash)
+		}
+
+		report.Sets = append(report.Sets, set)
+		report.Savings += set.Savings()
+	}
+
+	sort.Slice(report.Sets, func(i, j int) bool {
+		a, b := report.Sets[i], report.Sets[j]
+		if a.Savings() != b.Savings() {
+			return a.Savings() > b.Savings()
+		}
+
+		return a.Files[0].File.Name < b.Files[0].File.Name
+	})
+
+	return report, nil
+}
+
+// hashCandidates splits each group of candidates by the hash of their
+// contents. Files are read in archive order so each solid block is only
+// decoded once.
+func hashCandidates(candidates map[duplicateKey][]FileRef, newHash func() hash.Hash) (map[duplicateKey][]FileRef, error) {
+	var files []FileRef
+
+	for _, refs := range candidates {
+		if len(refs) > 1 {
+			files = append(files, refs...)
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		a, b := files[i], files[j]
+
+		switch {
+		case a.Reader != b.Reader:
+			return a.Reader < b.Reader
+		case a.File.folder != b.File.folder:
+			return a.File.folder < b.File.folder
+		default:
+			return a.File.offset < b.File.offset
+		}
+	})
+
+	hashed := make(map[duplicateKey][]FileRef)
+
+	for _, ref := range files {
+		sum, err := hashFile(ref.File, newHash())
+		if err != nil {
+			return nil, err
+		}
+
+		crc, ok := ref.File.Digest()
+		key := duplicateKey{size: ref.File.UncompressedSize, crc: crc, ok: ok, hash: string(sum)}
+		hashed[key] = append(hashed[key], ref)
+	}
+
+	return hashed, nil
+}
+
+func hashFile(f *File, h hash.Hash) (sum []byte, err error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		err = errors.Join(err, rc.Close())
+	}()
+
+	if _, err := io.Copy(h, rc); err != nil {
+		return nil, fmt.Errorf("sevenzip: error hashing %s: %w", f.Name, err)
+	}
+
+	return h.Sum(nil), nil
+}
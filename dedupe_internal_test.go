@@ -0,0 +1,53 @@
+package sevenzip
+
+import (
+	"crypto/sha256"
+	"hash/crc32"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFindDuplicatesNoCRC checks files without a CRC32, which a.txt and
+// c.txt of blockDigestArchive are until their block is decoded, aren't
+// reported as duplicates just because they are the same size.
+func TestFindDuplicatesNoCRC(t *testing.T) {
+	t.Parallel()
+
+	r1, contents := blockDigestArchive(t, 0)
+	r2, _ := blockDigestArchive(t, 0)
+
+	report, err := FindDuplicates([]*Reader{r1, r2})
+	require.NoError(t, err)
+	assert.Empty(t, report.Sets)
+
+	// Hashing tells the files apart, so only the copies in each reader
+	// match
+	report, err = FindDuplicates([]*Reader{r1, r2}, WithContentHash(sha256.New))
+	require.NoError(t, err)
+	require.Len(t, report.Sets, 3)
+
+	for _, set := range report.Sets {
+		require.Len(t, set.Files, 2)
+		assert.Zero(t, set.CRC32)
+		assert.Equal(t, 0, set.Files[0].Reader)
+		assert.Equal(t, 1, set.Files[1].Reader)
+		assert.Equal(t, set.Files[0].File.Name, set.Files[1].File.Name)
+	}
+
+	// Once their blocks have been decoded the files have a CRC32
+	for _, r := range []*Reader{r1, r2} {
+		require.NoError(t, r.verifyFolder(0, ""))
+	}
+
+	report, err = FindDuplicates([]*Reader{r1, r2})
+	require.NoError(t, err)
+	require.Len(t, report.Sets, 3)
+
+	for _, set := range report.Sets {
+		require.Len(t, set.Files, 2)
+		assert.Equal(t, crc32.ChecksumIEEE(contents[set.Files[0].File.Name]), set.CRC32)
+		assert.Equal(t, set.Files[0].File.Name, set.Files[1].File.Name)
+	}
+}
@@ -0,0 +1,78 @@
+package sevenzip_test
+
+import (
+	"crypto/sha256"
+	"path/filepath"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	t.Parallel()
+
+	// t0.7z and t1.7z share two 4 byte files, copy.7z, lzma.7z and
+	// lzma2.7z share ten files so two copies of each can be saved
+	const savings = 2*4 + 2*(3572+3164+3305+3229+3886+3985+3071+3684+4171+3987)
+
+	tables := []struct {
+		name    string
+		opts    []sevenzip.DuplicateOption
+		sets    int
+		savings uint64
+	}{
+		{
+			name:    "crc",
+			sets:    12,
+			savings: savings,
+		},
+		{
+			name:    "content hash",
+			opts:    []sevenzip.DuplicateOption{sevenzip.WithContentHash(sha256.New)},
+			sets:    12,
+			savings: savings,
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			var readers []*sevenzip.Reader
+
+			for _, file := range []string{"t0.7z", "t1.7z", "copy.7z", "lzma.7z", "lzma2.7z"} {
+				r, err := sevenzip.OpenReader(filepath.Join("testdata", file))
+				require.NoError(t, err)
+
+				t.Cleanup(func() {
+					require.NoError(t, r.Close())
+				})
+
+				readers = append(readers, &r.Reader)
+			}
+
+			report, err := sevenzip.FindDuplicates(readers, table.opts...)
+			require.NoError(t, err)
+
+			assert.Len(t, report.Sets, table.sets)
+			assert.Equal(t, table.savings, report.Savings)
+
+			var savings uint64
+
+			for _, set := range report.Sets {
+				assert.Equal(t, table.opts != nil, set.Hash != nil)
+
+				for _, ref := range set.Files {
+					assert.Equal(t, set.Size, ref.File.UncompressedSize)
+					assert.Equal(t, set.CRC32, ref.File.CRC32)
+				}
+
+				savings += set.Savings()
+			}
+
+			assert.Equal(t, report.Savings, savings)
+		})
+	}
+}
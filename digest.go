@@ -0,0 +1,145 @@
+package sevenzip
+
+import (
+	"hash"
+	"hash/crc32"
+	"io"
+	"slices"
+	"sync/atomic"
+)
+
+// fileDigest is the CRC32 of a file worked out while decoding its block,
+// shared by any clones of the Reader.
+type fileDigest struct {
+	crc atomic.Uint32
+	ok  atomic.Bool
+}
+
+// Digest returns the CRC32 of the contents of the file and true, or false if
+// it isn't known. Archives with solid blocks sometimes only store a CRC32
+// for each block rather than each file, leaving [FileHeader.CRC32] unset. In
+// that case the CRC32 of each file is worked out the first time its whole
+// block is decoded, by [Reader.OpenFolder], [Reader.StreamFolderFiles],
+// [Reader.Extract] reading every file of the block to the end or verifying
+// the archive with [WithVerify], and then returned by Digest once the block
+// has been checked against its own CRC32, so later checks and catalogs get
+// one without decoding the block again.
+func (f *File) Digest() (uint32, bool) {
+	if f.crcDefined {
+		return f.CRC32, true
+	}
+
+	if f.digest != nil && f.digest.ok.Load() {
+		return f.digest.crc.Load(), true
+	}
+
+	return 0, false
+}
+
+// setDigests records sums as the CRC32 of each of files lacking one, once
+// the block holding them has been decoded and matched its CRC32.
+func setDigests(files []*File, sums []uint32) {
+	if len(files) != len(sums) {
+		return
+	}
+
+	for i, f := range files {
+		if f.crcDefined || f.digest == nil {
+			continue
+		}
+
+		f.digest.crc.Store(sums[i])
+		f.digest.ok.Store(true)
+	}
+}
+
+// blockDigest works out the CRC32 of each file of a block as
+// [Reader.Extract] decodes them in order, along with that of the block
+// itself, so they can be recorded with setDigests once every file of the
+// block has been decoded and the block matched its CRC32.
+type blockDigest struct {
+	crc   uint32
+	size  int64
+	block hash.Hash32
+	off   int64
+	files []*File
+	sums  []uint32
+}
+
+// newBlockDigest returns a blockDigest for files, the files of one stream
+// being extracted, or nil if they all have a CRC32 already or the block
+// doesn't have one to check against.
+func (z *Reader) newBlockDigest(files []*File) *blockDigest {
+	if len(files) == 0 || !files[0].HasStream() || !slices.ContainsFunc(files, func(f *File) bool {
+		return !f.crcDefined
+	}) {
+		return nil
+	}
+
+	folder := files[0].folder
+	if len(z.si.unpackInfo.defined) == 0 || !z.si.unpackInfo.defined[folder] {
+		return nil
+	}
+
+	return &blockDigest{
+		crc:   z.si.unpackInfo.digest[folder],
+		size:  int64(z.si.unpackInfo.folder[folder].unpackSize()), //nolint:gosec
+		block: crc32.NewIEEE(),
+	}
+}
+
+// digestReader hashes a file as it is decoded.
+type digestReader struct {
+	r     io.Reader
+	h     hash.Hash32
+	block hash.Hash32
+	n     int64
+}
+
+func (dr *digestReader) Read(p []byte) (int, error) {
+	n, err := dr.r.Read(p)
+	dr.h.Write(p[:n])
+	dr.block.Write(p[:n])
+	dr.n += int64(n)
+
+	return n, err //nolint:wrapcheck
+}
+
+// reader returns r, the decoded contents of f, hashing what is read from it
+// if d isn't nil.
+func (d *blockDigest) reader(r io.Reader) io.Reader {
+	if d == nil {
+		return r
+	}
+
+	return &digestReader{r: r, h: crc32.NewIEEE(), block: d.block}
+}
+
+// add records the CRC32 of f once it has been decoded through r. Files must
+// follow on from each other and be read to the end, otherwise nothing is
+// recorded for the block.
+func (d *blockDigest) add(f *File, r io.Reader) {
+	dr, ok := r.(*digestReader)
+	if d == nil || !ok {
+		return
+	}
+
+	if f.offset != d.off || uint64(dr.n) != f.UncompressedSize { //nolint:gosec
+		d.off = -1
+
+		return
+	}
+
+	d.off += dr.n
+	d.files, d.sums = append(d.files, f), append(d.sums, dr.h.Sum32())
+}
+
+// done records the CRC32 of each file if the whole block was decoded and
+// matched its CRC32.
+func (d *blockDigest) done() {
+	if d == nil || d.off != d.size || d.block.Sum32() != d.crc {
+		return
+	}
+
+	setDigests(d.files, d.sums)
+}
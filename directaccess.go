@@ -0,0 +1,90 @@
+package sevenzip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrDirectAccessMismatch is returned by [CompareDirectAccess] when reading a
+// file directly from its offset in the archive doesn't give the same bytes as
+// decoding it.
+var ErrDirectAccessMismatch = errors.New("sevenzip: direct access mismatch")
+
+const compareChunkSize = 32 << 10
+
+// CompareDirectAccess decodes the file with [File.Open] and reads it again
+// straight from the archive at the offset given by [File.DataOffset], which
+// is also checked against the offset returned by
+// [Reader.ListFilesWithOffsets], and reports the first byte that differs.
+// It is a diagnostic for tools and tests that rely on direct access to stored
+// files, so that any archive where the offsets are wrong is caught.
+//
+// It returns nil if both reads match, an error wrapping
+// [ErrDirectAccessMismatch] if they don't, and the error from
+// [File.DataOffset], such as [ErrNotStored], for files that can't be read
+// directly.
+func CompareDirectAccess(f *File) (err error) {
+	offset, _, err := f.DataOffset()
+	if err != nil {
+		return err
+	}
+
+	z := f.zip
+
+	offsets, _ := z.si.folderOffsets()
+	if listed := z.start + offsets[f.folder] + f.offset; listed != offset {
+		return fmt.Errorf("%w: %s is at offset %d but listed at %d", ErrDirectAccessMismatch, f.Name, offset, listed)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err = errors.Join(err, rc.Close())
+	}()
+
+	size := int64(f.UncompressedSize) //nolint:gosec
+	direct := io.NewSectionReader(&closedReaderAt{z.r, z.done}, offset, size)
+
+	decoded, raw := make([]byte, compareChunkSize), make([]byte, compareChunkSize)
+
+	for n := int64(0); n < size; {
+		m := int(min(size-n, compareChunkSize))
+
+		if _, err := io.ReadFull(rc, decoded[:m]); err != nil {
+			return fmt.Errorf("sevenzip: %s: error decoding: %w", f.Name, err)
+		}
+
+		if _, err := io.ReadFull(direct, raw[:m]); err != nil {
+			return fmt.Errorf("%w: %s: error reading at offset %d: %w", ErrDirectAccessMismatch, f.Name, offset+n, err)
+		}
+
+		if i := firstDifference(decoded[:m], raw[:m]); i >= 0 {
+			return fmt.Errorf("%w: %s differs at byte %d, offset %d", ErrDirectAccessMismatch, f.Name, n+int64(i), offset+n+int64(i))
+		}
+
+		n += int64(m)
+	}
+
+	return nil
+}
+
+// firstDifference returns the index of the first byte that differs between a
+// and b, which are the same length, or -1 if they are equal.
+func firstDifference(a, b []byte) int {
+	if bytes.Equal(a, b) {
+		return -1
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,123 @@
+package sevenzip_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareDirectAccess(t *testing.T) {
+	t.Parallel()
+
+	archive, err := os.ReadFile(filepath.Join("testdata", "copy.7z"))
+	require.NoError(t, err)
+
+	const volumeSize = 4096
+
+	fs := afero.NewMemMapFs()
+
+	for i := 0; i*volumeSize < len(archive); i++ {
+		volume := archive[i*volumeSize : min((i+1)*volumeSize, len(archive))]
+		require.NoError(t, afero.WriteFile(fs, fmt.Sprintf("copy.7z.%03d", i+1), volume, 0o644))
+	}
+
+	tables := []struct {
+		name   string
+		open   func() (*sevenzip.ReadCloser, error)
+		stored bool
+	}{
+		{
+			name: "copy.7z",
+			open: func() (*sevenzip.ReadCloser, error) {
+				return sevenzip.OpenReader(filepath.Join("testdata", "copy.7z"))
+			},
+			stored: true,
+		},
+		{
+			name: "copy.7z.001",
+			open: func() (*sevenzip.ReadCloser, error) {
+				return sevenzip.OpenReader("copy.7z.001", fs)
+			},
+			stored: true,
+		},
+		{
+			name: "lzma2.7z",
+			open: func() (*sevenzip.ReadCloser, error) {
+				return sevenzip.OpenReader(filepath.Join("testdata", "lzma2.7z"))
+			},
+		},
+		{
+			name: "aes7z.7z",
+			open: func() (*sevenzip.ReadCloser, error) {
+				return sevenzip.OpenReaderWithPassword(filepath.Join("testdata", "aes7z.7z"), "password")
+			},
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := table.open()
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				require.NoError(t, r.Close())
+			})
+
+			for _, f := range r.File {
+				if !f.HasStream() {
+					continue
+				}
+
+				err := sevenzip.CompareDirectAccess(f)
+				if table.stored {
+					assert.NoError(t, err, f.Name)
+				} else {
+					assert.ErrorIs(t, err, sevenzip.ErrNotStored, f.Name)
+				}
+			}
+		})
+	}
+}
+
+// TestCompareDirectAccessTestdata checks every file in the test archives
+// that can be read directly.
+func TestCompareDirectAccessTestdata(t *testing.T) {
+	t.Parallel()
+
+	names, err := filepath.Glob(filepath.Join("testdata", "*.7z"))
+	require.NoError(t, err)
+
+	for _, name := range names {
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			t.Parallel()
+
+			r, err := sevenzip.OpenReaderWithPassword(name, "password")
+			if err != nil {
+				t.Skip(err)
+			}
+
+			t.Cleanup(func() {
+				require.NoError(t, r.Close())
+			})
+
+			for _, f := range r.File {
+				if !f.HasStream() {
+					continue
+				}
+
+				if err := sevenzip.CompareDirectAccess(f); !errors.Is(err, sevenzip.ErrNotStored) {
+					assert.NoError(t, err, f.Name)
+				}
+			}
+		})
+	}
+}
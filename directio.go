@@ -0,0 +1,118 @@
+package sevenzip
+
+import (
+	"errors"
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Direct I/O needs buffers, offsets and lengths aligned to the logical block
+// size of the device, 4 KiB covers almost every modern disk.
+const (
+	directAlignment  = 4 << 10
+	directBufferSize = 1 << 20
+)
+
+func alignedBuffer(size int) []byte {
+	b := make([]byte, size+directAlignment)
+
+	off := 0
+	if r := int(uintptr(unsafe.Pointer(&b[0])) & (directAlignment - 1)); r != 0 { //nolint:gosec
+		off = directAlignment - r
+	}
+
+	return b[off : off+size]
+}
+
+// directWriter writes to a file opened with O_DIRECT in aligned blocks. Any
+// unaligned tail is written through a second, buffered, handle when closed.
+type directWriter struct {
+	f    *os.File
+	path string
+	buf  []byte
+	n    int
+	off  int64
+}
+
+// openDirect opens path for writing with O_DIRECT, creating it with mode,
+// returning nil without an error if direct I/O isn't available on this
+// platform or filesystem.
+func openDirect(path string, mode iofs.FileMode) (*directWriter, error) {
+	if oDirect == 0 {
+		return nil, nil //nolint:nilnil
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC|oDirect, mode)
+	if err != nil {
+		// Not every filesystem supports O_DIRECT, in which case fall
+		// back to a normal file rather than failing
+		if errors.Is(err, syscall.EINVAL) {
+			return nil, nil //nolint:nilnil
+		}
+
+		return nil, fmt.Errorf("sevenzip: error creating file: %w", err)
+	}
+
+	return &directWriter{
+		f:    f,
+		path: path,
+		buf:  alignedBuffer(directBufferSize),
+	}, nil
+}
+
+func (w *directWriter) flush() error {
+	if _, err := w.f.WriteAt(w.buf[:w.n], w.off); err != nil {
+		return fmt.Errorf("sevenzip: error writing %s: %w", w.path, err)
+	}
+
+	w.off += int64(w.n)
+	w.n = 0
+
+	return nil
+}
+
+func (w *directWriter) Write(p []byte) (int, error) {
+	written := 0
+
+	for len(p) > 0 {
+		c := copy(w.buf[w.n:], p)
+		w.n += c
+		written += c
+		p = p[c:]
+
+		if w.n == len(w.buf) {
+			if err := w.flush(); err != nil {
+				return written, err
+			}
+		}
+	}
+
+	return written, nil
+}
+
+// Close writes any remaining data and closes the file.
+func (w *directWriter) Close() error {
+	tail := w.buf[w.n&^(directAlignment-1) : w.n]
+	w.n -= len(tail)
+
+	err := w.flush()
+	err = errors.Join(err, w.f.Close())
+
+	if err != nil || len(tail) == 0 {
+		return err
+	}
+
+	f, err := os.OpenFile(w.path, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("sevenzip: error opening %s: %w", w.path, err)
+	}
+
+	if _, err := f.WriteAt(tail, w.off); err != nil {
+		return errors.Join(fmt.Errorf("sevenzip: error writing %s: %w", w.path, err), f.Close())
+	}
+
+	return f.Close() //nolint:wrapcheck
+}
@@ -0,0 +1,20 @@
+package sevenzip
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+const oDirect = syscall.O_DIRECT
+
+// preallocate reserves size bytes for f with fallocate(2). Filesystems that
+// don't support it are silently ignored as it's only an optimisation.
+func preallocate(f *os.File, size int64) error {
+	err := syscall.Fallocate(int(f.Fd()), 0, 0, size) //nolint:gosec
+	if errors.Is(err, syscall.EOPNOTSUPP) || errors.Is(err, syscall.ENOSYS) {
+		return nil
+	}
+
+	return err //nolint:wrapcheck
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package sevenzip
+
+import "os"
+
+// Direct I/O is only supported on Linux.
+const oDirect = 0
+
+func preallocate(_ *os.File, _ int64) error {
+	return nil
+}
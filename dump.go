@@ -0,0 +1,1111 @@
+package sevenzip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/bodgit/windows"
+)
+
+// dumpBytes is how many bytes of an opaque value are shown before it's cut
+// short.
+const dumpBytes = 32
+
+//nolint:gochecknoglobals
+var propertyNames = []string{
+	idEnd:                   "End",
+	idHeader:                "Header",
+	idArchiveProperties:     "ArchiveProperties",
+	idAdditionalStreamsInfo: "AdditionalStreamsInfo",
+	idMainStreamsInfo:       "MainStreamsInfo",
+	idFilesInfo:             "FilesInfo",
+	idPackInfo:              "PackInfo",
+	idUnpackInfo:            "UnpackInfo",
+	idSubStreamsInfo:        "SubStreamsInfo",
+	idSize:                  "Size",
+	idCRC:                   "CRC",
+	idFolder:                "Folder",
+	idCodersUnpackSize:      "CodersUnpackSize",
+	idNumUnpackStream:       "NumUnpackStream",
+	idEmptyStream:           "EmptyStream",
+	idEmptyFile:             "EmptyFile",
+	idAnti:                  "Anti",
+	idName:                  "Name",
+	idCTime:                 "CTime",
+	idATime:                 "ATime",
+	idMTime:                 "MTime",
+	idWinAttributes:         "WinAttributes",
+	idComment:               "Comment",
+	idEncodedHeader:         "EncodedHeader",
+	idStartPos:              "StartPos",
+	idDummy:                 "Dummy",
+}
+
+func propertyName(id byte) string {
+	if int(id) < len(propertyNames) {
+		return propertyNames[id]
+	}
+
+	return "Unknown"
+}
+
+// DumpHeader writes an annotated dump of the headers of the archive in r to
+// w, for working out why an archive can't be opened. Every property is
+// listed with its ID and offset, indented under the property containing it,
+// along with the sizes, vectors and values it holds. Encoded headers, and
+// any additional streams holding external properties, are decoded and
+// dumped in turn, which needs [WithPassword] if they are encrypted. The
+// signature is searched for the same as [NewReaderWithOptions], or set with
+// [WithBaseOffset].
+//
+// The dump stops at the first problem, with the error that caused it
+// returned and also written to w, so everything up to that point is shown.
+func DumpHeader(r io.ReaderAt, size int64, w io.Writer, opts ...ReaderOption) error {
+	z := &Reader{opts: newReaderOptions(opts), r: r, size: size, done: make(chan struct{})}
+	defer close(z.done)
+
+	d := &dumper{w: w, z: z}
+
+	err := d.archive()
+	if err != nil {
+		d.printf("error: %v", err)
+	}
+
+	if err = errors.Join(err, d.err); err != nil {
+		return fmt.Errorf("sevenzip: error dumping header: %w", err)
+	}
+
+	return nil
+}
+
+// dumper walks the bytes of a header in the same way as readHeader, writing
+// out each property as it goes rather than building the header.
+type dumper struct {
+	w     io.Writer
+	z     *Reader
+	b     []byte
+	r     *bytes.Reader
+	base  int64
+	depth int
+	data  [][]byte
+	err   error
+
+	// redact, if set, collects what's needed for a diagnostic bundle
+	redact *redactor
+}
+
+// sub returns a dumper for b, which starts at offset base, that shares the
+// output and depth of d.
+func (d *dumper) sub(b []byte, base int64) *dumper {
+	return &dumper{w: d.w, z: d.z, b: b, r: bytes.NewReader(b), base: base, depth: d.depth, data: d.data, redact: d.redact}
+}
+
+func (d *dumper) printf(format string, a ...any) {
+	if d.err != nil {
+		return
+	}
+
+	_, d.err = fmt.Fprintf(d.w, strings.Repeat("  ", d.depth)+format+"\n", a...)
+}
+
+func (d *dumper) indent() func() {
+	d.depth++
+
+	return func() {
+		d.depth--
+	}
+}
+
+// pos returns the offset of the next byte to be read.
+func (d *dumper) pos() int64 {
+	return d.base + int64(len(d.b)-d.r.Len())
+}
+
+func (d *dumper) id() (byte, error) {
+	off := d.pos()
+
+	id, err := d.r.ReadByte()
+	if err != nil {
+		return 0, fmt.Errorf("reading property id at %#x: %w", off, err)
+	}
+
+	d.printf("[%#06x] %#02x %s", off, id, propertyName(id))
+
+	return id, nil
+}
+
+func (d *dumper) number(name string) (uint64, error) {
+	v, err := readUint64(d.r)
+	if err != nil {
+		return 0, err
+	}
+
+	d.printf("%s: %d", name, v)
+
+	return v, nil
+}
+
+func (d *dumper) numbers(name string, count uint64) ([]uint64, error) {
+	if count > uint64(d.r.Len()) {
+		return nil, errIncompleteRead
+	}
+
+	v, err := readSizes(d.r, count)
+	if err != nil {
+		return nil, err
+	}
+
+	d.printf("%s: %v", name, v)
+
+	return v, nil
+}
+
+func (d *dumper) bytes(name string, n uint64) ([]byte, error) {
+	if n > uint64(d.r.Len()) {
+		return nil, errIncompleteRead
+	}
+
+	b := make([]byte, n)
+	_, _ = d.r.Read(b)
+
+	d.printf("%s: %s", name, dumpHex(b))
+
+	return b, nil
+}
+
+func (d *dumper) vector(name string, count uint64) ([]bool, error) {
+	if count > 8*uint64(d.r.Len()) {
+		return nil, errIncompleteRead
+	}
+
+	v, err := readBool(d.r, count)
+	if err != nil {
+		return nil, err
+	}
+
+	d.printf("%s: %s", name, dumpBits(v))
+
+	return v, nil
+}
+
+// defined reads the vector of which items of a property are present,
+// returning nil if they all are.
+func (d *dumper) defined(count uint64) ([]bool, error) {
+	all, err := d.r.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("reading all defined: %w", err)
+	}
+
+	d.printf("AllDefined: %t", all != 0)
+
+	if all != 0 {
+		return nil, nil
+	}
+
+	return d.vector("Defined", count)
+}
+
+// external reads the external flag of a property and returns the dumper to
+// read the rest of it from, which is d itself when it's stored inline.
+func (d *dumper) external() (*dumper, error) {
+	external, err := d.r.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("reading external: %w", err)
+	}
+
+	if external == 0 {
+		return d, nil
+	}
+
+	index, err := readUint64(d.r)
+	if err != nil {
+		return nil, err
+	}
+
+	d.printf("External: additional stream %d", index)
+
+	if index >= uint64(len(d.data)) {
+		return nil, errExternal
+	}
+
+	return d.sub(d.data[index], 0), nil
+}
+
+func (d *dumper) digests(count uint64) error {
+	defined, err := d.defined(count)
+	if err != nil {
+		return err
+	}
+
+	var crcs []string
+
+	for i := range count {
+		if defined != nil && !defined[i] {
+			crcs = append(crcs, "-")
+
+			continue
+		}
+
+		var crc uint32
+		if err := binary.Read(d.r, binary.LittleEndian, &crc); err != nil {
+			return fmt.Errorf("reading CRC: %w", err)
+		}
+
+		crcs = append(crcs, fmt.Sprintf("%08x", crc))
+	}
+
+	d.printf("CRCs: [%s]", strings.Join(crcs, " "))
+
+	return nil
+}
+
+func (d *dumper) end(id byte) error {
+	if id != idEnd {
+		return fmt.Errorf("%w: %#02x", errUnexpectedID, id)
+	}
+
+	return nil
+}
+
+// archive dumps the signature header and then the header it points to.
+func (d *dumper) archive() error {
+	signature := []byte{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c}
+
+	offsets := []int64{d.z.opts.baseOffset}
+	if d.z.opts.baseOffset < 0 {
+		var err error
+		if offsets, err = findSignature(d.z.r, signature, d.z.opts.searchLimit); err != nil {
+			return err
+		}
+
+		offsets = rankSignatures(d.z.r, offsets, d.z.size)
+	}
+
+	if len(offsets) == 0 {
+		return errFormat
+	}
+
+	var (
+		sh    signatureHeader
+		start startHeader
+		crc   uint32
+		found bool
+	)
+
+	// Use the first signature with a valid start header, or the first
+	// that could be read if there isn't one, so its problems are shown
+	for _, off := range offsets {
+		var b [32]byte
+		if _, err := d.z.r.ReadAt(b[:], off); err != nil {
+			continue
+		}
+
+		valid := bytes.Equal(b[:6], signature) && crc32.ChecksumIEEE(b[12:]) == binary.LittleEndian.Uint32(b[8:])
+		if found && !valid {
+			continue
+		}
+
+		_ = binary.Read(bytes.NewReader(b[:12]), binary.LittleEndian, &sh)
+		_ = binary.Read(bytes.NewReader(b[12:]), binary.LittleEndian, &start)
+		crc, found = crc32.ChecksumIEEE(b[12:]), true
+		d.z.base = off
+
+		if valid {
+			break
+		}
+	}
+
+	if !found {
+		return errFormat
+	}
+
+	d.printf("SignatureHeader at %#x", d.z.base)
+	done := d.indent()
+	d.printf("Signature: %x", sh.Signature)
+	d.printf("Version: %d.%d", sh.Major, sh.Minor)
+	d.printf("StartHeaderCRC: %08x%s", sh.CRC, dumpCheck(sh.CRC, crc))
+	d.printf("NextHeaderOffset: %d", start.Offset)
+	d.printf("NextHeaderSize: %d", start.Size)
+	d.printf("NextHeaderCRC: %08x", start.CRC)
+	done()
+
+	if !bytes.Equal(sh.Signature[:], signature) {
+		return errFormat
+	}
+
+	d.z.version = [2]byte{sh.Major, sh.Minor}
+	d.z.p = d.z.opts.password
+	d.z.start = d.z.base + 32                 //nolint:mnd
+	d.z.end = d.z.start + int64(start.Offset) //nolint:gosec
+
+	if d.z.end < d.z.start || d.z.end > d.z.size || start.Size > uint64(d.z.size-d.z.end) { //nolint:gosec
+		return ErrTruncated
+	}
+
+	if err := d.z.checkHeaderSize(start.Size); err != nil {
+		return err
+	}
+
+	b := make([]byte, start.Size)
+	if _, err := d.z.r.ReadAt(b, d.z.end); err != nil {
+		return fmt.Errorf("reading header: %w", err)
+	}
+
+	d.printf("Header at %#x, %d bytes, CRC %08x%s", d.z.end, len(b), start.CRC, dumpCheck(start.CRC, crc32.ChecksumIEEE(b)))
+
+	return d.sub(b, 0).top(0)
+}
+
+// top dumps a header, or an encoded header followed by what it decodes to.
+// Offsets are relative to the start of the header.
+func (d *dumper) top(level int) error {
+	if level == maxHeaderDepth {
+		return errHeaderDepth
+	}
+
+	done := d.indent()
+	defer done()
+
+	id, err := d.id()
+	if err != nil {
+		return err
+	}
+
+	if d.redact != nil {
+		d.redact.header = d.b
+	}
+
+	switch id {
+	case idHeader:
+		err = d.header()
+	case idEncodedHeader:
+		err = d.encodedHeader(level)
+	default:
+		err = fmt.Errorf("%w: %#02x", errUnexpectedID, id)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if n := d.r.Len(); n > 0 {
+		d.printf("Trailing: %d bytes", n)
+	}
+
+	return nil
+}
+
+func (d *dumper) encodedHeader(level int) error {
+	off := len(d.b) - d.r.Len()
+
+	if err := d.streamsInfo(); err != nil {
+		return err
+	}
+
+	si, err := readStreamsInfo(bytes.NewReader(d.b[off:]), nil)
+	if err != nil {
+		return err
+	}
+
+	if si.Folders() != 1 {
+		return errOneHeaderStream
+	}
+
+	if err := d.z.checkHeaderSize(si.unpackInfo.folder[0].unpackSize()); err != nil {
+		return err
+	}
+
+	data, err := d.z.decodeStreams(si)
+	if err != nil {
+		return err
+	}
+
+	d.printf("Decoded header, %d bytes", len(data[0]))
+
+	return d.sub(data[0], 0).top(level + 1)
+}
+
+//nolint:cyclop
+func (d *dumper) header() error {
+	done := d.indent()
+	defer done()
+
+	id, err := d.id()
+	if err != nil {
+		return err
+	}
+
+	if id == idArchiveProperties {
+		if err := d.archiveProperties(); err != nil {
+			return err
+		}
+
+		if id, err = d.id(); err != nil {
+			return err
+		}
+	}
+
+	if id == idAdditionalStreamsInfo {
+		if err := d.additionalStreams(); err != nil {
+			return err
+		}
+
+		if id, err = d.id(); err != nil {
+			return err
+		}
+	}
+
+	if id == idMainStreamsInfo {
+		if err := d.streamsInfo(); err != nil {
+			return err
+		}
+
+		if id, err = d.id(); err != nil {
+			return err
+		}
+	}
+
+	if id == idFilesInfo {
+		if err := d.filesInfo(); err != nil {
+			return err
+		}
+
+		if id, err = d.id(); err != nil {
+			return err
+		}
+	}
+
+	return d.end(id)
+}
+
+func (d *dumper) archiveProperties() error {
+	done := d.indent()
+	defer done()
+
+	for {
+		property, err := d.r.ReadByte()
+		if err != nil {
+			return fmt.Errorf("reading archive property: %w", err)
+		}
+
+		if property == idEnd {
+			break
+		}
+
+		length, err := readUint64(d.r)
+		if err != nil {
+			return err
+		}
+
+		if _, err := d.bytes(fmt.Sprintf("Property %#02x", property), length); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// additionalStreams dumps the streams holding external properties and then
+// decodes them so the properties can be dumped where they're used.
+func (d *dumper) additionalStreams() error {
+	off := len(d.b) - d.r.Len()
+
+	if err := d.streamsInfo(); err != nil {
+		return err
+	}
+
+	si, err := readStreamsInfo(bytes.NewReader(d.b[off:]), nil)
+	if err != nil {
+		return err
+	}
+
+	if d.data, err = d.z.decodeStreams(si); err != nil {
+		return err
+	}
+
+	sizes := make([]int, len(d.data))
+	for i, b := range d.data {
+		sizes[i] = len(b)
+	}
+
+	done := d.indent()
+	d.printf("Decoded additional streams, sizes %v", sizes)
+	done()
+
+	return nil
+}
+
+//nolint:cyclop
+func (d *dumper) streamsInfo() error {
+	done := d.indent()
+	defer done()
+
+	id, err := d.id()
+	if err != nil {
+		return err
+	}
+
+	if id == idPackInfo {
+		if err := d.packInfo(); err != nil {
+			return err
+		}
+
+		if id, err = d.id(); err != nil {
+			return err
+		}
+	}
+
+	var folders uint64
+
+	if id == idUnpackInfo {
+		if folders, err = d.unpackInfo(); err != nil {
+			return err
+		}
+
+		if id, err = d.id(); err != nil {
+			return err
+		}
+	}
+
+	if id == idSubStreamsInfo {
+		if err := d.subStreamsInfo(folders); err != nil {
+			return err
+		}
+
+		if id, err = d.id(); err != nil {
+			return err
+		}
+	}
+
+	return d.end(id)
+}
+
+func (d *dumper) packInfo() error {
+	done := d.indent()
+	defer done()
+
+	if _, err := d.number("PackPos"); err != nil {
+		return err
+	}
+
+	streams, err := d.number("NumPackStreams")
+	if err != nil {
+		return err
+	}
+
+	id, err := d.id()
+	if err != nil {
+		return err
+	}
+
+	if id == idSize {
+		done := d.indent()
+		_, err := d.numbers("Sizes", streams)
+
+		done()
+
+		if err != nil {
+			return err
+		}
+
+		if id, err = d.id(); err != nil {
+			return err
+		}
+	}
+
+	if id == idCRC {
+		done := d.indent()
+		err := d.digests(streams)
+
+		done()
+
+		if err != nil {
+			return err
+		}
+
+		if id, err = d.id(); err != nil {
+			return err
+		}
+	}
+
+	return d.end(id)
+}
+
+// unpackInfo dumps the folders and returns how many there are.
+//
+//nolint:cyclop,funlen
+func (d *dumper) unpackInfo() (uint64, error) {
+	done := d.indent()
+	defer done()
+
+	id, err := d.id()
+	if err != nil {
+		return 0, err
+	}
+
+	if id != idFolder {
+		return 0, fmt.Errorf("%w: %#02x", errUnexpectedID, id)
+	}
+
+	indent := d.indent()
+
+	folders, err := d.number("NumFolders")
+	if err != nil {
+		return 0, err
+	}
+
+	fd, err := d.external()
+	if err != nil {
+		return 0, err
+	}
+
+	outs := make([]uint64, 0, min(folders, uint64(d.r.Len())))
+
+	for i := range folders {
+		fd.printf("Folder %d", i)
+
+		fd.depth++
+		out, err := fd.folder()
+		fd.depth--
+
+		if err != nil {
+			return 0, err
+		}
+
+		outs = append(outs, out)
+	}
+
+	indent()
+
+	if id, err = d.id(); err != nil {
+		return 0, err
+	}
+
+	if id != idCodersUnpackSize {
+		return 0, fmt.Errorf("%w: %#02x", errUnexpectedID, id)
+	}
+
+	indent = d.indent()
+
+	for i, out := range outs {
+		if _, err := d.numbers(fmt.Sprintf("Folder %d", i), out); err != nil {
+			return 0, err
+		}
+	}
+
+	indent()
+
+	if id, err = d.id(); err != nil {
+		return 0, err
+	}
+
+	if id == idCRC {
+		indent := d.indent()
+		err := d.digests(folders)
+
+		indent()
+
+		if err != nil {
+			return 0, err
+		}
+
+		if id, err = d.id(); err != nil {
+			return 0, err
+		}
+	}
+
+	return folders, d.end(id)
+}
+
+// folder dumps a folder and returns the number of output streams of its
+// coders.
+//
+//nolint:cyclop,funlen
+func (d *dumper) folder() (uint64, error) {
+	coders, err := d.number("NumCoders")
+	if err != nil {
+		return 0, err
+	}
+
+	var in, out uint64
+
+	for i := range coders {
+		d.printf("Coder %d", i)
+		done := d.indent()
+
+		flags, err := d.r.ReadByte()
+		if err != nil {
+			return 0, fmt.Errorf("reading coder flags: %w", err)
+		}
+
+		d.printf("Flags: %#02x", flags)
+
+		id, err := d.bytes("ID", uint64(flags&0xf))
+		if err != nil {
+			return 0, err
+		}
+
+		d.printf("Method: %s", Coder{ID: id}.Name())
+
+		cin, cout := uint64(1), uint64(1)
+
+		if flags&0x10 != 0 {
+			if cin, err = d.number("NumInStreams"); err != nil {
+				return 0, err
+			}
+
+			if cout, err = d.number("NumOutStreams"); err != nil {
+				return 0, err
+			}
+		}
+
+		if flags&0x20 != 0 {
+			size, err := d.number("PropertiesSize")
+			if err != nil {
+				return 0, err
+			}
+
+			if _, err := d.bytes("Properties", size); err != nil {
+				return 0, err
+			}
+		}
+
+		done()
+
+		in += cin
+		out += cout
+	}
+
+	if out == 0 || in+1 < out {
+		return 0, errFormat
+	}
+
+	for i := range out - 1 {
+		pin, err := readUint64(d.r)
+		if err != nil {
+			return 0, err
+		}
+
+		pout, err := readUint64(d.r)
+		if err != nil {
+			return 0, err
+		}
+
+		d.printf("BindPair %d: InIndex %d, OutIndex %d", i, pin, pout)
+	}
+
+	if packed := in - (out - 1); packed > 1 {
+		if _, err := d.numbers("PackedStreams", packed); err != nil {
+			return 0, err
+		}
+	}
+
+	return out, nil
+}
+
+//nolint:cyclop,funlen
+func (d *dumper) subStreamsInfo(folders uint64) error {
+	done := d.indent()
+	defer done()
+
+	id, err := d.id()
+	if err != nil {
+		return err
+	}
+
+	streams := make([]uint64, folders)
+	for i := range streams {
+		streams[i] = 1
+	}
+
+	if id == idNumUnpackStream {
+		indent := d.indent()
+		streams, err = d.numbers("NumUnpackStreams", folders)
+
+		indent()
+
+		if err != nil {
+			return err
+		}
+
+		if id, err = d.id(); err != nil {
+			return err
+		}
+	}
+
+	var files uint64
+	for _, n := range streams {
+		files += n
+	}
+
+	if id == idSize {
+		indent := d.indent()
+
+		for i, n := range streams {
+			if n == 0 {
+				continue
+			}
+
+			if _, err := d.numbers(fmt.Sprintf("Folder %d", i), n-1); err != nil {
+				return err
+			}
+		}
+
+		indent()
+
+		if id, err = d.id(); err != nil {
+			return err
+		}
+	}
+
+	if id == idCRC {
+		indent := d.indent()
+		err := d.digests(files)
+
+		indent()
+
+		if err != nil {
+			return err
+		}
+
+		if id, err = d.id(); err != nil {
+			return err
+		}
+	}
+
+	return d.end(id)
+}
+
+func (d *dumper) filesInfo() error {
+	done := d.indent()
+	defer done()
+
+	files, err := d.number("NumFiles")
+	if err != nil {
+		return err
+	}
+
+	var emptyStreams uint64
+
+	for {
+		property, err := d.id()
+		if err != nil {
+			return err
+		}
+
+		if property == idEnd {
+			return nil
+		}
+
+		length, err := readUint64(d.r)
+		if err != nil {
+			return err
+		}
+
+		if length > uint64(d.r.Len()) {
+			return errIncompleteRead
+		}
+
+		// Each property is dumped from its own bytes so anything
+		// unexpected in one doesn't throw off the rest
+		pd := d.sub(d.b[len(d.b)-d.r.Len():][:length], d.pos())
+		_, _ = d.r.Seek(int64(length), io.SeekCurrent)
+
+		pd.depth++
+		pd.printf("Size: %d", length)
+
+		n, err := pd.fileProperty(property, files, emptyStreams)
+		d.err = pd.err
+
+		if err != nil {
+			return err
+		}
+
+		if property == idEmptyStream {
+			emptyStreams = n
+		}
+	}
+}
+
+// fileProperty dumps a property of the files. For EmptyStream it returns the
+// number of empty streams.
+//
+//nolint:cyclop
+func (d *dumper) fileProperty(property byte, files, emptyStreams uint64) (uint64, error) {
+	switch property {
+	case idEmptyStream:
+		v, err := d.vector("EmptyStream", files)
+
+		var n uint64
+
+		for _, empty := range v {
+			if empty {
+				n++
+			}
+		}
+
+		return n, err
+	case idEmptyFile, idAnti:
+		_, err := d.vector(propertyName(property), emptyStreams)
+
+		return 0, err
+	case idCTime, idATime, idMTime:
+		return 0, d.times(files)
+	case idName:
+		return 0, d.names()
+	case idWinAttributes:
+		return 0, d.attributes(files)
+	default:
+		_, err := d.bytes("Data", uint64(d.r.Len()))
+
+		return 0, err
+	}
+}
+
+func (d *dumper) times(files uint64) error {
+	defined, err := d.defined(files)
+	if err != nil {
+		return err
+	}
+
+	td, err := d.external()
+	if err != nil {
+		return err
+	}
+
+	for i := range files {
+		if defined != nil && !defined[i] {
+			continue
+		}
+
+		var ft windows.Filetime
+		if err := binary.Read(td.r, binary.LittleEndian, &ft); err != nil {
+			return fmt.Errorf("reading time: %w", err)
+		}
+
+		d.printf("%d: %s", i, time.Unix(0, ft.Nanoseconds()).UTC().Format(time.RFC3339Nano))
+	}
+
+	return nil
+}
+
+func (d *dumper) names() error {
+	nd, err := d.external()
+	if err != nil {
+		return err
+	}
+
+	// Names are read in place so any redacted are written back
+	b := nd.b[len(nd.b)-nd.r.Len():]
+	_, _ = nd.r.Seek(0, io.SeekEnd)
+
+	if len(b)%2 != 0 {
+		return errIncompleteRead
+	}
+
+	var (
+		u     []uint16
+		start int
+		n     int
+	)
+
+	for i := 0; i < len(b); i += 2 {
+		c := binary.LittleEndian.Uint16(b[i:])
+		if c != 0 {
+			u = append(u, c)
+
+			continue
+		}
+
+		d.printf("%d: %q", n, d.name(b[start:], u))
+		u, start = u[:0], i+2
+		n++
+	}
+
+	if len(u) > 0 {
+		d.printf("Unterminated: %q", d.name(b[start:], u))
+	}
+
+	return nil
+}
+
+// name returns the name held in u, which was read from the start of b. If
+// names are being redacted the replacement is returned instead, having been
+// written over the original in b.
+func (d *dumper) name(b []byte, u []uint16) string {
+	if d.redact == nil {
+		return string(utf16.Decode(u))
+	}
+
+	u = d.redact.name(u)
+	for i, c := range u {
+		binary.LittleEndian.PutUint16(b[2*i:], c)
+	}
+
+	return string(utf16.Decode(u))
+}
+
+func (d *dumper) attributes(files uint64) error {
+	defined, err := d.defined(files)
+	if err != nil {
+		return err
+	}
+
+	ad, err := d.external()
+	if err != nil {
+		return err
+	}
+
+	for i := range files {
+		if defined != nil && !defined[i] {
+			continue
+		}
+
+		var a uint32
+		if err := binary.Read(ad.r, binary.LittleEndian, &a); err != nil {
+			return fmt.Errorf("reading attributes: %w", err)
+		}
+
+		d.printf("%d: %#08x", i, a)
+	}
+
+	return nil
+}
+
+func dumpHex(b []byte) string {
+	if len(b) <= dumpBytes {
+		return fmt.Sprintf("%x", b)
+	}
+
+	return fmt.Sprintf("%s... (%d bytes)", hex.EncodeToString(b[:dumpBytes]), len(b))
+}
+
+func dumpBits(v []bool) string {
+	var sb strings.Builder
+
+	for _, b := range v {
+		if b {
+			sb.WriteByte('1')
+		} else {
+			sb.WriteByte('0')
+		}
+	}
+
+	return sb.String()
+}
+
+func dumpCheck(want, got uint32) string {
+	if want == got {
+		return " (ok)"
+	}
+
+	return fmt.Sprintf(" (mismatch, computed %08x)", got)
+}
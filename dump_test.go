@@ -0,0 +1,136 @@
+package sevenzip_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpHeader(t *testing.T) {
+	t.Parallel()
+
+	tables := []struct {
+		name string
+		file string
+		opts []sevenzip.ReaderOption
+		want []string
+	}{
+		{
+			name: "encoded header",
+			file: "copy.7z",
+			want: []string{"0x17 EncodedHeader", "Method: LZMA", "Decoded header, 334 bytes", "0x01 Header"},
+		},
+		{
+			name: "external properties",
+			file: "external.7z",
+			want: []string{"0x03 AdditionalStreamsInfo", "External: additional stream 0", "External: additional stream 1"},
+		},
+		{
+			name: "multiple packed streams",
+			file: "bcj2.7z",
+			want: []string{"Method: BCJ2", "NumInStreams: 4", "PackedStreams: [0 1 2 3]"},
+		},
+		{
+			name: "encrypted header",
+			file: "t2.7z",
+			opts: []sevenzip.ReaderOption{sevenzip.WithPassword("password")},
+			want: []string{"Method: 7zAES", "BindPair 0: InIndex 1, OutIndex 0"},
+		},
+		{
+			name: "sfx",
+			file: "sfx.exe",
+			want: []string{"Signature: 377abcaf271c", "0x01 Header"},
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			name := filepath.Join("testdata", table.file)
+
+			b, err := os.ReadFile(name)
+			require.NoError(t, err)
+
+			out := new(bytes.Buffer)
+			require.NoError(t, sevenzip.DumpHeader(bytes.NewReader(b), int64(len(b)), out, table.opts...))
+
+			for _, want := range table.want {
+				assert.Contains(t, out.String(), want)
+			}
+
+			r, err := sevenzip.NewReaderWithOptions(bytes.NewReader(b), int64(len(b)), table.opts...)
+			require.NoError(t, err)
+
+			for _, f := range r.File {
+				assert.Contains(t, out.String(), strconv.Quote(f.Name))
+			}
+		})
+	}
+}
+
+func TestDumpHeaderErrors(t *testing.T) {
+	t.Parallel()
+
+	b, err := os.ReadFile(filepath.Join("testdata", "external.7z"))
+	require.NoError(t, err)
+
+	// Replace the FilesInfo ID, which is 0x44 bytes into the header
+	corrupt := bytes.Clone(b)
+	corrupt[0x64+0x44] = 0x30
+
+	tables := []struct {
+		name string
+		b    []byte
+		opts []sevenzip.ReaderOption
+		want []string
+	}{
+		{
+			name: "unexpected property",
+			b:    corrupt,
+			want: []string{"(mismatch, computed", "0x04 MainStreamsInfo", "[0x000044] 0x30 Unknown", "error: "},
+		},
+		{
+			name: "truncated",
+			b:    b[:len(b)-1],
+			want: []string{"NextHeaderSize: 81", "error: " + sevenzip.ErrTruncated.Error()},
+		},
+		{
+			name: "not an archive",
+			b:    []byte("not an archive"),
+			want: []string{"error: "},
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			out := new(bytes.Buffer)
+			err := sevenzip.DumpHeader(bytes.NewReader(table.b), int64(len(table.b)), out, table.opts...)
+			require.Error(t, err)
+
+			for _, want := range table.want {
+				assert.Contains(t, out.String(), want)
+			}
+		})
+	}
+
+	t.Run("no password", func(t *testing.T) {
+		t.Parallel()
+
+		b, err := os.ReadFile(filepath.Join("testdata", "t2.7z"))
+		require.NoError(t, err)
+
+		out := new(bytes.Buffer)
+		err = sevenzip.DumpHeader(bytes.NewReader(b), int64(len(b)), out)
+		assert.ErrorIs(t, err, sevenzip.ErrPasswordRequired)
+		assert.Contains(t, out.String(), "Method: 7zAES")
+	})
+}
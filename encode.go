@@ -0,0 +1,629 @@
+package sevenzip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/bodgit/plumbing"
+	"github.com/bodgit/windows"
+	"github.com/javi11/sevenzip/internal/aes7z"
+	"github.com/javi11/sevenzip/internal/lzma2"
+	"golang.org/x/text/encoding/unicode"
+)
+
+var errPackedSize = errors.New("sevenzip: packed stream size mismatch")
+
+// The functions in this file are the inverse of those in types.go and
+// serialise the header structures. They write to a bytes.Buffer so can't
+// fail, the whole header is always built in memory before being written out.
+
+//nolint:mnd
+func writeUint64(b *bytes.Buffer, v uint64) {
+	var l int
+	for l = 0; l < 8; l++ {
+		if v < 1<<(7*(l+1)) {
+			break
+		}
+	}
+
+	first := ^byte(0xff >> l)
+	if l < 7 {
+		first |= byte(v >> (8 * l))
+	}
+
+	_ = b.WriteByte(first)
+
+	for i := range l {
+		_ = b.WriteByte(byte(v >> (8 * i)))
+	}
+}
+
+func writeBool(b *bytes.Buffer, values []bool) {
+	var v, mask byte = 0, 0x80
+
+	for _, value := range values {
+		if value {
+			v |= mask
+		}
+
+		if mask >>= 1; mask == 0 {
+			_ = b.WriteByte(v)
+			v, mask = 0, 0x80
+		}
+	}
+
+	if mask != 0x80 {
+		_ = b.WriteByte(v)
+	}
+}
+
+func allTrue(values []bool) bool {
+	for _, v := range values {
+		if !v {
+			return false
+		}
+	}
+
+	return true
+}
+
+func writeOptionalBool(b *bytes.Buffer, values []bool) {
+	if allTrue(values) {
+		_ = b.WriteByte(1)
+
+		return
+	}
+
+	_ = b.WriteByte(0)
+
+	writeBool(b, values)
+}
+
+func writeSizes(b *bytes.Buffer, sizes []uint64) {
+	for _, size := range sizes {
+		writeUint64(b, size)
+	}
+}
+
+// writeCRC writes the digests, only those that are defined having a value.
+func writeCRC(b *bytes.Buffer, crcs []uint32, defined []bool) {
+	writeOptionalBool(b, defined)
+
+	for i, crc := range crcs {
+		if defined[i] {
+			_ = binary.Write(b, binary.LittleEndian, crc)
+		}
+	}
+}
+
+// hasCRC reports whether any digest is defined.
+func hasCRC(defined []bool) bool {
+	return slices.Contains(defined, true)
+}
+
+func writePackInfo(b *bytes.Buffer, p *packInfo) {
+	writeUint64(b, p.position)
+	writeUint64(b, p.streams)
+
+	_ = b.WriteByte(idSize)
+	writeSizes(b, p.size)
+
+	if hasCRC(p.defined) {
+		_ = b.WriteByte(idCRC)
+		writeCRC(b, p.digest, p.defined)
+	}
+
+	_ = b.WriteByte(idEnd)
+}
+
+func writeCoder(b *bytes.Buffer, c *coder) {
+	flags := byte(len(c.id))
+
+	if c.in != 1 || c.out != 1 {
+		flags |= 0x10
+	}
+
+	if len(c.properties) > 0 {
+		flags |= 0x20
+	}
+
+	_ = b.WriteByte(flags)
+	_, _ = b.Write(c.id)
+
+	if c.in != 1 || c.out != 1 {
+		writeUint64(b, c.in)
+		writeUint64(b, c.out)
+	}
+
+	if len(c.properties) > 0 {
+		writeUint64(b, uint64(len(c.properties)))
+		_, _ = b.Write(c.properties)
+	}
+}
+
+func writeFolder(b *bytes.Buffer, f *folder) {
+	writeUint64(b, uint64(len(f.coder)))
+
+	for _, c := range f.coder {
+		writeCoder(b, c)
+	}
+
+	for _, bp := range f.bindPair {
+		writeUint64(b, bp.in)
+		writeUint64(b, bp.out)
+	}
+
+	if f.packedStreams > 1 {
+		writeSizes(b, f.packed)
+	}
+}
+
+func writeUnpackInfo(b *bytes.Buffer, u *unpackInfo) {
+	_ = b.WriteByte(idFolder)
+	writeUint64(b, uint64(len(u.folder)))
+	_ = b.WriteByte(0) // Not external
+
+	for _, f := range u.folder {
+		writeFolder(b, f)
+	}
+
+	_ = b.WriteByte(idCodersUnpackSize)
+
+	for _, f := range u.folder {
+		writeSizes(b, f.size)
+	}
+
+	if hasCRC(u.defined) {
+		_ = b.WriteByte(idCRC)
+		writeCRC(b, u.digest, u.defined)
+	}
+
+	_ = b.WriteByte(idEnd)
+}
+
+func writeSubStreamsInfo(b *bytes.Buffer, s *subStreamsInfo) {
+	multiple := false
+
+	for _, n := range s.streams {
+		if n != 1 {
+			multiple = true
+
+			break
+		}
+	}
+
+	if multiple {
+		_ = b.WriteByte(idNumUnpackStream)
+		writeSizes(b, s.streams)
+	}
+
+	if multiple && s.size != nil {
+		_ = b.WriteByte(idSize)
+
+		k := 0
+
+		for _, n := range s.streams {
+			// The size of the last stream in each folder is implied
+			for j := uint64(1); j < n; j++ {
+				writeUint64(b, s.size[k])
+				k++
+			}
+
+			if n > 0 {
+				k++
+			}
+		}
+	}
+
+	if hasCRC(s.defined) {
+		_ = b.WriteByte(idCRC)
+		writeCRC(b, s.digest, s.defined)
+	}
+
+	_ = b.WriteByte(idEnd)
+}
+
+func writeStreamsInfo(b *bytes.Buffer, s *streamsInfo) {
+	if s.packInfo != nil {
+		_ = b.WriteByte(idPackInfo)
+		writePackInfo(b, s.packInfo)
+	}
+
+	if s.unpackInfo != nil {
+		_ = b.WriteByte(idUnpackInfo)
+		writeUnpackInfo(b, s.unpackInfo)
+	}
+
+	if s.subStreamsInfo != nil {
+		_ = b.WriteByte(idSubStreamsInfo)
+		writeSubStreamsInfo(b, s.subStreamsInfo)
+	}
+
+	_ = b.WriteByte(idEnd)
+}
+
+// writeProperty writes a files info property, prefixed with its id and the
+// length of the data.
+func writeProperty(b *bytes.Buffer, id byte, data []byte) {
+	_ = b.WriteByte(id)
+	writeUint64(b, uint64(len(data)))
+	_, _ = b.Write(data)
+}
+
+func writeTimes(b *bytes.Buffer, id byte, times []time.Time) {
+	defined := make([]bool, len(times))
+	anyDefined := false
+
+	for i, t := range times {
+		defined[i] = !t.IsZero()
+		anyDefined = anyDefined || defined[i]
+	}
+
+	if !anyDefined {
+		return
+	}
+
+	p := new(bytes.Buffer)
+	writeOptionalBool(p, defined)
+	_ = p.WriteByte(0) // Not external
+
+	for i, t := range times {
+		if defined[i] {
+			_ = binary.Write(p, binary.LittleEndian, windows.NsecToFiletime(t.UnixNano()))
+		}
+	}
+
+	writeProperty(b, id, p.Bytes())
+}
+
+// encodeString encodes s as a NUL-terminated UTF-16LE string, as used for
+// names and comments.
+func encodeString(s string) []byte {
+	encoded, _ := unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewEncoder().Bytes([]byte(s))
+
+	return append(encoded, 0, 0)
+}
+
+func writeNames(b *bytes.Buffer, names []string) {
+	p := new(bytes.Buffer)
+	_ = p.WriteByte(0) // Not external
+
+	for _, name := range names {
+		_, _ = p.Write(encodeString(name))
+	}
+
+	writeProperty(b, idName, p.Bytes())
+}
+
+// writeAttributes writes the attributes of every file if any have them.
+// Files without any, such as those copied from an archive that only had
+// attributes for some, get the archive attribute as libarchive rejects
+// archives where only some files have attributes.
+func writeAttributes(b *bytes.Buffer, attributes []uint32) {
+	if !slices.ContainsFunc(attributes, func(a uint32) bool { return a != 0 }) {
+		return
+	}
+
+	p := new(bytes.Buffer)
+	_ = p.WriteByte(1) // All defined
+	_ = p.WriteByte(0) // Not external
+
+	for _, a := range attributes {
+		if a == 0 {
+			a = msdosArchive
+		}
+
+		_ = binary.Write(p, binary.LittleEndian, a)
+	}
+
+	writeProperty(b, idWinAttributes, p.Bytes())
+}
+
+//nolint:cyclop
+func writeFilesInfo(b *bytes.Buffer, f *filesInfo) {
+	writeUint64(b, uint64(len(f.file)))
+
+	var (
+		emptyStream = make([]bool, len(f.file))
+		emptyFile   []bool
+		anyEmpty    bool
+		anyFile     bool
+		names       = make([]string, len(f.file))
+		created     = make([]time.Time, len(f.file))
+		accessed    = make([]time.Time, len(f.file))
+		modified    = make([]time.Time, len(f.file))
+		attributes  = make([]uint32, len(f.file))
+	)
+
+	for i, fh := range f.file {
+		emptyStream[i] = fh.isEmptyStream
+
+		if fh.isEmptyStream {
+			anyEmpty = true
+
+			emptyFile = append(emptyFile, fh.isEmptyFile)
+			anyFile = anyFile || fh.isEmptyFile
+		}
+
+		names[i] = fh.Name
+		created[i], accessed[i], modified[i] = fh.Created, fh.Accessed, fh.Modified
+		attributes[i] = fh.Attributes
+	}
+
+	if anyEmpty {
+		p := new(bytes.Buffer)
+		writeBool(p, emptyStream)
+		writeProperty(b, idEmptyStream, p.Bytes())
+	}
+
+	if anyFile {
+		p := new(bytes.Buffer)
+		writeBool(p, emptyFile)
+		writeProperty(b, idEmptyFile, p.Bytes())
+	}
+
+	if len(f.file) > 0 {
+		writeNames(b, names)
+	}
+
+	writeTimes(b, idCTime, created)
+	writeTimes(b, idATime, accessed)
+	writeTimes(b, idMTime, modified)
+	writeAttributes(b, attributes)
+
+	if f.comment != nil {
+		writeProperty(b, idComment, f.comment)
+	}
+
+	_ = b.WriteByte(idEnd)
+}
+
+func writeHeader(b *bytes.Buffer, h *header) {
+	_ = b.WriteByte(idHeader)
+
+	if len(h.properties) > 0 {
+		_ = b.WriteByte(idArchiveProperties)
+
+		for _, p := range h.properties {
+			writeProperty(b, p.Type, p.Data)
+		}
+
+		_ = b.WriteByte(idEnd)
+	}
+
+	if h.streamsInfo != nil && h.streamsInfo.Folders() > 0 {
+		_ = b.WriteByte(idMainStreamsInfo)
+		writeStreamsInfo(b, h.streamsInfo)
+	}
+
+	if h.filesInfo != nil && len(h.filesInfo.file) > 0 {
+		_ = b.WriteByte(idFilesInfo)
+		writeFilesInfo(b, h.filesInfo)
+	}
+
+	_ = b.WriteByte(idEnd)
+}
+
+// empty reports whether h has no files and no archive properties, so there
+// is nothing to write.
+func (h *header) empty() bool {
+	return len(h.properties) == 0 && (h.filesInfo == nil || len(h.filesInfo.file) == 0) &&
+		(h.streamsInfo == nil || h.streamsInfo.Folders() == 0)
+}
+
+// A packedStream is written verbatim to the packed streams area of a new
+// archive. The size must be known before the archive can be written.
+type packedStream struct {
+	size  uint64
+	write func(w io.Writer) error
+}
+
+type headerOptions struct {
+	compress bool
+	password string    // Encrypt the header if not empty
+	kept     []*header // Earlier headers to write before the header
+}
+
+// encodeHeader compresses and optionally encrypts the raw header, returning
+// the packed stream and the streams info describing it.
+func encodeHeader(raw []byte, position uint64, opts headerOptions) ([]byte, *streamsInfo, error) {
+	var (
+		packed = new(bytes.Buffer)
+		cw     = new(plumbing.WriteCounter)
+		w      io.Writer
+		aw     io.WriteCloser
+		aesp   []byte
+		err    error
+	)
+
+	w = packed
+
+	if opts.password != "" {
+		if aesp, err = aes7z.NewProperties(aes7z.DefaultCycles); err != nil {
+			return nil, nil, fmt.Errorf("sevenzip: error encoding header: %w", err)
+		}
+
+		if aw, err = aes7z.NewWriter(packed, aesp, opts.password); err != nil {
+			return nil, nil, fmt.Errorf("sevenzip: error encoding header: %w", err)
+		}
+
+		w = aw
+	}
+
+	lw, lp, err := lzma2.NewWriter(io.MultiWriter(w, cw), len(raw))
+	if err != nil {
+		return nil, nil, fmt.Errorf("sevenzip: error encoding header: %w", err)
+	}
+
+	if _, err = lw.Write(raw); err != nil {
+		return nil, nil, fmt.Errorf("sevenzip: error encoding header: %w", err)
+	}
+
+	if err = lw.Close(); err != nil {
+		return nil, nil, fmt.Errorf("sevenzip: error encoding header: %w", err)
+	}
+
+	f := &folder{
+		in:            1,
+		out:           1,
+		packedStreams: 1,
+		coder:         []*coder{{id: []byte{0x21}, in: 1, out: 1, properties: lp}},
+		bindPair:      []*bindPair{},
+		size:          []uint64{uint64(len(raw))},
+		packed:        []uint64{0},
+	}
+
+	if aw != nil {
+		if err = aw.Close(); err != nil {
+			return nil, nil, fmt.Errorf("sevenzip: error encoding header: %w", err)
+		}
+
+		// Coders are decoded in order so AES comes first, with its
+		// output bound to the input of LZMA2
+		f.in, f.out = 2, 2
+		f.coder = append([]*coder{{id: methodAES, in: 1, out: 1, properties: aesp}}, f.coder...)
+		f.bindPair = []*bindPair{{in: 1, out: 0}}
+		f.size = []uint64{cw.Count(), uint64(len(raw))}
+		f.packed = []uint64{0}
+	}
+
+	si := &streamsInfo{
+		packInfo: &packInfo{
+			position: position,
+			streams:  1,
+			size:     []uint64{uint64(packed.Len())},
+		},
+		unpackInfo: &unpackInfo{
+			folder:  []*folder{f},
+			digest:  []uint32{crc32.ChecksumIEEE(raw)},
+			defined: []bool{true},
+		},
+	}
+
+	return packed.Bytes(), si, nil
+}
+
+// encodeArchive builds everything written around the packed streams for the
+// header h, which must already describe dataSize bytes of them. The prefix is
+// the signature and start headers that precede the packed streams and the
+// suffix is the header that follows them.
+func encodeArchive(h *header, dataSize uint64, opts headerOptions) (prefix, suffix []byte, err error) {
+	// An archive with nothing in it is just the signature header with no
+	// header following it, the same as 7-Zip, as libarchive rejects one
+	// with a header that is empty
+	if dataSize == 0 && len(opts.kept) == 0 && h.empty() {
+		return encodeSignatureHeader(startHeader{}), nil, nil
+	}
+
+	// Any kept headers are written in full, each after its own packed
+	// stream, between the packed streams and the header
+	var kept []byte
+
+	for _, k := range opts.kept {
+		encoded, next, err := encodeNextHeader(k, dataSize+uint64(len(kept)), opts)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		kept = append(append(kept, encoded...), next...)
+	}
+
+	encoded, next, err := encodeNextHeader(h, dataSize+uint64(len(kept)), opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	start := startHeader{
+		Offset: dataSize + uint64(len(kept)) + uint64(len(encoded)),
+		Size:   uint64(len(next)),
+		CRC:    crc32.ChecksumIEEE(next),
+	}
+
+	return encodeSignatureHeader(start), append(append(kept, encoded...), next...), nil
+}
+
+// encodeSignatureHeader returns the signature header followed by the start
+// header start.
+func encodeSignatureHeader(start startHeader) []byte {
+	sb := new(bytes.Buffer)
+	_ = binary.Write(sb, binary.LittleEndian, start)
+
+	sh := signatureHeader{
+		Signature: [6]byte{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c},
+		Major:     formatMajorVersion,
+		Minor:     formatMinorVersion,
+		CRC:       crc32.ChecksumIEEE(sb.Bytes()),
+	}
+
+	b := new(bytes.Buffer)
+	_ = binary.Write(b, binary.LittleEndian, sh)
+	_, _ = b.Write(sb.Bytes())
+
+	return b.Bytes()
+}
+
+// encodeNextHeader encodes the header h, returning any packed stream of it,
+// which starts at position, and the header that follows.
+func encodeNextHeader(h *header, position uint64, opts headerOptions) (encoded, next []byte, err error) {
+	raw := new(bytes.Buffer)
+	writeHeader(raw, h)
+
+	if !opts.compress && opts.password == "" {
+		return nil, raw.Bytes(), nil
+	}
+
+	encoded, si, err := encodeHeader(raw.Bytes(), position, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	b := new(bytes.Buffer)
+	_ = b.WriteByte(idEncodedHeader)
+	writeStreamsInfo(b, si)
+
+	return encoded, b.Bytes(), nil
+}
+
+// writeArchive writes a complete archive to w consisting of the packed
+// streams followed by the header h, which must already describe them. As
+// everything apart from the packed streams is built in memory first, w only
+// needs to be written to sequentially.
+func writeArchive(w io.Writer, h *header, streams []packedStream, opts headerOptions) error {
+	var dataSize uint64
+	for _, s := range streams {
+		dataSize += s.size
+	}
+
+	prefix, suffix, err := encodeArchive(h, dataSize, opts)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(prefix); err != nil {
+		return fmt.Errorf("sevenzip: error writing signature header: %w", err)
+	}
+
+	for i, s := range streams {
+		cw := new(plumbing.WriteCounter)
+
+		if err := s.write(io.MultiWriter(w, cw)); err != nil {
+			return err
+		}
+
+		if cw.Count() != s.size {
+			return fmt.Errorf("sevenzip: packed stream %d: wrote %d bytes, expected %d: %w", i, cw.Count(), s.size, errPackedSize)
+		}
+	}
+
+	if _, err := w.Write(suffix); err != nil {
+		return fmt.Errorf("sevenzip: error writing header: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package sevenzip
+
+import (
+	"bufio"
+	"bytes"
+	"hash/crc32"
+	"io"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteUint64(t *testing.T) {
+	t.Parallel()
+
+	values := []uint64{0, 1, 0x7f, 0x80, 0x3fff, 0x4000, 1<<21 - 1, 1 << 21, 1<<49 - 1, 1 << 49, 1<<56 - 1, 1 << 56, math.MaxUint64}
+
+	for _, v := range values {
+		b := new(bytes.Buffer)
+		writeUint64(b, v)
+
+		got, err := readUint64(b)
+		require.NoError(t, err)
+		assert.Equal(t, v, got)
+		assert.Zero(t, b.Len())
+	}
+}
+
+func TestWriteHeader(t *testing.T) {
+	t.Parallel()
+
+	files, err := filepath.Glob(filepath.Join("testdata", "*.7z"))
+	require.NoError(t, err)
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			t.Parallel()
+
+			r, err := OpenReaderWithPassword(file, "password")
+			if err != nil {
+				t.Skip(err)
+			}
+
+			defer r.Close()
+
+			b := new(bytes.Buffer)
+			writeHeader(b, r.h)
+
+			br := bufio.NewReader(b)
+
+			id, err := br.ReadByte()
+			require.NoError(t, err)
+			require.Equal(t, byte(idHeader), id)
+
+			h, err := readHeader(br, nil)
+			require.NoError(t, err)
+
+			if r.h.streamsInfo != nil && r.h.streamsInfo.Folders() > 0 {
+				assert.Equal(t, r.h.streamsInfo, h.streamsInfo)
+			}
+
+			assert.Equal(t, r.h.properties, h.properties)
+			assert.Equal(t, r.h.filesInfo, h.filesInfo)
+		})
+	}
+}
+
+func TestWriteZeroCRC(t *testing.T) {
+	t.Parallel()
+
+	// The CRC32 of these contents is zero, which is still written
+	contents := []byte("zero crc QV\x9a>")
+	require.Zero(t, crc32.ChecksumIEEE(contents))
+
+	b := new(bytes.Buffer)
+	w := NewWriter(b)
+
+	fw, err := w.Create("zero.txt")
+	require.NoError(t, err)
+
+	_, err = fw.Write(contents)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	r, err := NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	require.NoError(t, err)
+	require.Len(t, r.File, 1)
+	assert.True(t, r.File[0].crcDefined)
+	assert.Equal(t, []bool{true}, r.si.subStreamsInfo.defined)
+
+	// Copying the archive keeps it
+	c := new(bytes.Buffer)
+	w = NewWriter(c)
+	require.NoError(t, w.Copy(r))
+	require.NoError(t, w.Close())
+	require.NoError(t, r.Close())
+
+	r, err = NewReader(bytes.NewReader(c.Bytes()), int64(c.Len()))
+	require.NoError(t, err)
+	require.Len(t, r.File, 1)
+	assert.True(t, r.File[0].crcDefined)
+	require.NoError(t, r.Close())
+}
+
+func TestReadZeroCRC(t *testing.T) {
+	t.Parallel()
+
+	contents := []byte("zero crc QV\x9a>")
+	require.Zero(t, crc32.ChecksumIEEE(contents))
+
+	b := new(bytes.Buffer)
+	w := NewWriter(b, WithStore())
+
+	fw, err := w.Create("zero.txt")
+	require.NoError(t, err)
+
+	_, err = fw.Write(contents)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	r, err := NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	require.NoError(t, err)
+	require.Len(t, r.File, 1)
+
+	// A zero CRC32 is still a CRC32
+	crc, ok := r.File[0].Digest()
+	assert.True(t, ok)
+	assert.Zero(t, crc)
+	assert.NotContains(t, r.File[0].ETag(), "W/")
+	require.NoError(t, r.Close())
+
+	// Corrupt the contents, which are stored as is
+	corrupt := bytes.Clone(b.Bytes())
+	corrupt[bytes.Index(corrupt, contents)] ^= 0xff
+
+	r, err = NewReader(bytes.NewReader(corrupt), int64(len(corrupt)))
+	require.NoError(t, err)
+
+	fr, err := r.OpenFolder(0)
+	require.NoError(t, err)
+
+	_, err = io.ReadAll(fr)
+	require.ErrorIs(t, err, errChecksum)
+	require.NoError(t, fr.Close())
+	require.NoError(t, r.Close())
+}
@@ -0,0 +1,28 @@
+package sevenzip
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// identity returns a string identifying the archive, derived from the start
+// header which records the offset, size and CRC of the header so it changes
+// if any files are added, removed or changed.
+func (z *Reader) identity() string {
+	return fmt.Sprintf("%x-%x-%08x", z.sh.Offset, z.sh.Size, z.sh.CRC)
+}
+
+// ETag returns an HTTP entity tag for the contents of the file, built from
+// the identity of the archive and the CRC32 and size of the file, so that
+// content served from an archive can be cached correctly. Files with the same
+// contents in the same archive share a tag.
+//
+// If the archive doesn't record a CRC32 for the file the tag is weak, as
+// described by RFC 9110, and built from the name of the file instead.
+func (f *File) ETag() string {
+	if !f.crcDefined {
+		return "W/" + strconv.Quote(fmt.Sprintf("%s-%x-%x", f.zip.identity(), f.UncompressedSize, f.Name))
+	}
+
+	return strconv.Quote(fmt.Sprintf("%s-%x-%08x", f.zip.identity(), f.UncompressedSize, f.CRC32))
+}
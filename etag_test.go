@@ -0,0 +1,47 @@
+package sevenzip_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func etags(t *testing.T, file string) map[string]string {
+	t.Helper()
+
+	r, err := sevenzip.OpenReader(filepath.Join("testdata", file))
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, r.Close())
+	}()
+
+	tags := make(map[string]string, len(r.File))
+	for _, f := range r.File {
+		tags[f.Name] = f.ETag()
+	}
+
+	return tags
+}
+
+func TestETag(t *testing.T) {
+	t.Parallel()
+
+	lzma := etags(t, "lzma.7z")
+
+	// The same archive produces the same tags
+	assert.Equal(t, lzma, etags(t, "lzma.7z"))
+
+	for name, tag := range lzma {
+		assert.True(t, strings.HasPrefix(tag, `"`) && strings.HasSuffix(tag, `"`), name)
+	}
+
+	// The same contents in a different archive don't
+	for name, tag := range etags(t, "lzma2.7z") {
+		assert.NotEqual(t, lzma[name], tag, name)
+	}
+}
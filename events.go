@@ -0,0 +1,142 @@
+package sevenzip
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// An ExtractEventType is the type of an [ExtractEvent].
+type ExtractEventType string
+
+const (
+	// EventStart is written when a file is handed to the function passed
+	// to [Reader.Extract].
+	EventStart ExtractEventType = "start"
+	// EventProgress is written periodically while a file is being read.
+	EventProgress ExtractEventType = "progress"
+	// EventFinish is written once the function has finished with a file
+	// without error.
+	EventFinish ExtractEventType = "finish"
+	// EventError is written if the function fails for a file, or once if
+	// extraction fails for any other reason, such as a file that can't be
+	// opened, in which case Name is empty.
+	EventError ExtractEventType = "error"
+)
+
+// progressInterval is the minimum time between progress events for a file.
+const progressInterval = 100 * time.Millisecond
+
+// An ExtractEvent is written as a line of JSON by [WithEvents].
+type ExtractEvent struct {
+	Type ExtractEventType `json:"type"`
+	// Name is the name of the file.
+	Name string `json:"name,omitempty"`
+	// Size is the uncompressed size of the file.
+	Size uint64 `json:"size"`
+	// Written is the number of bytes of the file read so far.
+	Written int64 `json:"written"`
+	// Error is the error for an [EventError].
+	Error string `json:"error,omitempty"`
+}
+
+// WithEvents makes [Reader.Extract] write an [ExtractEvent] to w as a line of
+// JSON when each file is started and finished, along with progress while it
+// is read and any errors, so that an application wrapping the extraction,
+// such as a UI in another process, can follow it without polling. Writes to
+// w are serialised and an error writing an event stops the extraction.
+func WithEvents(w io.Writer) ExtractOption {
+	return func(o *extractOptions) {
+		o.events = &eventWriter{enc: json.NewEncoder(w)}
+	}
+}
+
+type eventWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+
+	// reported is set once an error event has been written for a file
+	reported atomic.Bool
+}
+
+func (w *eventWriter) emit(e ExtractEvent) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err == nil {
+		if err := w.enc.Encode(e); err != nil {
+			w.err = fmt.Errorf("sevenzip: error writing event: %w", err)
+		}
+	}
+
+	return w.err
+}
+
+// progressReader writes progress events for a file as it is read.
+type progressReader struct {
+	r    io.Reader
+	w    *eventWriter
+	f    *File
+	n    int64
+	last time.Time
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.r.Read(p)
+	pr.n += int64(n)
+
+	if now := time.Now(); n > 0 && now.Sub(pr.last) >= progressInterval {
+		pr.last = now
+
+		if werr := pr.w.emit(pr.event(EventProgress)); werr != nil {
+			return n, werr
+		}
+	}
+
+	return n, err //nolint:wrapcheck
+}
+
+func (pr *progressReader) event(t ExtractEventType) ExtractEvent {
+	return ExtractEvent{
+		Type:    t,
+		Name:    pr.f.Name,
+		Size:    pr.f.UncompressedSize,
+		Written: pr.n,
+	}
+}
+
+// wrap wraps fn so that events are written for each file.
+func (w *eventWriter) wrap(fn ExtractFunc) ExtractFunc {
+	return func(f *File, r io.Reader) error {
+		pr := &progressReader{r: r, w: w, f: f, last: time.Now()}
+
+		if err := w.emit(pr.event(EventStart)); err != nil {
+			return err
+		}
+
+		if err := fn(f, pr); err != nil {
+			w.reported.Store(true)
+
+			e := pr.event(EventError)
+			e.Error = err.Error()
+
+			_ = w.emit(e)
+
+			return err
+		}
+
+		return w.emit(pr.event(EventFinish))
+	}
+}
+
+// fail writes an error event for err unless one has already been written for
+// a file.
+func (w *eventWriter) fail(err error) {
+	if err != nil && !w.reported.Load() {
+		_ = w.emit(ExtractEvent{Type: EventError, Error: err.Error()})
+	}
+}
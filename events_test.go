@@ -0,0 +1,197 @@
+package sevenzip_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncBuffer is a bytes.Buffer that is safe to read while events are still
+// being written.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.b.Write(p) //nolint:wrapcheck
+}
+
+func (b *syncBuffer) events(t *testing.T) []sevenzip.ExtractEvent {
+	t.Helper()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var events []sevenzip.ExtractEvent
+
+	dec := json.NewDecoder(&b.b)
+
+	for {
+		var e sevenzip.ExtractEvent
+
+		err := dec.Decode(&e)
+		if errors.Is(err, io.EOF) {
+			return events
+		}
+
+		require.NoError(t, err)
+
+		events = append(events, e)
+	}
+}
+
+type failingWriter struct{}
+
+var errWrite = errors.New("write failed")
+
+func (failingWriter) Write(_ []byte) (int, error) {
+	return 0, errWrite
+}
+
+func TestWithEvents(t *testing.T) {
+	t.Parallel()
+
+	r, err := sevenzip.OpenReader(filepath.Join("testdata", "copy.7z"))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, r.Close())
+	})
+
+	errStop := errors.New("stop")
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+
+		var b syncBuffer
+
+		err := r.Extract(context.Background(), func(f *sevenzip.File, rc io.Reader) error {
+			if f.Name == "01" {
+				// Read slowly enough to be given a progress event
+				if _, err := io.CopyN(io.Discard, rc, 1); err != nil {
+					return err
+				}
+
+				time.Sleep(150 * time.Millisecond)
+			}
+
+			_, err := io.Copy(io.Discard, rc)
+
+			return err
+		}, sevenzip.WithEvents(&b))
+		require.NoError(t, err)
+
+		events := make(map[string][]sevenzip.ExtractEvent, len(r.File))
+		for _, e := range b.events(t) {
+			events[e.Name] = append(events[e.Name], e)
+		}
+
+		require.Len(t, events, len(r.File))
+
+		for _, f := range r.File {
+			e := events[f.Name]
+			require.GreaterOrEqual(t, len(e), 2, f.Name)
+
+			assert.Equal(t, sevenzip.ExtractEvent{Type: sevenzip.EventStart, Name: f.Name, Size: f.UncompressedSize}, e[0])
+			assert.Equal(t, sevenzip.ExtractEvent{
+				Type:    sevenzip.EventFinish,
+				Name:    f.Name,
+				Size:    f.UncompressedSize,
+				Written: int64(f.UncompressedSize), //nolint:gosec
+			}, e[len(e)-1])
+
+			for _, p := range e[1 : len(e)-1] {
+				assert.Equal(t, sevenzip.EventProgress, p.Type, f.Name)
+				assert.Positive(t, p.Written, f.Name)
+			}
+
+			if f.Name == "01" {
+				assert.Len(t, e, 3)
+			}
+		}
+	})
+
+	t.Run("file error", func(t *testing.T) {
+		t.Parallel()
+
+		var b syncBuffer
+
+		err := r.Extract(context.Background(), func(f *sevenzip.File, rc io.Reader) error {
+			if f.Name == "02" {
+				return errStop
+			}
+
+			_, err := io.Copy(io.Discard, rc)
+
+			return err
+		}, sevenzip.WithEvents(&b))
+		require.ErrorIs(t, err, errStop)
+
+		var failed []sevenzip.ExtractEvent
+
+		for _, e := range b.events(t) {
+			if e.Type == sevenzip.EventError {
+				failed = append(failed, e)
+			}
+		}
+
+		assert.Contains(t, failed, sevenzip.ExtractEvent{
+			Type:  sevenzip.EventError,
+			Name:  "02",
+			Size:  r.File[1].UncompressedSize,
+			Error: errStop.Error(),
+		})
+
+		for _, e := range failed {
+			assert.NotEmpty(t, e.Name)
+		}
+	})
+
+	t.Run("write error", func(t *testing.T) {
+		t.Parallel()
+
+		err := r.Extract(context.Background(), func(_ *sevenzip.File, rc io.Reader) error {
+			_, err := io.Copy(io.Discard, rc)
+
+			return err
+		}, sevenzip.WithEvents(failingWriter{}))
+		assert.ErrorIs(t, err, errWrite)
+	})
+}
+
+func TestWithEventsOpenError(t *testing.T) {
+	t.Parallel()
+
+	r, err := sevenzip.OpenReader(filepath.Join("testdata", "copy.7z"))
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+
+	var b syncBuffer
+
+	err = r.Extract(context.Background(), func(_ *sevenzip.File, _ io.Reader) error {
+		return nil
+	}, sevenzip.WithEvents(&b))
+	require.ErrorIs(t, err, sevenzip.ErrReaderClosed)
+
+	events := b.events(t)
+	require.NotEmpty(t, events)
+
+	last := events[len(events)-1]
+	assert.Equal(t, sevenzip.EventError, last.Type)
+	assert.Empty(t, last.Name)
+	assert.Equal(t, sevenzip.ErrReaderClosed.Error(), last.Error)
+}
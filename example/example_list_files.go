@@ -210,47 +210,45 @@ func main() {
 // extractUsingStandardMethod extracts a file using the standard 7zip API
 func extractUsingStandardMethod(reader *sevenzip.ReadCloser, fileInfo sevenzip.FileInfo, outputDir string) {
 	// Find the file in the archive
-	for _, file := range reader.File {
-		if file.Name == fileInfo.Name {
-			// Open the file from the archive
-			rc, err := file.Open()
-			if err != nil {
-				log.Printf("Failed to open file %s: %v", fileInfo.Name, err)
-				return
-			}
-			defer rc.Close()
-
-			// Create output directory if it doesn't exist
-			if err := os.MkdirAll(outputDir, 0755); err != nil {
-				log.Printf("Failed to create output directory: %v", err)
-				return
-			}
+	file, ok := reader.FileByName(fileInfo.Name)
+	if !ok {
+		log.Printf("File %s not found in archive", fileInfo.Name)
+		return
+	}
 
-			// Create the output file
-			outputPath := filepath.Join(outputDir, filepath.Base(fileInfo.Name))
-			outFile, err := os.Create(outputPath)
-			if err != nil {
-				log.Printf("Failed to create output file: %v", err)
-				return
-			}
-			defer outFile.Close()
+	// Open the file from the archive
+	rc, err := file.Open()
+	if err != nil {
+		log.Printf("Failed to open file %s: %v", fileInfo.Name, err)
+		return
+	}
+	defer rc.Close()
 
-			// Copy the file contents
-			written, err := io.Copy(outFile, rc)
-			if err != nil {
-				log.Printf("Failed to extract file: %v", err)
-				return
-			}
+	// Create output directory if it doesn't exist
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		log.Printf("Failed to create output directory: %v", err)
+		return
+	}
 
-			fmt.Printf("\nSuccessfully extracted using standard method:\n")
-			fmt.Printf("  Output: %s\n", outputPath)
-			fmt.Printf("  Bytes written: %d\n", written)
+	// Create the output file
+	outputPath := filepath.Join(outputDir, filepath.Base(fileInfo.Name))
+	outFile, err := os.Create(outputPath)
+	if err != nil {
+		log.Printf("Failed to create output file: %v", err)
+		return
+	}
+	defer outFile.Close()
 
-			return
-		}
+	// Copy the file contents
+	written, err := io.Copy(outFile, rc)
+	if err != nil {
+		log.Printf("Failed to extract file: %v", err)
+		return
 	}
 
-	log.Printf("File %s not found in archive", fileInfo.Name)
+	fmt.Printf("\nSuccessfully extracted using standard method:\n")
+	fmt.Printf("  Output: %s\n", outputPath)
+	fmt.Printf("  Bytes written: %d\n", written)
 }
 
 // Helper function to truncate long strings
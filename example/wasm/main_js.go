@@ -0,0 +1,99 @@
+// Command wasm lists and extracts files from an archive in the browser,
+// fetching only the parts that are needed with HTTP Range requests. Build it
+// with:
+//
+//	GOOS=js GOARCH=wasm go build -o sevenzip.wasm ./example/wasm
+//
+// and load it with the wasm_exec.js shipped with Go. It adds two functions
+// to the page, both taking the URLs of every volume of the archive, or just
+// the one URL for an archive that isn't split:
+//
+//	const files = await sevenzipList(["archive.7z"]);
+//	const bytes = await sevenzipExtract(["archive.7z"], files[0].name);
+//
+// sevenzipList resolves to an array of {name, size, stored} objects and
+// sevenzipExtract to a Uint8Array.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"syscall/js"
+
+	"github.com/javi11/sevenzip"
+)
+
+// promise runs fn in a goroutine, as fetch blocks, and returns a Promise
+// settled with its result.
+func promise(fn func() (any, error)) js.Value {
+	handler := js.FuncOf(func(_ js.Value, args []js.Value) any {
+		resolve, reject := args[0], args[1]
+
+		go func() {
+			v, err := fn()
+			if err != nil {
+				reject.Invoke(js.Global().Get("Error").New(err.Error()))
+
+				return
+			}
+
+			resolve.Invoke(v)
+		}()
+
+		return nil
+	})
+
+	return js.Global().Get("Promise").New(handler)
+}
+
+func urls(v js.Value) []string {
+	s := make([]string, v.Length())
+	for i := range s {
+		s[i] = v.Index(i).String()
+	}
+
+	return s
+}
+
+func withArchive(v js.Value, fn func(*sevenzip.Reader) (any, error)) js.Value {
+	return promise(func() (any, error) {
+		r, err := openArchive(context.Background(), urls(v)...)
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+
+		return fn(r)
+	})
+}
+
+func main() {
+	js.Global().Set("sevenzipList", js.FuncOf(func(_ js.Value, args []js.Value) any {
+		return withArchive(args[0], func(r *sevenzip.Reader) (any, error) {
+			b, err := json.Marshal(list(r))
+			if err != nil {
+				return nil, err
+			}
+
+			return js.Global().Get("JSON").Call("parse", string(b)), nil
+		})
+	}))
+
+	js.Global().Set("sevenzipExtract", js.FuncOf(func(_ js.Value, args []js.Value) any {
+		name := args[1].String()
+
+		return withArchive(args[0], func(r *sevenzip.Reader) (any, error) {
+			b, err := extract(r, name)
+			if err != nil {
+				return nil, err
+			}
+
+			a := js.Global().Get("Uint8Array").New(len(b))
+			js.CopyBytesToJS(a, b)
+
+			return a, nil
+		})
+	}))
+
+	select {}
+}
@@ -0,0 +1,49 @@
+//go:build !js
+
+// Command wasm lists and extracts files from an archive over HTTP with Range
+// requests. It is meant to be built for the browser, see main_js.go, but
+// outside of it the same code can be tried from the command line:
+//
+//	wasm [-x name] url...
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+func main() {
+	name := flag.String("x", "", "Extract the named file to stdout instead of listing the archive")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	r, err := openArchive(context.Background(), flag.Args()...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer r.Close()
+
+	if *name != "" {
+		b, err := extract(r, *name)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if _, err := os.Stdout.Write(b); err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	for _, e := range list(r) {
+		fmt.Printf("%10d %-6v %s\n", e.Size, e.Stored, e.Name)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/javi11/sevenzip"
+)
+
+var errNoRanges = errors.New("server doesn't support range requests")
+
+// rangeVolumeSource is a sevenzip.VolumeSource that reads each volume of an
+// archive over HTTP with Range requests, so only the parts of the archive
+// that are needed are downloaded. When built for the browser, net/http makes
+// the requests with the fetch API, so the server must allow the Range header
+// and expose Content-Length and Content-Range to cross-origin requests.
+type rangeVolumeSource struct {
+	client *http.Client
+	urls   []string
+	sizes  []int64
+}
+
+// newRangeVolumeSource finds the size of each volume with a HEAD request.
+func newRangeVolumeSource(ctx context.Context, client *http.Client, urls ...string) (*rangeVolumeSource, error) {
+	src := &rangeVolumeSource{
+		client: client,
+		urls:   urls,
+		sizes:  make([]int64, len(urls)),
+	}
+
+	for i, url := range urls {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK || resp.ContentLength < 0 {
+			return nil, fmt.Errorf("%s: unexpected response %q", url, resp.Status)
+		}
+
+		src.sizes[i] = resp.ContentLength
+	}
+
+	return src, nil
+}
+
+func (src *rangeVolumeSource) Sizes() []int64 {
+	return src.sizes
+}
+
+// Wait returns immediately as every volume is already on the server.
+func (src *rangeVolumeSource) Wait(ctx context.Context, _ int, _ int64) error {
+	return ctx.Err()
+}
+
+func (src *rangeVolumeSource) Open(i int) (io.ReaderAt, error) {
+	return &rangeReaderAt{
+		client: src.client,
+		url:    src.urls[i],
+		size:   src.sizes[i],
+	}, nil
+}
+
+// rangeReaderAt reads a volume with a Range request for each call to ReadAt.
+// The sevenzip package reads ahead, so most reads are for a whole buffer.
+type rangeReaderAt struct {
+	client *http.Client
+	url    string
+	size   int64
+}
+
+func (ra *rangeReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off >= ra.size {
+		return 0, io.EOF
+	}
+
+	end := min(off+int64(len(p)), ra.size)
+
+	req, err := http.NewRequest(http.MethodGet, ra.url, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, end-1))
+
+	resp, err := ra.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return 0, fmt.Errorf("%s: %w: unexpected response %q", ra.url, errNoRanges, resp.Status)
+	}
+
+	n, err := io.ReadFull(resp.Body, p[:end-off])
+	if err == nil && n < len(p) {
+		err = io.EOF
+	}
+
+	return n, err
+}
+
+// openArchive opens the archive split across urls, or a single archive if
+// there is only one.
+func openArchive(ctx context.Context, urls ...string) (*sevenzip.Reader, error) {
+	src, err := newRangeVolumeSource(ctx, http.DefaultClient, urls...)
+	if err != nil {
+		return nil, err
+	}
+
+	return sevenzip.NewReaderFromVolumeSource(ctx, src)
+}
+
+// entry is a file in the archive as listed by the example.
+type entry struct {
+	Name   string `json:"name"`
+	Size   uint64 `json:"size"`
+	Stored bool   `json:"stored"`
+}
+
+func list(r *sevenzip.Reader) []entry {
+	entries := make([]entry, 0, len(r.File))
+
+	for _, f := range r.File {
+		_, _, err := f.DataOffset()
+
+		entries = append(entries, entry{
+			Name:   f.Name,
+			Size:   f.UncompressedSize,
+			Stored: err == nil,
+		})
+	}
+
+	return entries
+}
+
+// extract returns the contents of the named file. Stored files are read
+// directly with a single range request per read ahead buffer, anything else
+// is decoded from the start of its block.
+func extract(r *sevenzip.Reader, name string) ([]byte, error) {
+	f, ok := r.FileByName(name)
+	if !ok {
+		return nil, fmt.Errorf("%s: file not found", name)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return io.ReadAll(rc)
+}
@@ -1,6 +1,22 @@
 package sevenzip
 
 var (
+	ErrChecksum          = errChecksum
+	ErrEmptyName         = errEmptyName
+	ErrFiltered          = errFiltered
+	ErrFormat            = errFormat
+	ErrHeaderPassword    = errHeaderPassword
 	ErrMissingUnpackInfo = errMissingUnpackInfo
 	ErrNegativeSize      = errNegativeSize
+	ErrNoBlock           = errNoBlock
+	ErrNotRegular        = errNotRegular
+	ErrReaderClosed      = errReaderClosed
+	ErrUnexpectedID      = errUnexpectedID
+	ErrUpdateEncrypted   = errUpdateEncrypted
+	ErrUpdateVolumes     = errUpdateVolumes
+	ErrVolumeName        = errVolumeName
+	ErrVolumeTooSmall    = errVolumeTooSmall
+	ErrWriteDirectory    = errWriteDirectory
+	ErrWriteMethod       = errWriteMethod
+	ErrWriterClosed      = errWriterClosed
 )
@@ -0,0 +1,707 @@
+package sevenzip
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	iofs "io/fs"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// ExtractFunc is the function called by [Reader.Extract] for each file. The
+// reader r provides the file contents and is only valid until the function
+// returns.
+type ExtractFunc func(f *File, r io.Reader) error
+
+type extractOptions struct {
+	priority    func(*File) int
+	queueDepth  int
+	preallocate bool
+	directIOMin int64
+	atomic      bool
+	rollback    bool
+	maxBytes    int64
+	maxFiles    int64
+	forceMode   bool
+	modeMask    iofs.FileMode
+	dirMode     iofs.FileMode
+	chown       bool
+	uid, gid    int
+	restore     func(*File, string) error
+	done        func(ExtractResult)
+	newHash     func() hash.Hash
+	events      *eventWriter
+	files       []*File
+	// skip reports the files the function will leave alone, such as
+	// [Reader.ExtractAll] does for unsafe names, so they aren't counted
+	// against [WithMaxOutputFiles].
+	skip func(*File) bool
+}
+
+// An ExtractOption configures a call to [Reader.Extract].
+type ExtractOption func(*extractOptions)
+
+// WithPriority sets a function returning the priority of each file; files
+// with a higher priority are processed first. As each stream can only be
+// decoded sequentially, a stream is scheduled according to the highest
+// priority of the files it contains and the files within it are still
+// processed in archive order. This allows, for example, a file that is being
+// streamed to a user to be extracted before bulk data without decoding any
+// stream more than once.
+func WithPriority(fn func(*File) int) ExtractOption {
+	return func(o *extractOptions) {
+		o.priority = fn
+	}
+}
+
+// WithWriteQueue decouples decoding each stream from fn by queueing up to
+// depth buffers of decoded data, so a slow fn, such as one writing to a slow
+// disk, doesn't stall the decoder and vice versa. Each file is passed to fn
+// in its own goroutine so the decoder can move on to the next file in the
+// stream while fn is still consuming the previous one, which means fn can
+// be called concurrently for files in the same stream. The queue bounds the
+// memory used regardless of the size of the files. A depth of zero, the
+// default, disables queueing.
+func WithWriteQueue(depth int) ExtractOption {
+	return func(o *extractOptions) {
+		o.queueDepth = max(depth, 0)
+	}
+}
+
+// WithPreallocate makes [Reader.ExtractAll] reserve the final size of each
+// file before writing it, which reduces fragmentation when restoring large
+// files. It uses fallocate(2) on Linux and has no effect elsewhere or on
+// filesystems that don't support it.
+func WithPreallocate() ExtractOption {
+	return func(o *extractOptions) {
+		o.preallocate = true
+	}
+}
+
+// WithDirectIO makes [Reader.ExtractAll] write files of at least minSize
+// bytes with O_DIRECT, bypassing the page cache so that restoring many large
+// files doesn't evict everything else from it. It is only supported on
+// Linux, elsewhere or on filesystems that don't support it files are written
+// normally.
+func WithDirectIO(minSize int64) ExtractOption {
+	return func(o *extractOptions) {
+		o.directIOMin = max(minSize, 1)
+	}
+}
+
+// WithAtomicWrites makes [Reader.ExtractAll] write each file to a temporary
+// name in the same directory and only rename it to its final name once its
+// contents have been written and match the CRC recorded in the archive, so
+// a partially written or corrupt file is never visible in the destination.
+// The temporary file is removed if anything fails.
+func WithAtomicWrites() ExtractOption {
+	return func(o *extractOptions) {
+		o.atomic = true
+	}
+}
+
+// WithRollback makes [Reader.ExtractAll] record every file and directory it
+// creates and, if extraction fails, remove them again and restore any
+// existing files that were overwritten, leaving the destination as it was
+// found. Overwritten files are kept alongside the new ones until extraction
+// finishes.
+func WithRollback() ExtractOption {
+	return func(o *extractOptions) {
+		o.rollback = true
+	}
+}
+
+// WithModeMask makes [Reader.ExtractAll] create files and directories with
+// the permissions recorded in the archive, with the bits in mask cleared in
+// the same way as a umask. The resulting mode is set explicitly so it isn't
+// affected by the umask of the process. Without it files are created with
+// mode 0644 and directories with mode 0755, less the umask.
+func WithModeMask(mask iofs.FileMode) ExtractOption {
+	return func(o *extractOptions) {
+		o.forceMode = true
+		o.modeMask = mask.Perm()
+	}
+}
+
+// WithDirMode sets the mode of any directories created by
+// [Reader.ExtractAll] that aren't themselves in the archive, such as the
+// destination or parents of files, along with directories in the archive
+// unless [WithModeMask] is used. The mode is set explicitly so it isn't
+// affected by the umask of the process.
+func WithDirMode(mode iofs.FileMode) ExtractOption {
+	return func(o *extractOptions) {
+		o.dirMode = mode.Perm()
+	}
+}
+
+// WithOwner makes [Reader.ExtractAll] change the owner of every file and
+// directory it creates to uid and gid as they are written, which usually
+// requires running as root. It isn't supported on Windows.
+func WithOwner(uid, gid int) ExtractOption {
+	return func(o *extractOptions) {
+		o.chown = true
+		o.uid, o.gid = uid, gid
+	}
+}
+
+// WithRestoreFunc sets a function that [Reader.ExtractAll] calls with each
+// file or directory and the path it was extracted to once it is in place,
+// so that metadata the archive can't hold, such as extended attributes or
+// ACLs, can be restored from elsewhere in the same pass. It may be called
+// concurrently and an error stops the extraction.
+func WithRestoreFunc(fn func(f *File, path string) error) ExtractOption {
+	return func(o *extractOptions) {
+		o.restore = fn
+	}
+}
+
+// WithMaxOutputBytes limits the total number of bytes of file contents that
+// can be extracted to n. The limit is enforced on the data as it is
+// decoded rather than on the sizes claimed by the archive headers, once it
+// is exceeded extraction fails with [ErrLimitExceeded].
+func WithMaxOutputBytes(n int64) ExtractOption {
+	return func(o *extractOptions) {
+		o.maxBytes = max(n, 0)
+	}
+}
+
+// WithMaxOutputFiles limits the number of files, including directories,
+// that can be extracted to n, after which extraction fails with
+// [ErrLimitExceeded]. Files that [Reader.ExtractAll] skips, such as those
+// with unsafe names, aren't counted.
+func WithMaxOutputFiles(n int64) ExtractOption {
+	return func(o *extractOptions) {
+		o.maxFiles = max(n, 0)
+	}
+}
+
+// quota tracks the output of an extraction against the limits set with
+// [WithMaxOutputBytes] and [WithMaxOutputFiles].
+type quota struct {
+	maxBytes, maxFiles int64
+	bytes, files       atomic.Int64
+	skip               func(*File) bool
+}
+
+type quotaReader struct {
+	r io.Reader
+	q *quota
+}
+
+func (qr *quotaReader) Read(p []byte) (int, error) {
+	n, err := qr.r.Read(p)
+
+	if total := qr.q.bytes.Add(int64(n)); total > qr.q.maxBytes {
+		return n - int(min(total-qr.q.maxBytes, int64(n))), fmt.Errorf("%w: output exceeds %d bytes", ErrLimitExceeded, qr.q.maxBytes)
+	}
+
+	return n, err //nolint:wrapcheck
+}
+
+// enforce wraps fn so that the limits are enforced.
+func (q *quota) enforce(fn ExtractFunc) ExtractFunc {
+	return func(f *File, r io.Reader) error {
+		// Files that won't be written don't count
+		if q.skip != nil && q.skip(f) {
+			return fn(f, r)
+		}
+
+		if q.maxFiles > 0 && q.files.Add(1) > q.maxFiles {
+			return fmt.Errorf("%w: output exceeds %d files", ErrLimitExceeded, q.maxFiles)
+		}
+
+		if q.maxBytes > 0 {
+			r = &quotaReader{r: r, q: q}
+		}
+
+		return fn(f, r)
+	}
+}
+
+// An ExtractResult describes a file once the function passed to
+// [Reader.Extract] has finished with it.
+type ExtractResult struct {
+	File *File
+	// Written is the number of bytes of the file that were read.
+	Written int64
+	// CRCOK is true if the whole file was read and it matches the CRC
+	// recorded in the archive, or no CRC was recorded.
+	CRCOK bool
+	// Duration is how long the file took to process.
+	Duration time.Duration
+	// Sum is the hash of what was read of the file when [WithFileHash] is
+	// used.
+	Sum []byte
+	// Err is the error returned for the file, if any.
+	Err error
+}
+
+// WithFileDone sets a function that is called with the result of each file
+// as soon as it has been processed, including files that fail, so that an
+// application can act on each file, such as updating a database, without
+// waiting for the whole archive. Like the function passed to
+// [Reader.Extract] it may be called concurrently.
+func WithFileDone(fn func(ExtractResult)) ExtractOption {
+	return func(o *extractOptions) {
+		o.done = fn
+	}
+}
+
+// WithFileHash makes [Reader.Extract] hash the contents of each file as the
+// function passed to it reads them, with a hash created by newHash, and
+// report the sum in [ExtractResult.Sum] to the function set with
+// [WithFileDone]. Hashes are reused between files, but never shared by files
+// being read at the same time, so any [hash.Hash] can be used even though
+// files are read concurrently. See [Reader.HashFiles] to just hash the files.
+func WithFileHash(newHash func() hash.Hash) ExtractOption {
+	return func(o *extractOptions) {
+		o.newHash = newHash
+	}
+}
+
+// hashReader counts and hashes everything read through it, optionally with
+// a second hash.
+type hashReader struct {
+	r   io.Reader
+	h   hash.Hash32
+	sum hash.Hash
+	n   int64
+}
+
+func (hr *hashReader) Read(p []byte) (int, error) {
+	n, err := hr.r.Read(p)
+	hr.h.Write(p[:n])
+	hr.n += int64(n)
+
+	if hr.sum != nil {
+		hr.sum.Write(p[:n])
+	}
+
+	return n, err //nolint:wrapcheck
+}
+
+// reportDone wraps fn so that done is called with the result of each file,
+// including its hash if newHash isn't nil.
+func reportDone(fn ExtractFunc, done func(ExtractResult), newHash func() hash.Hash) ExtractFunc {
+	var hashes sync.Pool
+
+	if newHash != nil {
+		hashes.New = func() any {
+			return newHash()
+		}
+	}
+
+	return func(f *File, r io.Reader) error {
+		start := time.Now()
+		hr := &hashReader{r: r, h: crc32.NewIEEE()}
+
+		if h, ok := hashes.Get().(hash.Hash); ok {
+			h.Reset()
+			hr.sum = h
+
+			defer hashes.Put(h)
+		}
+
+		err := fn(f, hr)
+		crc, ok := f.Digest()
+
+		result := ExtractResult{
+			File:     f,
+			Written:  hr.n,
+			CRCOK:    uint64(hr.n) == f.UncompressedSize && (!ok || hr.h.Sum32() == crc), //nolint:gosec
+			Duration: time.Since(start),
+			Err:      err,
+		}
+
+		if hr.sum != nil {
+			result.Sum = hr.sum.Sum(nil)
+		}
+
+		done(result)
+
+		return err
+	}
+}
+
+type extractGroup struct {
+	priority int
+	files    []*File
+}
+
+// WithFiles restricts extraction to files, such as those picked by
+// [Reader.Sample], which must be from the same archive. They can be in any
+// order as each stream is still decoded in archive order. [Reader.ExtractDir]
+// ignores it.
+func WithFiles(files []*File) ExtractOption {
+	// Never nil, which would mean every file
+	files = append(make([]*File, 0, len(files)), files...)
+
+	slices.SortStableFunc(files, func(a, b *File) int {
+		return cmp.Or(cmp.Compare(streamOf(a), streamOf(b)), cmp.Compare(a.offset, b.offset))
+	})
+
+	return func(o *extractOptions) {
+		o.files = files
+	}
+}
+
+// streamOf returns the stream of f, or -1 if it doesn't have one.
+func streamOf(f *File) int {
+	if !f.HasStream() {
+		return -1
+	}
+
+	return f.Stream
+}
+
+func (z *Reader) extractGroups(files []*File, priority func(*File) int) []*extractGroup {
+	var (
+		groups = make([]*extractGroup, 0, z.si.Folders()+1)
+		index  = make(map[int]*extractGroup, z.si.Folders()+1)
+	)
+
+	for _, f := range files {
+		stream := f.Stream
+		if !f.HasStream() {
+			stream = -1
+		}
+
+		var p int
+		if priority != nil {
+			p = priority(f)
+		}
+
+		g, ok := index[stream]
+		if !ok {
+			g = &extractGroup{priority: p}
+			index[stream] = g
+			groups = append(groups, g)
+		}
+
+		g.priority = max(g.priority, p)
+		g.files = append(g.files, f)
+	}
+
+	if priority != nil {
+		sort.SliceStable(groups, func(i, j int) bool {
+			return groups[i].priority > groups[j].priority
+		})
+	}
+
+	return groups
+}
+
+// contextReader fails reads once its context is done so that a callback
+// copying a large file stops promptly when extraction is cancelled.
+type contextReader struct {
+	ctx context.Context //nolint:containedctx
+	r   io.Reader
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err //nolint:wrapcheck
+	}
+
+	return cr.r.Read(p) //nolint:wrapcheck
+}
+
+func extractOne(ctx context.Context, f *File, fn ExtractFunc, d *blockDigest) (err error) {
+	var rc io.ReadCloser
+
+	if rc, err = f.openContext(ctx); err != nil {
+		return err
+	}
+
+	defer func() {
+		if cerr := rc.Close(); cerr != nil {
+			err = errors.Join(err, fmt.Errorf("sevenzip: error closing %s: %w", f.Name, cerr))
+		}
+	}()
+
+	r := d.reader(rc)
+	if err = fn(f, &contextReader{ctx, r}); err != nil {
+		return err
+	}
+
+	d.add(f, r)
+
+	return nil
+}
+
+const queueBufferSize = 32 << 10
+
+type queuedChunk struct {
+	b   []byte
+	err error
+}
+
+// queueReader reads the chunks of a file decoded by extractQueued, returning
+// each buffer to the free list once it has been consumed.
+type queueReader struct {
+	ch   <-chan queuedChunk
+	free chan<- []byte
+	buf  []byte
+	cur  []byte
+}
+
+func (q *queueReader) release() {
+	if q.buf != nil {
+		q.free <- q.buf[:cap(q.buf)]
+		q.buf, q.cur = nil, nil
+	}
+}
+
+func (q *queueReader) Read(p []byte) (int, error) {
+	for len(q.cur) == 0 {
+		q.release()
+
+		c, ok := <-q.ch
+		if !ok {
+			return 0, io.EOF
+		}
+
+		if c.err != nil {
+			return 0, c.err
+		}
+
+		q.buf, q.cur = c.b, c.b
+	}
+
+	n := copy(p, q.cur)
+	q.cur = q.cur[n:]
+
+	return n, nil
+}
+
+// drain returns any buffers fn didn't consume so the decoder isn't starved.
+func (q *queueReader) drain() {
+	q.release()
+
+	for c := range q.ch {
+		if c.b != nil {
+			q.free <- c.b[:cap(c.b)]
+		}
+	}
+}
+
+// decodeQueued decodes f into buffers taken from free and sends them to ch,
+// which is closed once the file has been decoded or an error occurs.
+func decodeQueued(ctx context.Context, f *File, ch chan<- queuedChunk, free chan []byte, d *blockDigest) (err error) {
+	defer close(ch)
+
+	defer func() {
+		if err != nil {
+			ch <- queuedChunk{err: err}
+		}
+	}()
+
+	rc, err := f.openContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if cerr := rc.Close(); cerr != nil {
+			err = errors.Join(err, fmt.Errorf("sevenzip: error closing %s: %w", f.Name, cerr))
+		}
+	}()
+
+	r := d.reader(rc)
+
+	for {
+		var buf []byte
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err() //nolint:wrapcheck
+		case buf = <-free:
+		}
+
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			ch <- queuedChunk{b: buf[:n]}
+		} else {
+			free <- buf
+		}
+
+		switch {
+		case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+			d.add(f, r)
+
+			return nil
+		case err != nil:
+			return err //nolint:wrapcheck
+		}
+	}
+}
+
+// extractQueued decodes the files of a stream in order, handing each to fn
+// in its own goroutine through a queue of at most depth buffers.
+func extractQueued(ctx context.Context, files []*File, fn ExtractFunc, depth int, d *blockDigest) error {
+	free := make(chan []byte, depth)
+	for range depth {
+		free <- make([]byte, queueBufferSize)
+	}
+
+	eg, ctx := errgroup.WithContext(ctx)
+
+	var derr error
+
+	for _, f := range files {
+		if derr = ctx.Err(); derr != nil {
+			break
+		}
+
+		// Never blocks as there are only depth buffers in circulation
+		ch := make(chan queuedChunk, depth+1)
+		q := &queueReader{ch: ch, free: free}
+
+		eg.Go(func() error {
+			defer q.drain()
+
+			return fn(f, &contextReader{ctx, q})
+		})
+
+		if derr = decodeQueued(ctx, f, ch, free, d); derr != nil {
+			break
+		}
+	}
+
+	// Errors from fn take precedence as they are likely the cause of any
+	// decoding being cancelled
+	if err := eg.Wait(); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	return derr
+}
+
+// Extract calls fn for every file in the archive.
+//
+// Files stored in the same stream are processed in archive order by the same
+// goroutine so that each stream is only decoded once, which is the most
+// efficient way to read a solid archive. Separate streams are decoded in
+// parallel, up to the limit set with [WithConcurrency], so fn may be called
+// concurrently from multiple goroutines.
+//
+// Streams are started in archive order unless [WithPriority] is used.
+// Extraction stops at the first error, which is returned. Cancelling ctx, or
+// closing the Reader, interrupts any files currently being decoded, including
+// deriving the key for an encrypted file.
+func (z *Reader) Extract(ctx context.Context, fn ExtractFunc, opts ...ExtractOption) error {
+	o := new(extractOptions)
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.maxBytes > 0 || o.maxFiles > 0 {
+		fn = (&quota{maxBytes: o.maxBytes, maxFiles: o.maxFiles, skip: o.skip}).enforce(fn)
+	}
+
+	if o.done != nil {
+		fn = reportDone(fn, o.done, o.newHash)
+	}
+
+	if o.events != nil {
+		fn = o.events.wrap(fn)
+	}
+
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.SetLimit(max(z.opts.concurrency, 1))
+
+	files := z.File
+	if o.files != nil {
+		files = o.files
+	}
+
+	for _, g := range z.extractGroups(files, o.priority) {
+		eg.Go(func() error {
+			d := z.newBlockDigest(g.files)
+
+			if o.queueDepth > 0 {
+				if err := extractQueued(ctx, g.files, fn, o.queueDepth, d); err != nil {
+					return err
+				}
+
+				d.done()
+
+				return nil
+			}
+
+			for _, f := range g.files {
+				if err := ctx.Err(); err != nil {
+					return err //nolint:wrapcheck
+				}
+
+				if err := extractOne(ctx, f, fn, d); err != nil {
+					return err
+				}
+			}
+
+			d.done()
+
+			return nil
+		})
+	}
+
+	err := eg.Wait()
+
+	if o.events != nil {
+		o.events.fail(err)
+	}
+
+	return err //nolint:wrapcheck
+}
+
+// HashFiles reads every file in the archive, or those set with [WithFiles],
+// the same as [Reader.Extract] and returns the hash of each, created by
+// newHash, keyed by file. Directories and empty files get the hash of
+// nothing. Files are read concurrently but each has its own hash, and the
+// results are gathered safely, so any [hash.Hash] can be used. Hashing stops
+// at the first error, which is returned; any function set with
+// [WithFileDone] is still called for each file.
+func (z *Reader) HashFiles(ctx context.Context, newHash func() hash.Hash, opts ...ExtractOption) (map[*File][]byte, error) {
+	o := new(extractOptions)
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	var (
+		mu   sync.Mutex
+		sums = make(map[*File][]byte)
+	)
+
+	opts = append(opts[:len(opts):len(opts)], WithFileHash(newHash), WithFileDone(func(r ExtractResult) {
+		if r.Err == nil {
+			mu.Lock()
+			sums[r.File] = r.Sum
+			mu.Unlock()
+		}
+
+		if o.done != nil {
+			o.done(r)
+		}
+	}))
+
+	err := z.Extract(ctx, func(_ *File, r io.Reader) error {
+		_, err := io.Copy(io.Discard, r)
+
+		return err //nolint:wrapcheck
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return sums, nil
+}
@@ -0,0 +1,53 @@
+package sevenzip
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractCancelKeyDerivation(t *testing.T) {
+	t.Parallel()
+
+	b := new(bytes.Buffer)
+	w := NewWriter(b, WithWriterPassword("cancel"))
+
+	f, err := w.Create("file")
+	require.NoError(t, err)
+
+	_, err = f.Write([]byte("contents"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	for _, depth := range []int{0, 1} {
+		r, err := NewReaderWithOptions(bytes.NewReader(b.Bytes()), int64(b.Len()), WithPassword("cancel"))
+		require.NoError(t, err)
+
+		// Raise the rounds of key derivation so it would take far longer
+		// than the test
+		for _, c := range r.si.unpackInfo.folder[0].coder {
+			if bytes.Equal(c.id, []byte{0x06, 0xf1, 0x07, 0x01}) {
+				c.properties[0] = c.properties[0]&^0x3f | 40
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+
+		start := time.Now()
+		err = r.Extract(ctx, func(_ *File, r io.Reader) error {
+			_, err := io.Copy(io.Discard, r)
+
+			return err //nolint:wrapcheck
+		}, WithWriteQueue(depth))
+
+		cancel()
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 5*time.Second)
+	}
+}
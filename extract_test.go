@@ -0,0 +1,486 @@
+package sevenzip_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/javi11/sevenzip"
+	"github.com/javi11/sevenzip/sevenziptest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtract(t *testing.T) {
+	t.Parallel()
+
+	tables := []struct {
+		name, file string
+		opts       []sevenzip.ReaderOption
+		extract    []sevenzip.ExtractOption
+	}{
+		{
+			name: "default",
+			file: "lzma1900.7z",
+		},
+		{
+			name: "single stream at a time",
+			file: "lzma1900.7z",
+			opts: []sevenzip.ReaderOption{sevenzip.WithConcurrency(1)},
+		},
+		{
+			name: "empty streams and files",
+			file: "empty.7z",
+		},
+		{
+			name:    "write queue",
+			file:    "lzma1900.7z",
+			extract: []sevenzip.ExtractOption{sevenzip.WithWriteQueue(4)},
+		},
+		{
+			name:    "write queue of one",
+			file:    "lzma1900.7z",
+			opts:    []sevenzip.ReaderOption{sevenzip.WithConcurrency(1)},
+			extract: []sevenzip.ExtractOption{sevenzip.WithWriteQueue(1)},
+		},
+		{
+			name:    "write queue with empty streams and files",
+			file:    "empty.7z",
+			extract: []sevenzip.ExtractOption{sevenzip.WithWriteQueue(4)},
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := sevenzip.OpenReaderWithOptions(filepath.Join("testdata", table.file), table.opts...)
+			require.NoError(t, err)
+
+			defer func() {
+				require.NoError(t, r.Close())
+			}()
+
+			var (
+				mu   sync.Mutex
+				seen = make(map[*sevenzip.File]struct{}, len(r.File))
+			)
+
+			err = r.Extract(context.Background(), func(f *sevenzip.File, rc io.Reader) error {
+				if err := extractFile(t, rc, crc32.NewIEEE(), f); err != nil {
+					return err
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				seen[f] = struct{}{}
+
+				return nil
+			}, table.extract...)
+			require.NoError(t, err)
+			assert.Len(t, seen, len(r.File))
+		})
+	}
+}
+
+func TestExtractFileDone(t *testing.T) {
+	t.Parallel()
+
+	b, err := os.ReadFile(filepath.Join("testdata", "copy.7z"))
+	require.NoError(t, err)
+
+	// Corrupt the stored contents of the first file
+	b[100] ^= 0xff
+
+	r, err := sevenzip.NewReaderWithOptions(bytes.NewReader(b), int64(len(b)), sevenzip.WithConcurrency(1))
+	require.NoError(t, err)
+
+	var (
+		mu      sync.Mutex
+		results = make(map[string]sevenzip.ExtractResult, len(r.File))
+	)
+
+	done := func(res sevenzip.ExtractResult) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		results[res.File.Name] = res
+	}
+
+	errShort := errors.New("short read")
+
+	err = r.Extract(context.Background(), func(f *sevenzip.File, rc io.Reader) error {
+		if f.Name == "02" {
+			_, err := io.CopyN(io.Discard, rc, 10)
+			require.NoError(t, err)
+
+			return errShort
+		}
+
+		_, err := io.Copy(io.Discard, rc)
+
+		return err
+	}, sevenzip.WithFileDone(done))
+	require.ErrorIs(t, err, errShort)
+
+	assert.False(t, results["01"].CRCOK)
+	assert.NoError(t, results["01"].Err)
+	assert.Equal(t, int64(3572), results["01"].Written)
+
+	assert.False(t, results["02"].CRCOK)
+	assert.ErrorIs(t, results["02"].Err, errShort)
+	assert.Equal(t, int64(10), results["02"].Written)
+
+	for name, res := range results {
+		assert.Equal(t, name, res.File.Name)
+		assert.Positive(t, res.Duration, name)
+
+		if name != "01" && name != "02" {
+			assert.True(t, res.CRCOK, name)
+			assert.Equal(t, res.File.UncompressedSize, uint64(res.Written), name)
+		}
+	}
+}
+
+func TestHashFilesConcurrent(t *testing.T) {
+	t.Parallel()
+
+	buf := new(bytes.Buffer)
+	w := sevenzip.NewWriter(buf, sevenzip.WithSolidBlockFiles(3))
+	want := make(map[string][sha256.Size]byte)
+
+	for i := range 40 {
+		name := fmt.Sprintf("%02d", i)
+		contents := bytes.Repeat([]byte(name), i*100)
+
+		fw, err := w.Create(name)
+		require.NoError(t, err)
+
+		_, err = fw.Write(contents)
+		require.NoError(t, err)
+
+		want[name] = sha256.Sum256(contents)
+	}
+
+	_, err := w.Create("dir/")
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	want["dir/"] = sha256.Sum256(nil)
+
+	r, err := sevenzip.NewReaderWithOptions(bytes.NewReader(buf.Bytes()), int64(buf.Len()), sevenzip.WithConcurrency(8))
+	require.NoError(t, err)
+
+	sums, err := r.HashFiles(context.Background(), sha256.New)
+	require.NoError(t, err)
+	require.Len(t, sums, len(want))
+
+	for f, sum := range sums {
+		expected := want[f.Name]
+		assert.Equal(t, expected[:], sum, f.Name)
+	}
+
+	// Only some of the files, and any WithFileDone function is still called
+	var done atomic.Int64
+
+	sums, err = r.HashFiles(context.Background(), sha256.New, sevenzip.WithFiles(r.File[10:20]),
+		sevenzip.WithFileDone(func(res sevenzip.ExtractResult) {
+			assert.True(t, res.CRCOK, res.File.Name)
+			done.Add(1)
+		}))
+	require.NoError(t, err)
+	assert.Len(t, sums, 10)
+	assert.Equal(t, int64(10), done.Load())
+
+	for _, f := range r.File[10:20] {
+		expected := want[f.Name]
+		assert.Equal(t, expected[:], sums[f], f.Name)
+	}
+}
+
+func TestExtractLimits(t *testing.T) {
+	t.Parallel()
+
+	r, err := sevenzip.OpenReader(filepath.Join("testdata", "lzma1900.7z"))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, r.Close())
+	})
+
+	var size int64
+	for _, f := range r.File {
+		size += int64(f.UncompressedSize)
+	}
+
+	files := int64(len(r.File))
+
+	tables := []struct {
+		name  string
+		opts  []sevenzip.ExtractOption
+		err   error
+		limit int64
+	}{
+		{
+			name: "bytes within limit",
+			opts: []sevenzip.ExtractOption{sevenzip.WithMaxOutputBytes(size)},
+		},
+		{
+			name:  "bytes over limit",
+			opts:  []sevenzip.ExtractOption{sevenzip.WithMaxOutputBytes(size / 2)},
+			err:   sevenzip.ErrLimitExceeded,
+			limit: size / 2,
+		},
+		{
+			name:  "bytes over limit with write queue",
+			opts:  []sevenzip.ExtractOption{sevenzip.WithMaxOutputBytes(size / 2), sevenzip.WithWriteQueue(4)},
+			err:   sevenzip.ErrLimitExceeded,
+			limit: size / 2,
+		},
+		{
+			name: "files within limit",
+			opts: []sevenzip.ExtractOption{sevenzip.WithMaxOutputFiles(files)},
+		},
+		{
+			name: "files over limit",
+			opts: []sevenzip.ExtractOption{sevenzip.WithMaxOutputFiles(files - 1)},
+			err:  sevenzip.ErrLimitExceeded,
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			var read atomic.Int64
+
+			err := r.Extract(context.Background(), func(_ *sevenzip.File, rc io.Reader) error {
+				n, err := io.Copy(io.Discard, rc)
+				read.Add(n)
+
+				return err
+			}, table.opts...)
+
+			if table.err == nil {
+				require.NoError(t, err)
+				assert.Equal(t, size, read.Load())
+
+				return
+			}
+
+			require.ErrorIs(t, err, table.err)
+
+			if table.limit > 0 {
+				assert.LessOrEqual(t, read.Load(), table.limit)
+			}
+		})
+	}
+}
+
+func TestExtractError(t *testing.T) {
+	t.Parallel()
+
+	r, err := sevenzip.OpenReader(filepath.Join("testdata", "lzma1900.7z"))
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, r.Close())
+	}()
+
+	errStop := errors.New("stop")
+
+	err = r.Extract(context.Background(), func(_ *sevenzip.File, _ io.Reader) error {
+		return errStop
+	})
+	assert.ErrorIs(t, err, errStop)
+
+	// Returning without consuming the queue mustn't stall the decoder
+	err = r.Extract(context.Background(), func(_ *sevenzip.File, _ io.Reader) error {
+		return errStop
+	}, sevenzip.WithWriteQueue(1))
+	assert.ErrorIs(t, err, errStop)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = r.Extract(ctx, func(_ *sevenzip.File, _ io.Reader) error {
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestExtractPriority(t *testing.T) {
+	t.Parallel()
+
+	r, err := sevenzip.OpenReaderWithOptions(filepath.Join("testdata", "lzma1900.7z"), sevenzip.WithConcurrency(1))
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, r.Close())
+	}()
+
+	// Pick a file from the last stream so it would normally be extracted last
+	var target *sevenzip.File
+
+	for _, f := range r.File {
+		if target == nil || f.Stream > target.Stream {
+			target = f
+		}
+	}
+
+	require.NotNil(t, target)
+	require.Positive(t, target.Stream)
+
+	var order []*sevenzip.File
+
+	err = r.Extract(context.Background(), func(f *sevenzip.File, _ io.Reader) error {
+		order = append(order, f)
+
+		return nil
+	}, sevenzip.WithPriority(func(f *sevenzip.File) int {
+		if f == target {
+			return 1
+		}
+
+		return 0
+	}))
+	require.NoError(t, err)
+	require.Len(t, order, len(r.File))
+
+	// The whole stream containing the target is processed first, in order
+	assert.Equal(t, target.Stream, order[0].Stream)
+	assert.Contains(t, order[:len(order)/2], target)
+
+	for i := 1; i < len(order) && order[i].Stream == target.Stream; i++ {
+		assert.Less(t, indexOf(r.File, order[i-1]), indexOf(r.File, order[i]))
+	}
+}
+
+func indexOf(files []*sevenzip.File, f *sevenzip.File) int {
+	for i, v := range files {
+		if v == f {
+			return i
+		}
+	}
+
+	return -1
+}
+
+//nolint:paralleltest
+func TestCloseDuringExtract(t *testing.T) {
+	for _, file := range []string{"lzma1900.7z", "zstd.7z"} {
+		t.Run(file, func(t *testing.T) {
+			before := runtime.NumGoroutine()
+
+			r, err := sevenzip.OpenReader(filepath.Join("testdata", file))
+			require.NoError(t, err)
+
+			started, closed := make(chan struct{}), make(chan struct{})
+
+			var once sync.Once
+
+			errc := make(chan error, 1)
+
+			go func() {
+				errc <- r.Extract(context.Background(), func(_ *sevenzip.File, r io.Reader) error {
+					once.Do(func() {
+						close(started)
+						<-closed
+					})
+
+					_, err := io.Copy(io.Discard, r)
+
+					return err
+				})
+			}()
+
+			<-started
+
+			require.NoError(t, r.Close())
+			close(closed)
+			assert.ErrorIs(t, <-errc, sevenzip.ErrReaderClosed)
+
+			_, err = r.File[len(r.File)-1].Open()
+			assert.ErrorIs(t, err, sevenzip.ErrReaderClosed)
+
+			// Any decoder goroutines should wind down shortly after. Poll
+			// rather than use assert.Eventually as that starts goroutines
+			// of its own
+			deadline := time.Now().Add(5 * time.Second)
+			for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+		})
+	}
+}
+
+type fakeTB struct {
+	testing.TB
+	failed bool
+}
+
+func (ft *fakeTB) Errorf(string, ...any) {
+	ft.failed = true
+}
+
+func TestOpenHandles(t *testing.T) {
+	t.Parallel()
+
+	r, err := sevenzip.OpenReader(filepath.Join("testdata", "lzma1900.7z"))
+	require.NoError(t, err)
+
+	defer r.Close()
+
+	var rcs []io.ReadCloser
+
+	for _, f := range r.File {
+		if f.UncompressedSize == 0 {
+			continue
+		}
+
+		rc, err := f.Open()
+		require.NoError(t, err)
+
+		rcs = append(rcs, rc)
+
+		if len(rcs) == 3 {
+			break
+		}
+	}
+
+	assert.Equal(t, len(rcs), r.OpenHandles())
+
+	ft := &fakeTB{TB: t}
+	assert.False(t, sevenziptest.AssertClosed(ft, &r.Reader))
+	assert.True(t, ft.failed)
+
+	for _, rc := range rcs {
+		require.NoError(t, rc.Close())
+		require.NoError(t, rc.Close())
+	}
+
+	assert.Equal(t, 0, r.OpenHandles())
+	sevenziptest.AssertClosed(t, &r.Reader)
+
+	require.NoError(t, r.Extract(context.Background(), func(_ *sevenzip.File, r io.Reader) error {
+		_, err := io.Copy(io.Discard, r)
+
+		return err
+	}))
+
+	sevenziptest.AssertClosed(t, &r.Reader)
+}
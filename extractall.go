@@ -0,0 +1,436 @@
+package sevenzip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrPathTooLong is returned by [Reader.ExtractAll] and [Reader.ExtractDir]
+// for a file whose path is too long, or too deeply nested, to be created on
+// the local filesystem. The error names the file; it can still be listed
+// and read through the archive's [fs.FS] interface.
+var ErrPathTooLong = errors.New("sevenzip: path too long")
+
+// maxNameLen is the longest name of a single file or directory supported by
+// all of the common filesystems, see pathLen for how it is measured.
+const maxNameLen = 255
+
+// destination resolves the names of files within the directory dir they are
+// being extracted to.
+type destination struct {
+	dir     string
+	prefix  string
+	opts    *extractOptions
+	journal *journal
+
+	mu   sync.Mutex
+	dirs map[string]time.Time
+}
+
+// path returns the path that f is extracted to, or an empty string if the
+// name is empty once any leading slashes or parent directory elements have
+// been removed, so nothing can be written outside of the directory. If a
+// prefix is set only files below it are extracted, relative to it.
+func (d *destination) path(f *File) string {
+	name := toValidName(f.Name)
+
+	if d.prefix != "" {
+		if !strings.HasPrefix(name, d.prefix+"/") {
+			return ""
+		}
+
+		name = name[len(d.prefix)+1:]
+	}
+
+	name = filepath.FromSlash(name)
+	if name == "." || !filepath.IsLocal(name) {
+		return ""
+	}
+
+	return filepath.Join(d.dir, name)
+}
+
+// directory records the modification time of a directory so it can be set
+// once everything inside it has been written.
+func (d *destination) directory(path string, modTime time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.dirs[path] = modTime
+}
+
+// dirMode returns the mode that directories are created with.
+func (d *destination) dirMode() iofs.FileMode {
+	if d.opts.dirMode != 0 {
+		return d.opts.dirMode
+	}
+
+	return 0o755 //nolint:mnd
+}
+
+// fileMode returns the mode that f is created with.
+func (d *destination) fileMode(f *File) iofs.FileMode {
+	switch {
+	case d.opts.forceMode:
+		return f.Mode().Perm() &^ d.opts.modeMask
+	case f.FileInfo().IsDir():
+		return d.dirMode()
+	default:
+		return 0o644 //nolint:mnd
+	}
+}
+
+// setMode sets the mode and owner of the file or directory at path if
+// either has been requested, regardless of the umask.
+func (d *destination) setMode(path string, mode iofs.FileMode, force bool) error {
+	if force {
+		if err := os.Chmod(path, mode); err != nil {
+			return fmt.Errorf("sevenzip: error setting mode: %w", err)
+		}
+	}
+
+	if d.opts.chown {
+		if err := os.Lchown(path, d.opts.uid, d.opts.gid); err != nil {
+			return fmt.Errorf("sevenzip: error setting owner: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// mkdirAll creates the directory path along with any missing parents.
+func (d *destination) mkdirAll(path string) error {
+	var missing []string
+
+	for p := path; ; p = filepath.Dir(p) {
+		if _, err := os.Lstat(p); err == nil {
+			break
+		} else if !errors.Is(err, iofs.ErrNotExist) {
+			return fmt.Errorf("sevenzip: error retrieving file info: %w", err)
+		}
+
+		missing = append(missing, p)
+
+		if filepath.Dir(p) == p {
+			break
+		}
+	}
+
+	if err := os.MkdirAll(path, d.dirMode()); err != nil {
+		return fmt.Errorf("sevenzip: error creating directory: %w", err)
+	}
+
+	if d.journal != nil {
+		d.journal.directories(missing)
+	}
+
+	for _, p := range missing {
+		if err := d.setMode(p, d.dirMode(), d.opts.dirMode != 0); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// create creates the file at path that will hold size bytes.
+func (d *destination) create(path string, size uint64, mode iofs.FileMode) (io.WriteCloser, error) {
+	var (
+		w  io.WriteCloser
+		f  *os.File
+		dw *directWriter
+	)
+
+	if d.opts.directIOMin > 0 && size >= uint64(d.opts.directIOMin) {
+		var err error
+		if dw, err = openDirect(path, mode); err != nil {
+			return nil, err
+		}
+	}
+
+	if dw != nil {
+		w, f = dw, dw.f
+	} else {
+		var err error
+		if f, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode); err != nil {
+			return nil, fmt.Errorf("sevenzip: error creating file: %w", err)
+		}
+
+		w = f
+	}
+
+	// A temporary file already exists so its mode needs setting
+	if d.opts.forceMode || d.opts.atomic {
+		if err := f.Chmod(mode); err != nil {
+			return nil, errors.Join(fmt.Errorf("sevenzip: error setting mode: %w", err), w.Close())
+		}
+	}
+
+	if d.opts.chown {
+		if err := f.Chown(d.opts.uid, d.opts.gid); err != nil {
+			return nil, errors.Join(fmt.Errorf("sevenzip: error setting owner: %w", err), w.Close())
+		}
+	}
+
+	if d.opts.preallocate && size > 0 {
+		if err := preallocate(f, int64(size)); err != nil { //nolint:gosec
+			return nil, errors.Join(fmt.Errorf("sevenzip: error preallocating %s: %w", path, err), w.Close())
+		}
+	}
+
+	return w, nil
+}
+
+// checkPath returns an error if path, which f is extracted to, can't be
+// created, so nothing is created for a file that would only fail part of
+// the way through its parent directories.
+func checkPath(f *File, path string) error {
+	if n := pathLen(path); n > maxPathLen {
+		return fmt.Errorf("%w: %s: path length %d exceeds %d", ErrPathTooLong, f.Name, n, maxPathLen)
+	}
+
+	for _, elem := range strings.Split(path, string(filepath.Separator)) {
+		if n := pathLen(elem); n > maxNameLen {
+			return fmt.Errorf("%w: %s: name length %d exceeds %d", ErrPathTooLong, f.Name, n, maxNameLen)
+		}
+	}
+
+	return nil
+}
+
+func (d *destination) extract(f *File, r io.Reader) error {
+	path := d.path(f)
+	if path == "" {
+		return nil
+	}
+
+	if err := checkPath(f, path); err != nil {
+		return err
+	}
+
+	var err error
+
+	if f.FileInfo().IsDir() {
+		err = d.extractDir(f, path)
+	} else {
+		err = d.extractFile(f, path, r)
+	}
+
+	// The system may have a lower limit than checked for
+	if errors.Is(err, syscall.ENAMETOOLONG) {
+		return fmt.Errorf("%w: %s: %w", ErrPathTooLong, f.Name, err)
+	}
+
+	if err != nil || d.opts.restore == nil {
+		return err
+	}
+
+	if err := d.opts.restore(f, path); err != nil {
+		return fmt.Errorf("sevenzip: error restoring %s: %w", f.Name, err)
+	}
+
+	return nil
+}
+
+func (d *destination) extractDir(f *File, path string) error {
+	if err := d.mkdirAll(path); err != nil {
+		return err
+	}
+
+	if err := d.setMode(path, d.fileMode(f), d.opts.forceMode); err != nil {
+		return err
+	}
+
+	d.directory(path, f.Modified)
+
+	return nil
+}
+
+func (d *destination) extractFile(f *File, path string, r io.Reader) (err error) {
+	if err := d.mkdirAll(filepath.Dir(path)); err != nil {
+		return err
+	}
+
+	if d.journal != nil {
+		if err := d.journal.file(path); err != nil {
+			return err
+		}
+	}
+
+	target := path
+
+	if d.opts.atomic {
+		if target, err = tempPath(path); err != nil {
+			return err
+		}
+	}
+
+	w, err := d.create(target, f.UncompressedSize, d.fileMode(f))
+	if err != nil {
+		return errors.Join(err, removeTemp(target, path))
+	}
+
+	defer func() {
+		if cerr := w.Close(); cerr != nil {
+			err = errors.Join(err, fmt.Errorf("sevenzip: error closing %s: %w", target, cerr))
+		}
+
+		if err == nil && !f.Modified.IsZero() {
+			if terr := os.Chtimes(target, f.Modified, f.Modified); terr != nil {
+				err = fmt.Errorf("sevenzip: error setting times: %w", terr)
+			}
+		}
+
+		if target == path {
+			return
+		}
+
+		if err != nil {
+			err = errors.Join(err, removeTemp(target, path))
+
+			return
+		}
+
+		if rerr := os.Rename(target, path); rerr != nil {
+			err = errors.Join(fmt.Errorf("sevenzip: error renaming %s: %w", target, rerr), removeTemp(target, path))
+		}
+	}()
+
+	if !d.opts.atomic {
+		if _, err := io.Copy(w, r); err != nil {
+			return fmt.Errorf("sevenzip: error extracting %s: %w", f.Name, err)
+		}
+
+		return nil
+	}
+
+	h := crc32.NewIEEE()
+
+	if _, err := io.Copy(io.MultiWriter(w, h), r); err != nil {
+		return fmt.Errorf("sevenzip: error extracting %s: %w", f.Name, err)
+	}
+
+	if crc, ok := f.Digest(); ok && h.Sum32() != crc {
+		return fmt.Errorf("sevenzip: error extracting %s: %w", f.Name, errChecksum)
+	}
+
+	return nil
+}
+
+// tempPath creates an empty file alongside path to extract to before it is
+// renamed into place.
+func tempPath(path string) (string, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("sevenzip: error creating temporary file: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return "", errors.Join(fmt.Errorf("sevenzip: error closing: %w", err), os.Remove(f.Name()))
+	}
+
+	return f.Name(), nil
+}
+
+// removeTemp removes the temporary file target unless it is the final path.
+func removeTemp(target, path string) error {
+	if target == path {
+		return nil
+	}
+
+	if err := os.Remove(target); err != nil && !errors.Is(err, iofs.ErrNotExist) {
+		return fmt.Errorf("sevenzip: error removing temporary file: %w", err)
+	}
+
+	return nil
+}
+
+// ExtractAll extracts every file in the archive to the directory dir, which
+// is created if necessary, using [Reader.Extract] so the same options apply.
+// Names are sanitised so nothing is written outside of dir and the
+// modification times of files and directories are restored.
+func (z *Reader) ExtractAll(ctx context.Context, dir string, opts ...ExtractOption) error {
+	return z.extractTo(ctx, dir, "", opts)
+}
+
+// ExtractDir extracts the files below the directory name in the archive, a
+// slash separated path as accepted by [Reader.Open], to the directory dir
+// in the same way as [Reader.ExtractAll], with their names relative to name.
+// Only the files below name are visited, so extracting a small part of a
+// very large archive doesn't depend on the number of files in it, although
+// any files preceding them in the same stream still have to be decoded.
+func (z *Reader) ExtractDir(ctx context.Context, name, dir string, opts ...ExtractOption) error {
+	n, err := z.dir("extract", name)
+	if err != nil {
+		return err
+	}
+
+	prefix := n.entry.name
+	if name == "." {
+		prefix = ""
+	}
+
+	return z.extractTo(ctx, dir, prefix, append(opts[:len(opts):len(opts)], WithFiles(n.files())))
+}
+
+func (z *Reader) extractTo(ctx context.Context, dir, prefix string, opts []ExtractOption) (err error) {
+	o := new(extractOptions)
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	d := &destination{
+		dir:    dir,
+		prefix: prefix,
+		opts:   o,
+		dirs:   make(map[string]time.Time),
+	}
+
+	if o.rollback {
+		d.journal = newJournal()
+
+		defer func() {
+			if err != nil {
+				err = errors.Join(err, d.journal.rollback())
+			} else {
+				err = d.journal.commit()
+			}
+		}()
+	}
+
+	if err := d.mkdirAll(dir); err != nil {
+		return err
+	}
+
+	skip := func(o *extractOptions) {
+		o.skip = func(f *File) bool {
+			return d.path(f) == ""
+		}
+	}
+
+	if err := z.Extract(ctx, d.extract, append(opts[:len(opts):len(opts)], skip)...); err != nil {
+		return err
+	}
+
+	for path, modTime := range d.dirs {
+		if modTime.IsZero() {
+			continue
+		}
+
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			return fmt.Errorf("sevenzip: error setting times: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package sevenzip
+
+import (
+	"bytes"
+	"io"
+	iofs "io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDestinationPath(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.FromSlash("/tmp/dest")
+	d := &destination{dir: dir}
+
+	tables := []struct {
+		name, expected string
+	}{
+		{"file.txt", "file.txt"},
+		{"dir/file.txt", "dir/file.txt"},
+		{"../../etc/passwd", "etc/passwd"},
+		{"/etc/passwd", "etc/passwd"},
+		{`..\..\evil.exe`, "evil.exe"},
+		{"dir/../../evil", "evil"},
+		{"..", ""},
+		{"/", ""},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			f := &File{FileHeader: FileHeader{Name: table.name}}
+
+			expected := ""
+			if table.expected != "" {
+				expected = filepath.Join(dir, filepath.FromSlash(table.expected))
+			}
+
+			assert.Equal(t, expected, d.path(f))
+		})
+	}
+}
+
+func TestDirectWriter(t *testing.T) {
+	t.Parallel()
+
+	for _, size := range []int{0, 1, directAlignment, directAlignment + 1, directBufferSize, directBufferSize + 5} {
+		t.Run(strconv.Itoa(size), func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "file")
+
+			w, err := openDirect(path, 0o600)
+			require.NoError(t, err)
+
+			if w == nil {
+				t.Skip("direct I/O not supported")
+			}
+
+			b := make([]byte, size)
+			_, _ = rand.New(rand.NewSource(int64(size))).Read(b) //nolint:gosec
+
+			// Write in odd sized pieces to exercise the buffering
+			_, err = io.CopyBuffer(w, struct{ io.Reader }{bytes.NewReader(b)}, make([]byte, 1000))
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+
+			actual, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.True(t, bytes.Equal(b, actual))
+
+			// Created with the mode asked for, rather than a fixed one
+			info, err := os.Stat(path)
+			require.NoError(t, err)
+			assert.Equal(t, iofs.FileMode(0o600), info.Mode().Perm())
+		})
+	}
+}
@@ -0,0 +1,432 @@
+package sevenzip_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractAll(t *testing.T) {
+	t.Parallel()
+
+	tables := []struct {
+		name, file string
+		opts       []sevenzip.ExtractOption
+	}{
+		{
+			name: "default",
+			file: "lzma1900.7z",
+		},
+		{
+			name: "write queue",
+			file: "lzma1900.7z",
+			opts: []sevenzip.ExtractOption{sevenzip.WithWriteQueue(8)},
+		},
+		{
+			name: "empty streams and files",
+			file: "empty.7z",
+		},
+		{
+			name: "preallocate",
+			file: "lzma1900.7z",
+			opts: []sevenzip.ExtractOption{sevenzip.WithPreallocate()},
+		},
+		{
+			name: "direct io",
+			file: "lzma1900.7z",
+			opts: []sevenzip.ExtractOption{sevenzip.WithDirectIO(1), sevenzip.WithPreallocate()},
+		},
+		{
+			name: "atomic writes",
+			file: "lzma1900.7z",
+			opts: []sevenzip.ExtractOption{sevenzip.WithAtomicWrites(), sevenzip.WithWriteQueue(8)},
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := sevenzip.OpenReader(filepath.Join("testdata", table.file))
+			require.NoError(t, err)
+
+			defer func() {
+				require.NoError(t, r.Close())
+			}()
+
+			dir := t.TempDir()
+
+			require.NoError(t, r.ExtractAll(context.Background(), dir, table.opts...))
+
+			for _, f := range r.File {
+				path := filepath.Join(dir, filepath.FromSlash(f.Name))
+
+				info, err := os.Stat(path)
+				require.NoError(t, err, f.Name)
+				assert.Equal(t, f.FileInfo().IsDir(), info.IsDir(), f.Name)
+
+				if !f.Modified.IsZero() {
+					assert.True(t, f.Modified.Equal(info.ModTime()), f.Name)
+				}
+
+				if info.IsDir() {
+					continue
+				}
+
+				b, err := os.ReadFile(path)
+				require.NoError(t, err)
+				assert.Equal(t, f.UncompressedSize, uint64(len(b)), f.Name)
+
+				if f.CRC32 != 0 {
+					assert.Equal(t, f.CRC32, crc32.ChecksumIEEE(b), f.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractAllAtomicWrites(t *testing.T) {
+	t.Parallel()
+
+	b, err := os.ReadFile(filepath.Join("testdata", "copy.7z"))
+	require.NoError(t, err)
+
+	// Corrupt the stored contents of the first file
+	b[100] ^= 0xff
+
+	name := filepath.Join(t.TempDir(), "copy.7z")
+	require.NoError(t, os.WriteFile(name, b, 0o600))
+
+	r, err := sevenzip.OpenReader(name)
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, r.Close())
+	}()
+
+	dir := t.TempDir()
+
+	err = r.ExtractAll(context.Background(), dir, sevenzip.WithAtomicWrites())
+	require.ErrorIs(t, err, sevenzip.ErrChecksum)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	for _, e := range entries {
+		assert.NotEqual(t, "01", e.Name())
+		assert.NotEqual(t, ".tmp", filepath.Ext(e.Name()), e.Name())
+	}
+
+	// Without the option the corrupt file is left in place
+	dir = t.TempDir()
+
+	require.NoError(t, r.ExtractAll(context.Background(), dir))
+	assert.FileExists(t, filepath.Join(dir, "01"))
+}
+
+func TestExtractAllRollback(t *testing.T) {
+	t.Parallel()
+
+	b, err := os.ReadFile(filepath.Join("testdata", "copy.7z"))
+	require.NoError(t, err)
+
+	good := bytes.Clone(b)
+
+	// Corrupt the stored contents of the fifth file so extraction fails
+	// after the earlier files have been written
+	b[13400] ^= 0xff
+
+	tables := []struct {
+		name    string
+		archive []byte
+		err     error
+	}{
+		{
+			name:    "success",
+			archive: good,
+		},
+		{
+			name:    "failure",
+			archive: b,
+			err:     sevenzip.ErrChecksum,
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := sevenzip.NewReaderWithOptions(bytes.NewReader(table.archive), int64(len(table.archive)), sevenzip.WithConcurrency(1))
+			require.NoError(t, err)
+
+			opts := []sevenzip.ExtractOption{sevenzip.WithAtomicWrites(), sevenzip.WithRollback()}
+
+			// A destination that doesn't exist yet
+			root := t.TempDir()
+			dir := filepath.Join(root, "a", "b")
+
+			err = r.ExtractAll(context.Background(), dir, opts...)
+			require.ErrorIs(t, err, table.err)
+
+			entries, err := os.ReadDir(root)
+			require.NoError(t, err)
+
+			if table.err != nil {
+				assert.Empty(t, entries)
+			} else {
+				assert.Len(t, entries, 1)
+			}
+
+			// A destination with existing files, one of which is
+			// overwritten
+			dir = t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "03"), []byte("original"), 0o600))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "keep"), []byte("keep"), 0o600))
+
+			err = r.ExtractAll(context.Background(), dir, opts...)
+			require.ErrorIs(t, err, table.err)
+
+			entries, err = os.ReadDir(dir)
+			require.NoError(t, err)
+
+			if table.err != nil {
+				require.Len(t, entries, 2)
+				assert.Equal(t, "03", entries[0].Name())
+				assert.Equal(t, "keep", entries[1].Name())
+
+				b, err := os.ReadFile(filepath.Join(dir, "03"))
+				require.NoError(t, err)
+				assert.Equal(t, "original", string(b))
+			} else {
+				assert.Len(t, entries, len(r.File)+1)
+
+				info, err := os.Stat(filepath.Join(dir, "03"))
+				require.NoError(t, err)
+				assert.Equal(t, int64(r.File[2].UncompressedSize), info.Size())
+			}
+		})
+	}
+}
+
+func TestExtractAllMode(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes aren't supported on Windows")
+	}
+
+	r, err := sevenzip.OpenReader(filepath.Join("testdata", "empty.7z"))
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, r.Close())
+	}()
+
+	dir := filepath.Join(t.TempDir(), "a", "b")
+
+	require.NoError(t, r.ExtractAll(context.Background(), dir,
+		sevenzip.WithModeMask(0o077),
+		sevenzip.WithDirMode(0o750),
+		sevenzip.WithOwner(os.Getuid(), os.Getgid()),
+		sevenzip.WithAtomicWrites()))
+
+	for _, path := range []string{filepath.Dir(dir), dir} {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o750), info.Mode().Perm(), path)
+	}
+
+	for _, f := range r.File {
+		info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(f.Name)))
+		require.NoError(t, err)
+		assert.Equal(t, f.Mode().Perm()&^0o077, info.Mode().Perm(), f.Name)
+	}
+}
+
+func TestExtractAllRestoreFunc(t *testing.T) {
+	t.Parallel()
+
+	r, err := sevenzip.OpenReader(filepath.Join("testdata", "empty.7z"))
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, r.Close())
+	}()
+
+	dir := t.TempDir()
+
+	var (
+		mu    sync.Mutex
+		paths = make(map[string]string, len(r.File))
+	)
+
+	err = r.ExtractAll(context.Background(), dir, sevenzip.WithAtomicWrites(), sevenzip.WithRestoreFunc(func(f *sevenzip.File, path string) error {
+		// The file is already in place
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		assert.Equal(t, f.FileInfo().IsDir(), info.IsDir(), f.Name)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		paths[f.Name] = path
+
+		return nil
+	}))
+	require.NoError(t, err)
+	require.Len(t, paths, len(r.File))
+
+	for _, f := range r.File {
+		assert.Equal(t, filepath.Join(dir, filepath.FromSlash(f.Name)), paths[f.Name])
+	}
+
+	errRestore := errors.New("restore failed")
+
+	err = r.ExtractAll(context.Background(), t.TempDir(), sevenzip.WithRestoreFunc(func(*sevenzip.File, string) error {
+		return errRestore
+	}))
+	assert.ErrorIs(t, err, errRestore)
+}
+
+func TestExtractDir(t *testing.T) {
+	t.Parallel()
+
+	r, err := sevenzip.OpenReader(filepath.Join("testdata", "lzma1900.7z"))
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, r.Close())
+	}()
+
+	dir := t.TempDir()
+
+	require.NoError(t, r.ExtractDir(context.Background(), "Asm/x86", dir))
+
+	want := make(map[string]uint32)
+
+	for _, f := range r.File {
+		if rel, ok := strings.CutPrefix(f.Name, "Asm/x86/"); ok && !f.FileInfo().IsDir() {
+			want[filepath.FromSlash(rel)] = f.CRC32
+		}
+	}
+
+	require.NotEmpty(t, want)
+
+	got := make(map[string]uint32)
+
+	err = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		got[rel] = crc32.ChecksumIEEE(b)
+
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	dir = t.TempDir()
+
+	require.NoError(t, r.ExtractDir(context.Background(), ".", dir))
+
+	for _, f := range r.File {
+		_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(f.Name)))
+		assert.NoError(t, err, f.Name)
+	}
+
+	err = r.ExtractDir(context.Background(), "missing", t.TempDir())
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	err = r.ExtractDir(context.Background(), "bin/x64/7zr.exe", t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestExtractAllPathTooLong(t *testing.T) {
+	t.Parallel()
+
+	tables := []struct {
+		name, file, created string
+	}{
+		{
+			name:    "path",
+			file:    strings.Repeat(strings.Repeat("x", 200)+"/", 30) + "long.txt",
+			created: strings.Repeat("x", 200),
+		},
+		{
+			name:    "element",
+			file:    "dir/" + strings.Repeat("y", 300) + ".txt",
+			created: "dir",
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := archiveOf(t, table.file)
+			dir := t.TempDir()
+
+			err := r.ExtractAll(context.Background(), dir)
+			require.ErrorIs(t, err, sevenzip.ErrPathTooLong)
+			assert.Contains(t, err.Error(), table.file)
+
+			// Nothing was created for the file
+			_, err = os.Stat(filepath.Join(dir, table.created))
+			assert.ErrorIs(t, err, os.ErrNotExist)
+		})
+	}
+
+	// Deep nesting on its own is fine
+	name := strings.Repeat("d/", 300) + "deep.txt"
+	dir := t.TempDir()
+
+	require.NoError(t, archiveOf(t, name).ExtractAll(context.Background(), dir))
+
+	b, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	require.NoError(t, err)
+	assert.Equal(t, "deep.txt", string(b))
+}
+
+func TestExtractAllMaxOutputFilesSkipped(t *testing.T) {
+	t.Parallel()
+
+	// The unsafe names are skipped so only a.txt and b.txt count
+	r := archiveOf(t, "..", "a.txt", "../..", "b.txt", "dir/..")
+
+	dir := t.TempDir()
+	require.NoError(t, r.ExtractAll(context.Background(), dir, sevenzip.WithMaxOutputFiles(2)))
+
+	for _, name := range []string{"a.txt", "b.txt"} {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		assert.Equal(t, name, string(b))
+	}
+
+	err := r.ExtractAll(context.Background(), t.TempDir(), sevenzip.WithMaxOutputFiles(1))
+	require.ErrorIs(t, err, sevenzip.ErrLimitExceeded)
+}
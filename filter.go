@@ -0,0 +1,204 @@
+package sevenzip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"sync"
+)
+
+// A filterWriter returns an io.WriteCloser that applies a filter, configured
+// by the properties p, to what is written to it before writing it to wc.
+type filterWriter func(p []byte, wc io.WriteCloser) (io.WriteCloser, error)
+
+var (
+	//nolint:gochecknoglobals
+	filterWriters sync.Map
+)
+
+// registerFilterWriter allows a filter for a method ID to be written, only
+// done where the same filter can also be read.
+func registerFilterWriter(method []byte, fw filterWriter) {
+	filterWriters.Store(string(method), fw)
+}
+
+//nolint:gochecknoglobals
+var (
+	filterBCJ   = []byte{0x03, 0x03, 0x01, 0x03}
+	filterPPC   = []byte{0x03, 0x03, 0x02, 0x05}
+	filterIA64  = []byte{0x03, 0x03, 0x04, 0x01}
+	filterARM   = []byte{0x03, 0x03, 0x05, 0x01}
+	filterARMT  = []byte{0x03, 0x03, 0x07, 0x01}
+	filterSPARC = []byte{0x03, 0x03, 0x08, 0x05}
+	filterRISCV = []byte{0x0b}
+	filterDelta = []byte{0x03}
+)
+
+// A filter is applied to the contents of a block before compressing them.
+type filter struct {
+	id, props []byte
+}
+
+func (f *filter) equal(o *filter) bool {
+	if f == nil || o == nil {
+		return f == o
+	}
+
+	return bytes.Equal(f.id, o.id) && bytes.Equal(f.props, o.props)
+}
+
+// writer returns the writer applying the filter, or nil if it isn't
+// registered.
+func (f *filter) writer() filterWriter {
+	if fw, ok := filterWriters.Load(string(f.id)); ok {
+		if fw, ok := fw.(filterWriter); ok {
+			return fw
+		}
+	}
+
+	return nil
+}
+
+// WithAutoFilters makes the [Writer] look at the start of the contents of
+// each file compressed with LZMA2 and filter them first where that helps,
+// the same as 7-zip does. Executables for x86, ARM, PowerPC and SPARC, in
+// PE, ELF or Mach-O format, are filtered with the matching branch converter,
+// such as BCJ, and uncompressed WAV audio with Delta. Files needing
+// different filters are never compressed together in the same solid block.
+//
+// Only the first write to a file is looked at, which is enough for
+// [io.Copy] and the like. Filters aren't available when built with the
+// sevenzip_nobcj, sevenzip_nodelta or sevenzip_minimal tags, in which case
+// files are compressed without them.
+func WithAutoFilters() WriterOption {
+	return func(o *writerOptions) {
+		o.filters = true
+	}
+}
+
+// filter returns the filter for a file compressed with method m whose
+// contents start with b, or nil if it shouldn't be filtered.
+func (o *writerOptions) filter(m Method, b []byte) *filter {
+	if !o.filters || m != MethodLZMA2 {
+		return nil
+	}
+
+	f := detectFilter(b)
+	if f == nil || f.writer() == nil {
+		return nil
+	}
+
+	return f
+}
+
+// detectFilter returns the filter suiting contents starting with b.
+//
+//nolint:cyclop,mnd
+func detectFilter(b []byte) *filter {
+	var id []byte
+
+	switch {
+	case len(b) >= 0x40 && bytes.HasPrefix(b, []byte("MZ")):
+		id = peFilter(b)
+	case len(b) >= 20 && bytes.HasPrefix(b, []byte("\x7fELF")):
+		id = elfFilter(b)
+	case len(b) >= 8:
+		id = machoFilter(b)
+	}
+
+	if id != nil {
+		return &filter{id: id}
+	}
+
+	// Uncompressed WAV audio, with the distance being the size of one
+	// sample across all channels
+	if len(b) >= 36 && bytes.HasPrefix(b, []byte("RIFF")) &&
+		string(b[8:16]) == "WAVEfmt " && binary.LittleEndian.Uint16(b[20:]) == 1 {
+		if align := binary.LittleEndian.Uint16(b[32:]); align > 0 && align <= 256 {
+			return &filter{id: filterDelta, props: []byte{byte(align - 1)}}
+		}
+	}
+
+	return nil
+}
+
+// peFilter returns the filter for the machine type of a PE executable.
+//
+//nolint:mnd
+func peFilter(b []byte) []byte {
+	off := int(binary.LittleEndian.Uint32(b[0x3c:]))
+	if off < 0 || off > len(b)-6 || string(b[off:off+4]) != "PE\x00\x00" {
+		return nil
+	}
+
+	switch binary.LittleEndian.Uint16(b[off+4:]) {
+	case 0x014c, 0x8664: // i386, AMD64
+		return filterBCJ
+	case 0x01c0: // ARM
+		return filterARM
+	case 0x01c2, 0x01c4: // Thumb, ARMv7 Thumb-2
+		return filterARMT
+	case 0x01f0, 0x01f1: // PowerPC
+		return filterPPC
+	case 0x0200: // IA64
+		return filterIA64
+	case 0x5032, 0x5064: // RISC-V 32-bit, RISC-V 64-bit
+		return filterRISCV
+	default:
+		return nil
+	}
+}
+
+// elfFilter returns the filter for the machine type of an ELF executable.
+//
+//nolint:mnd
+func elfFilter(b []byte) []byte {
+	var order binary.ByteOrder = binary.LittleEndian
+	if b[5] == 2 {
+		order = binary.BigEndian
+	}
+
+	switch order.Uint16(b[18:]) {
+	case 3, 62: // i386, x86-64
+		return filterBCJ
+	case 40: // ARM
+		return filterARM
+	case 20, 21: // PowerPC, PowerPC64
+		return filterPPC
+	case 2, 18, 43: // SPARC, SPARC32PLUS, SPARCV9
+		return filterSPARC
+	case 50: // IA-64
+		return filterIA64
+	case 243: // RISC-V
+		return filterRISCV
+	default:
+		return nil
+	}
+}
+
+// machoFilter returns the filter for the CPU type of a Mach-O executable.
+//
+//nolint:mnd
+func machoFilter(b []byte) []byte {
+	var order binary.ByteOrder
+
+	switch binary.BigEndian.Uint32(b) {
+	case 0xfeedface, 0xfeedfacf:
+		order = binary.BigEndian
+	case 0xcefaedfe, 0xcffaedfe:
+		order = binary.LittleEndian
+	default:
+		return nil
+	}
+
+	switch order.Uint32(b[4:]) {
+	case 7, 0x01000007: // x86, x86-64
+		return filterBCJ
+	case 12: // ARM
+		return filterARM
+	case 18, 0x01000012: // PowerPC, PowerPC64
+		return filterPPC
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,339 @@
+package sevenzip
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"sync"
+
+	"github.com/javi11/sevenzip/internal/util"
+)
+
+const (
+	// streamChunkSize and streamChunks bound how far decoding can run
+	// ahead of the function passed to [Reader.StreamFolderFiles].
+	streamChunkSize = 64 << 10
+	streamChunks    = 16
+)
+
+// A FolderMember is a file stored in a block, located within its decoded
+// stream.
+type FolderMember struct {
+	File *File
+	// Offset is where the contents of the file start in the decoded
+	// stream of the block.
+	Offset int64
+	// Size is the size of the contents of the file.
+	Size int64
+}
+
+// A FolderReader reads the whole decoded stream of a block, as returned by
+// [Reader.OpenFolder].
+type FolderReader struct {
+	rc         *folderReadCloser
+	crc        uint32
+	crcDefined bool
+	n, off     int64
+	member     int
+	memberCRC  hash.Hash32
+	sums       []uint32
+
+	// Members are the files stored in the block, in the order their
+	// contents appear in the stream.
+	Members []FolderMember
+	// Size is the size of the decoded stream, which is the sum of the
+	// sizes of the members unless the Reader was opened with a name
+	// filter.
+	Size int64
+}
+
+// Read reads the decoded stream of the block. The CRC32 of each member is
+// checked as the end of its contents is read, and that of the whole block
+// once everything has been read, with a [*ReadError] returned if either
+// doesn't match. Members without a CRC32 of their own then get one, as
+// returned by [File.Digest].
+func (r *FolderReader) Read(p []byte) (int, error) {
+	if r.n <= 0 {
+		return 0, io.EOF
+	}
+
+	if int64(len(p)) > r.n {
+		p = p[:r.n]
+	}
+
+	n, err := r.rc.Read(p)
+	r.n -= int64(n)
+
+	if cerr := r.check(p[:n]); cerr != nil {
+		return n, cerr
+	}
+
+	switch {
+	case err != nil && !errors.Is(err, io.EOF):
+		return n, readError(r.rc.hasEncryption, err)
+	case r.n > 0 && errors.Is(err, io.EOF):
+		return n, readError(r.rc.hasEncryption, io.ErrUnexpectedEOF)
+	case r.n == 0 && r.crcDefined && !util.CRC32Equal(r.rc.Checksum(), r.crc):
+		return n, readError(r.rc.hasEncryption, errChecksum)
+	case r.n == 0 && r.crcDefined:
+		files := make([]*File, len(r.Members))
+		for i, m := range r.Members {
+			files[i] = m.File
+		}
+
+		setDigests(files, r.sums)
+	}
+
+	return n, nil
+}
+
+// check adds b, the next bytes of the stream, to the CRC32 of the current
+// member and checks it once the member is complete.
+func (r *FolderReader) check(b []byte) (err error) {
+	for len(b) > 0 && r.member < len(r.Members) {
+		m := r.Members[r.member]
+
+		// Skip anything before the member, only possible when files
+		// were left out by a name filter
+		if r.off < m.Offset {
+			skip := min(m.Offset-r.off, int64(len(b)))
+			b, r.off = b[skip:], r.off+skip
+
+			continue
+		}
+
+		take := min(m.Offset+m.Size-r.off, int64(len(b)))
+		_, _ = r.memberCRC.Write(b[:take])
+		b, r.off = b[take:], r.off+take
+
+		if r.off < m.Offset+m.Size {
+			break
+		}
+
+		sum := r.memberCRC.Sum32()
+		r.sums = append(r.sums, sum)
+		r.memberCRC.Reset()
+		r.member++
+
+		if crc, ok := m.File.Digest(); ok && sum != crc && err == nil {
+			err = readError(r.rc.hasEncryption, fmt.Errorf("%s: %w", m.File.Name, errChecksum))
+		}
+	}
+
+	r.off += int64(len(b))
+
+	return err
+}
+
+// Close closes the decoders.
+func (r *FolderReader) Close() error {
+	if err := r.rc.Close(); err != nil {
+		return fmt.Errorf("sevenzip: error closing: %w", err)
+	}
+
+	return nil
+}
+
+// OpenFolder returns a [*FolderReader] for the decoded stream of block i, as
+// listed by [Reader.Blocks], along with where each file stored in it starts
+// and ends. For solid blocks decoding once and splitting the stream into
+// files is the most efficient way to read every file, as opening each file
+// with [File.Open] can't always reuse the decoder of the file before it.
+// The block is decoded by a new set of decoders, bypassing the cache used by
+// [File.Open].
+func (z *Reader) OpenFolder(i int) (*FolderReader, error) {
+	if z.isClosed() {
+		return nil, errReaderClosed
+	}
+
+	if i < 0 || i >= z.si.Folders() {
+		return nil, fmt.Errorf("sevenzip: block %d: %w", i, errNoBlock)
+	}
+
+	r := &FolderReader{
+		Size:      int64(z.si.unpackInfo.folder[i].unpackSize()), //nolint:gosec
+		memberCRC: crc32.NewIEEE(),
+	}
+
+	for _, f := range z.File {
+		if f.isMissing {
+			return nil, &ReadError{Err: errMissingUnpackInfo}
+		}
+
+		if f.HasStream() && f.folder == i {
+			if f.oversized != nil {
+				return nil, f.oversized
+			}
+
+			r.Members = append(r.Members, FolderMember{
+				File:   f,
+				Offset: f.offset,
+				Size:   int64(f.UncompressedSize), //nolint:gosec
+			})
+		}
+	}
+
+	rc, crc, crcDefined, encrypted, err := z.folderReader(z.si, i)
+	if err != nil {
+		return nil, readError(encrypted, err)
+	}
+
+	r.rc, r.crc, r.crcDefined, r.n = rc, crc, crcDefined, r.Size
+
+	return r, nil
+}
+
+// A folderChunk is the next part of the contents of a member, never more
+// than one, and any error reading it.
+type folderChunk struct {
+	b   []byte
+	err error
+}
+
+// stream sends the contents of each member to ch in chunks, skipping anything
+// between them, until everything is read, there's an error or done is closed.
+func (r *FolderReader) stream(ch chan<- folderChunk, done <-chan struct{}) {
+	send := func(c folderChunk) bool {
+		select {
+		case ch <- c:
+			return c.err == nil
+		case <-done:
+			return false
+		}
+	}
+
+	var off int64
+
+	for _, m := range r.Members {
+		if _, err := io.CopyN(io.Discard, r, m.Offset-off); err != nil {
+			send(folderChunk{err: err})
+
+			return
+		}
+
+		for n := m.Size; n > 0; {
+			b := make([]byte, min(n, streamChunkSize))
+
+			k, err := r.Read(b)
+			n -= int64(k)
+
+			if !send(folderChunk{b: b[:k], err: err}) {
+				return
+			}
+		}
+
+		off = m.Offset + m.Size
+	}
+
+	// Read to the end for the CRC32 of the whole block
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		send(folderChunk{err: err})
+	}
+}
+
+// memberReader reads the contents of one member from the chunks sent by
+// [FolderReader.stream].
+type memberReader struct {
+	ch     <-chan folderChunk
+	n      int64
+	b      []byte
+	err    error
+	closed bool
+}
+
+func (r *memberReader) Read(p []byte) (int, error) {
+	if r.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	if len(r.b) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+
+		if r.n <= 0 {
+			return 0, io.EOF
+		}
+
+		c, ok := <-r.ch
+		if !ok {
+			return 0, io.ErrUnexpectedEOF
+		}
+
+		r.b, r.err = c.b, c.err
+	}
+
+	n := copy(p, r.b)
+	r.b, r.n = r.b[n:], r.n-int64(n)
+
+	if len(r.b) == 0 && r.err != nil {
+		return n, r.err
+	}
+
+	return n, nil
+}
+
+// StreamFolderFiles decodes block i, as listed by [Reader.Blocks], once and
+// calls fn in turn for each file stored in it with a reader of its
+// contents, the same files as [FolderReader.Members]. Decoding carries on in
+// another goroutine while fn runs, buffering up to 1 MiB ahead of what fn has
+// read, so that fn can hash, upload or otherwise process one file while the
+// rest of the block is decoded, without holding the whole block in memory.
+//
+// The reader is only valid until fn returns, and anything fn doesn't read is
+// skipped. The CRC32 of each file is checked as the end of its contents is
+// read, with a [*ReadError] returned by the reader and then by
+// StreamFolderFiles if it doesn't match. Any error returned by fn stops the
+// decoding and is returned as is.
+func (z *Reader) StreamFolderFiles(i int, fn func(*File, io.Reader) error) (err error) {
+	fr, err := z.OpenFolder(i)
+	if err != nil {
+		return err
+	}
+
+	var (
+		ch   = make(chan folderChunk, streamChunks)
+		done = make(chan struct{})
+		wg   sync.WaitGroup
+	)
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		defer close(ch)
+
+		fr.stream(ch, done)
+	}()
+
+	defer func() {
+		close(done)
+		wg.Wait()
+
+		err = errors.Join(err, fr.Close())
+	}()
+
+	for _, m := range fr.Members {
+		r := &memberReader{ch: ch, n: m.Size}
+
+		if err := fn(m.File, r); err != nil {
+			return err
+		}
+
+		if _, err := io.Copy(io.Discard, r); err != nil {
+			return err
+		}
+
+		r.closed = true
+	}
+
+	for c := range ch {
+		if c.err != nil {
+			return c.err
+		}
+	}
+
+	return nil
+}
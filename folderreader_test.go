@@ -0,0 +1,279 @@
+package sevenzip_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenFolder(t *testing.T) {
+	t.Parallel()
+
+	tables := []struct {
+		file     string
+		password string
+	}{
+		{file: "t1.7z"},
+		{file: "t2.7z", password: "password"},
+		{file: "bcj2.7z"},
+		{file: "lzma2.7z"},
+		{file: "file_and_empty.7z"},
+		{file: "aes7z.7z", password: "password"},
+	}
+
+	for _, table := range tables {
+		t.Run(table.file, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := sevenzip.OpenReaderWithPassword(filepath.Join("testdata", table.file), table.password)
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				require.NoError(t, r.Close())
+			})
+
+			want := readAll(t, &r.Reader)
+			got := make(map[string][]byte, len(want))
+
+			for _, f := range r.File {
+				if !f.HasStream() {
+					got[f.Name] = want[f.Name]
+				}
+			}
+
+			for i, block := range r.Blocks() {
+				fr, err := r.OpenFolder(i)
+				require.NoError(t, err)
+
+				b, err := io.ReadAll(fr)
+				require.NoError(t, err)
+				require.NoError(t, fr.Close())
+
+				assert.Equal(t, int64(block.UnpackedSize), fr.Size)
+				assert.Len(t, b, int(fr.Size))
+				assert.Len(t, fr.Members, block.NumFiles)
+
+				for _, m := range fr.Members {
+					assert.Equal(t, i, m.File.Stream)
+					got[m.File.Name] = b[m.Offset : m.Offset+m.Size]
+				}
+			}
+
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestOpenFolderErrors(t *testing.T) {
+	t.Parallel()
+
+	buf := new(bytes.Buffer)
+	w := sevenzip.NewWriter(buf, sevenzip.WithStore(), sevenzip.WithSolidBlockFiles(10))
+
+	for _, name := range []string{"a.txt", "b.txt"} {
+		fw, err := w.Create(name)
+		require.NoError(t, err)
+
+		_, err = io.WriteString(fw, "contents of "+name)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, w.Close())
+
+	// Corrupt the second file, which is stored as is
+	b := bytes.Clone(buf.Bytes())
+	b[bytes.Index(b, []byte("contents of b.txt"))] ^= 0xff
+
+	r, err := sevenzip.NewReader(bytes.NewReader(b), int64(len(b)))
+	require.NoError(t, err)
+
+	_, err = r.OpenFolder(1)
+	require.ErrorIs(t, err, sevenzip.ErrNoBlock)
+
+	_, err = r.OpenFolder(-1)
+	require.ErrorIs(t, err, sevenzip.ErrNoBlock)
+
+	fr, err := r.OpenFolder(0)
+	require.NoError(t, err)
+
+	contents, err := io.ReadAll(fr)
+	require.ErrorIs(t, err, sevenzip.ErrChecksum)
+	require.NoError(t, fr.Close())
+
+	var re *sevenzip.ReadError
+	require.ErrorAs(t, err, &re)
+	assert.ErrorContains(t, err, "b.txt")
+
+	// Everything up to the end of the bad member is returned
+	assert.Len(t, contents, len("contents of a.txt")+len("contents of b.txt"))
+
+	require.NoError(t, r.Close())
+
+	_, err = r.OpenFolder(0)
+	require.ErrorIs(t, err, sevenzip.ErrReaderClosed)
+}
+
+func TestStreamFolderFiles(t *testing.T) {
+	t.Parallel()
+
+	tables := []struct {
+		file     string
+		password string
+	}{
+		{file: "t1.7z"},
+		{file: "t2.7z", password: "password"},
+		{file: "bcj2.7z"},
+		{file: "lzma2.7z"},
+		{file: "file_and_empty.7z"},
+		{file: "aes7z.7z", password: "password"},
+	}
+
+	for _, table := range tables {
+		t.Run(table.file, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := sevenzip.OpenReaderWithPassword(filepath.Join("testdata", table.file), table.password)
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				require.NoError(t, r.Close())
+			})
+
+			want := readAll(t, &r.Reader)
+			got := make(map[string][]byte, len(want))
+
+			for _, f := range r.File {
+				if !f.HasStream() {
+					got[f.Name] = want[f.Name]
+				}
+			}
+
+			for i := range r.Blocks() {
+				require.NoError(t, r.StreamFolderFiles(i, func(f *sevenzip.File, rd io.Reader) error {
+					assert.Equal(t, i, f.Stream)
+
+					b, err := io.ReadAll(rd)
+					got[f.Name] = b
+
+					return err
+				}))
+			}
+
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+//nolint:funlen
+func TestStreamFolderFilesLarge(t *testing.T) {
+	t.Parallel()
+
+	// Several times the buffer so decoding has to wait for the reader
+	files := make(map[string][]byte)
+	buf := new(bytes.Buffer)
+	w := sevenzip.NewWriter(buf, sevenzip.WithSolidBlockFiles(10))
+
+	for i, name := range []string{"a.bin", "b.bin", "c.bin", "d.bin"} {
+		files[name] = make([]byte, 1<<20+i)
+		_, err := rand.New(rand.NewSource(int64(i))).Read(files[name]) //nolint:gosec
+		require.NoError(t, err)
+
+		fw, err := w.Create(name)
+		require.NoError(t, err)
+
+		_, err = fw.Write(files[name])
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, w.Close())
+
+	r, err := sevenzip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, r.Blocks(), 1)
+
+	// Files not read, or only partly read, are skipped
+	var names []string
+
+	require.NoError(t, r.StreamFolderFiles(0, func(f *sevenzip.File, rd io.Reader) error {
+		names = append(names, f.Name)
+
+		switch f.Name {
+		case "a.bin":
+			return nil
+		case "b.bin":
+			b := make([]byte, 100)
+			_, err := io.ReadFull(rd, b)
+			assert.Equal(t, files[f.Name][:100], b)
+
+			return err
+		}
+
+		b, err := io.ReadAll(rd)
+		assert.Equal(t, files[f.Name], b, f.Name)
+
+		return err
+	}))
+
+	assert.Equal(t, []string{"a.bin", "b.bin", "c.bin", "d.bin"}, names)
+
+	// Errors from fn stop everything
+	errStop := errors.New("stop")
+	names = nil
+
+	err = r.StreamFolderFiles(0, func(f *sevenzip.File, _ io.Reader) error {
+		names = append(names, f.Name)
+
+		return errStop
+	})
+	require.ErrorIs(t, err, errStop)
+	assert.Equal(t, []string{"a.bin"}, names)
+
+	err = r.StreamFolderFiles(1, func(*sevenzip.File, io.Reader) error {
+		return nil
+	})
+	require.ErrorIs(t, err, sevenzip.ErrNoBlock)
+}
+
+func TestStreamFolderFilesChecksum(t *testing.T) {
+	t.Parallel()
+
+	buf := new(bytes.Buffer)
+	w := sevenzip.NewWriter(buf, sevenzip.WithStore(), sevenzip.WithSolidBlockFiles(10))
+
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		fw, err := w.Create(name)
+		require.NoError(t, err)
+
+		_, err = io.WriteString(fw, "contents of "+name)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, w.Close())
+
+	// Corrupt the second file, which is stored as is
+	b := bytes.Clone(buf.Bytes())
+	b[bytes.Index(b, []byte("contents of b.txt"))] ^= 0xff
+
+	r, err := sevenzip.NewReader(bytes.NewReader(b), int64(len(b)))
+	require.NoError(t, err)
+
+	var names []string
+
+	// The error is returned even if fn ignores it
+	err = r.StreamFolderFiles(0, func(f *sevenzip.File, rd io.Reader) error {
+		names = append(names, f.Name)
+		_, _ = io.ReadAll(rd)
+
+		return nil
+	})
+	require.ErrorIs(t, err, sevenzip.ErrChecksum)
+	assert.ErrorContains(t, err, "b.txt")
+	assert.Equal(t, []string{"a.txt", "b.txt"}, names)
+}
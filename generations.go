@@ -0,0 +1,279 @@
+package sevenzip
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"slices"
+)
+
+// generationChunk is how much of the archive is read at a time when looking
+// for earlier headers.
+const generationChunk = 1 << 20
+
+// A Generation is an earlier version of an archive, described by a header
+// that was superseded when the archive was updated but is still present in
+// it. The embedded [Reader] lists and reads the files as they were in that
+// version, as if the archive ended with that header, so calling
+// [Reader.Generations] on it returns the versions before it. Contents that
+// were since overwritten fail their CRC check.
+type Generation struct {
+	// Offset is where the header starts in what the archive is read
+	// from, in the same way as [Reader.BaseOffset].
+	Offset int64
+	Reader
+}
+
+// Generations returns the earlier versions of the archive whose headers are
+// still present, oldest first, or nil if there aren't any. The parts of the
+// archive not used by the current header are searched: any gap before the
+// first packed stream, blocks holding no files, the space before the
+// header, which is where [WithKeepOldHeader] keeps them, and anything
+// trailing it, which is where a longer header is left when an archive is
+// updated in place without being truncated. Each Generation should be
+// closed when no longer needed.
+//
+// Headers are only found if they can be read with the same password as the
+// archive, and can't be found if the archive was opened with a name filter.
+func (z *Reader) Generations() ([]*Generation, error) {
+	if z.opts.nameFilter != nil {
+		return nil, errFiltered
+	}
+
+	var gens []*Generation
+
+	for _, gap := range z.unused() {
+		for off := gap[0]; off < gap[1]; {
+			next, err := z.findHeader(off, gap[1])
+			if err != nil {
+				return nil, err
+			}
+
+			if next < 0 {
+				break
+			}
+
+			g, n, err := z.generationAt(next, gap[1])
+			if err != nil {
+				// Something that looked like a header but wasn't
+				off = next + 1
+
+				continue
+			}
+
+			gens = append(gens, g)
+			off = next + n
+		}
+	}
+
+	return gens, nil
+}
+
+// unused returns the ranges of the archive, as absolute offsets, that aren't
+// used by the current header or the files it lists.
+func (z *Reader) unused() [][2]int64 {
+	used := [][2]int64{{z.end, z.end + int64(z.sh.Size)}} //nolint:gosec
+
+	if si := z.si; si != nil && si.packInfo != nil && si.unpackInfo != nil {
+		k := 0
+
+		for i, f := range si.unpackInfo.folder {
+			n := int(f.packedStreams) //nolint:gosec
+
+			if si.subStreamsInfo == nil || si.subStreamsInfo.streams[i] > 0 {
+				used = append(used, [2]int64{z.start + si.packedOffset(k), z.start + si.packedOffset(k+n)})
+			}
+
+			k += n
+		}
+	}
+
+	slices.SortFunc(used, func(a, b [2]int64) int {
+		return cmp.Compare(a[0], b[0])
+	})
+
+	var (
+		gaps [][2]int64
+		off  = z.start
+	)
+
+	for _, r := range used {
+		if r[0] > off {
+			gaps = append(gaps, [2]int64{off, r[0]})
+		}
+
+		off = max(off, r[1])
+	}
+
+	if z.size > off {
+		gaps = append(gaps, [2]int64{off, z.size})
+	}
+
+	return gaps
+}
+
+// findHeader returns the offset of the first byte between off and end that
+// could start a header, or -1 if there isn't one.
+func (z *Reader) findHeader(off, end int64) (int64, error) {
+	buf := make([]byte, min(generationChunk, end-off))
+
+	for off < end {
+		n, err := z.r.ReadAt(buf[:min(int64(len(buf)), end-off)], off)
+		if n == 0 && err != nil {
+			return -1, fmt.Errorf("sevenzip: error reading: %w", err)
+		}
+
+		// The last byte of each chunk is looked at again at the start
+		// of the next as a header needs at least two
+		for i := range n - 1 {
+			if headerStart(buf[i], buf[i+1]) {
+				return off + int64(i), nil
+			}
+		}
+
+		if off+int64(n) >= end {
+			break
+		}
+
+		off += int64(max(n-1, 1))
+	}
+
+	return -1, nil
+}
+
+// headerStart returns whether a and b could be the first two bytes of a
+// header.
+func headerStart(a, b byte) bool {
+	switch a {
+	case idHeader:
+		return b == idArchiveProperties || b == idMainStreamsInfo || b == idFilesInfo
+	case idEncodedHeader:
+		return b == idPackInfo
+	default:
+		return false
+	}
+}
+
+// generationAt reads the header at off, which ends no later than end, and
+// returns the Generation it describes along with the length of the header.
+func (z *Reader) generationAt(off, end int64) (g *Generation, n int64, err error) {
+	g = &Generation{Offset: off}
+
+	c := &g.Reader
+	c.r = z.r
+	c.base, c.start, c.end, c.size = z.base, z.start, off, z.size
+	c.p, c.opts = z.opts.password, z.opts
+	c.volumes, c.version = z.volumes, z.version
+	c.done = make(chan struct{})
+
+	if c.opts.audit {
+		c.audit = &audit{ranges: make(map[*File][]ReadRange)}
+	}
+
+	z.decompressors.Range(func(k, v any) bool {
+		c.decompressors.Store(k, v)
+
+		return true
+	})
+
+	z.multiDecompressors.Range(func(k, v any) bool {
+		c.multiDecompressors.Store(k, v)
+
+		return true
+	})
+
+	sr := io.NewSectionReader(z.r, off, end-off)
+	br := bufio.NewReader(sr)
+
+	id, err := br.ReadByte()
+	if err != nil {
+		return nil, 0, fmt.Errorf("sevenzip: error reading header id: %w", err)
+	}
+
+	var h *header
+
+	switch id {
+	case idHeader:
+		if h, err = readHeader(br, c.decodeStreams); err != nil {
+			return nil, 0, err
+		}
+	case idEncodedHeader:
+		si, err := readStreamsInfo(br, nil)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if si.Folders() != 1 {
+			return nil, 0, errOneHeaderStream
+		}
+
+		if !c.precedes(si, off) {
+			return nil, 0, errFormat
+		}
+
+		if err := c.checkHeaderSize(si.unpackInfo.folder[0].unpackSize()); err != nil {
+			return nil, 0, err
+		}
+
+		if h, err = c.decodeHeader(si); err != nil {
+			return nil, 0, err
+		}
+
+		c.headerPacked += si.packedSize()
+	default:
+		return nil, 0, errUnexpectedID
+	}
+
+	if !c.precedes(h.streamsInfo, off) {
+		return nil, 0, errFormat
+	}
+
+	pos, err := sr.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, 0, fmt.Errorf("sevenzip: error seeking current position: %w", err)
+	}
+
+	n = pos - int64(br.Buffered())
+
+	crc := crc32.NewIEEE()
+	if _, err := io.Copy(crc, io.NewSectionReader(z.r, off, n)); err != nil {
+		return nil, 0, fmt.Errorf("sevenzip: error reading header: %w", err)
+	}
+
+	// The archive ended with the header at the time
+	c.size = off + n
+	c.sh = startHeader{Offset: uint64(off - z.start), Size: uint64(n), CRC: crc.Sum32()} //nolint:gosec
+
+	if err := c.initHeader(h); err != nil {
+		return nil, 0, err
+	}
+
+	return g, n, nil
+}
+
+// precedes returns whether the packed streams described by si, if any, all
+// end before off, as they must for a header written after them.
+func (z *Reader) precedes(si *streamsInfo, off int64) bool {
+	if si == nil || si.packInfo == nil {
+		return true
+	}
+
+	limit := uint64(off - z.start) //nolint:gosec
+
+	end := si.packInfo.position
+	if end > limit {
+		return false
+	}
+
+	for _, size := range si.packInfo.size {
+		if size > limit-end {
+			return false
+		}
+
+		end += size
+	}
+
+	return true
+}
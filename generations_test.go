@@ -0,0 +1,170 @@
+package sevenzip_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:funlen
+func TestGenerations(t *testing.T) {
+	t.Parallel()
+
+	tables := []struct {
+		name string
+		opts []sevenzip.WriterOption
+	}{
+		{
+			name: "plain",
+		},
+		{
+			name: "encrypted header",
+			opts: []sevenzip.WriterOption{
+				sevenzip.WithWriterPassword("password"),
+				sevenzip.WithEncryptedHeader(),
+			},
+		},
+		{
+			name: "solid",
+			opts: []sevenzip.WriterOption{sevenzip.WithSolidBlockFiles(10)},
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			name := filepath.Join(t.TempDir(), "test.7z")
+
+			f, err := os.Create(name)
+			require.NoError(t, err)
+
+			w := sevenzip.NewWriter(f, table.opts...)
+
+			add := func(w *sevenzip.Writer, names ...string) {
+				for _, name := range names {
+					fw, err := w.Create(name)
+					require.NoError(t, err)
+
+					_, err = io.WriteString(fw, "contents of "+name)
+					require.NoError(t, err)
+				}
+			}
+
+			add(w, "a.txt", "b.txt")
+			require.NoError(t, w.Close())
+			require.NoError(t, f.Close())
+
+			for _, added := range []string{"c.txt", "d.txt"} {
+				wc, err := sevenzip.OpenWriter(name, append(table.opts, sevenzip.WithKeepOldHeader())...)
+				require.NoError(t, err)
+
+				add(&wc.Writer, added)
+				require.NoError(t, wc.Close())
+			}
+
+			r, err := sevenzip.OpenReaderWithPassword(name, "password")
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				require.NoError(t, r.Close())
+			})
+
+			assert.Equal(t, []string{"a.txt", "b.txt", "c.txt", "d.txt"}, names(&r.Reader))
+			require.NoError(t, checkContents(&r.Reader))
+
+			gens, err := r.Generations()
+			require.NoError(t, err)
+			require.Len(t, gens, 2)
+
+			t.Cleanup(func() {
+				for _, g := range gens {
+					require.NoError(t, g.Close())
+				}
+			})
+
+			assert.Equal(t, []string{"a.txt", "b.txt"}, names(&gens[0].Reader))
+			assert.Equal(t, []string{"a.txt", "b.txt", "c.txt"}, names(&gens[1].Reader))
+			assert.Less(t, gens[0].Offset, gens[1].Offset)
+
+			for i, g := range gens {
+				require.NoError(t, checkContents(&g.Reader))
+
+				// Each version only has those before it
+				earlier, err := g.Generations()
+				require.NoError(t, err)
+				require.Len(t, earlier, i)
+
+				for j, e := range earlier {
+					assert.Equal(t, gens[j].Offset, e.Offset)
+					require.NoError(t, e.Close())
+				}
+			}
+		})
+	}
+}
+
+func TestGenerationsNone(t *testing.T) {
+	t.Parallel()
+
+	tables := []string{
+		"t1.7z",
+		"bcj2.7z",
+		"empty.7z",
+		"empty2.7z",
+		"comment.7z",
+		"sfx.exe",
+		"padded.7z",
+		"multi.7z.001",
+	}
+
+	for _, table := range tables {
+		t.Run(table, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := sevenzip.OpenReader(filepath.Join("testdata", table))
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				require.NoError(t, r.Close())
+			})
+
+			gens, err := r.Generations()
+			require.NoError(t, err)
+			assert.Empty(t, gens)
+		})
+	}
+
+	// Appending normally writes over the old header
+	name := filepath.Join(t.TempDir(), "test.7z")
+
+	b, err := os.ReadFile(filepath.Join("testdata", "t1.7z"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(name, b, 0o600))
+
+	wc, err := sevenzip.OpenWriter(name)
+	require.NoError(t, err)
+
+	fw, err := wc.Create("appended.txt")
+	require.NoError(t, err)
+
+	_, err = io.WriteString(fw, "appended")
+	require.NoError(t, err)
+	require.NoError(t, wc.Close())
+
+	r, err := sevenzip.OpenReader(name)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, r.Close())
+	})
+
+	gens, err := r.Generations()
+	require.NoError(t, err)
+	assert.Empty(t, gens)
+}
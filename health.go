@@ -0,0 +1,260 @@
+package sevenzip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Thresholds used by [Reader.Health].
+const (
+	healthLargeBlock     = 1 << 30
+	healthFragmentation  = 0.1
+	healthErrorPenalty   = 40
+	healthWarningPenalty = 10
+)
+
+// A HealthSeverity is the severity of a [HealthIssue].
+type HealthSeverity int
+
+const (
+	// HealthInfo issues are worth knowing about but don't need any action.
+	HealthInfo HealthSeverity = iota
+	// HealthWarning issues make the archive slower or less safe to use.
+	HealthWarning
+	// HealthError issues mean that some or all of the archive can't be
+	// extracted.
+	HealthError
+)
+
+func (s HealthSeverity) String() string {
+	switch s {
+	case HealthInfo:
+		return "info"
+	case HealthWarning:
+		return "warning"
+	case HealthError:
+		return "error"
+	default:
+		return fmt.Sprintf("HealthSeverity(%d)", int(s))
+	}
+}
+
+// A HealthIssue is a problem found by [Reader.Health] along with what can be
+// done about it.
+type HealthIssue struct {
+	Severity HealthSeverity
+	// Block is the index of the block with the problem, as returned by
+	// [Reader.Blocks], or -1 if it affects the whole archive.
+	Block int
+	// Problem describes what was found.
+	Problem string
+	// Recommendation describes how to fix it, it may be empty if there is
+	// nothing to be done.
+	Recommendation string
+}
+
+// A HealthReport summarises the state of an archive for archive management
+// tools.
+type HealthReport struct {
+	// Score ranges from 100 for an archive without any warnings or errors
+	// down to 0. Each error costs 40 points and each warning 10.
+	Score int
+	// Blocks is the number of blocks and SolidBlocks the number of those
+	// holding more than one file.
+	Blocks      int
+	SolidBlocks int
+	// LargestBlock is the unpacked size of the largest block.
+	LargestBlock uint64
+	// FilesWithoutCRC is the number of files with contents but no CRC32
+	// to check them against.
+	FilesWithoutCRC int
+	// Fragmentation is the fraction of the packed data area not used by
+	// the packed streams of the files or the header, such as padding or
+	// data left behind by an interrupted update.
+	Fragmentation float64
+	// Verified is true if every block was decoded and checked, see
+	// [WithVerify].
+	Verified bool
+	// Issues are ordered by severity, most severe first.
+	Issues []HealthIssue
+}
+
+type healthOptions struct {
+	verify bool
+}
+
+// A HealthOption configures a call to [Reader.Health].
+type HealthOption func(*healthOptions)
+
+// WithVerify makes [Reader.Health] decode every block and check the CRC32 of
+// every file, which reads the whole archive. Without it only the header is
+// analysed.
+func WithVerify() HealthOption {
+	return func(o *healthOptions) {
+		o.verify = true
+	}
+}
+
+func (r *HealthReport) add(severity HealthSeverity, block int, problem, recommendation string) {
+	r.Issues = append(r.Issues, HealthIssue{
+		Severity:       severity,
+		Block:          block,
+		Problem:        problem,
+		Recommendation: recommendation,
+	})
+}
+
+// supported returns true if there is a decompressor registered for c.
+func (z *Reader) supported(c Coder) bool {
+	if c.NumOutStreams > 1 {
+		return multiDecompressor(c.ID) != nil
+	}
+
+	return z.decompressor(c.ID) != nil
+}
+
+// Health analyses the archive and returns a summary of any problems found,
+// each with a recommendation, such as solid blocks that are too large for
+// efficient random access, files stored without a CRC32 or methods that
+// can't be decoded. An error is only returned if ctx is done.
+//
+//nolint:cyclop,funlen,gocognit
+func (z *Reader) Health(ctx context.Context, opts ...HealthOption) (*HealthReport, error) {
+	o := new(healthOptions)
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	blocks := z.Blocks()
+	r := &HealthReport{
+		Blocks: len(blocks),
+	}
+
+	var (
+		packed    uint64
+		encrypted bool
+	)
+
+	for i, b := range blocks {
+		r.LargestBlock = max(r.LargestBlock, b.UnpackedSize)
+		packed += b.PackedSize
+		encrypted = encrypted || b.Encrypted()
+
+		if b.NumFiles > 1 {
+			r.SolidBlocks++
+
+			if b.UnpackedSize > healthLargeBlock {
+				r.add(HealthWarning, i, fmt.Sprintf("solid block of %d files is larger than 1 GiB, which hurts random access", b.NumFiles),
+					"recreate the archive with a smaller solid block size")
+			}
+		}
+
+		if j := slices.IndexFunc(b.Coders, func(c Coder) bool { return !z.supported(c) }); j >= 0 {
+			r.add(HealthError, i, fmt.Sprintf("method %s is not supported", b.Coders[j].Name()),
+				"register a decompressor for the method")
+		}
+
+		if b.PackedSize >= b.UnpackedSize && b.UnpackedSize > 0 && !slices.ContainsFunc(b.Coders, func(c Coder) bool {
+			return c.Name() == "Copy"
+		}) {
+			r.add(HealthInfo, i, "compression doesn't reduce the size of the block",
+				"store the files without compression to make extraction faster")
+		}
+	}
+
+	var missing, oversized int
+
+	for _, f := range z.File {
+		switch {
+		case f.isMissing:
+			missing++
+		case f.oversized != nil:
+			oversized++
+		case f.HasStream() && !f.crcDefined:
+			r.FilesWithoutCRC++
+		}
+	}
+
+	if missing > 0 {
+		r.add(HealthError, -1, fmt.Sprintf("%d files have no data in the archive", missing),
+			"recreate the archive from the original files")
+	}
+
+	if oversized > 0 {
+		r.add(HealthError, -1, fmt.Sprintf("%d files declare more data than their block holds", oversized),
+			"open the archive with WithTruncateOversized to read what there is")
+	}
+
+	if r.FilesWithoutCRC > 0 {
+		r.add(HealthWarning, -1, fmt.Sprintf("%d files have no CRCs stored so corruption can't be detected", r.FilesWithoutCRC),
+			"recreate the archive with a tool that stores CRCs")
+	}
+
+	if area := uint64(z.end - z.start); area > 0 { //nolint:gosec
+		used := min(packed+z.headerPacked, area)
+		r.Fragmentation = float64(area-used) / float64(area)
+
+		if r.Fragmentation > healthFragmentation {
+			r.add(HealthWarning, -1, fmt.Sprintf("%.0f%% of the packed data area is unused", r.Fragmentation*100), //nolint:mnd
+				"recreate the archive to reclaim the unused space")
+		}
+	}
+
+	for _, w := range z.warnings {
+		r.add(HealthWarning, -1, w, "check for an update that supports the archive")
+	}
+
+	if encrypted && !z.encryptedHeader {
+		r.add(HealthInfo, -1, "file contents are encrypted but file names are not",
+			"recreate the archive with header encryption to hide the file names")
+	}
+
+	if o.verify {
+		r.Verified = true
+
+		for i := range blocks {
+			if err := ctx.Err(); err != nil {
+				return nil, err //nolint:wrapcheck
+			}
+
+			err := z.verifyFolder(i, z.p)
+
+			switch {
+			case err == nil:
+			case errors.Is(err, ErrPasswordRequired):
+				r.Verified = false
+
+				r.add(HealthInfo, i, "encrypted block can't be verified without a password",
+					"provide the password to verify the block")
+			case errors.Is(err, ErrUnsupportedMethod):
+				// Already reported
+				r.Verified = false
+			default:
+				r.add(HealthError, i, fmt.Sprintf("block is corrupt: %v", err),
+					"restore the archive from a backup")
+			}
+		}
+	}
+
+	slices.SortStableFunc(r.Issues, func(a, b HealthIssue) int {
+		return int(b.Severity) - int(a.Severity)
+	})
+
+	r.Score = 100 //nolint:mnd
+
+	for _, issue := range r.Issues {
+		switch issue.Severity {
+		case HealthError:
+			r.Score -= healthErrorPenalty
+		case HealthWarning:
+			r.Score -= healthWarningPenalty
+		case HealthInfo:
+		}
+	}
+
+	r.Score = max(r.Score, 0)
+
+	return r, nil
+}
@@ -0,0 +1,133 @@
+package sevenzip_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealth(t *testing.T) {
+	t.Parallel()
+
+	corrupt, err := os.ReadFile(filepath.Join("testdata", "copy.7z"))
+	require.NoError(t, err)
+
+	corrupt[100] ^= 0xff
+
+	tables := []struct {
+		name, file string
+		b          []byte
+		opts       []sevenzip.HealthOption
+		score      int
+		verified   bool
+		severities []sevenzip.HealthSeverity
+	}{
+		{
+			name:     "healthy",
+			file:     "lzma1900.7z",
+			opts:     []sevenzip.HealthOption{sevenzip.WithVerify()},
+			score:    100,
+			verified: true,
+		},
+		{
+			name:  "header only",
+			file:  "lzma1900.7z",
+			score: 100,
+		},
+		{
+			name:       "padded",
+			file:       "padded.7z",
+			score:      90,
+			severities: []sevenzip.HealthSeverity{sevenzip.HealthWarning},
+		},
+		{
+			name:       "missing streams",
+			file:       "nostreams.7z",
+			score:      60,
+			severities: []sevenzip.HealthSeverity{sevenzip.HealthError},
+		},
+		{
+			name:       "encrypted without password",
+			file:       "t4.7z",
+			opts:       []sevenzip.HealthOption{sevenzip.WithVerify()},
+			score:      100,
+			severities: []sevenzip.HealthSeverity{sevenzip.HealthInfo, sevenzip.HealthInfo, sevenzip.HealthInfo},
+		},
+		{
+			name:       "corrupt",
+			b:          corrupt,
+			opts:       []sevenzip.HealthOption{sevenzip.WithVerify()},
+			score:      60,
+			verified:   true,
+			severities: []sevenzip.HealthSeverity{sevenzip.HealthError},
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			var r *sevenzip.Reader
+
+			if table.b != nil {
+				var err error
+
+				r, err = sevenzip.NewReader(bytes.NewReader(table.b), int64(len(table.b)))
+				require.NoError(t, err)
+			} else {
+				rc, err := sevenzip.OpenReader(filepath.Join("testdata", table.file))
+				require.NoError(t, err)
+
+				t.Cleanup(func() {
+					require.NoError(t, rc.Close())
+				})
+
+				r = &rc.Reader
+			}
+
+			report, err := r.Health(context.Background(), table.opts...)
+			require.NoError(t, err)
+
+			assert.Equal(t, table.score, report.Score)
+			assert.Equal(t, table.verified, report.Verified)
+			assert.Equal(t, len(r.Blocks()), report.Blocks)
+
+			severities := make([]sevenzip.HealthSeverity, 0, len(report.Issues))
+
+			for _, issue := range report.Issues {
+				severities = append(severities, issue.Severity)
+
+				assert.NotEmpty(t, issue.Problem)
+				assert.NotEmpty(t, issue.Recommendation)
+			}
+
+			assert.ElementsMatch(t, table.severities, severities)
+		})
+	}
+}
+
+func TestHealthCancelled(t *testing.T) {
+	t.Parallel()
+
+	r, err := sevenzip.OpenReader(filepath.Join("testdata", "lzma1900.7z"))
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, r.Close())
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = r.Health(ctx, sevenzip.WithVerify())
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = r.Health(ctx)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,181 @@
+package sevenzip
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// IndexSchema is the SQLite schema written by [WriteIndex]. It is created if
+// it doesn't already exist so a single database can catalog many archives.
+//
+// Columns holding optional values, such as an undefined CRC, a missing
+// timestamp or the block of an empty file, are NULL when the value isn't
+// present. Timestamps are stored as RFC 3339 strings in UTC and booleans as 0
+// or 1.
+const IndexSchema = `
+CREATE TABLE IF NOT EXISTS archives (
+	id               INTEGER PRIMARY KEY,
+	path             TEXT NOT NULL UNIQUE, -- path as passed to WriteIndex
+	files            INTEGER NOT NULL,     -- number of entries
+	blocks           INTEGER NOT NULL,     -- number of solid blocks
+	encrypted_header INTEGER NOT NULL,     -- 1 if the header is encrypted
+	indexed_at       TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS files (
+	archive_id     INTEGER NOT NULL REFERENCES archives (id),
+	idx            INTEGER NOT NULL, -- position of the entry in the archive
+	name           TEXT NOT NULL,
+	is_dir         INTEGER NOT NULL,
+	size           INTEGER NOT NULL, -- uncompressed size
+	crc32          INTEGER,
+	modified       TEXT,
+	created        TEXT,
+	accessed       TEXT,
+	attributes     INTEGER NOT NULL,
+	block          INTEGER,          -- solid block containing the file
+	offset         INTEGER,          -- absolute offset, only usable for direct reads if neither compressed nor encrypted
+	packed_size    INTEGER,          -- packed size of the whole block
+	compressed     INTEGER NOT NULL,
+	encrypted      INTEGER NOT NULL,
+	aes_salt       BLOB,
+	aes_iv         BLOB,
+	kdf_iterations INTEGER,
+	PRIMARY KEY (archive_id, idx)
+);
+
+CREATE INDEX IF NOT EXISTS files_name ON files (name);
+`
+
+type blockIndex struct {
+	offset, packedSize    int64
+	compressed, encrypted bool
+	salt, iv              []byte
+	iterations            int
+}
+
+func (z *Reader) blockIndexes() []blockIndex {
+	blocks := make([]blockIndex, z.si.Folders())
+	offsets, _ := z.si.folderOffsets()
+
+	for i, b := range z.Blocks() {
+		blocks[i] = blockIndex{
+			offset:     z.start + offsets[i],
+			packedSize: int64(b.PackedSize), //nolint:gosec
+			compressed: slices.ContainsFunc(b.Coders, func(c Coder) bool {
+				return !slices.Equal(c.ID, []byte{0x00}) && !slices.Equal(c.ID, methodAES)
+			}),
+		}
+
+		blocks[i].salt, blocks[i].iv, blocks[i].iterations, blocks[i].encrypted = extractAESParams(z.si.unpackInfo.folder[i])
+	}
+
+	return blocks
+}
+
+func nullTime(t time.Time) sql.NullString {
+	if t.IsZero() {
+		return sql.NullString{}
+	}
+
+	return sql.NullString{String: t.UTC().Format(time.RFC3339Nano), Valid: true}
+}
+
+// WriteIndex writes the file table of z, including offsets, CRCs and AES
+// parameters, to db using [IndexSchema], so catalog tools can query the
+// contents of archives without reopening them. Any previous index of the
+// archive with the same name is replaced.
+//
+// The database must be SQLite, although no particular driver is required.
+//
+//nolint:funlen
+func WriteIndex(ctx context.Context, db *sql.DB, name string, z *Reader) (err error) {
+	if _, err := db.ExecContext(ctx, IndexSchema); err != nil {
+		return fmt.Errorf("sevenzip: error creating index schema: %w", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sevenzip: error starting index transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, tx.Rollback())
+		}
+	}()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM files WHERE archive_id IN (SELECT id FROM archives WHERE path = ?)", name); err != nil {
+		return fmt.Errorf("sevenzip: error removing previous index: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM archives WHERE path = ?", name); err != nil {
+		return fmt.Errorf("sevenzip: error removing previous index: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx,
+		"INSERT INTO archives (path, files, blocks, encrypted_header, indexed_at) VALUES (?, ?, ?, ?, ?)",
+		name, len(z.File), z.si.Folders(), z.encryptedHeader, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("sevenzip: error indexing archive: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("sevenzip: error indexing archive: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO files (
+	archive_id, idx, name, is_dir, size, crc32, modified, created, accessed, attributes,
+	block, offset, packed_size, compressed, encrypted, aes_salt, aes_iv, kdf_iterations
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("sevenzip: error preparing index statement: %w", err)
+	}
+
+	defer func() {
+		err = errors.Join(err, stmt.Close())
+	}()
+
+	blocks := z.blockIndexes()
+
+	for i, f := range z.File {
+		var (
+			crc                         sql.NullInt64
+			block, offset, packed, kdfs sql.NullInt64
+			b                           blockIndex
+		)
+
+		if sum, ok := f.Digest(); ok {
+			crc = sql.NullInt64{Int64: int64(sum), Valid: true}
+		}
+
+		if f.HasStream() {
+			b = blocks[f.folder]
+			block = sql.NullInt64{Int64: int64(f.folder), Valid: true}
+			offset = sql.NullInt64{Int64: b.offset + f.offset, Valid: true}
+			packed = sql.NullInt64{Int64: b.packedSize, Valid: true}
+
+			if b.encrypted {
+				kdfs = sql.NullInt64{Int64: int64(b.iterations), Valid: true}
+			}
+		}
+
+		if _, err := stmt.ExecContext(ctx,
+			id, i, f.Name, f.FileInfo().IsDir(), int64(f.UncompressedSize), crc, //nolint:gosec
+			nullTime(f.Modified), nullTime(f.Created), nullTime(f.Accessed), int64(f.Attributes),
+			block, offset, packed, b.compressed, b.encrypted, b.salt, b.iv, kdfs); err != nil {
+			return fmt.Errorf("sevenzip: error indexing %s: %w", f.Name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("sevenzip: error committing index: %w", err)
+	}
+
+	return nil
+}
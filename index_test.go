@@ -0,0 +1,97 @@
+package sevenzip_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func TestWriteIndex(t *testing.T) {
+	t.Parallel()
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "index.db"))
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+
+	tables := []struct {
+		file, password    string
+		encrypted, header bool
+	}{
+		{
+			file: "t1.7z",
+		},
+		{
+			file:      "t2.7z",
+			password:  "password",
+			encrypted: true,
+			header:    true,
+		},
+		{
+			file:      "aes7z.7z",
+			password:  "password",
+			encrypted: true,
+			header:    true,
+		},
+	}
+
+	ctx := context.Background()
+
+	for _, table := range tables {
+		r, err := sevenzip.OpenReaderWithPassword(filepath.Join("testdata", table.file), table.password)
+		require.NoError(t, err)
+
+		// Indexing twice should replace rather than duplicate the rows
+		require.NoError(t, sevenzip.WriteIndex(ctx, db, table.file, &r.Reader))
+		require.NoError(t, sevenzip.WriteIndex(ctx, db, table.file, &r.Reader))
+
+		var (
+			count  int
+			header bool
+		)
+
+		require.NoError(t, db.QueryRowContext(ctx,
+			"SELECT COUNT(*), encrypted_header FROM files JOIN archives ON archives.id = files.archive_id WHERE path = ?",
+			table.file).Scan(&count, &header))
+		assert.Equal(t, len(r.File), count, table.file)
+		assert.Equal(t, table.header, header, table.file)
+
+		for i, f := range r.File {
+			var (
+				name      string
+				size      int64
+				crc       sql.NullInt64
+				encrypted bool
+				salt, iv  []byte
+			)
+
+			require.NoError(t, db.QueryRowContext(ctx, `SELECT name, size, crc32, encrypted, aes_salt, aes_iv
+FROM files JOIN archives ON archives.id = files.archive_id WHERE path = ? AND idx = ?`,
+				table.file, i).Scan(&name, &size, &crc, &encrypted, &salt, &iv))
+
+			assert.Equal(t, f.Name, name)
+			assert.Equal(t, int64(f.UncompressedSize), size) //nolint:gosec
+			assert.Equal(t, f.CRC32 != 0, crc.Valid)
+			assert.Equal(t, int64(f.CRC32), crc.Int64)
+
+			if f.UncompressedSize > 0 {
+				assert.Equal(t, table.encrypted, encrypted)
+				assert.Equal(t, table.encrypted, len(iv) > 0)
+			}
+		}
+
+		require.NoError(t, r.Close())
+	}
+
+	var archives int
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT COUNT(*) FROM archives").Scan(&archives))
+	assert.Equal(t, len(tables), archives)
+}
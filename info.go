@@ -0,0 +1,138 @@
+package sevenzip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maxTrailingSize is the most trailing data returned by [Reader.Info], and
+// how far before the archive a configuration block is looked for.
+const maxTrailingSize = 1 << 20
+
+// The markers around the configuration block of a 7-Zip installer stub.
+const (
+	sfxConfigStart = ";!@Install@!UTF-8!"
+	sfxConfigEnd   = ";!@InstallEnd@!"
+)
+
+// An ArchiveProperty is a property recorded for the whole archive. The
+// format reserves space for them but doesn't define any, so they are
+// returned as they are found.
+type ArchiveProperty struct {
+	Type byte
+	Data []byte
+}
+
+// ArchiveInfo holds auxiliary data found in an archive that isn't needed to
+// read its files, such as data embedded by some packers. Everything is
+// returned as raw bytes as there is no agreed format.
+type ArchiveInfo struct {
+	// Properties are the archive properties from the header.
+	Properties []ArchiveProperty
+	// Comment is the raw data of the comment property from the files
+	// information in the header, which some packers use to store a
+	// comment.
+	Comment []byte
+	// Trailing is the data following the end of the archive, limited to
+	// the first 1 MiB.
+	Trailing []byte
+	// TrailingSize is the full size of the data following the end of the
+	// archive.
+	TrailingSize int64
+	// StubSize is the size of the data before the archive, such as the
+	// executable of a self-extracting archive, up to any SFXConfig. It's
+	// zero when the archive is at the start of the file. Repackaging
+	// tools can copy this much of the file, then SFXConfig, then write
+	// the new archive in place of the one at [Reader.BaseOffset].
+	StubSize int64
+	// SFXConfig is the configuration block of a 7-Zip installer stub
+	// between the stub and the archive, from ";!@Install@!UTF-8!" up to
+	// and including ";!@InstallEnd@!", or nil if there isn't one. It
+	// holds settings such as the title and program to run.
+	SFXConfig []byte
+	// MajorVersion and MinorVersion are the format version of the
+	// archive, 0.4 for archives written by current versions of 7-Zip.
+	MajorVersion byte
+	MinorVersion byte
+	// Warnings describe problems found while opening the archive that
+	// didn't stop it being read, such as a newer format version.
+	Warnings []string
+	// HeaderChain describes how the header was encoded, one block for
+	// each level of encoded header starting with the one referenced by
+	// the start header, or is nil if the header wasn't encoded. 7-Zip
+	// writes a single level, compressed and optionally encrypted, but
+	// other writers can nest further levels. NumFiles is always zero.
+	HeaderChain []Block
+}
+
+// Info returns any auxiliary data found in the archive. Any trailing data is
+// read from the archive so an error is returned if that fails.
+func (z *Reader) Info() (*ArchiveInfo, error) {
+	info := &ArchiveInfo{
+		Properties:   z.h.properties,
+		TrailingSize: max(z.size-z.end-int64(z.sh.Size), 0), //nolint:gosec
+		MajorVersion: z.version[0],
+		MinorVersion: z.version[1],
+		Warnings:     z.warnings,
+	}
+
+	if z.h.filesInfo != nil {
+		info.Comment = z.h.filesInfo.comment
+	}
+
+	if err := z.sfxConfig(info); err != nil {
+		return nil, err
+	}
+
+	for _, si := range z.headerChain {
+		for _, b := range z.blocks(si) {
+			b.NumFiles = 0
+			info.HeaderChain = append(info.HeaderChain, b)
+		}
+	}
+
+	if info.TrailingSize > 0 {
+		info.Trailing = make([]byte, min(info.TrailingSize, maxTrailingSize))
+
+		if _, err := z.r.ReadAt(info.Trailing, z.end+int64(z.sh.Size)); err != nil && !errors.Is(err, io.EOF) { //nolint:gosec
+			return nil, fmt.Errorf("sevenzip: error reading trailing data: %w", err)
+		}
+	}
+
+	return info, nil
+}
+
+// sfxConfig sets the size of the stub before the archive and the
+// configuration block of a 7-Zip installer stub if there is one. The block
+// must be followed by nothing but line endings or padding up to the archive,
+// as the stub itself contains the markers it looks for.
+func (z *Reader) sfxConfig(info *ArchiveInfo) error {
+	info.StubSize = z.base
+	if z.base == 0 {
+		return nil
+	}
+
+	start := max(z.base-maxTrailingSize, 0)
+
+	b := make([]byte, z.base-start)
+	if _, err := z.r.ReadAt(b, start); err != nil {
+		return fmt.Errorf("sevenzip: error reading stub: %w", err)
+	}
+
+	i := bytes.LastIndex(b, []byte(sfxConfigStart))
+	if i == -1 {
+		return nil
+	}
+
+	n := bytes.Index(b[i:], []byte(sfxConfigEnd))
+	if n == -1 || len(bytes.Trim(b[i+n+len(sfxConfigEnd):], " \t\r\n\x00")) > 0 {
+		return nil
+	}
+
+	info.StubSize = start + int64(i)
+	info.SFXConfig = b[i : i+n+len(sfxConfigEnd)]
+
+	return nil
+}
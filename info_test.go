@@ -0,0 +1,96 @@
+package sevenzip_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInfo(t *testing.T) {
+	t.Parallel()
+
+	comment := []byte{
+		'A', 0, ' ', 0, 'c', 0, 'o', 0, 'm', 0, 'm', 0, 'e', 0, 'n', 0, 't', 0, 0, 0,
+	}
+
+	tables := []struct {
+		name string
+		file string
+		opts []sevenzip.ReaderOption
+		info *sevenzip.ArchiveInfo
+		// The methods of each level of the encoded header
+		chain []string
+	}{
+		{
+			name: "none",
+			file: "t0.7z",
+			info: &sevenzip.ArchiveInfo{MinorVersion: 4},
+		},
+		{
+			name:  "legacy version",
+			file:  "issue87.7z",
+			info:  &sevenzip.ArchiveInfo{MinorVersion: 3},
+			chain: []string{"LZMA:12"},
+		},
+		{
+			name: "comment and trailing data",
+			file: "comment.7z",
+			info: &sevenzip.ArchiveInfo{
+				Properties: []sevenzip.ArchiveProperty{
+					{Type: 0x42, Data: []byte("packer")},
+				},
+				Comment:      comment,
+				Trailing:     []byte("trailing data"),
+				TrailingSize: 13,
+				MinorVersion: 4,
+			},
+		},
+		{
+			name: "with name filter",
+			file: "comment.7z",
+			opts: []sevenzip.ReaderOption{
+				sevenzip.WithNameFilter(func(string) bool {
+					return false
+				}),
+			},
+			info: &sevenzip.ArchiveInfo{
+				Properties: []sevenzip.ArchiveProperty{
+					{Type: 0x42, Data: []byte("packer")},
+				},
+				Comment:      comment,
+				Trailing:     []byte("trailing data"),
+				TrailingSize: 13,
+				MinorVersion: 4,
+			},
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := sevenzip.OpenReaderWithOptions(filepath.Join("testdata", table.file), table.opts...)
+			require.NoError(t, err)
+
+			defer func() {
+				require.NoError(t, r.Close())
+			}()
+
+			info, err := r.Info()
+			require.NoError(t, err)
+
+			var chain []string
+			for _, b := range info.HeaderChain {
+				chain = append(chain, b.Method())
+			}
+
+			assert.Equal(t, table.chain, chain)
+
+			info.HeaderChain = nil
+			assert.Equal(t, table.info, info)
+		})
+	}
+}
@@ -19,14 +19,20 @@ type cacheKey struct {
 	salt     string // []byte isn't comparable
 }
 
-const cacheSize = 10
+const (
+	cacheSize = 10
+
+	// How many rounds of hashing to perform between checking for
+	// cancellation
+	checkInterval = 1 << 16
+)
 
 //nolint:gochecknoglobals
 var once = sync.OnceValues(func() (*lru.Cache[cacheKey, []byte], error) {
 	return lru.New[cacheKey, []byte](cacheSize)
 })
 
-func calculateKey(password string, cycles int, salt []byte) ([]byte, error) {
+func calculateKey(password string, cycles int, salt []byte, done <-chan struct{}) ([]byte, error) {
 	cache, err := once()
 	if err != nil {
 		return nil, fmt.Errorf("aes7z: error creating cache: %w", err)
@@ -42,7 +48,9 @@ func calculateKey(password string, cycles int, salt []byte) ([]byte, error) {
 		return key, nil
 	}
 
-	b := bytes.NewBuffer(salt)
+	// Copy the salt as it is usually a slice of the coder properties and
+	// appending to it in place would overwrite whatever follows
+	b := bytes.NewBuffer(bytes.Clone(salt))
 
 	// Convert password to UTF-16LE
 	utf16le := unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
@@ -55,6 +63,10 @@ func calculateKey(password string, cycles int, salt []byte) ([]byte, error) {
 	} else {
 		h := sha256.New()
 		for i := range uint64(1 << cycles) {
+			if i%checkInterval == 0 && isDone(done) {
+				return nil, errCancelled
+			}
+
 			// These will never error
 			_, _ = h.Write(b.Bytes())
 			_ = binary.Write(h, binary.LittleEndian, i)
@@ -67,3 +79,12 @@ func calculateKey(password string, cycles int, salt []byte) ([]byte, error) {
 
 	return key, nil
 }
+
+func isDone(done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
@@ -16,6 +16,7 @@ var (
 	errInsufficientProperties = errors.New("aes7z: not enough properties")
 	errNoPasswordSet          = errors.New("aes7z: no password set")
 	errUnsupportedMethod      = errors.New("aes7z: unsupported compression method")
+	errCancelled              = errors.New("aes7z: key derivation cancelled")
 )
 
 type readCloser struct {
@@ -24,6 +25,8 @@ type readCloser struct {
 	cycles   int
 	cbc      cipher.BlockMode
 	buf      bytes.Buffer
+	done     <-chan struct{}
+	newCBC   func(key, iv []byte) (cipher.BlockMode, error)
 }
 
 func (rc *readCloser) Close() error {
@@ -40,22 +43,45 @@ func (rc *readCloser) Close() error {
 	return nil
 }
 
+// SetCancel allows the key derivation started by Password to be abandoned
+// when done is closed.
+func (rc *readCloser) SetCancel(done <-chan struct{}) {
+	rc.done = done
+}
+
+// SetCBCDecrypter replaces the default crypto/aes implementation used once
+// the key has been derived.
+func (rc *readCloser) SetCBCDecrypter(fn func(key, iv []byte) (cipher.BlockMode, error)) {
+	rc.newCBC = fn
+}
+
 func (rc *readCloser) Password(p string) error {
-	key, err := calculateKey(p, rc.cycles, rc.salt)
+	key, err := calculateKey(p, rc.cycles, rc.salt, rc.done)
 	if err != nil {
 		return err
 	}
 
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return fmt.Errorf("aes7z: error creating cipher: %w", err)
+	newCBC := rc.newCBC
+	if newCBC == nil {
+		newCBC = newCBCDecrypter
 	}
 
-	rc.cbc = cipher.NewCBCDecrypter(block, rc.iv)
+	if rc.cbc, err = newCBC(key, rc.iv); err != nil {
+		return fmt.Errorf("aes7z: error creating cipher: %w", err)
+	}
 
 	return nil
 }
 
+func newCBCDecrypter(key, iv []byte) (cipher.BlockMode, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return cipher.NewCBCDecrypter(block, iv), nil
+}
+
 func (rc *readCloser) Read(p []byte) (int, error) {
 	if rc.rc == nil {
 		return 0, errAlreadyClosed
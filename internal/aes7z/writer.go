@@ -0,0 +1,123 @@
+package aes7z
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"io"
+)
+
+// DefaultCycles is the number of key derivation cycles used by 7-zip, as a
+// power of two.
+const DefaultCycles = 19
+
+type writeCloser struct {
+	w   io.Writer
+	cbc cipher.BlockMode
+	buf []byte
+}
+
+func (wc *writeCloser) Write(p []byte) (int, error) {
+	if wc.w == nil {
+		return 0, errAlreadyClosed
+	}
+
+	n := len(p)
+
+	wc.buf = append(wc.buf, p...)
+
+	if full := len(wc.buf) / aes.BlockSize * aes.BlockSize; full > 0 {
+		wc.cbc.CryptBlocks(wc.buf[:full], wc.buf[:full])
+
+		if _, err := wc.w.Write(wc.buf[:full]); err != nil {
+			return 0, fmt.Errorf("aes7z: error writing: %w", err)
+		}
+
+		wc.buf = wc.buf[:copy(wc.buf, wc.buf[full:])]
+	}
+
+	return n, nil
+}
+
+// Close pads and writes any remaining partial block. It does not close the
+// underlying io.Writer.
+func (wc *writeCloser) Close() error {
+	if wc.w == nil {
+		return errAlreadyClosed
+	}
+
+	if len(wc.buf) > 0 {
+		block := make([]byte, aes.BlockSize)
+		copy(block, wc.buf)
+
+		wc.cbc.CryptBlocks(block, block)
+
+		if _, err := wc.w.Write(block); err != nil {
+			return fmt.Errorf("aes7z: error writing: %w", err)
+		}
+	}
+
+	wc.w, wc.buf = nil, nil
+
+	return nil
+}
+
+// EncryptedSize returns the size of the stream produced by encrypting size
+// bytes, which is always padded to a multiple of the AES block size.
+func EncryptedSize(size uint64) uint64 {
+	return (size + aes.BlockSize - 1) / aes.BlockSize * aes.BlockSize
+}
+
+// NewProperties returns the properties for a new stream using 2^cycles rounds
+// of key derivation and a random initialisation vector. There is no salt,
+// the same as 7-zip.
+func NewProperties(cycles int) ([]byte, error) {
+	if cycles < 0 || cycles > 0x3f {
+		return nil, errUnsupportedMethod
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, fmt.Errorf("aes7z: error generating iv: %w", err)
+	}
+
+	return append([]byte{0x40 | byte(cycles), aes.BlockSize - 1}, iv...), nil
+}
+
+// Cycles returns the number of key derivation rounds, as a power of two,
+// described by the properties p.
+func Cycles(p []byte) int {
+	if len(p) == 0 {
+		return DefaultCycles
+	}
+
+	return int(p[0] & 0x3f)
+}
+
+// NewWriter returns a new AES-256-CBC & SHA-256 io.WriteCloser that encrypts
+// to w with a key derived from password, described by the properties p as
+// returned by NewProperties.
+func NewWriter(w io.Writer, p []byte, password string) (io.WriteCloser, error) {
+	rc, err := NewReader(p, 0, []io.ReadCloser{io.NopCloser(nil)})
+	if err != nil {
+		return nil, err
+	}
+
+	params := rc.(*readCloser) //nolint:forcetypeassert
+
+	key, err := calculateKey(password, params.cycles, params.salt, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("aes7z: error creating cipher: %w", err)
+	}
+
+	return &writeCloser{
+		w:   w,
+		cbc: cipher.NewCBCEncrypter(block, params.iv),
+	}, nil
+}
@@ -7,6 +7,8 @@ import (
 
 const armAlignment = 4
 
+// The program counter on ARM is two instructions ahead so ip starts one
+// instruction ahead of the start offset and is advanced before each one.
 type arm struct {
 	ip uint32
 }
@@ -18,10 +20,6 @@ func (c *arm) Convert(b []byte, encoding bool) int {
 		return 0
 	}
 
-	if c.ip == 0 {
-		c.ip += armAlignment
-	}
-
 	var i int
 
 	for i = 0; i < len(b) & ^(armAlignment-1); i += armAlignment {
@@ -49,7 +47,13 @@ func (c *arm) Convert(b []byte, encoding bool) int {
 	return i
 }
 
-// NewARMReader returns a new ARM io.ReadCloser.
-func NewARMReader(_ []byte, _ uint64, readers []io.ReadCloser) (io.ReadCloser, error) {
-	return newReader(readers, new(arm))
+// NewARMReader returns a new ARM io.ReadCloser. The properties may contain
+// an optional start offset.
+func NewARMReader(p []byte, _ uint64, readers []io.ReadCloser) (io.ReadCloser, error) {
+	ip, err := startOffset(p, armAlignment)
+	if err != nil {
+		return nil, err
+	}
+
+	return newReader(readers, &arm{ip: ip + armAlignment})
 }
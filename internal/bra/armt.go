@@ -0,0 +1,67 @@
+package bra
+
+import "io"
+
+const (
+	armtAlignment = 2
+	// A BL instruction is a pair of halfwords holding the upper and lower
+	// halves of the offset.
+	armtLookahead = 4
+)
+
+// The program counter in Thumb state is four bytes ahead of the BL
+// instruction being converted.
+type armt struct {
+	ip uint32
+}
+
+func (c *armt) Size() int { return armtLookahead }
+
+func (c *armt) Convert(b []byte, encoding bool) int {
+	if len(b) < c.Size() {
+		return 0
+	}
+
+	var i int
+
+	for i = 0; i <= len(b)-armtLookahead; i += armtAlignment {
+		if b[i+1]&0xf8 != 0xf0 || b[i+3]&0xf8 != 0xf8 {
+			continue
+		}
+
+		v := uint32(b[i+1]&0x07)<<19 | uint32(b[i])<<11 | uint32(b[i+3]&0x07)<<8 | uint32(b[i+2])
+		v <<= 1
+
+		pc := c.ip + uint32(i) + armtLookahead //nolint:gosec
+
+		if encoding {
+			v += pc
+		} else {
+			v -= pc
+		}
+
+		v >>= 1
+
+		b[i+1] = byte(0xf0 | (v>>19)&0x07)
+		b[i] = byte(v >> 11)
+		b[i+3] = byte(0xf8 | (v>>8)&0x07)
+		b[i+2] = byte(v)
+
+		i += armtLookahead - armtAlignment
+	}
+
+	c.ip += uint32(i) //nolint:gosec
+
+	return i
+}
+
+// NewARMTReader returns a new ARM Thumb io.ReadCloser. The properties may
+// contain an optional start offset.
+func NewARMTReader(p []byte, _ uint64, readers []io.ReadCloser) (io.ReadCloser, error) {
+	ip, err := startOffset(p, armtAlignment)
+	if err != nil {
+		return nil, err
+	}
+
+	return newReader(readers, &armt{ip: ip})
+}
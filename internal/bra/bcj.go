@@ -98,7 +98,13 @@ func (c *bcj) Convert(b []byte, encoding bool) int {
 	}
 }
 
-// NewBCJReader returns a new BCJ io.ReadCloser.
-func NewBCJReader(_ []byte, _ uint64, readers []io.ReadCloser) (io.ReadCloser, error) {
-	return newReader(readers, new(bcj))
+// NewBCJReader returns a new BCJ io.ReadCloser. The properties may contain
+// an optional start offset.
+func NewBCJReader(p []byte, _ uint64, readers []io.ReadCloser) (io.ReadCloser, error) {
+	ip, err := startOffset(p, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return newReader(readers, &bcj{ip: ip})
 }
@@ -1,7 +1,31 @@
 // Package bra implements the branch rewriting filter for binaries.
 package bra
 
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var errInvalidProperties = errors.New("bra: invalid properties")
+
 type converter interface {
 	Size() int
 	Convert(b []byte, encoding bool) int
 }
+
+// startOffset returns the start offset from the filter properties, which is
+// an optional little-endian 32-bit value that must be a multiple of the
+// alignment of the instructions. It is used when the data being filtered
+// isn't loaded at address zero.
+func startOffset(p []byte, alignment uint32) (uint32, error) {
+	switch len(p) {
+	case 0:
+		return 0, nil
+	case 4: //nolint:mnd
+		if offset := binary.LittleEndian.Uint32(p); offset%alignment == 0 {
+			return offset, nil
+		}
+	}
+
+	return 0, errInvalidProperties
+}
@@ -0,0 +1,99 @@
+package bra
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// The testdata/*.in files are random bytes with the instructions each filter
+// converts mixed in. The matching *.out files are what XZ Utils 5.6.4 makes
+// of them, taken from between its filter and LZMA2 with
+//
+//	xz --format=raw --<filter>[=start=4096] --lzma2=preset=0 -c x.in |
+//	xz -d --format=raw --lzma2=preset=0 -c > x.out
+//
+//nolint:gochecknoglobals
+var vectors = []struct {
+	name      string
+	newReader func([]byte, uint64, []io.ReadCloser) (io.ReadCloser, error)
+	newWriter func([]byte, io.WriteCloser) (io.WriteCloser, error)
+}{
+	{"riscv", NewRISCVReader, NewRISCVWriter},
+	{"ia64", NewIA64Reader, NewIA64Writer},
+	{"armt", NewARMTReader, NewARMTWriter},
+}
+
+func testdata(t *testing.T, name string) []byte {
+	t.Helper()
+
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+
+	return b
+}
+
+func TestVectors(t *testing.T) {
+	t.Parallel()
+
+	for _, v := range vectors {
+		for _, start := range []uint32{0, 4096} {
+			t.Run(v.name+"/"+strconv.Itoa(int(start)), func(t *testing.T) {
+				t.Parallel()
+
+				in := testdata(t, v.name+".in")
+
+				name, p := v.name+".out", []byte(nil)
+				if start != 0 {
+					name = v.name + "_" + strconv.Itoa(int(start)) + ".out"
+					p = binary.LittleEndian.AppendUint32(nil, start)
+				}
+
+				want := testdata(t, name)
+
+				// Write in uneven pieces so instructions are split
+				// between writes
+				buf := new(bytes.Buffer)
+
+				wc, err := v.newWriter(p, nopWriteCloser{buf})
+				require.NoError(t, err)
+
+				for b := in; len(b) > 0; {
+					n := min(len(b), 1+len(b)%13)
+
+					_, err = wc.Write(b[:n])
+					require.NoError(t, err)
+
+					b = b[n:]
+				}
+
+				require.NoError(t, wc.Close())
+				assert.True(t, bytes.Equal(want, buf.Bytes()), "encoded output differs from XZ")
+
+				rc, err := v.newReader(p, uint64(len(want)), []io.ReadCloser{io.NopCloser(bytes.NewReader(want))})
+				require.NoError(t, err)
+
+				// Like the archive reader, stop at the unpacked size as
+				// the filter doesn't report the end of the stream itself
+				out, err := io.ReadAll(io.LimitReader(iotest.OneByteReader(rc), int64(len(want))))
+				require.NoError(t, err)
+				require.NoError(t, rc.Close())
+				assert.True(t, bytes.Equal(in, out), "decoded output differs from input")
+			})
+		}
+	}
+}
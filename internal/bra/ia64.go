@@ -0,0 +1,93 @@
+package bra
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+const ia64Alignment = 16
+
+// ia64Slots is the mask of the slots of each bundle template that can hold
+// a branch.
+//
+//nolint:gochecknoglobals
+var ia64Slots = [32]uint32{
+	0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0,
+	4, 4, 6, 6, 0, 0, 7, 7,
+	4, 4, 0, 0, 4, 4, 0, 0,
+}
+
+// Each 16 byte bundle holds a 5 bit template followed by three 41 bit
+// instructions.
+type ia64 struct {
+	ip uint32
+}
+
+func (c *ia64) Size() int { return ia64Alignment }
+
+func (c *ia64) Convert(b []byte, encoding bool) int {
+	if len(b) < c.Size() {
+		return 0
+	}
+
+	var i int
+
+	for i = 0; i+ia64Alignment <= len(b); i += ia64Alignment {
+		mask := ia64Slots[b[i]&0x1f]
+
+		for slot, pos := 0, 5; slot < 3; slot, pos = slot+1, pos+41 {
+			if (mask>>slot)&1 == 0 {
+				continue
+			}
+
+			var p [8]byte
+
+			copy(p[:], b[i+pos/8:i+pos/8+6])
+
+			shift := pos % 8
+			v := binary.LittleEndian.Uint64(p[:])
+			inst := v >> shift
+
+			if (inst>>37)&0xf != 0x5 || (inst>>9)&0x7 != 0 {
+				continue
+			}
+
+			addr := uint32((inst>>13)&0xfffff | ((inst>>36)&1)<<20) //nolint:gosec
+			addr <<= 4
+
+			if encoding {
+				addr += c.ip + uint32(i) //nolint:gosec
+			} else {
+				addr -= c.ip + uint32(i) //nolint:gosec
+			}
+
+			addr >>= 4
+
+			inst &^= uint64(0x8fffff) << 13
+			inst |= uint64(addr&0xfffff) << 13
+			inst |= uint64(addr&0x100000) << (36 - 20)
+
+			v &= 1<<shift - 1
+			v |= inst << shift
+
+			binary.LittleEndian.PutUint64(p[:], v)
+			copy(b[i+pos/8:], p[:6])
+		}
+	}
+
+	c.ip += uint32(i) //nolint:gosec
+
+	return i
+}
+
+// NewIA64Reader returns a new IA64 io.ReadCloser. The properties may contain
+// an optional start offset.
+func NewIA64Reader(p []byte, _ uint64, readers []io.ReadCloser) (io.ReadCloser, error) {
+	ip, err := startOffset(p, ia64Alignment)
+	if err != nil {
+		return nil, err
+	}
+
+	return newReader(readers, &ia64{ip: ip})
+}
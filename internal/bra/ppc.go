@@ -42,7 +42,13 @@ func (c *ppc) Convert(b []byte, encoding bool) int {
 	return i
 }
 
-// NewPPCReader returns a new PPC io.ReadCloser.
-func NewPPCReader(_ []byte, _ uint64, readers []io.ReadCloser) (io.ReadCloser, error) {
-	return newReader(readers, new(ppc))
+// NewPPCReader returns a new PPC io.ReadCloser. The properties may contain
+// an optional start offset.
+func NewPPCReader(p []byte, _ uint64, readers []io.ReadCloser) (io.ReadCloser, error) {
+	ip, err := startOffset(p, ppcAlignment)
+	if err != nil {
+		return nil, err
+	}
+
+	return newReader(readers, &ppc{ip: ip})
 }
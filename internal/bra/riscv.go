@@ -0,0 +1,142 @@
+package bra
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+const (
+	riscvAlignment = 2
+	// Pairs of AUIPC and a following instruction are converted together
+	// so up to eight bytes are needed to decide on each position.
+	riscvLookahead = 8
+)
+
+// riscv converts the targets of JAL instructions and AUIPC pairs, the same as
+// the RISCV filter in 7-Zip and XZ. An AUIPC whose destination is used by
+// the next instruction is rewritten with the absolute address, in big-endian
+// order, and with its register set to x2 as a marker. An AUIPC that already
+// uses x2 but isn't one of these is swapped into the same layout so the two
+// can be told apart when decoding.
+type riscv struct {
+	ip uint32
+}
+
+func (c *riscv) Size() int { return riscvLookahead }
+
+//nolint:cyclop,funlen
+func (c *riscv) Convert(b []byte, encoding bool) int {
+	if len(b) < c.Size() {
+		return 0
+	}
+
+	var i int
+
+	for i = 0; i <= len(b)-riscvLookahead; i += riscvAlignment {
+		inst := uint32(b[i])
+
+		switch {
+		case inst == 0xef: // JAL with rd x1 or x5
+			if b[i+1]&0x0d != 0 {
+				continue
+			}
+
+			b1, b2, b3 := uint32(b[i+1]), uint32(b[i+2]), uint32(b[i+3])
+			pc := c.ip + uint32(i) //nolint:gosec
+
+			if encoding {
+				addr := (b1&0xf0)<<8 | (b2&0x0f)<<16 | (b2&0x10)<<7 | (b2&0xe0)>>4 | (b3&0x7f)<<4 | (b3&0x80)<<13
+				addr += pc
+
+				b[i+1] = byte(b1&0x0f | (addr>>13)&0xf0)
+				b[i+2] = byte(addr >> 9)
+				b[i+3] = byte(addr >> 1)
+			} else {
+				addr := (b1&0xf0)<<13 | b2<<9 | b3<<1
+				addr -= pc
+
+				b[i+1] = byte(b1&0x0f | (addr>>8)&0xf0)
+				b[i+2] = byte((addr>>16)&0x0f | (addr>>7)&0x10 | (addr<<4)&0xe0)
+				b[i+3] = byte((addr>>4)&0x7f | (addr>>13)&0x80)
+			}
+
+			i += 4 - riscvAlignment
+		case inst&0x7f == 0x17: // AUIPC
+			inst = binary.LittleEndian.Uint32(b[i:])
+			pc := c.ip + uint32(i) //nolint:gosec
+
+			// The register isn't x0 or x2 in the input, which is an
+			// unconverted pair when encoding and one swapped to
+			// avoid the marker when decoding
+			if inst&0xe80 != 0 {
+				inst2 := binary.LittleEndian.Uint32(b[i+4:])
+
+				// The next instruction must use the register
+				// of the AUIPC as its source
+				if ((inst<<8)^(inst2-3))&0xf8003 != 0 {
+					i += 6 - riscvAlignment
+
+					continue
+				}
+
+				if encoding {
+					addr := inst&0xfffff000 + inst2>>20 - (inst2>>19)&0x1000
+					addr += pc
+
+					binary.LittleEndian.PutUint32(b[i:], 0x17|2<<7|inst2<<12)
+					binary.BigEndian.PutUint32(b[i+4:], addr)
+				} else {
+					addr := inst&0xfffff000 + inst2>>20
+
+					binary.LittleEndian.PutUint32(b[i:], 0x17|2<<7|inst2<<12)
+					binary.LittleEndian.PutUint32(b[i+4:], addr)
+				}
+
+				i += 8 - riscvAlignment
+
+				continue
+			}
+
+			// The register is x0 or x2, only the latter of which can
+			// be the marker, and the source register of the pair is
+			// stored where the top of the address would be
+			rs1 := inst >> 27
+
+			if (inst-0x3117)<<18 >= rs1&0x1d {
+				i += 4 - riscvAlignment
+
+				continue
+			}
+
+			if encoding {
+				addr := binary.LittleEndian.Uint32(b[i+4:])
+
+				binary.LittleEndian.PutUint32(b[i:], 0x17|rs1<<7|addr&0xfffff000)
+				binary.LittleEndian.PutUint32(b[i+4:], inst>>12|addr<<20)
+			} else {
+				addr := binary.BigEndian.Uint32(b[i+4:])
+				addr -= pc
+
+				binary.LittleEndian.PutUint32(b[i:], 0x17|rs1<<7|(addr+0x800)&0xfffff000)
+				binary.LittleEndian.PutUint32(b[i+4:], inst>>12|addr<<20)
+			}
+
+			i += 8 - riscvAlignment
+		}
+	}
+
+	c.ip += uint32(i) //nolint:gosec
+
+	return i
+}
+
+// NewRISCVReader returns a new RISC-V io.ReadCloser. The properties may
+// contain an optional start offset.
+func NewRISCVReader(p []byte, _ uint64, readers []io.ReadCloser) (io.ReadCloser, error) {
+	ip, err := startOffset(p, riscvAlignment)
+	if err != nil {
+		return nil, err
+	}
+
+	return newReader(readers, &riscv{ip: ip})
+}
@@ -47,7 +47,13 @@ func (c *sparc) Convert(b []byte, encoding bool) int {
 	return i
 }
 
-// NewSPARCReader returns a new SPARC io.ReadCloser.
-func NewSPARCReader(_ []byte, _ uint64, readers []io.ReadCloser) (io.ReadCloser, error) {
-	return newReader(readers, new(sparc))
+// NewSPARCReader returns a new SPARC io.ReadCloser. The properties may contain
+// an optional start offset.
+func NewSPARCReader(p []byte, _ uint64, readers []io.ReadCloser) (io.ReadCloser, error) {
+	ip, err := startOffset(p, sparcAlignment)
+	if err != nil {
+		return nil, err
+	}
+
+	return newReader(readers, &sparc{ip: ip})
 }
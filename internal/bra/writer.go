@@ -0,0 +1,140 @@
+package bra
+
+import (
+	"fmt"
+	"io"
+)
+
+type writeCloser struct {
+	wc   io.WriteCloser
+	buf  []byte
+	conv converter
+}
+
+func (wc *writeCloser) Write(p []byte) (int, error) {
+	if wc.wc == nil {
+		return 0, errAlreadyClosed
+	}
+
+	wc.buf = append(wc.buf, p...)
+
+	// Anything the converter can't yet decide on is kept until there's
+	// more to follow it
+	n := wc.conv.Convert(wc.buf, true)
+	if _, err := wc.wc.Write(wc.buf[:n]); err != nil {
+		return 0, fmt.Errorf("bra: error writing: %w", err)
+	}
+
+	wc.buf = append(wc.buf[:0], wc.buf[n:]...)
+
+	return len(p), nil
+}
+
+// Close writes anything left unconverted, the same as the reader passes it
+// through at the end of the stream, and closes the underlying writer.
+func (wc *writeCloser) Close() error {
+	if wc.wc == nil {
+		return errAlreadyClosed
+	}
+
+	n := wc.conv.Convert(wc.buf, true)
+	if _, err := wc.wc.Write(wc.buf[:n]); err != nil {
+		return fmt.Errorf("bra: error writing: %w", err)
+	}
+
+	if _, err := wc.wc.Write(wc.buf[n:]); err != nil {
+		return fmt.Errorf("bra: error writing: %w", err)
+	}
+
+	if err := wc.wc.Close(); err != nil {
+		return fmt.Errorf("bra: error closing: %w", err)
+	}
+
+	wc.wc, wc.buf = nil, nil
+
+	return nil
+}
+
+// NewBCJWriter returns a new BCJ io.WriteCloser that filters what is written
+// to it before writing it to wc, which is closed when it's closed. The
+// properties may contain an optional start offset.
+func NewBCJWriter(p []byte, wc io.WriteCloser) (io.WriteCloser, error) {
+	ip, err := startOffset(p, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return &writeCloser{wc: wc, conv: &bcj{ip: ip}}, nil
+}
+
+// NewARMWriter returns a new ARM io.WriteCloser that filters what is written
+// to it before writing it to wc, which is closed when it's closed. The
+// properties may contain an optional start offset.
+func NewARMWriter(p []byte, wc io.WriteCloser) (io.WriteCloser, error) {
+	ip, err := startOffset(p, armAlignment)
+	if err != nil {
+		return nil, err
+	}
+
+	return &writeCloser{wc: wc, conv: &arm{ip: ip + armAlignment}}, nil
+}
+
+// NewPPCWriter returns a new PPC io.WriteCloser that filters what is written
+// to it before writing it to wc, which is closed when it's closed. The
+// properties may contain an optional start offset.
+func NewPPCWriter(p []byte, wc io.WriteCloser) (io.WriteCloser, error) {
+	ip, err := startOffset(p, ppcAlignment)
+	if err != nil {
+		return nil, err
+	}
+
+	return &writeCloser{wc: wc, conv: &ppc{ip: ip}}, nil
+}
+
+// NewSPARCWriter returns a new SPARC io.WriteCloser that filters what is
+// written to it before writing it to wc, which is closed when it's closed.
+// The properties may contain an optional start offset.
+func NewSPARCWriter(p []byte, wc io.WriteCloser) (io.WriteCloser, error) {
+	ip, err := startOffset(p, sparcAlignment)
+	if err != nil {
+		return nil, err
+	}
+
+	return &writeCloser{wc: wc, conv: &sparc{ip: ip}}, nil
+}
+
+// NewRISCVWriter returns a new RISC-V io.WriteCloser that filters what is
+// written to it before writing it to wc, which is closed when it's closed.
+// The properties may contain an optional start offset.
+func NewRISCVWriter(p []byte, wc io.WriteCloser) (io.WriteCloser, error) {
+	ip, err := startOffset(p, riscvAlignment)
+	if err != nil {
+		return nil, err
+	}
+
+	return &writeCloser{wc: wc, conv: &riscv{ip: ip}}, nil
+}
+
+// NewIA64Writer returns a new IA64 io.WriteCloser that filters what is
+// written to it before writing it to wc, which is closed when it's closed.
+// The properties may contain an optional start offset.
+func NewIA64Writer(p []byte, wc io.WriteCloser) (io.WriteCloser, error) {
+	ip, err := startOffset(p, ia64Alignment)
+	if err != nil {
+		return nil, err
+	}
+
+	return &writeCloser{wc: wc, conv: &ia64{ip: ip}}, nil
+}
+
+// NewARMTWriter returns a new ARM Thumb io.WriteCloser that filters what is
+// written to it before writing it to wc, which is closed when it's closed.
+// The properties may contain an optional start offset.
+func NewARMTWriter(p []byte, wc io.WriteCloser) (io.WriteCloser, error) {
+	ip, err := startOffset(p, armtAlignment)
+	if err != nil {
+		return nil, err
+	}
+
+	return &writeCloser{wc: wc, conv: &armt{ip: ip}}, nil
+}
@@ -0,0 +1,63 @@
+package delta
+
+import (
+	"fmt"
+	"io"
+)
+
+type writeCloser struct {
+	wc      io.WriteCloser
+	history [stateSize]byte
+	delta   int
+	pos     int
+	buf     []byte
+}
+
+func (wc *writeCloser) Write(p []byte) (int, error) {
+	if wc.wc == nil {
+		return 0, errAlreadyClosed
+	}
+
+	wc.buf = wc.buf[:0]
+
+	for _, b := range p {
+		// The history holds the last delta bytes as a ring
+		wc.buf = append(wc.buf, b-wc.history[wc.pos])
+		wc.history[wc.pos] = b
+		wc.pos = (wc.pos + 1) % wc.delta
+	}
+
+	if _, err := wc.wc.Write(wc.buf); err != nil {
+		return 0, fmt.Errorf("delta: error writing: %w", err)
+	}
+
+	return len(p), nil
+}
+
+func (wc *writeCloser) Close() error {
+	if wc.wc == nil {
+		return errAlreadyClosed
+	}
+
+	if err := wc.wc.Close(); err != nil {
+		return fmt.Errorf("delta: error closing: %w", err)
+	}
+
+	wc.wc = nil
+
+	return nil
+}
+
+// NewWriter returns a new Delta io.WriteCloser that filters what is written
+// to it with the distance in the properties p before writing it to wc,
+// which is closed when it's closed.
+func NewWriter(p []byte, wc io.WriteCloser) (io.WriteCloser, error) {
+	if len(p) != 1 {
+		return nil, errInsufficientProperties
+	}
+
+	return &writeCloser{
+		wc:    wc,
+		delta: int(p[0]) + 1,
+	}, nil
+}
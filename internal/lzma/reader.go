@@ -48,7 +48,10 @@ func (rc *readCloser) Read(p []byte) (int, error) {
 	return n, err
 }
 
-// NewReader returns a new LZMA io.ReadCloser.
+// NewReader returns a new LZMA io.ReadCloser. The stream may be terminated
+// by an end marker as well as by reaching the uncompressed size s, which
+// 7-zip always records; a size of all ones marks it as unknown in which case
+// the end marker is required.
 func NewReader(p []byte, s uint64, readers []io.ReadCloser) (io.ReadCloser, error) {
 	if len(readers) != 1 {
 		return nil, errNeedOneReader
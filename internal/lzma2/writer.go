@@ -0,0 +1,81 @@
+package lzma2
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ulikunitz/xz/lzma"
+)
+
+var errInvalidDictionary = errors.New("lzma2: invalid dictionary size")
+
+// The largest dictionary that can be described by the properties byte, other
+// than the special value for 4 GiB - 1
+const maxDictProperty = 39
+
+type writeCloser struct {
+	w *lzma.Writer2
+}
+
+func (wc *writeCloser) Write(p []byte) (int, error) {
+	if wc.w == nil {
+		return 0, errAlreadyClosed
+	}
+
+	n, err := wc.w.Write(p)
+	if err != nil {
+		err = fmt.Errorf("lzma2: error writing: %w", err)
+	}
+
+	return n, err
+}
+
+// Close flushes any remaining data and writes the end of stream marker. It
+// does not close the underlying io.Writer.
+func (wc *writeCloser) Close() error {
+	if wc.w == nil {
+		return errAlreadyClosed
+	}
+
+	if err := wc.w.Close(); err != nil {
+		return fmt.Errorf("lzma2: error closing: %w", err)
+	}
+
+	wc.w = nil
+
+	return nil
+}
+
+func dictSize(p byte) int {
+	return (2 | (int(p) & 1)) << (p/2 + 11)
+}
+
+// NewWriter returns a new LZMA2 io.WriteCloser writing to w with a
+// dictionary of at least dictCap bytes, along with the properties describing
+// the stream.
+func NewWriter(w io.Writer, dictCap int) (io.WriteCloser, []byte, error) {
+	if dictCap <= 0 {
+		return nil, nil, errInvalidDictionary
+	}
+
+	var p byte
+	for p < maxDictProperty && dictSize(p) < dictCap {
+		p++
+	}
+
+	config := lzma.Writer2Config{
+		DictCap: dictSize(p),
+	}
+
+	if err := config.Verify(); err != nil {
+		return nil, nil, fmt.Errorf("lzma2: error verifying config: %w", err)
+	}
+
+	lw, err := config.NewWriter2(w)
+	if err != nil {
+		return nil, nil, fmt.Errorf("lzma2: error creating writer: %w", err)
+	}
+
+	return &writeCloser{w: lw}, []byte{p}, nil
+}
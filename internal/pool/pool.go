@@ -3,6 +3,7 @@ package pool
 
 import (
 	"container/list"
+	"errors"
 	"runtime"
 	"sort"
 	"sync"
@@ -14,6 +15,7 @@ import (
 type Pooler interface {
 	Get(offset int64) (util.SizeReadSeekCloser, bool)
 	Put(offset int64, rc util.SizeReadSeekCloser) (bool, error)
+	Close() error
 }
 
 // Constructor is the function prototype used to instantiate a pool.
@@ -34,11 +36,16 @@ func (noopPool) Put(_ int64, rc util.SizeReadSeekCloser) (bool, error) {
 	return false, rc.Close() //nolint:wrapcheck
 }
 
+func (noopPool) Close() error {
+	return nil
+}
+
 type pool struct {
 	mutex     sync.Mutex
 	size      int
 	evictList *list.List
 	items     map[int64]*list.Element
+	closed    bool
 }
 
 type entry struct {
@@ -47,10 +54,15 @@ type entry struct {
 }
 
 // NewPool returns a Pooler that uses a LRU strategy to maintain a fixed pool
-// of util.SizeReadSeekCloser's keyed by their stream offset.
-func NewPool() (Pooler, error) {
+// of size util.SizeReadSeekCloser's keyed by their stream offset. If size is
+// not positive, the number of CPUs is used.
+func NewPool(size int) (Pooler, error) {
+	if size <= 0 {
+		size = runtime.NumCPU()
+	}
+
 	return &pool{
-		size:      runtime.NumCPU(),
+		size:      size,
 		evictList: list.New(),
 		items:     make(map[int64]*list.Element),
 	}, nil
@@ -87,6 +99,10 @@ func (p *pool) Put(offset int64, rc util.SizeReadSeekCloser) (bool, error) {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
+	if p.closed {
+		return false, rc.Close() //nolint:wrapcheck
+	}
+
 	if _, ok := p.items[offset]; ok {
 		return false, nil
 	}
@@ -105,6 +121,23 @@ func (p *pool) Put(offset int64, rc util.SizeReadSeekCloser) (bool, error) {
 	return evict, err
 }
 
+// Close closes every pooled util.SizeReadSeekCloser. Anything subsequently
+// added to the pool is closed immediately.
+func (p *pool) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.closed = true
+
+	var errs []error
+
+	for p.evictList.Len() > 0 {
+		errs = append(errs, p.removeOldest())
+	}
+
+	return errors.Join(errs...)
+}
+
 func (p *pool) keys() []int64 {
 	keys := make([]int64, len(p.items))
 	i := 0
@@ -0,0 +1,893 @@
+package ppmd
+
+import "encoding/binary"
+
+// The model is a port of Ppmd7.c from 7-Zip, which is PPMd variant H by
+// Dmitry Shkarin. Everything the model allocates lives in a single block of
+// memory and is referred to by its offset, exactly as the original does with
+// 32-bit references, so that the model runs out of memory, and restarts, at
+// the same point as 7-Zip. Any difference would stop the streams from being
+// decoded by other implementations.
+//
+// Contexts are 12 bytes:
+//
+//	0  NumStats uint16
+//	2  SummFreq uint16, or Symbol and Freq of the only state
+//	4  Stats    uint32, or Successor of the only state
+//	8  Suffix   uint32
+//
+// States are 6 bytes:
+//
+//	0  Symbol    byte
+//	1  Freq      byte
+//	2  Successor uint32
+//
+// Free blocks are linked by a reference in their first 4 bytes and, while
+// they are being glued together, are 12 bytes:
+//
+//	0  Stamp uint16, zero if the block is free
+//	2  NU    uint16, the number of units in the block
+//	4  Next  uint32
+//	8  Prev  uint32
+
+const (
+	minOrder   = 2
+	maxOrder   = 64
+	minMemSize = 1 << 11
+	maxMemSize = 0xffffffff - 12*3
+
+	unitSize  = 12
+	stateSize = 6
+
+	maxFreq    = 124
+	intBits    = 7
+	periodBits = 7
+	binScale   = 1 << (intBits + periodBits)
+	numIndexes = 4 + 4 + 4 + 26
+)
+
+//nolint:gochecknoglobals
+var (
+	expEscape  = [16]byte{25, 14, 9, 7, 5, 5, 4, 4, 4, 3, 3, 3, 2, 2, 2, 2}
+	initBinEsc = [8]uint16{0x3cdd, 0x1f3f, 0x59bf, 0x48f3, 0x64a1, 0x5abc, 0x6632, 0x6051}
+
+	indx2Units, units2Indx, ns2Indx, ns2BSIndx, hb2Flag = tables()
+)
+
+func tables() (i2u [numIndexes]byte, u2i [128]byte, ns2i, ns2bs, hb2f [256]byte) {
+	k := 0
+
+	for i := range numIndexes {
+		step := 4
+		if i < 12 {
+			step = i>>2 + 1
+		}
+
+		for ; step > 0; step-- {
+			u2i[k] = byte(i)
+			k++
+		}
+
+		i2u[i] = byte(k)
+	}
+
+	ns2bs[0] = 0 << 1
+	ns2bs[1] = 1 << 1
+
+	for i := 2; i < 11; i++ {
+		ns2bs[i] = 2 << 1
+	}
+
+	for i := 11; i < 256; i++ {
+		ns2bs[i] = 3 << 1
+	}
+
+	for i := range 3 {
+		ns2i[i] = byte(i)
+	}
+
+	for i, m, step := 3, 3, 1; i < 256; i++ {
+		ns2i[i] = byte(m)
+
+		if step--; step == 0 {
+			m++
+			step = m - 2
+		}
+	}
+
+	for i := 0x40; i < 0x100; i++ {
+		hb2f[i] = 8
+	}
+
+	return i2u, u2i, ns2i, ns2bs, hb2f
+}
+
+// A see is a secondary escape estimation context.
+type see struct {
+	summ  uint16
+	shift byte
+	count byte
+}
+
+func (s *see) update() {
+	if s.shift < periodBits {
+		if s.count--; s.count == 0 {
+			s.summ <<= 1
+			s.count = byte(3 << s.shift)
+			s.shift++
+		}
+	}
+}
+
+type model struct {
+	mem         []byte
+	size        uint32
+	alignOffset uint32
+	glueCount   uint32
+
+	text, loUnit, hiUnit, unitsStart uint32
+
+	freeList [numIndexes]uint32
+
+	minContext, maxContext uint32
+	foundState             uint32
+
+	orderFall, initEsc, prevSuccess, maxOrder, hiBitsFlag uint32
+	runLength, initRL                                     int32
+
+	dummySee see
+	see      [25][16]see
+	binSumm  [128][64]uint16
+}
+
+func newModel(order int, size uint32) *model {
+	m := &model{
+		size:        size,
+		alignOffset: 4 - size&3,
+		maxOrder:    uint32(order), //nolint:gosec
+	}
+
+	// The extra unit at the end is the head of the list of free blocks
+	// while they are glued together
+	m.mem = make([]byte, m.alignOffset+size+unitSize)
+
+	m.restart()
+
+	m.dummySee = see{shift: periodBits, count: 64}
+
+	return m
+}
+
+func b2u(b bool) uint32 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+func (m *model) u16(o uint32) uint16 { return binary.LittleEndian.Uint16(m.mem[o:]) }
+
+func (m *model) u32(o uint32) uint32 { return binary.LittleEndian.Uint32(m.mem[o:]) }
+
+func (m *model) setU16(o uint32, v uint16) { binary.LittleEndian.PutUint16(m.mem[o:], v) }
+
+func (m *model) setU32(o uint32, v uint32) { binary.LittleEndian.PutUint32(m.mem[o:], v) }
+
+func (m *model) numStats(c uint32) uint32 { return uint32(m.u16(c)) }
+
+func (m *model) setNumStats(c, v uint32) { m.setU16(c, uint16(v)) } //nolint:gosec
+
+func (m *model) summFreq(c uint32) uint32 { return uint32(m.u16(c + 2)) }
+
+func (m *model) setSummFreq(c, v uint32) { m.setU16(c+2, uint16(v)) } //nolint:gosec
+
+func (m *model) stats(c uint32) uint32 { return m.u32(c + 4) }
+
+func (m *model) setStats(c, v uint32) { m.setU32(c+4, v) }
+
+func (m *model) suffix(c uint32) uint32 { return m.u32(c + 8) }
+
+func (m *model) setSuffix(c, v uint32) { m.setU32(c+8, v) }
+
+func oneState(c uint32) uint32 { return c + 2 }
+
+func (m *model) symbol(s uint32) byte { return m.mem[s] }
+
+func (m *model) freq(s uint32) uint32 { return uint32(m.mem[s+1]) }
+
+func (m *model) setFreq(s, v uint32) { m.mem[s+1] = byte(v) }
+
+func (m *model) successor(s uint32) uint32 { return m.u32(s + 2) }
+
+func (m *model) setSuccessor(s, v uint32) { m.setU32(s+2, v) }
+
+func (m *model) copyState(dst, src uint32) {
+	copy(m.mem[dst:dst+stateSize], m.mem[src:src+stateSize])
+}
+
+func (m *model) swapStates(s1, s2 uint32) {
+	var tmp [stateSize]byte
+
+	copy(tmp[:], m.mem[s1:s1+stateSize])
+	m.copyState(s1, s2)
+	copy(m.mem[s2:s2+stateSize], tmp[:])
+}
+
+func (m *model) insertNode(node, indx uint32) {
+	m.setU32(node, m.freeList[indx])
+	m.freeList[indx] = node
+}
+
+func (m *model) removeNode(indx uint32) uint32 {
+	node := m.freeList[indx]
+	m.freeList[indx] = m.u32(node)
+
+	return node
+}
+
+func (m *model) splitBlock(ptr, oldIndx, newIndx uint32) {
+	nu := uint32(indx2Units[oldIndx]) - uint32(indx2Units[newIndx])
+	ptr += uint32(indx2Units[newIndx]) * unitSize
+
+	i := uint32(units2Indx[nu-1])
+	if uint32(indx2Units[i]) != nu {
+		i--
+		k := uint32(indx2Units[i])
+		m.insertNode(ptr+k*unitSize, nu-k-1)
+	}
+
+	m.insertNode(ptr, i)
+}
+
+//nolint:cyclop
+func (m *model) glueFreeBlocks() {
+	head := m.alignOffset + m.size
+	n := head
+
+	m.glueCount = 255
+
+	// Create a doubly-linked list of the free blocks
+	for i := range uint32(numIndexes) {
+		nu := uint16(indx2Units[i])
+		next := m.freeList[i]
+		m.freeList[i] = 0
+
+		for next != 0 {
+			node := next
+			m.setU32(node+4, n)
+			m.setU32(n+8, next)
+			n = next
+			next = m.u32(node)
+			m.setU16(node, 0)
+			m.setU16(node+2, nu)
+		}
+	}
+
+	m.setU16(head, 1)
+	m.setU32(head+4, n)
+	m.setU32(n+8, head)
+
+	if m.loUnit != m.hiUnit {
+		m.setU16(m.loUnit, 1)
+	}
+
+	// Glue together adjacent free blocks
+	for n != head {
+		node := n
+		nu := uint32(m.u16(node + 2))
+
+		for {
+			node2 := node + nu*unitSize
+			nu += uint32(m.u16(node2 + 2))
+
+			if m.u16(node2) != 0 || nu >= 0x10000 {
+				break
+			}
+
+			prev, next := m.u32(node2+8), m.u32(node2+4)
+			m.setU32(prev+4, next)
+			m.setU32(next+8, prev)
+			m.setU16(node+2, uint16(nu))
+		}
+
+		n = m.u32(node + 4)
+	}
+
+	// Fill the lists of free blocks again
+	for n = m.u32(head + 4); n != head; {
+		node := n
+		next := m.u32(node + 4)
+		nu := uint32(m.u16(node + 2))
+
+		for ; nu > 128; nu, node = nu-128, node+128*unitSize {
+			m.insertNode(node, numIndexes-1)
+		}
+
+		i := uint32(units2Indx[nu-1])
+		if uint32(indx2Units[i]) != nu {
+			i--
+			k := uint32(indx2Units[i])
+			m.insertNode(node+k*unitSize, nu-k-1)
+		}
+
+		m.insertNode(node, i)
+		n = next
+	}
+}
+
+// allocUnitsRare returns zero if there is no memory left.
+func (m *model) allocUnitsRare(indx uint32) uint32 {
+	if m.glueCount == 0 {
+		m.glueFreeBlocks()
+
+		if m.freeList[indx] != 0 {
+			return m.removeNode(indx)
+		}
+	}
+
+	i := indx
+
+	for {
+		if i++; i == numIndexes {
+			numBytes := uint32(indx2Units[indx]) * unitSize
+			m.glueCount--
+
+			if m.unitsStart-m.text > numBytes {
+				m.unitsStart -= numBytes
+
+				return m.unitsStart
+			}
+
+			return 0
+		}
+
+		if m.freeList[i] != 0 {
+			break
+		}
+	}
+
+	ptr := m.removeNode(i)
+	m.splitBlock(ptr, i, indx)
+
+	return ptr
+}
+
+func (m *model) allocUnits(indx uint32) uint32 {
+	if m.freeList[indx] != 0 {
+		return m.removeNode(indx)
+	}
+
+	numBytes := uint32(indx2Units[indx]) * unitSize
+	if numBytes <= m.hiUnit-m.loUnit {
+		ptr := m.loUnit
+		m.loUnit += numBytes
+
+		return ptr
+	}
+
+	return m.allocUnitsRare(indx)
+}
+
+func (m *model) shrinkUnits(oldPtr, oldNU, newNU uint32) uint32 {
+	i0, i1 := uint32(units2Indx[oldNU-1]), uint32(units2Indx[newNU-1])
+	if i0 == i1 {
+		return oldPtr
+	}
+
+	if m.freeList[i1] != 0 {
+		ptr := m.removeNode(i1)
+		copy(m.mem[ptr:ptr+newNU*unitSize], m.mem[oldPtr:oldPtr+newNU*unitSize])
+		m.insertNode(oldPtr, i0)
+
+		return ptr
+	}
+
+	m.splitBlock(oldPtr, i0, i1)
+
+	return oldPtr
+}
+
+func (m *model) restart() {
+	m.freeList = [numIndexes]uint32{}
+
+	m.text = m.alignOffset
+	m.hiUnit = m.text + m.size
+	m.loUnit = m.hiUnit - m.size/8/unitSize*7*unitSize
+	m.unitsStart = m.loUnit
+	m.glueCount = 0
+
+	m.orderFall = m.maxOrder
+	m.initRL = -int32(min(m.maxOrder, 12)) - 1 //nolint:gosec
+	m.runLength = m.initRL
+	m.prevSuccess = 0
+
+	m.hiUnit -= unitSize
+	m.minContext, m.maxContext = m.hiUnit, m.hiUnit
+	m.setSuffix(m.minContext, 0)
+	m.setNumStats(m.minContext, 256)
+	m.setSummFreq(m.minContext, 256+1)
+
+	m.foundState = m.loUnit
+	m.setStats(m.minContext, m.loUnit)
+
+	for i := range uint32(256) {
+		s := m.loUnit + i*stateSize
+		m.mem[s] = byte(i)
+		m.setFreq(s, 1)
+		m.setSuccessor(s, 0)
+	}
+
+	m.loUnit += 256 / 2 * unitSize
+
+	for i := range m.binSumm {
+		for k, esc := range initBinEsc {
+			val := uint16(binScale - uint32(esc)/uint32(i+2)) //nolint:gosec
+			for j := 0; j < 64; j += 8 {
+				m.binSumm[i][k+j] = val
+			}
+		}
+	}
+
+	for i := range m.see {
+		for k := range m.see[i] {
+			m.see[i][k] = see{
+				summ:  uint16((5*i + 10) << (periodBits - 4)), //nolint:gosec
+				shift: periodBits - 4,
+				count: 4,
+			}
+		}
+	}
+}
+
+// createSuccessors returns zero if there is no memory left.
+//
+//nolint:cyclop
+func (m *model) createSuccessors(skip bool) uint32 {
+	c := m.minContext
+	upBranch := m.successor(m.foundState)
+	sym := m.symbol(m.foundState)
+
+	var (
+		ps    [maxOrder]uint32
+		numPs int
+	)
+
+	if !skip {
+		ps[numPs] = m.foundState
+		numPs++
+	}
+
+	for m.suffix(c) != 0 {
+		c = m.suffix(c)
+
+		s := oneState(c)
+		if m.numStats(c) != 1 {
+			for s = m.stats(c); m.symbol(s) != sym; s += stateSize {
+			}
+		}
+
+		if successor := m.successor(s); successor != upBranch {
+			c = successor
+			if numPs == 0 {
+				return c
+			}
+
+			break
+		}
+
+		ps[numPs] = s
+		numPs++
+	}
+
+	upSymbol := m.mem[upBranch]
+	upSuccessor := upBranch + 1
+
+	var upFreq uint32
+
+	if m.numStats(c) == 1 {
+		upFreq = m.freq(oneState(c))
+	} else {
+		s := m.stats(c)
+		for m.symbol(s) != upSymbol {
+			s += stateSize
+		}
+
+		cf := m.freq(s) - 1
+		s0 := m.summFreq(c) - m.numStats(c) - cf
+
+		if 2*cf <= s0 {
+			upFreq = 1 + b2u(5*cf > s0)
+		} else {
+			upFreq = 1 + (2*cf+3*s0-1)/(2*s0)
+		}
+	}
+
+	for {
+		var c1 uint32
+
+		switch {
+		case m.hiUnit != m.loUnit:
+			m.hiUnit -= unitSize
+			c1 = m.hiUnit
+		case m.freeList[0] != 0:
+			c1 = m.removeNode(0)
+		default:
+			if c1 = m.allocUnitsRare(0); c1 == 0 {
+				return 0
+			}
+		}
+
+		m.setNumStats(c1, 1)
+		m.mem[oneState(c1)] = upSymbol
+		m.setFreq(oneState(c1), upFreq)
+		m.setSuccessor(oneState(c1), upSuccessor)
+		m.setSuffix(c1, c)
+
+		numPs--
+		m.setSuccessor(ps[numPs], c1)
+		c = c1
+
+		if numPs == 0 {
+			break
+		}
+	}
+
+	return c
+}
+
+//nolint:cyclop,funlen,gocognit
+func (m *model) updateModel() {
+	fs := m.foundState
+	sym := m.symbol(fs)
+	fSuccessor := m.successor(fs)
+
+	if m.freq(fs) < maxFreq/4 && m.suffix(m.minContext) != 0 {
+		c := m.suffix(m.minContext)
+
+		if m.numStats(c) == 1 {
+			if s := oneState(c); m.freq(s) < 32 {
+				m.setFreq(s, m.freq(s)+1)
+			}
+		} else {
+			s := m.stats(c)
+			if m.symbol(s) != sym {
+				for {
+					s += stateSize
+					if m.symbol(s) == sym {
+						break
+					}
+				}
+
+				if m.freq(s) >= m.freq(s-stateSize) {
+					m.swapStates(s, s-stateSize)
+					s -= stateSize
+				}
+			}
+
+			if m.freq(s) < maxFreq-9 {
+				m.setFreq(s, m.freq(s)+2)
+				m.setSummFreq(c, m.summFreq(c)+2)
+			}
+		}
+	}
+
+	if m.orderFall == 0 {
+		m.minContext = m.createSuccessors(true)
+		m.maxContext = m.minContext
+
+		if m.minContext == 0 {
+			m.restart()
+
+			return
+		}
+
+		m.setSuccessor(m.foundState, m.minContext)
+
+		return
+	}
+
+	m.mem[m.text] = sym
+	m.text++
+	successor := m.text
+
+	if m.text >= m.unitsStart {
+		m.restart()
+
+		return
+	}
+
+	if fSuccessor != 0 {
+		// Successors pointing into the text haven't been created yet
+		if fSuccessor <= successor {
+			cs := m.createSuccessors(false)
+			if cs == 0 {
+				m.restart()
+
+				return
+			}
+
+			fSuccessor = cs
+		}
+
+		if m.orderFall--; m.orderFall == 0 {
+			successor = fSuccessor
+
+			if m.maxContext != m.minContext {
+				m.text--
+			}
+		}
+	} else {
+		m.setSuccessor(fs, successor)
+		fSuccessor = m.minContext
+	}
+
+	ns := m.numStats(m.minContext)
+	s0 := m.summFreq(m.minContext) - ns - (m.freq(fs) - 1)
+
+	for c := m.maxContext; c != m.minContext; c = m.suffix(c) {
+		ns1 := m.numStats(c)
+
+		if ns1 != 1 {
+			if ns1&1 == 0 {
+				// Grow the stats by one unit
+				oldNU := ns1 >> 1
+
+				if i := uint32(units2Indx[oldNU-1]); i != uint32(units2Indx[oldNU]) {
+					ptr := m.allocUnits(i + 1)
+					if ptr == 0 {
+						m.restart()
+
+						return
+					}
+
+					oldPtr := m.stats(c)
+					copy(m.mem[ptr:ptr+oldNU*unitSize], m.mem[oldPtr:oldPtr+oldNU*unitSize])
+					m.insertNode(oldPtr, i)
+					m.setStats(c, ptr)
+				}
+			}
+
+			sf := m.summFreq(c)
+			m.setSummFreq(c, sf+b2u(2*ns1 < ns)+2*(b2u(4*ns1 <= ns)&b2u(sf <= 8*ns1)))
+		} else {
+			s := m.allocUnits(0)
+			if s == 0 {
+				m.restart()
+
+				return
+			}
+
+			m.copyState(s, oneState(c))
+			m.setStats(c, s)
+
+			if freq := m.freq(s); freq < maxFreq/4-1 {
+				m.setFreq(s, freq<<1)
+			} else {
+				m.setFreq(s, maxFreq-4)
+			}
+
+			m.setSummFreq(c, m.freq(s)+m.initEsc+b2u(ns > 3))
+		}
+
+		cf := 2 * m.freq(fs) * (m.summFreq(c) + 6)
+		sf := s0 + m.summFreq(c)
+
+		if cf < 6*sf {
+			cf = 1 + b2u(cf > sf) + b2u(cf >= 4*sf)
+			m.setSummFreq(c, m.summFreq(c)+3)
+		} else {
+			cf = 4 + b2u(cf >= 9*sf) + b2u(cf >= 12*sf) + b2u(cf >= 15*sf)
+			m.setSummFreq(c, m.summFreq(c)+cf)
+		}
+
+		s := m.stats(c) + ns1*stateSize
+		m.setSuccessor(s, successor)
+		m.mem[s] = sym
+		m.setFreq(s, cf)
+		m.setNumStats(c, ns1+1)
+	}
+
+	m.minContext, m.maxContext = fSuccessor, fSuccessor
+}
+
+//nolint:cyclop
+func (m *model) rescale() {
+	mc := m.minContext
+	stats := m.stats(mc)
+	s := m.foundState
+
+	var tmp [stateSize]byte
+
+	// Move the found state to the front
+	copy(tmp[:], m.mem[s:s+stateSize])
+
+	for ; s != stats; s -= stateSize {
+		m.copyState(s, s-stateSize)
+	}
+
+	copy(m.mem[s:s+stateSize], tmp[:])
+
+	escFreq := m.summFreq(mc) - m.freq(s)
+	m.setFreq(s, m.freq(s)+4)
+	adder := b2u(m.orderFall != 0)
+	m.setFreq(s, (m.freq(s)+adder)>>1)
+	sumFreq := m.freq(s)
+
+	i := m.numStats(mc) - 1
+
+	for {
+		s += stateSize
+		escFreq -= m.freq(s)
+		m.setFreq(s, (m.freq(s)+adder)>>1)
+		sumFreq += m.freq(s)
+
+		if m.freq(s) > m.freq(s-stateSize) {
+			s1 := s
+			copy(tmp[:], m.mem[s1:s1+stateSize])
+
+			for {
+				m.copyState(s1, s1-stateSize)
+				s1 -= stateSize
+
+				if s1 == stats || uint32(tmp[1]) <= m.freq(s1-stateSize) {
+					break
+				}
+			}
+
+			copy(m.mem[s1:s1+stateSize], tmp[:])
+		}
+
+		if i--; i == 0 {
+			break
+		}
+	}
+
+	if m.freq(s) == 0 {
+		numStats := m.numStats(mc)
+
+		for {
+			i++
+			s -= stateSize
+
+			if m.freq(s) != 0 {
+				break
+			}
+		}
+
+		escFreq += i
+		m.setNumStats(mc, numStats-i)
+
+		if numStats-i == 1 {
+			copy(tmp[:], m.mem[stats:stats+stateSize])
+
+			for {
+				tmp[1] -= tmp[1] >> 1
+
+				if escFreq >>= 1; escFreq <= 1 {
+					break
+				}
+			}
+
+			m.insertNode(stats, uint32(units2Indx[((numStats+1)>>1)-1]))
+			m.foundState = oneState(mc)
+			copy(m.mem[m.foundState:m.foundState+stateSize], tmp[:])
+
+			return
+		}
+
+		if n0, n1 := (numStats+1)>>1, (numStats-i+1)>>1; n0 != n1 {
+			m.setStats(mc, m.shrinkUnits(stats, n0, n1))
+		}
+	}
+
+	m.setSummFreq(mc, sumFreq+escFreq-escFreq>>1)
+	m.foundState = m.stats(mc)
+}
+
+func (m *model) makeEscFreq(numMasked uint32) (*see, uint32) {
+	mc := m.minContext
+	ns := m.numStats(mc)
+
+	if ns == 256 {
+		return &m.dummySee, 1
+	}
+
+	nonMasked := ns - numMasked
+	s := &m.see[ns2Indx[nonMasked-1]][b2u(nonMasked < m.numStats(m.suffix(mc))-ns)+
+		2*b2u(m.summFreq(mc) < 11*ns)+
+		4*b2u(numMasked > nonMasked)+
+		m.hiBitsFlag]
+
+	r := uint32(s.summ >> s.shift)
+	s.summ -= uint16(r) //nolint:gosec
+
+	return s, r + b2u(r == 0)
+}
+
+// binSummFor returns the probability of the only symbol in the current
+// binary context.
+func (m *model) binSummFor() *uint16 {
+	s := oneState(m.minContext)
+	m.hiBitsFlag = uint32(hb2Flag[m.symbol(m.foundState)])
+
+	return &m.binSumm[m.freq(s)-1][m.prevSuccess+
+		uint32(ns2BSIndx[m.numStats(m.suffix(m.minContext))-1])+
+		m.hiBitsFlag+
+		2*uint32(hb2Flag[m.symbol(s)])+
+		uint32((m.runLength>>26)&0x20)] //nolint:gosec
+}
+
+func getMean(prob uint16) uint16 {
+	return (prob + 1<<(periodBits-2)) >> periodBits
+}
+
+func (m *model) nextContext() {
+	if c := m.successor(m.foundState); m.orderFall == 0 && c > m.text {
+		m.minContext, m.maxContext = c, c
+	} else {
+		m.updateModel()
+	}
+}
+
+// update1 is called after a symbol other than the first is found in a
+// context with more than one symbol.
+func (m *model) update1() {
+	s := m.foundState
+	m.setFreq(s, m.freq(s)+4)
+	m.setSummFreq(m.minContext, m.summFreq(m.minContext)+4)
+
+	if m.freq(s) > m.freq(s-stateSize) {
+		m.swapStates(s, s-stateSize)
+		s -= stateSize
+		m.foundState = s
+
+		if m.freq(s) > maxFreq {
+			m.rescale()
+		}
+	}
+
+	m.nextContext()
+}
+
+// update1First is called after the first symbol is found in a context with
+// more than one symbol.
+func (m *model) update1First() {
+	s := m.foundState
+	m.prevSuccess = b2u(2*m.freq(s) > m.summFreq(m.minContext))
+	m.runLength += int32(m.prevSuccess) //nolint:gosec
+	m.setSummFreq(m.minContext, m.summFreq(m.minContext)+4)
+	m.setFreq(s, m.freq(s)+4)
+
+	if m.freq(s) > maxFreq {
+		m.rescale()
+	}
+
+	m.nextContext()
+}
+
+// updateBin is called after the symbol is found in a binary context.
+func (m *model) updateBin() {
+	s := m.foundState
+	if m.freq(s) < 128 {
+		m.setFreq(s, m.freq(s)+1)
+	}
+
+	m.prevSuccess = 1
+	m.runLength++
+	m.nextContext()
+}
+
+// update2 is called after a symbol is found following an escape.
+func (m *model) update2() {
+	s := m.foundState
+	m.setFreq(s, m.freq(s)+4)
+	m.setSummFreq(m.minContext, m.summFreq(m.minContext)+4)
+
+	if m.freq(s) > maxFreq {
+		m.rescale()
+	}
+
+	m.runLength = m.initRL
+	m.updateModel()
+}
@@ -0,0 +1,206 @@
+package ppmd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fox returns the lines the quick brown fox fixture was made from.
+func fox(lines int) []byte {
+	b := new(bytes.Buffer)
+
+	for i := range lines {
+		fmt.Fprintf(b, "%d The quick brown fox jumps over the lazy dog.\n", i)
+	}
+
+	return b.Bytes()
+}
+
+// mixed returns size bytes of text interleaved with runs of random bytes, so
+// the model keeps creating contexts and small memory sizes run out.
+func mixed(size int) []byte {
+	rnd := rand.New(rand.NewSource(1)) //nolint:gosec
+	b := new(bytes.Buffer)
+
+	for b.Len() < size {
+		b.Write(fox(1 + rnd.Intn(20)))
+
+		r := make([]byte, rnd.Intn(512))
+		_, _ = rnd.Read(r)
+		b.Write(r)
+	}
+
+	return b.Bytes()[:size]
+}
+
+func compress(t testing.TB, b []byte, order int, memSize uint32) ([]byte, []byte) {
+	t.Helper()
+
+	buf := new(bytes.Buffer)
+
+	wc, props, err := NewWriter(buf, order, memSize)
+	require.NoError(t, err)
+
+	_, err = wc.Write(b)
+	require.NoError(t, err)
+	require.NoError(t, wc.Close())
+
+	return buf.Bytes(), props
+}
+
+func decompress(t testing.TB, b, props []byte, size int) []byte {
+	t.Helper()
+
+	rc, err := NewReader(props, uint64(size), []io.ReadCloser{io.NopCloser(bytes.NewReader(b))}) //nolint:gosec
+	require.NoError(t, err)
+
+	out, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+
+	return out
+}
+
+func TestRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	random := make([]byte, 64<<10)
+	_, _ = rand.New(rand.NewSource(2)).Read(random) //nolint:gosec
+
+	inputs := map[string][]byte{
+		"empty":  nil,
+		"byte":   {'a'},
+		"zeros":  make([]byte, 64<<10),
+		"text":   fox(2000),
+		"mixed":  mixed(256 << 10),
+		"random": random,
+	}
+
+	for _, order := range []int{minOrder, 6, 16, maxOrder} {
+		for _, memSize := range []uint32{minMemSize, 64 << 10, 16 << 20} {
+			for name, in := range inputs {
+				t.Run(strconv.Itoa(order)+"/"+strconv.Itoa(int(memSize))+"/"+name, func(t *testing.T) {
+					t.Parallel()
+
+					b, props := compress(t, in, order, memSize)
+					assert.Equal(t, byte(order), props[0])
+					assert.Equal(t, memSize, binary.LittleEndian.Uint32(props[1:]))
+
+					out := decompress(t, b, props, len(in))
+					assert.True(t, bytes.Equal(in, out))
+				})
+			}
+		}
+	}
+}
+
+// TestRestart checks the model restarts once it runs out of memory, on both
+// sides, and that the stream still decodes.
+func TestRestart(t *testing.T) {
+	t.Parallel()
+
+	in := mixed(256 << 10)
+	buf := new(bytes.Buffer)
+
+	wc, props, err := NewWriter(buf, 16, minMemSize)
+	require.NoError(t, err)
+
+	m := wc.(*writeCloser).m //nolint:forcetypeassert
+	restarts := 0
+
+	// The text area only moves backwards when the model restarts
+	for _, c := range in {
+		text := m.text
+
+		_, err = wc.Write([]byte{c})
+		require.NoError(t, err)
+
+		if m.text < text {
+			restarts++
+		}
+	}
+
+	require.NoError(t, wc.Close())
+	assert.Positive(t, restarts)
+
+	assert.True(t, bytes.Equal(in, decompress(t, buf.Bytes(), props, len(in))))
+}
+
+// TestGolden compares the encoder with testdata/fox.ppmd, the packed stream
+// of an archive of fox(500) made by libarchive 3.7.7 with
+// `bsdtar -cf fox.7z --format 7zip --options 7zip:compression=ppmd`.
+func TestGolden(t *testing.T) {
+	t.Parallel()
+
+	want, err := os.ReadFile(filepath.Join("testdata", "fox.ppmd"))
+	require.NoError(t, err)
+
+	in := fox(500)
+
+	// libarchive uses the same order and memory size as 7-Zip
+	b, props := compress(t, in, 6, 16<<20)
+	assert.Equal(t, want, b)
+
+	assert.True(t, bytes.Equal(in, decompress(t, want, props, len(in))))
+}
+
+func TestErrors(t *testing.T) {
+	t.Parallel()
+
+	_, _, err := NewWriter(io.Discard, minOrder-1, minMemSize)
+	require.ErrorIs(t, err, errInvalidProperties)
+
+	_, _, err = NewWriter(io.Discard, minOrder, minMemSize-1)
+	require.ErrorIs(t, err, errInvalidProperties)
+
+	r := []io.ReadCloser{io.NopCloser(bytes.NewReader(nil))}
+
+	_, err = NewReader([]byte{6, 0, 0, 0}, 0, r)
+	require.ErrorIs(t, err, errInsufficientProperties)
+
+	_, err = NewReader([]byte{maxOrder + 1, 0, 0, 0, 1}, 0, r)
+	require.ErrorIs(t, err, errInvalidProperties)
+
+	_, err = NewReader([]byte{6, 0, 0, 0, 1}, 0, nil)
+	require.ErrorIs(t, err, errNeedOneReader)
+
+	wc, _, err := NewWriter(io.Discard, 6, minMemSize)
+	require.NoError(t, err)
+	require.NoError(t, wc.Close())
+	require.ErrorIs(t, wc.Close(), errAlreadyClosed)
+
+	_, err = wc.Write([]byte{0})
+	require.ErrorIs(t, err, errAlreadyClosed)
+}
+
+func BenchmarkWriter(b *testing.B) {
+	in := fox(20000)
+
+	b.SetBytes(int64(len(in)))
+
+	for range b.N {
+		compress(b, in, 6, 16<<20)
+	}
+}
+
+func BenchmarkReader(b *testing.B) {
+	in := fox(20000)
+	packed, props := compress(b, in, 6, 16<<20)
+
+	b.SetBytes(int64(len(in)))
+	b.ResetTimer()
+
+	for range b.N {
+		decompress(b, packed, props, len(in))
+	}
+}
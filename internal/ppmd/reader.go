@@ -0,0 +1,320 @@
+// Package ppmd implements the PPMd variant H compressor and decompressor,
+// with the range coder used by 7-Zip.
+package ppmd
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+type readCloser struct {
+	c io.Closer
+	d *decoder
+}
+
+var (
+	errAlreadyClosed          = errors.New("ppmd: already closed")
+	errNeedOneReader          = errors.New("ppmd: need exactly one reader")
+	errInsufficientProperties = errors.New("ppmd: not enough properties")
+	errInvalidProperties      = errors.New("ppmd: invalid order or memory size")
+	errCorrupt                = errors.New("ppmd: corrupt stream")
+)
+
+const topValue = 1 << 24
+
+type rangeDecoder struct {
+	br   io.ByteReader
+	rng  uint32
+	code uint32
+	err  error
+}
+
+func (rd *rangeDecoder) readByte() uint32 {
+	b, err := rd.br.ReadByte()
+	if err != nil && rd.err == nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+
+		rd.err = err
+	}
+
+	return uint32(b)
+}
+
+func (rd *rangeDecoder) init() error {
+	rd.rng = 0xffffffff
+
+	if rd.readByte() != 0 {
+		return errCorrupt
+	}
+
+	for range 4 {
+		rd.code = rd.code<<8 | rd.readByte()
+	}
+
+	if rd.err != nil {
+		return rd.err
+	}
+
+	if rd.code == 0xffffffff {
+		return errCorrupt
+	}
+
+	return nil
+}
+
+func (rd *rangeDecoder) threshold(total uint32) uint32 {
+	if rd.rng /= total; rd.rng == 0 {
+		// Only possible with a corrupt stream, which then fails when
+		// the count is found to be out of range
+		return total
+	}
+
+	return rd.code / rd.rng
+}
+
+// normalize shifts in at most two bytes, the same as 7-Zip.
+func (rd *rangeDecoder) normalize() {
+	for i := 0; i < 2 && rd.rng < topValue; i++ {
+		rd.code = rd.code<<8 | rd.readByte()
+		rd.rng <<= 8
+	}
+}
+
+func (rd *rangeDecoder) decode(start, size uint32) {
+	rd.code -= start * rd.rng
+	rd.rng *= size
+	rd.normalize()
+}
+
+func (rd *rangeDecoder) decodeBit(size0, total uint32) uint32 {
+	bound := (rd.rng / total) * size0
+
+	var bit uint32
+
+	if rd.code < bound {
+		rd.rng = bound
+	} else {
+		bit = 1
+		rd.code -= bound
+		rd.rng -= bound
+	}
+
+	rd.normalize()
+
+	return bit
+}
+
+type decoder struct {
+	m  *model
+	rd rangeDecoder
+	n  uint64
+}
+
+// decodeSymbol returns -1 for the end marker and -2 if the stream is
+// corrupt.
+//
+//nolint:cyclop,funlen
+func (m *model) decodeSymbol(rd *rangeDecoder) int {
+	var charMask [256]bool
+
+	if ns := m.numStats(m.minContext); ns != 1 {
+		s := m.stats(m.minContext)
+		summ := m.summFreq(m.minContext)
+		count := rd.threshold(summ)
+
+		hiCnt := m.freq(s)
+		if count < hiCnt {
+			rd.decode(0, hiCnt)
+			m.foundState = s
+			symbol := m.symbol(s)
+			m.update1First()
+
+			return int(symbol)
+		}
+
+		m.prevSuccess = 0
+
+		for i := ns - 1; i > 0; i-- {
+			s += stateSize
+
+			if hiCnt += m.freq(s); hiCnt > count {
+				rd.decode(hiCnt-m.freq(s), m.freq(s))
+				m.foundState = s
+				symbol := m.symbol(s)
+				m.update1()
+
+				return int(symbol)
+			}
+		}
+
+		if count >= summ {
+			return -2
+		}
+
+		m.hiBitsFlag = uint32(hb2Flag[m.symbol(m.foundState)])
+		rd.decode(hiCnt, summ-hiCnt)
+
+		for i, s := uint32(0), m.stats(m.minContext); i < ns; i, s = i+1, s+stateSize {
+			charMask[m.symbol(s)] = true
+		}
+	} else {
+		prob := m.binSummFor()
+
+		if rd.decodeBit(uint32(*prob), binScale) == 0 {
+			*prob = *prob + 1<<intBits - getMean(*prob)
+			m.foundState = oneState(m.minContext)
+			symbol := m.symbol(m.foundState)
+			m.updateBin()
+
+			return int(symbol)
+		}
+
+		*prob -= getMean(*prob)
+		m.initEsc = uint32(expEscape[*prob>>10])
+		charMask[m.symbol(oneState(m.minContext))] = true
+		m.prevSuccess = 0
+	}
+
+	var ps [256]uint32
+
+	for {
+		numMasked := m.numStats(m.minContext)
+
+		for {
+			m.orderFall++
+
+			if m.suffix(m.minContext) == 0 {
+				return -1
+			}
+
+			if m.minContext = m.suffix(m.minContext); m.numStats(m.minContext) != numMasked {
+				break
+			}
+		}
+
+		hiCnt := uint32(0)
+		num := m.numStats(m.minContext) - numMasked
+
+		for i, s := uint32(0), m.stats(m.minContext); i != num; s += stateSize {
+			if !charMask[m.symbol(s)] {
+				hiCnt += m.freq(s)
+				ps[i] = s
+				i++
+			}
+		}
+
+		see, freqSum := m.makeEscFreq(numMasked)
+		freqSum += hiCnt
+		count := rd.threshold(freqSum)
+
+		if count < hiCnt {
+			k := 0
+
+			for hiCnt = m.freq(ps[k]); hiCnt <= count; hiCnt += m.freq(ps[k]) {
+				k++
+			}
+
+			s := ps[k]
+			rd.decode(hiCnt-m.freq(s), m.freq(s))
+			see.update()
+			m.foundState = s
+			symbol := m.symbol(s)
+			m.update2()
+
+			return int(symbol)
+		}
+
+		if count >= freqSum {
+			return -2
+		}
+
+		rd.decode(hiCnt, freqSum-hiCnt)
+		see.summ += uint16(freqSum) //nolint:gosec
+
+		for _, s := range ps[:num] {
+			charMask[m.symbol(s)] = true
+		}
+	}
+}
+
+func (rc *readCloser) Close() error {
+	if rc.c == nil || rc.d == nil {
+		return errAlreadyClosed
+	}
+
+	if err := rc.c.Close(); err != nil {
+		return fmt.Errorf("ppmd: error closing: %w", err)
+	}
+
+	rc.c, rc.d = nil, nil
+
+	return nil
+}
+
+func (rc *readCloser) Read(p []byte) (int, error) {
+	if rc.d == nil {
+		return 0, errAlreadyClosed
+	}
+
+	d := rc.d
+
+	if d.n == 0 {
+		return 0, io.EOF
+	}
+
+	n := 0
+
+	for ; n < len(p) && d.n > 0; n++ {
+		symbol := d.m.decodeSymbol(&d.rd)
+
+		if d.rd.err != nil {
+			return n, fmt.Errorf("ppmd: error reading: %w", d.rd.err)
+		}
+
+		if symbol < 0 {
+			return n, errCorrupt
+		}
+
+		p[n] = byte(symbol)
+		d.n--
+	}
+
+	return n, nil
+}
+
+// NewReader returns a new PPMd io.ReadCloser.
+func NewReader(p []byte, uncompressedSize uint64, readers []io.ReadCloser) (io.ReadCloser, error) {
+	if len(readers) != 1 {
+		return nil, errNeedOneReader
+	}
+
+	if len(p) != 5 {
+		return nil, errInsufficientProperties
+	}
+
+	order, size := int(p[0]), binary.LittleEndian.Uint32(p[1:])
+	if order < minOrder || order > maxOrder || size < minMemSize || size > maxMemSize {
+		return nil, errInvalidProperties
+	}
+
+	d := &decoder{
+		rd: rangeDecoder{br: bufio.NewReader(readers[0])},
+		n:  uncompressedSize,
+	}
+
+	if err := d.rd.init(); err != nil {
+		return nil, fmt.Errorf("ppmd: error reading: %w", err)
+	}
+
+	d.m = newModel(order, size)
+
+	return &readCloser{
+		c: readers[0],
+		d: d,
+	}, nil
+}
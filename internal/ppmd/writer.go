@@ -0,0 +1,254 @@
+package ppmd
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+type rangeEncoder struct {
+	bw        *bufio.Writer
+	low       uint64
+	rng       uint32
+	cache     byte
+	cacheSize uint64
+	err       error
+}
+
+func (re *rangeEncoder) writeByte(b byte) {
+	if err := re.bw.WriteByte(b); err != nil && re.err == nil {
+		re.err = err
+	}
+}
+
+func (re *rangeEncoder) shiftLow() {
+	if uint32(re.low) < 0xff000000 || re.low>>32 != 0 {
+		temp := re.cache
+
+		for {
+			re.writeByte(temp + byte(re.low>>32))
+			temp = 0xff
+
+			if re.cacheSize--; re.cacheSize == 0 {
+				break
+			}
+		}
+
+		re.cache = byte(uint32(re.low) >> 24)
+	}
+
+	re.cacheSize++
+	re.low = uint64(uint32(re.low) << 8)
+}
+
+func (re *rangeEncoder) normalize() {
+	for re.rng < topValue {
+		re.rng <<= 8
+		re.shiftLow()
+	}
+}
+
+func (re *rangeEncoder) encode(start, size, total uint32) {
+	re.rng /= total
+	re.low += uint64(start) * uint64(re.rng)
+	re.rng *= size
+	re.normalize()
+}
+
+func (re *rangeEncoder) encodeBit0(size0 uint32) {
+	re.rng = (re.rng >> 14) * size0
+	re.normalize()
+}
+
+func (re *rangeEncoder) encodeBit1(size0 uint32) {
+	bound := (re.rng >> 14) * size0
+	re.low += uint64(bound)
+	re.rng -= bound
+	re.normalize()
+}
+
+func (re *rangeEncoder) flush() {
+	for range 5 {
+		re.shiftLow()
+	}
+}
+
+//nolint:cyclop,funlen
+func (m *model) encodeSymbol(re *rangeEncoder, symbol byte) {
+	var charMask [256]bool
+
+	if ns := m.numStats(m.minContext); ns != 1 {
+		s := m.stats(m.minContext)
+		summ := m.summFreq(m.minContext)
+
+		if m.symbol(s) == symbol {
+			re.encode(0, m.freq(s), summ)
+			m.foundState = s
+			m.update1First()
+
+			return
+		}
+
+		m.prevSuccess = 0
+		sum := m.freq(s)
+
+		for i := ns - 1; i > 0; i-- {
+			s += stateSize
+
+			if m.symbol(s) == symbol {
+				re.encode(sum, m.freq(s), summ)
+				m.foundState = s
+				m.update1()
+
+				return
+			}
+
+			sum += m.freq(s)
+		}
+
+		m.hiBitsFlag = uint32(hb2Flag[m.symbol(m.foundState)])
+
+		for i, s := uint32(0), m.stats(m.minContext); i < ns; i, s = i+1, s+stateSize {
+			charMask[m.symbol(s)] = true
+		}
+
+		re.encode(sum, summ-sum, summ)
+	} else {
+		prob := m.binSummFor()
+		s := oneState(m.minContext)
+
+		if m.symbol(s) == symbol {
+			re.encodeBit0(uint32(*prob))
+			*prob = *prob + 1<<intBits - getMean(*prob)
+			m.foundState = s
+			m.updateBin()
+
+			return
+		}
+
+		re.encodeBit1(uint32(*prob))
+		*prob -= getMean(*prob)
+		m.initEsc = uint32(expEscape[*prob>>10])
+		charMask[m.symbol(s)] = true
+		m.prevSuccess = 0
+	}
+
+	for {
+		numMasked := m.numStats(m.minContext)
+
+		for {
+			m.orderFall++
+
+			// Every symbol is in the order -1 context so this is
+			// only reached when encoding the end marker, which is
+			// never written
+			if m.suffix(m.minContext) == 0 {
+				return
+			}
+
+			if m.minContext = m.suffix(m.minContext); m.numStats(m.minContext) != numMasked {
+				break
+			}
+		}
+
+		see, escFreq := m.makeEscFreq(numMasked)
+		sum := uint32(0)
+		s := m.stats(m.minContext)
+
+		for i := m.numStats(m.minContext); i > 0; i, s = i-1, s+stateSize {
+			cur := m.symbol(s)
+
+			if cur == symbol {
+				low, s1 := sum, s
+
+				for ; i > 0; i, s = i-1, s+stateSize {
+					if !charMask[m.symbol(s)] {
+						sum += m.freq(s)
+					}
+				}
+
+				re.encode(low, m.freq(s1), sum+escFreq)
+				see.update()
+				m.foundState = s1
+				m.update2()
+
+				return
+			}
+
+			if !charMask[cur] {
+				sum += m.freq(s)
+			}
+
+			charMask[cur] = true
+		}
+
+		re.encode(sum, escFreq, sum+escFreq)
+		see.summ += uint16(sum + escFreq) //nolint:gosec
+	}
+}
+
+type writeCloser struct {
+	m  *model
+	re *rangeEncoder
+}
+
+func (wc *writeCloser) Write(p []byte) (int, error) {
+	if wc.m == nil {
+		return 0, errAlreadyClosed
+	}
+
+	for i, b := range p {
+		wc.m.encodeSymbol(wc.re, b)
+
+		if wc.re.err != nil {
+			return i, fmt.Errorf("ppmd: error writing: %w", wc.re.err)
+		}
+	}
+
+	return len(p), nil
+}
+
+// Close flushes any remaining data, without writing an end marker the same
+// as 7-Zip. It does not close the underlying io.Writer.
+func (wc *writeCloser) Close() error {
+	if wc.m == nil {
+		return errAlreadyClosed
+	}
+
+	wc.re.flush()
+
+	if wc.re.err == nil {
+		wc.re.err = wc.re.bw.Flush()
+	}
+
+	if wc.re.err != nil {
+		return fmt.Errorf("ppmd: error closing: %w", wc.re.err)
+	}
+
+	wc.m, wc.re = nil, nil
+
+	return nil
+}
+
+// NewWriter returns a new PPMd io.WriteCloser writing to w using a model of
+// the given order and memory size, along with the properties describing the
+// stream.
+func NewWriter(w io.Writer, order int, memSize uint32) (io.WriteCloser, []byte, error) {
+	if order < minOrder || order > maxOrder || memSize < minMemSize || memSize > maxMemSize {
+		return nil, nil, errInvalidProperties
+	}
+
+	props := make([]byte, 5)
+	props[0] = byte(order)
+	binary.LittleEndian.PutUint32(props[1:], memSize)
+
+	return &writeCloser{
+		m: newModel(order, memSize),
+		re: &rangeEncoder{
+			bw:        bufio.NewWriter(w),
+			rng:       0xffffffff,
+			cacheSize: 1,
+		},
+	}, props, nil
+}
@@ -1,7 +1,10 @@
 // Package util implements various utility types and interfaces.
 package util
 
-import "io"
+import (
+	"crypto/cipher"
+	"io"
+)
 
 // SizeReadSeekCloser is an io.Reader, io.Seeker, and io.Closer with a Size
 // method.
@@ -24,6 +27,19 @@ type ReadCloser interface {
 	io.Closer
 }
 
+// Canceller is implemented by decompressors that may perform long running
+// work outside of Read, such as key derivation, so that it can be abandoned
+// once done is closed.
+type Canceller interface {
+	SetCancel(done <-chan struct{})
+}
+
+// CBCDecrypterSetter is implemented by decompressors using AES in CBC mode
+// that allow the block cipher implementation to be replaced.
+type CBCDecrypterSetter interface {
+	SetCBCDecrypter(fn func(key, iv []byte) (cipher.BlockMode, error))
+}
+
 type nopCloser struct {
 	Reader
 }
@@ -29,6 +29,10 @@ func (rc *readCloser) Close() error {
 		return errAlreadyClosed
 	}
 
+	// Stop any decoding goroutines before pooling the decoder, otherwise
+	// they linger until the decoder is next reused
+	_ = rc.r.Reset(nil)
+
 	if err := rc.c.Close(); err != nil {
 		return fmt.Errorf("zstd: error closing: %w", err)
 	}
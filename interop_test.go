@@ -0,0 +1,217 @@
+package sevenzip_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bsdtarExtract extracts the archive at name with libarchive's bsdtar and
+// returns the contents of every regular file by name, skipping the test if
+// bsdtar isn't installed.
+func bsdtarExtract(t *testing.T, name string) map[string]string {
+	t.Helper()
+
+	bsdtar, err := exec.LookPath("bsdtar")
+	if err != nil {
+		t.Skip("bsdtar not found")
+	}
+
+	dir := t.TempDir()
+
+	out, err := exec.Command(bsdtar, "-xf", name, "-C", dir).CombinedOutput() //nolint:gosec
+	require.NoError(t, err, string(out))
+
+	files := make(map[string]string)
+
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		files[filepath.ToSlash(rel)] = string(b)
+
+		return err
+	})
+	require.NoError(t, err)
+
+	return files
+}
+
+//nolint:funlen
+func TestWriterInterop(t *testing.T) {
+	t.Parallel()
+
+	contents := map[string]string{
+		"dir/a.txt": strings.Repeat("a", 100),
+		"b.txt":     strings.Repeat("b", 200),
+	}
+
+	write := func(t *testing.T, name string, empty bool, opts ...sevenzip.WriterOption) {
+		t.Helper()
+
+		f, err := os.Create(name)
+		require.NoError(t, err)
+
+		w := sevenzip.NewWriter(f, opts...)
+
+		if !empty {
+			_, err = w.Create("dir/")
+			require.NoError(t, err)
+
+			for _, n := range []string{"dir/a.txt", "b.txt"} {
+				fw, err := w.Create(n)
+				require.NoError(t, err)
+
+				_, err = fw.Write([]byte(contents[n]))
+				require.NoError(t, err)
+			}
+		}
+
+		require.NoError(t, w.Close())
+		require.NoError(t, f.Close())
+	}
+
+	tables := []struct {
+		name string
+		// Whatever is done to the archive after it's written, and the
+		// contents expected afterwards
+		after func(t *testing.T, name string) map[string]string
+		opts  []sevenzip.WriterOption
+		empty bool // Write no files at all
+	}{
+		{
+			name: "plain",
+		},
+		{
+			name:  "empty",
+			empty: true,
+		},
+		{
+			name:  "copied empty",
+			empty: true,
+			after: func(t *testing.T, name string) map[string]string {
+				t.Helper()
+
+				for _, src := range []string{"empty2.7z", "COMPRESS-492.7z", "conformance/p7zip/empty.7z"} {
+					r, err := sevenzip.OpenReader(filepath.Join("testdata", src))
+					require.NoError(t, err)
+
+					f, err := os.Create(name)
+					require.NoError(t, err)
+
+					w := sevenzip.NewWriter(f)
+					require.NoError(t, w.Copy(&r.Reader), src)
+					require.NoError(t, w.Close(), src)
+					require.NoError(t, errors.Join(f.Close(), r.Close()))
+
+					assert.Empty(t, bsdtarExtract(t, name), src)
+				}
+
+				return nil
+			},
+		},
+		{
+			name: "stored",
+			opts: []sevenzip.WriterOption{sevenzip.WithStore()},
+		},
+		{
+			name: "solid",
+			opts: []sevenzip.WriterOption{sevenzip.WithSolidBlockSize(1 << 20)},
+		},
+		{
+			name: "ppmd",
+			opts: []sevenzip.WriterOption{
+				sevenzip.WithMethodRules(sevenzip.MethodForSize(sevenzip.MethodPPMd, 0, 0)),
+			},
+		},
+		{
+			name: "appended",
+			after: func(t *testing.T, name string) map[string]string {
+				t.Helper()
+
+				w, err := sevenzip.OpenWriter(name)
+				require.NoError(t, err)
+
+				fw, err := w.Create("c.txt")
+				require.NoError(t, err)
+
+				_, err = fw.Write([]byte("appended"))
+				require.NoError(t, err)
+				require.NoError(t, w.Close())
+
+				return map[string]string{"c.txt": "appended"}
+			},
+		},
+		{
+			name: "kept header",
+			after: func(t *testing.T, name string) map[string]string {
+				t.Helper()
+
+				for _, n := range []string{"c.txt", "d.txt"} {
+					w, err := sevenzip.OpenWriter(name, sevenzip.WithKeepOldHeader())
+					require.NoError(t, err)
+
+					fw, err := w.Create(n)
+					require.NoError(t, err)
+
+					_, err = fw.Write([]byte(n))
+					require.NoError(t, err)
+					require.NoError(t, w.Close())
+				}
+
+				return map[string]string{"c.txt": "c.txt", "d.txt": "d.txt"}
+			},
+		},
+		{
+			name: "updated",
+			after: func(t *testing.T, name string) map[string]string {
+				t.Helper()
+
+				u := new(sevenzip.Update)
+				u.Replace("b.txt", strings.NewReader("replaced"))
+				require.NoError(t, sevenzip.UpdateFile(name, u))
+
+				return map[string]string{"b.txt": "replaced"}
+			},
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			name := filepath.Join(t.TempDir(), "archive.7z")
+			write(t, name, table.empty, table.opts...)
+
+			want := make(map[string]string)
+			if !table.empty {
+				for k, v := range contents {
+					want[k] = v
+				}
+			}
+
+			if table.after != nil {
+				for k, v := range table.after(t, name) {
+					want[k] = v
+				}
+			}
+
+			assert.Equal(t, want, bsdtarExtract(t, name))
+		})
+	}
+}
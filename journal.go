@@ -0,0 +1,126 @@
+package sevenzip
+
+import (
+	"errors"
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// journal records the changes made by [Reader.ExtractAll] so that they can
+// be undone if extraction fails.
+type journal struct {
+	mu      sync.Mutex
+	dirs    []string
+	files   []string
+	created map[string]struct{}
+	backups map[string]string
+}
+
+func newJournal() *journal {
+	return &journal{
+		created: make(map[string]struct{}),
+		backups: make(map[string]string),
+	}
+}
+
+// directories records directories that have been created.
+func (j *journal) directories(dirs []string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.dirs = append(j.dirs, dirs...)
+}
+
+// file records that the file path is about to be written. If a file that
+// wasn't created by the extraction already exists it is moved aside so it
+// can be restored.
+func (j *journal) file(path string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, ok := j.created[path]; ok {
+		return nil
+	}
+
+	info, err := os.Lstat(path)
+
+	switch {
+	case errors.Is(err, iofs.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("sevenzip: error retrieving file info: %w", err)
+	case !info.IsDir():
+		f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.orig")
+		if err != nil {
+			return fmt.Errorf("sevenzip: error creating backup: %w", err)
+		}
+
+		if err := f.Close(); err != nil {
+			return errors.Join(fmt.Errorf("sevenzip: error closing: %w", err), os.Remove(f.Name()))
+		}
+
+		if err := os.Rename(path, f.Name()); err != nil {
+			return errors.Join(fmt.Errorf("sevenzip: error creating backup: %w", err), os.Remove(f.Name()))
+		}
+
+		j.backups[path] = f.Name()
+	}
+
+	j.created[path] = struct{}{}
+	j.files = append(j.files, path)
+
+	return nil
+}
+
+// rollback removes every file and directory that was created and restores
+// any files that were replaced.
+func (j *journal) rollback() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var errs []error
+
+	for _, path := range j.files {
+		if err := os.Remove(path); err != nil && !errors.Is(err, iofs.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("sevenzip: error removing %s: %w", path, err))
+		}
+	}
+
+	for path, backup := range j.backups {
+		if err := os.Rename(backup, path); err != nil {
+			errs = append(errs, fmt.Errorf("sevenzip: error restoring %s: %w", path, err))
+		}
+	}
+
+	// Remove the deepest directories first
+	sort.Slice(j.dirs, func(a, b int) bool {
+		return len(j.dirs[a]) > len(j.dirs[b])
+	})
+
+	for _, path := range j.dirs {
+		if err := os.Remove(path); err != nil && !errors.Is(err, iofs.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("sevenzip: error removing %s: %w", path, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// commit removes the backups of any files that were replaced.
+func (j *journal) commit() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var errs []error
+
+	for _, backup := range j.backups {
+		if err := os.Remove(backup); err != nil {
+			errs = append(errs, fmt.Errorf("sevenzip: error removing backup: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,104 @@
+package sevenzip
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// A Method is a way of compressing the contents of the files written by a
+// [Writer], either built in or returned by [RegisterCompressor].
+type Method int
+
+const (
+	// MethodLZMA2 compresses with LZMA2 using a 16 MiB dictionary, the
+	// default.
+	MethodLZMA2 Method = iota
+	// MethodCopy stores the contents as is, the same as [WithStore], which
+	// suits files that are already compressed, such as images and video.
+	MethodCopy
+	// MethodPPMd compresses with PPMd variant H using a model of order 6
+	// and 16 MiB of memory, the same as 7-zip's defaults. It usually beats
+	// LZMA2 on text, such as source code and logs, but is slower and
+	// decompresses at about the same speed as it compresses. Writing with
+	// it fails with [ErrUnsupportedMethod] when built with the
+	// sevenzip_noppmd or sevenzip_minimal tags.
+	MethodPPMd
+)
+
+func (m Method) String() string {
+	switch m {
+	case MethodLZMA2:
+		return "LZMA2"
+	case MethodCopy:
+		return "Copy"
+	case MethodPPMd:
+		return "PPMD"
+	}
+
+	if c, ok := m.compressor(); ok {
+		return Coder{ID: c.id}.Name()
+	}
+
+	return fmt.Sprintf("Method(%d)", int(m))
+}
+
+// A MethodRule chooses the method used by a [Writer] for a file from the
+// [FileHeader] passed to [Writer.CreateHeader], returning false if it
+// doesn't apply to the file. See [WithMethodRules].
+type MethodRule func(fh *FileHeader) (Method, bool)
+
+// WithMethodRules makes the [Writer] choose the method for each file with the
+// first of rules that applies to it, falling back to LZMA2, or Copy if
+// [WithStore] is also used. Files using different methods are never
+// compressed together in the same solid block. Further calls add more rules
+// after those already set.
+//
+// The contents of a file aren't known when it's added so rules can only use
+// its header. The UncompressedSize field isn't otherwise used by
+// [Writer.CreateHeader], so it can be set to the expected size for
+// [MethodForSize], as [Writer.AddFS] does.
+func WithMethodRules(rules ...MethodRule) WriterOption {
+	return func(o *writerOptions) {
+		o.rules = append(o.rules, rules...)
+	}
+}
+
+// MethodForExtensions returns a [MethodRule] that chooses m for files whose
+// name ends with one of exts, such as ".jpg", ignoring case.
+func MethodForExtensions(m Method, exts ...string) MethodRule {
+	set := make(map[string]struct{}, len(exts))
+	for _, ext := range exts {
+		set[strings.ToLower(ext)] = struct{}{}
+	}
+
+	return func(fh *FileHeader) (Method, bool) {
+		_, ok := set[strings.ToLower(path.Ext(fh.Name))]
+
+		return m, ok
+	}
+}
+
+// MethodForSize returns a [MethodRule] that chooses m for files whose
+// UncompressedSize, as passed to [Writer.CreateHeader], is at least lower and,
+// unless upper is zero, less than upper.
+func MethodForSize(m Method, lower, upper uint64) MethodRule {
+	return func(fh *FileHeader) (Method, bool) {
+		return m, fh.UncompressedSize >= lower && (upper == 0 || fh.UncompressedSize < upper)
+	}
+}
+
+// method returns the method for the file with header fh.
+func (o *writerOptions) method(fh *FileHeader) Method {
+	for _, rule := range o.rules {
+		if m, ok := rule(fh); ok {
+			return m
+		}
+	}
+
+	if o.store {
+		return MethodCopy
+	}
+
+	return MethodLZMA2
+}
@@ -0,0 +1,202 @@
+// Package mobile is a small facade over the sevenzip package for use with
+// gomobile bind. Everything it exports only uses the types gomobile can
+// bind to Java and Objective-C/Swift: strings, byte slices, integers,
+// booleans, pointers to structs and interfaces with methods using only
+// those types, so archives can be listed and extracted from Android and iOS
+// apps without wrapping the interface-heavy sevenzip API by hand.
+//
+// Build the bindings with:
+//
+//	gomobile bind -target=android github.com/javi11/sevenzip/mobile
+//	gomobile bind -target=ios github.com/javi11/sevenzip/mobile
+package mobile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/javi11/sevenzip"
+)
+
+// An Archive is an open 7-zip archive.
+type Archive struct {
+	r     *sevenzip.Reader
+	close func() error
+}
+
+// Open opens the archive at path, which may be the first volume of a
+// multi-volume archive with a ".001" suffix. The password is only needed if
+// the archive is encrypted and may otherwise be empty.
+func Open(path, password string) (*Archive, error) {
+	rc, err := sevenzip.OpenReaderWithPassword(path, password)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return &Archive{r: &rc.Reader, close: rc.Close}, nil
+}
+
+// OpenBytes opens an archive held entirely in memory, such as one read
+// from an asset or a content URI.
+func OpenBytes(data []byte, password string) (*Archive, error) {
+	r, err := sevenzip.NewReaderWithPassword(bytes.NewReader(data), int64(len(data)), password)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return &Archive{r: r, close: r.Close}, nil
+}
+
+// Close closes the archive.
+func (a *Archive) Close() error {
+	return a.close()
+}
+
+// Count returns the number of entries in the archive.
+func (a *Archive) Count() int {
+	return len(a.r.File)
+}
+
+// An Entry describes a file or directory in an archive.
+type Entry struct {
+	// Name is the slash separated path of the entry, directories have a
+	// trailing slash.
+	Name string
+	// Size is the uncompressed size in bytes.
+	Size int64
+	// Modified is the modification time in milliseconds since the Unix
+	// epoch, or 0 if it isn't known.
+	Modified int64
+	// CRC32 is the CRC32 of the contents, or 0 if it isn't known.
+	CRC32 int64
+	// IsDir is true if the entry is a directory.
+	IsDir bool
+	// Encrypted is true if the entry needs a password to be read.
+	Encrypted bool
+}
+
+// Entry returns the entry at index i, counting from 0 up to [Archive.Count].
+func (a *Archive) Entry(i int) (*Entry, error) {
+	f, ok := a.r.FileByIndex(i)
+	if !ok {
+		return nil, fmt.Errorf("mobile: entry %d: %w", i, iofs.ErrNotExist)
+	}
+
+	e := &Entry{
+		Name:  f.Name,
+		Size:  int64(f.UncompressedSize), //nolint:gosec
+		CRC32: int64(f.CRC32),
+		IsDir: f.FileInfo().IsDir(),
+	}
+
+	if !f.Modified.IsZero() {
+		e.Modified = f.Modified.UnixMilli()
+	}
+
+	for _, c := range f.Coders() {
+		if c.Name() == "7zAES" {
+			e.Encrypted = true
+		}
+	}
+
+	return e, nil
+}
+
+func (a *Archive) file(name string) (*sevenzip.File, error) {
+	f, ok := a.r.FileByName(name)
+	if !ok {
+		return nil, fmt.Errorf("mobile: %s: %w", name, iofs.ErrNotExist)
+	}
+
+	return f, nil
+}
+
+// Read returns the contents of the named file. It is only suitable for
+// files small enough to hold in memory, larger files should be written to
+// disk with [Archive.Extract].
+func (a *Archive) Read(name string) ([]byte, error) {
+	f, err := a.file(name)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	b, err := io.ReadAll(rc)
+	if err = errors.Join(err, rc.Close()); err != nil {
+		return nil, fmt.Errorf("mobile: error reading %s: %w", name, err)
+	}
+
+	return b, nil
+}
+
+// Extract writes the contents of the named file to path, replacing it if it
+// already exists.
+func (a *Archive) Extract(name, path string) (err error) {
+	f, err := a.file(name)
+	if err != nil {
+		return err
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	defer func() {
+		err = errors.Join(err, rc.Close())
+	}()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { //nolint:mnd
+		return fmt.Errorf("mobile: error creating directory: %w", err)
+	}
+
+	w, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("mobile: error creating file: %w", err)
+	}
+
+	_, err = io.Copy(w, rc)
+	if err = errors.Join(err, w.Close()); err != nil {
+		return fmt.Errorf("mobile: error extracting %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// A Listener is told about each file as it is extracted by
+// [Archive.ExtractAll]. Its methods may be called concurrently.
+type Listener interface {
+	// FileDone is called once the file name has been extracted, with
+	// the number of bytes written and, if it failed, the error message,
+	// otherwise an empty string.
+	FileDone(name string, written int64, errorMessage string)
+}
+
+// ExtractAll extracts every entry in the archive to the directory dir, as
+// with [sevenzip.Reader.ExtractAll]. The listener may be nil.
+func (a *Archive) ExtractAll(dir string, listener Listener) error {
+	var opts []sevenzip.ExtractOption
+
+	if listener != nil {
+		opts = append(opts, sevenzip.WithFileDone(func(r sevenzip.ExtractResult) {
+			var msg string
+			if r.Err != nil {
+				msg = r.Err.Error()
+			}
+
+			listener.FileDone(r.File.Name, r.Written, msg)
+		}))
+	}
+
+	return a.r.ExtractAll(context.Background(), dir, opts...) //nolint:wrapcheck
+}
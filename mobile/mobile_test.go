@@ -0,0 +1,112 @@
+package mobile_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/javi11/sevenzip/mobile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testdata(name string) string {
+	return filepath.Join("..", "testdata", name)
+}
+
+type listener struct {
+	mu   sync.Mutex
+	done map[string]int64
+}
+
+func (l *listener) FileDone(name string, written int64, errorMessage string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if errorMessage == "" {
+		l.done[name] = written
+	}
+}
+
+func TestArchive(t *testing.T) {
+	t.Parallel()
+
+	b, err := os.ReadFile(testdata("aes7z.7z"))
+	require.NoError(t, err)
+
+	tables := []struct {
+		name string
+		open func() (*mobile.Archive, error)
+	}{
+		{
+			name: "file",
+			open: func() (*mobile.Archive, error) { return mobile.Open(testdata("aes7z.7z"), "password") },
+		},
+		{
+			name: "bytes",
+			open: func() (*mobile.Archive, error) { return mobile.OpenBytes(b, "password") },
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			a, err := table.open()
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				require.NoError(t, a.Close())
+			})
+
+			require.Equal(t, 10, a.Count())
+
+			e, err := a.Entry(0)
+			require.NoError(t, err)
+			assert.Equal(t, "01", e.Name)
+			assert.Equal(t, int64(3572), e.Size)
+			assert.NotZero(t, e.Modified)
+			assert.NotZero(t, e.CRC32)
+			assert.False(t, e.IsDir)
+			assert.True(t, e.Encrypted)
+
+			_, err = a.Entry(10)
+			assert.ErrorIs(t, err, fs.ErrNotExist)
+
+			contents, err := a.Read("01")
+			require.NoError(t, err)
+			assert.Len(t, contents, 3572)
+
+			_, err = a.Read("missing")
+			assert.ErrorIs(t, err, fs.ErrNotExist)
+
+			dir := t.TempDir()
+			path := filepath.Join(dir, "sub", "01")
+			require.NoError(t, a.Extract("01", path))
+
+			extracted, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, contents, extracted)
+
+			l := &listener{done: make(map[string]int64)}
+			require.NoError(t, a.ExtractAll(filepath.Join(dir, "all"), l))
+			assert.Len(t, l.done, 10)
+			assert.Equal(t, int64(3572), l.done["01"])
+
+			require.NoError(t, a.ExtractAll(filepath.Join(dir, "quiet"), nil))
+			assert.FileExists(t, filepath.Join(dir, "quiet", "10"))
+		})
+	}
+}
+
+func TestOpenError(t *testing.T) {
+	t.Parallel()
+
+	_, err := mobile.Open(testdata("t2.7z"), "")
+	require.Error(t, err)
+
+	_, err = mobile.OpenBytes([]byte("not an archive"), "")
+	require.Error(t, err)
+}
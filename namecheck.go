@@ -0,0 +1,158 @@
+package sevenzip
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// A NameIssueKind is the kind of problem found by [File.ValidateName].
+type NameIssueKind int
+
+const (
+	// NameTraversal is an absolute path, or one with a drive letter or
+	// ".." element, that could be written outside of the directory it is
+	// extracted to.
+	NameTraversal NameIssueKind = iota
+	// NameControl is a name containing control characters, which can be
+	// used to disguise it on a terminal.
+	NameControl
+	// NameEmpty is an empty name or one with empty or "." elements.
+	NameEmpty
+	// NameTooLong is an element longer than the limit of the policy.
+	NameTooLong
+	// NameReserved is an element that can't be created on Windows, see
+	// [NamePolicy].
+	NameReserved
+)
+
+func (k NameIssueKind) String() string {
+	switch k {
+	case NameTraversal:
+		return "traversal"
+	case NameControl:
+		return "control"
+	case NameEmpty:
+		return "empty"
+	case NameTooLong:
+		return "too long"
+	case NameReserved:
+		return "reserved"
+	default:
+		return fmt.Sprintf("NameIssueKind(%d)", int(k))
+	}
+}
+
+// A NameIssue is a problem with the name of a file found by
+// [File.ValidateName].
+type NameIssue struct {
+	Kind NameIssueKind
+	// Element is the element of the name with the problem, or empty if it
+	// affects the whole name.
+	Element string
+	// Problem describes what was found.
+	Problem string
+}
+
+// A NamePolicy configures the checks made by [File.ValidateName]. The zero
+// value checks for everything that could be a problem on any system apart
+// from Windows.
+type NamePolicy struct {
+	// Windows also reports elements that can't be created on Windows:
+	// reserved device names such as CON or NUL, with or without an
+	// extension, the characters < > : " | ? * and elements ending with a
+	// dot or space.
+	Windows bool
+	// MaxElementLength is the longest element allowed, measured in the
+	// same way as when extracting. If zero the limit is 255, that of the
+	// common filesystems.
+	MaxElementLength int
+}
+
+//nolint:gochecknoglobals
+var reservedNames = map[string]struct{}{
+	"CON": {}, "PRN": {}, "AUX": {}, "NUL": {},
+	"COM1": {}, "COM2": {}, "COM3": {}, "COM4": {}, "COM5": {}, "COM6": {}, "COM7": {}, "COM8": {}, "COM9": {},
+	"LPT1": {}, "LPT2": {}, "LPT3": {}, "LPT4": {}, "LPT5": {}, "LPT6": {}, "LPT7": {}, "LPT8": {}, "LPT9": {},
+}
+
+// ValidateName checks the name of the file against policy and returns any
+// issues found, in the order they appear in the name, or nil if there
+// aren't any. Backslashes are treated as separators, the same as when
+// extracting. It's intended for presenting warnings about each file for
+// review before extracting, which sanitises names regardless.
+//
+//nolint:cyclop,funlen
+func (f *File) ValidateName(policy NamePolicy) []NameIssue {
+	var issues []NameIssue
+
+	add := func(kind NameIssueKind, elem, format string, a ...any) {
+		issues = append(issues, NameIssue{Kind: kind, Element: elem, Problem: fmt.Sprintf(format, a...)})
+	}
+
+	maxLen := policy.MaxElementLength
+	if maxLen == 0 {
+		maxLen = maxNameLen
+	}
+
+	name := strings.ReplaceAll(strings.TrimSuffix(f.Name, "/"), `\`, "/")
+
+	if name == "" {
+		add(NameEmpty, "", "name is empty")
+
+		return issues
+	}
+
+	if strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		add(NameControl, "", "name contains control characters: %q", name)
+	}
+
+	switch {
+	case strings.HasPrefix(name, "/"):
+		add(NameTraversal, "", "name is an absolute path")
+
+		name = strings.TrimLeft(name, "/")
+	case len(name) >= 2 && name[1] == ':' && isLetter(name[0]):
+		add(NameTraversal, name[:2], "name starts with a drive letter")
+	}
+
+	for _, elem := range strings.Split(name, "/") {
+		switch elem {
+		case "..":
+			add(NameTraversal, elem, "name has a parent directory element")
+
+			continue
+		case "", ".":
+			add(NameEmpty, elem, "name has an empty element")
+
+			continue
+		}
+
+		if n := pathLen(elem); n > maxLen {
+			add(NameTooLong, elem, "element length %d exceeds %d", n, maxLen)
+		}
+
+		if !policy.Windows {
+			continue
+		}
+
+		base, _, _ := strings.Cut(elem, ".")
+		if _, ok := reservedNames[strings.ToUpper(strings.TrimRight(base, " "))]; ok {
+			add(NameReserved, elem, "element is a reserved device name on Windows")
+		}
+
+		if i := strings.IndexAny(elem, `<>:"|?*`); i >= 0 {
+			add(NameReserved, elem, "element contains %q which isn't allowed on Windows", elem[i])
+		}
+
+		if strings.HasSuffix(elem, ".") || strings.HasSuffix(elem, " ") {
+			add(NameReserved, elem, "element ends with a dot or space which Windows removes")
+		}
+	}
+
+	return issues
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
@@ -0,0 +1,160 @@
+package sevenzip_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+)
+
+//nolint:funlen
+func TestValidateName(t *testing.T) {
+	t.Parallel()
+
+	tables := []struct {
+		name    string
+		file    string
+		policy  sevenzip.NamePolicy
+		kinds   []sevenzip.NameIssueKind
+		element string
+	}{
+		{
+			name: "ok",
+			file: "dir/file.txt",
+		},
+		{
+			name: "windows ok",
+			file: "dir/file.txt",
+			policy: sevenzip.NamePolicy{
+				Windows: true,
+			},
+		},
+		{
+			name:  "absolute",
+			file:  "/etc/passwd",
+			kinds: []sevenzip.NameIssueKind{sevenzip.NameTraversal},
+		},
+		{
+			name:    "parent",
+			file:    "dir/../../evil.txt",
+			kinds:   []sevenzip.NameIssueKind{sevenzip.NameTraversal, sevenzip.NameTraversal},
+			element: "..",
+		},
+		{
+			name:    "backslash parent",
+			file:    `..\evil.txt`,
+			kinds:   []sevenzip.NameIssueKind{sevenzip.NameTraversal},
+			element: "..",
+		},
+		{
+			name:    "drive letter",
+			file:    `C:\Windows\evil.dll`,
+			kinds:   []sevenzip.NameIssueKind{sevenzip.NameTraversal},
+			element: "C:",
+		},
+		{
+			name:  "control",
+			file:  "invoice.pdf\r\x1b[Kreadme.txt",
+			kinds: []sevenzip.NameIssueKind{sevenzip.NameControl},
+		},
+		{
+			name:    "empty element",
+			file:    "dir/./file.txt",
+			kinds:   []sevenzip.NameIssueKind{sevenzip.NameEmpty},
+			element: ".",
+		},
+		{
+			name:    "too long",
+			file:    "dir/" + strings.Repeat("x", 256),
+			kinds:   []sevenzip.NameIssueKind{sevenzip.NameTooLong},
+			element: strings.Repeat("x", 256),
+		},
+		{
+			name: "longest",
+			file: "dir/" + strings.Repeat("x", 255),
+		},
+		{
+			name: "policy length",
+			file: "dir/file.txt",
+			policy: sevenzip.NamePolicy{
+				MaxElementLength: 5,
+			},
+			kinds:   []sevenzip.NameIssueKind{sevenzip.NameTooLong},
+			element: "file.txt",
+		},
+		{
+			name: "reserved ignored",
+			file: "dir/CON.txt",
+		},
+		{
+			name: "reserved",
+			file: "dir/con.txt",
+			policy: sevenzip.NamePolicy{
+				Windows: true,
+			},
+			kinds:   []sevenzip.NameIssueKind{sevenzip.NameReserved},
+			element: "con.txt",
+		},
+		{
+			name: "reserved directory",
+			file: "LPT1/file.txt",
+			policy: sevenzip.NamePolicy{
+				Windows: true,
+			},
+			kinds:   []sevenzip.NameIssueKind{sevenzip.NameReserved},
+			element: "LPT1",
+		},
+		{
+			name: "not reserved",
+			file: "dir/CONSOLE.txt",
+			policy: sevenzip.NamePolicy{
+				Windows: true,
+			},
+		},
+		{
+			name: "invalid character",
+			file: "dir/what?.txt",
+			policy: sevenzip.NamePolicy{
+				Windows: true,
+			},
+			kinds:   []sevenzip.NameIssueKind{sevenzip.NameReserved},
+			element: "what?.txt",
+		},
+		{
+			name: "trailing dot",
+			file: "dir./file.txt",
+			policy: sevenzip.NamePolicy{
+				Windows: true,
+			},
+			kinds:   []sevenzip.NameIssueKind{sevenzip.NameReserved},
+			element: "dir.",
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := archiveOf(t, table.file)
+			assert.Len(t, r.File, 1)
+
+			issues := r.File[0].ValidateName(table.policy)
+
+			kinds := make([]sevenzip.NameIssueKind, 0, len(issues))
+			for _, issue := range issues {
+				kinds = append(kinds, issue.Kind)
+				assert.NotEmpty(t, issue.Problem)
+			}
+
+			if table.kinds == nil {
+				assert.Nil(t, issues)
+
+				return
+			}
+
+			assert.Equal(t, table.kinds, kinds)
+			assert.Equal(t, table.element, issues[len(issues)-1].Element)
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package sevenzip
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/javi11/sevenzip/internal/lzma2"
+)
+
+// normalizedDictionary is the largest LZMA2 dictionary used by [Normalize],
+// smaller archives use a dictionary no bigger than their contents.
+const normalizedDictionary = 64 << 20
+
+type normalizeOptions struct {
+	modTime time.Time
+	tempDir string
+	spool   SpoolFunc
+}
+
+// A NormalizeOption configures a call to [Normalize].
+type NormalizeOption func(*normalizeOptions)
+
+// WithModTime sets the modification time of every entry to t. By default all
+// timestamps are removed.
+func WithModTime(t time.Time) NormalizeOption {
+	return func(o *normalizeOptions) {
+		o.modTime = t
+	}
+}
+
+// WithTempDir sets the directory used for temporary files. By default
+// [os.TempDir] is used.
+func WithTempDir(dir string) NormalizeOption {
+	return func(o *normalizeOptions) {
+		o.tempDir = dir
+	}
+}
+
+// WithSpool sets where the contents of the archive are staged while it is
+// rebuilt, instead of temporary files in the directory set with
+// [WithTempDir].
+func WithSpool(fn SpoolFunc) NormalizeOption {
+	return func(o *normalizeOptions) {
+		o.spool = fn
+	}
+}
+
+type spooledFile struct {
+	offset, size int64
+}
+
+// spoolFiles copies the contents of every non-empty file in src, in archive
+// order so each stream is only decoded once, to w.
+func spoolFiles(src *Reader, w io.Writer) ([]spooledFile, error) {
+	files := make([]spooledFile, len(src.File))
+
+	var offset int64
+
+	for i, f := range src.File {
+		if f.isEmptyStream || f.isEmptyFile || f.UncompressedSize == 0 {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+
+		n, err := io.Copy(w, rc)
+		if err = errors.Join(err, rc.Close()); err != nil {
+			return nil, fmt.Errorf("sevenzip: error reading %s: %w", f.Name, err)
+		}
+
+		files[i] = spooledFile{offset, n}
+		offset += n
+	}
+
+	return files, nil
+}
+
+// Normalize writes a copy of src to dst in a canonical form so that archives
+// with the same contents always produce byte-identical output, for use in
+// reproducible build pipelines. Entries are sorted by name, creation and
+// access times are removed, modification times are removed or set with
+// [WithModTime], and the contents of all files are compressed into a single
+// solid LZMA2 stream with fixed settings. Empty files are stored as empty
+// streams and the output is never encrypted.
+//
+// The contents of src are staged in temporary files while the archive is
+// built, or wherever is set with [WithSpool].
+//
+//nolint:cyclop,funlen
+func Normalize(src *Reader, dst io.Writer, opts ...NormalizeOption) (err error) {
+	o := new(normalizeOptions)
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.spool == nil {
+		o.spool = DiskSpool(o.tempDir)
+	}
+
+	if src.opts.nameFilter != nil {
+		return errFiltered
+	}
+
+	var fi []FileHeader
+	if src.h.filesInfo != nil {
+		fi = src.h.filesInfo.file
+	}
+
+	order := make([]int, len(fi))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return fi[order[i]].Name < fi[order[j]].Name
+	})
+
+	unpacked, err := o.spool()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err = errors.Join(err, unpacked.Close())
+	}()
+
+	spooled, err := spoolFiles(src, unpacked)
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for _, s := range spooled {
+		total += s.size
+	}
+
+	packed, err := o.spool()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err = errors.Join(err, packed.Close())
+	}()
+
+	lw, properties, err := lzma2.NewWriter(packed, int(min(max(total, 1), normalizedDictionary)))
+	if err != nil {
+		return fmt.Errorf("sevenzip: error compressing: %w", err)
+	}
+
+	var (
+		files   = make([]FileHeader, 0, len(fi))
+		sizes   []uint64
+		digests []uint32
+		defined []bool
+	)
+
+	for _, i := range order {
+		fh := FileHeader{
+			Name:          fi[i].Name,
+			Modified:      o.modTime,
+			Attributes:    fi[i].Attributes,
+			isEmptyStream: spooled[i].size == 0,
+		}
+
+		if spooled[i].size == 0 {
+			fh.isEmptyFile = !fi[i].FileInfo().IsDir()
+		} else {
+			h := crc32.NewIEEE()
+
+			if _, err := io.Copy(io.MultiWriter(lw, h), io.NewSectionReader(unpacked, spooled[i].offset, spooled[i].size)); err != nil {
+				return fmt.Errorf("sevenzip: error compressing %s: %w", fh.Name, err)
+			}
+
+			fh.UncompressedSize = uint64(spooled[i].size) //nolint:gosec
+			fh.CRC32, fh.crcDefined = h.Sum32(), true
+
+			sizes = append(sizes, fh.UncompressedSize)
+			digests = append(digests, fh.CRC32)
+			defined = append(defined, true)
+		}
+
+		files = append(files, fh)
+	}
+
+	if err := lw.Close(); err != nil {
+		return fmt.Errorf("sevenzip: error compressing: %w", err)
+	}
+
+	h := &header{
+		filesInfo: &filesInfo{file: files},
+	}
+
+	var streams []packedStream
+
+	if len(sizes) > 0 {
+		size := packed.Size()
+
+		h.streamsInfo = &streamsInfo{
+			packInfo: &packInfo{
+				streams: 1,
+				size:    []uint64{uint64(size)}, //nolint:gosec
+			},
+			unpackInfo: &unpackInfo{
+				folder: []*folder{
+					{
+						in:            1,
+						out:           1,
+						packedStreams: 1,
+						coder:         []*coder{{id: []byte{0x21}, in: 1, out: 1, properties: properties}},
+						bindPair:      []*bindPair{},
+						size:          []uint64{uint64(total)}, //nolint:gosec
+						packed:        []uint64{0},
+					},
+				},
+			},
+			subStreamsInfo: &subStreamsInfo{
+				streams: []uint64{uint64(len(sizes))},
+				size:    sizes,
+				digest:  digests,
+				defined: defined,
+			},
+		}
+
+		streams = []packedStream{
+			{
+				size: uint64(size), //nolint:gosec
+				write: func(w io.Writer) error {
+					if _, err := io.Copy(w, io.NewSectionReader(packed, 0, size)); err != nil {
+						return fmt.Errorf("sevenzip: error copying packed stream: %w", err)
+					}
+
+					return nil
+				},
+			},
+		}
+	}
+
+	return writeArchive(dst, h, streams, headerOptions{compress: true})
+}
@@ -0,0 +1,134 @@
+package sevenzip_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func normalize(t *testing.T, r *sevenzip.Reader, opts ...sevenzip.NormalizeOption) []byte {
+	t.Helper()
+
+	b := new(bytes.Buffer)
+	require.NoError(t, sevenzip.Normalize(r, b, append([]sevenzip.NormalizeOption{sevenzip.WithTempDir(t.TempDir())}, opts...)...))
+
+	return b.Bytes()
+}
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+
+	tables := []struct {
+		name, file, password string
+	}{
+		{
+			name: "t0",
+			file: "t0.7z",
+		},
+		{
+			name: "t1",
+			file: "t1.7z",
+		},
+		{
+			name: "copy",
+			file: "copy.7z",
+		},
+		{
+			name: "empty",
+			file: "empty.7z",
+		},
+		{
+			name: "file and empty",
+			file: "file_and_empty.7z",
+		},
+		{
+			name:     "encrypted",
+			file:     "t3.7z",
+			password: "password",
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			src, err := sevenzip.OpenReaderWithPassword(filepath.Join("testdata", table.file), table.password)
+			require.NoError(t, err)
+
+			defer func() {
+				require.NoError(t, src.Close())
+			}()
+
+			b := normalize(t, &src.Reader)
+
+			// Output is reproducible
+			assert.Equal(t, b, normalize(t, &src.Reader))
+
+			dst, err := sevenzip.NewReader(bytes.NewReader(b), int64(len(b)))
+			require.NoError(t, err)
+
+			expected := summarise(t, &src.Reader)
+			sort.SliceStable(expected, func(i, j int) bool {
+				return expected[i].name < expected[j].name
+			})
+
+			assert.Equal(t, expected, summarise(t, dst))
+
+			for _, f := range dst.File {
+				assert.True(t, f.Modified.IsZero())
+				assert.True(t, f.Created.IsZero())
+				assert.True(t, f.Accessed.IsZero())
+			}
+
+			// Normalizing the normalized archive changes nothing
+			assert.Equal(t, b, normalize(t, dst))
+		})
+	}
+}
+
+func TestNormalizeModTime(t *testing.T) {
+	t.Parallel()
+
+	src, err := sevenzip.OpenReader(filepath.Join("testdata", "t1.7z"))
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, src.Close())
+	}()
+
+	mtime := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	b := normalize(t, &src.Reader, sevenzip.WithModTime(mtime))
+
+	dst, err := sevenzip.NewReader(bytes.NewReader(b), int64(len(b)))
+	require.NoError(t, err)
+
+	for _, f := range dst.File {
+		assert.True(t, mtime.Equal(f.Modified))
+	}
+}
+
+func TestNormalizeSameContents(t *testing.T) {
+	t.Parallel()
+
+	src, err := sevenzip.OpenReaderWithPassword(filepath.Join("testdata", "t4.7z"), "password")
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, src.Close())
+	}()
+
+	b := new(bytes.Buffer)
+	require.NoError(t, sevenzip.RemoveEncryption(&src.Reader, b, "password"))
+
+	decrypted, err := sevenzip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	require.NoError(t, err)
+
+	assert.Equal(t, normalize(t, &src.Reader), normalize(t, decrypted))
+}
@@ -0,0 +1,306 @@
+package sevenzip
+
+import (
+	"crypto/cipher"
+	"errors"
+	"runtime"
+
+	"github.com/spf13/afero"
+)
+
+// ErrLimitExceeded is returned when an archive exceeds one of the limits
+// configured with [WithMaxFiles], [WithMaxHeaderSize] or
+// [WithMaxUnpackedSize], or when extraction exceeds one of the limits
+// configured with [WithMaxOutputBytes] or [WithMaxOutputFiles].
+var ErrLimitExceeded = errors.New("sevenzip: limit exceeded")
+
+const defaultReadAhead = 4096
+
+// A CBCDecrypterFunc returns a [cipher.BlockMode] that decrypts using
+// AES-256 in CBC mode with the given key and initialisation vector.
+type CBCDecrypterFunc func(key, iv []byte) (cipher.BlockMode, error)
+
+type readerOptions struct {
+	password          string
+	cbc               CBCDecrypterFunc
+	fs                afero.Fs
+	concurrency       int
+	readAhead         int
+	poolSize          int
+	maxFiles          int
+	maxHeaderSize     uint64
+	maxUnpackedSize   uint64
+	baseOffset        int64
+	searchLimit       int64
+	nameFilter        func(string) bool
+	volumeNamer       func(string, int) string
+	strictVersion     bool
+	audit             bool
+	normalizePassword bool
+	truncateOversized bool
+}
+
+func newReaderOptions(opts []ReaderOption) readerOptions {
+	o := readerOptions{
+		concurrency: runtime.NumCPU(),
+		readAhead:   defaultReadAhead,
+		poolSize:    runtime.NumCPU(),
+		baseOffset:  -1,
+		searchLimit: searchLimit,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// A ReaderOption configures how an archive is opened and read. Options are
+// applied in order so later options override earlier ones, which allows a
+// [Profile] to be used as a base and then tweaked.
+type ReaderOption func(*readerOptions)
+
+// WithPassword sets the password used as the basis of the decryption key.
+func WithPassword(password string) ReaderOption {
+	return func(o *readerOptions) {
+		o.password = password
+	}
+}
+
+// WithCBCDecrypter replaces the crypto/aes implementation used to decrypt
+// AES encrypted archives, for example with a FIPS-validated or hardware
+// backed implementation. The key derivation is unchanged.
+func WithCBCDecrypter(fn CBCDecrypterFunc) ReaderOption {
+	return func(o *readerOptions) {
+		o.cbc = fn
+	}
+}
+
+// WithFs sets the filesystem used to open the archive and any additional
+// volumes. If not specified, the default OS filesystem is used.
+func WithFs(fs afero.Fs) ReaderOption {
+	return func(o *readerOptions) {
+		o.fs = fs
+	}
+}
+
+// WithConcurrency sets the maximum number of streams that [Reader.Extract]
+// will decode at the same time. Values less than one are treated as one.
+func WithConcurrency(n int) ReaderOption {
+	return func(o *readerOptions) {
+		o.concurrency = max(n, 1)
+	}
+}
+
+// WithReadAhead sets the size of the buffer used when reading each packed
+// stream from the underlying archive. Larger values reduce the number of
+// reads issued against slow or remote storage at the cost of memory.
+func WithReadAhead(n int) ReaderOption {
+	return func(o *readerOptions) {
+		o.readAhead = max(n, 16) //nolint:mnd
+	}
+}
+
+// WithCacheSize sets the number of partially read decoders kept per stream
+// so that reading files out of order doesn't always restart decoding from the
+// beginning of the stream. Each cached decoder holds its own dictionary so
+// this has the biggest effect on memory usage.
+func WithCacheSize(n int) ReaderOption {
+	return func(o *readerOptions) {
+		o.poolSize = max(n, 1)
+	}
+}
+
+// WithMaxFiles limits the number of entries an archive may declare. Zero
+// means no limit.
+func WithMaxFiles(n int) ReaderOption {
+	return func(o *readerOptions) {
+		o.maxFiles = n
+	}
+}
+
+// WithMaxHeaderSize limits the size of the archive header, after any
+// decompression. Zero means no limit.
+func WithMaxHeaderSize(n uint64) ReaderOption {
+	return func(o *readerOptions) {
+		o.maxHeaderSize = n
+	}
+}
+
+// WithMaxUnpackedSize limits the total uncompressed size of all streams an
+// archive may declare. Zero means no limit.
+func WithMaxUnpackedSize(n uint64) ReaderOption {
+	return func(o *readerOptions) {
+		o.maxUnpackedSize = n
+	}
+}
+
+// WithBaseOffset sets the offset of the archive within the file, skipping the
+// search for the signature. By default only the first 1 MiB of the file is
+// searched so this is needed for archives appended to a larger payload, such
+// as a disk image or installer, unless the search is widened with
+// [WithSearchLimit]. Negative values restore the search.
+func WithBaseOffset(offset int64) ReaderOption {
+	return func(o *readerOptions) {
+		o.baseOffset = offset
+	}
+}
+
+// WithSearchLimit sets how far into the file the signature is searched for
+// when no offset is given with [WithBaseOffset], 1 MiB by default. Raise it
+// for self-extracting installers whose stub and configuration are larger
+// than that. Zero or less searches the whole file.
+func WithSearchLimit(n int64) ReaderOption {
+	return func(o *readerOptions) {
+		o.searchLimit = n
+	}
+}
+
+// WithNameFilter only adds files whose name matches fn to [Reader.File],
+// which cuts the time and memory needed to open archives with a very large
+// number of entries when only a few are wanted. Directory names have a
+// trailing slash. The rest of the archive can't be reached through the
+// reader and it can't be rewritten with functions such as [Normalize] or
+// [ReEncrypt].
+func WithNameFilter(fn func(name string) bool) ReaderOption {
+	return func(o *readerOptions) {
+		o.nameFilter = fn
+	}
+}
+
+// WithVolumeNamer sets the function used to find the volumes following the
+// first when opening an archive by name, for naming schemes other than the
+// default ".001", ".002" and so on. It is called with the name of the first
+// volume and the number of each following volume, counting the first as
+// one, until the volumes hold the whole archive. When set, volumes are
+// looked for regardless of the suffix of the first name.
+func WithVolumeNamer(fn func(base string, index int) string) ReaderOption {
+	return func(o *readerOptions) {
+		o.volumeNamer = fn
+	}
+}
+
+// WithStrictVersion refuses archives with a newer minor format version than
+// 0.4 with [ErrUnsupportedVersion]. By default they are read as long as the
+// header can be parsed, with a warning recorded in [ArchiveInfo.Warnings],
+// as 7-Zip only bumps the minor version for additions older readers can
+// mostly ignore.
+func WithStrictVersion() ReaderOption {
+	return func(o *readerOptions) {
+		o.strictVersion = true
+	}
+}
+
+// WithAudit records the ranges of the archive read to satisfy each call to
+// [File.Open], which can be retrieved with [File.ReadRanges] afterwards, to
+// check that offsets recorded elsewhere match what the decoders actually
+// read. It adds a little overhead to every read so is off by default.
+func WithAudit() ReaderOption {
+	return func(o *readerOptions) {
+		o.audit = true
+	}
+}
+
+// WithTruncateOversized reads archives where the size declared for a file
+// is more than what is left of the decoded output of the block holding it,
+// which otherwise fails to open with an [OversizedError]. The size of each
+// such file is cut down to what the block can provide instead, with a
+// warning recorded in [ArchiveInfo.Warnings] naming the file and both
+// sizes.
+func WithTruncateOversized() ReaderOption {
+	return func(o *readerOptions) {
+		o.truncateOversized = true
+	}
+}
+
+// A Profile is a preset combination of options suited to a particular kind
+// of workload.
+type Profile int
+
+const (
+	// ProfileDefault uses the same settings as when no options are given.
+	ProfileDefault Profile = iota
+	// ProfileLowMemory decodes one stream at a time with minimal buffering
+	// and caching, for constrained environments.
+	ProfileLowMemory
+	// ProfileServer favours throughput with large read-ahead buffers and
+	// decodes as many streams in parallel as there are CPUs.
+	ProfileServer
+	// ProfileInteractive favours latency when accessing individual files
+	// in a random order, such as when serving an archive as a filesystem.
+	ProfileInteractive
+	// ProfileUntrusted applies conservative limits for archives from
+	// untrusted sources, guarding against resource exhaustion.
+	ProfileUntrusted
+)
+
+const (
+	kiB = 1 << 10
+	miB = 1 << 20
+	giB = 1 << 30
+)
+
+// WithProfile applies the options bundled by profile p. Every profile sets
+// the limits as well, so a later profile replaces those of an earlier one.
+//
+//nolint:mnd,funlen
+func WithProfile(p Profile) ReaderOption {
+	return func(o *readerOptions) {
+		cpus := runtime.NumCPU()
+
+		var opts []ReaderOption
+
+		switch p {
+		case ProfileDefault:
+			opts = []ReaderOption{
+				WithConcurrency(cpus),
+				WithReadAhead(defaultReadAhead),
+				WithCacheSize(cpus),
+				WithMaxFiles(0),
+				WithMaxHeaderSize(0),
+				WithMaxUnpackedSize(0),
+			}
+		case ProfileLowMemory:
+			opts = []ReaderOption{
+				WithConcurrency(1),
+				WithReadAhead(defaultReadAhead),
+				WithCacheSize(1),
+				WithMaxFiles(0),
+				WithMaxHeaderSize(0),
+				WithMaxUnpackedSize(0),
+			}
+		case ProfileServer:
+			opts = []ReaderOption{
+				WithConcurrency(cpus),
+				WithReadAhead(1 * miB),
+				WithCacheSize(cpus),
+				WithMaxFiles(0),
+				WithMaxHeaderSize(0),
+				WithMaxUnpackedSize(0),
+			}
+		case ProfileInteractive:
+			opts = []ReaderOption{
+				WithConcurrency(min(cpus, 2)),
+				WithReadAhead(64 * kiB),
+				WithCacheSize(2 * cpus),
+				WithMaxFiles(0),
+				WithMaxHeaderSize(0),
+				WithMaxUnpackedSize(0),
+			}
+		case ProfileUntrusted:
+			opts = []ReaderOption{
+				WithConcurrency(min(cpus, 4)),
+				WithReadAhead(defaultReadAhead),
+				WithCacheSize(2),
+				WithMaxFiles(1 << 20),
+				WithMaxHeaderSize(64 * miB),
+				WithMaxUnpackedSize(16 * giB),
+			}
+		}
+
+		for _, opt := range opts {
+			opt(o)
+		}
+	}
+}
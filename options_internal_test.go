@@ -0,0 +1,18 @@
+package sevenzip
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfileDefaultRestores(t *testing.T) {
+	t.Parallel()
+
+	want := newReaderOptions(nil)
+
+	for _, p := range []Profile{ProfileLowMemory, ProfileServer, ProfileInteractive, ProfileUntrusted} {
+		got := newReaderOptions([]ReaderOption{WithProfile(p), WithProfile(ProfileDefault)})
+		assert.Equal(t, want, got, p)
+	}
+}
@@ -0,0 +1,171 @@
+package sevenzip_test
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"hash/crc32"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfiles(t *testing.T) {
+	t.Parallel()
+
+	profiles := map[string]sevenzip.Profile{
+		"default":     sevenzip.ProfileDefault,
+		"low memory":  sevenzip.ProfileLowMemory,
+		"server":      sevenzip.ProfileServer,
+		"interactive": sevenzip.ProfileInteractive,
+		"untrusted":   sevenzip.ProfileUntrusted,
+	}
+
+	for name, profile := range profiles {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := sevenzip.OpenReaderWithOptions(filepath.Join("testdata", "t1.7z"), sevenzip.WithProfile(profile))
+			require.NoError(t, err)
+
+			defer func() {
+				require.NoError(t, r.Close())
+			}()
+
+			require.NoError(t, extractArchive(t, &r.Reader, -1, crc32.NewIEEE(), iotest.OneByteReader, true))
+		})
+	}
+}
+
+func TestLimits(t *testing.T) {
+	t.Parallel()
+
+	tables := []struct {
+		name, file string
+		opts       []sevenzip.ReaderOption
+		err        error
+	}{
+		{
+			name: "within limits",
+			file: "lzma1900.7z",
+			opts: []sevenzip.ReaderOption{sevenzip.WithProfile(sevenzip.ProfileUntrusted)},
+		},
+		{
+			name: "too many files",
+			file: "lzma1900.7z",
+			opts: []sevenzip.ReaderOption{sevenzip.WithMaxFiles(10)},
+			err:  sevenzip.ErrLimitExceeded,
+		},
+		{
+			name: "header too large",
+			file: "t0.7z",
+			opts: []sevenzip.ReaderOption{sevenzip.WithMaxHeaderSize(16)},
+			err:  sevenzip.ErrLimitExceeded,
+		},
+		{
+			name: "compressed header too large",
+			file: "t1.7z",
+			opts: []sevenzip.ReaderOption{sevenzip.WithMaxHeaderSize(16)},
+			err:  sevenzip.ErrLimitExceeded,
+		},
+		{
+			name: "too much unpacked data",
+			file: "lzma1900.7z",
+			opts: []sevenzip.ReaderOption{sevenzip.WithMaxUnpackedSize(1024)},
+			err:  sevenzip.ErrLimitExceeded,
+		},
+		{
+			name: "profile overridden",
+			file: "lzma1900.7z",
+			opts: []sevenzip.ReaderOption{
+				sevenzip.WithProfile(sevenzip.ProfileUntrusted),
+				sevenzip.WithMaxFiles(10),
+			},
+			err: sevenzip.ErrLimitExceeded,
+		},
+		{
+			name: "limits reset by default profile",
+			file: "lzma1900.7z",
+			opts: []sevenzip.ReaderOption{
+				sevenzip.WithMaxFiles(10),
+				sevenzip.WithMaxUnpackedSize(1024),
+				sevenzip.WithProfile(sevenzip.ProfileDefault),
+			},
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := sevenzip.OpenReaderWithOptions(filepath.Join("testdata", table.file), table.opts...)
+			if table.err != nil {
+				assert.ErrorIs(t, err, table.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.NoError(t, r.Close())
+		})
+	}
+}
+
+func TestWithCBCDecrypter(t *testing.T) {
+	t.Parallel()
+
+	for _, file := range []string{"t2.7z", "t3.7z"} {
+		t.Run(file, func(t *testing.T) {
+			t.Parallel()
+
+			var calls atomic.Int32
+
+			fn := func(key, iv []byte) (cipher.BlockMode, error) {
+				calls.Add(1)
+
+				block, err := aes.NewCipher(key)
+				if err != nil {
+					return nil, err
+				}
+
+				return cipher.NewCBCDecrypter(block, iv), nil
+			}
+
+			r, err := sevenzip.OpenReaderWithOptions(filepath.Join("testdata", file),
+				sevenzip.WithPassword("password"), sevenzip.WithCBCDecrypter(fn))
+			require.NoError(t, err)
+
+			defer func() {
+				require.NoError(t, r.Close())
+			}()
+
+			require.NoError(t, extractArchive(t, &r.Reader, -1, crc32.NewIEEE(), iotest.OneByteReader, true))
+			assert.Positive(t, calls.Load())
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+
+		errCipher := errors.New("cipher unavailable")
+
+		r, err := sevenzip.OpenReaderWithOptions(filepath.Join("testdata", "t5.7z"),
+			sevenzip.WithPassword("password"),
+			sevenzip.WithCBCDecrypter(func(_, _ []byte) (cipher.BlockMode, error) {
+				return nil, errCipher
+			}))
+		require.NoError(t, err)
+
+		defer func() {
+			require.NoError(t, r.Close())
+		}()
+
+		_, err = r.File[0].Open()
+		assert.ErrorIs(t, err, errCipher)
+	})
+}
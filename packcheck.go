@@ -0,0 +1,152 @@
+package sevenzip
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var (
+	errPackBeyond  = errors.New("sevenzip: packed stream extends beyond the end of the archive")
+	errPackHeader  = errors.New("sevenzip: packed stream overlaps the header")
+	errPackOverlap = errors.New("sevenzip: packed stream overlaps another")
+	errPackMissing = errors.New("sevenzip: block uses an undeclared packed stream")
+)
+
+// A PackError is returned when the header of an archive declares packed
+// streams that can't be where it says they are, such as ones overlapping
+// each other or extending beyond the end of the archive, which only happens
+// with corrupt or crafted archives. Err wraps the reason.
+type PackError struct {
+	// Stream is the index of the packed stream.
+	Stream int
+	// Header is set if the stream holds an encoded header rather than
+	// the contents of files.
+	Header bool
+	// Offset is the position the stream is declared at, relative to the
+	// start of the archive, and Size its declared size.
+	Offset int64
+	Size   uint64
+	Err    error
+}
+
+func (e *PackError) Error() string {
+	kind := "packed stream"
+	if e.Header {
+		kind = "header packed stream"
+	}
+
+	return fmt.Sprintf("sevenzip: %s %d at %d, %d bytes: %v", kind, e.Stream, e.Offset, e.Size, e.Err)
+}
+
+func (e *PackError) Unwrap() error {
+	return e.Err
+}
+
+// packRange is where a packed stream lies within the streams data.
+type packRange struct {
+	start, end uint64
+	stream     int
+	header     bool
+}
+
+func (z *Reader) packError(r packRange, size uint64, err error) *PackError {
+	return &PackError{
+		Stream: r.stream,
+		Header: r.header,
+		Offset: z.start - z.base + int64(r.start), //nolint:gosec
+		Size:   size,
+		Err:    err,
+	}
+}
+
+// packRanges returns where the packed streams of si lie, which holds an
+// encoded header if header is set, checking that each of them lies before
+// the header and that there are enough for every folder.
+func (z *Reader) packRanges(si *streamsInfo, header bool) ([]packRange, error) {
+	if si == nil || si.packInfo == nil {
+		return nil, nil
+	}
+
+	var (
+		ranges = make([]packRange, 0, len(si.packInfo.size))
+		area   = uint64(z.end - z.start)  //nolint:gosec
+		total  = uint64(z.size - z.start) //nolint:gosec
+		offset = si.packInfo.position
+	)
+
+	for k, size := range si.packInfo.size {
+		r := packRange{start: offset, stream: k, header: header}
+
+		switch {
+		case offset > total || size > total-offset:
+			return nil, z.packError(r, size, errPackBeyond)
+		case offset+size > area:
+			return nil, z.packError(r, size, errPackHeader)
+		}
+
+		r.end = offset + size
+
+		ranges = append(ranges, r)
+		offset = r.end
+	}
+
+	var used uint64
+
+	if si.unpackInfo != nil {
+		for _, f := range si.unpackInfo.folder {
+			used += f.packedStreams
+		}
+	}
+
+	if n := uint64(len(si.packInfo.size)); used > n {
+		return nil, z.packError(packRange{start: offset, stream: int(n), header: header}, 0, errPackMissing) //nolint:gosec
+	}
+
+	return ranges, nil
+}
+
+// checkPacked checks the packed streams of the files and of every level of
+// encoded header don't overlap each other or the header itself.
+func (z *Reader) checkPacked(si *streamsInfo) error {
+	ranges, err := z.packRanges(si, false)
+	if err != nil {
+		return err
+	}
+
+	for _, hsi := range z.headerChain {
+		hr, err := z.packRanges(hsi, true)
+		if err != nil {
+			return err
+		}
+
+		ranges = append(ranges, hr...)
+	}
+
+	slices.SortStableFunc(ranges, func(a, b packRange) int {
+		switch {
+		case a.start < b.start:
+			return -1
+		case a.start > b.start:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	var end uint64
+
+	for _, r := range ranges {
+		if r.start == r.end {
+			continue
+		}
+
+		if r.start < end {
+			return z.packError(r, r.end-r.start, errPackOverlap)
+		}
+
+		end = r.end
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package sevenzip
+
+import (
+	"errors"
+	"slices"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// WithPasswordNormalization retries a password that fails to decrypt the
+// archive with its Unicode NFC and NFD normalizations, where they differ
+// from it, before reporting it as wrong. The same characters typed on
+// different systems can be encoded differently, such as precomposed on
+// Windows and decomposed on macOS, and the key is derived from the exact
+// code points. The variant that works is used for the rest of the archive.
+//
+// If the file names aren't encrypted, picking a variant means decoding the
+// smallest encrypted block when the archive is opened, which only happens
+// when the password has variants at all, so never for ASCII passwords.
+func WithPasswordNormalization() ReaderOption {
+	return func(o *readerOptions) {
+		o.normalizePassword = true
+	}
+}
+
+// passwords returns the password followed by any variants to try if it's
+// wrong.
+func (z *Reader) passwords() []string {
+	passwords := []string{z.p}
+
+	if !z.opts.normalizePassword || z.p == "" {
+		return passwords
+	}
+
+	for _, p := range []string{norm.NFC.String(z.p), norm.NFD.String(z.p)} {
+		if !slices.Contains(passwords, p) {
+			passwords = append(passwords, p)
+		}
+	}
+
+	return passwords
+}
+
+// matchPassword picks the variant of the password that decrypts the
+// smallest encrypted block, if decrypting the header didn't already pick
+// one. If none of them do the password is left as given so that the error
+// is reported as usual when reading.
+func (z *Reader) matchPassword() {
+	passwords := z.passwords()
+	if len(passwords) < 2 || z.encryptedHeader || z.si == nil || z.si.unpackInfo == nil {
+		return
+	}
+
+	// Only blocks with every file listed can be checked, which they might
+	// not be with a name filter
+	listed := make(map[int]uint64)
+
+	for _, f := range z.File {
+		if f.HasStream() {
+			listed[f.folder]++
+		}
+	}
+
+	folder := -1
+
+	for i, f := range z.si.unpackInfo.folder {
+		streams := uint64(1)
+		if z.si.subStreamsInfo != nil {
+			streams = z.si.subStreamsInfo.streams[i]
+		}
+
+		if !slices.ContainsFunc(f.coder, (*coder).isAES) || listed[i] != streams {
+			continue
+		}
+
+		if folder < 0 || f.unpackSize() < z.si.unpackInfo.folder[folder].unpackSize() {
+			folder = i
+		}
+	}
+
+	if folder < 0 {
+		return
+	}
+
+	for _, p := range passwords {
+		err := z.verifyFolder(folder, p)
+		if err == nil {
+			z.p = p
+
+			return
+		}
+
+		if re := new(ReadError); !errors.As(err, &re) || !re.Encrypted {
+			return
+		}
+	}
+}
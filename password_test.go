@@ -0,0 +1,162 @@
+package sevenzip_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/unicode/norm"
+)
+
+// encryptedArchive returns an archive of a couple of files encrypted with
+// password.
+func encryptedArchive(t *testing.T, password string, opts ...sevenzip.WriterOption) []byte {
+	t.Helper()
+
+	buf := new(bytes.Buffer)
+	w := sevenzip.NewWriter(buf, append([]sevenzip.WriterOption{sevenzip.WithWriterPassword(password)}, opts...)...)
+
+	for _, name := range []string{"a.txt", "b.txt"} {
+		fw, err := w.Create(name)
+		require.NoError(t, err)
+
+		_, err = io.WriteString(fw, "contents of "+name)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
+
+func checkContents(r *sevenzip.Reader) error {
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+
+		got, err := io.ReadAll(rc)
+		err = errors.Join(err, rc.Close())
+
+		if err != nil {
+			return err
+		}
+
+		if string(got) != "contents of "+f.Name {
+			return errors.New("wrong contents")
+		}
+	}
+
+	return nil
+}
+
+//nolint:funlen
+func TestPasswordNormalization(t *testing.T) {
+	t.Parallel()
+
+	const password = "Grüße, café"
+
+	nfc, nfd := norm.NFC.String(password), norm.NFD.String(password)
+	require.NotEqual(t, nfc, nfd)
+
+	tables := []struct {
+		name      string
+		written   string
+		given     string
+		header    bool
+		normalize bool
+		ok        bool
+	}{
+		{
+			name:    "same",
+			written: nfc,
+			given:   nfc,
+			ok:      true,
+		},
+		{
+			name:    "nfd without option",
+			written: nfc,
+			given:   nfd,
+		},
+		{
+			name:      "nfd",
+			written:   nfc,
+			given:     nfd,
+			normalize: true,
+			ok:        true,
+		},
+		{
+			name:      "nfc",
+			written:   nfd,
+			given:     nfc,
+			normalize: true,
+			ok:        true,
+		},
+		{
+			name:    "header without option",
+			written: nfc,
+			given:   nfd,
+			header:  true,
+		},
+		{
+			name:      "header",
+			written:   nfc,
+			given:     nfd,
+			header:    true,
+			normalize: true,
+			ok:        true,
+		},
+		{
+			name:      "wrong",
+			written:   nfc,
+			given:     "Grosse, cafe",
+			normalize: true,
+		},
+		{
+			name:      "wrong header",
+			written:   nfc,
+			given:     norm.NFD.String("Größe"),
+			header:    true,
+			normalize: true,
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			var wopts []sevenzip.WriterOption
+			if table.header {
+				wopts = append(wopts, sevenzip.WithEncryptedHeader())
+			}
+
+			b := encryptedArchive(t, table.written, wopts...)
+
+			opts := []sevenzip.ReaderOption{sevenzip.WithPassword(table.given)}
+			if table.normalize {
+				opts = append(opts, sevenzip.WithPasswordNormalization())
+			}
+
+			r, err := sevenzip.NewReaderWithOptions(bytes.NewReader(b), int64(len(b)), opts...)
+			if err == nil {
+				err = checkContents(r)
+			}
+
+			if table.ok {
+				assert.NoError(t, err)
+
+				return
+			}
+
+			var re *sevenzip.ReadError
+			if assert.ErrorAs(t, err, &re) {
+				assert.True(t, re.Encrypted)
+			}
+		})
+	}
+}
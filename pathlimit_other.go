@@ -0,0 +1,13 @@
+//go:build !windows
+
+package sevenzip
+
+// maxPathLen is the longest path that can be passed to a system call, the
+// same as PATH_MAX on Linux less the terminating NUL. Other systems with a
+// lower limit report ENAMETOOLONG which is handled the same way.
+const maxPathLen = 4095
+
+// pathLen returns the length of s in bytes.
+func pathLen(s string) int {
+	return len(s)
+}
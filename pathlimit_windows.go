@@ -0,0 +1,13 @@
+package sevenzip
+
+import "unicode/utf16"
+
+// maxPathLen is the longest path that can be created, Go adds the \\?\
+// prefix to long paths so the limit is that of the Windows API.
+const maxPathLen = 32767
+
+// pathLen returns the length of s as counted by Windows, in UTF-16 code
+// units.
+func pathLen(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
@@ -0,0 +1,56 @@
+package sevenzip
+
+// selectedBlocks returns the number of bytes that have to be decoded from
+// each block to reach the end of the last of the selected files stored in
+// it. Files that aren't stored in a block, or belong to another reader, are
+// ignored.
+func (z *Reader) selectedBlocks(selected []*File) map[int]uint64 {
+	blocks := make(map[int]uint64)
+
+	for _, f := range selected {
+		if f.zip != z || !f.HasStream() {
+			continue
+		}
+
+		end := uint64(f.offset) + f.UncompressedSize //nolint:gosec
+		blocks[f.folder] = max(blocks[f.folder], end)
+	}
+
+	return blocks
+}
+
+// TotalUncompressedSize returns the number of bytes that have to be decoded
+// to extract the selected files, which is more than the sum of their sizes
+// when they are stored in solid blocks. Every file in a block before the
+// last selected file has to be decoded too, however each block only counts
+// once regardless of how many of its files are selected, matching how
+// [Reader.Extract] and reading the files in archive order decode each block
+// from the start. It can be used as the total of a progress bar that is
+// advanced by the bytes read from each file plus any bytes skipped over.
+func (z *Reader) TotalUncompressedSize(selected []*File) uint64 {
+	var total uint64
+
+	for _, size := range z.selectedBlocks(selected) {
+		total += size
+	}
+
+	return total
+}
+
+// TotalCompressedToRead returns the number of bytes of packed streams that
+// have to be read from the archive to extract the selected files. Each block
+// containing a selected file counts once with its whole packed size, as
+// returned by [Reader.Blocks]. The decoder may stop short of the end of a
+// block if none of the files after the last selected one are needed, so this
+// is exact when whole blocks are extracted and an upper bound otherwise.
+func (z *Reader) TotalCompressedToRead(selected []*File) uint64 {
+	var total uint64
+
+	_, packedSizes := z.si.folderOffsets()
+
+	for folder := range z.selectedBlocks(selected) {
+		total += packedSizes[folder]
+	}
+
+	return total
+}
@@ -0,0 +1,99 @@
+package sevenzip_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTotals(t *testing.T) {
+	t.Parallel()
+
+	r, err := sevenzip.OpenReader(filepath.Join("testdata", "lzma1900.7z"))
+	require.NoError(t, err)
+
+	defer r.Close()
+
+	blocks := r.Blocks()
+	require.Greater(t, len(blocks), 1)
+
+	var (
+		first, last = make(map[int]*sevenzip.File), make(map[int]*sevenzip.File)
+		files       []*sevenzip.File
+	)
+
+	for _, f := range r.File {
+		if f.Coders() == nil {
+			continue
+		}
+
+		if _, ok := first[f.Stream]; !ok {
+			first[f.Stream] = f
+		}
+
+		last[f.Stream] = f
+		files = append(files, f)
+	}
+
+	var unpacked, packed uint64
+
+	for _, b := range blocks {
+		unpacked += b.UnpackedSize
+		packed += b.PackedSize
+	}
+
+	other, err := sevenzip.OpenReader(filepath.Join("testdata", "t1.7z"))
+	require.NoError(t, err)
+
+	defer other.Close()
+
+	tables := []struct {
+		name             string
+		selected         []*sevenzip.File
+		unpacked, packed uint64
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:     "all",
+			selected: files,
+			unpacked: unpacked,
+			packed:   packed,
+		},
+		{
+			name:     "first of block",
+			selected: []*sevenzip.File{first[0]},
+			unpacked: first[0].UncompressedSize,
+			packed:   blocks[0].PackedSize,
+		},
+		{
+			name:     "last of block",
+			selected: []*sevenzip.File{last[0]},
+			unpacked: blocks[0].UnpackedSize,
+			packed:   blocks[0].PackedSize,
+		},
+		{
+			name:     "overlapping",
+			selected: []*sevenzip.File{first[0], last[0], last[0], first[1]},
+			unpacked: blocks[0].UnpackedSize + first[1].UncompressedSize,
+			packed:   blocks[0].PackedSize + blocks[1].PackedSize,
+		},
+		{
+			name:     "other reader",
+			selected: other.File,
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, table.unpacked, r.TotalUncompressedSize(table.selected))
+			assert.Equal(t, table.packed, r.TotalCompressedToRead(table.selected))
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package sevenzip
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// A RawReader reads the contents of a file without any verification, as
+// returned by [File.OpenRaw].
+type RawReader struct {
+	rc *folderReadCloser
+	n  int64
+
+	// Packed reads the packed streams of the block holding the file, as
+	// stored in the archive, one after the other. It is only set when
+	// the file is the only one in its block so the packed data belongs
+	// to it alone. [Block.PackedStreams] describes each stream.
+	Packed *io.SectionReader
+}
+
+// Read reads the decoded contents of the file. Unlike the reader returned by
+// [File.Open] any error from the decoders is returned along with the bytes
+// decoded before it, so as much of a damaged file as possible can be
+// recovered.
+func (r *RawReader) Read(p []byte) (int, error) {
+	if r.n <= 0 {
+		return 0, io.EOF
+	}
+
+	if int64(len(p)) > r.n {
+		p = p[:r.n]
+	}
+
+	n, err := r.rc.Read(p)
+	r.n -= int64(n)
+
+	if err != nil && !errors.Is(err, io.EOF) {
+		return n, readError(r.rc.hasEncryption, err)
+	}
+
+	if errors.Is(err, io.EOF) && r.n > 0 {
+		return n, readError(r.rc.hasEncryption, io.ErrUnexpectedEOF)
+	}
+
+	return n, err //nolint:wrapcheck
+}
+
+// Close closes the decoders.
+func (r *RawReader) Close() error {
+	if r.rc == nil {
+		return nil
+	}
+
+	if err := r.rc.Close(); err != nil {
+		return fmt.Errorf("sevenzip: error closing: %w", err)
+	}
+
+	return nil
+}
+
+// OpenRaw returns a [*RawReader] for the file's contents for forensic and
+// repair tools. The block holding the file is decoded from the start by a
+// new set of decoders, bypassing the cache used by [File.Open], and neither
+// the CRC32 of the file nor that of the block is checked so a file can be
+// read even if it is corrupt. The packed data of the block is also available
+// if the file is the only one in it.
+//
+// Directories and empty files return a reader with no contents.
+func (f *File) OpenRaw() (*RawReader, error) {
+	if f.zip.isClosed() {
+		return nil, errReaderClosed
+	}
+
+	if f.isMissing {
+		return nil, &ReadError{Err: errMissingUnpackInfo}
+	}
+
+	if !f.HasStream() {
+		return new(RawReader), nil
+	}
+
+	rc, _, _, encrypted, err := f.zip.folderReader(f.zip.si, f.folder)
+	if err != nil {
+		return nil, readError(encrypted, err)
+	}
+
+	if _, err := rc.Seek(f.offset, io.SeekStart); err != nil {
+		return nil, errors.Join(readError(encrypted, err), rc.Close())
+	}
+
+	r := &RawReader{
+		rc: rc,
+		n:  int64(f.UncompressedSize), //nolint:gosec
+	}
+
+	si := f.zip.si
+	if si.subStreamsInfo == nil || si.subStreamsInfo.streams[f.folder] == 1 {
+		k := si.packedIndex(f.folder)
+
+		var size uint64
+		for _, s := range si.packInfo.size[k : k+int(si.unpackInfo.folder[f.folder].packedStreams)] { //nolint:gosec
+			size += s
+		}
+
+		r.Packed = io.NewSectionReader(f.zip.streams(), si.packedOffset(k), int64(size)) //nolint:gosec
+	}
+
+	return r, nil
+}
@@ -0,0 +1,131 @@
+package sevenzip_test
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenRaw(t *testing.T) {
+	t.Parallel()
+
+	for _, file := range []string{"copy.7z", "lzma2.7z", "bcj2.7z"} {
+		t.Run(file, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := sevenzip.OpenReader(filepath.Join("testdata", file))
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				require.NoError(t, r.Close())
+			})
+
+			blocks := r.Blocks()
+
+			for _, f := range r.File {
+				rr, err := f.OpenRaw()
+				require.NoError(t, err)
+
+				b, err := io.ReadAll(rr)
+				require.NoError(t, err)
+				require.NoError(t, rr.Close())
+
+				assert.Equal(t, f.UncompressedSize, uint64(len(b)), f.Name)
+
+				if f.CRC32 != 0 {
+					assert.Equal(t, f.CRC32, crc32.ChecksumIEEE(b), f.Name)
+				}
+
+				if !f.HasStream() || blocks[f.Stream].NumFiles > 1 {
+					assert.Nil(t, rr.Packed, f.Name)
+				} else {
+					assert.NotNil(t, rr.Packed, f.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenRawPacked(t *testing.T) {
+	t.Parallel()
+
+	r, err := sevenzip.OpenReader(filepath.Join("testdata", "xor.7z"))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, r.Close())
+	})
+
+	f, ok := r.FileByName("a.txt")
+	require.True(t, ok)
+
+	rr, err := f.OpenRaw()
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, rr.Close())
+	})
+
+	require.NotNil(t, rr.Packed)
+
+	packed, err := io.ReadAll(rr.Packed)
+	require.NoError(t, err)
+
+	// The file is XORed with the key in the coder properties
+	for i := range packed {
+		packed[i] ^= 0x5a
+	}
+
+	assert.Equal(t, "The quick brown fox jumps over the lazy dog.\n", string(packed))
+}
+
+func TestOpenRawCorrupt(t *testing.T) {
+	t.Parallel()
+
+	r, err := sevenzip.OpenReader(filepath.Join("testdata", "copy.7z"))
+	require.NoError(t, err)
+
+	var (
+		f      *sevenzip.File
+		offset int64
+	)
+
+	for _, file := range r.File {
+		if offset, _, err = file.DataOffset(); err == nil && file.CRC32 != 0 {
+			f = file
+
+			break
+		}
+	}
+
+	require.NotNil(t, f)
+	require.NoError(t, r.Close())
+
+	archive, err := os.ReadFile(filepath.Join("testdata", "copy.7z"))
+	require.NoError(t, err)
+
+	archive[offset] ^= 0xff
+
+	zr, err := sevenzip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+
+	f, ok := zr.FileByName(f.Name)
+	require.True(t, ok)
+
+	rr, err := f.OpenRaw()
+	require.NoError(t, err)
+
+	b, err := io.ReadAll(rr)
+	require.NoError(t, err)
+	require.NoError(t, rr.Close())
+
+	assert.Equal(t, archive[offset:offset+int64(len(b))], b)
+	assert.NotEqual(t, f.CRC32, crc32.ChecksumIEEE(b))
+}
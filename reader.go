@@ -4,17 +4,22 @@ package sevenzip
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"hash/crc32"
 	"io"
 	iofs "io/fs"
+	"math"
+	"os"
 	"path"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bodgit/plumbing"
@@ -30,11 +35,49 @@ var (
 	errTooMuch         = errors.New("sevenzip: too much data")
 	errNegativeSize    = errors.New("sevenzip: size cannot be negative")
 	errOneHeaderStream = errors.New("sevenzip: expected only one folder in header stream")
+	errReaderClosed    = errors.New("sevenzip: reader closed")
+	errFiltered        = errors.New("sevenzip: archive opened with a name filter")
+	errChanged         = errors.New("sevenzip: archive has changed since it was opened")
+	errHeaderDepth     = errors.New("sevenzip: encoded header nested too deeply")
+)
+
+// maxHeaderDepth is the most levels of encoded header followed before the
+// header itself is reached. 7-Zip only writes one but other writers can
+// compress and encrypt the header as separate levels.
+const maxHeaderDepth = 4
+
+var (
+	// ErrPasswordRequired is returned when the header or a file is
+	// encrypted and no password was provided.
+	ErrPasswordRequired = errors.New("sevenzip: password required")
+	// ErrUnsupportedMethod is returned when the header or a file uses a
+	// method with no registered decompressor.
+	ErrUnsupportedMethod = errors.New("sevenzip: unsupported compression algorithm")
+	// ErrTruncated is returned when the archive is shorter than its start
+	// header says it should be, such as when the later volumes of a
+	// multi-volume archive are missing.
+	ErrTruncated = errors.New("sevenzip: archive is truncated")
+	// ErrUnsupportedVersion is returned for archives with a major format
+	// version other than zero, for archives using a format version other
+	// than 0.4 whose header can't be parsed, and for newer minor versions
+	// when [WithStrictVersion] is used.
+	ErrUnsupportedVersion = errors.New("sevenzip: unsupported format version")
+)
+
+// The format version written by current versions of 7-Zip. Older minor
+// versions use the same layout but were written by early releases that
+// may not follow it exactly.
+const (
+	formatMajorVersion = 0
+	formatMinorVersion = 4
 )
 
 // ReadError is used to wrap read I/O errors.
 type ReadError struct {
-	// Encrypted is a hint that there is encryption involved.
+	// Encrypted is a hint that there is encryption involved. Err then
+	// wraps a [HeaderPasswordError] or a [DataPasswordError] depending on
+	// whether it was the header or the contents of a file that couldn't
+	// be decrypted.
 	Encrypted bool
 	Err       error
 }
@@ -47,18 +90,135 @@ func (e ReadError) Unwrap() error {
 	return e.Err
 }
 
-// A Reader serves content from a 7-Zip archive.
+// An OversizedError is returned when opening a file that declares more bytes
+// than are left of the decoded output of the block holding it, which only
+// happens with corrupt or badly written archives. The rest of the archive
+// can still be read, or the file cut down to fit with
+// [WithTruncateOversized].
+type OversizedError struct {
+	// Name is the name of the file and Size the number of bytes it
+	// declares.
+	Name string
+	Size uint64
+	// Block is the index of the block holding the file, BlockSize the
+	// size of its decoded output and Left what is left of it for the file.
+	Block     int
+	BlockSize uint64
+	Left      uint64
+}
+
+func (e *OversizedError) Error() string {
+	return fmt.Sprintf("sevenzip: %s declares %d bytes but only %d are left of the %d bytes of block %d",
+		e.Name, e.Size, e.Left, e.BlockSize, e.Block)
+}
+
+// HeaderPasswordError is wrapped by a [ReadError] when the header of the
+// archive is encrypted and can't be decrypted, either because no password
+// was given, in which case it also wraps [ErrPasswordRequired], or because
+// the password is wrong. Nothing in the archive can be listed until the
+// right password is given.
+type HeaderPasswordError struct {
+	Err error
+}
+
+func (e HeaderPasswordError) Error() string {
+	return fmt.Sprintf("sevenzip: can't decrypt header: %v", e.Err)
+}
+
+func (e HeaderPasswordError) Unwrap() error {
+	return e.Err
+}
+
+// DataPasswordError is wrapped by a [ReadError] when the contents of a file
+// are encrypted and can't be decrypted, either because no password was
+// given, in which case it also wraps [ErrPasswordRequired], or because the
+// password is wrong. Decoding with the wrong password fails in all sorts of
+// ways so any error reading an encrypted block is reported like this. The
+// archive can still be listed, only extracting needs the right password.
+type DataPasswordError struct {
+	Err error
+}
+
+func (e DataPasswordError) Error() string {
+	return fmt.Sprintf("sevenzip: can't decrypt data: %v", e.Err)
+}
+
+func (e DataPasswordError) Unwrap() error {
+	return e.Err
+}
+
+// readError returns a [*ReadError] wrapping err from reading the contents
+// of files, marked as a [DataPasswordError] if the block is encrypted.
+func readError(encrypted bool, err error) *ReadError {
+	if encrypted {
+		err = &DataPasswordError{Err: err}
+	}
+
+	return &ReadError{Encrypted: encrypted, Err: err}
+}
+
+// headerReadError returns a [*ReadError] wrapping err from reading the
+// header, marked as a [HeaderPasswordError] if it is encrypted.
+func headerReadError(encrypted bool, err error) *ReadError {
+	if encrypted {
+		err = &HeaderPasswordError{Err: err}
+	}
+
+	return &ReadError{Encrypted: encrypted, Err: err}
+}
+
+// A Reader serves content from a 7-Zip archive. Once opened it is safe for
+// concurrent use by multiple goroutines without any external locking.
 type Reader struct {
 	r     io.ReaderAt
+	base  int64
 	start int64
 	end   int64
+	size  int64
 	si    *streamsInfo
 	p     string
+	opts  readerOptions
 	File  []*File
 	pool  []pool.Pooler
 
+	// The header as read, used when rewriting the archive
+	h               *header
+	encryptedHeader bool
+	headerPacked    uint64
+
+	// Each level of encoded header, outermost first
+	headerChain []*streamsInfo
+
+	// The start header, which identifies the archive, and the format
+	// version from the signature header
+	sh      startHeader
+	version [2]byte
+
+	// Problems found while opening the archive that didn't stop it being
+	// read
+	warnings []string
+
+	// Set by WithAudit
+	audit *audit
+
+	done      chan struct{}
+	closeOnce sync.Once
+	handles   atomic.Int64
+
 	fileListOnce sync.Once
 	fileList     []fileListEntry
+	trie         *pathNode
+
+	fileMapOnce sync.Once
+	fileMap     map[string]*File
+
+	// Decompressors registered with RegisterDecompressor and
+	// RegisterMultiDecompressor
+	decompressors      sync.Map
+	multiDecompressors sync.Map
+
+	// The size of each volume, if known
+	volumes []int64
 }
 
 // A ReadCloser is a [Reader] that must be closed when no longer needed.
@@ -72,9 +232,11 @@ type ReadCloser struct {
 // [File.Open].
 type File struct {
 	FileHeader
-	zip    *Reader
-	folder int
-	offset int64
+	zip       *Reader
+	folder    int
+	offset    int64
+	digest    *fileDigest
+	oversized *OversizedError
 }
 
 type fileReader struct {
@@ -92,6 +254,10 @@ func (fr *fileReader) Read(p []byte) (int, error) {
 		return 0, nil
 	}
 
+	if fr.f.zip.isClosed() {
+		return 0, errReaderClosed
+	}
+
 	if fr.n <= 0 {
 		return 0, io.EOF
 	}
@@ -104,15 +270,9 @@ func (fr *fileReader) Read(p []byte) (int, error) {
 	fr.n -= int64(n)
 
 	if err != nil && !errors.Is(err, io.EOF) {
-		e := &ReadError{
-			Err: err,
-		}
+		frc, ok := fr.rc.(*folderReadCloser)
 
-		if frc, ok := fr.rc.(*folderReadCloser); ok {
-			e.Encrypted = frc.hasEncryption
-		}
-
-		return n, e
+		return n, readError(ok && frc.hasEncryption, err)
 	}
 
 	return n, err //nolint:wrapcheck
@@ -123,35 +283,85 @@ func (fr *fileReader) Close() error {
 		return nil
 	}
 
-	offset, err := fr.rc.Seek(0, io.SeekCurrent)
+	rc := fr.rc
+	fr.rc = nil
+
+	fr.f.zip.handles.Add(-1)
+
+	offset, err := rc.Seek(0, io.SeekCurrent)
 	if err != nil {
-		return fmt.Errorf("sevenzip: error seeking current position: %w", err)
+		return errors.Join(fmt.Errorf("sevenzip: error seeking current position: %w", err), rc.Close())
 	}
 
-	if offset == fr.rc.Size() { // EOF reached
-		if err := fr.rc.Close(); err != nil {
+	if offset == rc.Size() { // EOF reached
+		if err := rc.Close(); err != nil {
 			return fmt.Errorf("sevenzip: error closing: %w", err)
 		}
 	} else {
 		f := fr.f
-		if _, err := f.zip.pool[f.folder].Put(offset, fr.rc); err != nil {
+		if _, err := f.zip.pool[f.folder].Put(offset, rc); err != nil {
 			return fmt.Errorf("sevenzip: error adding to pool: %w", err)
 		}
 	}
 
-	fr.rc = nil
-
 	return nil
 }
 
 // Open returns an [io.ReadCloser] that provides access to the [File]'s
-// contents. Multiple files may be read concurrently.
+// contents. It is safe to call from multiple goroutines, including for the
+// same File; each reader decodes independently, sharing only the underlying
+// [io.ReaderAt] and the cache of partially read decoders, so files can be
+// read concurrently without any external locking. The io.ReaderAt given to
+// [NewReader] must support concurrent calls to ReadAt, as [io.ReaderAt]
+// requires.
 func (f *File) Open() (io.ReadCloser, error) {
+	return f.open(nil)
+}
+
+// openContext is like Open, except that opening is also abandoned if ctx is
+// done while deriving the key for an encrypted file.
+func (f *File) openContext(ctx context.Context) (io.ReadCloser, error) {
+	if ctx.Done() == nil {
+		return f.open(nil)
+	}
+
+	// Closed either when ctx is done or once opened, whichever is first
+	done := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		close(done)
+	})
+
+	rc, err := f.open(done)
+
+	if stop() {
+		close(done)
+	} else if err != nil {
+		return nil, errors.Join(ctx.Err(), err)
+	}
+
+	return rc, err
+}
+
+// open opens the file, abandoning any key derivation when either the Reader
+// is closed or cancel is, if it isn't nil.
+func (f *File) open(cancel <-chan struct{}) (io.ReadCloser, error) {
 	if f.isEmptyStream || f.isEmptyFile {
 		// Return empty reader for directory or empty file
 		return io.NopCloser(bytes.NewReader(nil)), nil
 	}
 
+	if f.zip.isClosed() {
+		return nil, errReaderClosed
+	}
+
+	if f.isMissing {
+		return nil, &ReadError{Err: errMissingUnpackInfo}
+	}
+
+	if f.oversized != nil {
+		return nil, f.oversized
+	}
+
 	rc, _ := f.zip.pool[f.folder].Get(f.offset)
 	if rc == nil {
 		var (
@@ -159,27 +369,29 @@ func (f *File) Open() (io.ReadCloser, error) {
 			err       error
 		)
 
-		rc, _, encrypted, err = f.zip.folderReader(f.zip.si, f.folder)
+		cfg := f.zip.folderConfig()
+		if cancel != nil {
+			cfg.done = mergeDone(f.zip.done, cancel)
+		}
+
+		rc, _, _, encrypted, err = f.zip.folderReaderConfig(f.zip.si, f.folder, cfg)
 		if err != nil {
-			return nil, &ReadError{
-				Encrypted: encrypted,
-				Err:       err,
-			}
+			return nil, readError(encrypted, err)
 		}
 	}
 
-	if _, err := rc.Seek(f.offset, io.SeekStart); err != nil {
-		e := &ReadError{
-			Err: err,
-		}
+	if fr, ok := rc.(*folderReadCloser); ok && fr.audit != nil {
+		fr.audit.setFile(f)
+	}
 
-		if fr, ok := rc.(*folderReadCloser); ok {
-			e.Encrypted = fr.hasEncryption
-		}
+	if _, err := rc.Seek(f.offset, io.SeekStart); err != nil {
+		fr, ok := rc.(*folderReadCloser)
 
-		return nil, e
+		return nil, readError(ok && fr.hasEncryption, err)
 	}
 
+	f.zip.handles.Add(1)
+
 	return &fileReader{
 		rc: rc,
 		f:  f,
@@ -187,89 +399,161 @@ func (f *File) Open() (io.ReadCloser, error) {
 	}, nil
 }
 
-func openReader(fs afero.Fs, name string) (io.ReaderAt, int64, []afero.File, error) {
+// lockedReaderAt serialises calls to ReadAt, for files from filesystems such
+// as [afero.MemMapFs] whose ReadAt moves a shared offset and so isn't safe
+// for concurrent use.
+type lockedReaderAt struct {
+	mu sync.Mutex
+	r  io.ReaderAt
+}
+
+func (l *lockedReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.r.ReadAt(p, off) //nolint:wrapcheck
+}
+
+// concurrentReaderAt returns a reader for f that is safe for concurrent use.
+// An [*os.File] already is, anything else is serialised.
+func concurrentReaderAt(f afero.File) io.ReaderAt {
+	if _, ok := f.(*os.File); ok {
+		return f
+	}
+
+	return &lockedReaderAt{r: f}
+}
+
+// openReader opens name, along with any further volumes if it has a ".001"
+// suffix or namer is set, and returns a reader covering them all along with
+// the size of each volume.
+//
+// The number of volumes is worked out from the size of the archive recorded
+// in the start header of the first volume, so stale files following the last
+// volume aren't picked up. Only if the first volume doesn't start with the
+// signature, such as a self-extracting archive, are volumes opened until one
+// is missing.
+//
+//nolint:cyclop,funlen
+func openReader(fs afero.Fs, name string, namer func(string, int) string) (io.ReaderAt, []int64, []afero.File, error) {
+	var files []afero.File
+
+	fail := func(err error) (io.ReaderAt, []int64, []afero.File, error) {
+		errs := make([]error, 0, len(files)+1)
+		errs = append(errs, err)
+
+		for _, file := range files {
+			errs = append(errs, file.Close())
+		}
+
+		return nil, nil, nil, errors.Join(errs...)
+	}
+
 	f, err := fs.Open(filepath.Clean(name))
 	if err != nil {
-		return nil, 0, nil, fmt.Errorf("sevenzip: error opening: %w", err)
+		return nil, nil, nil, fmt.Errorf("sevenzip: error opening: %w", err)
 	}
 
+	files = append(files, f)
+
 	info, err := f.Stat()
 	if err != nil {
-		err = errors.Join(err, f.Close())
+		return fail(fmt.Errorf("sevenzip: error retrieving file info: %w", err))
+	}
+
+	reader := concurrentReaderAt(f)
+	sizes := []int64{info.Size()}
 
-		return nil, 0, nil, fmt.Errorf("sevenzip: error retrieving file info: %w", err)
+	if namer == nil && filepath.Ext(name) == ".001" {
+		namer = volumeName
 	}
 
-	var reader io.ReaderAt = f
+	if namer == nil {
+		return reader, sizes, files, nil
+	}
 
-	size := info.Size()
-	files := []afero.File{f}
+	// The total size of the archive, or -1 if it isn't known
+	total := int64(-1)
 
-	if ext := filepath.Ext(name); ext == ".001" {
-		sr := []readerutil.SizeReaderAt{io.NewSectionReader(f, 0, size)}
+	start, err := readStartHeader(io.NewSectionReader(reader, 0, sizes[0]))
 
-		for i := 2; true; i++ {
-			f, err := fs.Open(fmt.Sprintf("%s.%03d", strings.TrimSuffix(name, ext), i))
-			if err != nil {
-				if errors.Is(err, iofs.ErrNotExist) {
-					break
-				}
+	switch {
+	case err == nil:
+		total = signatureHeaderSize + int64(start.Offset) + int64(start.Size) //nolint:gosec
+	case !errors.Is(err, errFormat):
+		return fail(err)
+	}
 
-				errs := make([]error, 0, len(files)+1)
-				errs = append(errs, err)
+	sr := []readerutil.SizeReaderAt{io.NewSectionReader(reader, 0, sizes[0])}
 
-				for _, file := range files {
-					errs = append(errs, file.Close())
-				}
+	for i := 2; total < 0 || sum(sizes) < total; i++ {
+		n := filepath.Clean(namer(name, i))
 
-				return nil, 0, nil, fmt.Errorf("sevenzip: error opening: %w", errors.Join(errs...))
+		f, err := fs.Open(n)
+		if err != nil {
+			switch {
+			case !errors.Is(err, iofs.ErrNotExist):
+				return fail(fmt.Errorf("sevenzip: error opening: %w", err))
+			case total >= 0:
+				return fail(fmt.Errorf("%w: volume %s is missing", ErrTruncated, n))
 			}
 
-			files = append(files, f)
+			break
+		}
 
-			info, err = f.Stat()
-			if err != nil {
-				errs := make([]error, 0, len(files)+1)
-				errs = append(errs, err)
+		files = append(files, f)
 
-				for _, file := range files {
-					errs = append(errs, file.Close())
-				}
+		info, err = f.Stat()
+		if err != nil {
+			return fail(fmt.Errorf("sevenzip: error retrieving file info: %w", err))
+		}
 
-				return nil, 0, nil, fmt.Errorf("sevenzip: error retrieving file info: %w", errors.Join(errs...))
-			}
+		size := info.Size()
 
-			sr = append(sr, io.NewSectionReader(f, 0, info.Size()))
+		// Every volume is the same size as the first apart from the last
+		if want := min(sizes[0], total-sum(sizes)); total >= 0 && size != want {
+			return fail(fmt.Errorf("%w: volume %s is %d bytes, expected %d", ErrVolumeSize, n, size, want))
 		}
 
-		mr := readerutil.NewMultiReaderAt(sr...)
-		reader, size = mr, mr.Size()
+		sizes = append(sizes, size)
+		sr = append(sr, io.NewSectionReader(concurrentReaderAt(f), 0, size))
 	}
 
-	return reader, size, files, nil
+	return readerutil.NewMultiReaderAt(sr...), sizes, files, nil
 }
 
-// OpenReaderWithPassword will open the 7-zip file specified by name using
-// password as the basis of the decryption key and return a [*ReadCloser]. If
-// name has a ".001" suffix it is assumed there are multiple volumes and each
-// sequential volume will be opened. An optional custom filesystem can be provided;
-// if not specified, the default OS filesystem is used.
-func OpenReaderWithPassword(name, password string, fs ...afero.Fs) (*ReadCloser, error) {
+func sum(sizes []int64) (n int64) {
+	for _, size := range sizes {
+		n += size
+	}
+
+	return n
+}
+
+// OpenReaderWithOptions will open the 7-zip file specified by name, configured
+// by opts, and return a [*ReadCloser]. If name has a ".001" suffix it is
+// assumed there are multiple volumes and each sequential volume will be
+// opened, other naming schemes can be handled with [WithVolumeNamer].
+func OpenReaderWithOptions(name string, opts ...ReaderOption) (*ReadCloser, error) {
+	o := newReaderOptions(opts)
+
 	// Use provided filesystem or default to OS filesystem
-	filesystem := afero.NewOsFs()
-	if len(fs) > 0 && fs[0] != nil {
-		filesystem = fs[0]
+	filesystem := o.fs
+	if filesystem == nil {
+		filesystem = afero.NewOsFs()
 	}
 
-	reader, size, files, err := openReader(filesystem, name)
+	reader, sizes, files, err := openReader(filesystem, name, o.volumeNamer)
 	if err != nil {
 		return nil, err
 	}
 
 	r := new(ReadCloser)
-	r.p = password
+	r.p = o.password
+	r.opts = o
+	r.volumes = sizes
 
-	if err := r.init(reader, size); err != nil {
+	if err := r.init(reader, sum(sizes)); err != nil {
 		errs := make([]error, 0, len(files)+1)
 		errs = append(errs, err)
 
@@ -285,6 +569,20 @@ func OpenReaderWithPassword(name, password string, fs ...afero.Fs) (*ReadCloser,
 	return r, nil
 }
 
+// OpenReaderWithPassword will open the 7-zip file specified by name using
+// password as the basis of the decryption key and return a [*ReadCloser]. If
+// name has a ".001" suffix it is assumed there are multiple volumes and each
+// sequential volume will be opened. An optional custom filesystem can be provided;
+// if not specified, the default OS filesystem is used.
+func OpenReaderWithPassword(name, password string, fs ...afero.Fs) (*ReadCloser, error) {
+	opts := []ReaderOption{WithPassword(password)}
+	if len(fs) > 0 {
+		opts = append(opts, WithFs(fs[0]))
+	}
+
+	return OpenReaderWithOptions(name, opts...)
+}
+
 // OpenReader will open the 7-zip file specified by name and return a
 // [*ReadCloser]. If name has a ".001" suffix it is assumed there are multiple
 // volumes and each sequential volume will be opened. An optional custom filesystem
@@ -293,16 +591,16 @@ func OpenReader(name string, fs ...afero.Fs) (*ReadCloser, error) {
 	return OpenReaderWithPassword(name, "", fs...)
 }
 
-// NewReaderWithPassword returns a new [*Reader] reading from r using password
-// as the basis of the decryption key, which is assumed to have the given size
-// in bytes.
-func NewReaderWithPassword(r io.ReaderAt, size int64, password string) (*Reader, error) {
+// NewReaderWithOptions returns a new [*Reader] reading from r, configured by
+// opts, which is assumed to have the given size in bytes.
+func NewReaderWithOptions(r io.ReaderAt, size int64, opts ...ReaderOption) (*Reader, error) {
 	if size < 0 {
 		return nil, errNegativeSize
 	}
 
 	zr := new(Reader)
-	zr.p = password
+	zr.opts = newReaderOptions(opts)
+	zr.p = zr.opts.password
 
 	if err := zr.init(r, size); err != nil {
 		return nil, err
@@ -311,10 +609,17 @@ func NewReaderWithPassword(r io.ReaderAt, size int64, password string) (*Reader,
 	return zr, nil
 }
 
+// NewReaderWithPassword returns a new [*Reader] reading from r using password
+// as the basis of the decryption key, which is assumed to have the given size
+// in bytes.
+func NewReaderWithPassword(r io.ReaderAt, size int64, password string) (*Reader, error) {
+	return NewReaderWithOptions(r, size, WithPassword(password))
+}
+
 // NewReader returns a new [*Reader] reading from r, which is assumed to have
 // the given size in bytes.
 func NewReader(r io.ReaderAt, size int64) (*Reader, error) {
-	return NewReaderWithPassword(r, size, "")
+	return NewReaderWithOptions(r, size)
 }
 
 // Open opens the named file in the 7-zip archive, using the semantics of
@@ -344,9 +649,234 @@ func (z *Reader) Open(name string) (iofs.File, error) {
 	return rc.(iofs.File), nil //nolint:forcetypeassert
 }
 
-func (z *Reader) folderReader(si *streamsInfo, f int) (*folderReadCloser, uint32, bool, error) {
-	// Create a SectionReader covering all of the streams data
-	return si.folderReader(io.NewSectionReader(z.r, z.start, z.end-z.start), f, z.p)
+// FileByName returns the file with exactly the given name, as it appears in
+// [Reader.File], and true, or false if there isn't one. The trailing slash
+// on the name of a directory may be omitted. If more than one file has the
+// name the first is returned. The lookup uses a map built on first use so
+// finding many files by name doesn't require scanning [Reader.File] each
+// time.
+func (z *Reader) FileByName(name string) (*File, bool) {
+	z.fileMapOnce.Do(func() {
+		z.fileMap = make(map[string]*File, len(z.File))
+
+		for _, f := range z.File {
+			if _, ok := z.fileMap[f.Name]; !ok {
+				z.fileMap[f.Name] = f
+			}
+		}
+	})
+
+	if f, ok := z.fileMap[name]; ok {
+		return f, true
+	}
+
+	if f, ok := z.fileMap[name+"/"]; ok {
+		return f, true
+	}
+
+	return nil, false
+}
+
+// FileByIndex returns the file at index i of [Reader.File] and true, or false
+// if i is out of range.
+func (z *Reader) FileByIndex(i int) (*File, bool) {
+	if i < 0 || i >= len(z.File) {
+		return nil, false
+	}
+
+	return z.File[i], true
+}
+
+// decodeStreams decodes the additional streams of the header, which are
+// subject to the same limits as the header itself.
+func (z *Reader) decodeStreams(si *streamsInfo) ([][]byte, error) {
+	data := make([][]byte, si.Folders())
+	z.headerPacked += si.packedSize()
+
+	if _, err := z.packRanges(si, true); err != nil {
+		return nil, err
+	}
+
+	for i := range data {
+		if err := z.checkHeaderSize(si.unpackInfo.folder[i].unpackSize()); err != nil {
+			return nil, err
+		}
+
+		fr, crc, crcDefined, encrypted, err := z.folderReader(si, i)
+		if err != nil {
+			return nil, headerReadError(encrypted, err)
+		}
+
+		data[i], err = io.ReadAll(fr)
+		if err = errors.Join(err, fr.Close()); err != nil {
+			return nil, headerReadError(fr.hasEncryption, err)
+		}
+
+		if crcDefined && !util.CRC32Equal(fr.Checksum(), crc) {
+			return nil, headerReadError(fr.hasEncryption, errChecksum)
+		}
+	}
+
+	return data, nil
+}
+
+// versionError wraps err, from parsing the header, with
+// [ErrUnsupportedVersion] if the archive uses an older or newer format
+// version, as the failure is more likely down to the version than
+// corruption.
+func (z *Reader) versionError(err error) error {
+	if z.version[1] == formatMinorVersion {
+		return err
+	}
+
+	return fmt.Errorf("%w: %d.%d: %w", ErrUnsupportedVersion, z.version[0], z.version[1], err)
+}
+
+func (z *Reader) folderConfig() *folderConfig {
+	return &folderConfig{
+		password:    z.p,
+		readAhead:   z.opts.readAhead,
+		done:        z.done,
+		cbc:         z.opts.cbc,
+		lookup:      z.decompressor,
+		multiLookup: z.multiDecompressor,
+	}
+}
+
+// RegisterDecompressor registers or overrides a custom decompressor for a
+// specific method ID for this reader only. If a decompressor for a given
+// method is not found, Reader will default to looking up the decompressor
+// registered with the package level [RegisterDecompressor].
+func (z *Reader) RegisterDecompressor(method []byte, dcomp Decompressor) {
+	z.decompressors.Store(string(method), dcomp)
+}
+
+func (z *Reader) decompressor(method []byte) Decompressor {
+	if di, ok := z.decompressors.Load(string(method)); ok {
+		if d, ok := di.(Decompressor); ok {
+			return d
+		}
+	}
+
+	return decompressor(method)
+}
+
+// RegisterMultiDecompressor is the equivalent of [Reader.RegisterDecompressor]
+// for methods with more than one output stream, falling back to those
+// registered with the package level [RegisterMultiDecompressor].
+func (z *Reader) RegisterMultiDecompressor(method []byte, dcomp MultiDecompressor) {
+	z.multiDecompressors.Store(string(method), dcomp)
+}
+
+func (z *Reader) multiDecompressor(method []byte) MultiDecompressor {
+	if di, ok := z.multiDecompressors.Load(string(method)); ok {
+		if d, ok := di.(MultiDecompressor); ok {
+			return d
+		}
+	}
+
+	return multiDecompressor(method)
+}
+
+// streams returns a SectionReader covering all of the streams data.
+func (z *Reader) streams() *io.SectionReader {
+	return io.NewSectionReader(&closedReaderAt{z.r, z.done}, z.start, z.end-z.start)
+}
+
+func (z *Reader) folderReader(si *streamsInfo, f int) (*folderReadCloser, uint32, bool, bool, error) {
+	return z.folderReaderConfig(si, f, z.folderConfig())
+}
+
+func (z *Reader) folderReaderConfig(si *streamsInfo, f int, cfg *folderConfig) (*folderReadCloser, uint32, bool, bool, error) {
+	if z.audit == nil {
+		return si.folderReader(z.streams(), f, cfg)
+	}
+
+	sink := &auditSink{z: z}
+	r := io.NewSectionReader(&auditReaderAt{&closedReaderAt{z.r, z.done}, sink}, z.start, z.end-z.start)
+
+	fr, crc, crcDefined, encrypted, err := si.folderReader(r, f, cfg)
+	if fr != nil {
+		fr.audit = sink
+	}
+
+	return fr, crc, crcDefined, encrypted, err
+}
+
+// mergeDone returns a channel that is closed once either a or b is. The
+// goroutine waiting on them lasts until one of them is closed, so b must
+// always be closed eventually.
+func mergeDone(a, b <-chan struct{}) <-chan struct{} {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		select {
+		case <-a:
+		case <-b:
+		}
+	}()
+
+	return done
+}
+
+// closedReaderAt fails any reads once done is closed so that decoders reading
+// from the archive stop promptly when the Reader is closed.
+type closedReaderAt struct {
+	r    io.ReaderAt
+	done <-chan struct{}
+}
+
+func (ra *closedReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	select {
+	case <-ra.done:
+		return 0, errReaderClosed
+	default:
+	}
+
+	return ra.r.ReadAt(p, off) //nolint:wrapcheck
+}
+
+// OpenHandles returns the number of readers returned by [File.Open] that are
+// backed by a decoder and have not yet been closed. Empty files and
+// directories don't hold a decoder and so aren't counted. A non-zero value
+// once an application has finished with the archive indicates a leak; see
+// the sevenziptest package for a helper to check this in tests.
+func (z *Reader) OpenHandles() int {
+	return int(z.handles.Load())
+}
+
+func (z *Reader) isClosed() bool {
+	select {
+	case <-z.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close releases any decoders cached for reuse and causes any files still
+// being read to fail with an error. It does not close the underlying
+// [io.ReaderAt].
+func (z *Reader) Close() error {
+	z.closeOnce.Do(func() {
+		if z.done != nil {
+			close(z.done)
+		}
+	})
+
+	errs := make([]error, 0, len(z.pool))
+
+	for _, p := range z.pool {
+		errs = append(errs, p.Close())
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("sevenzip: error closing: %w", err)
+	}
+
+	return nil
 }
 
 const (
@@ -354,11 +884,15 @@ const (
 	searchLimit = 1 << 20 // 1 MiB
 )
 
-func findSignature(r io.ReaderAt, search []byte) ([]int64, error) {
+func findSignature(r io.ReaderAt, search []byte, limit int64) ([]int64, error) {
 	chunk := make([]byte, chunkSize+len(search))
 	offsets := make([]int64, 0, 2)
 
-	for offset := int64(0); offset < searchLimit; offset += chunkSize {
+	if limit <= 0 {
+		limit = math.MaxInt64
+	}
+
+	for offset := int64(0); offset < limit; offset += chunkSize {
 		n, err := r.ReadAt(chunk, offset)
 
 		for i := 0; ; {
@@ -388,8 +922,163 @@ func findSignature(r io.ReaderAt, search []byte) ([]int64, error) {
 	return offsets, nil
 }
 
+// rankSignatures orders the offsets where the signature was found so those
+// whose start header checks out and points at a header within the file come
+// first, then those that only check out, keeping the order otherwise. The
+// stub of a self-extracting installer, or its configuration, can contain the
+// signature too and even a start header that checks out by chance.
+func rankSignatures(r io.ReaderAt, offsets []int64, size int64) []int64 {
+	if len(offsets) < 2 { //nolint:mnd
+		return offsets
+	}
+
+	rank := func(off int64) int {
+		var b [32]byte
+		if _, err := r.ReadAt(b[:], off); err != nil || size-off < int64(len(b)) {
+			return 0
+		}
+
+		if crc32.ChecksumIEEE(b[12:]) != binary.LittleEndian.Uint32(b[8:]) {
+			return 0
+		}
+
+		end := uint64(size - off - int64(len(b))) //nolint:gosec
+		next, length := binary.LittleEndian.Uint64(b[12:]), binary.LittleEndian.Uint64(b[20:])
+
+		if next > end || length > end-next {
+			return 1
+		}
+
+		return 2 //nolint:mnd
+	}
+
+	ranks := make(map[int64]int, len(offsets))
+	for _, off := range offsets {
+		ranks[off] = rank(off)
+	}
+
+	offsets = slices.Clone(offsets)
+	slices.SortStableFunc(offsets, func(a, b int64) int {
+		return ranks[b] - ranks[a]
+	})
+
+	return offsets
+}
+
+// decodeHeader decodes the encoded header described by si, retrying with
+// any variants of the password if the first attempt fails because of it.
+func (z *Reader) decodeHeader(si *streamsInfo) (*header, error) {
+	var first error
+
+	for _, p := range z.passwords() {
+		z.p = p
+
+		h, err := z.readEncodedHeader(si)
+		if err == nil {
+			return h, nil
+		}
+
+		if first == nil {
+			first = err
+		}
+
+		if re := new(ReadError); !errors.As(err, &re) || !re.Encrypted {
+			break
+		}
+	}
+
+	z.p = z.opts.password
+
+	return nil, first
+}
+
+// readEncodedHeader decodes the encoded header described by si, following
+// any further levels of encoded header found inside it, each of which is
+// added to z.headerChain.
+func (z *Reader) readEncodedHeader(si *streamsInfo) (*header, error) {
+	z.headerChain, z.encryptedHeader = nil, false
+
+	for {
+		if len(z.headerChain) == maxHeaderDepth {
+			return nil, errHeaderDepth
+		}
+
+		z.headerChain = append(z.headerChain, si)
+
+		if _, err := z.packRanges(si, true); err != nil {
+			return nil, err
+		}
+
+		h, next, err := z.readHeaderLevel(si)
+		if err != nil || next == nil {
+			return h, err
+		}
+
+		if next.Folders() != 1 {
+			return nil, errOneHeaderStream
+		}
+
+		if err := z.checkHeaderSize(next.unpackInfo.folder[0].unpackSize()); err != nil {
+			return nil, err
+		}
+
+		z.headerPacked += next.packedSize()
+		si = next
+	}
+}
+
+// readHeaderLevel decodes one level of encoded header described by si,
+// returning either the header or the next level if it's encoded again.
+func (z *Reader) readHeaderLevel(si *streamsInfo) (h *header, next *streamsInfo, err error) {
+	fr, crc, crcDefined, encrypted, err := z.folderReader(si, 0)
+	if err != nil {
+		return nil, nil, headerReadError(encrypted, err)
+	}
+
+	defer func() {
+		err = errors.Join(err, fr.Close())
+	}()
+
+	r := util.ByteReadCloser(fr)
+
+	id, err := r.ReadByte()
+	if err != nil {
+		return nil, nil, headerReadError(fr.hasEncryption, fmt.Errorf("readEncodedHeader: ReadByte error: %w", err))
+	}
+
+	switch id {
+	case idHeader:
+		h, err = readHeader(r, z.decodeStreams)
+	case idEncodedHeader:
+		next, err = readStreamsInfo(r, nil)
+	default:
+		err = errUnexpectedID
+	}
+
+	if err != nil {
+		return nil, nil, headerReadError(fr.hasEncryption, err)
+	}
+
+	// A wrong password usually fails above but can occasionally
+	// produce a header that parses, in which case only the CRC
+	// catches it
+	if crcDefined && !util.CRC32Equal(fr.Checksum(), crc) {
+		return nil, nil, headerReadError(fr.hasEncryption, errChecksum)
+	}
+
+	z.encryptedHeader = z.encryptedHeader || fr.hasEncryption
+
+	return h, next, nil
+}
+
 //nolint:cyclop,funlen,gocognit,gocyclo,maintidx
 func (z *Reader) init(r io.ReaderAt, size int64) (err error) {
+	z.done = make(chan struct{})
+
+	if z.opts.audit {
+		z.audit = &audit{ranges: make(map[*File][]ReadRange)}
+	}
+
 	h := crc32.NewIEEE()
 	tra := plumbing.TeeReaderAt(r, h)
 
@@ -398,11 +1087,18 @@ func (z *Reader) init(r io.ReaderAt, size int64) (err error) {
 		offsets   []int64
 	)
 
-	offsets, err = findSignature(r, signature)
-	if err != nil {
+	if z.opts.baseOffset >= 0 {
+		if z.opts.baseOffset > size {
+			return errFormat
+		}
+
+		offsets = []int64{z.opts.baseOffset}
+	} else if offsets, err = findSignature(r, signature, z.opts.searchLimit); err != nil {
 		return err
 	}
 
+	offsets = rankSignatures(r, offsets, size)
+
 	if len(offsets) == 0 {
 		return errFormat
 	}
@@ -421,6 +1117,13 @@ func (z *Reader) init(r io.ReaderAt, size int64) (err error) {
 			return fmt.Errorf("sevenzip: error reading signature header: %w", err)
 		}
 
+		// Only needed when the offset was given rather than found
+		if !bytes.Equal(sh.Signature[:], signature) {
+			err = errFormat
+
+			continue
+		}
+
 		z.r = r
 
 		h.Reset()
@@ -431,6 +1134,8 @@ func (z *Reader) init(r io.ReaderAt, size int64) (err error) {
 
 		// CRC of the start header should match
 		if util.CRC32Equal(h.Sum(nil), sh.CRC) {
+			z.version = [2]byte{sh.Major, sh.Minor}
+
 			break
 		}
 
@@ -441,6 +1146,14 @@ func (z *Reader) init(r io.ReaderAt, size int64) (err error) {
 		return err
 	}
 
+	switch {
+	case z.version[0] != formatMajorVersion, z.version[1] > formatMinorVersion && z.opts.strictVersion:
+		return fmt.Errorf("%w: %d.%d", ErrUnsupportedVersion, z.version[0], z.version[1])
+	case z.version[1] > formatMinorVersion:
+		z.warnings = append(z.warnings, fmt.Sprintf("format version %d.%d is newer than %d.%d, anything added since may be ignored",
+			z.version[0], z.version[1], formatMajorVersion, formatMinorVersion))
+	}
+
 	// Work out where we are in the file (32, avoiding magic numbers)
 	if z.start, err = sr.Seek(0, io.SeekCurrent); err != nil {
 		return fmt.Errorf("sevenzip: error seeking current position: %w", err)
@@ -451,8 +1164,29 @@ func (z *Reader) init(r io.ReaderAt, size int64) (err error) {
 		return fmt.Errorf("sevenzip: error seeking over streams: %w", err)
 	}
 
+	z.base = off
 	z.start += off
 	z.end += off
+	z.size = size
+	z.sh = start
+
+	if z.end > size || start.Size > uint64(size-z.end) { //nolint:gosec
+		return ErrTruncated
+	}
+
+	// An archive with nothing in it is written by 7-Zip as just the
+	// signature header with no header following it
+	if start.Size == 0 {
+		if start.Offset != 0 {
+			return errFormat
+		}
+
+		return z.initHeader(new(header))
+	}
+
+	if err = z.checkHeaderSize(start.Size); err != nil {
+		return err
+	}
 
 	h.Reset()
 
@@ -471,21 +1205,30 @@ func (z *Reader) init(r io.ReaderAt, size int64) (err error) {
 
 	switch id {
 	case idHeader:
-		if header, err = readHeader(br); err != nil {
-			return err
+		if header, err = readHeader(br, z.decodeStreams); err != nil {
+			return z.versionError(err)
 		}
 	case idEncodedHeader:
-		if streamsInfo, err = readStreamsInfo(br); err != nil {
-			return err
+		if streamsInfo, err = readStreamsInfo(br, nil); err != nil {
+			return z.versionError(err)
 		}
 	default:
-		return errUnexpectedID
+		return z.versionError(errUnexpectedID)
 	}
 
 	// If there's more data to read, we've not parsed this correctly. This
-	// won't break with trailing data as the bufio.Reader was bounded
-	if n, _ := io.CopyN(io.Discard, br, 1); n != 0 {
-		return errTooMuch
+	// won't break with trailing data as the bufio.Reader was bounded. Some
+	// writers pad the header with zeroes, which 7-Zip tolerates, so only
+	// reject anything else
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			break
+		}
+
+		if b != 0 {
+			return errTooMuch
+		}
 	}
 
 	// CRC should match the one from the start header
@@ -500,85 +1243,130 @@ func (z *Reader) init(r io.ReaderAt, size int64) (err error) {
 			return errOneHeaderStream
 		}
 
-		var (
-			fr        *folderReadCloser
-			crc       uint32
-			encrypted bool
-		)
+		if err = z.checkHeaderSize(streamsInfo.unpackInfo.folder[0].unpackSize()); err != nil {
+			return err
+		}
 
-		fr, crc, encrypted, err = z.folderReader(streamsInfo, 0)
-		if err != nil {
-			return &ReadError{
-				Encrypted: encrypted,
-				Err:       err,
-			}
+		if header, err = z.decodeHeader(streamsInfo); err != nil {
+			return err
 		}
 
-		defer func() {
-			err = errors.Join(err, fr.Close())
-		}()
+		z.headerPacked += streamsInfo.packedSize()
+	}
 
-		if header, err = readEncodedHeader(util.ByteReadCloser(fr)); err != nil {
-			return &ReadError{
-				Encrypted: fr.hasEncryption,
-				Err:       err,
-			}
-		}
+	return z.initHeader(header)
+}
 
-		if crc != 0 && !util.CRC32Equal(fr.Checksum(), crc) {
-			return errChecksum
-		}
+// initHeader checks header against the limits and builds the list of files
+// from it.
+//
+//nolint:cyclop,gocognit
+func (z *Reader) initHeader(header *header) error {
+	if err := z.checkLimits(header); err != nil {
+		return err
 	}
 
+	if err := z.checkPacked(header.streamsInfo); err != nil {
+		return err
+	}
+
+	z.h = header
 	z.si = header.streamsInfo
 
-	// spew.Dump(header)
-	filesPerStream := make(map[int]int, z.si.Folders())
+	filter := z.opts.nameFilter
+	if filter != nil {
+		// The full list of files is only needed for rewriting, which
+		// isn't possible with a partial list, so don't hold on to it
+		partial := *header
+		partial.filesInfo = nil
+
+		if header.filesInfo != nil {
+			partial.filesInfo = &filesInfo{comment: header.filesInfo.comment}
+		}
+
+		z.h = &partial
+	}
 
+	// spew.Dump(header)
 	if header.filesInfo != nil {
 		folder, offset := 0, int64(0)
-		z.File = make([]*File, 0, len(header.filesInfo.file))
 		j := 0
 
-		for _, fh := range header.filesInfo.file {
-			f := new(File)
-			f.zip = z
-			f.FileHeader = fh
+		if filter == nil {
+			z.File = make([]*File, 0, len(header.filesInfo.file))
+		}
 
-			if f.FileHeader.FileInfo().IsDir() && !strings.HasSuffix(f.Name, "/") {
-				f.Name += "/"
+		for _, fh := range header.filesInfo.file {
+			if fh.FileInfo().IsDir() && !strings.HasSuffix(fh.Name, "/") {
+				fh.Name += "/"
 			}
 
-			if !fh.isEmptyStream && !fh.isEmptyFile {
-				f.folder, _, _ = header.streamsInfo.FileFolderAndSize(j)
+			// Files not matching the filter still have to be walked
+			// to work out the offsets of the files that follow them
+			keep := filter == nil || filter(fh.Name)
 
-				// Make an exported copy of the folder index
-				f.Stream = f.folder
+			var (
+				fileFolder int
+				fileOffset int64
+				oversized  *OversizedError
+			)
 
-				filesPerStream[f.folder]++
+			if !fh.isEmptyStream && !fh.isEmptyFile && j >= header.streamsInfo.Files() {
+				// There are more files than streams to hold them
+				fh.isMissing = true
+			} else if !fh.isEmptyStream && !fh.isEmptyFile {
+				fileFolder, _, _, _ = header.streamsInfo.FileFolderAndSize(j)
 
-				if f.folder != folder {
+				// Make an exported copy of the folder index
+				fh.Stream = fileFolder
+
+				if fileFolder != folder {
 					offset = 0
 				}
 
-				f.offset = offset
-				offset += int64(f.UncompressedSize) //nolint:gosec
-				folder = f.folder
+				oversized = z.checkSize(&fh, fileFolder, header.streamsInfo, offset)
+
+				fileOffset = offset
+				offset += int64(fh.UncompressedSize) //nolint:gosec
+				folder = fileFolder
 				j++
 			}
 
-			z.File = append(z.File, f)
+			if keep {
+				z.File = append(z.File, &File{
+					FileHeader: fh,
+					zip:        z,
+					folder:     fileFolder,
+					offset:     fileOffset,
+					digest:     new(fileDigest),
+					oversized:  oversized,
+				})
+			}
 		}
 	}
 
-	// spew.Dump(filesPerStream)
+	return z.initPools()
+}
+
+// initPools creates the cache of decoders for each stream, which is only
+// useful for streams holding more than one file.
+func (z *Reader) initPools() (err error) {
+	filesPerStream := make(map[int]int, z.si.Folders())
+
+	for _, f := range z.File {
+		if f.HasStream() {
+			filesPerStream[f.folder]++
+		}
+	}
 
 	z.pool = make([]pool.Pooler, z.si.Folders())
 	for i := range z.pool {
 		var newPool pool.Constructor = pool.NewNoopPool
 
 		if filesPerStream[i] > 1 {
-			newPool = pool.NewPool
+			newPool = func() (pool.Pooler, error) {
+				return pool.NewPool(z.opts.poolSize)
+			}
 		}
 
 		if z.pool[i], err = newPool(); err != nil {
@@ -586,6 +1374,122 @@ func (z *Reader) init(r io.ReaderAt, size int64) (err error) {
 		}
 	}
 
+	z.matchPassword()
+
+	return nil
+}
+
+// clone initialises c as a copy of z reading from r, sharing everything
+// parsed from the header.
+func (z *Reader) clone(c *Reader, r io.ReaderAt) error {
+	c.r = r
+	c.base, c.start, c.end, c.size = z.base, z.start, z.end, z.size
+	c.si, c.h, c.sh = z.si, z.h, z.sh
+	c.p, c.opts = z.p, z.opts
+	c.encryptedHeader, c.headerPacked, c.headerChain = z.encryptedHeader, z.headerPacked, z.headerChain
+	c.volumes, c.version, c.warnings = z.volumes, z.version, z.warnings
+
+	z.decompressors.Range(func(k, v any) bool {
+		c.decompressors.Store(k, v)
+
+		return true
+	})
+
+	z.multiDecompressors.Range(func(k, v any) bool {
+		c.multiDecompressors.Store(k, v)
+
+		return true
+	})
+
+	c.done = make(chan struct{})
+
+	if c.opts.audit {
+		c.audit = &audit{ranges: make(map[*File][]ReadRange)}
+	}
+
+	c.File = make([]*File, len(z.File))
+
+	for i, f := range z.File {
+		nf := *f
+		nf.zip = c
+		c.File[i] = &nf
+	}
+
+	return c.initPools()
+}
+
+// Clone returns a new [*Reader] for the same archive that shares everything
+// parsed from the header, which is never modified, so it is almost free
+// compared to opening the archive again. The clone has its own cache of
+// decoders and is closed independently, which can be useful to give each
+// worker its own Reader. It reads from the same [io.ReaderAt] as z, so when z
+// is a [*ReadCloser] the clone is only usable until z is closed; use
+// [ReadCloser.Clone] to open the volumes again instead.
+func (z *Reader) Clone() (*Reader, error) {
+	c := new(Reader)
+	if err := z.clone(c, z.r); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (z *Reader) checkHeaderSize(size uint64) error {
+	if limit := z.opts.maxHeaderSize; limit > 0 && size > limit {
+		return fmt.Errorf("%w: header size %d exceeds %d", ErrLimitExceeded, size, limit)
+	}
+
+	return nil
+}
+
+func (z *Reader) checkLimits(h *header) error {
+	if limit := z.opts.maxFiles; limit > 0 && h.filesInfo != nil && len(h.filesInfo.file) > limit {
+		return fmt.Errorf("%w: %d files exceeds %d", ErrLimitExceeded, len(h.filesInfo.file), limit)
+	}
+
+	if limit := z.opts.maxUnpackedSize; limit > 0 && h.streamsInfo != nil && h.streamsInfo.unpackInfo != nil {
+		var total uint64
+
+		for _, f := range h.streamsInfo.unpackInfo.folder {
+			total += f.unpackSize()
+			if total > limit || total < f.unpackSize() {
+				return fmt.Errorf("%w: unpacked size exceeds %d", ErrLimitExceeded, limit)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkSize checks fh, starting at offset in folder, fits in what is left of
+// the folder's output, truncating it if [WithTruncateOversized] is used.
+// Otherwise it returns the error for opening the file.
+func (z *Reader) checkSize(fh *FileHeader, folder int, si *streamsInfo, offset int64) *OversizedError {
+	size := si.unpackInfo.folder[folder].unpackSize()
+
+	left := uint64(0)
+	if uint64(offset) < size { //nolint:gosec
+		left = size - uint64(offset) //nolint:gosec
+	}
+
+	if fh.UncompressedSize <= left {
+		return nil
+	}
+
+	if !z.opts.truncateOversized {
+		return &OversizedError{
+			Name:      fh.Name,
+			Size:      fh.UncompressedSize,
+			Block:     folder,
+			BlockSize: size,
+			Left:      left,
+		}
+	}
+
+	z.warnings = append(z.warnings, fmt.Sprintf("%s declares %d bytes but only %d are left of block %d, truncated",
+		fh.Name, fh.UncompressedSize, left, folder))
+	fh.UncompressedSize = left
+
 	return nil
 }
 
@@ -600,9 +1504,46 @@ func (rc *ReadCloser) Volumes() []string {
 	return volumes
 }
 
+// Clone returns a new [*ReadCloser] for the same archive like
+// [Reader.Clone], except that the volumes are opened again so the clone
+// doesn't share any file handles with rc and remains usable after rc is
+// closed.
+func (rc *ReadCloser) Clone() (*ReadCloser, error) {
+	fs := rc.opts.fs
+	if fs == nil {
+		fs = afero.NewOsFs()
+	}
+
+	reader, sizes, files, err := openReader(fs, rc.f[0].Name(), rc.opts.volumeNamer)
+	if err != nil {
+		return nil, err
+	}
+
+	c := new(ReadCloser)
+
+	if !slices.Equal(sizes, rc.volumes) {
+		err = errChanged
+	} else {
+		err = rc.clone(&c.Reader, reader)
+	}
+
+	if err != nil {
+		for _, f := range files {
+			err = errors.Join(err, f.Close())
+		}
+
+		return nil, err
+	}
+
+	c.f = files
+
+	return c, nil
+}
+
 // Close closes the 7-zip file or volumes, rendering them unusable for I/O.
 func (rc *ReadCloser) Close() error {
-	errs := make([]error, 0, len(rc.f))
+	errs := make([]error, 0, len(rc.f)+1)
+	errs = append(errs, rc.Reader.Close())
 
 	for _, f := range rc.f {
 		errs = append(errs, f.Close())
@@ -702,8 +1643,15 @@ func (z *Reader) initFileList() {
 				continue
 			}
 
-			for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
-				dirs[dir] = struct{}{}
+			// The name is already clean so its parents are prefixes of
+			// it, and once one has been seen so have all of its parents,
+			// which keeps deeply nested names from being quadratic
+			for i := strings.LastIndexByte(name, '/'); i > 0; i = strings.LastIndexByte(name[:i], '/') {
+				if _, ok := dirs[name[:i]]; ok {
+					break
+				}
+
+				dirs[name[:i]] = struct{}{}
 			}
 
 			idx := len(z.fileList)
@@ -737,6 +1685,8 @@ func (z *Reader) initFileList() {
 		}
 
 		sort.Slice(z.fileList, func(i, j int) bool { return fileEntryLess(z.fileList[i].name, z.fileList[j].name) })
+
+		z.trie = buildTrie(z.fileList)
 	})
 }
 
@@ -768,45 +1718,19 @@ func split(name string) (dir, elem string) {
 var dotFile = &fileListEntry{name: "./", isDir: true}
 
 func (z *Reader) openLookup(name string) *fileListEntry {
-	if name == "." {
-		return dotFile
-	}
-
-	dir, elem := split(name)
-
-	files := z.fileList
-	i := sort.Search(len(files), func(i int) bool {
-		idir, ielem := split(files[i].name)
-
-		return idir > dir || idir == dir && ielem >= elem
-	})
-
-	if i < len(files) {
-		fname := files[i].name
-		if fname == name || len(fname) == len(name)+1 && fname[len(name)] == '/' && fname[:len(name)] == name {
-			return &files[i]
-		}
+	if n := z.trie.lookup(name); n != nil {
+		return n.entry
 	}
 
 	return nil
 }
 
 func (z *Reader) openReadDir(dir string) []fileListEntry {
-	files := z.fileList
-
-	i := sort.Search(len(files), func(i int) bool {
-		idir, _ := split(files[i].name)
-
-		return idir >= dir
-	})
-
-	j := sort.Search(len(files), func(j int) bool {
-		jdir, _ := split(files[j].name)
-
-		return jdir > dir
-	})
+	if n := z.trie.lookup(dir); n != nil {
+		return n.children
+	}
 
-	return files[i:j]
+	return nil
 }
 
 type openDir struct {
@@ -915,6 +1839,16 @@ func extractAESParams(folder *folder) (salt []byte, iv []byte, iterations int, h
 	return nil, nil, 0, false
 }
 
+// BaseOffset returns the offset of the archive within the underlying file,
+// which is non-zero for archives appended to other data such as
+// self-extracting executables or disk images. Offsets returned by
+// [Reader.ListFilesWithOffsets] already include it, anything else that
+// computes offsets from the headers relative to the start of the archive
+// must add it.
+func (z *Reader) BaseOffset() int64 {
+	return z.base
+}
+
 // ListFilesWithOffsets returns information about files in the archive including their
 // absolute offsets and sizes. All files are included in the result, with flags indicating
 // whether they are compressed or encrypted.
@@ -928,6 +1862,8 @@ func extractAESParams(folder *folder) (salt []byte, iv []byte, iterations int, h
 //
 // For encrypted files, AES encryption parameters (salt, IV, KDF iterations) are populated
 // to enable external streaming and seeking. See FileInfo documentation for usage details.
+//
+// To find the offset of a single stored file use [File.DataOffset] instead.
 func (z *Reader) ListFilesWithOffsets() ([]FileInfo, error) {
 	if z.si == nil {
 		return nil, errors.New("sevenzip: no streams info available")
@@ -996,10 +1932,12 @@ func (z *Reader) ListFilesWithOffsets() ([]FileInfo, error) {
 		}
 	}
 
+	folderOffsets, packedSizes := z.si.folderOffsets()
+
 	// Process each file
 	for _, file := range z.File {
 		// Skip empty files and directories
-		if file.FileHeader.isEmptyStream || file.FileHeader.isEmptyFile {
+		if !file.HasStream() {
 			continue
 		}
 
@@ -1016,24 +1954,9 @@ func (z *Reader) ListFilesWithOffsets() ([]FileInfo, error) {
 		// in the packed stream plus the file's offset within the folder
 		var absoluteOffset int64
 		var packedSize uint64
-		if z.si.packInfo != nil {
-			folderOffset := z.si.folderOffset(file.folder)
-			absoluteOffset = z.start + folderOffset + file.offset
-
-			// Calculate packed size for this folder
-			// Sum up all packed stream sizes for this folder
-			if z.si.unpackInfo != nil && file.folder < len(z.si.unpackInfo.folder) {
-				folder := z.si.unpackInfo.folder[file.folder]
-				packedOffset := 0
-				for i := 0; i < file.folder; i++ {
-					packedOffset += len(z.si.unpackInfo.folder[i].packed)
-				}
-				for i := 0; i < len(folder.packed); i++ {
-					if packedOffset+i < len(z.si.packInfo.size) {
-						packedSize += z.si.packInfo.size[packedOffset+i]
-					}
-				}
-			}
+		if z.si.packInfo != nil && file.folder < len(folderOffsets) {
+			absoluteOffset = z.start + folderOffsets[file.folder] + file.offset
+			packedSize = packedSizes[file.folder]
 		}
 
 		info := FileInfo{
@@ -2,9 +2,16 @@
 package sevenzip
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
 	"errors"
+	"hash/crc32"
+	"io"
 	iofs "io/fs"
+	"math"
 	"os"
+	"slices"
 	"testing"
 	"time"
 
@@ -282,7 +289,41 @@ var (
 	_ afero.Fs    = new(mockFs)
 )
 
-//nolint:funlen
+// startHeaderBytes returns the signature and start header of an archive
+// that is total bytes long.
+func startHeaderBytes(tb testing.TB, total int64) []byte {
+	tb.Helper()
+
+	start := new(bytes.Buffer)
+	require.NoError(tb, binary.Write(start, binary.LittleEndian, startHeader{Offset: uint64(total) - signatureHeaderSize})) //nolint:gosec
+
+	b := new(bytes.Buffer)
+	require.NoError(tb, binary.Write(b, binary.LittleEndian, signatureHeader{
+		Signature: [6]byte{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c},
+		Minor:     4,
+		CRC:       crc32.ChecksumIEEE(start.Bytes()),
+	}))
+
+	return append(b.Bytes(), start.Bytes()...)
+}
+
+// expectStartHeader sets up f to return header when the start header is
+// read.
+func expectStartHeader(f *mockFile, header []byte) {
+	for _, off := range []int64{0, 12} {
+		n := 12
+		if off > 0 {
+			n = len(header) - 12
+		}
+
+		f.On("ReadAt", mock.Anything, off).Run(func(args mock.Arguments) {
+			p, _ := args.Get(0).([]byte)
+			copy(p, header[off:])
+		}).Return(n, nil).Once()
+	}
+}
+
+//nolint:funlen,maintidx
 func TestOpenReader(t *testing.T) {
 	t.Parallel()
 
@@ -302,6 +343,32 @@ func TestOpenReader(t *testing.T) {
 				one := newMockFile(tb)
 				one.On("Stat").Return(info, nil).Once()
 				one.On("Close").Return(nil).Once()
+				expectStartHeader(one, startHeaderBytes(tb, 200))
+
+				two := newMockFile(tb)
+				two.On("Stat").Return(info, nil).Once()
+				two.On("Close").Return(nil).Once()
+
+				// Any further volumes are never opened
+				fs := newMockFs(tb)
+				fs.On("Open", "filename.7z.001").Return(one, nil).Once()
+				fs.On("Open", "filename.7z.002").Return(two, nil).Once()
+
+				return fs
+			},
+		},
+		{
+			name: "no signature",
+			fs: func(tb testing.TB) afero.Fs {
+				tb.Helper()
+
+				info := newMockFileInfo(tb)
+				info.On("Size").Return(int64(100)).Twice()
+
+				one := newMockFile(tb)
+				one.On("Stat").Return(info, nil).Once()
+				one.On("Close").Return(nil).Once()
+				one.On("ReadAt", mock.Anything, int64(0)).Return(12, nil).Once()
 
 				two := newMockFile(tb)
 				two.On("Stat").Return(info, nil).Once()
@@ -343,6 +410,26 @@ func TestOpenReader(t *testing.T) {
 			},
 			err: iofs.ErrPermission,
 		},
+		{
+			name: "first read error",
+			fs: func(tb testing.TB) afero.Fs {
+				tb.Helper()
+
+				info := newMockFileInfo(tb)
+				info.On("Size").Return(int64(100)).Once()
+
+				one := newMockFile(tb)
+				one.On("Stat").Return(info, nil).Once()
+				one.On("ReadAt", mock.Anything, int64(0)).Return(0, iofs.ErrPermission).Once()
+				one.On("Close").Return(nil).Once()
+
+				fs := newMockFs(tb)
+				fs.On("Open", "filename.7z.001").Return(one, nil).Once()
+
+				return fs
+			},
+			err: iofs.ErrPermission,
+		},
 		{
 			name: "multi open error",
 			fs: func(tb testing.TB) afero.Fs {
@@ -354,6 +441,7 @@ func TestOpenReader(t *testing.T) {
 				one := newMockFile(tb)
 				one.On("Stat").Return(info, nil).Once()
 				one.On("Close").Return(nil).Once()
+				expectStartHeader(one, startHeaderBytes(tb, 200))
 
 				fs := newMockFs(tb)
 				fs.On("Open", "filename.7z.001").Return(one, nil).Once()
@@ -374,6 +462,7 @@ func TestOpenReader(t *testing.T) {
 				one := newMockFile(tb)
 				one.On("Stat").Return(info, nil).Once()
 				one.On("Close").Return(nil).Once()
+				expectStartHeader(one, startHeaderBytes(tb, 200))
 
 				two := newMockFile(tb)
 				two.On("Stat").Return(nil, iofs.ErrPermission).Once()
@@ -387,13 +476,92 @@ func TestOpenReader(t *testing.T) {
 			},
 			err: iofs.ErrPermission,
 		},
+		{
+			name: "missing volume",
+			fs: func(tb testing.TB) afero.Fs {
+				tb.Helper()
+
+				info := newMockFileInfo(tb)
+				info.On("Size").Return(int64(100)).Twice()
+
+				one := newMockFile(tb)
+				one.On("Stat").Return(info, nil).Once()
+				one.On("Close").Return(nil).Once()
+				expectStartHeader(one, startHeaderBytes(tb, 300))
+
+				two := newMockFile(tb)
+				two.On("Stat").Return(info, nil).Once()
+				two.On("Close").Return(nil).Once()
+
+				fs := newMockFs(tb)
+				fs.On("Open", "filename.7z.001").Return(one, nil).Once()
+				fs.On("Open", "filename.7z.002").Return(two, nil).Once()
+				fs.On("Open", "filename.7z.003").Return(nil, iofs.ErrNotExist).Once()
+
+				return fs
+			},
+			err: ErrTruncated,
+		},
+		{
+			name: "wrong volume size",
+			fs: func(tb testing.TB) afero.Fs {
+				tb.Helper()
+
+				info := newMockFileInfo(tb)
+				info.On("Size").Return(int64(100)).Once()
+
+				short := newMockFileInfo(tb)
+				short.On("Size").Return(int64(50)).Once()
+
+				one := newMockFile(tb)
+				one.On("Stat").Return(info, nil).Once()
+				one.On("Close").Return(nil).Once()
+				expectStartHeader(one, startHeaderBytes(tb, 300))
+
+				two := newMockFile(tb)
+				two.On("Stat").Return(short, nil).Once()
+				two.On("Close").Return(nil).Once()
+
+				fs := newMockFs(tb)
+				fs.On("Open", "filename.7z.001").Return(one, nil).Once()
+				fs.On("Open", "filename.7z.002").Return(two, nil).Once()
+
+				return fs
+			},
+			err: ErrVolumeSize,
+		},
+		{
+			name: "extra data",
+			fs: func(tb testing.TB) afero.Fs {
+				tb.Helper()
+
+				info := newMockFileInfo(tb)
+				info.On("Size").Return(int64(100)).Twice()
+
+				one := newMockFile(tb)
+				one.On("Stat").Return(info, nil).Once()
+				one.On("Close").Return(nil).Once()
+				expectStartHeader(one, startHeaderBytes(tb, 150))
+
+				two := newMockFile(tb)
+				two.On("Stat").Return(info, nil).Once()
+				two.On("Close").Return(nil).Once()
+
+				fs := newMockFs(tb)
+				fs.On("Open", "filename.7z.001").Return(one, nil).Once()
+				fs.On("Open", "filename.7z.002").Return(two, nil).Once()
+
+				return fs
+			},
+			err: ErrVolumeSize,
+		},
 	}
 
 	for _, table := range tables {
 		t.Run(table.name, func(t *testing.T) {
 			t.Parallel()
 
-			_, _, files, err := openReader(table.fs(t), "filename.7z.001")
+			_, _, files, err := openReader(table.fs(t), "filename.7z.001", nil)
 			if table.err == nil {
 				require.NoError(t, err)
 			} else {
@@ -412,3 +580,570 @@ func TestOpenReader(t *testing.T) {
 		})
 	}
 }
+
+// nestedArchive returns an archive of a couple of stored files whose header
+// is encoded depth times, each level with opts.
+func nestedArchive(tb testing.TB, depth int, opts headerOptions) []byte {
+	tb.Helper()
+
+	buf := new(bytes.Buffer)
+	w := NewWriter(buf, WithStore())
+
+	for _, name := range []string{"a.txt", "b.txt"} {
+		fw, err := w.Create(name)
+		require.NoError(tb, err)
+
+		_, err = fw.Write([]byte("contents of " + name))
+		require.NoError(tb, err)
+	}
+
+	require.NoError(tb, w.Close())
+
+	z, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(tb, err)
+	require.NoError(tb, z.Close())
+
+	dataSize := z.si.packedOffset(len(z.si.packInfo.size))
+	packed := bytes.Clone(buf.Bytes()[signatureHeaderSize : signatureHeaderSize+dataSize])
+
+	level := new(bytes.Buffer)
+	writeHeader(level, z.h)
+
+	for range depth {
+		encoded, si, err := encodeHeader(level.Bytes(), uint64(len(packed)), opts)
+		require.NoError(tb, err)
+
+		packed = append(packed, encoded...)
+
+		level = new(bytes.Buffer)
+		_ = level.WriteByte(idEncodedHeader)
+		writeStreamsInfo(level, si)
+	}
+
+	start := new(bytes.Buffer)
+	require.NoError(tb, binary.Write(start, binary.LittleEndian, startHeader{
+		Offset: uint64(len(packed)),
+		Size:   uint64(level.Len()),
+		CRC:    crc32.ChecksumIEEE(level.Bytes()),
+	}))
+
+	b := new(bytes.Buffer)
+	require.NoError(tb, binary.Write(b, binary.LittleEndian, signatureHeader{
+		Signature: [6]byte{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c},
+		Minor:     4,
+		CRC:       crc32.ChecksumIEEE(start.Bytes()),
+	}))
+
+	return slices.Concat(b.Bytes(), start.Bytes(), packed, level.Bytes())
+}
+
+//nolint:funlen
+func TestNestedEncodedHeader(t *testing.T) {
+	t.Parallel()
+
+	tables := []struct {
+		name      string
+		depth     int
+		opts      headerOptions
+		password  string
+		encrypted bool
+		err       error
+	}{
+		{
+			name:  "single",
+			depth: 1,
+			opts:  headerOptions{compress: true},
+		},
+		{
+			name:  "double",
+			depth: 2,
+			opts:  headerOptions{compress: true},
+		},
+		{
+			name:      "encrypted",
+			depth:     2,
+			opts:      headerOptions{compress: true, password: "password"},
+			password:  "password",
+			encrypted: true,
+		},
+		{
+			name:  "deepest",
+			depth: maxHeaderDepth,
+			opts:  headerOptions{compress: true},
+		},
+		{
+			name:  "too deep",
+			depth: maxHeaderDepth + 1,
+			opts:  headerOptions{compress: true},
+			err:   errHeaderDepth,
+		},
+		{
+			name:  "encrypted without password",
+			depth: 2,
+			opts:  headerOptions{compress: true, password: "password"},
+			err:   ErrPasswordRequired,
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := nestedArchive(t, table.depth, table.opts)
+
+			z, err := NewReaderWithPassword(bytes.NewReader(b), int64(len(b)), table.password)
+			if table.err != nil {
+				require.ErrorIs(t, err, table.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				require.NoError(t, z.Close())
+			})
+
+			assert.Equal(t, table.encrypted, z.encryptedHeader)
+
+			for _, f := range z.File {
+				rc, err := f.Open()
+				require.NoError(t, err)
+
+				contents, err := io.ReadAll(rc)
+				require.NoError(t, err)
+				require.NoError(t, rc.Close())
+				assert.Equal(t, "contents of "+f.Name, string(contents))
+			}
+
+			assert.Len(t, z.File, 2)
+
+			info, err := z.Info()
+			require.NoError(t, err)
+			require.Len(t, info.HeaderChain, table.depth)
+
+			for _, block := range info.HeaderChain {
+				assert.Zero(t, block.NumFiles)
+				assert.Equal(t, table.encrypted, block.Encrypted())
+			}
+		})
+	}
+}
+
+// blockDigestArchive returns a Reader for a solid archive whose header only
+// has a CRC32 for the block, as some writers produce, along with the
+// contents of each file.
+func blockDigestArchive(t *testing.T, digest uint32) (*Reader, map[string][]byte) {
+	t.Helper()
+
+	contents := map[string][]byte{
+		"a.txt": []byte("contents of a.txt"),
+		"b.txt": bytes.Repeat([]byte("b"), 1000),
+		"c.txt": []byte("contents of c.txt"),
+	}
+
+	buf := new(bytes.Buffer)
+	w := NewWriter(buf, WithSolidBlockFiles(10))
+
+	h := crc32.NewIEEE()
+
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		fw, err := w.Create(name)
+		require.NoError(t, err)
+
+		_, err = fw.Write(contents[name])
+		require.NoError(t, err)
+
+		_, _ = h.Write(contents[name])
+	}
+
+	require.NoError(t, w.Close())
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	if digest == 0 {
+		digest = h.Sum32()
+	}
+
+	r.si.unpackInfo.digest, r.si.unpackInfo.defined = []uint32{digest}, []bool{true}
+
+	for _, f := range r.File {
+		f.CRC32, f.crcDefined = 0, false
+	}
+
+	return r, contents
+}
+
+//nolint:funlen
+func TestFileDigest(t *testing.T) {
+	t.Parallel()
+
+	tables := []struct {
+		name   string
+		decode func(*Reader) error
+	}{
+		{
+			name: "verify",
+			decode: func(r *Reader) error {
+				return r.verifyFolder(0, "")
+			},
+		},
+		{
+			name: "folder",
+			decode: func(r *Reader) error {
+				fr, err := r.OpenFolder(0)
+				if err != nil {
+					return err
+				}
+
+				_, err = io.Copy(io.Discard, fr)
+
+				return errors.Join(err, fr.Close())
+			},
+		},
+		{
+			name: "stream",
+			decode: func(r *Reader) error {
+				return r.StreamFolderFiles(0, func(*File, io.Reader) error {
+					return nil
+				})
+			},
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, contents := blockDigestArchive(t, 0)
+
+			for _, f := range r.File {
+				_, ok := f.Digest()
+				assert.False(t, ok, f.Name)
+			}
+
+			require.NoError(t, table.decode(r))
+
+			// Clones share what was worked out
+			c, err := r.Clone()
+			require.NoError(t, err)
+
+			for _, z := range []*Reader{r, c} {
+				for _, f := range z.File {
+					crc, ok := f.Digest()
+					assert.True(t, ok, f.Name)
+					assert.Equal(t, crc32.ChecksumIEEE(contents[f.Name]), crc, f.Name)
+					assert.Zero(t, f.CRC32, f.Name)
+				}
+			}
+
+			require.NoError(t, table.decode(r))
+
+			// Nothing is kept if the block doesn't match its CRC32
+			r, _ = blockDigestArchive(t, 1)
+
+			require.ErrorIs(t, table.decode(r), errChecksum)
+
+			for _, f := range r.File {
+				_, ok := f.Digest()
+				assert.False(t, ok, f.Name)
+			}
+		})
+	}
+}
+
+// TestFileDigestExtract checks extracting a whole block works out the CRC32
+// of each file the same as the other ways of decoding it. Extraction doesn't
+// fail if the block doesn't match, as every file has already been handed
+// over, but nothing is kept.
+func TestFileDigestExtract(t *testing.T) {
+	t.Parallel()
+
+	readAll := func(_ *File, r io.Reader) error {
+		_, err := io.Copy(io.Discard, r)
+
+		return err
+	}
+
+	tables := []struct {
+		name    string
+		extract func(*testing.T, *Reader) error
+	}{
+		{
+			name: "extract",
+			extract: func(_ *testing.T, r *Reader) error {
+				return r.Extract(context.Background(), readAll)
+			},
+		},
+		{
+			name: "queued",
+			extract: func(_ *testing.T, r *Reader) error {
+				return r.Extract(context.Background(), readAll, WithWriteQueue(2))
+			},
+		},
+		{
+			name: "extract all",
+			extract: func(t *testing.T, r *Reader) error {
+				return r.ExtractAll(context.Background(), t.TempDir())
+			},
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, contents := blockDigestArchive(t, 0)
+
+			require.NoError(t, table.extract(t, r))
+
+			for _, f := range r.File {
+				crc, ok := f.Digest()
+				assert.True(t, ok, f.Name)
+				assert.Equal(t, crc32.ChecksumIEEE(contents[f.Name]), crc, f.Name)
+			}
+
+			r, _ = blockDigestArchive(t, 1)
+
+			require.NoError(t, table.extract(t, r))
+
+			for _, f := range r.File {
+				_, ok := f.Digest()
+				assert.False(t, ok, f.Name)
+			}
+		})
+	}
+
+	// Nor if a file isn't read to the end
+	r, _ := blockDigestArchive(t, 0)
+
+	require.NoError(t, r.Extract(context.Background(), func(f *File, r io.Reader) error {
+		if f.Name == "b.txt" {
+			_, err := io.CopyN(io.Discard, r, 10)
+
+			return err
+		}
+
+		return readAll(f, r)
+	}))
+
+	for _, f := range r.File {
+		_, ok := f.Digest()
+		assert.False(t, ok, f.Name)
+	}
+}
+
+// packedArchive returns nestedArchive with a single level of encoded header
+// using opts, or none, after mutate has changed the packed streams declared
+// for the files.
+func packedArchive(tb testing.TB, opts headerOptions, mutate func(*packInfo)) []byte {
+	tb.Helper()
+
+	b := nestedArchive(tb, 0, headerOptions{})
+
+	z, err := NewReader(bytes.NewReader(b), int64(len(b)))
+	require.NoError(tb, err)
+	require.NoError(tb, z.Close())
+
+	dataSize := z.si.packedOffset(len(z.si.packInfo.size))
+	packed := b[signatureHeaderSize : signatureHeaderSize+dataSize]
+
+	mutate(z.si.packInfo)
+
+	prefix, suffix, err := encodeArchive(z.h, uint64(dataSize), opts) //nolint:gosec
+	require.NoError(tb, err)
+
+	return slices.Concat(prefix, packed, suffix)
+}
+
+//nolint:funlen
+func TestPackedStreams(t *testing.T) {
+	t.Parallel()
+
+	tables := []struct {
+		name   string
+		opts   headerOptions
+		mutate func(*packInfo)
+		err    error
+		stream int
+		header bool
+	}{
+		{
+			name:   "valid",
+			mutate: func(*packInfo) {},
+		},
+		{
+			name:   "valid encoded",
+			opts:   headerOptions{compress: true},
+			mutate: func(*packInfo) {},
+		},
+		{
+			name: "beyond the end",
+			mutate: func(p *packInfo) {
+				p.size[1] = 1 << 40
+			},
+			err:    errPackBeyond,
+			stream: 1,
+		},
+		{
+			name: "wrapping around",
+			mutate: func(p *packInfo) {
+				p.size[1] = math.MaxUint64 - 10
+			},
+			err:    errPackBeyond,
+			stream: 1,
+		},
+		{
+			name: "into the header",
+			mutate: func(p *packInfo) {
+				p.position += 10
+			},
+			err:    errPackHeader,
+			stream: 1,
+		},
+		{
+			name: "overlapping the encoded header",
+			opts: headerOptions{compress: true},
+			mutate: func(p *packInfo) {
+				p.size[1] += 10
+			},
+			err:    errPackOverlap,
+			header: true,
+		},
+		{
+			name: "undeclared",
+			mutate: func(p *packInfo) {
+				p.streams, p.size = 1, p.size[:1]
+				if len(p.digest) > 0 {
+					p.digest, p.defined = p.digest[:1], p.defined[:1]
+				}
+			},
+			err:    errPackMissing,
+			stream: 1,
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := packedArchive(t, table.opts, table.mutate)
+
+			z, err := NewReader(bytes.NewReader(b), int64(len(b)))
+			if table.err == nil {
+				require.NoError(t, err)
+
+				for _, f := range z.File {
+					rc, err := f.Open()
+					require.NoError(t, err)
+
+					contents, err := io.ReadAll(rc)
+					require.NoError(t, err)
+					require.NoError(t, rc.Close())
+					assert.Equal(t, "contents of "+f.Name, string(contents))
+				}
+
+				require.NoError(t, z.Close())
+
+				return
+			}
+
+			require.ErrorIs(t, err, table.err)
+
+			var pe *PackError
+			require.ErrorAs(t, err, &pe)
+			assert.Equal(t, table.stream, pe.Stream)
+			assert.Equal(t, table.header, pe.Header)
+		})
+	}
+}
+
+// oversizedArchive returns a solid archive of a couple of stored files where
+// the first declares more than the whole block.
+func oversizedArchive(tb testing.TB) []byte {
+	tb.Helper()
+
+	buf := new(bytes.Buffer)
+	w := NewWriter(buf, WithStore(), WithSolidBlockFiles(10))
+
+	for _, name := range []string{"a.txt", "b.txt"} {
+		fw, err := w.Create(name)
+		require.NoError(tb, err)
+
+		_, err = fw.Write([]byte("contents of " + name))
+		require.NoError(tb, err)
+	}
+
+	require.NoError(tb, w.Close())
+
+	z, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(tb, err)
+	require.NoError(tb, z.Close())
+
+	dataSize := z.si.packedOffset(len(z.si.packInfo.size))
+	packed := buf.Bytes()[signatureHeaderSize : signatureHeaderSize+dataSize]
+
+	z.si.subStreamsInfo.size[0] = z.si.unpackInfo.folder[0].unpackSize() + 5
+	z.si.subStreamsInfo.digest, z.si.subStreamsInfo.defined = nil, nil
+
+	prefix, suffix, err := encodeArchive(z.h, uint64(dataSize), headerOptions{}) //nolint:gosec
+	require.NoError(tb, err)
+
+	return slices.Concat(prefix, packed, suffix)
+}
+
+func TestOversizedFile(t *testing.T) {
+	t.Parallel()
+
+	b := oversizedArchive(t)
+
+	// Only the oversized files fail to open
+	z, err := NewReader(bytes.NewReader(b), int64(len(b)))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, z.Close())
+	})
+
+	require.Len(t, z.File, 2)
+
+	_, err = z.File[0].Open()
+
+	var oe *OversizedError
+	require.ErrorAs(t, err, &oe)
+	assert.Equal(t, OversizedError{Name: "a.txt", Size: 39, Block: 0, BlockSize: 34, Left: 34}, *oe)
+	assert.EqualError(t, err, "sevenzip: a.txt declares 39 bytes but only 34 are left of the 34 bytes of block 0")
+
+	_, err = z.OpenFolder(0)
+	require.ErrorAs(t, err, &oe)
+
+	report, err := z.Health(context.Background())
+	require.NoError(t, err)
+	require.NotEmpty(t, report.Issues)
+	assert.Equal(t, "2 files declare more data than their block holds", report.Issues[0].Problem)
+
+	z, err = NewReaderWithOptions(bytes.NewReader(b), int64(len(b)), WithTruncateOversized())
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, z.Close())
+	})
+
+	require.Len(t, z.File, 2)
+	assert.Equal(t, uint64(34), z.File[0].UncompressedSize)
+	assert.Equal(t, uint64(0), z.File[1].UncompressedSize)
+
+	info, err := z.Info()
+	require.NoError(t, err)
+	require.Len(t, info.Warnings, 2)
+	assert.Equal(t, "a.txt declares 39 bytes but only 34 are left of block 0, truncated", info.Warnings[0])
+
+	rc, err := z.File[0].Open()
+	require.NoError(t, err)
+
+	contents, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	assert.Equal(t, "contents of a.txtcontents of b.txt", string(contents))
+}
@@ -1,21 +1,28 @@
 package sevenzip_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"hash"
 	"hash/crc32"
 	"io"
+	iofs "io/fs"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"sync"
 	"testing"
 	"testing/fstest"
 	"testing/iotest"
+	"time"
 
 	"github.com/javi11/sevenzip"
 	"github.com/javi11/sevenzip/internal/util"
+	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/sync/errgroup"
@@ -180,10 +187,38 @@ func TestOpenReader(t *testing.T) {
 			name: "arm",
 			file: "arm.7z",
 		},
+		{
+			name: "bcj with start offset",
+			file: "x86_offset.7z",
+		},
+		{
+			name: "arm with start offset",
+			file: "arm_offset.7z",
+		},
 		{
 			name: "sparc",
 			file: "sparc.7z",
 		},
+		{
+			name: "riscv",
+			file: "riscv.7z",
+		},
+		{
+			name: "ia64",
+			file: "ia64.7z",
+		},
+		{
+			name: "armt",
+			file: "armt.7z",
+		},
+		{
+			name: "external header properties",
+			file: "external.7z",
+		},
+		{
+			name: "padded pack streams",
+			file: "padded.7z",
+		},
 		{
 			name: "issue 87",
 			file: "issue87.7z",
@@ -195,7 +230,6 @@ func TestOpenReader(t *testing.T) {
 		{
 			name: "issue 113",
 			file: "COMPRESS-492.7z",
-			err:  sevenzip.ErrMissingUnpackInfo,
 		},
 	}
 
@@ -237,6 +271,209 @@ func TestOpenReader(t *testing.T) {
 	}
 }
 
+// memFs returns an in-memory filesystem holding a copy of each of the named
+// files from testdata.
+func memFs(t *testing.T, names ...string) afero.Fs {
+	t.Helper()
+
+	fs := afero.NewMemMapFs()
+
+	for _, name := range names {
+		b, err := os.ReadFile(filepath.Join("testdata", name))
+		require.NoError(t, err)
+		require.NoError(t, afero.WriteFile(fs, name, b, 0o644))
+	}
+
+	return fs
+}
+
+func TestConcurrentOpen(t *testing.T) {
+	t.Parallel()
+
+	tables := []struct {
+		name, file string
+		fs         afero.Fs
+		opts       []sevenzip.ReaderOption
+	}{
+		{
+			name: "solid",
+			file: "lzma1900.7z",
+		},
+		{
+			name: "multiple streams",
+			file: "copy.7z",
+		},
+		{
+			name: "bcj2",
+			file: "bcj2.7z",
+		},
+		{
+			name: "encrypted",
+			file: "aes7z.7z",
+			opts: []sevenzip.ReaderOption{sevenzip.WithPassword("password")},
+		},
+		{
+			name: "multiple volumes",
+			file: "multi.7z.001",
+		},
+		{
+			name: "memory filesystem",
+			file: "lzma1900.7z",
+			fs:   memFs(t, "lzma1900.7z"),
+		},
+		{
+			name: "single cached decoder",
+			file: "lzma1900.7z",
+			opts: []sevenzip.ReaderOption{sevenzip.WithCacheSize(1)},
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			name, opts := filepath.Join("testdata", table.file), table.opts
+			if table.fs != nil {
+				name, opts = table.file, append(opts, sevenzip.WithFs(table.fs))
+			}
+
+			r, err := sevenzip.OpenReaderWithOptions(name, opts...)
+			require.NoError(t, err)
+
+			defer func() {
+				require.NoError(t, r.Close())
+			}()
+
+			// Every goroutine reads every file, each starting at a
+			// different point so the same file and stream are often
+			// being read at the same time
+			eg := new(errgroup.Group)
+
+			for g := range 8 {
+				eg.Go(func() error {
+					h := crc32.NewIEEE()
+
+					for i := range r.File {
+						f := r.File[(i+g*len(r.File)/8)%len(r.File)]
+
+						rc, err := f.Open()
+						if err != nil {
+							return err
+						}
+
+						if err := errors.Join(extractFile(t, rc, h, f), rc.Close()); err != nil {
+							return fmt.Errorf("%s: %w", f.Name, err)
+						}
+					}
+
+					return nil
+				})
+			}
+
+			require.NoError(t, eg.Wait())
+			assert.Zero(t, r.OpenHandles())
+		})
+	}
+}
+
+func TestClone(t *testing.T) {
+	t.Parallel()
+
+	b, err := os.ReadFile(filepath.Join("testdata", "lzma1900.7z"))
+	require.NoError(t, err)
+
+	r, err := sevenzip.NewReader(bytes.NewReader(b), int64(len(b)))
+	require.NoError(t, err)
+
+	c, err := r.Clone()
+	require.NoError(t, err)
+
+	require.Len(t, c.File, len(r.File))
+
+	for i := range r.File {
+		assert.NotSame(t, r.File[i], c.File[i])
+		assert.Equal(t, r.File[i].FileHeader, c.File[i].FileHeader)
+	}
+
+	// Closing the original doesn't affect the clone
+	require.NoError(t, r.Close())
+	require.NoError(t, extractArchive(t, c, -1, crc32.NewIEEE(), iotest.OneByteReader, true))
+	require.NoError(t, c.Close())
+}
+
+func TestReadCloserClone(t *testing.T) {
+	t.Parallel()
+
+	r, err := sevenzip.OpenReader(filepath.Join("testdata", "multi.7z.001"))
+	require.NoError(t, err)
+
+	c, err := r.Clone()
+	require.NoError(t, err)
+
+	assert.Equal(t, r.Volumes(), c.Volumes())
+
+	// The clone has its own volume handles
+	require.NoError(t, r.Close())
+	require.NoError(t, extractArchive(t, &c.Reader, -1, crc32.NewIEEE(), reader, true))
+	require.NoError(t, c.Close())
+}
+
+func TestFileByName(t *testing.T) {
+	t.Parallel()
+
+	r, err := sevenzip.OpenReader(filepath.Join("testdata", "empty.7z"))
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, r.Close())
+	}()
+
+	for i, f := range r.File {
+		got, ok := r.FileByName(f.Name)
+		require.True(t, ok, f.Name)
+		assert.Same(t, f, got)
+
+		got, ok = r.FileByIndex(i)
+		require.True(t, ok)
+		assert.Same(t, f, got)
+	}
+
+	f, ok := r.FileByName("01")
+	require.True(t, ok)
+	assert.Equal(t, "01/", f.Name)
+
+	_, ok = r.FileByName("missing")
+	assert.False(t, ok)
+
+	_, ok = r.FileByIndex(-1)
+	assert.False(t, ok)
+
+	_, ok = r.FileByIndex(len(r.File))
+	assert.False(t, ok)
+}
+
+func TestOpenReaderExternal(t *testing.T) {
+	t.Parallel()
+
+	r, err := sevenzip.OpenReader(filepath.Join("testdata", "external.7z"))
+	require.NoError(t, err)
+
+	defer func() {
+		if err := r.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	require.Len(t, r.File, 2)
+
+	for i, name := range []string{"hello.txt", "world.txt"} {
+		assert.Equal(t, name, r.File[i].Name)
+		assert.True(t, modified.Equal(r.File[i].Modified))
+	}
+}
+
 func TestOpenReaderWithPassword(t *testing.T) {
 	t.Parallel()
 
@@ -304,6 +541,13 @@ func TestOpenReaderWithWrongPassword(t *testing.T) {
 		if assert.ErrorAs(t, err, &e) {
 			assert.True(t, e.Encrypted)
 		}
+
+		var he *sevenzip.HeaderPasswordError
+		assert.ErrorAs(t, err, &he)
+
+		var de *sevenzip.DataPasswordError
+		assert.False(t, errors.As(err, &de))
+		assert.NotErrorIs(t, err, sevenzip.ErrPasswordRequired)
 	})
 
 	t.Run("unencrypted headers compressed files", func(t *testing.T) {
@@ -322,6 +566,12 @@ func TestOpenReaderWithWrongPassword(t *testing.T) {
 		if assert.ErrorAs(t, err, &e) {
 			assert.True(t, e.Encrypted)
 		}
+
+		var de *sevenzip.DataPasswordError
+		assert.ErrorAs(t, err, &de)
+
+		var he *sevenzip.HeaderPasswordError
+		assert.False(t, errors.As(err, &he))
 	})
 
 	t.Run("unencrypted headers uncompressed files", func(t *testing.T) {
@@ -339,6 +589,59 @@ func TestOpenReaderWithWrongPassword(t *testing.T) {
 	})
 }
 
+func TestOpenReaderWithoutPassword(t *testing.T) {
+	t.Parallel()
+
+	t.Run("encrypted headers", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := sevenzip.OpenReader(filepath.Join("testdata", "t2.7z"))
+
+		var e *sevenzip.ReadError
+		if assert.ErrorAs(t, err, &e) {
+			assert.True(t, e.Encrypted)
+		}
+
+		var he *sevenzip.HeaderPasswordError
+		assert.ErrorAs(t, err, &he)
+		assert.ErrorIs(t, err, sevenzip.ErrPasswordRequired)
+	})
+
+	t.Run("unencrypted headers", func(t *testing.T) {
+		t.Parallel()
+
+		r, err := sevenzip.OpenReader(filepath.Join("testdata", "t4.7z"))
+		require.NoError(t, err)
+
+		defer func() {
+			require.NoError(t, r.Close())
+		}()
+
+		err = extractArchive(t, &r.Reader, -1, crc32.NewIEEE(), iotest.OneByteReader, true)
+		assert.ErrorIs(t, err, sevenzip.ErrPasswordRequired)
+
+		var de *sevenzip.DataPasswordError
+		assert.ErrorAs(t, err, &de)
+	})
+}
+
+func TestOpenReaderMissingVolumes(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	for i := 1; i <= 3; i++ {
+		name := fmt.Sprintf("multi.7z.%03d", i)
+
+		b, err := os.ReadFile(filepath.Join("testdata", name))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), b, 0o600))
+	}
+
+	_, err := sevenzip.OpenReader(filepath.Join(dir, "multi.7z.001"))
+	assert.ErrorIs(t, err, sevenzip.ErrTruncated)
+}
+
 func TestNewReader(t *testing.T) {
 	t.Parallel()
 
@@ -357,6 +660,12 @@ func TestNewReader(t *testing.T) {
 			size: -1,
 			err:  sevenzip.ErrNegativeSize,
 		},
+		{
+			name: "truncated",
+			file: "t0.7z",
+			size: 100,
+			err:  sevenzip.ErrTruncated,
+		},
 	}
 
 	for _, table := range tables {
@@ -400,6 +709,217 @@ func TestNewReader(t *testing.T) {
 	}
 }
 
+func TestNewReaderAppended(t *testing.T) {
+	t.Parallel()
+
+	archive, err := os.ReadFile(filepath.Join("testdata", "copy.7z"))
+	require.NoError(t, err)
+
+	tables := []struct {
+		name    string
+		payload int64
+		opts    []sevenzip.ReaderOption
+		err     error
+	}{
+		{
+			name:    "found by search",
+			payload: 4096,
+		},
+		{
+			name:    "beyond search",
+			payload: 2 << 20,
+			err:     sevenzip.ErrFormat,
+		},
+		{
+			name:    "with base offset",
+			payload: 2 << 20,
+			opts:    []sevenzip.ReaderOption{sevenzip.WithBaseOffset(2 << 20)},
+		},
+		{
+			name:    "wrong base offset",
+			payload: 4096,
+			opts:    []sevenzip.ReaderOption{sevenzip.WithBaseOffset(4000)},
+			err:     sevenzip.ErrFormat,
+		},
+		{
+			name:    "base offset past end",
+			payload: 4096,
+			opts:    []sevenzip.ReaderOption{sevenzip.WithBaseOffset(1 << 30)},
+			err:     sevenzip.ErrFormat,
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Simulate an archive appended to a disk image or installer
+			b := append(bytes.Repeat([]byte{0xaa}, int(table.payload)), archive...)
+
+			r, err := sevenzip.NewReaderWithOptions(bytes.NewReader(b), int64(len(b)), table.opts...)
+			if table.err != nil {
+				assert.ErrorIs(t, err, table.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, table.payload, r.BaseOffset())
+
+			files, err := r.ListFilesWithOffsets()
+			require.NoError(t, err)
+
+			for _, file := range files {
+				rc, err := r.Open(file.Name)
+				require.NoError(t, err)
+
+				expected, err := io.ReadAll(rc)
+				require.NoError(t, err)
+				require.NoError(t, rc.Close())
+
+				assert.Equal(t, expected, b[file.Offset:file.Offset+int64(file.Size)], file.Name) //nolint:gosec
+			}
+		})
+	}
+}
+
+//nolint:funlen
+func TestNewReaderSFXInstaller(t *testing.T) {
+	t.Parallel()
+
+	archive, err := os.ReadFile(filepath.Join("testdata", "t2.7z"))
+	require.NoError(t, err)
+
+	// A signature in the stub with a start header that checks out but
+	// points beyond the end of the file
+	decoy := []byte{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c, 0, 4, 0, 0, 0, 0}
+	start := binary.LittleEndian.AppendUint64(nil, 1<<40)
+	start = binary.LittleEndian.AppendUint64(start, 100)
+	start = binary.LittleEndian.AppendUint32(start, 0)
+	binary.LittleEndian.PutUint32(decoy[8:], crc32.ChecksumIEEE(start))
+	decoy = append(decoy, start...)
+
+	config := []byte(";!@Install@!UTF-8!\r\nTitle=\"Installer\"\r\nRunProgram=\"setup.exe\"\r\n;!@InstallEnd@!")
+
+	tables := []struct {
+		name   string
+		stub   int
+		config []byte
+		opts   []sevenzip.ReaderOption
+		err    error
+	}{
+		{
+			name:   "config",
+			stub:   4096,
+			config: config,
+		},
+		{
+			name: "no config",
+			stub: 4096,
+		},
+		{
+			// Only the decoy is found
+			name:   "beyond search",
+			stub:   2 << 20,
+			config: config,
+			err:    sevenzip.ErrTruncated,
+		},
+		{
+			name:   "with search limit",
+			stub:   2 << 20,
+			config: config,
+			opts:   []sevenzip.ReaderOption{sevenzip.WithSearchLimit(4 << 20)},
+		},
+		{
+			name:   "whole file",
+			stub:   2 << 20,
+			config: config,
+			opts:   []sevenzip.ReaderOption{sevenzip.WithSearchLimit(0)},
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			stub := append([]byte("MZ"), bytes.Repeat([]byte{0xaa}, table.stub-2)...)
+			copy(stub[table.stub/2:], decoy)
+
+			b := slices.Concat(stub, table.config, []byte("\r\n"), archive)
+
+			opts := append([]sevenzip.ReaderOption{sevenzip.WithPassword("password")}, table.opts...)
+
+			r, err := sevenzip.NewReaderWithOptions(bytes.NewReader(b), int64(len(b)), opts...)
+			if table.err != nil {
+				assert.ErrorIs(t, err, table.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(b)-len(archive)), r.BaseOffset())
+
+			info, err := r.Info()
+			require.NoError(t, err)
+
+			if table.config != nil {
+				assert.Equal(t, int64(table.stub), info.StubSize)
+				assert.Equal(t, table.config, info.SFXConfig)
+			} else {
+				assert.Equal(t, r.BaseOffset(), info.StubSize)
+				assert.Nil(t, info.SFXConfig)
+			}
+
+			if err := extractArchive(t, r, -1, crc32.NewIEEE(), iotest.OneByteReader, true); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestOpenReaderWithNameFilter(t *testing.T) {
+	t.Parallel()
+
+	name := filepath.Join("testdata", "lzma1900.7z")
+
+	all, err := sevenzip.OpenReader(name)
+	require.NoError(t, err)
+
+	defer all.Close()
+
+	// Pick a file from the middle of the solid block so the offsets of
+	// the files before it still have to be accounted for
+	want := all.File[len(all.File)/2]
+
+	r, err := sevenzip.OpenReaderWithOptions(name, sevenzip.WithNameFilter(func(name string) bool {
+		return name == want.Name
+	}))
+	require.NoError(t, err)
+
+	defer r.Close()
+
+	require.Len(t, r.File, 1)
+	assert.Equal(t, want.FileHeader, r.File[0].FileHeader)
+
+	for _, f := range []*sevenzip.File{want, r.File[0]} {
+		rc, err := f.Open()
+		require.NoError(t, err)
+
+		h := crc32.NewIEEE()
+		_, err = io.Copy(h, rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+
+		assert.Equal(t, want.CRC32, h.Sum32())
+	}
+
+	_, err = r.Open(all.File[0].Name)
+	assert.ErrorIs(t, err, iofs.ErrNotExist)
+
+	assert.ErrorIs(t, sevenzip.Normalize(&r.Reader, io.Discard), sevenzip.ErrFiltered)
+	assert.ErrorIs(t, sevenzip.RemoveEncryption(&r.Reader, io.Discard, ""), sevenzip.ErrFiltered)
+}
+
 func TestFS(t *testing.T) {
 	t.Parallel()
 
@@ -648,6 +1168,18 @@ func BenchmarkSPARC(b *testing.B) {
 	benchmarkArchive(b, "sparc.7z", "", true)
 }
 
+func BenchmarkRISCV(b *testing.B) {
+	benchmarkArchive(b, "riscv.7z", "", true)
+}
+
+func BenchmarkIA64(b *testing.B) {
+	benchmarkArchive(b, "ia64.7z", "", true)
+}
+
+func BenchmarkARMT(b *testing.B) {
+	benchmarkArchive(b, "armt.7z", "", true)
+}
+
 func TestListFilesWithOffsets(t *testing.T) {
 	t.Parallel()
 
@@ -687,6 +1219,38 @@ func TestListFilesWithOffsets(t *testing.T) {
 		assert.Equal(t, len(files), uncompressedCount, "All files in copy.7z should be uncompressed")
 	})
 
+	// Test with an archive whose pack streams are padded to 16 byte
+	// boundaries, including before the first stream
+	t.Run("PaddedStreams", func(t *testing.T) {
+		name := filepath.Join("testdata", "padded.7z")
+
+		r, err := sevenzip.OpenReader(name)
+		require.NoError(t, err)
+		defer r.Close()
+
+		b, err := os.ReadFile(name)
+		require.NoError(t, err)
+
+		files, err := r.ListFilesWithOffsets()
+		require.NoError(t, err)
+
+		expected := map[string]string{
+			"a.txt": "alpha",
+			"b.txt": "bravo charlie",
+			"c.txt": "delta\n\n",
+		}
+
+		require.Len(t, files, len(expected))
+
+		blocks := r.Blocks()
+
+		for _, file := range files {
+			assert.Equal(t, expected[file.Name], string(b[file.Offset:file.Offset+int64(file.Size)]), file.Name) //nolint:gosec
+			assert.Equal(t, uint64(16), file.PackedSize, file.Name)
+			assert.Equal(t, uint64(16), blocks[file.FolderIndex].PackedSize, file.Name)
+		}
+	})
+
 	// Test with a compressed archive
 	t.Run("CompressedFiles", func(t *testing.T) {
 		r, err := sevenzip.OpenReader(filepath.Join("testdata", "lzma.7z"))
@@ -859,3 +1423,160 @@ func TestListFilesWithOffsets(t *testing.T) {
 		}
 	})
 }
+
+func TestMissingStreams(t *testing.T) {
+	t.Parallel()
+
+	r, err := sevenzip.OpenReader(filepath.Join("testdata", "COMPRESS-492.7z"))
+	require.NoError(t, err)
+	assert.Empty(t, r.File)
+	require.NoError(t, r.Close())
+
+	r, err = sevenzip.OpenReader(filepath.Join("testdata", "nostreams.7z"))
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, r.Close())
+	}()
+
+	require.Len(t, r.File, 3)
+
+	tables := []struct {
+		name      string
+		hasStream bool
+		err       error
+	}{
+		{
+			name: "dir/",
+		},
+		{
+			name: "dir/a.txt",
+			err:  sevenzip.ErrMissingUnpackInfo,
+		},
+		{
+			name: "empty.txt",
+		},
+	}
+
+	for i, table := range tables {
+		f := r.File[i]
+		assert.Equal(t, table.name, f.Name)
+		assert.Equal(t, table.hasStream, f.HasStream(), f.Name)
+		assert.Nil(t, f.Coders(), f.Name)
+
+		rc, err := f.Open()
+		if table.err != nil {
+			var re *sevenzip.ReadError
+
+			assert.ErrorAs(t, err, &re)
+			assert.ErrorIs(t, err, table.err)
+
+			continue
+		}
+
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+	}
+
+	err = r.ExtractAll(context.Background(), t.TempDir())
+	assert.ErrorIs(t, err, sevenzip.ErrMissingUnpackInfo)
+}
+
+func TestUnsupportedVersion(t *testing.T) {
+	t.Parallel()
+
+	archive, err := os.ReadFile(filepath.Join("testdata", "copy.7z"))
+	require.NoError(t, err)
+
+	// The version isn't covered by any CRC
+	version := func(major, minor byte, corrupt bool) []byte {
+		b := bytes.Clone(archive)
+		b[6], b[7] = major, minor
+
+		if corrupt {
+			// Replace the header ID
+			b[32+binary.LittleEndian.Uint64(b[12:])] = 0xff
+		}
+
+		return b
+	}
+
+	tables := []struct {
+		name     string
+		archive  []byte
+		opts     []sevenzip.ReaderOption
+		err      error
+		version  bool
+		warnings int
+	}{
+		{
+			name:    "legacy",
+			archive: version(0, 3, false),
+		},
+		{
+			name:     "newer",
+			archive:  version(0, 5, false),
+			warnings: 1,
+		},
+		{
+			name:    "newer strict",
+			archive: version(0, 5, false),
+			opts:    []sevenzip.ReaderOption{sevenzip.WithStrictVersion()},
+			err:     sevenzip.ErrUnsupportedVersion,
+			version: true,
+		},
+		{
+			name:    "newer corrupt",
+			archive: version(0, 5, true),
+			err:     sevenzip.ErrUnsupportedVersion,
+			version: true,
+		},
+		{
+			name:    "major",
+			archive: version(1, 0, false),
+			err:     sevenzip.ErrUnsupportedVersion,
+			version: true,
+		},
+		{
+			name:    "legacy corrupt",
+			archive: version(0, 2, true),
+			err:     sevenzip.ErrUnsupportedVersion,
+			version: true,
+		},
+		{
+			name:    "corrupt",
+			archive: version(0, 4, true),
+			err:     sevenzip.ErrUnexpectedID,
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := sevenzip.NewReaderWithOptions(bytes.NewReader(table.archive), int64(len(table.archive)), table.opts...)
+			if table.err == nil {
+				require.NoError(t, err)
+
+				info, err := r.Info()
+				require.NoError(t, err)
+				assert.Equal(t, table.archive[7], info.MinorVersion)
+				assert.Len(t, info.Warnings, table.warnings)
+
+				health, err := r.Health(context.Background())
+				require.NoError(t, err)
+				assert.Equal(t, 100-10*table.warnings, health.Score)
+
+				return
+			}
+
+			require.ErrorIs(t, err, table.err)
+
+			if table.version {
+				assert.ErrorContains(t, err, fmt.Sprintf("%d.%d", table.archive[6], table.archive[7]))
+			} else {
+				assert.NotErrorIs(t, err, sevenzip.ErrUnsupportedVersion)
+			}
+		})
+	}
+}
@@ -2,31 +2,35 @@ package sevenzip
 
 import (
 	"errors"
+	"fmt"
 	"io"
+	"slices"
+	"strings"
 	"sync"
 
 	"github.com/javi11/sevenzip/internal/aes7z"
-	"github.com/javi11/sevenzip/internal/bcj2"
-	"github.com/javi11/sevenzip/internal/bra"
-	"github.com/javi11/sevenzip/internal/brotli"
-	"github.com/javi11/sevenzip/internal/bzip2"
-	"github.com/javi11/sevenzip/internal/deflate"
-	"github.com/javi11/sevenzip/internal/delta"
-	"github.com/javi11/sevenzip/internal/lz4"
-	"github.com/javi11/sevenzip/internal/lzma"
 	"github.com/javi11/sevenzip/internal/lzma2"
-	"github.com/javi11/sevenzip/internal/zstd"
 )
 
 // Decompressor describes the function signature that decompression/decryption
 // methods must implement to return a new instance of themselves. They are
 // passed any property bytes, the size of the stream and a slice of at least
-// one io.ReadCloser's providing the stream(s) of bytes.
+// one io.ReadCloser's providing the stream(s) of bytes. Methods with more
+// than one input stream, such as BCJ2, are passed one io.ReadCloser for each
+// in the order they are numbered by the coder.
 type Decompressor func([]byte, uint64, []io.ReadCloser) (io.ReadCloser, error)
 
+// MultiDecompressor is the equivalent of [Decompressor] for methods with more
+// than one output stream. They are passed the size of each output stream
+// instead and must return an io.ReadCloser for each, in the order they are
+// numbered by the coder.
+type MultiDecompressor func([]byte, []uint64, []io.ReadCloser) ([]io.ReadCloser, error)
+
 var (
 	//nolint:gochecknoglobals
 	decompressors sync.Map
+	//nolint:gochecknoglobals
+	multiDecompressors sync.Map
 
 	errNeedOneReader = errors.New("copy: need exactly one reader")
 )
@@ -39,45 +43,142 @@ func newCopyReader(_ []byte, _ uint64, readers []io.ReadCloser) (io.ReadCloser,
 	return readers[0], nil
 }
 
+// Copy, LZMA2 and AES are always available as the Writer uses them by
+// default. Every other method is registered in its own file so it can be
+// compiled out with a build tag: sevenzip_nodelta, sevenzip_nolzma,
+// sevenzip_nobcj for BCJ, PPC, IA64, ARM, ARMT, SPARC and RISCV,
+// sevenzip_nobcj2, sevenzip_noppmd, sevenzip_nodeflate, sevenzip_nobzip2,
+// sevenzip_nozstd, sevenzip_nobrotli and sevenzip_nolz4, or all of them with
+// sevenzip_minimal.
+//
 //nolint:gochecknoinits
 func init() {
 	// Copy
 	RegisterDecompressor([]byte{0x00}, Decompressor(newCopyReader))
-	// Delta
-	RegisterDecompressor([]byte{0x03}, Decompressor(delta.NewReader))
-	// LZMA
-	RegisterDecompressor([]byte{0x03, 0x01, 0x01}, Decompressor(lzma.NewReader))
-	// BCJ
-	RegisterDecompressor([]byte{0x03, 0x03, 0x01, 0x03}, Decompressor(bra.NewBCJReader))
-	// BCJ2
-	RegisterDecompressor([]byte{0x03, 0x03, 0x01, 0x1b}, Decompressor(bcj2.NewReader))
-	// PPC
-	RegisterDecompressor([]byte{0x03, 0x03, 0x02, 0x05}, Decompressor(bra.NewPPCReader))
-	// ARM
-	RegisterDecompressor([]byte{0x03, 0x03, 0x05, 0x01}, Decompressor(bra.NewARMReader))
-	// SPARC
-	RegisterDecompressor([]byte{0x03, 0x03, 0x08, 0x05}, Decompressor(bra.NewSPARCReader))
-	// Deflate
-	RegisterDecompressor([]byte{0x04, 0x01, 0x08}, Decompressor(deflate.NewReader))
-	// Bzip2
-	RegisterDecompressor([]byte{0x04, 0x02, 0x02}, Decompressor(bzip2.NewReader))
-	// Zstandard
-	RegisterDecompressor([]byte{0x04, 0xf7, 0x11, 0x01}, Decompressor(zstd.NewReader))
-	// Brotli
-	RegisterDecompressor([]byte{0x04, 0xf7, 0x11, 0x02}, Decompressor(brotli.NewReader))
-	// LZ4
-	RegisterDecompressor([]byte{0x04, 0xf7, 0x11, 0x04}, Decompressor(lz4.NewReader))
 	// AES-CBC-256 & SHA-256
 	RegisterDecompressor([]byte{0x06, 0xf1, 0x07, 0x01}, Decompressor(aes7z.NewReader))
 	// LZMA2
 	RegisterDecompressor([]byte{0x21}, Decompressor(lzma2.NewReader))
 }
 
+// optionalMethods are the IDs of the methods that can be compiled out.
+//
+//nolint:gochecknoglobals
+var optionalMethods = []string{
+	"\x03",             // Delta
+	"\x03\x01\x01",     // LZMA
+	"\x03\x03\x01\x03", // BCJ
+	"\x03\x03\x01\x1b", // BCJ2
+	"\x03\x03\x02\x05", // PPC
+	"\x03\x03\x04\x01", // IA64
+	"\x03\x03\x05\x01", // ARM
+	"\x03\x03\x07\x01", // ARMT
+	"\x03\x03\x08\x05", // SPARC
+	"\x03\x04\x01",     // PPMd
+	"\x04\x01\x08",     // Deflate
+	"\x04\x02\x02",     // Bzip2
+	"\x04\xf7\x11\x01", // Zstandard
+	"\x04\xf7\x11\x02", // Brotli
+	"\x04\xf7\x11\x04", // LZ4
+	"\x0b",             // RISCV
+}
+
+// compiledOut returns the names of the methods compiled out of this build
+// that haven't been registered since.
+func compiledOut() []string {
+	var names []string
+
+	for _, method := range optionalMethods {
+		if decompressor([]byte(method)) == nil {
+			names = append(names, Coder{ID: []byte(method)}.Name())
+		}
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// unsupportedMethod returns the error for method not having a decompressor,
+// listing what was compiled out if it was one of them.
+func unsupportedMethod(method []byte) error {
+	name := Coder{ID: method}.Name()
+
+	if !slices.Contains(optionalMethods, string(method)) {
+		return fmt.Errorf("%w: %s", ErrUnsupportedMethod, name)
+	}
+
+	return fmt.Errorf("%w: %s was compiled out with build tags, this build is without %s",
+		ErrUnsupportedMethod, name, strings.Join(compiledOut(), ", "))
+}
+
 // RegisterDecompressor allows custom decompressors for a specified method ID.
+// Any ID can be used, including private or vendor specific IDs outside of
+// the ranges used by 7-zip, and registering an ID again replaces the
+// previous decompressor.
 func RegisterDecompressor(method []byte, dcomp Decompressor) {
 	decompressors.Store(string(method), dcomp)
 }
 
+// Compressor describes the function signature that compression methods
+// registered with [RegisterCompressor] must implement to return a new
+// instance of themselves. They are passed the io.Writer to write the
+// compressed stream to and return an io.WriteCloser that is closed once
+// everything in the block has been written, along with any property bytes
+// to store for the matching [Decompressor].
+type Compressor func(io.Writer) (io.WriteCloser, []byte, error)
+
+type registeredCompressor struct {
+	id   []byte
+	comp Compressor
+}
+
+var (
+	//nolint:gochecknoglobals
+	compressorsMu sync.RWMutex
+	//nolint:gochecknoglobals
+	compressors []registeredCompressor
+)
+
+// methodCompressor is the first [Method] used for compressors registered with
+// [RegisterCompressor], leaving room for any built in methods.
+const methodCompressor Method = 1 << 8
+
+// RegisterCompressor allows custom compressors for a specified method ID to
+// be used by the [Writer], returning the [Method] to choose it with
+// [WithMethodRules]. Registering an ID again replaces the previous
+// compressor and returns the same Method. A [Decompressor] for the ID must
+// also be registered for the archives written to be read back. Compressors
+// only support a single input and output stream.
+func RegisterCompressor(method []byte, comp Compressor) Method {
+	compressorsMu.Lock()
+	defer compressorsMu.Unlock()
+
+	for i, c := range compressors {
+		if slices.Equal(c.id, method) {
+			compressors[i].comp = comp
+
+			return methodCompressor + Method(i)
+		}
+	}
+
+	compressors = append(compressors, registeredCompressor{id: slices.Clone(method), comp: comp})
+
+	return methodCompressor + Method(len(compressors)-1)
+}
+
+// compressor returns the compressor registered for m, if any.
+func (m Method) compressor() (registeredCompressor, bool) {
+	compressorsMu.RLock()
+	defer compressorsMu.RUnlock()
+
+	if i := int(m - methodCompressor); m >= methodCompressor && i < len(compressors) {
+		return compressors[i], true
+	}
+
+	return registeredCompressor{}, false
+}
+
 func decompressor(method []byte) Decompressor {
 	di, ok := decompressors.Load(string(method))
 	if !ok {
@@ -90,3 +191,24 @@ func decompressor(method []byte) Decompressor {
 
 	return nil
 }
+
+// RegisterMultiDecompressor allows custom decompressors with more than one
+// output stream for a specified method ID. It is only used for coders with
+// more than one output stream, otherwise any [Decompressor] registered with
+// [RegisterDecompressor] for the same ID is used.
+func RegisterMultiDecompressor(method []byte, dcomp MultiDecompressor) {
+	multiDecompressors.Store(string(method), dcomp)
+}
+
+func multiDecompressor(method []byte) MultiDecompressor {
+	di, ok := multiDecompressors.Load(string(method))
+	if !ok {
+		return nil
+	}
+
+	if d, ok := di.(MultiDecompressor); ok {
+		return d
+	}
+
+	return nil
+}
@@ -0,0 +1,31 @@
+//go:build !sevenzip_nobcj && !sevenzip_minimal
+
+package sevenzip
+
+import "github.com/javi11/sevenzip/internal/bra"
+
+//nolint:gochecknoinits
+func init() {
+	// BCJ
+	RegisterDecompressor([]byte{0x03, 0x03, 0x01, 0x03}, Decompressor(bra.NewBCJReader))
+	// PPC
+	RegisterDecompressor([]byte{0x03, 0x03, 0x02, 0x05}, Decompressor(bra.NewPPCReader))
+	// IA64
+	RegisterDecompressor([]byte{0x03, 0x03, 0x04, 0x01}, Decompressor(bra.NewIA64Reader))
+	// ARM
+	RegisterDecompressor([]byte{0x03, 0x03, 0x05, 0x01}, Decompressor(bra.NewARMReader))
+	// ARMT
+	RegisterDecompressor([]byte{0x03, 0x03, 0x07, 0x01}, Decompressor(bra.NewARMTReader))
+	// SPARC
+	RegisterDecompressor([]byte{0x03, 0x03, 0x08, 0x05}, Decompressor(bra.NewSPARCReader))
+	// RISCV
+	RegisterDecompressor([]byte{0x0b}, Decompressor(bra.NewRISCVReader))
+
+	registerFilterWriter(filterBCJ, bra.NewBCJWriter)
+	registerFilterWriter(filterPPC, bra.NewPPCWriter)
+	registerFilterWriter(filterIA64, bra.NewIA64Writer)
+	registerFilterWriter(filterARM, bra.NewARMWriter)
+	registerFilterWriter(filterARMT, bra.NewARMTWriter)
+	registerFilterWriter(filterSPARC, bra.NewSPARCWriter)
+	registerFilterWriter(filterRISCV, bra.NewRISCVWriter)
+}
@@ -0,0 +1,11 @@
+//go:build !sevenzip_nobcj2 && !sevenzip_minimal
+
+package sevenzip
+
+import "github.com/javi11/sevenzip/internal/bcj2"
+
+//nolint:gochecknoinits
+func init() {
+	// BCJ2
+	RegisterDecompressor([]byte{0x03, 0x03, 0x01, 0x1b}, Decompressor(bcj2.NewReader))
+}
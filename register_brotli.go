@@ -0,0 +1,11 @@
+//go:build !sevenzip_nobrotli && !sevenzip_minimal
+
+package sevenzip
+
+import "github.com/javi11/sevenzip/internal/brotli"
+
+//nolint:gochecknoinits
+func init() {
+	// Brotli
+	RegisterDecompressor([]byte{0x04, 0xf7, 0x11, 0x02}, Decompressor(brotli.NewReader))
+}
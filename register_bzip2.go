@@ -0,0 +1,11 @@
+//go:build !sevenzip_nobzip2 && !sevenzip_minimal
+
+package sevenzip
+
+import "github.com/javi11/sevenzip/internal/bzip2"
+
+//nolint:gochecknoinits
+func init() {
+	// Bzip2
+	RegisterDecompressor([]byte{0x04, 0x02, 0x02}, Decompressor(bzip2.NewReader))
+}
@@ -0,0 +1,11 @@
+//go:build !sevenzip_nodeflate && !sevenzip_minimal
+
+package sevenzip
+
+import "github.com/javi11/sevenzip/internal/deflate"
+
+//nolint:gochecknoinits
+func init() {
+	// Deflate
+	RegisterDecompressor([]byte{0x04, 0x01, 0x08}, Decompressor(deflate.NewReader))
+}
@@ -0,0 +1,13 @@
+//go:build !sevenzip_nodelta && !sevenzip_minimal
+
+package sevenzip
+
+import "github.com/javi11/sevenzip/internal/delta"
+
+//nolint:gochecknoinits
+func init() {
+	// Delta
+	RegisterDecompressor([]byte{0x03}, Decompressor(delta.NewReader))
+
+	registerFilterWriter(filterDelta, delta.NewWriter)
+}
@@ -0,0 +1,11 @@
+//go:build !sevenzip_nolz4 && !sevenzip_minimal
+
+package sevenzip
+
+import "github.com/javi11/sevenzip/internal/lz4"
+
+//nolint:gochecknoinits
+func init() {
+	// LZ4
+	RegisterDecompressor([]byte{0x04, 0xf7, 0x11, 0x04}, Decompressor(lz4.NewReader))
+}
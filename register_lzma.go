@@ -0,0 +1,11 @@
+//go:build !sevenzip_nolzma && !sevenzip_minimal
+
+package sevenzip
+
+import "github.com/javi11/sevenzip/internal/lzma"
+
+//nolint:gochecknoinits
+func init() {
+	// LZMA
+	RegisterDecompressor([]byte{0x03, 0x01, 0x01}, Decompressor(lzma.NewReader))
+}
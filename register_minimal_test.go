@@ -0,0 +1,67 @@
+//go:build sevenzip_minimal
+
+package sevenzip_test
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run with go test -tags sevenzip_minimal -run TestMinimal, the other tests
+// need every method.
+func TestMinimal(t *testing.T) {
+	t.Parallel()
+
+	// Archives made by the Writer only need what's always available, so
+	// executables aren't filtered
+	elf := "\x7fELF\x02\x01\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02\x00\x3e\x00"
+
+	buf := new(bytes.Buffer)
+	w := sevenzip.NewWriter(buf,
+		sevenzip.WithWriterPassword("password"),
+		sevenzip.WithEncryptedHeader(),
+		sevenzip.WithAutoFilters(),
+	)
+
+	fw, err := w.Create("a.txt")
+	require.NoError(t, err)
+
+	_, err = io.WriteString(fw, elf)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	r, err := sevenzip.NewReaderWithPassword(bytes.NewReader(buf.Bytes()), int64(buf.Len()), "password")
+	require.NoError(t, err)
+	require.Len(t, r.File, 1)
+	assert.Equal(t, "LZMA2:24 7zAES:19", r.Blocks()[0].Method())
+
+	rc, err := r.File[0].Open()
+	require.NoError(t, err)
+
+	b, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	assert.Equal(t, []byte(elf), b)
+
+	// 7-Zip compresses headers with LZMA, which is compiled out too
+	_, err = sevenzip.OpenReader(filepath.Join("testdata", "bzip2.7z"))
+	require.ErrorIs(t, err, sevenzip.ErrUnsupportedMethod)
+	assert.ErrorContains(t, err, "LZMA was compiled out with build tags")
+	assert.ErrorContains(t, err, "without ARM, ARMT, BCJ, BCJ2, BZip2, Brotli, Deflate, Delta, IA64, LZ4, LZMA, PPC, PPMD, RISCV, SPARC, ZSTD")
+
+	// Writing with PPMd needs it compiled in
+	w = sevenzip.NewWriter(new(bytes.Buffer), sevenzip.WithMethodRules(sevenzip.MethodForExtensions(sevenzip.MethodPPMd, ".txt")))
+
+	fw, err = w.Create("a.txt")
+	require.NoError(t, err)
+
+	_, err = io.WriteString(fw, "contents")
+	require.ErrorIs(t, err, sevenzip.ErrUnsupportedMethod)
+	assert.ErrorContains(t, err, "PPMD was compiled out with build tags")
+}
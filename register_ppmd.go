@@ -0,0 +1,13 @@
+//go:build !sevenzip_noppmd && !sevenzip_minimal
+
+package sevenzip
+
+import "github.com/javi11/sevenzip/internal/ppmd"
+
+//nolint:gochecknoinits
+func init() {
+	// PPMd
+	RegisterDecompressor([]byte{0x03, 0x04, 0x01}, Decompressor(ppmd.NewReader))
+
+	newPPMdWriter = ppmd.NewWriter
+}
@@ -0,0 +1,436 @@
+package sevenzip_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"hash/crc32"
+	"io"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	methodXOR     = []byte{0x7f, 0x00, 0x00, 0x01}
+	methodXORPair = []byte{0x7f, 0x00, 0x00, 0x02}
+	methodSplit   = []byte{0x7f, 0x00, 0x00, 0x03}
+
+	errXORReaders = errors.New("xor: wrong number of readers")
+)
+
+// xorReader XORs the bytes of its first reader with either a fixed key or
+// the bytes of its second reader.
+type xorReader struct {
+	readers []io.ReadCloser
+	key     byte
+}
+
+func (xr *xorReader) Read(p []byte) (int, error) {
+	n, err := xr.readers[0].Read(p)
+
+	if len(xr.readers) == 1 {
+		for i := range p[:n] {
+			p[i] ^= xr.key
+		}
+
+		return n, err //nolint:wrapcheck
+	}
+
+	pad := make([]byte, n)
+	if _, err := io.ReadFull(xr.readers[1], pad); err != nil {
+		return 0, err //nolint:wrapcheck
+	}
+
+	for i := range p[:n] {
+		p[i] ^= pad[i]
+	}
+
+	return n, err //nolint:wrapcheck
+}
+
+func (xr *xorReader) Close() error {
+	var errs []error
+	for _, rc := range xr.readers {
+		errs = append(errs, rc.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+func newXORReader(p []byte, _ uint64, readers []io.ReadCloser) (io.ReadCloser, error) {
+	if len(p) != 1 || len(readers) != 1 {
+		return nil, errXORReaders
+	}
+
+	return &xorReader{readers: readers, key: p[0]}, nil
+}
+
+func newXORPairReader(_ []byte, _ uint64, readers []io.ReadCloser) (io.ReadCloser, error) {
+	if len(readers) != 2 { //nolint:mnd
+		return nil, errXORReaders
+	}
+
+	return &xorReader{readers: readers}, nil
+}
+
+// newSplitReaders returns two readers, one for the even bytes of its reader
+// and one for the odd bytes.
+func newSplitReaders(_ []byte, sizes []uint64, readers []io.ReadCloser) ([]io.ReadCloser, error) {
+	if len(readers) != 1 || len(sizes) != 2 { //nolint:mnd
+		return nil, errXORReaders
+	}
+
+	b, err := io.ReadAll(readers[0])
+	if err = errors.Join(err, readers[0].Close()); err != nil {
+		return nil, err
+	}
+
+	var even, odd []byte
+
+	for i, c := range b {
+		if i%2 == 0 {
+			even = append(even, c)
+		} else {
+			odd = append(odd, c)
+		}
+	}
+
+	return []io.ReadCloser{
+		io.NopCloser(bytes.NewReader(even)),
+		io.NopCloser(bytes.NewReader(odd)),
+	}, nil
+}
+
+//nolint:gochecknoinits
+func init() {
+	sevenzip.RegisterDecompressor(methodXOR, sevenzip.Decompressor(newXORReader))
+	sevenzip.RegisterDecompressor(methodXORPair, sevenzip.Decompressor(newXORPairReader))
+	sevenzip.RegisterMultiDecompressor(methodSplit, sevenzip.MultiDecompressor(newSplitReaders))
+}
+
+func TestRegisterDecompressor(t *testing.T) {
+	t.Parallel()
+
+	r, err := sevenzip.OpenReader(filepath.Join("testdata", "xor.7z"))
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, r.Close())
+	}()
+
+	tables := []struct {
+		name, want, method string
+		coder              sevenzip.Coder
+	}{
+		{
+			name:   "a.txt",
+			want:   "The quick brown fox jumps over the lazy dog.\n",
+			method: "7f000001",
+			coder: sevenzip.Coder{
+				ID:            methodXOR,
+				Properties:    []byte{0x5a},
+				NumInStreams:  1,
+				NumOutStreams: 1,
+			},
+		},
+		{
+			name:   "b.txt",
+			want:   "Pack my box with five dozen liquor jugs.\n",
+			method: "7f000002",
+			coder: sevenzip.Coder{
+				ID:            methodXORPair,
+				NumInStreams:  2,
+				NumOutStreams: 1,
+			},
+		},
+	}
+
+	for _, table := range tables {
+		f, ok := r.FileByName(table.name)
+		require.True(t, ok, table.name)
+
+		coders := f.Coders()
+		require.Len(t, coders, 1)
+		assert.Equal(t, table.coder, coders[0])
+		assert.Equal(t, table.method, coders[0].Name())
+
+		rc, err := f.Open()
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+
+		assert.Equal(t, table.want, string(b))
+		assert.Equal(t, f.CRC32, crc32.ChecksumIEEE(b))
+	}
+
+	// Atomic writes verify the checksum of every file
+	assert.NoError(t, r.ExtractAll(context.Background(), t.TempDir(), sevenzip.WithAtomicWrites()))
+}
+
+func TestRegisterMultiDecompressor(t *testing.T) {
+	t.Parallel()
+
+	// The first stream lists its coders in the order 7-zip does, with the
+	// last coder applied first, and the second splits its packed stream
+	// into two streams which are then combined again
+	r, err := sevenzip.OpenReader(filepath.Join("testdata", "xorgraph.7z"))
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, r.Close())
+	}()
+
+	tables := []struct {
+		name, want string
+		coders     []sevenzip.Coder
+	}{
+		{
+			name: "a.txt",
+			want: "Sphinx of black quartz, judge my vow.\n",
+			coders: []sevenzip.Coder{
+				{ID: methodCopy, NumInStreams: 1, NumOutStreams: 1},
+				{ID: methodXOR, Properties: []byte{0x33}, NumInStreams: 1, NumOutStreams: 1},
+			},
+		},
+		{
+			name: "b.txt",
+			want: "How vexingly quick daft zebras jump!\n",
+			coders: []sevenzip.Coder{
+				{ID: methodSplit, NumInStreams: 1, NumOutStreams: 2},
+				{ID: methodXORPair, NumInStreams: 2, NumOutStreams: 1},
+			},
+		},
+	}
+
+	for _, table := range tables {
+		f, ok := r.FileByName(table.name)
+		require.True(t, ok, table.name)
+
+		assert.ElementsMatch(t, table.coders, f.Coders(), table.name)
+
+		rc, err := f.Open()
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+
+		assert.Equal(t, table.want, string(b))
+	}
+
+	blocks := r.Blocks()
+	require.Len(t, blocks, 2)
+
+	assert.Equal(t, []sevenzip.BindPair{{InIndex: 0, OutIndex: 1}, {InIndex: 1, OutIndex: 2}}, blocks[1].BindPairs)
+	require.Len(t, blocks[1].PackedStreams, 1)
+	assert.Equal(t, 2, blocks[1].PackedStreams[0].InIndex)
+	assert.Len(t, blocks[1].UnpackSizes, 3)
+
+	assert.NoError(t, r.ExtractAll(context.Background(), t.TempDir(), sevenzip.WithAtomicWrites()))
+}
+
+func TestReaderRegisterDecompressor(t *testing.T) {
+	t.Parallel()
+
+	name := filepath.Join("testdata", "copy.7z")
+
+	r, err := sevenzip.OpenReader(name)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, r.Close())
+	})
+
+	other, err := sevenzip.OpenReader(name)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, other.Close())
+	})
+
+	// Override the Copy method to XOR every byte
+	r.RegisterDecompressor([]byte{0x00}, func(_ []byte, _ uint64, readers []io.ReadCloser) (io.ReadCloser, error) {
+		return newXORReader([]byte{0xff}, 0, readers)
+	})
+
+	var f *sevenzip.File
+
+	for _, file := range r.File {
+		if file.UncompressedSize > 0 {
+			f = file
+
+			break
+		}
+	}
+
+	require.NotNil(t, f)
+
+	read := func(f *sevenzip.File) []byte {
+		t.Helper()
+
+		rc, err := f.Open()
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+
+		return b
+	}
+
+	got := read(f)
+
+	g, ok := other.FileByName(f.Name)
+	require.True(t, ok)
+
+	want := read(g)
+	assert.Equal(t, f.CRC32, crc32.ChecksumIEEE(want), "other readers use the package level decompressor")
+
+	for i := range want {
+		want[i] ^= 0xff
+	}
+
+	assert.Equal(t, want, got)
+}
+
+// xorWriter XORs everything written to it with a fixed key.
+type xorWriter struct {
+	w   io.Writer
+	key byte
+}
+
+func (xw *xorWriter) Write(p []byte) (int, error) {
+	b := bytes.Clone(p)
+	for i := range b {
+		b[i] ^= xw.key
+	}
+
+	return xw.w.Write(b) //nolint:wrapcheck
+}
+
+func (xw *xorWriter) Close() error {
+	return nil
+}
+
+func TestRegisterCompressor(t *testing.T) {
+	t.Parallel()
+
+	compressor := func(w io.Writer) (io.WriteCloser, []byte, error) {
+		return &xorWriter{w: w, key: 0x5a}, []byte{0x5a}, nil
+	}
+
+	m := sevenzip.RegisterCompressor(methodXOR, compressor)
+	assert.Equal(t, m, sevenzip.RegisterCompressor(methodXOR, compressor))
+	assert.Equal(t, "7f000001", m.String())
+
+	tables := []struct {
+		name   string
+		opts   []sevenzip.WriterOption
+		method string
+	}{
+		{
+			name:   "plain",
+			method: "7f000001",
+		},
+		{
+			name:   "encrypted",
+			opts:   []sevenzip.WriterOption{sevenzip.WithWriterPassword("password")},
+			method: "7f000001 7zAES:19",
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := new(bytes.Buffer)
+			w := sevenzip.NewWriter(buf, append(table.opts, sevenzip.WithMethodRules(
+				sevenzip.MethodForExtensions(m, ".txt"),
+			))...)
+
+			for _, name := range []string{"a.txt", "b.bin"} {
+				fw, err := w.Create(name)
+				require.NoError(t, err)
+
+				_, err = io.WriteString(fw, "contents of "+name)
+				require.NoError(t, err)
+			}
+
+			require.NoError(t, w.Close())
+
+			r, err := sevenzip.NewReaderWithPassword(bytes.NewReader(buf.Bytes()), int64(buf.Len()), "password")
+			require.NoError(t, err)
+
+			blocks := r.Blocks()
+			require.Len(t, blocks, 2)
+			assert.Equal(t, table.method, blocks[0].Method())
+			assert.NotContains(t, blocks[1].Method(), "7f000001")
+			require.NoError(t, checkContents(r))
+		})
+	}
+}
+
+// cbcReadCloser records whether a replacement CBC decrypter was passed to it.
+type cbcReadCloser struct {
+	io.ReadCloser
+	set *atomic.Bool
+}
+
+func (rc cbcReadCloser) SetCBCDecrypter(func(key, iv []byte) (cipher.BlockMode, error)) {
+	rc.set.Store(true)
+}
+
+func TestReaderRegisterMultiDecompressor(t *testing.T) {
+	t.Parallel()
+
+	var called, set atomic.Bool
+
+	r, err := sevenzip.OpenReaderWithOptions(filepath.Join("testdata", "xorgraph.7z"),
+		sevenzip.WithCBCDecrypter(func(key, iv []byte) (cipher.BlockMode, error) {
+			block, err := aes.NewCipher(key)
+			if err != nil {
+				return nil, err //nolint:wrapcheck
+			}
+
+			return cipher.NewCBCDecrypter(block, iv), nil
+		}))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, r.Close())
+	})
+
+	r.RegisterMultiDecompressor(methodSplit, func(p []byte, sizes []uint64, readers []io.ReadCloser) ([]io.ReadCloser, error) {
+		called.Store(true)
+
+		outs, err := newSplitReaders(p, sizes, readers)
+		for i, rc := range outs {
+			outs[i] = cbcReadCloser{rc, &set}
+		}
+
+		return outs, err
+	})
+
+	f, ok := r.FileByName("b.txt")
+	require.True(t, ok)
+
+	rc, err := f.Open()
+	require.NoError(t, err)
+
+	b, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+
+	assert.Equal(t, "How vexingly quick daft zebras jump!\n", string(b))
+	assert.True(t, called.Load(), "the reader's own decompressor is used")
+	assert.True(t, set.Load(), "the replacement CBC decrypter is passed on")
+}
@@ -0,0 +1,11 @@
+//go:build !sevenzip_nozstd && !sevenzip_minimal
+
+package sevenzip
+
+import "github.com/javi11/sevenzip/internal/zstd"
+
+//nolint:gochecknoinits
+func init() {
+	// Zstandard
+	RegisterDecompressor([]byte{0x04, 0xf7, 0x11, 0x01}, Decompressor(zstd.NewReader))
+}
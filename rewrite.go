@@ -0,0 +1,456 @@
+package sevenzip
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"slices"
+
+	"github.com/javi11/sevenzip/internal/aes7z"
+	"github.com/javi11/sevenzip/internal/util"
+)
+
+var (
+	errRewriteChain = errors.New("sevenzip: encryption is not applied directly to a packed stream")
+
+	//nolint:gochecknoglobals
+	methodAES = []byte{0x06, 0xf1, 0x07, 0x01}
+)
+
+func (c *coder) isAES() bool {
+	return slices.Equal(c.id, methodAES)
+}
+
+// coderStreams returns the index of the first input and output stream of the
+// coder c within the folder.
+func (f *folder) coderStreams(c int) (uint64, uint64) {
+	var in, out uint64
+
+	for _, cd := range f.coder[:c] {
+		in += cd.in
+		out += cd.out
+	}
+
+	return in, out
+}
+
+// packedIndex returns the index of the folder's first packed stream within
+// the pack info.
+func (si *streamsInfo) packedIndex(folder int) int {
+	var k uint64
+
+	for _, f := range si.unpackInfo.folder[:folder] {
+		k += f.packedStreams
+	}
+
+	return int(k) //nolint:gosec
+}
+
+// packedOffset returns the offset of packed stream k relative to the start
+// of the streams data.
+func (si *streamsInfo) packedOffset(k int) int64 {
+	offset := si.packInfo.position
+
+	for _, size := range si.packInfo.size[:k] {
+		offset += size
+	}
+
+	return int64(offset) //nolint:gosec
+}
+
+func (si *streamsInfo) clone() *streamsInfo {
+	if si == nil {
+		return nil
+	}
+
+	n := new(streamsInfo)
+
+	if si.packInfo != nil {
+		n.packInfo = &packInfo{
+			position: si.packInfo.position,
+			streams:  si.packInfo.streams,
+			size:     slices.Clone(si.packInfo.size),
+			digest:   slices.Clone(si.packInfo.digest),
+			defined:  slices.Clone(si.packInfo.defined),
+		}
+	}
+
+	if si.unpackInfo != nil {
+		n.unpackInfo = &unpackInfo{
+			folder:  make([]*folder, len(si.unpackInfo.folder)),
+			digest:  slices.Clone(si.unpackInfo.digest),
+			defined: slices.Clone(si.unpackInfo.defined),
+		}
+
+		for i, f := range si.unpackInfo.folder {
+			nf := *f
+			nf.coder = make([]*coder, len(f.coder))
+			nf.bindPair = make([]*bindPair, len(f.bindPair))
+			nf.size = slices.Clone(f.size)
+			nf.packed = slices.Clone(f.packed)
+
+			for j, c := range f.coder {
+				nc := *c
+				nc.id = slices.Clone(c.id)
+				nc.properties = slices.Clone(c.properties)
+				nf.coder[j] = &nc
+			}
+
+			for j, bp := range f.bindPair {
+				nbp := *bp
+				nf.bindPair[j] = &nbp
+			}
+
+			n.unpackInfo.folder[i] = &nf
+		}
+	}
+
+	if si.subStreamsInfo != nil {
+		n.subStreamsInfo = &subStreamsInfo{
+			streams: slices.Clone(si.subStreamsInfo.streams),
+			size:    slices.Clone(si.subStreamsInfo.size),
+			digest:  slices.Clone(si.subStreamsInfo.digest),
+			defined: slices.Clone(si.subStreamsInfo.defined),
+		}
+	}
+
+	return n
+}
+
+// encryptedStream is an AES coder that reads directly from a packed stream.
+type encryptedStream struct {
+	folder int
+	coder  int
+	packed int    // Index within the pack info
+	size   uint64 // Size of the decrypted stream
+}
+
+// encryptedStreams finds every AES coder in the archive. Each one must read
+// directly from a packed stream so that it can be decrypted without decoding
+// the rest of the folder.
+func (z *Reader) encryptedStreams() ([]encryptedStream, error) {
+	var streams []encryptedStream
+
+	for i := range z.si.Folders() {
+		f := z.si.unpackInfo.folder[i]
+
+		for j, c := range f.coder {
+			if !c.isAES() {
+				continue
+			}
+
+			in, out := f.coderStreams(j)
+
+			k := slices.Index(f.packed, in)
+			if k < 0 || f.findInBindPair(in) != nil {
+				return nil, fmt.Errorf("sevenzip: folder %d: %w", i, errRewriteChain)
+			}
+
+			streams = append(streams, encryptedStream{
+				folder: i,
+				coder:  j,
+				packed: z.si.packedIndex(i) + k,
+				size:   f.size[out],
+			})
+		}
+	}
+
+	return streams, nil
+}
+
+// verifyFolder decodes the whole folder using password and checks the
+// result against any recorded CRCs, so that a wrong password is detected
+// before anything is written.
+func (z *Reader) verifyFolder(folder int, password string) (err error) {
+	cfg := z.folderConfig()
+	cfg.password = password
+
+	fr, crc, crcDefined, encrypted, err := z.si.folderReader(z.streams(), folder, cfg)
+	if err != nil {
+		return readError(encrypted, err)
+	}
+
+	defer func() {
+		err = errors.Join(err, fr.Close())
+	}()
+
+	var (
+		files []*File
+		sums  []uint32
+		off   int64
+	)
+
+	for _, f := range z.File {
+		if f.folder != folder || !f.HasStream() {
+			continue
+		}
+
+		// Skip any files left out by a name filter
+		if _, err := io.CopyN(io.Discard, fr, f.offset-off); err != nil {
+			return readError(encrypted, err)
+		}
+
+		h := crc32.NewIEEE()
+
+		if _, err := io.CopyN(h, fr, int64(f.UncompressedSize)); err != nil { //nolint:gosec
+			return readError(encrypted, err)
+		}
+
+		off = f.offset + int64(f.UncompressedSize) //nolint:gosec
+
+		if crc, ok := f.Digest(); ok && h.Sum32() != crc {
+			return readError(encrypted, errChecksum)
+		}
+
+		files, sums = append(files, f), append(sums, h.Sum32())
+	}
+
+	if _, err := io.Copy(io.Discard, fr); err != nil {
+		return readError(encrypted, err)
+	}
+
+	if !crcDefined {
+		return nil
+	}
+
+	if !util.CRC32Equal(fr.Checksum(), crc) {
+		return readError(encrypted, errChecksum)
+	}
+
+	setDigests(files, sums)
+
+	return nil
+}
+
+// copyStream returns a packedStream that copies packed stream k verbatim.
+func (z *Reader) copyStream(k int) packedStream {
+	size := z.si.packInfo.size[k]
+
+	return packedStream{
+		size: size,
+		write: func(w io.Writer) error {
+			sr := io.NewSectionReader(z.streams(), z.si.packedOffset(k), int64(size)) //nolint:gosec
+			if _, err := io.Copy(w, sr); err != nil {
+				return fmt.Errorf("sevenzip: error copying packed stream: %w", err)
+			}
+
+			return nil
+		},
+	}
+}
+
+// decryptStream returns a reader of the decrypted contents of the packed
+// stream read by the encrypted stream es.
+func (z *Reader) decryptStream(es encryptedStream, password string) (io.ReadCloser, error) {
+	c := z.si.unpackInfo.folder[es.folder].coder[es.coder]
+
+	cfg := z.folderConfig()
+	cfg.password = password
+
+	sr := io.NewSectionReader(z.streams(), z.si.packedOffset(es.packed), int64(z.si.packInfo.size[es.packed])) //nolint:gosec,lll
+
+	rc, err := newCoderReader(c.id, c.properties, es.size, []io.ReadCloser{io.NopCloser(sr)}, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rc.(CryptoReadCloser).Password(password); err != nil { //nolint:forcetypeassert
+		return nil, fmt.Errorf("sevenzip: error setting password: %w", err)
+	}
+
+	return rc, nil
+}
+
+// A streamRewriter updates si to reflect how the encrypted stream es will be
+// rewritten and returns the replacement packed stream.
+type streamRewriter func(si *streamsInfo, es encryptedStream) (packedStream, error)
+
+// rewrite writes a copy of z to dst, passing every encrypted stream to fn.
+// Each encrypted folder is first checked with password so that nothing is
+// written if it's wrong.
+func (z *Reader) rewrite(dst io.Writer, password string, opts headerOptions, fn streamRewriter) error {
+	if z.opts.nameFilter != nil {
+		return errFiltered
+	}
+
+	encrypted, err := z.encryptedStreams()
+	if err != nil {
+		return err
+	}
+
+	folders := make(map[int]struct{}, len(encrypted))
+	for _, es := range encrypted {
+		folders[es.folder] = struct{}{}
+	}
+
+	for i := range z.si.Folders() {
+		if _, ok := folders[i]; !ok {
+			continue
+		}
+
+		if err := z.verifyFolder(i, password); err != nil {
+			return fmt.Errorf("sevenzip: error verifying folder %d: %w", i, err)
+		}
+	}
+
+	si := z.si.clone()
+
+	var streams []packedStream
+
+	if si != nil && si.packInfo != nil {
+		streams = make([]packedStream, len(si.packInfo.size))
+
+		for k := range streams {
+			streams[k] = z.copyStream(k)
+		}
+
+		si.packInfo.position = 0
+		si.packInfo.digest, si.packInfo.defined = nil, nil
+	}
+
+	// Work backwards so removing a coder doesn't affect the indices of any
+	// yet to be processed
+	for i := len(encrypted) - 1; i >= 0; i-- {
+		es := encrypted[i]
+
+		if streams[es.packed], err = fn(si, es); err != nil {
+			return err
+		}
+	}
+
+	h := &header{
+		streamsInfo: si,
+		filesInfo:   z.h.filesInfo,
+	}
+
+	return writeArchive(dst, h, streams, opts)
+}
+
+// transformStream returns a packedStream of size bytes that decrypts the
+// encrypted stream es using password and writes it through fn.
+func (z *Reader) transformStream(es encryptedStream, password string, size uint64,
+	fn func(w io.Writer) (io.WriteCloser, error),
+) packedStream {
+	return packedStream{
+		size: size,
+		write: func(w io.Writer) (err error) {
+			rc, err := z.decryptStream(es, password)
+			if err != nil {
+				return err
+			}
+
+			defer func() {
+				err = errors.Join(err, rc.Close())
+			}()
+
+			wc, err := fn(w)
+			if err != nil {
+				return err
+			}
+
+			if _, err = io.Copy(wc, rc); err != nil {
+				return fmt.Errorf("sevenzip: error rewriting stream: %w", err)
+			}
+
+			return wc.Close()
+		},
+	}
+}
+
+// ReEncrypt writes a copy of src to dst with every encrypted stream decrypted
+// using oldPass and encrypted again using newPass. The compressed data is
+// copied untouched so the archive is not recompressed, only decrypted and
+// encrypted again. If the header of src is encrypted, so is the header
+// written to dst. Streams that aren't encrypted are copied as is.
+//
+// The old password is checked by decoding each encrypted stream before
+// anything is written. Encryption must be applied directly to the packed
+// streams, which is how 7-zip always creates archives.
+func ReEncrypt(src *Reader, dst io.Writer, oldPass, newPass string) error {
+	opts := headerOptions{compress: true}
+	if src.encryptedHeader {
+		opts.password = newPass
+	}
+
+	return src.rewrite(dst, oldPass, opts, func(si *streamsInfo, es encryptedStream) (packedStream, error) {
+		c := si.unpackInfo.folder[es.folder].coder[es.coder]
+
+		properties, err := aes7z.NewProperties(aes7z.Cycles(c.properties))
+		if err != nil {
+			return packedStream{}, fmt.Errorf("sevenzip: error encrypting: %w", err)
+		}
+
+		c.properties = properties
+
+		size := aes7z.EncryptedSize(es.size)
+		si.packInfo.size[es.packed] = size
+
+		return src.transformStream(es, oldPass, size, func(w io.Writer) (io.WriteCloser, error) {
+			wc, err := aes7z.NewWriter(w, properties, newPass)
+			if err != nil {
+				return nil, fmt.Errorf("sevenzip: error encrypting: %w", err)
+			}
+
+			return wc, nil
+		}), nil
+	})
+}
+
+// removeCoder removes the single input and output coder c from the folder,
+// connecting whatever consumed its output directly to the packed stream it
+// read from. If nothing consumes its output, it is replaced with the Copy
+// method instead.
+func (f *folder) removeCoder(c int) {
+	in, out := f.coderStreams(c)
+
+	bp := f.findOutBindPair(out)
+	if bp == nil {
+		f.coder[c] = &coder{id: []byte{0x00}, in: 1, out: 1}
+
+		return
+	}
+
+	f.packed[slices.Index(f.packed, in)] = bp.in
+	f.bindPair = slices.DeleteFunc(f.bindPair, func(v *bindPair) bool { return v == bp })
+	f.coder = slices.Delete(f.coder, c, c+1)
+	f.size = slices.Delete(f.size, int(out), int(out)+1) //nolint:gosec
+	f.in--
+	f.out--
+
+	for _, v := range f.bindPair {
+		if v.in > in {
+			v.in--
+		}
+
+		if v.out > out {
+			v.out--
+		}
+	}
+
+	for i, v := range f.packed {
+		if v > in {
+			f.packed[i] = v - 1
+		}
+	}
+}
+
+// RemoveEncryption writes an unencrypted copy of src to dst, decrypting every
+// encrypted stream with password. The compressed data, methods and solid
+// layout are otherwise unchanged, and the header is written unencrypted.
+//
+// The password is checked by decoding each encrypted stream before anything
+// is written. Encryption must be applied directly to the packed streams,
+// which is how 7-zip always creates archives.
+func RemoveEncryption(src *Reader, dst io.Writer, password string) error {
+	opts := headerOptions{compress: true}
+
+	return src.rewrite(dst, password, opts, func(si *streamsInfo, es encryptedStream) (packedStream, error) {
+		si.unpackInfo.folder[es.folder].removeCoder(es.coder)
+		si.packInfo.size[es.packed] = es.size
+
+		return src.transformStream(es, password, es.size, func(w io.Writer) (io.WriteCloser, error) {
+			return util.NopWriteCloser(w), nil
+		}), nil
+	})
+}
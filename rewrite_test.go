@@ -0,0 +1,202 @@
+package sevenzip_test
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fileSummary struct {
+	name  string
+	size  uint64
+	crc   uint32
+	isDir bool
+}
+
+// summarise reads every file in r and returns its name, size and computed CRC.
+func summarise(t *testing.T, r *sevenzip.Reader) []fileSummary {
+	t.Helper()
+
+	summary := make([]fileSummary, 0, len(r.File))
+
+	for _, f := range r.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+
+		h := crc32.NewIEEE()
+
+		_, err = io.Copy(h, rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+
+		summary = append(summary, fileSummary{
+			name:  f.Name,
+			size:  f.UncompressedSize,
+			crc:   h.Sum32(),
+			isDir: f.FileInfo().IsDir(),
+		})
+	}
+
+	return summary
+}
+
+func TestReEncrypt(t *testing.T) {
+	t.Parallel()
+
+	tables := []struct {
+		name, file, password string
+		encryptedHeader      bool
+	}{
+		{
+			name:            "no header compression",
+			file:            "t2.7z",
+			password:        "password",
+			encryptedHeader: true,
+		},
+		{
+			name:            "with header compression",
+			file:            "t3.7z",
+			password:        "password",
+			encryptedHeader: true,
+		},
+		{
+			name:     "unencrypted headers compressed files",
+			file:     "t4.7z",
+			password: "password",
+		},
+		{
+			name:     "unencrypted headers uncompressed files",
+			file:     "t5.7z",
+			password: "password",
+		},
+		{
+			name:     "issue 75",
+			file:     "7zcracker.7z",
+			password: "876",
+		},
+		{
+			name: "not encrypted",
+			file: "t1.7z",
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			src, err := sevenzip.OpenReaderWithPassword(filepath.Join("testdata", table.file), table.password)
+			require.NoError(t, err)
+
+			defer func() {
+				require.NoError(t, src.Close())
+			}()
+
+			b := new(bytes.Buffer)
+			require.NoError(t, sevenzip.ReEncrypt(&src.Reader, b, table.password, "new password"))
+
+			dst, err := sevenzip.NewReaderWithPassword(bytes.NewReader(b.Bytes()), int64(b.Len()), "new password")
+			require.NoError(t, err)
+
+			assert.Equal(t, summarise(t, &src.Reader), summarise(t, dst))
+			require.NoError(t, extractArchive(t, dst, -1, crc32.NewIEEE(), iotest.OneByteReader, true))
+
+			if table.encryptedHeader {
+				_, err = sevenzip.NewReaderWithPassword(bytes.NewReader(b.Bytes()), int64(b.Len()), table.password)
+
+				var e *sevenzip.ReadError
+				if assert.ErrorAs(t, err, &e) {
+					assert.True(t, e.Encrypted)
+				}
+			}
+		})
+	}
+}
+
+func TestReEncryptWrongPassword(t *testing.T) {
+	t.Parallel()
+
+	src, err := sevenzip.OpenReaderWithPassword(filepath.Join("testdata", "t4.7z"), "password")
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, src.Close())
+	}()
+
+	b := new(bytes.Buffer)
+	err = sevenzip.ReEncrypt(&src.Reader, b, "notpassword", "new password")
+
+	var e *sevenzip.ReadError
+	if assert.ErrorAs(t, err, &e) {
+		assert.True(t, e.Encrypted)
+	}
+
+	assert.Zero(t, b.Len())
+}
+
+func TestRemoveEncryption(t *testing.T) {
+	t.Parallel()
+
+	tables := []struct {
+		name, file, password string
+	}{
+		{
+			name:     "no header compression",
+			file:     "t2.7z",
+			password: "password",
+		},
+		{
+			name:     "with header compression",
+			file:     "t3.7z",
+			password: "password",
+		},
+		{
+			name:     "unencrypted headers compressed files",
+			file:     "t4.7z",
+			password: "password",
+		},
+		{
+			name:     "unencrypted headers uncompressed files",
+			file:     "t5.7z",
+			password: "password",
+		},
+		{
+			name:     "issue 75",
+			file:     "7zcracker.7z",
+			password: "876",
+		},
+		{
+			name:     "aes7z",
+			file:     "aes7z.7z",
+			password: "password",
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			src, err := sevenzip.OpenReaderWithPassword(filepath.Join("testdata", table.file), table.password)
+			require.NoError(t, err)
+
+			defer func() {
+				require.NoError(t, src.Close())
+			}()
+
+			b := new(bytes.Buffer)
+			require.NoError(t, sevenzip.RemoveEncryption(&src.Reader, b, table.password))
+
+			dst, err := sevenzip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+			require.NoError(t, err)
+
+			assert.Equal(t, summarise(t, &src.Reader), summarise(t, dst))
+			require.NoError(t, extractArchive(t, dst, -1, crc32.NewIEEE(), iotest.OneByteReader, true))
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package sevenzip
+
+import (
+	"cmp"
+	"slices"
+)
+
+// Sample returns up to n files chosen to be representative of the whole
+// archive, for a quick check of a huge archive without extracting all of
+// it, such as with [Reader.Extract] and [WithFiles]. Only files with
+// contents are picked. They are first picked from blocks spread evenly
+// through the archive, taking the first file in each so that as little as
+// possible has to be decoded, and then any remaining picks are spread
+// across the range of file sizes. The files are returned in archive order
+// and the same files are always picked for the same archive and n.
+func (z *Reader) Sample(n int) []*File {
+	var (
+		files   []*File
+		folders []int
+		first   = make(map[int]int)
+	)
+
+	for _, f := range z.File {
+		if !f.HasStream() || f.isMissing || f.Mode().IsDir() {
+			continue
+		}
+
+		if _, ok := first[f.folder]; !ok {
+			first[f.folder] = len(files)
+			folders = append(folders, f.folder)
+		}
+
+		files = append(files, f)
+	}
+
+	if n >= len(files) {
+		return files
+	}
+
+	n = max(n, 0)
+
+	picked := make([]bool, len(files))
+
+	// The middle of n equal parts of the blocks
+	blocks := min(n, len(folders))
+	for i := range blocks {
+		picked[first[folders[(2*i+1)*len(folders)/(2*blocks)]]] = true
+	}
+
+	if rest := n - blocks; rest > 0 {
+		bySize := make([]int, 0, len(files)-blocks)
+
+		for i := range files {
+			if !picked[i] {
+				bySize = append(bySize, i)
+			}
+		}
+
+		slices.SortStableFunc(bySize, func(a, b int) int {
+			return cmp.Compare(files[a].UncompressedSize, files[b].UncompressedSize)
+		})
+
+		// The smallest, the largest and evenly spaced between
+		for i := range rest {
+			j := 0
+			if rest > 1 {
+				j = i * (len(bySize) - 1) / (rest - 1)
+			}
+
+			picked[bySize[j]] = true
+		}
+	}
+
+	sample := make([]*File, 0, n)
+
+	for i, f := range files {
+		if picked[i] {
+			sample = append(sample, f)
+		}
+	}
+
+	return sample
+}
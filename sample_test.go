@@ -0,0 +1,135 @@
+package sevenzip_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:funlen
+func TestSample(t *testing.T) {
+	t.Parallel()
+
+	// Twelve files in blocks of three, with sizes out of order, plus a
+	// directory and an empty file that are never picked
+	buf := new(bytes.Buffer)
+	w := sevenzip.NewWriter(buf, sevenzip.WithSolidBlockFiles(3))
+
+	_, err := w.Create("dir/")
+	require.NoError(t, err)
+
+	for i := range 12 {
+		fw, err := w.Create(fmt.Sprintf("dir/f%02d", i))
+		require.NoError(t, err)
+
+		_, err = fw.Write(bytes.Repeat([]byte{byte(i)}, (i*5%12+1)*100))
+		require.NoError(t, err)
+	}
+
+	_, err = w.Create("empty")
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	r, err := sevenzip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	names := func(files []*sevenzip.File) []string {
+		var s []string
+		for _, f := range files {
+			s = append(s, f.Name)
+		}
+
+		return s
+	}
+
+	all := make([]string, 0, 12)
+	for i := range 12 {
+		all = append(all, fmt.Sprintf("dir/f%02d", i))
+	}
+
+	tables := []struct {
+		n    int
+		want []string
+	}{
+		{
+			n: -1,
+		},
+		{
+			n: 0,
+		},
+		{
+			n:    1,
+			want: []string{"dir/f06"},
+		},
+		{
+			// The first files of the second and fourth blocks
+			n:    2,
+			want: []string{"dir/f03", "dir/f09"},
+		},
+		{
+			n:    4,
+			want: []string{"dir/f00", "dir/f03", "dir/f06", "dir/f09"},
+		},
+		{
+			// Then the smallest and largest of the rest, f05 is 200
+			// bytes and f07 is 1200
+			n:    6,
+			want: []string{"dir/f00", "dir/f03", "dir/f05", "dir/f06", "dir/f07", "dir/f09"},
+		},
+		{
+			n:    12,
+			want: all,
+		},
+		{
+			n:    100,
+			want: all,
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(fmt.Sprint(table.n), func(t *testing.T) {
+			t.Parallel()
+
+			sample := r.Sample(table.n)
+			assert.Equal(t, table.want, names(sample))
+			assert.Equal(t, sample, r.Sample(table.n))
+
+			var (
+				mu        sync.Mutex
+				extracted []string
+			)
+
+			// The order given doesn't matter
+			reversed := slices.Clone(sample)
+			slices.Reverse(reversed)
+
+			err := r.Extract(context.Background(), func(f *sevenzip.File, rc io.Reader) error {
+				b, err := io.ReadAll(rc)
+				if err != nil {
+					return err
+				}
+
+				assert.Len(t, b, int(f.UncompressedSize)) //nolint:gosec
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				extracted = append(extracted, f.Name)
+
+				return nil
+			}, sevenzip.WithFiles(reversed))
+			require.NoError(t, err)
+
+			slices.Sort(extracted)
+			assert.Equal(t, table.want, extracted)
+		})
+	}
+}
@@ -0,0 +1,24 @@
+// Package sevenziptest provides helpers for testing code that uses the
+// sevenzip package.
+package sevenziptest
+
+import (
+	"testing"
+
+	"github.com/javi11/sevenzip"
+)
+
+// AssertClosed marks the test as failed if any reader returned by
+// [sevenzip.File.Open] for r is still open. Call it once the code under test
+// has finished with the archive to catch readers that were never closed.
+func AssertClosed(tb testing.TB, r *sevenzip.Reader) bool {
+	tb.Helper()
+
+	if n := r.OpenHandles(); n != 0 {
+		tb.Errorf("sevenziptest: %d file reader(s) not closed", n)
+
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,147 @@
+package sevenzip
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var errNegativeOffset = errors.New("sevenzip: negative offset")
+
+// A Spool is temporary storage for data that has to be held while an archive
+// is built, such as the contents of files written to a [Writer] that can't
+// seek. Data is only ever appended with Write and read back with ReadAt.
+type Spool interface {
+	io.Writer
+	io.ReaderAt
+	// Size returns the number of bytes written so far.
+	Size() int64
+	// Close discards the data and releases any resources.
+	io.Closer
+}
+
+// A SpoolFunc returns a new, empty, [Spool]. It is called each time
+// temporary storage is needed so embedded and serverless environments can
+// control where the data goes.
+type SpoolFunc func() (Spool, error)
+
+// MemorySpool returns a [SpoolFunc] that keeps everything in memory.
+func MemorySpool() SpoolFunc {
+	return func() (Spool, error) {
+		return new(memorySpool), nil
+	}
+}
+
+// DiskSpool returns a [SpoolFunc] that writes everything to a temporary file
+// in dir, which is removed again when the [Spool] is closed. If dir is empty
+// [os.TempDir] is used.
+func DiskSpool(dir string) SpoolFunc {
+	return func() (Spool, error) {
+		f, err := os.CreateTemp(dir, "sevenzip-*")
+		if err != nil {
+			return nil, fmt.Errorf("sevenzip: error creating temporary file: %w", err)
+		}
+
+		return &fileSpool{f: f}, nil
+	}
+}
+
+// ThresholdSpool returns a [SpoolFunc] that keeps up to threshold bytes in
+// memory and only moves everything to a temporary file in dir, as with
+// [DiskSpool], once more is written.
+func ThresholdSpool(threshold int64, dir string) SpoolFunc {
+	return func() (Spool, error) {
+		return &thresholdSpool{
+			Spool:     new(memorySpool),
+			threshold: threshold,
+			disk:      DiskSpool(dir),
+		}, nil
+	}
+}
+
+type memorySpool struct {
+	b []byte
+}
+
+func (s *memorySpool) Write(p []byte) (int, error) {
+	s.b = append(s.b, p...)
+
+	return len(p), nil
+}
+
+func (s *memorySpool) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errNegativeOffset
+	}
+
+	if off >= int64(len(s.b)) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, s.b[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+func (s *memorySpool) Size() int64 {
+	return int64(len(s.b))
+}
+
+func (s *memorySpool) Close() error {
+	s.b = nil
+
+	return nil
+}
+
+type fileSpool struct {
+	f    *os.File
+	size int64
+}
+
+func (s *fileSpool) Write(p []byte) (int, error) {
+	n, err := s.f.Write(p)
+	s.size += int64(n)
+
+	return n, err //nolint:wrapcheck
+}
+
+func (s *fileSpool) ReadAt(p []byte, off int64) (int, error) {
+	return s.f.ReadAt(p, off) //nolint:wrapcheck
+}
+
+func (s *fileSpool) Size() int64 {
+	return s.size
+}
+
+func (s *fileSpool) Close() error {
+	return errors.Join(s.f.Close(), os.Remove(s.f.Name())) //nolint:wrapcheck
+}
+
+type thresholdSpool struct {
+	Spool
+	threshold int64
+	disk      SpoolFunc
+	spilled   bool
+}
+
+func (s *thresholdSpool) Write(p []byte) (int, error) {
+	if !s.spilled && s.Size()+int64(len(p)) > s.threshold {
+		disk, err := s.disk()
+		if err != nil {
+			return 0, err
+		}
+
+		if _, err := io.Copy(disk, io.NewSectionReader(s.Spool, 0, s.Size())); err != nil {
+			return 0, errors.Join(fmt.Errorf("sevenzip: error spilling to disk: %w", err), disk.Close())
+		}
+
+		_ = s.Spool.Close()
+		s.Spool, s.spilled = disk, true
+	}
+
+	return s.Spool.Write(p) //nolint:wrapcheck
+}
@@ -0,0 +1,141 @@
+package sevenzip_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tempFiles returns the number of files in dir.
+func tempFiles(t *testing.T, dir string) int {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	return len(entries)
+}
+
+func TestSpool(t *testing.T) {
+	t.Parallel()
+
+	tables := []struct {
+		name string
+		fn   func(dir string) sevenzip.SpoolFunc
+		// files is the number of temporary files after each write
+		files []int
+	}{
+		{
+			name: "memory",
+			fn: func(string) sevenzip.SpoolFunc {
+				return sevenzip.MemorySpool()
+			},
+			files: []int{0, 0},
+		},
+		{
+			name:  "disk",
+			fn:    sevenzip.DiskSpool,
+			files: []int{1, 1},
+		},
+		{
+			name: "threshold",
+			fn: func(dir string) sevenzip.SpoolFunc {
+				return sevenzip.ThresholdSpool(8, dir)
+			},
+			files: []int{0, 1},
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+
+			s, err := table.fn(dir)()
+			require.NoError(t, err)
+
+			var want []byte
+
+			for i, p := range [][]byte{[]byte("sevenzip"), []byte(" spool")} {
+				n, err := s.Write(p)
+				require.NoError(t, err)
+				assert.Equal(t, len(p), n)
+
+				want = append(want, p...)
+
+				assert.Equal(t, int64(len(want)), s.Size())
+				assert.Equal(t, table.files[i], tempFiles(t, dir))
+			}
+
+			b, err := io.ReadAll(io.NewSectionReader(s, 0, s.Size()))
+			require.NoError(t, err)
+			assert.Equal(t, want, b)
+
+			p := make([]byte, 4)
+			n, err := s.ReadAt(p, s.Size()-2)
+			assert.ErrorIs(t, err, io.EOF)
+			assert.Equal(t, []byte("ol"), p[:n])
+
+			require.NoError(t, s.Close())
+			assert.Zero(t, tempFiles(t, dir))
+		})
+	}
+}
+
+func TestNormalizeSpool(t *testing.T) {
+	t.Parallel()
+
+	src, err := sevenzip.OpenReader(filepath.Join("testdata", "t1.7z"))
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, src.Close())
+	}()
+
+	dir := t.TempDir()
+
+	assert.Equal(t, normalize(t, &src.Reader), normalize(t, &src.Reader, sevenzip.WithSpool(sevenzip.MemorySpool())))
+	assert.Equal(t, normalize(t, &src.Reader), normalize(t, &src.Reader, sevenzip.WithSpool(sevenzip.ThresholdSpool(16, dir))))
+	assert.Zero(t, tempFiles(t, dir))
+}
+
+func TestWriterSpool(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	var buf bytes.Buffer
+
+	w := sevenzip.NewWriter(&buf, sevenzip.WithStore(), sevenzip.WithWriterSpool(sevenzip.ThresholdSpool(16, dir)))
+
+	fw, err := w.Create("a.txt")
+	require.NoError(t, err)
+
+	_, err = fw.Write(bytes.Repeat([]byte("a"), 1000))
+	require.NoError(t, err)
+
+	// The contents have spilled to disk
+	assert.Equal(t, 1, tempFiles(t, dir))
+
+	require.NoError(t, w.Close())
+	assert.Zero(t, tempFiles(t, dir))
+
+	r, err := sevenzip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, r.File, 1)
+
+	rc, err := r.File[0].Open()
+	require.NoError(t, err)
+
+	b, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	assert.Equal(t, bytes.Repeat([]byte("a"), 1000), b)
+}
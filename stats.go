@@ -0,0 +1,117 @@
+package sevenzip
+
+import "slices"
+
+// SizeStats are the packed and unpacked sizes of part of an archive.
+type SizeStats struct {
+	PackedSize   uint64
+	UnpackedSize uint64
+}
+
+// Ratio returns the packed size as a fraction of the unpacked size, so
+// smaller is better, or zero if the unpacked size is zero.
+func (s SizeStats) Ratio() float64 {
+	if s.UnpackedSize == 0 {
+		return 0
+	}
+
+	return float64(s.PackedSize) / float64(s.UnpackedSize)
+}
+
+func (s *SizeStats) add(packed, unpacked uint64) {
+	s.PackedSize += packed
+	s.UnpackedSize += unpacked
+}
+
+// MethodStats are the sizes of the streams read and written by every use of
+// a method in an archive.
+type MethodStats struct {
+	// Blocks is the number of blocks using the method.
+	Blocks int
+	// PackedSize is the size of the streams read by the method and
+	// UnpackedSize the size of the streams it writes, so for a filter
+	// such as BCJ both are the same.
+	SizeStats
+}
+
+// Stats summarise the sizes of an archive for comparing methods across many
+// archives.
+type Stats struct {
+	// SizeStats are the sizes of every block in the archive, not
+	// including the header.
+	SizeStats
+	// Blocks has the sizes of each block in the same order as
+	// [Reader.Blocks].
+	Blocks []SizeStats
+	// Methods has the sizes for each method, keyed by [Coder.Name]. When
+	// a block uses more than one method each one is given the sizes of
+	// its own streams, so a block compressed with LZMA after the BCJ
+	// filter gives LZMA the packed size and the size of the filtered
+	// stream, and BCJ the size of the filtered stream for both.
+	Methods map[string]MethodStats
+}
+
+// Stats returns the packed and unpacked sizes of the archive, each block and
+// each method, computed from the header alone without decoding anything.
+func (z *Reader) Stats() *Stats {
+	s := &Stats{
+		Blocks:  make([]SizeStats, z.si.Folders()),
+		Methods: make(map[string]MethodStats),
+	}
+
+	_, packedSizes := z.si.folderOffsets()
+
+	k := 0
+
+	for i := range s.Blocks {
+		f := z.si.unpackInfo.folder[i]
+
+		s.Blocks[i].add(packedSizes[i], f.unpackSize())
+		s.add(packedSizes[i], f.unpackSize())
+
+		for j, c := range f.coder {
+			in, out := f.coderStreams(j)
+
+			var packed, unpacked uint64
+
+			for n := range c.in {
+				packed += z.streamSize(f, k, in+n)
+			}
+
+			for n := range c.out {
+				unpacked += f.size[out+n]
+			}
+
+			name := Coder{ID: c.id}.Name()
+
+			m := s.Methods[name]
+			m.add(packed, unpacked)
+
+			if !slices.ContainsFunc(f.coder[:j], func(o *coder) bool {
+				return Coder{ID: o.id}.Name() == name
+			}) {
+				m.Blocks++
+			}
+
+			s.Methods[name] = m
+		}
+
+		k += int(f.packedStreams) //nolint:gosec
+	}
+
+	return s
+}
+
+// streamSize returns the size of the input stream in of the folder f, whose
+// packed streams start at index k in the pack info.
+func (z *Reader) streamSize(f *folder, k int, in uint64) uint64 {
+	if bp := f.findInBindPair(in); bp != nil {
+		return f.size[bp.out]
+	}
+
+	if j := slices.Index(f.packed, in); j >= 0 && z.si.packInfo != nil && k+j < len(z.si.packInfo.size) {
+		return z.si.packInfo.size[k+j]
+	}
+
+	return 0
+}
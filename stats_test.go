@@ -0,0 +1,95 @@
+package sevenzip_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+
+	tables := []struct {
+		file    string
+		methods []string
+		filters []string
+	}{
+		{
+			file:    "copy.7z",
+			methods: []string{"Copy"},
+			filters: []string{"Copy"},
+		},
+		{
+			file:    "lzma2.7z",
+			methods: []string{"LZMA2"},
+		},
+		{
+			file:    "bcj.7z",
+			methods: []string{"BCJ", "LZMA2"},
+			filters: []string{"BCJ"},
+		},
+		{
+			file:    "bcj2.7z",
+			methods: []string{"BCJ2"},
+		},
+		{
+			file: "lzma1900.7z",
+		},
+		{
+			file: "empty.7z",
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.file, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := sevenzip.OpenReader(filepath.Join("testdata", table.file))
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				require.NoError(t, r.Close())
+			})
+
+			stats := r.Stats()
+			blocks := r.Blocks()
+
+			require.Len(t, stats.Blocks, len(blocks))
+
+			var total sevenzip.SizeStats
+
+			for i, b := range blocks {
+				assert.Equal(t, b.PackedSize, stats.Blocks[i].PackedSize)
+				assert.Equal(t, b.UnpackedSize, stats.Blocks[i].UnpackedSize)
+
+				total.PackedSize += b.PackedSize
+				total.UnpackedSize += b.UnpackedSize
+			}
+
+			assert.Equal(t, total, stats.SizeStats)
+
+			for _, name := range table.methods {
+				m, ok := stats.Methods[name]
+				require.True(t, ok, name)
+				assert.Equal(t, len(blocks), m.Blocks, name)
+				assert.NotZero(t, m.UnpackedSize, name)
+			}
+
+			for _, name := range table.filters {
+				m := stats.Methods[name]
+				assert.Equal(t, m.PackedSize, m.UnpackedSize, name)
+				assert.InDelta(t, 1, m.Ratio(), 0, name)
+			}
+
+			var methodBlocks int
+			for _, m := range stats.Methods {
+				methodBlocks += m.Blocks
+			}
+
+			assert.GreaterOrEqual(t, methodBlocks, len(blocks))
+		})
+	}
+}
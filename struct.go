@@ -16,14 +16,14 @@ import (
 )
 
 var (
-	errAlgorithm             = errors.New("sevenzip: unsupported compression algorithm")
-	errInvalidWhence         = errors.New("invalid whence")
-	errNegativeSeek          = errors.New("negative seek")
-	errSeekBackwards         = errors.New("cannot seek backwards")
-	errSeekEOF               = errors.New("cannot seek beyond EOF")
-	errMultipleOutputStreams = errors.New("more than one output stream")
-	errNoBoundStream         = errors.New("cannot find bound stream")
-	errNoUnboundStream       = errors.New("expecting one unbound output stream")
+	errInvalidWhence   = errors.New("invalid whence")
+	errNegativeSeek    = errors.New("negative seek")
+	errSeekBackwards   = errors.New("cannot seek backwards")
+	errSeekEOF         = errors.New("cannot seek beyond EOF")
+	errOutputStreams   = errors.New("wrong number of output streams")
+	errNoBoundStream   = errors.New("cannot find bound stream")
+	errNoUnboundStream = errors.New("expecting one unbound output stream")
+	errBindCycle       = errors.New("cycle in bound streams")
 )
 
 // CryptoReadCloser adds a Password method to decompressors.
@@ -49,6 +49,7 @@ type packInfo struct {
 	streams  uint64
 	size     []uint64
 	digest   []uint32
+	defined  []bool // Which of digest are defined
 }
 
 type coder struct {
@@ -90,25 +91,53 @@ func (f *folder) findOutBindPair(i uint64) *bindPair {
 	return nil
 }
 
-func (f *folder) coderReader(readers []io.ReadCloser, coder uint64, password string) (io.ReadCloser, bool, error) {
-	dcomp := decompressor(f.coder[coder].id)
-	if dcomp == nil {
-		return nil, false, errAlgorithm
+// folderConfig carries the per-Reader settings needed to decode a folder.
+type folderConfig struct {
+	password    string
+	readAhead   int
+	done        <-chan struct{}
+	cbc         CBCDecrypterFunc
+	lookup      func([]byte) Decompressor
+	multiLookup func([]byte) MultiDecompressor
+}
+
+// coderReaders returns a reader for each output stream of the coder c.
+func (f *folder) coderReaders(readers []io.ReadCloser, c int, cfg *folderConfig) ([]io.ReadCloser, bool, error) {
+	_, out := f.coderStreams(c)
+	cd := f.coder[c]
+
+	if cd.out != 1 {
+		outs, err := newMultiCoderReader(cd.id, cd.properties, f.size[out:out+cd.out], readers, cfg)
+
+		return outs, false, err
+	}
+
+	cr, encrypted, err := f.coderReader(readers, cd, f.size[out], cfg)
+	if err != nil {
+		return nil, encrypted, err
 	}
 
-	cr, err := dcomp(f.coder[coder].properties, f.size[coder], readers)
+	return []io.ReadCloser{cr}, encrypted, nil
+}
+
+func (f *folder) coderReader(readers []io.ReadCloser, c *coder, size uint64, cfg *folderConfig) (io.ReadCloser, bool, error) {
+	cr, err := newCoderReader(c.id, c.properties, size, readers, cfg)
 	if err != nil {
 		return nil, false, err
 	}
 
 	crc, ok := cr.(CryptoReadCloser)
 	if ok {
-		if err = crc.Password(password); err != nil {
+		if cfg.password == "" {
+			return nil, true, ErrPasswordRequired
+		}
+
+		if err = crc.Password(cfg.password); err != nil {
 			return nil, true, fmt.Errorf("sevenzip: error setting password: %w", err)
 		}
 	}
 
-	return plumbing.LimitReadCloser(cr, int64(f.size[coder])), ok, nil //nolint:gosec
+	return cr, ok, nil
 }
 
 type folderReadCloser struct {
@@ -117,6 +146,7 @@ type folderReadCloser struct {
 	wc            *plumbing.WriteCounter
 	size          int64
 	hasEncryption bool
+	audit         *auditSink
 }
 
 func (rc *folderReadCloser) Checksum() []byte {
@@ -186,14 +216,16 @@ func (f *folder) unpackSize() uint64 {
 }
 
 type unpackInfo struct {
-	folder []*folder
-	digest []uint32
+	folder  []*folder
+	digest  []uint32
+	defined []bool // Which of digest are defined
 }
 
 type subStreamsInfo struct {
 	streams []uint64
 	size    []uint64
 	digest  []uint32
+	defined []bool // Which of digest are defined
 }
 
 type streamsInfo struct {
@@ -210,14 +242,47 @@ func (si *streamsInfo) Folders() int {
 	return 0
 }
 
-func (si *streamsInfo) FileFolderAndSize(file int) (int, uint64, uint32) {
+// packedSize returns the total size of the packed streams.
+func (si *streamsInfo) packedSize() uint64 {
+	var size uint64
+
+	if si != nil && si.packInfo != nil {
+		for _, v := range si.packInfo.size {
+			size += v
+		}
+	}
+
+	return size
+}
+
+// Files returns the number of files stored in the folders.
+func (si *streamsInfo) Files() int {
+	if si == nil || si.subStreamsInfo == nil {
+		return si.Folders()
+	}
+
+	files := 0
+	for _, n := range si.subStreamsInfo.streams {
+		files += int(n) //nolint:gosec
+	}
+
+	return files
+}
+
+// FileFolderAndSize returns the folder holding file, its size and its CRC,
+// along with whether the CRC is defined.
+func (si *streamsInfo) FileFolderAndSize(file int) (int, uint64, uint32, bool) {
 	var (
 		folder  int
 		streams uint64 = 1
 		crc     uint32
+		defined bool
 	)
 
-	if si.subStreamsInfo != nil {
+	// Without substreams info each folder holds a single file
+	if si.subStreamsInfo == nil {
+		folder = file
+	} else {
 		total := uint64(0)
 
 		for folder, streams = range si.subStreamsInfo.streams {
@@ -227,94 +292,169 @@ func (si *streamsInfo) FileFolderAndSize(file int) (int, uint64, uint32) {
 			}
 		}
 
-		if len(si.subStreamsInfo.digest) > 0 {
-			crc = si.subStreamsInfo.digest[file]
+		if len(si.subStreamsInfo.defined) > 0 && si.subStreamsInfo.defined[file] {
+			crc, defined = si.subStreamsInfo.digest[file], true
 		}
 	}
 
 	if streams == 1 {
-		if len(si.unpackInfo.digest) > 0 {
-			crc = si.unpackInfo.digest[folder]
+		if len(si.unpackInfo.defined) > 0 && si.unpackInfo.defined[folder] {
+			crc, defined = si.unpackInfo.digest[folder], true
 		}
 
-		return folder, si.unpackInfo.folder[folder].size[len(si.unpackInfo.folder[folder].coder)-1], crc
+		return folder, si.unpackInfo.folder[folder].unpackSize(), crc, defined
 	}
 
-	return folder, si.subStreamsInfo.size[file], crc
+	return folder, si.subStreamsInfo.size[file], crc, defined
 }
 
-func (si *streamsInfo) folderOffset(folder int) int64 {
-	offset := uint64(0)
+// folderOffsets returns the offset and total size of the packed streams of
+// every folder relative to the start of the streams data. These are taken
+// strictly from the pack position and sizes in the header rather than
+// assuming the streams start where the headers begin or follow the unpacked
+// sizes, so any padding a writer places before the first stream or at the
+// end of each stream to align them is accounted for.
+func (si *streamsInfo) folderOffsets() (offsets []int64, sizes []uint64) {
+	offsets = make([]int64, si.Folders())
+	sizes = make([]uint64, si.Folders())
+
+	if si.Folders() == 0 || si.packInfo == nil {
+		return offsets, sizes
+	}
 
-	for i, k := 0, uint64(0); i < folder; i++ {
-		for j := k; j < k+si.unpackInfo.folder[i].packedStreams; j++ {
-			offset += si.packInfo.size[j]
-		}
+	offset, k := si.packInfo.position, 0
 
-		k += si.unpackInfo.folder[i].packedStreams
+	for i, f := range si.unpackInfo.folder {
+		offsets[i] = int64(offset) //nolint:gosec
+
+		for j := uint64(0); j < f.packedStreams && k < len(si.packInfo.size); j++ {
+			sizes[i] += si.packInfo.size[k]
+			offset += si.packInfo.size[k]
+			k++
+		}
 	}
 
-	return int64(si.packInfo.position + offset) //nolint:gosec
+	return offsets, sizes
 }
 
-//nolint:cyclop,funlen,lll
-func (si *streamsInfo) folderReader(r io.ReaderAt, folder int, password string) (*folderReadCloser, uint32, bool, error) {
-	f := si.unpackInfo.folder[folder]
-	in := make([]io.ReadCloser, f.in)
-	out := make([]io.ReadCloser, f.out)
+// A folderGraph resolves the streams of a folder. The coders and the bind
+// pairs joining their streams form a directed acyclic graph which is built
+// on demand from the unbound output stream, so the coders can be listed in
+// any order and have any number of input and output streams.
+type folderGraph struct {
+	f         *folder
+	cfg       *folderConfig
+	in        []io.ReadCloser
+	out       []io.ReadCloser
+	state     []int
+	encrypted bool
+}
+
+const (
+	coderPending = iota
+	coderVisiting
+	coderDone
+)
+
+// coderFor returns the index of the coder owning the output stream o.
+func (g *folderGraph) coderFor(o uint64) (int, bool) {
+	var out uint64
 
-	packedOffset := 0
-	for i := range folder {
-		packedOffset += len(si.unpackInfo.folder[i].packed)
+	for i, c := range g.f.coder {
+		if o < out+c.out {
+			return i, true
+		}
+
+		out += c.out
 	}
 
-	offset := int64(0)
+	return 0, false
+}
 
-	for i, input := range f.packed {
-		size := int64(si.packInfo.size[packedOffset+i]) //nolint:gosec
-		in[input] = util.NopCloser(bufio.NewReader(io.NewSectionReader(r, si.folderOffset(folder)+offset, size)))
-		offset += size
+// output returns the reader for the output stream o, creating the coder
+// that writes it, and recursively any coders bound to its inputs, first.
+// Each stream can only be read once.
+func (g *folderGraph) output(o uint64) (io.ReadCloser, error) {
+	c, ok := g.coderFor(o)
+	if !ok {
+		return nil, errNoBoundStream
 	}
 
-	var (
-		hasEncryption bool
-		input, output uint64
-	)
+	switch g.state[c] {
+	case coderVisiting:
+		return nil, errBindCycle
+	case coderPending:
+		g.state[c] = coderVisiting
 
-	for i, c := range f.coder {
-		if c.out != 1 {
-			return nil, 0, hasEncryption, errMultipleOutputStreams
+		if err := g.build(c); err != nil {
+			return nil, err
 		}
 
-		for j := input; j < input+c.in; j++ {
-			if in[j] != nil {
-				continue
-			}
+		g.state[c] = coderDone
+	}
 
-			bp := f.findInBindPair(j)
-			if bp == nil || out[bp.out] == nil {
-				return nil, 0, hasEncryption, errNoBoundStream
-			}
+	rc := g.out[o]
+	if rc == nil {
+		return nil, errNoBoundStream
+	}
 
-			in[j] = out[bp.out]
-		}
+	g.out[o] = nil
+
+	return rc, nil
+}
+
+// build creates the coder c, resolving each of its inputs to either a packed
+// stream or the output of another coder.
+func (g *folderGraph) build(c int) error {
+	first, out := g.f.coderStreams(c)
+	readers := make([]io.ReadCloser, g.f.coder[c].in)
+
+	for j := range readers {
+		i := first + uint64(j) //nolint:gosec
+
+		if g.in[i] != nil {
+			readers[j], g.in[i] = g.in[i], nil
 
-		var (
-			isEncrypted bool
-			err         error
-		)
+			continue
+		}
 
-		out[output], isEncrypted, err = f.coderReader(in[input:input+c.in], uint64(i), password) //nolint:gosec
-		if err != nil {
-			return nil, 0, hasEncryption, err
+		bp := g.f.findInBindPair(i)
+		if bp == nil {
+			return errNoBoundStream
 		}
 
-		if isEncrypted {
-			hasEncryption = true
+		var err error
+		if readers[j], err = g.output(bp.out); err != nil {
+			return err
 		}
+	}
+
+	outs, encrypted, err := g.f.coderReaders(readers, c, g.cfg)
+	g.encrypted = g.encrypted || encrypted
 
-		input += c.in
-		output += c.out
+	if err != nil {
+		return err
+	}
+
+	copy(g.out[out:], outs)
+
+	return nil
+}
+
+// folderReader returns a reader for the decoded stream of folder, along with
+// its CRC, whether the CRC is defined and whether any of its coders are
+// encrypted.
+func (si *streamsInfo) folderReader(r io.ReaderAt, folder int, cfg *folderConfig) (*folderReadCloser, uint32, bool, bool, error) {
+	f := si.unpackInfo.folder[folder]
+	in := make([]io.ReadCloser, f.in)
+
+	k := si.packedIndex(folder)
+	offset := si.packedOffset(k)
+
+	for i, input := range f.packed {
+		size := int64(si.packInfo.size[k+i]) //nolint:gosec
+		in[input] = util.NopCloser(bufio.NewReaderSize(io.NewSectionReader(r, offset, size), cfg.readAhead))
+		offset += size
 	}
 
 	unbound := make([]uint64, 0, f.out)
@@ -325,24 +465,39 @@ func (si *streamsInfo) folderReader(r io.ReaderAt, folder int, password string)
 		}
 	}
 
-	if len(unbound) != 1 || out[unbound[0]] == nil {
-		return nil, 0, hasEncryption, errNoUnboundStream
+	if len(unbound) != 1 {
+		return nil, 0, false, false, errNoUnboundStream
+	}
+
+	g := &folderGraph{
+		f:     f,
+		cfg:   cfg,
+		in:    in,
+		out:   make([]io.ReadCloser, f.out),
+		state: make([]int, len(f.coder)),
+	}
+
+	rc, err := g.output(unbound[0])
+	if err != nil {
+		return nil, 0, false, g.encrypted, err
 	}
 
-	fr := newFolderReadCloser(out[unbound[0]], int64(f.unpackSize()), hasEncryption) //nolint:gosec
+	fr := newFolderReadCloser(rc, int64(f.unpackSize()), g.encrypted) //nolint:gosec
 
-	if si.unpackInfo.digest != nil {
-		return fr, si.unpackInfo.digest[folder], hasEncryption, nil
+	if len(si.unpackInfo.defined) > 0 && si.unpackInfo.defined[folder] {
+		return fr, si.unpackInfo.digest[folder], true, g.encrypted, nil
 	}
 
-	return fr, 0, hasEncryption, nil
+	return fr, 0, false, g.encrypted, nil
 }
 
 type filesInfo struct {
-	file []FileHeader
+	file    []FileHeader
+	comment []byte
 }
 
 type header struct {
+	properties  []ArchiveProperty
 	streamsInfo *streamsInfo
 	filesInfo   *filesInfo
 }
@@ -364,6 +519,16 @@ type FileHeader struct {
 
 	isEmptyStream bool
 	isEmptyFile   bool
+	isMissing     bool
+	crcDefined    bool
+}
+
+// HasStream reports whether the contents of the file are stored in a
+// stream. It is false for directories and empty files, and also for files
+// whose contents are missing from the archive, such as when the header has
+// no unpack info, which can't be opened.
+func (h *FileHeader) HasStream() bool {
+	return !h.isEmptyStream && !h.isEmptyFile && !h.isMissing
 }
 
 // FileInfo returns an [fs.FileInfo] for the FileHeader.
@@ -402,6 +567,7 @@ const (
 
 	msdosDir      = 0x10
 	msdosReadOnly = 0x01
+	msdosArchive  = 0x20
 )
 
 // Mode returns the permission and mode bits for the FileHeader.
@@ -465,3 +631,53 @@ func unixModeToFileMode(m uint32) iofs.FileMode {
 
 	return mode
 }
+
+// fileModeToAttributes returns the attributes for mode, with the POSIX
+// attributes in the high 16 bits flagged with 0x8000 the same as 7-Zip, and
+// the MS-DOS directory and read-only attributes set to match.
+//
+//nolint:cyclop
+func fileModeToAttributes(mode iofs.FileMode) uint32 {
+	m := uint32(mode.Perm())
+
+	switch {
+	case mode&iofs.ModeDir != 0:
+		m |= sIFDIR
+	case mode&iofs.ModeSymlink != 0:
+		m |= sIFLNK
+	case mode&iofs.ModeNamedPipe != 0:
+		m |= sIFIFO
+	case mode&iofs.ModeSocket != 0:
+		m |= sIFSOCK
+	case mode&iofs.ModeCharDevice != 0:
+		m |= sIFCHR
+	case mode&iofs.ModeDevice != 0:
+		m |= sIFBLK
+	default:
+		m |= sIFREG
+	}
+
+	if mode&iofs.ModeSetuid != 0 {
+		m |= sISUID
+	}
+
+	if mode&iofs.ModeSetgid != 0 {
+		m |= sISGID
+	}
+
+	if mode&iofs.ModeSticky != 0 {
+		m |= sISVTX
+	}
+
+	attributes := m<<16 | 0x8000
+
+	if mode.IsDir() {
+		attributes |= msdosDir
+	}
+
+	if mode.Perm()&0o222 == 0 {
+		attributes |= msdosReadOnly
+	}
+
+	return attributes
+}
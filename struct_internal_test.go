@@ -26,7 +26,7 @@ func TestFileReadCloser_Seek(t *testing.T) {
 
 	require.GreaterOrEqual(t, len(r.File), 1)
 
-	rc, _, _, err := r.folderReader(r.si, r.File[0].folder)
+	rc, _, _, _, err := r.folderReader(r.si, r.File[0].folder)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -0,0 +1,192 @@
+#!/bin/sh
+#
+# Builds the fixtures for TestConformance: archives written by other tools,
+# along with a manifest of what every archive should contain. Run from
+# anywhere, then check in the result:
+#
+#   testdata/conformance/generate.sh
+#
+# Archives come from whichever of these producers are available, anything
+# missing is reported and skipped:
+#
+#   - 7-Zip: the same input files compressed by each version listed below
+#     with each method, each version runs in its own docker container so
+#     nothing needs installing. The versions run from 9.20, as packaged by
+#     p7zip on Debian wheezy, to a current release. None of the checked in
+#     archives come from 7-Zip yet
+#   - libarchive: the same input files compressed by bsdtar with each method
+#     it can write
+#   - p7zip: archives made by p7zip for github.com/saracen/go7z-fixtures,
+#     fetched from the Go module proxy along with the files they were made
+#     from
+#
+# Versions and methods can be added to the lists below; the test picks up
+# whatever is in manifest.json.
+
+set -eu
+
+cd "$(dirname "$0")"
+
+# Docker image, command to install 7-Zip and the binary it provides
+VERSIONS='
+debian/eol:wheezy|apt-get -o Acquire::Check-Valid-Until=false update -qq && apt-get install -qq -y p7zip-full >/dev/null|7z
+debian:bookworm|apt-get update -qq && apt-get install -qq -y p7zip-full >/dev/null|7z
+debian:bookworm|apt-get update -qq && apt-get install -qq -y 7zip >/dev/null|7zz
+alpine:3.21|apk add -q 7zip|7zz
+'
+
+# Name of the fixture and the switches used to create it
+CODECS='
+copy|-m0=Copy
+lzma|-m0=LZMA
+lzma2|-m0=LZMA2
+ppmd|-m0=PPMd
+bzip2|-m0=BZip2
+deflate|-m0=Deflate
+bcj|-mf=BCJ -m0=LZMA2
+delta|-mf=Delta:4 -m0=LZMA2
+arm|-mf=ARM -m0=LZMA2
+solid|-ms=on -m0=LZMA2
+aes|-m0=LZMA2 -pconformance -mhe=on
+'
+
+# Name of the fixture, the bsdtar compression and the method 7-Zip reports
+# for it with libarchive's default settings
+LIBARCHIVE='
+copy|store|Copy
+deflate|deflate|Deflate
+bzip2|bzip2|BZip2
+lzma|lzma1|LZMA:23
+lzma2|lzma2|LZMA2:23
+ppmd|ppmd|PPMD:o6:mem24
+'
+
+# Name of the fixture, the archive it is copied from in go7z-fixtures, the
+# method p7zip used and the files it was made from
+P7ZIP='
+empty|empty.7z||
+executables|executables-bcj2-386-amd64.7z|LZMA2:1536k|executables/hello-world-linux-386 executables/hello-world-linux-amd64
+ppmd|ppmd.7z|PPMD:o6:mem24|random_01.bin
+'
+
+P7ZIP_MODULE=github.com/saracen/go7z-fixtures@v0.0.0-20190623165746-aa6b8fba1d2f
+
+rm -rf input
+mkdir -p input/dir
+
+i=0
+while [ $i -lt 2000 ]; do
+	echo "$i The quick brown fox jumps over the lazy dog."
+	i=$((i + 1))
+done >input/text.txt
+head -c 4096 /dev/urandom >input/random.bin
+head -c 65536 /dev/zero >input/zeros.bin
+: >input/empty.txt
+echo "nested" >input/dir/nested.txt
+
+manifest=manifest.json.tmp
+printf '{\n  "archives": [' >$manifest
+sep=''
+
+# entry adds an archive to the manifest, the files it should contain are
+# read relative to dir
+entry() {
+	path=$1 producer=$2 codec=$3 password=$4 method=$5 dir=$6
+	shift 6
+
+	printf '%s\n    {\n      "path": "%s",\n      "producer": "%s",\n      "codec": "%s",\n      "password": "%s",\n      "method": "%s",\n      "files": [' \
+		"$sep" "$path" "$producer" "$codec" "$password" "$method" >>$manifest
+	fsep=''
+
+	for f in "$@"; do
+		size=$(wc -c <"$dir/$f" | tr -d ' ')
+		sum=$(sha256sum "$dir/$f" | cut -d' ' -f1)
+		printf '%s\n        {"name": "%s", "size": %s, "sha256": "%s"}' "$fsep" "$f" "$size" "$sum" >>$manifest
+		fsep=','
+	done
+
+	printf '\n      ]\n    }' >>$manifest
+	sep=','
+}
+
+inputs=$(cd input && find . -type f | sed 's|^\./||' | sort)
+
+# Lists are read from files rather than piped so entry runs in this shell
+echo "$CODECS" | sed '/^$/d' >codecs.tmp
+echo "$VERSIONS" | sed '/^$/d' >versions.tmp
+
+if command -v docker >/dev/null; then
+	while IFS='|' read -r image install bin; do
+		# Everything for one version is done in a single container
+		docker run --rm -v "$PWD:/work" -w /work/input "$image" sh -c "
+			set -e
+			$install
+			# The banner is the only way to get the version from 9.20,
+			# which has no i command, and p7zip puts [64] before it
+			version=7-zip-\$($bin 2>&1 | sed -n 's/^7-Zip.* \([0-9][0-9]*\.[0-9][0-9]\) .*/\1/p' | head -n 1)
+			mkdir -p ../\$version
+			echo \$version >../version.tmp
+			while IFS='|' read -r name switches; do
+				rm -f ../\$version/\$name.7z
+				$bin a -bd -y \$switches ../\$version/\$name.7z . >/dev/null
+				$bin l -slt -pconformance ../\$version/\$name.7z >../\$version/\$name.slt
+			done <../codecs.tmp
+		" </dev/null
+
+		version=$(cat version.tmp)
+
+		while IFS='|' read -r name switches; do
+			# The method of the first file with contents
+			method=$(sed -n '/^----------$/,$p' "$version/$name.slt" |
+				awk -F' = ' '$1 == "Size" { size = $2 } $1 == "Method" && size > 0 { print $2; exit }')
+			rm -f "$version/$name.slt"
+
+			password=''
+			case "$switches" in
+			*-pconformance*) password=conformance ;;
+			esac
+
+			# shellcheck disable=SC2086
+			entry "$version/$name.7z" "$version" "$name" "$password" "$method" input $inputs
+		done <codecs.tmp
+	done <versions.tmp
+else
+	echo "docker not found, skipping 7-Zip" >&2
+fi
+
+if command -v bsdtar >/dev/null; then
+	version=libarchive-$(bsdtar --version | sed -n 's/.*libarchive \([0-9][0-9.]*\).*/\1/p')
+	mkdir -p "$version"
+
+	echo "$LIBARCHIVE" | sed '/^$/d' >libarchive.tmp
+
+	while IFS='|' read -r name compression method; do
+		rm -f "$version/$name.7z"
+		# shellcheck disable=SC2086
+		(cd input && bsdtar -cf "../$version/$name.7z" --format 7zip --options "7zip:compression=$compression" $inputs)
+		# shellcheck disable=SC2086
+		entry "$version/$name.7z" "$version" "$name" '' "$method" input $inputs
+	done <libarchive.tmp
+else
+	echo "bsdtar not found, skipping libarchive" >&2
+fi
+
+if command -v go >/dev/null; then
+	dir=$(go mod download -json "$P7ZIP_MODULE" | sed -n 's/^[[:space:]]*"Dir": "\(.*\)",$/\1/p')/testdata
+	mkdir -p p7zip
+
+	echo "$P7ZIP" | sed '/^$/d' >p7zip.tmp
+
+	while IFS='|' read -r name archive method files; do
+		cp "$dir/archives/$archive" "p7zip/$name.7z"
+		chmod 644 "p7zip/$name.7z"
+		# shellcheck disable=SC2086
+		entry "p7zip/$name.7z" p7zip "$name" '' "$method" "$dir" $files
+	done <p7zip.tmp
+else
+	echo "go not found, skipping p7zip" >&2
+fi
+
+printf '\n  ]\n}\n' >>$manifest
+mv $manifest manifest.json
+rm -rf input codecs.tmp versions.tmp version.tmp libarchive.tmp p7zip.tmp
@@ -0,0 +1,221 @@
+package sevenzip
+
+import (
+	"errors"
+	iofs "io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+var errNotDirectory = errors.New("not a directory")
+
+// A pathNode is an entry in the trie of paths built from the file list. The
+// children of a directory are a contiguous run of the sorted file list so
+// they can be listed without copying.
+type pathNode struct {
+	entry    *fileListEntry
+	children []fileListEntry
+	nodes    []*pathNode
+	byName   map[string]*pathNode
+
+	// byPath maps the full path of every node to it, only set on the root
+	// so looking up a deeply nested path doesn't walk every level
+	byPath map[string]*pathNode
+}
+
+// buildTrie builds the trie of paths from the sorted file list.
+func buildTrie(files []fileListEntry) *pathNode {
+	root := &pathNode{entry: dotFile}
+
+	var (
+		nodes  = make([]*pathNode, len(files))
+		byName = make(map[string]*pathNode, len(files)+1)
+		start  = make(map[*pathNode]int)
+	)
+
+	byName["."] = root
+
+	for i := range files {
+		nodes[i] = &pathNode{entry: &files[i]}
+
+		// A file and directory may share a name, the first wins
+		if _, ok := byName[files[i].name]; !ok {
+			byName[files[i].name] = nodes[i]
+		}
+	}
+
+	for i := range files {
+		dir, elem := split(files[i].name)
+
+		parent := byName[dir]
+		if parent == nil {
+			continue
+		}
+
+		if parent.byName == nil {
+			parent.byName = make(map[string]*pathNode)
+			start[parent] = i
+		}
+
+		parent.children = files[start[parent] : i+1]
+		parent.nodes = append(parent.nodes, nodes[i])
+
+		if _, ok := parent.byName[elem]; !ok {
+			parent.byName[elem] = nodes[i]
+		}
+	}
+
+	root.byPath = byName
+
+	return root
+}
+
+// lookup returns the node for name, which must be a valid path, or nil if
+// it doesn't exist.
+func (n *pathNode) lookup(name string) *pathNode {
+	if name == "." {
+		return n
+	}
+
+	if n.byPath != nil {
+		return n.byPath[name]
+	}
+
+	for _, elem := range strings.Split(name, "/") {
+		if n = n.byName[elem]; n == nil {
+			return nil
+		}
+	}
+
+	return n
+}
+
+// glob appends the name of every node below n matching the remaining
+// elements of a pattern to matches.
+func (n *pathNode) glob(elems []string, matches []string) []string {
+	elem, rest := elems[0], elems[1:]
+
+	for _, c := range n.nodes {
+		if ok, _ := path.Match(elem, c.entry.Name()); !ok {
+			continue
+		}
+
+		if len(rest) == 0 {
+			matches = append(matches, c.entry.name)
+		} else {
+			matches = c.glob(rest, matches)
+		}
+	}
+
+	return matches
+}
+
+// files returns every file below n in archive order, so that extracting them
+// decodes each stream sequentially.
+func (n *pathNode) files() []*File {
+	files := make([]*File, 0)
+
+	var walk func(*pathNode)
+
+	walk = func(n *pathNode) {
+		for _, c := range n.nodes {
+			if c.entry.file != nil {
+				files = append(files, c.entry.file)
+			}
+
+			walk(c)
+		}
+	}
+
+	walk(n)
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].folder < files[j].folder || files[i].folder == files[j].folder && files[i].offset < files[j].offset
+	})
+
+	return files
+}
+
+// dir returns the node for the directory name, for the operation op.
+func (z *Reader) dir(op, name string) (*pathNode, error) {
+	if !iofs.ValidPath(name) {
+		return nil, &iofs.PathError{Op: op, Path: name, Err: iofs.ErrInvalid}
+	}
+
+	z.initFileList()
+
+	n := z.trie.lookup(name)
+	if n == nil {
+		return nil, &iofs.PathError{Op: op, Path: name, Err: iofs.ErrNotExist}
+	}
+
+	if !n.entry.isDir {
+		return nil, &iofs.PathError{Op: op, Path: name, Err: errNotDirectory}
+	}
+
+	return n, nil
+}
+
+// ReadDir reads the named directory and returns a list of directory entries
+// sorted by filename, using the semantics of [fs.ReadDirFS]. Only the
+// directory itself is visited so this doesn't depend on the number of files
+// in the archive.
+func (z *Reader) ReadDir(name string) ([]iofs.DirEntry, error) {
+	n, err := z.dir("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]iofs.DirEntry, len(n.children))
+
+	for i := range n.children {
+		if entries[i], err = n.children[i].stat(); err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
+
+// Glob returns the names of all files matching pattern, using the semantics
+// of [fs.GlobFS]. Only directories matching the pattern are visited.
+func (z *Reader) Glob(pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	if pattern == "." {
+		return []string{"."}, nil
+	}
+
+	z.initFileList()
+
+	return z.trie.glob(strings.Split(pattern, "/"), nil), nil
+}
+
+// readerFS hides [Reader.Sub] so that [fs.Sub] can be used to implement it.
+type readerFS struct {
+	z *Reader
+}
+
+func (f readerFS) Open(name string) (iofs.File, error) { return f.z.Open(name) }
+
+func (f readerFS) ReadDir(name string) ([]iofs.DirEntry, error) { return f.z.ReadDir(name) }
+
+func (f readerFS) Glob(pattern string) ([]string, error) { return f.z.Glob(pattern) }
+
+// Sub returns an [fs.FS] corresponding to the subtree rooted at dir, using
+// the semantics of [fs.SubFS], except that dir must exist and be a
+// directory.
+func (z *Reader) Sub(dir string) (iofs.FS, error) {
+	if _, err := z.dir("sub", dir); err != nil {
+		return nil, err
+	}
+
+	if dir == "." {
+		return z, nil
+	}
+
+	return iofs.Sub(readerFS{z}, dir) //nolint:wrapcheck
+}
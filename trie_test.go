@@ -0,0 +1,188 @@
+package sevenzip_test
+
+import (
+	"bytes"
+	"io"
+	iofs "io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// plainFS hides everything but Open so the fs package falls back to its
+// generic implementations.
+type plainFS struct {
+	iofs.FS
+}
+
+func TestReadDir(t *testing.T) {
+	t.Parallel()
+
+	r, err := sevenzip.OpenReader(filepath.Join("testdata", "lzma1900.7z"))
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, r.Close())
+	}()
+
+	err = iofs.WalkDir(plainFS{r}, ".", func(name string, d iofs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+
+		want, err := iofs.ReadDir(plainFS{r}, name)
+		require.NoError(t, err)
+
+		got, err := r.ReadDir(name)
+		require.NoError(t, err, name)
+		require.Len(t, got, len(want), name)
+
+		for i := range want {
+			assert.Equal(t, want[i].Name(), got[i].Name(), name)
+			assert.Equal(t, want[i].IsDir(), got[i].IsDir(), name)
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	_, err = r.ReadDir("missing")
+	assert.ErrorIs(t, err, iofs.ErrNotExist)
+
+	_, err = r.ReadDir("bin/x64/7zr.exe")
+	assert.Error(t, err)
+
+	_, err = r.ReadDir("../bin")
+	assert.ErrorIs(t, err, iofs.ErrInvalid)
+}
+
+func TestGlob(t *testing.T) {
+	t.Parallel()
+
+	r, err := sevenzip.OpenReader(filepath.Join("testdata", "lzma1900.7z"))
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, r.Close())
+	}()
+
+	patterns := []string{
+		".",
+		"*",
+		"Asm/*/*.asm",
+		"*/x86/7z*",
+		"C/Util/*",
+		"bin/x64/7zr.exe",
+		"bin/x64",
+		"bin/x64/",
+		"bin/x64/7zr.exe/*",
+		"missing/*",
+		"[AC]*/*",
+		"",
+	}
+
+	for _, pattern := range patterns {
+		want, err := iofs.Glob(plainFS{r}, pattern)
+		require.NoError(t, err)
+
+		got, err := r.Glob(pattern)
+		require.NoError(t, err, pattern)
+		assert.Equal(t, want, got, pattern)
+	}
+
+	_, err = r.Glob("[")
+	assert.ErrorIs(t, err, path.ErrBadPattern)
+}
+
+func TestSub(t *testing.T) {
+	t.Parallel()
+
+	r, err := sevenzip.OpenReader(filepath.Join("testdata", "lzma1900.7z"))
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, r.Close())
+	}()
+
+	sub, err := r.Sub("Asm")
+	require.NoError(t, err)
+
+	b, err := iofs.ReadFile(sub, "arm/7zCrcOpt.asm")
+	require.NoError(t, err)
+
+	f, ok := r.FileByName("Asm/arm/7zCrcOpt.asm")
+	require.True(t, ok)
+	assert.Equal(t, f.UncompressedSize, uint64(len(b)))
+
+	matches, err := iofs.Glob(sub, "*/7zCrcOpt.asm")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"arm/7zCrcOpt.asm", "x86/7zCrcOpt.asm"}, matches)
+
+	_, err = r.Sub("missing")
+	assert.ErrorIs(t, err, iofs.ErrNotExist)
+
+	_, err = r.Sub("bin/x64/7zr.exe")
+	assert.Error(t, err)
+}
+
+// archiveOf returns an archive holding a file for each name, containing the
+// base of its name.
+func archiveOf(t *testing.T, names ...string) *sevenzip.Reader {
+	t.Helper()
+
+	buf := new(bytes.Buffer)
+	w := sevenzip.NewWriter(buf)
+
+	for _, name := range names {
+		fw, err := w.Create(name)
+		require.NoError(t, err)
+
+		_, err = io.WriteString(fw, path.Base(name))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, w.Close())
+
+	r, err := sevenzip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	return r
+}
+
+func TestLongPaths(t *testing.T) {
+	t.Parallel()
+
+	// A path of more than 32K characters and one nested more than 255
+	// directories deep
+	names := []string{
+		strings.Repeat(strings.Repeat("x", 300)+"/", 110) + "long.txt",
+		strings.Repeat("d/", 300) + "deep.txt",
+		"short.txt",
+	}
+	require.Greater(t, len(names[0]), 32*1024)
+
+	r := archiveOf(t, names...)
+
+	for i, f := range r.File {
+		assert.Equal(t, names[i], f.Name)
+	}
+
+	require.NoError(t, fstest.TestFS(r, names...))
+
+	for _, name := range names {
+		b, err := iofs.ReadFile(r, name)
+		require.NoError(t, err)
+		assert.Equal(t, path.Base(name), string(b))
+	}
+
+	entries, err := r.ReadDir(path.Dir(names[1]))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "deep.txt", entries[0].Name())
+}
@@ -39,7 +39,7 @@ const (
 	idATime
 	idMTime
 	idWinAttributes
-	idComment //nolint:deadcode,varcheck
+	idComment
 	idEncodedHeader
 	idStartPos
 	idDummy
@@ -63,7 +63,7 @@ const (
 //   - Extract bytes from decrypted stream at file offset within folder
 type FileInfo struct {
 	Name        string // File name
-	Offset      int64  // Absolute offset from the start of the archive file where the file's data begins
+	Offset      int64  // Absolute offset from the start of the archive file where the file's data begins, including any [Reader.BaseOffset]
 	Size        uint64 // Uncompressed size in bytes
 	Compressed  bool   // Whether the file uses compression (true means direct extraction not possible)
 	Encrypted   bool   // Whether the file is encrypted (true means direct extraction not possible)
@@ -84,8 +84,39 @@ var (
 	errUnexpectedID           = errors.New("sevenzip: unexpected id")
 	errMissingUnpackInfo      = errors.New("sevenzip: missing unpack info")
 	errWrongNumberOfFilenames = errors.New("sevenzip: wrong number of filenames")
+	errExternal               = errors.New("sevenzip: invalid external data")
 )
 
+// A streamsDecoder decodes each folder of the additional streams in a header
+// into memory.
+type streamsDecoder func(*streamsInfo) ([][]byte, error)
+
+// readExternal reads the external flag that precedes some properties. If it
+// is set the property is stored in one of the additional streams rather than
+// inline, in which case a reader for that stream and its size are returned
+// instead of r, otherwise the size is -1.
+func readExternal(r util.Reader, data [][]byte) (util.Reader, int64, error) {
+	external, err := r.ReadByte()
+	if err != nil {
+		return nil, 0, fmt.Errorf("readExternal: ReadByte error: %w", err)
+	}
+
+	if external == 0 {
+		return r, -1, nil
+	}
+
+	index, err := readUint64(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if index >= uint64(len(data)) {
+		return nil, 0, errExternal
+	}
+
+	return bytes.NewReader(data[index]), int64(len(data[index])), nil
+}
+
 func readUint64(r io.ByteReader) (uint64, error) {
 	b, err := r.ReadByte()
 	if err != nil {
@@ -167,10 +198,12 @@ func readSizes(r io.ByteReader, count uint64) ([]uint64, error) {
 	return sizes, nil
 }
 
-func readCRC(r util.Reader, count uint64) ([]uint32, error) {
+// readCRC returns the digests along with which of them are defined, those
+// that aren't being left as zero.
+func readCRC(r util.Reader, count uint64) ([]uint32, []bool, error) {
 	defined, err := readOptionalBool(r, count)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	crcs := make([]uint32, count)
@@ -178,12 +211,12 @@ func readCRC(r util.Reader, count uint64) ([]uint32, error) {
 	for i := range defined {
 		if defined[i] {
 			if err := binary.Read(r, binary.LittleEndian, &crcs[i]); err != nil {
-				return nil, fmt.Errorf("readCRC: Read error: %w", err)
+				return nil, nil, fmt.Errorf("readCRC: Read error: %w", err)
 			}
 		}
 	}
 
-	return crcs, nil
+	return crcs, defined, nil
 }
 
 //nolint:cyclop
@@ -219,7 +252,7 @@ func readPackInfo(r util.Reader) (*packInfo, error) {
 	}
 
 	if id == idCRC {
-		if p.digest, err = readCRC(r, p.streams); err != nil {
+		if p.digest, p.defined, err = readCRC(r, p.streams); err != nil {
 			return nil, err
 		}
 
@@ -350,7 +383,7 @@ func readFolder(r util.Reader) (*folder, error) {
 }
 
 //nolint:cyclop,funlen
-func readUnpackInfo(r util.Reader) (*unpackInfo, error) {
+func readUnpackInfo(r util.Reader, data [][]byte) (*unpackInfo, error) {
 	u := new(unpackInfo)
 
 	if id, err := r.ReadByte(); err != nil || id != idFolder {
@@ -366,29 +399,15 @@ func readUnpackInfo(r util.Reader) (*unpackInfo, error) {
 		return nil, err
 	}
 
-	external, err := r.ReadByte()
+	fr, _, err := readExternal(r, data)
 	if err != nil {
-		return nil, fmt.Errorf("readUnpackInfo: ReadByte error: %w", err)
-	}
-
-	if external > 0 {
-		/*
-			_, err := readUint64(r)
-			if err != nil {
-				return nil, err
-			}
-		*/
-		// TODO Apparently we seek to this read offset and read the
-		// folder information from there. Not clear if the offset is
-		// absolute for the whole file, or relative to some known
-		// position in the file. Cowardly waiting for an example
-		return nil, errors.New("sevenzip: TODO readUnpackInfo external") //nolint:err113
+		return nil, err
 	}
 
 	u.folder = make([]*folder, folders)
 
 	for i := range folders {
-		if u.folder[i], err = readFolder(r); err != nil {
+		if u.folder[i], err = readFolder(fr); err != nil {
 			return nil, err
 		}
 	}
@@ -421,7 +440,7 @@ func readUnpackInfo(r util.Reader) (*unpackInfo, error) {
 	}
 
 	if id == idCRC {
-		if u.digest, err = readCRC(r, folders); err != nil {
+		if u.digest, u.defined, err = readCRC(r, uint64(len(u.folder))); err != nil {
 			return nil, err
 		}
 
@@ -476,6 +495,11 @@ func readSubStreamsInfo(r util.Reader, folder []*folder) (*subStreamsInfo, error
 		k := 0
 
 		for i := range s.streams {
+			// Folders without any streams have no sizes either
+			if s.streams[i] == 0 {
+				continue
+			}
+
 			total := uint64(0)
 
 			for j := uint64(1); j < s.streams[i]; j++ {
@@ -498,7 +522,7 @@ func readSubStreamsInfo(r util.Reader, folder []*folder) (*subStreamsInfo, error
 	}
 
 	if id == idCRC {
-		if s.digest, err = readCRC(r, files); err != nil {
+		if s.digest, s.defined, err = readCRC(r, files); err != nil {
 			return nil, err
 		}
 
@@ -516,7 +540,7 @@ func readSubStreamsInfo(r util.Reader, folder []*folder) (*subStreamsInfo, error
 }
 
 //nolint:cyclop
-func readStreamsInfo(r util.Reader) (*streamsInfo, error) {
+func readStreamsInfo(r util.Reader, data [][]byte) (*streamsInfo, error) {
 	s := new(streamsInfo)
 
 	id, err := r.ReadByte()
@@ -536,7 +560,7 @@ func readStreamsInfo(r util.Reader) (*streamsInfo, error) {
 	}
 
 	if id == idUnpackInfo {
-		if s.unpackInfo, err = readUnpackInfo(r); err != nil {
+		if s.unpackInfo, err = readUnpackInfo(r, data); err != nil {
 			return nil, err
 		}
 
@@ -547,11 +571,14 @@ func readStreamsInfo(r util.Reader) (*streamsInfo, error) {
 	}
 
 	if id == idSubStreamsInfo {
-		if s.unpackInfo == nil {
-			return nil, errMissingUnpackInfo
+		// Some writers emit substreams info without any unpack info,
+		// which describes no folders
+		var folders []*folder
+		if s.unpackInfo != nil {
+			folders = s.unpackInfo.folder
 		}
 
-		if s.subStreamsInfo, err = readSubStreamsInfo(r, s.unpackInfo.folder); err != nil {
+		if s.subStreamsInfo, err = readSubStreamsInfo(r, folders); err != nil {
 			return nil, err
 		}
 
@@ -568,29 +595,15 @@ func readStreamsInfo(r util.Reader) (*streamsInfo, error) {
 	return s, nil
 }
 
-func readTimes(r util.Reader, count uint64) ([]time.Time, error) {
+func readTimes(r util.Reader, count uint64, data [][]byte) ([]time.Time, error) {
 	defined, err := readOptionalBool(r, count)
 	if err != nil {
 		return nil, err
 	}
 
-	external, err := r.ReadByte()
+	r, _, err = readExternal(r, data)
 	if err != nil {
-		return nil, fmt.Errorf("readTimes: ReadByte error: %w", err)
-	}
-
-	if external > 0 {
-		/*
-			_, err := readUint64(r)
-			if err != nil {
-				return nil, err
-			}
-		*/
-		// TODO Apparently we seek to this read offset and read the
-		// folder information from there. Not clear if the offset is
-		// absolute for the whole file, or relative to some known
-		// position in the file. Cowardly waiting for an example
-		return nil, errors.New("sevenzip: TODO readTimes external") //nolint:err113
+		return nil, err
 	}
 
 	times := make([]time.Time, count)
@@ -625,28 +638,20 @@ func splitNull(data []byte, atEOF bool) (advance int, token []byte, err error) {
 	return
 }
 
-func readNames(r util.Reader, count, length uint64) ([]string, error) {
-	external, err := r.ReadByte()
+func readNames(r util.Reader, count, length uint64, data [][]byte) ([]string, error) {
+	r, size, err := readExternal(r, data)
 	if err != nil {
-		return nil, fmt.Errorf("readNames: ReadByte error: %w", err)
+		return nil, err
 	}
 
-	if external > 0 {
-		/*
-			_, err := readUint64(r)
-			if err != nil {
-				return nil, err
-			}
-		*/
-		// TODO Apparently we seek to this read offset and read the
-		// folder information from there. Not clear if the offset is
-		// absolute for the whole file, or relative to some known
-		// position in the file. Cowardly waiting for an example
-		return nil, errors.New("sevenzip: TODO readNames external") //nolint:err113
+	// Inline names fill the rest of the property, external ones the whole
+	// of their stream
+	if size < 0 {
+		size = int64(length - 1) //nolint:gosec
 	}
 
 	utf16le := unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
-	scanner := bufio.NewScanner(transform.NewReader(io.LimitReader(r, int64(length-1)), utf16le.NewDecoder())) //nolint:gosec,lll
+	scanner := bufio.NewScanner(transform.NewReader(io.LimitReader(r, size), utf16le.NewDecoder()))
 	scanner.Split(splitNull)
 
 	names, i := make([]string, 0, count), uint64(0)
@@ -666,29 +671,15 @@ func readNames(r util.Reader, count, length uint64) ([]string, error) {
 	return names, nil
 }
 
-func readAttributes(r util.Reader, count uint64) ([]uint32, error) {
+func readAttributes(r util.Reader, count uint64, data [][]byte) ([]uint32, error) {
 	defined, err := readOptionalBool(r, count)
 	if err != nil {
 		return nil, err
 	}
 
-	external, err := r.ReadByte()
+	r, _, err = readExternal(r, data)
 	if err != nil {
-		return nil, fmt.Errorf("readAttributes: ReadByte error: %w", err)
-	}
-
-	if external > 0 {
-		/*
-			_, err := readUint64(r)
-			if err != nil {
-				return nil, err
-			}
-		*/
-		// TODO Apparently we seek to this read offset and read the
-		// folder information from there. Not clear if the offset is
-		// absolute for the whole file, or relative to some known
-		// position in the file. Cowardly waiting for an example
-		return nil, errors.New("sevenzip: TODO readAttributes external") //nolint:err113
+		return nil, err
 	}
 
 	attributes := make([]uint32, count)
@@ -705,7 +696,7 @@ func readAttributes(r util.Reader, count uint64) ([]uint32, error) {
 }
 
 //nolint:cyclop,funlen,gocognit,gocyclo
-func readFilesInfo(r util.Reader) (*filesInfo, error) {
+func readFilesInfo(r util.Reader, data [][]byte) (*filesInfo, error) {
 	f := new(filesInfo)
 
 	files, err := readUint64(r)
@@ -761,7 +752,7 @@ func readFilesInfo(r util.Reader) (*filesInfo, error) {
 				}
 			}
 		case idCTime:
-			times, err := readTimes(r, files)
+			times, err := readTimes(r, files, data)
 			if err != nil {
 				return nil, err
 			}
@@ -770,7 +761,7 @@ func readFilesInfo(r util.Reader) (*filesInfo, error) {
 				f.file[i].Created = t
 			}
 		case idATime:
-			times, err := readTimes(r, files)
+			times, err := readTimes(r, files, data)
 			if err != nil {
 				return nil, err
 			}
@@ -779,7 +770,7 @@ func readFilesInfo(r util.Reader) (*filesInfo, error) {
 				f.file[i].Accessed = t
 			}
 		case idMTime:
-			times, err := readTimes(r, files)
+			times, err := readTimes(r, files, data)
 			if err != nil {
 				return nil, err
 			}
@@ -788,7 +779,7 @@ func readFilesInfo(r util.Reader) (*filesInfo, error) {
 				f.file[i].Modified = t
 			}
 		case idName:
-			names, err := readNames(r, files, length)
+			names, err := readNames(r, files, length, data)
 			if err != nil {
 				return nil, err
 			}
@@ -797,7 +788,7 @@ func readFilesInfo(r util.Reader) (*filesInfo, error) {
 				f.file[i].Name = n
 			}
 		case idWinAttributes:
-			attributes, err := readAttributes(r, files)
+			attributes, err := readAttributes(r, files, data)
 			if err != nil {
 				return nil, err
 			}
@@ -805,9 +796,16 @@ func readFilesInfo(r util.Reader) (*filesInfo, error) {
 			for i, a := range attributes {
 				f.file[i].Attributes = a
 			}
-		case idStartPos:
-			return nil, errors.New("sevenzip: TODO idStartPos") //nolint:err113
-		case idDummy:
+		case idComment:
+			var b bytes.Buffer
+			if _, err := io.CopyN(&b, r, int64(length)); err != nil { //nolint:gosec
+				return nil, fmt.Errorf("readFilesInfo: CopyN error: %w", err)
+			}
+
+			f.comment = b.Bytes()
+		case idStartPos, idDummy:
+			// The start position of each file doesn't affect where its
+			// data is read from so is skipped the same as padding
 			if _, err := io.CopyN(io.Discard, r, int64(length)); err != nil { //nolint:gosec
 				return nil, fmt.Errorf("readFilesInfo: CopyN error: %w", err)
 			}
@@ -819,8 +817,40 @@ func readFilesInfo(r util.Reader) (*filesInfo, error) {
 	return f, nil
 }
 
+func readArchiveProperties(r util.Reader) ([]ArchiveProperty, error) {
+	var properties []ArchiveProperty
+
+	for {
+		property, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("readArchiveProperties: ReadByte error: %w", err)
+		}
+
+		if property == idEnd {
+			break
+		}
+
+		length, err := readUint64(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var b bytes.Buffer
+		if _, err := io.CopyN(&b, r, int64(length)); err != nil { //nolint:gosec
+			return nil, fmt.Errorf("readArchiveProperties: CopyN error: %w", err)
+		}
+
+		properties = append(properties, ArchiveProperty{Type: property, Data: b.Bytes()})
+	}
+
+	return properties, nil
+}
+
+// readHeader reads the header. If it has additional streams, which hold any
+// properties marked as external, they are decoded with decode.
+//
 //nolint:cyclop,funlen
-func readHeader(r util.Reader) (*header, error) {
+func readHeader(r util.Reader, decode streamsDecoder) (*header, error) {
 	h := new(header)
 
 	id, err := r.ReadByte()
@@ -829,27 +859,40 @@ func readHeader(r util.Reader) (*header, error) {
 	}
 
 	if id == idArchiveProperties {
-		/*
-			id, err = r.ReadByte()
-			if err != nil {
-				return nil, fmt.Errorf("readHeader: ReadByte error: %w", err)
-			}
-		*/
-		return nil, errors.New("sevenzip: TODO idArchiveProperties") //nolint:err113
+		if h.properties, err = readArchiveProperties(r); err != nil {
+			return nil, err
+		}
+
+		id, err = r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("readHeader: ReadByte error: %w", err)
+		}
 	}
 
+	var data [][]byte
+
 	if id == idAdditionalStreamsInfo {
-		/*
-			id, err = r.ReadByte()
-			if err != nil {
-				return nil, fmt.Errorf("readHeader: ReadByte error: %w", err)
-			}
-		*/
-		return nil, errors.New("sevenzip: TODO idAdditionalStreamsInfo") //nolint:err113
+		si, err := readStreamsInfo(r, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if decode == nil {
+			return nil, errExternal
+		}
+
+		if data, err = decode(si); err != nil {
+			return nil, err
+		}
+
+		id, err = r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("readHeader: ReadByte error: %w", err)
+		}
 	}
 
 	if id == idMainStreamsInfo {
-		if h.streamsInfo, err = readStreamsInfo(r); err != nil {
+		if h.streamsInfo, err = readStreamsInfo(r, data); err != nil {
 			return nil, err
 		}
 
@@ -860,7 +903,7 @@ func readHeader(r util.Reader) (*header, error) {
 	}
 
 	if id == idFilesInfo {
-		if h.filesInfo, err = readFilesInfo(r); err != nil {
+		if h.filesInfo, err = readFilesInfo(r, data); err != nil {
 			return nil, err
 		}
 
@@ -881,30 +924,14 @@ func readHeader(r util.Reader) (*header, error) {
 	j := 0
 
 	for i := range h.filesInfo.file {
-		if h.filesInfo.file[i].isEmptyStream {
+		if h.filesInfo.file[i].isEmptyStream || j >= h.streamsInfo.Files() {
 			continue
 		}
 
-		_, h.filesInfo.file[i].UncompressedSize, h.filesInfo.file[i].CRC32 = h.streamsInfo.FileFolderAndSize(j)
+		fh := &h.filesInfo.file[i]
+		_, fh.UncompressedSize, fh.CRC32, fh.crcDefined = h.streamsInfo.FileFolderAndSize(j)
 		j++
 	}
 
 	return h, nil
 }
-
-func readEncodedHeader(r util.Reader) (*header, error) {
-	if id, err := r.ReadByte(); err != nil || id != idHeader {
-		if err != nil {
-			return nil, fmt.Errorf("readEncodedHeader: ReadByte error: %w", err)
-		}
-
-		return nil, errUnexpectedID
-	}
-
-	header, err := readHeader(r)
-	if err != nil {
-		return nil, err
-	}
-
-	return header, nil
-}
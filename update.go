@@ -0,0 +1,486 @@
+package sevenzip
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	iofs "io/fs"
+	"path/filepath"
+	"time"
+
+	"github.com/bodgit/plumbing"
+	"github.com/javi11/sevenzip/internal/lzma2"
+	"github.com/spf13/afero"
+)
+
+var (
+	errUpdateEncrypted = errors.New("sevenzip: can't compress an encrypted block again")
+	errUpdateVolumes   = errors.New("sevenzip: can't update an archive split into volumes")
+)
+
+// An Update is a set of changes to the entries of an archive, applied with
+// [ApplyUpdate] or [UpdateFile]. The zero value is an empty Update ready to
+// use.
+type Update struct {
+	// Spool sets where blocks that are compressed again are held until
+	// the archive is written. By default they are written to a temporary
+	// file, see [DiskSpool].
+	Spool SpoolFunc
+
+	deleted  []string
+	replaced map[string]io.Reader
+}
+
+// Delete removes the entry with the given name, as it appears in
+// [Reader.File], from the archive. The trailing slash on the name of a
+// directory may be omitted. Deleting a directory doesn't delete its
+// contents.
+func (u *Update) Delete(name string) {
+	u.deleted = append(u.deleted, name)
+}
+
+// Replace replaces the contents of the file with the given name with
+// everything read from r, which isn't read until the update is applied. The
+// name, attributes and creation time of the file are kept and its
+// modification time is set to the current time.
+func (u *Update) Replace(name string, r io.Reader) {
+	if u.replaced == nil {
+		u.replaced = make(map[string]io.Reader)
+	}
+
+	u.replaced[name] = r
+}
+
+// updateWriter builds the packed streams and header of an updated archive.
+type updateWriter struct {
+	src   *Reader
+	spool Spool
+
+	files      []FileHeader
+	folders    []*folder
+	digests    []uint32
+	defined    []bool // Which of digests are defined
+	streams    []packedStream
+	counts     []uint64
+	sizes      []uint64
+	crcs       []uint32
+	crcDefined []bool // Which of crcs are defined
+}
+
+func (uw *updateWriter) addFolder(f *folder, digest uint32, defined bool, files []FileHeader) {
+	uw.folders = append(uw.folders, f)
+	uw.digests = append(uw.digests, digest)
+	uw.defined = append(uw.defined, defined)
+	uw.counts = append(uw.counts, uint64(len(files)))
+
+	for _, fh := range files {
+		uw.files = append(uw.files, fh)
+		uw.sizes = append(uw.sizes, fh.UncompressedSize)
+		uw.crcs = append(uw.crcs, fh.CRC32)
+		uw.crcDefined = append(uw.crcDefined, fh.crcDefined)
+	}
+}
+
+// copyFolder copies folder i of the source archive untouched.
+func (uw *updateWriter) copyFolder(i int, files []FileHeader) {
+	si := uw.src.si
+	f := si.unpackInfo.folder[i]
+	k := si.packedIndex(i)
+
+	for j := range int(f.packedStreams) { //nolint:gosec
+		uw.streams = append(uw.streams, uw.src.copyStream(k+j))
+	}
+
+	var (
+		digest  uint32
+		defined bool
+	)
+
+	if i < len(si.unpackInfo.defined) {
+		digest, defined = si.unpackInfo.digest[i], si.unpackInfo.defined[i]
+	}
+
+	uw.addFolder(f, digest, defined, files)
+}
+
+// encode adds a new LZMA2 folder whose contents are written by fn, which
+// returns the files it holds.
+func (uw *updateWriter) encode(fn func(w io.Writer) ([]FileHeader, error)) error {
+	offset := uw.spool.Size()
+
+	wc, properties, err := lzma2.NewWriter(uw.spool, defaultDictSize)
+	if err != nil {
+		return fmt.Errorf("sevenzip: error compressing: %w", err)
+	}
+
+	cw := new(plumbing.WriteCounter)
+
+	files, err := fn(io.MultiWriter(wc, cw))
+	if err != nil {
+		return err
+	}
+
+	if err := wc.Close(); err != nil {
+		return fmt.Errorf("sevenzip: error compressing: %w", err)
+	}
+
+	size := uw.spool.Size() - offset
+
+	uw.streams = append(uw.streams, packedStream{
+		size: uint64(size), //nolint:gosec
+		write: func(w io.Writer) error {
+			if _, err := io.Copy(w, io.NewSectionReader(uw.spool, offset, size)); err != nil {
+				return fmt.Errorf("sevenzip: error copying packed stream: %w", err)
+			}
+
+			return nil
+		},
+	})
+
+	uw.addFolder(&folder{
+		in:            1,
+		out:           1,
+		packedStreams: 1,
+		coder:         []*coder{{id: []byte{0x21}, in: 1, out: 1, properties: properties}},
+		bindPair:      []*bindPair{},
+		size:          []uint64{cw.Count()},
+		packed:        []uint64{0},
+	}, 0, false, files)
+
+	return nil
+}
+
+// reencode decodes files, which all belong to the same folder, and
+// compresses them again into a new folder.
+func (uw *updateWriter) reencode(files []*File, headers []FileHeader) error {
+	return uw.encode(func(w io.Writer) ([]FileHeader, error) {
+		for _, f := range files {
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+
+			_, err = io.Copy(w, rc)
+			if err = errors.Join(err, rc.Close()); err != nil {
+				return nil, fmt.Errorf("sevenzip: error reading %s: %w", f.Name, err)
+			}
+		}
+
+		return headers, nil
+	})
+}
+
+// replace adds fh with its contents read from r compressed into a new
+// folder, or as an empty file if there aren't any.
+func (uw *updateWriter) replace(fh FileHeader, r io.Reader) error {
+	fh.Modified = time.Now()
+	fh.isEmptyStream, fh.isEmptyFile = false, false
+
+	br := bufio.NewReader(r)
+	if _, err := br.Peek(1); err != nil {
+		if !errors.Is(err, io.EOF) {
+			return fmt.Errorf("sevenzip: error reading replacement for %s: %w", fh.Name, err)
+		}
+
+		fh.CRC32, fh.UncompressedSize, fh.crcDefined = 0, 0, false
+		fh.isEmptyStream, fh.isEmptyFile = true, true
+		uw.files = append(uw.files, fh)
+
+		return nil
+	}
+
+	return uw.encode(func(w io.Writer) ([]FileHeader, error) {
+		h := crc32.NewIEEE()
+
+		n, err := io.Copy(io.MultiWriter(w, h), br)
+		if err != nil {
+			return nil, fmt.Errorf("sevenzip: error reading replacement for %s: %w", fh.Name, err)
+		}
+
+		fh.CRC32, fh.UncompressedSize, fh.crcDefined = h.Sum32(), uint64(n), true //nolint:gosec
+
+		return []FileHeader{fh}, nil
+	})
+}
+
+func (uw *updateWriter) header() *header {
+	h := &header{
+		properties: uw.src.h.properties,
+		filesInfo:  &filesInfo{file: uw.files},
+	}
+
+	if fi := uw.src.h.filesInfo; fi != nil {
+		h.filesInfo.comment = fi.comment
+	}
+
+	if len(uw.folders) == 0 {
+		return h
+	}
+
+	si := &streamsInfo{
+		packInfo:   new(packInfo),
+		unpackInfo: &unpackInfo{folder: uw.folders},
+		subStreamsInfo: &subStreamsInfo{
+			streams: uw.counts,
+			size:    uw.sizes,
+			digest:  uw.crcs,
+			defined: uw.crcDefined,
+		},
+	}
+
+	if hasCRC(uw.defined) {
+		si.unpackInfo.digest, si.unpackInfo.defined = uw.digests, uw.defined
+	}
+
+	for _, s := range uw.streams {
+		si.packInfo.size = append(si.packInfo.size, s.size)
+	}
+
+	si.packInfo.streams = uint64(len(si.packInfo.size))
+	h.streamsInfo = si
+
+	return h
+}
+
+// changes resolves the names in u to the files of z.
+func (u *Update) changes(z *Reader) (map[*File]bool, map[*File]io.Reader, error) {
+	deleted := make(map[*File]bool, len(u.deleted))
+	replaced := make(map[*File]io.Reader, len(u.replaced))
+
+	for _, name := range u.deleted {
+		f, ok := z.FileByName(name)
+		if !ok {
+			return nil, nil, fmt.Errorf("sevenzip: %s: %w", name, iofs.ErrNotExist)
+		}
+
+		deleted[f] = true
+	}
+
+	for name, r := range u.replaced {
+		f, ok := z.FileByName(name)
+		if !ok {
+			return nil, nil, fmt.Errorf("sevenzip: %s: %w", name, iofs.ErrNotExist)
+		}
+
+		if f.FileInfo().IsDir() {
+			return nil, nil, fmt.Errorf("sevenzip: %s: %w", name, errWriteDirectory)
+		}
+
+		replaced[f] = r
+	}
+
+	return deleted, replaced, nil
+}
+
+// ApplyUpdate writes a copy of src to dst with the changes in u applied.
+// Blocks that don't hold any deleted or replaced files are copied as they are
+// without being decoded; only the files kept from the other blocks are
+// decoded and compressed again with LZMA2. Each replaced file is compressed
+// into a new block of its own. It fails if a name in u isn't in src, or if a
+// block that has to be compressed again is encrypted.
+//
+// If the header of src is encrypted, so is the header written to dst, using
+// the same password.
+//
+//nolint:cyclop,funlen
+func ApplyUpdate(src *Reader, dst io.Writer, u *Update) (err error) {
+	if src.opts.nameFilter != nil {
+		return errFiltered
+	}
+
+	deleted, replaced, err := u.changes(src)
+	if err != nil {
+		return err
+	}
+
+	// Work out which folders can't be copied and the headers of the files
+	// in each folder
+	affected := make(map[int]bool)
+	kept := make(map[int]int)
+	headers := make(map[int][]FileHeader)
+
+	for i, f := range src.File {
+		if f.isMissing {
+			return &ReadError{Err: errMissingUnpackInfo}
+		}
+
+		if !f.HasStream() {
+			continue
+		}
+
+		headers[f.folder] = append(headers[f.folder], src.h.filesInfo.file[i])
+
+		if _, ok := replaced[f]; ok || deleted[f] {
+			affected[f.folder] = true
+		} else {
+			kept[f.folder]++
+		}
+	}
+
+	// A folder only has to be decoded if some of its files are kept
+	for i := range affected {
+		if kept[i] == 0 {
+			continue
+		}
+
+		for _, c := range src.si.unpackInfo.folder[i].coder {
+			if c.isAES() {
+				return fmt.Errorf("%w: block %d", errUpdateEncrypted, i)
+			}
+		}
+	}
+
+	fn := u.Spool
+	if fn == nil {
+		fn = DiskSpool("")
+	}
+
+	spool, err := fn()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err = errors.Join(err, spool.Close())
+	}()
+
+	uw := &updateWriter{src: src, spool: spool}
+
+	// Files kept from an affected folder are compressed again in runs
+	// that end where a file from another folder comes between them
+	var (
+		run     []*File
+		runHdrs []FileHeader
+	)
+
+	flush := func() error {
+		if len(run) == 0 {
+			return nil
+		}
+
+		err := uw.reencode(run, runHdrs)
+		run, runHdrs = nil, nil
+
+		return err
+	}
+
+	copied := make(map[int]bool)
+
+	for i, f := range src.File {
+		fh := src.h.filesInfo.file[i]
+
+		if r, ok := replaced[f]; ok {
+			if err := flush(); err != nil {
+				return err
+			}
+
+			if err := uw.replace(fh, r); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		switch {
+		case deleted[f]:
+			// Dropped, even if it shares a folder with other files
+		case !f.HasStream():
+			uw.files = append(uw.files, fh)
+		case affected[f.folder]:
+			if len(run) > 0 && run[0].folder != f.folder {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+
+			run, runHdrs = append(run, f), append(runHdrs, fh)
+		case !copied[f.folder]:
+			if err := flush(); err != nil {
+				return err
+			}
+
+			uw.copyFolder(f.folder, headers[f.folder])
+			copied[f.folder] = true
+		}
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+
+	opts := headerOptions{compress: true}
+	if src.encryptedHeader {
+		opts.password = src.p
+	}
+
+	return writeArchive(dst, uw.header(), uw.streams, opts)
+}
+
+// UpdateFile applies u to the archive at name, as with [ApplyUpdate]. The
+// updated archive is written to a temporary file in the same directory which
+// is then renamed over the original, so the original is left untouched if
+// anything fails. The options are used to open the archive, which can't be
+// split into volumes, and the temporary file is created on the same
+// filesystem if [WithFs] is used.
+func UpdateFile(name string, u *Update, opts ...ReaderOption) (err error) {
+	r, err := OpenReaderWithOptions(name, opts...)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err = errors.Join(err, r.Close())
+	}()
+
+	if len(r.volumes) > 1 {
+		return errUpdateVolumes
+	}
+
+	fs := r.opts.fs
+	if fs == nil {
+		fs = afero.NewOsFs()
+	}
+
+	info, err := fs.Stat(name)
+	if err != nil {
+		return fmt.Errorf("sevenzip: error retrieving file info: %w", err)
+	}
+
+	f, err := afero.TempFile(fs, filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("sevenzip: error creating temporary file: %w", err)
+	}
+
+	if err := writeUpdate(fs, f, &r.Reader, u, info.Mode().Perm()); err != nil {
+		return errors.Join(err, fs.Remove(f.Name()))
+	}
+
+	if err := fs.Rename(f.Name(), name); err != nil {
+		return errors.Join(fmt.Errorf("sevenzip: error renaming: %w", err), fs.Remove(f.Name()))
+	}
+
+	return nil
+}
+
+// writeUpdate writes the updated archive to f on fs and closes it.
+func writeUpdate(fs afero.Fs, f afero.File, src *Reader, u *Update, perm iofs.FileMode) error {
+	if err := ApplyUpdate(src, f, u); err != nil {
+		return errors.Join(err, f.Close())
+	}
+
+	if err := f.Sync(); err != nil {
+		return errors.Join(fmt.Errorf("sevenzip: error syncing: %w", err), f.Close())
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("sevenzip: error closing: %w", err)
+	}
+
+	if err := fs.Chmod(f.Name(), perm); err != nil {
+		return fmt.Errorf("sevenzip: error setting permissions: %w", err)
+	}
+
+	return nil
+}
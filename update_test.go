@@ -0,0 +1,330 @@
+package sevenzip_test
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// coders returns the methods of each file, keyed by name.
+func coders(r *sevenzip.Reader) map[string]string {
+	m := make(map[string]string, len(r.File))
+
+	for _, f := range r.File {
+		var s []string
+		for _, c := range f.Coders() {
+			s = append(s, c.String())
+		}
+
+		m[f.Name] = strings.Join(s, " ")
+	}
+
+	return m
+}
+
+func names(r *sevenzip.Reader) []string {
+	s := make([]string, 0, len(r.File))
+	for _, f := range r.File {
+		s = append(s, f.Name)
+	}
+
+	return s
+}
+
+//nolint:funlen
+func TestApplyUpdate(t *testing.T) {
+	t.Parallel()
+
+	tables := []struct {
+		name     string
+		file     string
+		password string
+		delete   []string
+		replace  map[string]string
+		names    []string
+		blocks   int
+		// Files whose blocks must be copied untouched
+		copied []string
+	}{
+		{
+			name:    "copy",
+			file:    "copy.7z",
+			delete:  []string{"03"},
+			replace: map[string]string{"05": "replaced"},
+			names:   []string{"01", "02", "04", "05", "06", "07", "08", "09", "10"},
+			blocks:  9,
+			copied:  []string{"01", "02", "04", "06", "07", "08", "09", "10"},
+		},
+		{
+			name:   "solid",
+			file:   "lzma2.7z",
+			delete: []string{"05"},
+			names:  []string{"01", "02", "03", "04", "06", "07", "08", "09", "10"},
+			blocks: 1,
+		},
+		{
+			name:    "solid replace",
+			file:    "bcj2.7z",
+			replace: map[string]string{"03": "replaced"},
+			names:   []string{"01", "02", "03", "04", "05", "06", "07", "08", "09", "10"},
+			blocks:  3,
+		},
+		{
+			name:    "empty replacement",
+			file:    "t1.7z",
+			replace: map[string]string{"bar": ""},
+			names:   []string{"bar", "foo"},
+			blocks:  1,
+			copied:  []string{"foo"},
+		},
+		{
+			name:    "replace empty",
+			file:    "file_and_empty.7z",
+			replace: map[string]string{"empty": "no longer empty"},
+			names:   []string{"large", "empty"},
+			blocks:  2,
+			copied:  []string{"large"},
+		},
+		{
+			name:     "encrypted header",
+			file:     "t2.7z",
+			password: "password",
+			delete:   []string{"bar"},
+			names:    []string{"foo"},
+			blocks:   1,
+			copied:   []string{"foo"},
+		},
+		{
+			name:     "encrypted",
+			file:     "aes7z.7z",
+			password: "password",
+			delete:   []string{"01", "10"},
+			names:    []string{"02", "03", "04", "05", "06", "07", "08", "09"},
+			blocks:   8,
+			copied:   []string{"02", "03", "04", "05", "06", "07", "08", "09"},
+		},
+		{
+			name: "nothing",
+			file: "comment.7z",
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			src, err := sevenzip.OpenReaderWithPassword(filepath.Join("testdata", table.file), table.password)
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				require.NoError(t, src.Close())
+			})
+
+			want := readAll(t, &src.Reader)
+			before := coders(&src.Reader)
+
+			u := &sevenzip.Update{Spool: sevenzip.MemorySpool()}
+
+			for _, name := range table.delete {
+				u.Delete(name)
+				delete(want, name)
+			}
+
+			for name, s := range table.replace {
+				u.Replace(name, strings.NewReader(s))
+				want[name] = []byte(s)
+			}
+
+			b := new(bytes.Buffer)
+			require.NoError(t, sevenzip.ApplyUpdate(&src.Reader, b, u))
+
+			dst, err := sevenzip.NewReaderWithPassword(bytes.NewReader(b.Bytes()), int64(b.Len()), table.password)
+			require.NoError(t, err)
+
+			if table.names == nil {
+				table.names = names(&src.Reader)
+			}
+
+			assert.Equal(t, table.names, names(dst))
+			assert.Len(t, dst.Blocks(), table.blocks)
+
+			got := readAll(t, dst)
+			assert.Equal(t, len(want), len(got))
+
+			for name, b := range want {
+				assert.True(t, bytes.Equal(b, got[name]), name)
+			}
+
+			after := coders(dst)
+			for _, name := range table.copied {
+				assert.Equal(t, before[name], after[name], name)
+			}
+
+			srcInfo, err := src.Info()
+			require.NoError(t, err)
+
+			info, err := dst.Info()
+			require.NoError(t, err)
+			assert.Equal(t, srcInfo.Comment, info.Comment)
+			assert.Equal(t, srcInfo.Properties, info.Properties)
+		})
+	}
+}
+
+func TestApplyUpdateErrors(t *testing.T) {
+	t.Parallel()
+
+	tables := []struct {
+		name     string
+		file     string
+		password string
+		opts     []sevenzip.ReaderOption
+		update   func(u *sevenzip.Update)
+		err      error
+	}{
+		{
+			name:   "missing",
+			file:   "copy.7z",
+			update: func(u *sevenzip.Update) { u.Delete("missing") },
+			err:    fs.ErrNotExist,
+		},
+		{
+			name:   "replace missing",
+			file:   "copy.7z",
+			update: func(u *sevenzip.Update) { u.Replace("missing", strings.NewReader("")) },
+			err:    fs.ErrNotExist,
+		},
+		{
+			name:     "encrypted",
+			file:     "t4.7z",
+			password: "password",
+			update:   func(u *sevenzip.Update) { u.Delete("bar") },
+			err:      sevenzip.ErrUpdateEncrypted,
+		},
+		{
+			name:   "filtered",
+			file:   "copy.7z",
+			opts:   []sevenzip.ReaderOption{sevenzip.WithNameFilter(func(string) bool { return true })},
+			update: func(u *sevenzip.Update) { u.Delete("01") },
+			err:    sevenzip.ErrFiltered,
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			opts := append([]sevenzip.ReaderOption{sevenzip.WithPassword(table.password)}, table.opts...)
+
+			r, err := sevenzip.OpenReaderWithOptions(filepath.Join("testdata", table.file), opts...)
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				require.NoError(t, r.Close())
+			})
+
+			u := new(sevenzip.Update)
+			table.update(u)
+
+			err = sevenzip.ApplyUpdate(&r.Reader, io.Discard, u)
+			assert.ErrorIs(t, err, table.err)
+		})
+	}
+}
+
+func TestUpdateFile(t *testing.T) {
+	t.Parallel()
+
+	b, err := os.ReadFile(filepath.Join("testdata", "copy.7z"))
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	name := filepath.Join(dir, "copy.7z")
+	require.NoError(t, os.WriteFile(name, b, 0o600))
+
+	u := new(sevenzip.Update)
+	u.Delete("01")
+	u.Replace("02", strings.NewReader("replaced"))
+
+	require.NoError(t, sevenzip.UpdateFile(name, u))
+
+	r, err := sevenzip.OpenReader(name)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, r.Close())
+	})
+
+	assert.Equal(t, []string{"02", "03", "04", "05", "06", "07", "08", "09", "10"}, names(&r.Reader))
+	assert.Equal(t, []byte("replaced"), readAll(t, &r.Reader)["02"])
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// A failed update leaves the archive untouched
+	before, err := os.ReadFile(name)
+	require.NoError(t, err)
+
+	u = new(sevenzip.Update)
+	u.Delete("missing")
+	require.ErrorIs(t, sevenzip.UpdateFile(name, u), fs.ErrNotExist)
+
+	after, err := os.ReadFile(name)
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+
+	entries, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestUpdateFileVolumes(t *testing.T) {
+	t.Parallel()
+
+	u := new(sevenzip.Update)
+	u.Delete("01")
+
+	err := sevenzip.UpdateFile(filepath.Join("testdata", "multi.7z.001"), u)
+	assert.ErrorIs(t, err, sevenzip.ErrUpdateVolumes)
+}
+
+func TestUpdateFileFs(t *testing.T) {
+	t.Parallel()
+
+	b, err := os.ReadFile(filepath.Join("testdata", "copy.7z"))
+	require.NoError(t, err)
+
+	mfs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(mfs, "archives/copy.7z", b, 0o640))
+
+	u := new(sevenzip.Update)
+	u.Delete("01")
+
+	require.NoError(t, sevenzip.UpdateFile("archives/copy.7z", u, sevenzip.WithFs(mfs)))
+
+	r, err := sevenzip.OpenReaderWithOptions("archives/copy.7z", sevenzip.WithFs(mfs))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, r.Close())
+	})
+
+	assert.Equal(t, []string{"02", "03", "04", "05", "06", "07", "08", "09", "10"}, names(&r.Reader))
+
+	// The temporary file was on mfs and has gone, and the permissions are kept
+	entries, err := afero.ReadDir(mfs, "archives")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, fs.FileMode(0o640), entries[0].Mode().Perm())
+}
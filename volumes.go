@@ -0,0 +1,297 @@
+package sevenzip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	iofs "io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/afero"
+	"go4.org/readerutil"
+)
+
+// ErrVolumeSize is reported by [VerifyVolumes] for a volume that isn't the
+// expected size, such as one that is still downloading.
+var ErrVolumeSize = errors.New("sevenzip: wrong volume size")
+
+const signatureHeaderSize = 32
+
+type volumeOptions struct {
+	fs      afero.Fs
+	newHash func() hash.Hash
+}
+
+// A VolumeOption configures a call to [VerifyVolumes].
+type VolumeOption func(*volumeOptions)
+
+// WithVolumeFs sets the filesystem used to open the volumes. If not
+// specified, the default OS filesystem is used.
+func WithVolumeFs(fs afero.Fs) VolumeOption {
+	return func(o *volumeOptions) {
+		o.fs = fs
+	}
+}
+
+// WithVolumeHash makes [VerifyVolumes] hash the contents of each volume with
+// the hash returned by newHash, such as [crypto/sha256.New], so they can be
+// compared with checksums published alongside the volumes.
+func WithVolumeHash(newHash func() hash.Hash) VolumeOption {
+	return func(o *volumeOptions) {
+		o.newHash = newHash
+	}
+}
+
+// A VolumeStatus is the result of verifying a single volume.
+type VolumeStatus struct {
+	Name string
+	// ExpectedSize is the size the volume should be.
+	ExpectedSize int64
+	// Size is the actual size of the volume, or -1 if it's missing.
+	Size int64
+	// Hash is the hash of the volume if [WithVolumeHash] was used.
+	Hash []byte
+	// Err is nil if the volume is good, otherwise it describes the
+	// problem and the volume should be fetched again.
+	Err error
+}
+
+// OK returns true if the volume is present and correct.
+func (v VolumeStatus) OK() bool {
+	return v.Err == nil
+}
+
+// volumeName returns the name of volume n, counting from one, of the archive
+// whose first volume is name.
+func volumeName(name string, n int) string {
+	return fmt.Sprintf("%s.%03d", strings.TrimSuffix(name, filepath.Ext(name)), n)
+}
+
+// readStartHeader reads and validates the headers at the start of an
+// archive.
+func readStartHeader(r io.Reader) (*startHeader, error) {
+	var sh signatureHeader
+	if err := binary.Read(r, binary.LittleEndian, &sh); err != nil {
+		return nil, fmt.Errorf("sevenzip: error reading signature header: %w", err)
+	}
+
+	if !bytes.Equal(sh.Signature[:], []byte{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c}) {
+		return nil, errFormat
+	}
+
+	b := make([]byte, binary.Size(startHeader{}))
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, fmt.Errorf("sevenzip: error reading start header: %w", err)
+	}
+
+	if crc32.ChecksumIEEE(b) != sh.CRC {
+		return nil, errChecksum
+	}
+
+	start := new(startHeader)
+	_ = binary.Read(bytes.NewReader(b), binary.LittleEndian, start)
+
+	return start, nil
+}
+
+// VerifyVolumes checks the volumes of the archive whose first volume is name
+// without extracting anything, so a download manager can confirm each part
+// and only fetch the bad ones again. If name doesn't have a ".001" suffix it
+// is treated as a single volume.
+//
+// The expected size of every volume is worked out from the size of the
+// first volume and the total size of the archive recorded in its start
+// header, so the first volume must be complete; an error is returned if it
+// can't be read. Missing volumes are reported with an error wrapping
+// [fs.ErrNotExist], volumes of the wrong size with [ErrVolumeSize], and if
+// all of the volumes holding the header are present their CRC is checked
+// against the one in the start header.
+//
+//nolint:cyclop,funlen
+func VerifyVolumes(name string, opts ...VolumeOption) ([]VolumeStatus, error) {
+	o := new(volumeOptions)
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.fs == nil {
+		o.fs = afero.NewOsFs()
+	}
+
+	f, err := o.fs.Open(filepath.Clean(name))
+	if err != nil {
+		return nil, fmt.Errorf("sevenzip: error opening: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("sevenzip: error retrieving file info: %w", err), f.Close())
+	}
+
+	start, err := readStartHeader(f)
+	if err = errors.Join(err, f.Close()); err != nil {
+		return nil, err
+	}
+
+	total := signatureHeaderSize + int64(start.Offset) + int64(start.Size) //nolint:gosec
+	first := info.Size()
+
+	volumes := []VolumeStatus{{Name: name, ExpectedSize: total}}
+
+	if filepath.Ext(name) == ".001" && total > first {
+		n := int((total + first - 1) / first)
+		volumes = make([]VolumeStatus, n)
+
+		for i := range volumes {
+			volumes[i] = VolumeStatus{
+				Name:         volumeName(name, i+1),
+				ExpectedSize: min(first, total-int64(i)*first),
+			}
+		}
+	}
+
+	ok := true
+
+	for i := range volumes {
+		if err := verifyVolume(o, &volumes[i]); err != nil {
+			return nil, err
+		}
+
+		ok = ok && volumes[i].OK()
+	}
+
+	// The header is at the end so is spread across the last volumes, if
+	// they're all present its CRC can be checked
+	if ok {
+		if err := verifyHeader(o.fs, volumes, start); err != nil {
+			return nil, err
+		}
+	}
+
+	return volumes, nil
+}
+
+func verifyHeader(fs afero.Fs, volumes []VolumeStatus, start *startHeader) (err error) {
+	files := make([]afero.File, 0, len(volumes))
+	sr := make([]readerutil.SizeReaderAt, 0, len(volumes))
+
+	defer func() {
+		for _, f := range files {
+			err = errors.Join(err, f.Close())
+		}
+	}()
+
+	for _, v := range volumes {
+		f, oerr := fs.Open(v.Name)
+		if oerr != nil {
+			return fmt.Errorf("sevenzip: error opening: %w", oerr)
+		}
+
+		files = append(files, f)
+		sr = append(sr, io.NewSectionReader(f, 0, v.Size))
+	}
+
+	offset := signatureHeaderSize + int64(start.Offset)                                     //nolint:gosec
+	r := io.NewSectionReader(readerutil.NewMultiReaderAt(sr...), offset, int64(start.Size)) //nolint:gosec
+
+	h := crc32.NewIEEE()
+	if _, err := io.Copy(h, r); err != nil {
+		return fmt.Errorf("sevenzip: error reading header: %w", err)
+	}
+
+	if h.Sum32() == start.CRC {
+		return nil
+	}
+
+	// Blame every volume holding part of the header
+	var end int64
+
+	for i := range volumes {
+		end += volumes[i].Size
+		if end > offset {
+			volumes[i].Err = errChecksum
+		}
+	}
+
+	return nil
+}
+
+func verifyVolume(o *volumeOptions, v *VolumeStatus) error {
+	v.Size = -1
+
+	info, err := o.fs.Stat(v.Name)
+	if err != nil {
+		if errors.Is(err, iofs.ErrNotExist) {
+			v.Err = fmt.Errorf("sevenzip: missing volume: %w", err)
+
+			return nil
+		}
+
+		return fmt.Errorf("sevenzip: error retrieving file info: %w", err)
+	}
+
+	v.Size = info.Size()
+
+	if v.Size != v.ExpectedSize {
+		v.Err = ErrVolumeSize
+	}
+
+	if o.newHash == nil {
+		return nil
+	}
+
+	f, err := o.fs.Open(v.Name)
+	if err != nil {
+		return fmt.Errorf("sevenzip: error opening: %w", err)
+	}
+
+	h := o.newHash()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return errors.Join(fmt.Errorf("sevenzip: error hashing %s: %w", v.Name, err), f.Close())
+	}
+
+	v.Hash = h.Sum(nil)
+
+	return f.Close() //nolint:wrapcheck
+}
+
+// NewConcatenatedReader returns a new [*Reader] reading a multi-volume
+// archive from r, which holds every volume joined together in order, for
+// storage that presents the volumes as a single object. size is the total
+// size of r and volumeSizes the size of each volume, which must add up to
+// size, otherwise an error wrapping [ErrVolumeSize] is returned.
+//
+// Offsets such as those returned by [File.DataOffset] and
+// [Reader.ListFilesWithOffsets] are relative to the start of r, the volume
+// sizes are used to also work out which volume they fall in.
+func NewConcatenatedReader(r io.ReaderAt, size int64, volumeSizes []int64, opts ...ReaderOption) (*Reader, error) {
+	var total int64
+
+	for i, s := range volumeSizes {
+		if s <= 0 {
+			return nil, fmt.Errorf("%w: volume %d is %d bytes", ErrVolumeSize, i+1, s)
+		}
+
+		total += s
+	}
+
+	if total != size {
+		return nil, fmt.Errorf("%w: volumes total %d bytes, expected %d", ErrVolumeSize, total, size)
+	}
+
+	zr, err := NewReaderWithOptions(r, size, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	zr.volumes = slices.Clone(volumeSizes)
+
+	return zr, nil
+}
@@ -0,0 +1,321 @@
+package sevenzip_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// multiVolumeFs returns a filesystem holding copies of the multi.7z volumes,
+// with fn applied to the contents of each to simulate problems. Volumes for
+// which fn returns nil aren't created.
+func multiVolumeFs(t *testing.T, fn func(n int, b []byte) []byte) afero.Fs {
+	t.Helper()
+
+	fs := afero.NewMemMapFs()
+
+	for n := 1; n <= 6; n++ {
+		name := fmt.Sprintf("multi.7z.%03d", n)
+
+		b, err := os.ReadFile(filepath.Join("testdata", name))
+		require.NoError(t, err)
+
+		if b = fn(n, b); b != nil {
+			require.NoError(t, afero.WriteFile(fs, name, b, 0o644))
+		}
+	}
+
+	return fs
+}
+
+func TestVerifyVolumes(t *testing.T) {
+	t.Parallel()
+
+	tables := []struct {
+		name string
+		fn   func(int, []byte) []byte
+		errs map[int]error
+	}{
+		{
+			name: "good",
+			fn: func(_ int, b []byte) []byte {
+				return b
+			},
+		},
+		{
+			name: "missing",
+			fn: func(n int, b []byte) []byte {
+				if n == 3 {
+					return nil
+				}
+
+				return b
+			},
+			errs: map[int]error{3: iofs.ErrNotExist},
+		},
+		{
+			name: "still downloading",
+			fn: func(n int, b []byte) []byte {
+				if n == 5 {
+					return b[:100]
+				}
+
+				return b
+			},
+			errs: map[int]error{5: sevenzip.ErrVolumeSize},
+		},
+		{
+			name: "corrupt header",
+			fn: func(n int, b []byte) []byte {
+				if n == 6 {
+					b[len(b)-2] ^= 0xff
+				}
+
+				return b
+			},
+			errs: map[int]error{6: sevenzip.ErrChecksum},
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := multiVolumeFs(t, table.fn)
+
+			volumes, err := sevenzip.VerifyVolumes("multi.7z.001", sevenzip.WithVolumeFs(fs))
+			require.NoError(t, err)
+			require.Len(t, volumes, 6)
+
+			for i, v := range volumes {
+				assert.Equal(t, fmt.Sprintf("multi.7z.%03d", i+1), v.Name)
+
+				if i < 5 {
+					assert.Equal(t, int64(1024), v.ExpectedSize)
+				} else {
+					assert.Equal(t, int64(990), v.ExpectedSize)
+				}
+
+				if err, ok := table.errs[i+1]; ok {
+					assert.ErrorIs(t, v.Err, err, v.Name)
+					assert.False(t, v.OK())
+				} else {
+					assert.NoError(t, v.Err, v.Name)
+					assert.Equal(t, v.ExpectedSize, v.Size)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyVolumesHash(t *testing.T) {
+	t.Parallel()
+
+	volumes, err := sevenzip.VerifyVolumes(filepath.Join("testdata", "multi.7z.001"), sevenzip.WithVolumeHash(sha256.New))
+	require.NoError(t, err)
+
+	for _, v := range volumes {
+		b, err := os.ReadFile(v.Name)
+		require.NoError(t, err)
+
+		sum := sha256.Sum256(b)
+		assert.Equal(t, sum[:], v.Hash, v.Name)
+	}
+}
+
+func TestVerifyVolumesSingle(t *testing.T) {
+	t.Parallel()
+
+	volumes, err := sevenzip.VerifyVolumes(filepath.Join("testdata", "t0.7z"))
+	require.NoError(t, err)
+	require.Len(t, volumes, 1)
+	assert.True(t, volumes[0].OK())
+
+	_, err = sevenzip.VerifyVolumes(filepath.Join("testdata", "sfx.exe"))
+	assert.ErrorIs(t, err, sevenzip.ErrFormat)
+}
+
+func TestNewConcatenatedReader(t *testing.T) {
+	t.Parallel()
+
+	var (
+		archive []byte
+		sizes   []int64
+	)
+
+	for n := 1; n <= 6; n++ {
+		b, err := os.ReadFile(filepath.Join("testdata", fmt.Sprintf("multi.7z.%03d", n)))
+		require.NoError(t, err)
+
+		archive = append(archive, b...)
+		sizes = append(sizes, int64(len(b)))
+	}
+
+	r, err := sevenzip.NewConcatenatedReader(bytes.NewReader(archive), int64(len(archive)), sizes)
+	require.NoError(t, err)
+
+	want, err := sevenzip.OpenReader(filepath.Join("testdata", "multi.7z.001"))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, want.Close())
+	})
+
+	require.Len(t, r.File, len(want.File))
+
+	for i, f := range r.File {
+		assert.Equal(t, want.File[i].FileHeader, f.FileHeader)
+	}
+
+	assert.NoError(t, r.ExtractAll(context.Background(), t.TempDir(), sevenzip.WithAtomicWrites()))
+
+	_, err = sevenzip.NewConcatenatedReader(bytes.NewReader(archive), int64(len(archive)), sizes[1:])
+	assert.ErrorIs(t, err, sevenzip.ErrVolumeSize)
+
+	_, err = sevenzip.NewConcatenatedReader(bytes.NewReader(archive), int64(len(archive)), append([]int64{0}, sizes...))
+	assert.ErrorIs(t, err, sevenzip.ErrVolumeSize)
+}
+
+func TestNewConcatenatedReaderDataOffset(t *testing.T) {
+	t.Parallel()
+
+	archive, err := os.ReadFile(filepath.Join("testdata", "copy.7z"))
+	require.NoError(t, err)
+
+	const volumeSize = 4096
+
+	var sizes []int64
+	for i := 0; i < len(archive); i += volumeSize {
+		sizes = append(sizes, int64(min(volumeSize, len(archive)-i)))
+	}
+
+	r, err := sevenzip.NewConcatenatedReader(bytes.NewReader(archive), int64(len(archive)), sizes)
+	require.NoError(t, err)
+
+	var checked int
+
+	for _, f := range r.File {
+		if !f.HasStream() {
+			continue
+		}
+
+		offset, volume, err := f.DataOffset()
+		require.NoError(t, err)
+		assert.Equal(t, int(offset/volumeSize), volume, f.Name)
+
+		checked++
+	}
+
+	assert.NotZero(t, checked)
+}
+
+func TestWithVolumeNamer(t *testing.T) {
+	t.Parallel()
+
+	tables := []struct {
+		name  string
+		first string
+		namer func(string, int) string
+	}{
+		{
+			name:  "four digits",
+			first: "multi.7z.0001",
+			namer: func(base string, index int) string {
+				return fmt.Sprintf("%s.%04d", strings.TrimSuffix(base, ".0001"), index)
+			},
+		},
+		{
+			name:  "parts",
+			first: "multi.part01.7z",
+			namer: func(base string, index int) string {
+				return fmt.Sprintf("%s.part%02d.7z", strings.TrimSuffix(base, ".part01.7z"), index)
+			},
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := afero.NewMemMapFs()
+
+			for n := 1; n <= 6; n++ {
+				b, err := os.ReadFile(filepath.Join("testdata", fmt.Sprintf("multi.7z.%03d", n)))
+				require.NoError(t, err)
+
+				name := table.first
+				if n > 1 {
+					name = table.namer(table.first, n)
+				}
+
+				require.NoError(t, afero.WriteFile(fs, name, b, 0o644))
+			}
+
+			// Only the first volume is found without the namer
+			_, err := sevenzip.OpenReader(table.first, fs)
+			require.ErrorIs(t, err, sevenzip.ErrTruncated)
+
+			r, err := sevenzip.OpenReaderWithOptions(table.first, sevenzip.WithFs(fs), sevenzip.WithVolumeNamer(table.namer))
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				require.NoError(t, r.Close())
+			})
+
+			assert.Len(t, r.Volumes(), 6)
+			assert.NoError(t, r.ExtractAll(context.Background(), t.TempDir(), sevenzip.WithAtomicWrites()))
+
+			c, err := r.Clone()
+			require.NoError(t, err)
+			assert.Len(t, c.Volumes(), 6)
+			require.NoError(t, c.Close())
+		})
+	}
+}
+
+func TestOpenReaderVolumeDiscovery(t *testing.T) {
+	t.Parallel()
+
+	// A stale volume left over from another archive is ignored
+	fs := multiVolumeFs(t, func(_ int, b []byte) []byte { return b })
+	require.NoError(t, afero.WriteFile(fs, "multi.7z.007", []byte("junk"), 0o644))
+
+	r, err := sevenzip.OpenReader("multi.7z.001", fs)
+	require.NoError(t, err)
+	assert.Len(t, r.Volumes(), 6)
+	require.NoError(t, r.Close())
+
+	fs = multiVolumeFs(t, func(n int, b []byte) []byte {
+		if n == 6 {
+			return nil
+		}
+
+		return b
+	})
+
+	_, err = sevenzip.OpenReader("multi.7z.001", fs)
+	assert.ErrorIs(t, err, sevenzip.ErrTruncated)
+	assert.ErrorContains(t, err, "multi.7z.006")
+
+	fs = multiVolumeFs(t, func(n int, b []byte) []byte {
+		if n == 6 {
+			return append(b, 0)
+		}
+
+		return b
+	})
+
+	_, err = sevenzip.OpenReader("multi.7z.001", fs)
+	assert.ErrorIs(t, err, sevenzip.ErrVolumeSize)
+}
@@ -0,0 +1,246 @@
+package sevenzip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// A VolumeSource provides the volumes of a multi-volume archive that may
+// still be arriving, for example from a download manager, so that
+// extraction can start before every volume is complete. Volumes are
+// numbered from zero.
+type VolumeSource interface {
+	// Sizes returns the final size of each volume.
+	Sizes() []int64
+	// Wait blocks until at least the first n bytes of volume i are
+	// available or ctx is done.
+	Wait(ctx context.Context, i int, n int64) error
+	// Open returns a reader for volume i. It is only called once Wait
+	// has returned for the volume and the reader is reused for every
+	// later read, so it must be able to read data that arrives after it
+	// was opened.
+	Open(i int) (io.ReaderAt, error)
+}
+
+// NewReaderFromVolumeSource returns a new [*Reader] reading the archive
+// provided by src. Reads of data that hasn't arrived yet wait for it, until
+// ctx is done, after which they fail with the error from ctx. As the header
+// is stored at the end of the archive this doesn't return until the first
+// and last volumes are available, so they should be fetched first.
+//
+// The reader doesn't close any readers returned by src, a [*FileVolumeSource]
+// should be closed once the reader is no longer needed.
+func NewReaderFromVolumeSource(ctx context.Context, src VolumeSource, opts ...ReaderOption) (*Reader, error) {
+	r := newVolumeSourceReaderAt(ctx, src)
+
+	// Searching for the signature would read ahead into later volumes
+	opts = append([]ReaderOption{WithBaseOffset(0)}, opts...)
+
+	zr, err := NewReaderWithOptions(r, r.size, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	zr.volumes = r.sizes
+
+	return zr, nil
+}
+
+type volumeSourceReaderAt struct {
+	ctx     context.Context //nolint:containedctx
+	src     VolumeSource
+	sizes   []int64
+	offsets []int64
+	size    int64
+
+	mu      sync.Mutex
+	readers []io.ReaderAt
+}
+
+func newVolumeSourceReaderAt(ctx context.Context, src VolumeSource) *volumeSourceReaderAt {
+	r := &volumeSourceReaderAt{
+		ctx:   ctx,
+		src:   src,
+		sizes: src.Sizes(),
+	}
+
+	r.offsets = make([]int64, len(r.sizes))
+	r.readers = make([]io.ReaderAt, len(r.sizes))
+
+	for i, size := range r.sizes {
+		r.offsets[i] = r.size
+		r.size += size
+	}
+
+	return r
+}
+
+func (r *volumeSourceReaderAt) reader(i int) (io.ReaderAt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.readers[i] == nil {
+		ra, err := r.src.Open(i)
+		if err != nil {
+			return nil, fmt.Errorf("sevenzip: error opening volume %d: %w", i, err)
+		}
+
+		r.readers[i] = ra
+	}
+
+	return r.readers[i], nil
+}
+
+func (r *volumeSourceReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, iofs.ErrInvalid
+	}
+
+	for len(p) > 0 {
+		// Find the volume holding off
+		i := sort.Search(len(r.offsets), func(i int) bool {
+			return r.offsets[i]+r.sizes[i] > off
+		})
+		if i == len(r.offsets) {
+			return n, io.EOF
+		}
+
+		rel := off - r.offsets[i]
+		m := min(int64(len(p)), r.sizes[i]-rel)
+
+		if err := r.src.Wait(r.ctx, i, rel+m); err != nil {
+			return n, err //nolint:wrapcheck
+		}
+
+		ra, err := r.reader(i)
+		if err != nil {
+			return n, err
+		}
+
+		k, err := ra.ReadAt(p[:m], rel)
+		n += k
+
+		if int64(k) < m {
+			if err == nil || errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+
+			return n, err //nolint:wrapcheck
+		}
+
+		p, off = p[m:], off+m
+	}
+
+	return n, nil
+}
+
+// defaultVolumeInterval is how often a [FileVolumeSource] checks volumes for
+// new data if no interval is given.
+const defaultVolumeInterval = 100 * time.Millisecond
+
+// FileVolumeSource is a [VolumeSource] reading volumes from a filesystem
+// while they are still being written, such as by a download manager that
+// writes each volume in place.
+type FileVolumeSource struct {
+	fs       afero.Fs
+	names    []string
+	sizes    []int64
+	interval time.Duration
+
+	mu    sync.Mutex
+	files []afero.File
+}
+
+// NewFileVolumeSource returns a [*FileVolumeSource] for the archive whose
+// first volume is name, such as "archive.7z.001", with each subsequent
+// volume having the same name with the number incremented. The final size of
+// each volume must be given as they can't be known before they've arrived.
+// Volumes are checked for new data every interval, or every 100ms if it's
+// not positive. If fs is nil the default OS filesystem is used.
+func NewFileVolumeSource(fs afero.Fs, name string, sizes []int64, interval time.Duration) *FileVolumeSource {
+	if fs == nil {
+		fs = afero.NewOsFs()
+	}
+
+	if interval <= 0 {
+		interval = defaultVolumeInterval
+	}
+
+	names := make([]string, len(sizes))
+	for i := range names {
+		names[i] = volumeName(name, i+1)
+	}
+
+	return &FileVolumeSource{
+		fs:       fs,
+		names:    names,
+		sizes:    sizes,
+		interval: interval,
+	}
+}
+
+// Sizes returns the final size of each volume.
+func (s *FileVolumeSource) Sizes() []int64 {
+	return s.sizes
+}
+
+// Wait polls volume i until it exists and is at least n bytes long.
+func (s *FileVolumeSource) Wait(ctx context.Context, i int, n int64) error {
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+
+	for {
+		info, err := s.fs.Stat(s.names[i])
+
+		switch {
+		case err == nil && info.Size() >= n:
+			return nil
+		case err != nil && !errors.Is(err, iofs.ErrNotExist):
+			return fmt.Errorf("sevenzip: error retrieving file info: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err() //nolint:wrapcheck
+		case <-t.C:
+		}
+	}
+}
+
+// Open opens volume i.
+func (s *FileVolumeSource) Open(i int) (io.ReaderAt, error) {
+	f, err := s.fs.Open(s.names[i])
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.files = append(s.files, f)
+
+	return concurrentReaderAt(f), nil
+}
+
+// Close closes any volumes that have been opened.
+func (s *FileVolumeSource) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	errs := make([]error, 0, len(s.files))
+	for _, f := range s.files {
+		errs = append(errs, f.Close())
+	}
+
+	s.files = nil
+
+	return errors.Join(errs...)
+}
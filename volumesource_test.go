@@ -0,0 +1,132 @@
+package sevenzip_test
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/javi11/sevenzip"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewReaderFromVolumeSource(t *testing.T) {
+	t.Parallel()
+
+	var (
+		volumes = make([][]byte, 6)
+		sizes   = make([]int64, len(volumes))
+	)
+
+	for i := range volumes {
+		b, err := os.ReadFile(filepath.Join("testdata", fmt.Sprintf("multi.7z.%03d", i+1)))
+		require.NoError(t, err)
+
+		volumes[i], sizes[i] = b, int64(len(b))
+	}
+
+	// Only the first and last volumes have arrived, and the second is
+	// halfway through downloading
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "multi.7z.001", volumes[0], 0o644))
+	require.NoError(t, afero.WriteFile(fs, "multi.7z.002", volumes[1][:512], 0o644))
+	require.NoError(t, afero.WriteFile(fs, "multi.7z.006", volumes[5], 0o644))
+
+	src := sevenzip.NewFileVolumeSource(fs, "multi.7z.001", sizes, time.Millisecond)
+
+	defer func() {
+		require.NoError(t, src.Close())
+	}()
+
+	r, err := sevenzip.NewReaderFromVolumeSource(context.Background(), src)
+	require.NoError(t, err)
+
+	type result struct {
+		crc uint32
+		err error
+	}
+
+	done := make(chan result, 1)
+
+	go func() {
+		f := r.File[len(r.File)-1]
+
+		rc, err := f.Open()
+		if err != nil {
+			done <- result{err: err}
+
+			return
+		}
+		defer rc.Close()
+
+		h := crc32.NewIEEE()
+		_, err = io.Copy(h, rc)
+		done <- result{h.Sum32(), err}
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("read finished before the volumes arrived")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	for i := 1; i < 5; i++ {
+		require.NoError(t, afero.WriteFile(fs, fmt.Sprintf("multi.7z.%03d", i+1), volumes[i], 0o644))
+	}
+
+	res := <-done
+	require.NoError(t, res.err)
+	assert.Equal(t, r.File[len(r.File)-1].CRC32, res.crc)
+}
+
+func TestNewReaderFromVolumeSourceCancel(t *testing.T) {
+	t.Parallel()
+
+	b, err := os.ReadFile(filepath.Join("testdata", "multi.7z.001"))
+	require.NoError(t, err)
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "multi.7z.001", b, 0o644))
+
+	src := sevenzip.NewFileVolumeSource(fs, "multi.7z.001", []int64{1024, 1024, 1024, 1024, 1024, 990}, time.Millisecond)
+
+	defer func() {
+		require.NoError(t, src.Close())
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// The header is in the last volume which never arrives
+	_, err = sevenzip.NewReaderFromVolumeSource(ctx, src)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestFileVolumeSourceDefaultInterval(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+
+	for _, interval := range []time.Duration{0, -time.Second} {
+		src := sevenzip.NewFileVolumeSource(fs, "multi.7z.001", []int64{1024}, interval)
+
+		// Arrives after the first check so the ticker is needed
+		time.AfterFunc(10*time.Millisecond, func() {
+			_ = afero.WriteFile(fs, "multi.7z.001", make([]byte, 1024), 0o644)
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+		assert.NoError(t, src.Wait(ctx, 0, 1024), interval)
+		assert.NoError(t, src.Close())
+
+		cancel()
+		require.NoError(t, fs.Remove("multi.7z.001"))
+	}
+}
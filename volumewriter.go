@@ -0,0 +1,141 @@
+package sevenzip
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+var (
+	errVolumeName     = errors.New("sevenzip: first volume name must have a .001 suffix")
+	errVolumeTooSmall = errors.New("sevenzip: volume size too small")
+	errSeekOffset     = errors.New("sevenzip: invalid seek offset")
+)
+
+// CreateVolumes creates a new archive split into volumes of size bytes,
+// apart from the last which holds whatever is left, and returns a
+// [*WriteCloser] to add files to it as with [NewWriter]. The name of the
+// first volume must have a ".001" suffix and further volumes are named
+// ".002", ".003" and so on, the same as [OpenReader] expects. Volumes are
+// only created once there is something to write to them so there is never
+// an empty volume at the end, and any existing volumes with the same names
+// are overwritten.
+func CreateVolumes(name string, size int64, opts ...WriterOption) (*WriteCloser, error) {
+	if filepath.Ext(name) != ".001" {
+		return nil, errVolumeName
+	}
+
+	// The start header has to fit in the first volume
+	if size < signatureHeaderSize {
+		return nil, fmt.Errorf("%w: %d bytes, the minimum is %d", errVolumeTooSmall, size, signatureHeaderSize)
+	}
+
+	vw := &volumeWriter{name: name, size: size}
+
+	// Create the first volume now so that any problem creating it is
+	// reported straight away
+	if _, err := vw.volume(0); err != nil {
+		return nil, err
+	}
+
+	wc := &WriteCloser{c: vw}
+	wc.w = vw
+
+	for _, opt := range opts {
+		opt(&wc.opts)
+	}
+
+	return wc, nil
+}
+
+// volumeWriter is an [io.WriteSeeker] spreading everything written over
+// volumes of a fixed size.
+type volumeWriter struct {
+	name   string
+	size   int64
+	files  []*os.File
+	offset int64
+	end    int64
+}
+
+// volume returns volume i, counting from zero, creating it and any before
+// it if necessary.
+func (vw *volumeWriter) volume(i int) (*os.File, error) {
+	for n := len(vw.files); n <= i; n++ {
+		name := vw.name
+		if n > 0 {
+			name = volumeName(vw.name, n+1)
+		}
+
+		f, err := os.Create(name)
+		if err != nil {
+			return nil, fmt.Errorf("sevenzip: error creating volume: %w", err)
+		}
+
+		vw.files = append(vw.files, f)
+	}
+
+	return vw.files[i], nil
+}
+
+func (vw *volumeWriter) Write(p []byte) (int, error) {
+	var written int
+
+	for len(p) > 0 {
+		f, err := vw.volume(int(vw.offset / vw.size))
+		if err != nil {
+			return written, err
+		}
+
+		off := vw.offset % vw.size
+
+		n, err := f.WriteAt(p[:min(int64(len(p)), vw.size-off)], off)
+		written += n
+		vw.offset += int64(n)
+		vw.end = max(vw.end, vw.offset)
+
+		if err != nil {
+			return written, fmt.Errorf("sevenzip: error writing volume: %w", err)
+		}
+
+		p = p[n:]
+	}
+
+	return written, nil
+}
+
+func (vw *volumeWriter) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += vw.offset
+	case io.SeekEnd:
+		offset += vw.end
+	default:
+		return 0, errSeekOffset
+	}
+
+	if offset < 0 {
+		return 0, errSeekOffset
+	}
+
+	vw.offset = offset
+
+	return offset, nil
+}
+
+func (vw *volumeWriter) Close() error {
+	errs := make([]error, 0, len(vw.files))
+
+	for _, f := range vw.files {
+		errs = append(errs, f.Close())
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("sevenzip: error closing volume: %w", err)
+	}
+
+	return nil
+}
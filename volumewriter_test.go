@@ -0,0 +1,165 @@
+package sevenzip_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFiles adds the same few files to w and returns their contents.
+func writeFiles(t *testing.T, w *sevenzip.Writer) map[string][]byte {
+	t.Helper()
+
+	files := map[string][]byte{
+		"a.txt":     bytes.Repeat([]byte("a"), 3000),
+		"dir/b.txt": []byte(strings.Repeat("hello, world\n", 200)),
+		"empty.txt": {},
+	}
+
+	for _, name := range []string{"a.txt", "dir/b.txt", "empty.txt"} {
+		fw, err := w.CreateHeader(&sevenzip.FileHeader{
+			Name:     name,
+			Modified: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+		})
+		require.NoError(t, err)
+
+		_, err = fw.Write(files[name])
+		require.NoError(t, err)
+	}
+
+	return files
+}
+
+//nolint:funlen
+func TestCreateVolumes(t *testing.T) {
+	t.Parallel()
+
+	// Write the archive in one piece first to know how big it is
+	b := new(bytes.Buffer)
+	w := sevenzip.NewWriter(b, sevenzip.WithStore())
+	writeFiles(t, w)
+	require.NoError(t, w.Close())
+
+	total := int64(b.Len())
+
+	tables := []struct {
+		name    string
+		size    int64
+		volumes int
+	}{
+		{
+			name:    "split",
+			size:    1000,
+			volumes: int((total + 999) / 1000),
+		},
+		{
+			name:    "exact",
+			size:    total,
+			volumes: 1,
+		},
+		{
+			name:    "larger",
+			size:    total + 1,
+			volumes: 1,
+		},
+		{
+			name:    "smallest",
+			size:    32,
+			volumes: int((total + 31) / 32),
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			name := filepath.Join(dir, "archive.7z.001")
+
+			w, err := sevenzip.CreateVolumes(name, table.size, sevenzip.WithStore())
+			require.NoError(t, err)
+
+			want := writeFiles(t, &w.Writer)
+			require.NoError(t, w.Close())
+
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			assert.Len(t, entries, table.volumes)
+
+			// Joined together the volumes are the same as the archive
+			// written in one piece
+			joined := new(bytes.Buffer)
+
+			for i, entry := range entries {
+				info, err := entry.Info()
+				require.NoError(t, err)
+
+				if i < len(entries)-1 {
+					assert.Equal(t, table.size, info.Size(), entry.Name())
+				}
+
+				v, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+				require.NoError(t, err)
+
+				joined.Write(v)
+			}
+
+			assert.Equal(t, b.Bytes(), joined.Bytes())
+
+			r, err := sevenzip.OpenReader(name)
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				require.NoError(t, r.Close())
+			})
+
+			assert.Len(t, r.Volumes(), table.volumes)
+
+			for _, f := range r.File {
+				rc, err := f.Open()
+				require.NoError(t, err)
+
+				got, err := io.ReadAll(rc)
+				require.NoError(t, err)
+				require.NoError(t, rc.Close())
+
+				assert.Equal(t, want[f.Name], got, f.Name)
+			}
+
+			statuses, err := sevenzip.VerifyVolumes(name)
+			require.NoError(t, err)
+			assert.Len(t, statuses, table.volumes)
+
+			for _, s := range statuses {
+				assert.True(t, s.OK(), s.Name)
+			}
+		})
+	}
+}
+
+func TestCreateVolumesErrors(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	_, err := sevenzip.CreateVolumes(filepath.Join(dir, "archive.7z"), 1000)
+	assert.ErrorIs(t, err, sevenzip.ErrVolumeName)
+
+	_, err = sevenzip.CreateVolumes(filepath.Join(dir, "archive.7z.001"), 31)
+	assert.ErrorIs(t, err, sevenzip.ErrVolumeTooSmall)
+
+	_, err = sevenzip.CreateVolumes(filepath.Join(dir, "missing", "archive.7z.001"), 1000)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -0,0 +1,1022 @@
+package sevenzip
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	iofs "io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bodgit/plumbing"
+	"github.com/javi11/sevenzip/internal/aes7z"
+	"github.com/javi11/sevenzip/internal/lzma2"
+)
+
+var (
+	errWriterClosed   = errors.New("sevenzip: writer closed")
+	errWriteDirectory = errors.New("sevenzip: write to directory")
+	errEmptyName      = errors.New("sevenzip: empty name")
+	errHeaderPassword = errors.New("sevenzip: encrypted header without a password")
+	errNoBlock        = errors.New("sevenzip: no such block")
+	errNotRegular     = errors.New("sevenzip: not a regular file or directory")
+	errWriteMethod    = errors.New("sevenzip: method can't be used for writing")
+)
+
+// defaultDictSize is the LZMA2 dictionary size used for each file, the same
+// as the default used by 7-zip.
+const defaultDictSize = 1 << 24
+
+// The PPMd model order and memory size used for each file, the same as the
+// defaults used by 7-zip.
+const (
+	defaultPPMdOrder   = 6
+	defaultPPMdMemSize = 1 << 24
+)
+
+// newPPMdWriter returns a PPMd compressor, only set where PPMd can also be
+// read.
+//
+//nolint:gochecknoglobals
+var newPPMdWriter func(w io.Writer, order int, memSize uint32) (io.WriteCloser, []byte, error)
+
+type writerOptions struct {
+	store         bool
+	spool         SpoolFunc
+	password      string
+	encryptHeader bool
+	blockSize     uint64
+	blockFiles    int
+	byExtension   bool
+	keepHeader    bool
+	reproducible  bool
+	modTime       time.Time
+	rules         []MethodRule
+	filters       bool
+	progress      func(WriteProgress)
+}
+
+// solid returns whether files can share a block.
+func (o *writerOptions) solid() bool {
+	return o.blockSize > 0 || o.blockFiles > 0 || o.byExtension
+}
+
+// stamp sets the times of fh as required by [WithReproducible].
+func (o *writerOptions) stamp(fh *FileHeader) {
+	if o.reproducible {
+		fh.Created, fh.Accessed, fh.Modified = time.Time{}, time.Time{}, o.modTime
+	}
+}
+
+// check returns an error if the options can't be used together.
+func (o *writerOptions) check() error {
+	if o.encryptHeader && o.password == "" {
+		return errHeaderPassword
+	}
+
+	return nil
+}
+
+// A WriterOption configures a [Writer].
+type WriterOption func(*writerOptions)
+
+// WithStore makes the [Writer] store the contents of each file as is with
+// the Copy method rather than compressing them with LZMA2, so they can be
+// read directly using [File.DataOffset]. Use [WithMethodRules] to only store
+// some files.
+func WithStore() WriterOption {
+	return func(o *writerOptions) {
+		o.store = true
+	}
+}
+
+// WithWriterSpool sets where the [Writer] holds the contents of each file
+// until the archive is closed when the underlying writer can't seek. By
+// default they are held in memory, see [MemorySpool].
+func WithWriterSpool(fn SpoolFunc) WriterOption {
+	return func(o *writerOptions) {
+		o.spool = fn
+	}
+}
+
+// WithWriterPassword makes the [Writer] encrypt the contents of each file with
+// AES-256 using a key derived from password in the same way as 7-zip, so
+// the archive can be read with [OpenReaderWithPassword] or by 7-zip itself.
+// Only the contents are encrypted, the names and other details of the files
+// can still be read without the password unless [WithEncryptedHeader] is
+// also used.
+func WithWriterPassword(password string) WriterOption {
+	return func(o *writerOptions) {
+		o.password = password
+	}
+}
+
+// WithEncryptedHeader makes the [Writer] also encrypt the header of the
+// archive with the password set with [WithWriterPassword], the same as the
+// -mhe=on switch of 7-zip, so not even the names of the files can be read
+// without it. The Writer fails if there isn't a password. When appending to
+// an archive whose header is already encrypted it stays encrypted.
+func WithEncryptedHeader() WriterOption {
+	return func(o *writerOptions) {
+		o.encryptHeader = true
+	}
+}
+
+// WithKeepOldHeader makes a [Writer] appending to an archive, such as one
+// returned by [OpenWriter], keep the old header rather than writing the new
+// files over it, so the archive as it was before can still be listed with
+// [Reader.Generations]. The old header, along with any it kept itself, is
+// written again between the packed streams and the new header, where
+// readers, including 7-Zip and libarchive, don't look. The archive grows by
+// the size of the old headers each time it's appended to.
+func WithKeepOldHeader() WriterOption {
+	return func(o *writerOptions) {
+		o.keepHeader = true
+	}
+}
+
+// WithReproducible makes the [Writer] produce byte-identical archives when
+// given the same files in the same order, for use in reproducible build
+// pipelines. Creation and access times are removed and the modification time
+// of every file is set to modTime, or removed too if it's the zero time,
+// such as from SOURCE_DATE_EPOCH. Use [Writer.AddFS] to add a directory tree
+// in a stable order. Nothing else written depends on when or where the
+// archive is created, apart from the random IV of each encrypted block and
+// header when [WithWriterPassword] is used.
+func WithReproducible(modTime time.Time) WriterOption {
+	return func(o *writerOptions) {
+		o.reproducible, o.modTime = true, modTime
+	}
+}
+
+// WithSolidBlockSize makes the [Writer] compress files together in solid
+// blocks, the same as the -ms switch of 7-zip, starting a new block for the
+// next file once a block holds at least size bytes, so a block can exceed it
+// by up to the size of its last file as files aren't split across blocks.
+// Larger blocks compress better but reading a file has to decode everything
+// before it in its block.
+func WithSolidBlockSize(size uint64) WriterOption {
+	return func(o *writerOptions) {
+		o.blockSize = size
+	}
+}
+
+// WithSolidBlockFiles makes the [Writer] compress files together in solid
+// blocks of at most n files. It can be combined with [WithSolidBlockSize] and
+// [WithSolidByExtension], in which case a new block is started as soon as any
+// of the limits is reached.
+func WithSolidBlockFiles(n int) WriterOption {
+	return func(o *writerOptions) {
+		o.blockFiles = n
+	}
+}
+
+// WithSolidByExtension makes the [Writer] compress files together in solid
+// blocks, starting a new block whenever the extension of the file name,
+// ignoring case, differs from the files already in the block. Files are
+// written in the order they are added, so adding them sorted by extension
+// groups similar files together the same as 7-zip does with -ms=e.
+func WithSolidByExtension() WriterOption {
+	return func(o *writerOptions) {
+		o.byExtension = true
+	}
+}
+
+// A WriteProgress reports the progress of a [Writer] to the function set
+// with [WithWriterProgress].
+type WriteProgress struct {
+	// Name is the name of the file being written.
+	Name string
+	// Method is the chain of methods compressing the file, formatted the
+	// same as [Block.Method], such as "LZMA2:24 7zAES:19". It is empty
+	// for directories and empty files.
+	Method string
+	// Written is the number of bytes of the file written so far.
+	Written uint64
+	// Done is set for the last report for the file, once it is finished.
+	Done bool
+	// Files is the number of files finished so far.
+	Files int
+	// TotalWritten is the number of bytes of every file written so far.
+	TotalWritten uint64
+	// TotalPacked is the number of bytes the files have been compressed to
+	// so far, which lags behind TotalWritten as the compressor buffers its
+	// input.
+	TotalPacked uint64
+	// Elapsed is the time since the first file was added, so that the rate
+	// of compression, and from it how long is left, can be worked out.
+	Elapsed time.Duration
+}
+
+// WithWriterProgress makes the [Writer] call fn with the progress of the
+// file being written, at most every 100 milliseconds while its contents are
+// written and once it is finished, so a long running compression can show
+// its progress. It is called from whichever goroutine is writing to the
+// Writer and should return quickly as it holds up compression.
+func WithWriterProgress(fn func(WriteProgress)) WriterOption {
+	return func(o *writerOptions) {
+		o.progress = fn
+	}
+}
+
+// Writer implements a 7-zip file writer. By default each file is written to
+// its own block so that it can be read without decoding any other file, see
+// [WithSolidBlockSize], [WithSolidBlockFiles] and [WithSolidByExtension] to
+// compress files together instead.
+type Writer struct {
+	w    io.Writer
+	opts writerOptions
+
+	// Where the packed streams are written; either w directly when the
+	// start header can be written afterwards, or spool
+	data    io.Writer
+	spool   Spool
+	ws      io.WriteSeeker
+	start   int64
+	started bool
+
+	// The header of the archive being appended to, if any, and the
+	// headers being kept with WithKeepOldHeader, oldest first
+	base     *header
+	kept     []*header
+	truncate bool
+
+	files   []FileHeader
+	folders []*folder
+	packed  []uint64
+	streams []uint64
+	sizes   []uint64
+	digests []uint32
+	defined []bool // Which of digests are defined
+	comment []byte
+
+	// The CRC of each folder, only defined for blocks copied from another
+	// archive by CopyBlock
+	blockDigests []uint32
+	blockDefined []bool
+
+	block  *blockWriter
+	cur    *fileWriter
+	closed bool
+	err    error
+
+	// Counters for reporting progress
+	began       time.Time
+	reported    time.Time
+	written     uint64
+	packedBytes uint64
+}
+
+// NewWriter returns a new [*Writer] writing an archive to w. If w is an
+// [io.WriteSeeker] that supports seeking, such as an [*os.File], the
+// contents of each file are written to it as they are compressed and the
+// start header is filled in once the archive is closed, otherwise they are
+// held in memory, or wherever is set with [WithWriterSpool], until then as
+// the start header has to be written first.
+func NewWriter(w io.Writer, opts ...WriterOption) *Writer {
+	zw := &Writer{w: w}
+
+	for _, opt := range opts {
+		opt(&zw.opts)
+	}
+
+	return zw
+}
+
+// begin starts the archive, reserving space for the signature and start
+// headers if w can seek back to fill them in later.
+func (w *Writer) begin() error {
+	if w.started {
+		return nil
+	}
+
+	w.started = true
+
+	if ws, ok := w.w.(io.WriteSeeker); ok {
+		if start, err := ws.Seek(0, io.SeekCurrent); err == nil {
+			if _, err := ws.Write(make([]byte, signatureHeaderSize)); err != nil {
+				return fmt.Errorf("sevenzip: error writing start header: %w", err)
+			}
+
+			w.ws, w.start, w.data = ws, start, ws
+
+			return nil
+		}
+	}
+
+	fn := w.opts.spool
+	if fn == nil {
+		fn = MemorySpool()
+	}
+
+	spool, err := fn()
+	if err != nil {
+		return err
+	}
+
+	w.spool, w.data = spool, spool
+
+	return nil
+}
+
+// SetComment sets the comment of the archive, which is returned in
+// [ArchiveInfo.Comment] encoded as UTF-16LE in the same way as 7-zip.
+func (w *Writer) SetComment(comment string) error {
+	if w.closed {
+		return errWriterClosed
+	}
+
+	w.comment = encodeString(comment)
+
+	return nil
+}
+
+// Create adds a file to the archive using the provided name, with the
+// current time as its modification time, and returns a writer to which the
+// file contents should be written. The name must be a relative path using
+// forward slashes; a name ending in a slash adds a directory instead. The
+// file's contents must be written before the next call to [Writer.Create],
+// [Writer.CreateHeader] or [Writer.Close].
+func (w *Writer) Create(name string) (io.Writer, error) {
+	return w.CreateHeader(&FileHeader{
+		Name:     name,
+		Modified: time.Now(),
+	})
+}
+
+// CreateHeader adds a file to the archive using the provided [FileHeader]
+// for the name, times and attributes, and returns a writer to which the
+// file contents should be written. The CRC32 and UncompressedSize fields are
+// set from the contents as they are written. Directories are added either
+// with a name ending in a slash or with the directory attribute set in
+// fh.Attributes, and can't have any contents.
+//
+// The Writer takes a copy of fh, so fh can't be modified afterwards.
+func (w *Writer) CreateHeader(fh *FileHeader) (io.Writer, error) {
+	if err := w.finish(); err != nil {
+		return nil, err
+	}
+
+	if w.closed {
+		return nil, errWriterClosed
+	}
+
+	if err := w.opts.check(); err != nil {
+		return nil, err
+	}
+
+	h := *fh
+	m := w.opts.method(&h)
+	h.CRC32, h.UncompressedSize, h.Stream = 0, 0, 0
+	w.opts.stamp(&h)
+
+	if strings.HasSuffix(h.Name, "/") {
+		h.Name = strings.TrimSuffix(h.Name, "/")
+		h.Attributes |= msdosDir
+	}
+
+	// 7-Zip always gives every entry attributes and some readers, such
+	// as libarchive, reject archives where only some have them
+	if h.Attributes == 0 {
+		h.Attributes = msdosArchive
+	}
+
+	if h.Name == "" {
+		return nil, errEmptyName
+	}
+
+	w.cur = &fileWriter{
+		w:   w,
+		fh:  h,
+		m:   m,
+		dir: h.Mode().IsDir(),
+		crc: crc32.NewIEEE(),
+	}
+
+	if w.began.IsZero() {
+		w.began = time.Now()
+	}
+
+	return w.cur, nil
+}
+
+// AddFS adds the directories and files in fsys to the archive, walking it
+// with [iofs.WalkDir] so they are always added in lexical order, keeping
+// their modification times and permissions. It fails on anything other than
+// a directory or regular file.
+func (w *Writer) AddFS(fsys iofs.FS) error {
+	return iofs.WalkDir(fsys, ".", func(name string, d iofs.DirEntry, err error) error {
+		if err != nil || name == "." {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("sevenzip: error retrieving file info: %w", err)
+		}
+
+		if !d.IsDir() && !info.Mode().IsRegular() {
+			return fmt.Errorf("sevenzip: %s: %w", name, errNotRegular)
+		}
+
+		fh := &FileHeader{
+			Name:       name,
+			Modified:   info.ModTime(),
+			Attributes: fileModeToAttributes(info.Mode()),
+		}
+
+		if !d.IsDir() {
+			// Only used by any method rules
+			fh.UncompressedSize = uint64(info.Size()) //nolint:gosec
+		}
+
+		fw, err := w.CreateHeader(fh)
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		f, err := fsys.Open(name)
+		if err != nil {
+			return fmt.Errorf("sevenzip: error opening %s: %w", name, err)
+		}
+
+		_, err = io.Copy(fw, f)
+		if err = errors.Join(err, f.Close()); err != nil {
+			return fmt.Errorf("sevenzip: error adding %s: %w", name, err)
+		}
+
+		return nil
+	})
+}
+
+// finish finishes writing the current file, if any, and its block too unless
+// the next file can be added to it.
+func (w *Writer) finish() error {
+	if w.err != nil {
+		return w.err
+	}
+
+	if w.cur == nil {
+		return nil
+	}
+
+	fw := w.cur
+	w.cur = nil
+
+	fh := fw.fh
+
+	if fw.n == 0 {
+		fh.isEmptyStream = true
+		fh.isEmptyFile = !fw.dir
+		w.files = append(w.files, fh)
+		w.report(fw, true)
+
+		return nil
+	}
+
+	fh.CRC32, fh.UncompressedSize, fh.crcDefined = fw.crc.Sum32(), fw.n, true
+
+	w.files = append(w.files, fh)
+	w.sizes = append(w.sizes, fw.n)
+	w.digests = append(w.digests, fh.CRC32)
+	w.defined = append(w.defined, true)
+
+	if !w.opts.solid() {
+		if err := w.endBlock(); err != nil {
+			return err
+		}
+	}
+
+	w.report(fw, true)
+
+	return nil
+}
+
+// report calls the progress function, if any, with the progress of fw.
+// Unless it is done, reports are limited to one every progressInterval.
+func (w *Writer) report(fw *fileWriter, done bool) {
+	fn := w.opts.progress
+	if fn == nil {
+		return
+	}
+
+	now := time.Now()
+	if !done && now.Sub(w.reported) < progressInterval {
+		return
+	}
+
+	w.reported = now
+
+	p := WriteProgress{
+		Name:         fw.fh.Name,
+		Written:      fw.n,
+		Done:         done,
+		Files:        len(w.files),
+		TotalWritten: w.written,
+		TotalPacked:  w.packedBytes,
+		Elapsed:      now.Sub(w.began),
+	}
+
+	if bw := fw.bw; bw != nil {
+		p.Method = bw.method
+
+		// Until the block ends its packed size isn't counted yet
+		if bw == w.block {
+			p.TotalPacked += bw.cw.Count()
+		}
+	}
+
+	fn(p)
+}
+
+// blockFor returns the block the contents of the file called name, using
+// method m and filter f, should be written to, ending the current block and
+// starting a new one if the file can't be added to it.
+func (w *Writer) blockFor(name string, m Method, f *filter) (*blockWriter, error) {
+	ext := strings.ToLower(path.Ext(name))
+
+	if bw := w.block; bw != nil && w.fits(bw, ext, m, f) {
+		bw.files++
+
+		return bw, nil
+	}
+
+	if err := w.endBlock(); err != nil {
+		return nil, err
+	}
+
+	bw, err := w.newBlock(name, m, f)
+	if err != nil {
+		return nil, err
+	}
+
+	bw.files, bw.ext = 1, ext
+	w.block = bw
+
+	return bw, nil
+}
+
+// fits returns whether a file with extension ext using method m and filter f
+// can be added to bw.
+func (w *Writer) fits(bw *blockWriter, ext string, m Method, f *filter) bool {
+	o := &w.opts
+
+	return o.solid() && bw.m == m && bw.f.equal(f) &&
+		(o.blockFiles <= 0 || bw.files < o.blockFiles) &&
+		(o.blockSize == 0 || bw.n < o.blockSize) &&
+		(!o.byExtension || bw.ext == ext)
+}
+
+// endBlock finishes writing the current block, if any, and adds its folder.
+func (w *Writer) endBlock() error {
+	bw := w.block
+	if bw == nil {
+		return nil
+	}
+
+	w.block = nil
+
+	if w.err = bw.close(); w.err != nil {
+		return w.err
+	}
+
+	w.folders = append(w.folders, w.blockFolder(bw))
+	w.blockDigests = append(w.blockDigests, 0)
+	w.blockDefined = append(w.blockDefined, false)
+	w.packed = append(w.packed, bw.cw.Count())
+	w.streams = append(w.streams, uint64(bw.files)) //nolint:gosec
+	w.packedBytes += bw.cw.Count()
+
+	return nil
+}
+
+// blockFolder returns the folder describing the block written by bw.
+func (w *Writer) blockFolder(bw *blockWriter) *folder {
+	c := &coder{id: []byte{0x21}, in: 1, out: 1, properties: bw.props}
+
+	switch {
+	case bw.m == MethodCopy:
+		c = &coder{id: []byte{0x00}, in: 1, out: 1}
+	case bw.m == MethodPPMd:
+		c = &coder{id: []byte{0x03, 0x04, 0x01}, in: 1, out: 1, properties: bw.props}
+	case bw.id != nil:
+		c = &coder{id: bw.id, in: 1, out: 1, properties: bw.props}
+	}
+
+	coders, sizes := []*coder{c}, []uint64{bw.n}
+
+	if bw.aw != nil {
+		// Coders are decoded in order so AES comes first, with its
+		// output bound to the input of the other coder, the same as the
+		// encrypted header
+		coders = append([]*coder{{id: methodAES, in: 1, out: 1, properties: bw.aesp}}, coders...)
+		sizes = append([]uint64{bw.ecw.Count()}, sizes...)
+	}
+
+	if bw.f != nil {
+		// Any filter is undone last, and doesn't change the size
+		coders = append(coders, &coder{id: bw.f.id, in: 1, out: 1, properties: bw.f.props})
+		sizes = append(sizes, bw.n)
+	}
+
+	f := &folder{
+		in:            uint64(len(coders)),
+		out:           uint64(len(coders)),
+		packedStreams: 1,
+		coder:         coders,
+		bindPair:      []*bindPair{},
+		size:          sizes,
+		packed:        []uint64{0},
+	}
+
+	// Each coder's output is bound to the input of the one after it
+	for i := 1; i < len(coders); i++ {
+		f.bindPair = append(f.bindPair, &bindPair{in: uint64(i), out: uint64(i - 1)}) //nolint:gosec
+	}
+
+	return f
+}
+
+// header returns the header describing everything written so far, added to
+// the header of the archive being appended to, if any.
+func (w *Writer) header() *header {
+	h := &header{
+		filesInfo: &filesInfo{
+			file:    w.files,
+			comment: w.comment,
+		},
+	}
+
+	var si *streamsInfo
+
+	if w.base != nil {
+		h.properties = w.base.properties
+		si = w.base.streamsInfo.clone()
+
+		if fi := w.base.filesInfo; fi != nil {
+			h.filesInfo.file = slices.Concat(fi.file, w.files)
+
+			if w.comment == nil {
+				h.filesInfo.comment = fi.comment
+			}
+		}
+	}
+
+	if len(w.folders) == 0 {
+		h.streamsInfo = si
+
+		return h
+	}
+
+	if si == nil {
+		si = new(streamsInfo)
+	}
+
+	if si.packInfo == nil {
+		si.packInfo = new(packInfo)
+	}
+
+	if si.unpackInfo == nil {
+		si.unpackInfo = new(unpackInfo)
+	}
+
+	ss := si.subStreamsInfo
+	if ss == nil {
+		// Each existing folder holds a single file whose CRC, if any,
+		// is that of the folder
+		ss = &subStreamsInfo{
+			streams: ones(si.Folders()),
+			digest:  slices.Clone(si.unpackInfo.digest),
+			defined: slices.Clone(si.unpackInfo.defined),
+		}
+	}
+
+	if ss.digest == nil {
+		ss.digest = make([]uint32, si.Files())
+		ss.defined = make([]bool, si.Files())
+	}
+
+	if ss.size == nil {
+		// Only folders holding a single file can be without sizes,
+		// which is that of the folder
+		ss.size = make([]uint64, 0, si.Files())
+
+		for i, f := range si.unpackInfo.folder {
+			if ss.streams[i] == 1 {
+				ss.size = append(ss.size, f.unpackSize())
+			}
+		}
+	}
+
+	if si.unpackInfo.digest != nil || hasCRC(w.blockDefined) {
+		if si.unpackInfo.digest == nil {
+			si.unpackInfo.digest = make([]uint32, si.Folders())
+			si.unpackInfo.defined = make([]bool, si.Folders())
+		}
+
+		si.unpackInfo.digest = append(si.unpackInfo.digest, w.blockDigests...)
+		si.unpackInfo.defined = append(si.unpackInfo.defined, w.blockDefined...)
+	}
+
+	ss.streams = append(ss.streams, w.streams...)
+	ss.size = append(ss.size, w.sizes...)
+	ss.digest = append(ss.digest, w.digests...)
+	ss.defined = append(ss.defined, w.defined...)
+
+	si.packInfo.streams += uint64(len(w.packed))
+	si.packInfo.size = append(si.packInfo.size, w.packed...)
+	si.unpackInfo.folder = append(si.unpackInfo.folder, w.folders...)
+	si.subStreamsInfo = ss
+
+	h.streamsInfo = si
+
+	return h
+}
+
+// ones returns n streams counts of one.
+func ones(n int) []uint64 {
+	s := make([]uint64, n)
+	for i := range s {
+		s[i] = 1
+	}
+
+	return s
+}
+
+// Close finishes writing the archive by writing the header. It does not
+// close the underlying writer.
+func (w *Writer) Close() (err error) {
+	if w.closed {
+		return errWriterClosed
+	}
+
+	w.closed = true
+
+	defer func() {
+		if w.spool != nil {
+			err = errors.Join(err, w.spool.Close())
+		}
+	}()
+
+	if err := w.finish(); err != nil {
+		return err
+	}
+
+	if err := w.endBlock(); err != nil {
+		return err
+	}
+
+	if err := w.opts.check(); err != nil {
+		return err
+	}
+
+	if err := w.begin(); err != nil {
+		return err
+	}
+
+	h := w.header()
+
+	var dataSize uint64
+	if si := h.streamsInfo; si != nil && si.packInfo != nil {
+		dataSize = uint64(si.packedOffset(len(si.packInfo.size))) //nolint:gosec
+	}
+
+	opts := headerOptions{compress: true, kept: w.kept}
+	if w.opts.encryptHeader {
+		opts.password = w.opts.password
+	}
+
+	prefix, suffix, err := encodeArchive(h, dataSize, opts)
+	if err != nil {
+		return err
+	}
+
+	if w.ws != nil {
+		return w.patch(prefix, suffix)
+	}
+
+	if _, err := w.w.Write(prefix); err != nil {
+		return fmt.Errorf("sevenzip: error writing signature header: %w", err)
+	}
+
+	if _, err := io.Copy(w.w, io.NewSectionReader(w.spool, 0, w.spool.Size())); err != nil {
+		return fmt.Errorf("sevenzip: error copying packed streams: %w", err)
+	}
+
+	if _, err := w.w.Write(suffix); err != nil {
+		return fmt.Errorf("sevenzip: error writing header: %w", err)
+	}
+
+	return nil
+}
+
+// patch writes the header after the packed streams and then fills in the
+// signature and start headers reserved at the start of the archive. When
+// appending, anything left over from the old header is truncated.
+func (w *Writer) patch(prefix, suffix []byte) error {
+	if _, err := w.ws.Write(suffix); err != nil {
+		return fmt.Errorf("sevenzip: error writing header: %w", err)
+	}
+
+	end, err := w.ws.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("sevenzip: error seeking: %w", err)
+	}
+
+	if t, ok := w.ws.(interface{ Truncate(size int64) error }); ok && w.truncate {
+		if err := t.Truncate(end); err != nil {
+			return fmt.Errorf("sevenzip: error truncating: %w", err)
+		}
+	}
+
+	if _, err := w.ws.Seek(w.start, io.SeekStart); err != nil {
+		return fmt.Errorf("sevenzip: error seeking: %w", err)
+	}
+
+	if _, err := w.ws.Write(prefix); err != nil {
+		return fmt.Errorf("sevenzip: error writing start header: %w", err)
+	}
+
+	if _, err := w.ws.Seek(end, io.SeekStart); err != nil {
+		return fmt.Errorf("sevenzip: error seeking: %w", err)
+	}
+
+	return nil
+}
+
+// fileWriter writes the contents of a file to its block, keeping track of
+// their size and CRC.
+type fileWriter struct {
+	w   *Writer
+	fh  FileHeader
+	m   Method
+	dir bool
+	crc hash.Hash32
+	n   uint64
+	bw  *blockWriter
+}
+
+// blockWriter compresses the contents of the files in a block, as they are
+// written, into a single packed stream.
+type blockWriter struct {
+	cw     plumbing.WriteCounter
+	wc     io.WriteCloser
+	props  []byte
+	n      uint64
+	files  int
+	ext    string
+	m      Method
+	id     []byte
+	f      *filter
+	method string
+
+	// When encrypting, the AES writer and a count of what is written to
+	// it
+	aw   io.WriteCloser
+	aesp []byte
+	ecw  plumbing.WriteCounter
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func (fw *fileWriter) Write(p []byte) (int, error) {
+	if fw.w.cur != fw {
+		return 0, errWriterClosed
+	}
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if fw.dir {
+		return 0, errWriteDirectory
+	}
+
+	if fw.bw == nil {
+		bw, err := fw.w.blockFor(fw.fh.Name, fw.m, fw.w.opts.filter(fw.m, p))
+		if err != nil {
+			fw.w.err = err
+
+			return 0, err
+		}
+
+		fw.bw = bw
+	}
+
+	n, err := fw.bw.wc.Write(p)
+	fw.crc.Write(p[:n])
+	fw.n += uint64(n)         //nolint:gosec
+	fw.bw.n += uint64(n)      //nolint:gosec
+	fw.w.written += uint64(n) //nolint:gosec
+
+	if err != nil {
+		fw.w.err = fmt.Errorf("sevenzip: error writing %s: %w", fw.fh.Name, err)
+
+		return n, fw.w.err
+	}
+
+	fw.w.report(fw, false)
+
+	return n, nil
+}
+
+// newBlock starts the packed stream of a new block, which is only done once
+// there are contents to write so that empty files don't have one. The name
+// of the first file in the block is used in any errors.
+func (w *Writer) newBlock(name string, m Method, f *filter) (*blockWriter, error) {
+	rc, registered := m.compressor()
+	if m != MethodLZMA2 && m != MethodCopy && m != MethodPPMd && !registered {
+		return nil, fmt.Errorf("sevenzip: %s: %s: %w", name, m, errWriteMethod)
+	}
+
+	if m == MethodPPMd && newPPMdWriter == nil {
+		return nil, fmt.Errorf("sevenzip: %s: %w", name, unsupportedMethod([]byte{0x03, 0x04, 0x01}))
+	}
+
+	if err := w.begin(); err != nil {
+		return nil, err
+	}
+
+	bw := &blockWriter{m: m, f: f, id: rc.id}
+	out := io.MultiWriter(w.data, &bw.cw)
+
+	if password := w.opts.password; password != "" {
+		aesp, err := aes7z.NewProperties(aes7z.DefaultCycles)
+		if err != nil {
+			return nil, fmt.Errorf("sevenzip: error encrypting %s: %w", name, err)
+		}
+
+		aw, err := aes7z.NewWriter(out, aesp, password)
+		if err != nil {
+			return nil, fmt.Errorf("sevenzip: error encrypting %s: %w", name, err)
+		}
+
+		bw.aw, bw.aesp = aw, aesp
+		out = io.MultiWriter(aw, &bw.ecw)
+	}
+
+	switch {
+	case m == MethodCopy:
+		bw.wc = nopWriteCloser{out}
+	case m == MethodPPMd:
+		wc, props, err := newPPMdWriter(out, defaultPPMdOrder, defaultPPMdMemSize)
+		if err != nil {
+			return nil, fmt.Errorf("sevenzip: error compressing %s: %w", name, err)
+		}
+
+		bw.wc, bw.props = wc, props
+	case registered:
+		wc, props, err := rc.comp(out)
+		if err != nil {
+			return nil, fmt.Errorf("sevenzip: error compressing %s: %w", name, err)
+		}
+
+		bw.wc, bw.props = wc, props
+	default:
+		wc, props, err := lzma2.NewWriter(out, defaultDictSize)
+		if err != nil {
+			return nil, fmt.Errorf("sevenzip: error compressing %s: %w", name, err)
+		}
+
+		bw.wc, bw.props = wc, props
+	}
+
+	if f != nil {
+		wc, err := f.writer()(f.props, bw.wc)
+		if err != nil {
+			return nil, fmt.Errorf("sevenzip: error filtering %s: %w", name, err)
+		}
+
+		bw.wc = wc
+	}
+
+	bw.method = Block{Coders: w.blockFolder(bw).coders()}.Method()
+
+	return bw, nil
+}
+
+func (bw *blockWriter) close() error {
+	if err := bw.wc.Close(); err != nil {
+		return fmt.Errorf("sevenzip: error compressing: %w", err)
+	}
+
+	if bw.aw == nil {
+		return nil
+	}
+
+	if err := bw.aw.Close(); err != nil {
+		return fmt.Errorf("sevenzip: error encrypting: %w", err)
+	}
+
+	return nil
+}
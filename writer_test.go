@@ -0,0 +1,913 @@
+package sevenzip_test
+
+import (
+	"bytes"
+	"io"
+	iofs "io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/javi11/sevenzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type writerEntry struct {
+	header   sevenzip.FileHeader
+	contents []byte
+}
+
+func writerEntries(t *testing.T) []writerEntry {
+	t.Helper()
+
+	random := make([]byte, 1<<20)
+	_, err := rand.New(rand.NewSource(1)).Read(random) //nolint:gosec
+	require.NoError(t, err)
+
+	modified := time.Date(2024, 5, 6, 7, 8, 9, 100, time.UTC)
+
+	return []writerEntry{
+		{
+			header: sevenzip.FileHeader{Name: "dir/"},
+		},
+		{
+			header:   sevenzip.FileHeader{Name: "dir/hello.txt", Modified: modified},
+			contents: []byte("Hello, world!\n"),
+		},
+		{
+			header: sevenzip.FileHeader{Name: "empty.txt", Modified: modified},
+		},
+		{
+			header:   sevenzip.FileHeader{Name: "random.bin", Attributes: 0x8000 | 0o755<<16 | 0x8000<<16},
+			contents: random,
+		},
+		{
+			header:   sevenzip.FileHeader{Name: "repeated.txt", Created: modified, Accessed: modified},
+			contents: bytes.Repeat([]byte("7-zip "), 10000),
+		},
+	}
+}
+
+// seekBuffer is an in-memory io.WriteSeeker.
+type seekBuffer struct {
+	b   []byte
+	off int64
+}
+
+func (sb *seekBuffer) Write(p []byte) (int, error) {
+	if end := sb.off + int64(len(p)); end > int64(len(sb.b)) {
+		sb.b = append(sb.b, make([]byte, end-int64(len(sb.b)))...)
+	}
+
+	n := copy(sb.b[sb.off:], p)
+	sb.off += int64(n)
+
+	return n, nil
+}
+
+func (sb *seekBuffer) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += sb.off
+	case io.SeekEnd:
+		offset += int64(len(sb.b))
+	}
+
+	sb.off = offset
+
+	return offset, nil
+}
+
+func TestWriter(t *testing.T) {
+	t.Parallel()
+
+	entries := writerEntries(t)
+
+	tables := []struct {
+		name     string
+		seek     bool
+		opts     []sevenzip.WriterOption
+		method   string
+		password string
+		header   bool
+	}{
+		{
+			name:   "buffered",
+			method: "LZMA2",
+		},
+		{
+			name:   "seekable",
+			seek:   true,
+			method: "LZMA2",
+		},
+		{
+			name:   "stored",
+			seek:   true,
+			opts:   []sevenzip.WriterOption{sevenzip.WithStore()},
+			method: "Copy",
+		},
+		{
+			name:     "encrypted",
+			opts:     []sevenzip.WriterOption{sevenzip.WithWriterPassword("password")},
+			method:   "LZMA2",
+			password: "password",
+		},
+		{
+			name: "encrypted stored",
+			seek: true,
+			opts: []sevenzip.WriterOption{
+				sevenzip.WithStore(),
+				sevenzip.WithWriterPassword("password"),
+			},
+			method:   "Copy",
+			password: "password",
+		},
+		{
+			name: "encrypted header",
+			opts: []sevenzip.WriterOption{
+				sevenzip.WithWriterPassword("password"),
+				sevenzip.WithEncryptedHeader(),
+			},
+			method:   "LZMA2",
+			password: "password",
+			header:   true,
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				archive []byte
+				buf     bytes.Buffer
+				sb      = &seekBuffer{b: []byte("prefix")}
+				w       *sevenzip.Writer
+			)
+
+			if table.seek {
+				sb.off = int64(len(sb.b))
+				w = sevenzip.NewWriter(sb, table.opts...)
+			} else {
+				w = sevenzip.NewWriter(&buf, table.opts...)
+			}
+
+			require.NoError(t, w.SetComment("A comment"))
+
+			for _, e := range entries {
+				fw, err := w.CreateHeader(&e.header)
+				require.NoError(t, err)
+
+				_, err = fw.Write(e.contents)
+				require.NoError(t, err)
+			}
+
+			require.NoError(t, w.Close())
+			require.Error(t, w.Close())
+
+			archive = buf.Bytes()
+			if table.seek {
+				require.Equal(t, []byte("prefix"), sb.b[:6])
+				archive = sb.b[6:]
+			}
+
+			if table.password != "" {
+				r, err := sevenzip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+
+				if table.header {
+					require.ErrorIs(t, err, sevenzip.ErrPasswordRequired)
+				} else {
+					require.NoError(t, err)
+
+					_, err = r.File[1].Open()
+					require.ErrorIs(t, err, sevenzip.ErrPasswordRequired)
+				}
+			}
+
+			r, err := sevenzip.NewReaderWithPassword(bytes.NewReader(archive), int64(len(archive)), table.password)
+			require.NoError(t, err)
+
+			info, err := r.Info()
+			require.NoError(t, err)
+			assert.Equal(t, []byte{
+				'A', 0, ' ', 0, 'c', 0, 'o', 0, 'm', 0, 'm', 0, 'e', 0, 'n', 0, 't', 0, 0, 0,
+			}, info.Comment)
+
+			require.Len(t, r.File, len(entries))
+
+			for i, f := range r.File {
+				e := entries[i]
+
+				assert.Equal(t, e.header.Name, f.Name)
+				assert.Equal(t, e.header.Name == "dir/", f.FileInfo().IsDir(), f.Name)
+				assert.True(t, e.header.Modified.Truncate(100*time.Nanosecond).Equal(f.Modified), f.Name)
+				assert.True(t, e.header.Created.Equal(f.Created), f.Name)
+				assert.True(t, e.header.Accessed.Equal(f.Accessed), f.Name)
+				assert.Equal(t, uint64(len(e.contents)), f.UncompressedSize, f.Name)
+
+				if e.header.Attributes != 0 {
+					assert.Equal(t, e.header.Attributes, f.Attributes, f.Name)
+				}
+
+				rc, err := f.Open()
+				require.NoError(t, err)
+
+				b, err := io.ReadAll(rc)
+				require.NoError(t, err)
+				require.NoError(t, rc.Close())
+
+				assert.Equal(t, len(e.contents), len(b), f.Name)
+				assert.True(t, bytes.Equal(e.contents, b), f.Name)
+
+				if f.HasStream() {
+					var methods []string
+					for _, c := range f.Coders() {
+						methods = append(methods, c.Name())
+					}
+
+					want := []string{table.method}
+					if table.password != "" {
+						want = []string{"7zAES", table.method}
+					}
+
+					assert.Equal(t, want, methods, f.Name)
+					assert.NotZero(t, f.CRC32, f.Name)
+				}
+			}
+
+			if table.method == "Copy" && table.password == "" {
+				for _, f := range r.File {
+					if f.HasStream() {
+						assert.NoError(t, sevenzip.CompareDirectAccess(f), f.Name)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestWriterFile(t *testing.T) {
+	t.Parallel()
+
+	name := filepath.Join(t.TempDir(), "test.7z")
+
+	f, err := os.Create(name)
+	require.NoError(t, err)
+
+	w := sevenzip.NewWriter(f)
+
+	fw, err := w.Create("a.txt")
+	require.NoError(t, err)
+
+	_, err = io.WriteString(fw, "a")
+	require.NoError(t, err)
+
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+
+	r, err := sevenzip.OpenReader(name)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, r.Close())
+	})
+
+	require.Len(t, r.File, 1)
+	assert.Equal(t, "a.txt", r.File[0].Name)
+	assert.WithinDuration(t, time.Now(), r.File[0].Modified, time.Minute)
+
+	rc, err := r.File[0].Open()
+	require.NoError(t, err)
+
+	b, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	assert.Equal(t, []byte("a"), b)
+}
+
+func TestWriterEmpty(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	require.NoError(t, sevenzip.NewWriter(&buf).Close())
+
+	r, err := sevenzip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	assert.Empty(t, r.File)
+}
+
+func TestWriterErrors(t *testing.T) {
+	t.Parallel()
+
+	w := sevenzip.NewWriter(io.Discard)
+
+	_, err := w.Create("")
+	require.ErrorIs(t, err, sevenzip.ErrEmptyName)
+
+	dir, err := w.Create("dir/")
+	require.NoError(t, err)
+
+	_, err = dir.Write([]byte("a"))
+	require.ErrorIs(t, err, sevenzip.ErrWriteDirectory)
+
+	fw, err := w.Create("a.txt")
+	require.NoError(t, err)
+
+	_, err = w.Create("b.txt")
+	require.NoError(t, err)
+
+	// Writing to a file after the next one has been created fails
+	_, err = fw.Write([]byte("a"))
+	require.ErrorIs(t, err, sevenzip.ErrWriterClosed)
+
+	require.NoError(t, w.Close())
+
+	_, err = w.Create("c.txt")
+	require.ErrorIs(t, err, sevenzip.ErrWriterClosed)
+	require.ErrorIs(t, w.SetComment(""), sevenzip.ErrWriterClosed)
+
+	// An encrypted header needs a password
+	w = sevenzip.NewWriter(io.Discard, sevenzip.WithEncryptedHeader())
+
+	_, err = w.Create("a.txt")
+	require.ErrorIs(t, err, sevenzip.ErrHeaderPassword)
+	require.ErrorIs(t, w.Close(), sevenzip.ErrHeaderPassword)
+}
+
+//nolint:funlen
+func TestWriterSolid(t *testing.T) {
+	t.Parallel()
+
+	names := []string{"a.txt", "b.txt", "empty.txt", "c.bin", "D.BIN", "e.txt"}
+
+	contents := func(name string) []byte {
+		if name == "empty.txt" {
+			return []byte{}
+		}
+
+		return bytes.Repeat([]byte(name), 25)
+	}
+
+	tables := []struct {
+		name   string
+		opts   []sevenzip.WriterOption
+		blocks []int
+	}{
+		{
+			name:   "default",
+			blocks: []int{0, 1, -1, 2, 3, 4},
+		},
+		{
+			name:   "files",
+			opts:   []sevenzip.WriterOption{sevenzip.WithSolidBlockFiles(2)},
+			blocks: []int{0, 0, -1, 1, 1, 2},
+		},
+		{
+			name:   "size",
+			opts:   []sevenzip.WriterOption{sevenzip.WithSolidBlockSize(300)},
+			blocks: []int{0, 0, -1, 0, 1, 1},
+		},
+		{
+			name:   "extension",
+			opts:   []sevenzip.WriterOption{sevenzip.WithSolidByExtension()},
+			blocks: []int{0, 0, -1, 1, 1, 2},
+		},
+		{
+			name: "combined",
+			opts: []sevenzip.WriterOption{
+				sevenzip.WithSolidByExtension(),
+				sevenzip.WithSolidBlockFiles(1),
+			},
+			blocks: []int{0, 1, -1, 2, 3, 4},
+		},
+		{
+			name: "store",
+			opts: []sevenzip.WriterOption{
+				sevenzip.WithStore(),
+				sevenzip.WithSolidBlockFiles(10),
+			},
+			blocks: []int{0, 0, -1, 0, 0, 0},
+		},
+		{
+			name: "encrypted",
+			opts: []sevenzip.WriterOption{
+				sevenzip.WithWriterPassword("password"),
+				sevenzip.WithSolidBlockFiles(3),
+			},
+			blocks: []int{0, 0, -1, 0, 1, 1},
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			name := filepath.Join(t.TempDir(), "test.7z")
+
+			f, err := os.Create(name)
+			require.NoError(t, err)
+
+			w := sevenzip.NewWriter(f, table.opts...)
+
+			for _, name := range names {
+				fw, err := w.Create(name)
+				require.NoError(t, err)
+
+				_, err = fw.Write(contents(name))
+				require.NoError(t, err)
+			}
+
+			require.NoError(t, w.Close())
+			require.NoError(t, f.Close())
+
+			// Appending adds further blocks after the existing ones
+			wc, err := sevenzip.OpenWriter(name, table.opts...)
+			require.NoError(t, err)
+
+			for _, name := range []string{"f.txt", "g.txt"} {
+				fw, err := wc.Create(name)
+				require.NoError(t, err)
+
+				_, err = fw.Write(contents(name))
+				require.NoError(t, err)
+			}
+
+			require.NoError(t, wc.Close())
+
+			r, err := sevenzip.OpenReaderWithPassword(name, "password")
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				require.NoError(t, r.Close())
+			})
+
+			require.Len(t, r.File, len(names)+2)
+
+			blocks := make([]int, 0, len(names))
+
+			for i, f := range r.File {
+				if i < len(names) {
+					if f.HasStream() {
+						blocks = append(blocks, f.Stream)
+					} else {
+						blocks = append(blocks, -1)
+					}
+				}
+
+				rc, err := f.Open()
+				require.NoError(t, err)
+
+				b, err := io.ReadAll(rc)
+				require.NoError(t, err)
+				require.NoError(t, rc.Close())
+				assert.Equal(t, contents(f.Name), b, f.Name)
+			}
+
+			assert.Equal(t, table.blocks, blocks)
+		})
+	}
+}
+
+//nolint:funlen
+func TestWriterProgress(t *testing.T) {
+	t.Parallel()
+
+	tables := []struct {
+		name string
+		opts []sevenzip.WriterOption
+	}{
+		{
+			name: "lzma2",
+		},
+		{
+			name: "solid encrypted",
+			opts: []sevenzip.WriterOption{
+				sevenzip.WithSolidBlockFiles(10),
+				sevenzip.WithWriterPassword("password"),
+			},
+		},
+		{
+			name: "store",
+			opts: []sevenzip.WriterOption{sevenzip.WithStore()},
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			var reports []sevenzip.WriteProgress
+
+			buf := new(bytes.Buffer)
+			w := sevenzip.NewWriter(buf, append(table.opts, sevenzip.WithWriterProgress(func(p sevenzip.WriteProgress) {
+				reports = append(reports, p)
+			}))...)
+
+			sizes := map[string]int{"a.txt": 300000, "dir": 0, "b.txt": 5000, "empty.txt": 0}
+
+			for _, name := range []string{"a.txt", "dir/", "b.txt", "empty.txt"} {
+				fw, err := w.Create(name)
+				require.NoError(t, err)
+
+				for n := sizes[strings.TrimSuffix(name, "/")]; n > 0; n -= 1000 {
+					_, err = fw.Write(bytes.Repeat([]byte(name[:1]), 1000))
+					require.NoError(t, err)
+				}
+			}
+
+			require.NoError(t, w.Close())
+
+			r, err := sevenzip.NewReaderWithPassword(bytes.NewReader(buf.Bytes()), int64(buf.Len()), "password")
+			require.NoError(t, err)
+
+			method := r.Blocks()[0].Method()
+
+			var (
+				done     []string
+				total    uint64
+				previous sevenzip.WriteProgress
+			)
+
+			for _, p := range reports {
+				assert.GreaterOrEqual(t, p.TotalWritten, previous.TotalWritten)
+				assert.GreaterOrEqual(t, p.Elapsed, previous.Elapsed)
+
+				previous = p
+
+				if !p.Done {
+					continue
+				}
+
+				total += uint64(sizes[p.Name]) //nolint:gosec
+				done = append(done, p.Name)
+
+				assert.Equal(t, uint64(sizes[p.Name]), p.Written, p.Name) //nolint:gosec
+				assert.Equal(t, total, p.TotalWritten, p.Name)
+				assert.Len(t, done, p.Files, p.Name)
+
+				if sizes[p.Name] > 0 {
+					assert.Equal(t, method, p.Method, p.Name)
+				} else {
+					assert.Empty(t, p.Method, p.Name)
+				}
+			}
+
+			assert.Equal(t, []string{"a.txt", "dir", "b.txt", "empty.txt"}, done)
+			// Compressed output is buffered so might not have been
+			// counted yet
+			assert.LessOrEqual(t, previous.TotalPacked, uint64(buf.Len()))
+		})
+	}
+}
+
+//nolint:funlen
+func TestWriterReproducible(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"b.txt":       {Data: []byte("b"), Mode: 0o644, ModTime: time.Now()},
+		"dir":         {Mode: iofs.ModeDir | 0o755, ModTime: time.Now()},
+		"dir/c.txt":   {Data: bytes.Repeat([]byte("c"), 1000), Mode: 0o600, ModTime: time.Now()},
+		"a.txt":       {Data: []byte("a"), Mode: 0o444, ModTime: time.Now()},
+		"empty.txt":   {Mode: 0o644, ModTime: time.Now()},
+		"dir/sub/d.7": {Data: []byte("d"), Mode: 0o755, ModTime: time.Now()},
+	}
+
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tables := []struct {
+		name    string
+		modTime time.Time
+		opts    []sevenzip.WriterOption
+	}{
+		{
+			name: "no times",
+		},
+		{
+			name:    "fixed time",
+			modTime: modTime,
+		},
+		{
+			name:    "solid",
+			modTime: modTime,
+			opts:    []sevenzip.WriterOption{sevenzip.WithSolidBlockFiles(10)},
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			write := func(w io.Writer) {
+				zw := sevenzip.NewWriter(w, append(table.opts, sevenzip.WithReproducible(table.modTime))...)
+				require.NoError(t, zw.AddFS(fsys))
+
+				// Files added directly get the same times
+				fw, err := zw.CreateHeader(&sevenzip.FileHeader{
+					Name:     "extra.txt",
+					Created:  time.Now(),
+					Accessed: time.Now(),
+					Modified: time.Now(),
+				})
+				require.NoError(t, err)
+
+				_, err = io.WriteString(fw, "extra")
+				require.NoError(t, err)
+				require.NoError(t, zw.Close())
+			}
+
+			first, second := new(bytes.Buffer), new(seekBuffer)
+			write(first)
+			time.Sleep(time.Millisecond)
+			write(second)
+
+			assert.Equal(t, first.Bytes(), second.b)
+
+			r, err := sevenzip.NewReader(bytes.NewReader(first.Bytes()), int64(first.Len()))
+			require.NoError(t, err)
+
+			var names []string
+
+			for _, f := range r.File {
+				names = append(names, f.Name)
+
+				assert.True(t, f.Created.IsZero(), f.Name)
+				assert.True(t, f.Accessed.IsZero(), f.Name)
+				assert.True(t, f.Modified.Equal(table.modTime), f.Name)
+
+				if fi, ok := fsys[strings.TrimSuffix(f.Name, "/")]; ok {
+					assert.Equal(t, fi.Mode, f.Mode(), f.Name)
+
+					if !f.FileInfo().IsDir() {
+						rc, err := f.Open()
+						require.NoError(t, err)
+
+						b, err := io.ReadAll(rc)
+						require.NoError(t, err)
+						require.NoError(t, rc.Close())
+						assert.Equal(t, string(fi.Data), string(b), f.Name)
+					}
+				}
+			}
+
+			assert.Equal(t, []string{
+				"a.txt", "b.txt", "dir/", "dir/c.txt", "dir/sub/", "dir/sub/d.7", "empty.txt", "extra.txt",
+			}, names)
+		})
+	}
+}
+
+func TestWriterAddFSIrregular(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"link": {Data: []byte("target"), Mode: iofs.ModeSymlink | 0o777},
+	}
+
+	w := sevenzip.NewWriter(io.Discard)
+	require.ErrorIs(t, w.AddFS(fsys), sevenzip.ErrNotRegular)
+}
+
+//nolint:funlen
+func TestWriterMethodRules(t *testing.T) {
+	t.Parallel()
+
+	buf := new(bytes.Buffer)
+	w := sevenzip.NewWriter(buf,
+		sevenzip.WithSolidBlockFiles(10),
+		sevenzip.WithMethodRules(
+			sevenzip.MethodForExtensions(sevenzip.MethodCopy, ".jpg", ".mp4"),
+			sevenzip.MethodForSize(sevenzip.MethodCopy, 500, 0),
+		),
+		sevenzip.WithMethodRules(func(fh *sevenzip.FileHeader) (sevenzip.Method, bool) {
+			return sevenzip.MethodCopy, strings.HasPrefix(fh.Name, "raw/")
+		}),
+	)
+
+	files := []struct {
+		name string
+		size uint64
+	}{
+		{name: "a.txt"},
+		{name: "b.txt"},
+		{name: "c.jpg"},
+		{name: "d.MP4"},
+		{name: "e.bin", size: 1000},
+		{name: "f.txt", size: 100},
+		{name: "raw/g.txt"},
+	}
+
+	for _, f := range files {
+		fw, err := w.CreateHeader(&sevenzip.FileHeader{Name: f.name, UncompressedSize: f.size})
+		require.NoError(t, err)
+
+		_, err = fw.Write(bytes.Repeat([]byte(f.name), 50))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, w.Close())
+
+	r, err := sevenzip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	methods := make([]string, 0, len(r.Blocks()))
+	for _, b := range r.Blocks() {
+		methods = append(methods, b.Method())
+	}
+
+	assert.Equal(t, []string{"LZMA2:24", "Copy", "LZMA2:24", "Copy"}, methods)
+
+	streams := make([]int, 0, len(r.File))
+
+	for _, f := range r.File {
+		streams = append(streams, f.Stream)
+
+		rc, err := f.Open()
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		assert.Equal(t, bytes.Repeat([]byte(f.Name), 50), b)
+		assert.Equal(t, uint64(len(b)), f.UncompressedSize)
+	}
+
+	assert.Equal(t, []int{0, 0, 1, 1, 1, 2, 3}, streams)
+
+	// A method that can only be read fails once there is something to write
+	w = sevenzip.NewWriter(io.Discard, sevenzip.WithMethodRules(func(*sevenzip.FileHeader) (sevenzip.Method, bool) {
+		return sevenzip.Method(42), true
+	}))
+
+	fw, err := w.Create("a.txt")
+	require.NoError(t, err)
+
+	_, err = io.WriteString(fw, "a")
+	require.ErrorIs(t, err, sevenzip.ErrWriteMethod)
+	require.ErrorContains(t, err, "Method(42)")
+}
+
+// executable returns header followed by random code with plenty of bytes
+// that look like the start of branch instructions.
+func executable(header []byte, seed int64) []byte {
+	code := make([]byte, 1<<16)
+	rng := rand.New(rand.NewSource(seed)) //nolint:gosec
+	_, _ = rng.Read(code)
+
+	for i := 0; i < len(code); i += 8 {
+		code[i] = 0xe8
+		code[i+3] = 0xeb
+	}
+
+	b := make([]byte, 0x80)
+	copy(b, header)
+
+	return append(b, code...)
+}
+
+//nolint:funlen
+func TestWriterAutoFilters(t *testing.T) {
+	t.Parallel()
+
+	pe := make([]byte, 0x48)
+	copy(pe, "MZ")
+	pe[0x3c] = 0x40
+	copy(pe[0x40:], "PE\x00\x00\x64\x86")
+
+	thumb := bytes.Clone(pe)
+	copy(thumb[0x44:], "\xc4\x01")
+
+	wav := []byte("RIFF\x00\x00\x00\x00WAVEfmt \x10\x00\x00\x00\x01\x00\x02\x00" +
+		"\x44\xac\x00\x00\x10\xb1\x02\x00\x04\x00\x10\x00")
+
+	files := []struct {
+		name     string
+		contents []byte
+		method   string
+	}{
+		{"a.exe", executable(pe, 1), "BCJ LZMA2:24"},
+		{"b", executable([]byte("\x7fELF\x02\x01\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02\x00\x3e\x00"), 2), "BCJ LZMA2:24"},
+		{"c", executable([]byte("\x7fELF\x01\x01\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02\x00\x28\x00"), 3), "ARM LZMA2:24"},
+		{"d", executable([]byte("\x7fELF\x01\x02\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02\x00\x14"), 4), "PPC LZMA2:24"},
+		{"e", executable([]byte("\x7fELF\x01\x02\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02\x00\x02"), 5), "SPARC LZMA2:24"},
+		{"f", executable([]byte("\xcf\xfa\xed\xfe\x07\x00\x00\x01"), 6), "BCJ LZMA2:24"},
+		{"g.wav", executable(wav, 7), "Delta:4 LZMA2:24"},
+		{"h.txt", bytes.Repeat([]byte("not an executable\n"), 100), "LZMA2:24"},
+		{"i.exe", executable([]byte("MZ"), 8), "LZMA2:24"},
+		{"j", executable([]byte("\x7fELF\x02\x01\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02\x00\xf3\x00"), 9), "RISCV LZMA2:24"},
+		{"k", executable([]byte("\x7fELF\x02\x01\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02\x00\x32\x00"), 10), "IA64 LZMA2:24"},
+		{"l.exe", executable(thumb, 11), "ARMT LZMA2:24"},
+	}
+
+	tables := []struct {
+		name   string
+		opts   []sevenzip.WriterOption
+		suffix string
+	}{
+		{
+			name: "plain",
+		},
+		{
+			name:   "encrypted",
+			opts:   []sevenzip.WriterOption{sevenzip.WithWriterPassword("password")},
+			suffix: " 7zAES:19",
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := new(bytes.Buffer)
+			w := sevenzip.NewWriter(buf, append(table.opts, sevenzip.WithAutoFilters())...)
+
+			for _, f := range files {
+				fw, err := w.Create(f.name)
+				require.NoError(t, err)
+
+				// Written in uneven pieces so the filters are fed
+				// across boundaries
+				for b := f.contents; len(b) > 0; {
+					n, err := fw.Write(b[:min(len(b), 1001)])
+					require.NoError(t, err)
+
+					b = b[n:]
+				}
+			}
+
+			require.NoError(t, w.Close())
+
+			r, err := sevenzip.NewReaderWithPassword(bytes.NewReader(buf.Bytes()), int64(buf.Len()), "password")
+			require.NoError(t, err)
+
+			blocks := r.Blocks()
+			require.Len(t, blocks, len(files))
+
+			for i, f := range files {
+				assert.Equal(t, f.method+table.suffix, blocks[i].Method(), f.name)
+
+				rc, err := r.File[i].Open()
+				require.NoError(t, err)
+
+				b, err := io.ReadAll(rc)
+				require.NoError(t, err)
+				require.NoError(t, rc.Close())
+				assert.Equal(t, f.contents, b, f.name)
+			}
+		})
+	}
+}
+
+func TestWriterAutoFiltersSolid(t *testing.T) {
+	t.Parallel()
+
+	elf := executable([]byte("\x7fELF\x02\x01\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02\x00\x3e\x00"), 1)
+
+	contents := map[string][]byte{
+		"a":     elf,
+		"b":     elf[1:],
+		"c.txt": []byte("c.txt"),
+		"d":     elf,
+		"e.jpg": []byte("e.jpg"),
+	}
+
+	buf := new(bytes.Buffer)
+	w := sevenzip.NewWriter(buf, sevenzip.WithSolidBlockFiles(10), sevenzip.WithAutoFilters())
+
+	for _, name := range []string{"a", "b", "c.txt", "d", "e.jpg"} {
+		fw, err := w.Create(name)
+		require.NoError(t, err)
+
+		_, err = fw.Write(contents[name])
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, w.Close())
+
+	r, err := sevenzip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	methods := make([]string, 0, len(r.Blocks()))
+	for _, b := range r.Blocks() {
+		methods = append(methods, b.Method())
+	}
+
+	// Only files needing the same filter share a block
+	assert.Equal(t, []string{"BCJ LZMA2:24", "LZMA2:24", "BCJ LZMA2:24", "LZMA2:24"}, methods)
+
+	for _, f := range r.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		assert.Equal(t, contents[f.Name], b, f.Name)
+	}
+}
@@ -0,0 +1,200 @@
+// Package zipcompat lets code that handles both ZIP and 7-zip archives use a
+// single code path. [Reader] and [File] use the method names and semantics
+// of [zip.Reader] and [zip.File], and are implemented for both formats by
+// [FromZip] and [FromSevenZip], or [NewReader] for an archive of either.
+package zipcompat
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	iofs "io/fs"
+
+	"github.com/javi11/sevenzip"
+)
+
+// ErrNoDataOffset is returned by [File.DataOffset] for a 7-zip archive when
+// the contents of a file can't be read directly from the archive, see
+// [sevenzip.File.DataOffset].
+var ErrNoDataOffset = errors.New("zipcompat: file has no data offset")
+
+// A Reader is an archive of either format.
+type Reader interface {
+	// Open opens the named file using the semantics of [fs.FS.Open].
+	Open(name string) (iofs.File, error)
+	// Files returns the files in the archive, in the order they are
+	// stored.
+	Files() []File
+	// RegisterDecompressor registers or overrides a custom decompressor
+	// for a ZIP method ID for this reader only, the same as
+	// [zip.Reader.RegisterDecompressor]. For 7-zip archives it is used
+	// for the method 7-zip reserves for each ZIP method, such as
+	// 04 01 08 for Deflate.
+	RegisterDecompressor(method uint16, dcomp zip.Decompressor)
+}
+
+// A File is a single file in an archive.
+type File interface {
+	// Name returns the name of the file, as in [zip.FileHeader.Name].
+	Name() string
+	// FileInfo returns an [fs.FileInfo] for the file.
+	FileInfo() iofs.FileInfo
+	// CRC32 returns the CRC32 of the contents, or 0 if it isn't known.
+	// For 7-zip archives it is the one returned by
+	// [sevenzip.File.Digest], so files in solid blocks that only have a
+	// CRC32 for the whole block get one once the block has been decoded.
+	CRC32() uint32
+	// Open returns an [io.ReadCloser] that provides access to the
+	// contents of the file.
+	Open() (io.ReadCloser, error)
+	// DataOffset returns the offset of the file's possibly compressed
+	// data relative to the beginning of the archive, the same as
+	// [zip.File.DataOffset]. For 7-zip archives only stored files have
+	// an offset and for multi-volume archives it is from the start of
+	// the first volume.
+	DataOffset() (int64, error)
+}
+
+//nolint:gochecknoglobals
+var signature = []byte{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c}
+
+// NewReader returns a [Reader] for the archive of either format read from r,
+// which is size bytes long, the counterpart of [zip.NewReader]. An archive
+// starting with the 7-zip signature is read as 7-zip, anything else as ZIP
+// unless it isn't one, in which case it is tried as 7-zip as that may
+// follow other data such as a self-extracting stub.
+func NewReader(r io.ReaderAt, size int64) (Reader, error) {
+	b := make([]byte, len(signature))
+	if n, _ := r.ReadAt(b, 0); n == len(b) && bytes.Equal(b, signature) {
+		return newSevenZip(r, size)
+	}
+
+	zr, err := zip.NewReader(r, size)
+	if errors.Is(err, zip.ErrFormat) {
+		return newSevenZip(r, size)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("zipcompat: %w", err)
+	}
+
+	return FromZip(zr), nil
+}
+
+func newSevenZip(r io.ReaderAt, size int64) (Reader, error) {
+	sr, err := sevenzip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("zipcompat: %w", err)
+	}
+
+	return FromSevenZip(sr), nil
+}
+
+// FromZip returns a [Reader] for the ZIP archive r.
+func FromZip(r *zip.Reader) Reader {
+	return zipReader{r}
+}
+
+type zipReader struct {
+	r *zip.Reader
+}
+
+func (z zipReader) Open(name string) (iofs.File, error) {
+	return z.r.Open(name) //nolint:wrapcheck
+}
+
+func (z zipReader) Files() []File {
+	files := make([]File, len(z.r.File))
+	for i, f := range z.r.File {
+		files[i] = zipFile{f}
+	}
+
+	return files
+}
+
+func (z zipReader) RegisterDecompressor(method uint16, dcomp zip.Decompressor) {
+	z.r.RegisterDecompressor(method, dcomp)
+}
+
+type zipFile struct {
+	f *zip.File
+}
+
+func (f zipFile) Name() string                 { return f.f.Name }
+func (f zipFile) FileInfo() iofs.FileInfo      { return f.f.FileInfo() }
+func (f zipFile) CRC32() uint32                { return f.f.CRC32 }
+func (f zipFile) Open() (io.ReadCloser, error) { return f.f.Open() } //nolint:wrapcheck
+
+func (f zipFile) DataOffset() (int64, error) {
+	return f.f.DataOffset() //nolint:wrapcheck
+}
+
+// FromSevenZip returns a [Reader] for the 7-zip archive r.
+func FromSevenZip(r *sevenzip.Reader) Reader {
+	return sevenZipReader{r}
+}
+
+type sevenZipReader struct {
+	r *sevenzip.Reader
+}
+
+func (z sevenZipReader) Open(name string) (iofs.File, error) {
+	return z.r.Open(name) //nolint:wrapcheck
+}
+
+func (z sevenZipReader) Files() []File {
+	files := make([]File, len(z.r.File))
+	for i, f := range z.r.File {
+		files[i] = sevenZipFile{f}
+	}
+
+	return files
+}
+
+var errOneReader = errors.New("zipcompat: need exactly one reader")
+
+func (z sevenZipReader) RegisterDecompressor(method uint16, dcomp zip.Decompressor) {
+	z.r.RegisterDecompressor(sevenZipMethod(method), func(_ []byte, _ uint64, readers []io.ReadCloser) (io.ReadCloser, error) {
+		if len(readers) != 1 {
+			return nil, errOneReader
+		}
+
+		return dcomp(readers[0]), nil
+	})
+}
+
+// sevenZipMethod returns the 7-zip method ID for the ZIP method.
+func sevenZipMethod(method uint16) []byte {
+	if method > 0xff { //nolint:mnd
+		return []byte{0x04, 0x01, byte(method >> 8), byte(method)} //nolint:mnd
+	}
+
+	return []byte{0x04, 0x01, byte(method)}
+}
+
+type sevenZipFile struct {
+	f *sevenzip.File
+}
+
+func (f sevenZipFile) Name() string                 { return f.f.Name }
+func (f sevenZipFile) FileInfo() iofs.FileInfo      { return f.f.FileInfo() }
+func (f sevenZipFile) Open() (io.ReadCloser, error) { return f.f.Open() } //nolint:wrapcheck
+
+func (f sevenZipFile) CRC32() uint32 {
+	if crc, ok := f.f.Digest(); ok {
+		return crc
+	}
+
+	return f.f.CRC32
+}
+
+func (f sevenZipFile) DataOffset() (int64, error) {
+	offset, _, err := f.f.DataOffset()
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrNoDataOffset, err)
+	}
+
+	return offset, nil
+}
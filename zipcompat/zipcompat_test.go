@@ -0,0 +1,224 @@
+package zipcompat_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"hash/crc32"
+	"io"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/javi11/sevenzip"
+	"github.com/javi11/sevenzip/zipcompat"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+
+	for name, contents := range files {
+		fw, err := w.Create(name)
+		require.NoError(t, err)
+
+		_, err = io.WriteString(fw, contents)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
+
+func newZip(t *testing.T, files map[string]string) *zip.Reader {
+	t.Helper()
+
+	b := zipArchive(t, files)
+
+	r, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	require.NoError(t, err)
+
+	return r
+}
+
+func newSevenZip(t *testing.T, name string) *sevenzip.Reader {
+	t.Helper()
+
+	r, err := sevenzip.OpenReader(filepath.Join("..", "testdata", name))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, r.Close())
+	})
+
+	return &r.Reader
+}
+
+// check reads every file in r through the common interface.
+func check(t *testing.T, r zipcompat.Reader) {
+	t.Helper()
+
+	files := r.Files()
+	require.NotEmpty(t, files)
+
+	for _, f := range files {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		rc, err := f.Open()
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+
+		assert.Equal(t, f.FileInfo().Size(), int64(len(b)), f.Name())
+		assert.Equal(t, f.CRC32(), crc32.ChecksumIEEE(b), f.Name())
+
+		fsf, err := r.Open(f.Name())
+		require.NoError(t, err)
+		require.NoError(t, fsf.Close())
+	}
+}
+
+func TestReader(t *testing.T) {
+	t.Parallel()
+
+	tables := []struct {
+		name string
+		r    func(*testing.T) zipcompat.Reader
+	}{
+		{
+			name: "zip",
+			r: func(t *testing.T) zipcompat.Reader {
+				t.Helper()
+
+				return zipcompat.FromZip(newZip(t, map[string]string{
+					"a.txt":     "The quick brown fox jumps over the lazy dog.\n",
+					"dir/b.txt": "Pack my box with five dozen liquor jugs.\n",
+				}))
+			},
+		},
+		{
+			name: "7z",
+			r: func(t *testing.T) zipcompat.Reader {
+				t.Helper()
+
+				return zipcompat.FromSevenZip(newSevenZip(t, "lzma.7z"))
+			},
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			check(t, table.r(t))
+		})
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	t.Parallel()
+
+	lzma, err := os.ReadFile(filepath.Join("..", "testdata", "lzma.7z"))
+	require.NoError(t, err)
+
+	sfx, err := os.ReadFile(filepath.Join("..", "testdata", "sfx.exe"))
+	require.NoError(t, err)
+
+	tables := []struct {
+		name string
+		b    []byte
+		err  bool
+	}{
+		{
+			name: "zip",
+			b:    zipArchive(t, map[string]string{"a.txt": "The quick brown fox jumps over the lazy dog.\n"}),
+		},
+		{
+			name: "7z",
+			b:    lzma,
+		},
+		{
+			name: "sfx",
+			b:    sfx,
+		},
+		{
+			name: "neither",
+			b:    bytes.Repeat([]byte("not an archive"), 100),
+			err:  true,
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := zipcompat.NewReader(bytes.NewReader(table.b), int64(len(table.b)))
+			if table.err {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			check(t, r)
+		})
+	}
+}
+
+func TestDataOffset(t *testing.T) {
+	t.Parallel()
+
+	zr := zipcompat.FromZip(newZip(t, map[string]string{"a.txt": "a"}))
+
+	_, err := zr.Files()[0].DataOffset()
+	assert.NoError(t, err)
+
+	sr := zipcompat.FromSevenZip(newSevenZip(t, "lzma.7z"))
+
+	_, err = sr.Files()[0].DataOffset()
+	assert.ErrorIs(t, err, zipcompat.ErrNoDataOffset)
+	assert.ErrorIs(t, err, sevenzip.ErrNotStored)
+
+	sr = zipcompat.FromSevenZip(newSevenZip(t, "copy.7z"))
+
+	for _, f := range sr.Files() {
+		if f.FileInfo().IsDir() || f.FileInfo().Size() == 0 {
+			continue
+		}
+
+		_, err = f.DataOffset()
+		assert.NoError(t, err, f.Name())
+	}
+}
+
+func TestRegisterDecompressor(t *testing.T) {
+	t.Parallel()
+
+	var called bool
+
+	dcomp := func(r io.Reader) io.ReadCloser {
+		called = true
+
+		return flate.NewReader(r)
+	}
+
+	r := zipcompat.FromSevenZip(newSevenZip(t, "deflate.7z"))
+	r.RegisterDecompressor(zip.Deflate, dcomp)
+
+	check(t, r)
+	assert.True(t, called)
+
+	_, err := r.Open("missing")
+	assert.True(t, errors.Is(err, iofs.ErrNotExist))
+}